@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/aescanero/dago-node-router/internal/eval/cel"
+	"github.com/aescanero/dago-node-router/internal/router"
+)
+
+// runEval evaluates a CEL expression against a state file and prints the
+// result as JSON
+func runEval(args []string) int {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	statePath := fs.String("state", "", "graph state JSON file (- for stdin)")
+	configPath := fs.String("config", "", "NodeConfig JSON file whose number_types apply")
+	nodeID := fs.String("node-id", "router", "ID of the routed node, whose predecessor is prev")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: router-cli eval [-state file] [-config file] [-node-id id] <expression>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	ctx, state, err := loadState(*statePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var config *router.NodeConfig
+	if *configPath != "" {
+		config = &router.NodeConfig{}
+		if err := readJSON(*configPath, config); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read config: %v\n", err)
+			return 1
+		}
+	}
+
+	r, err := newRouter(nil, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	result, err := r.Evaluate(router.WithNodeID(ctx, *nodeID), fs.Arg(0), state, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Evaluation failed: %v\n", err)
+		return 1
+	}
+	if err := writeJSON(result); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runRender renders a prompt template against a state file
+func runRender(args []string) int {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	statePath := fs.String("state", "", "graph state JSON file (- for stdin)")
+	templatePath := fs.String("template", "", "file holding the template, instead of the argument")
+	engine := fs.String("engine", "", "template syntax: handlebars (default), go or jinja")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: router-cli render [-state file] [-engine syntax] (-template file | <template>)")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var text string
+	switch {
+	case *templatePath != "" && fs.NArg() == 0:
+		data, err := os.ReadFile(*templatePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read template: %v\n", err)
+			return 1
+		}
+		text = string(data)
+	case *templatePath == "" && fs.NArg() == 1:
+		text = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	ctx, state, err := loadState(*statePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	r, err := newRouter(nil, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	prompt, err := r.RenderPrompt(ctx, state, &router.LLMConfig{
+		PromptTemplate: text,
+		TemplateEngine: router.TemplateEngineType(*engine),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Rendering failed: %v\n", err)
+		return 1
+	}
+	fmt.Println(prompt)
+	return 0
+}
+
+// runRoute routes a state file with a NodeConfig and prints the decision as
+// JSON
+func runRoute(args []string) int {
+	fs := flag.NewFlagSet("route", flag.ContinueOnError)
+	configPath := fs.String("config", "", "NodeConfig JSON file (required)")
+	statePath := fs.String("state", "", "graph state JSON file (- for stdin)")
+	nodeID := fs.String("node-id", "router", "ID of the routed node")
+	verbose := fs.Bool("verbose", false, "log routing activity to stderr")
+	var llm llmFlags
+	llm.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: router-cli route -config file [-state file] [-llm-answer text | -llm-mock file | -llm-sim file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *configPath == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var config router.NodeConfig
+	if err := readJSON(*configPath, &config); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read config: %v\n", err)
+		return 1
+	}
+	ctx, state, err := loadState(*statePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	client, err := llm.client()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	r, err := newRouter(client, *verbose)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	result, err := r.Route(router.WithNodeID(ctx, *nodeID), state, &config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Routing failed: %v\n", err)
+		return 1
+	}
+	if err := writeJSON(result); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runValidate validates NodeConfig files, printing every problem found and
+// the lint warnings of their rules
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	complexity := fs.Bool("complexity", false, "print the node count and estimated cost of every rule expression")
+	costLimit := fs.Uint64("cost-limit", 1000000, "CEL cost limit expressions are checked against, as CEL_COST_LIMIT (0 disables)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: router-cli validate [-complexity] [-cost-limit n] <config.json>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	r, err := newRouter(nil, false, router.WithCELLimits(cel.Limits{CostLimit: *costLimit}))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	status := 0
+	for _, path := range fs.Args() {
+		var config router.NodeConfig
+		if err := readJSON(path, &config); err != nil {
+			fmt.Printf("%s: invalid JSON: %v\n", path, err)
+			status = 1
+			continue
+		}
+		errs := r.ValidateNodeConfig(&config)
+		if len(errs) == 0 {
+			fmt.Printf("%s: valid\n", path)
+		} else {
+			status = 1
+			problems := make([]string, len(errs))
+			for i, e := range errs {
+				problems[i] = "  " + e.Error()
+			}
+			fmt.Printf("%s: %d problem(s)\n%s\n", path, len(errs), strings.Join(problems, "\n"))
+		}
+		printLint(r.LintNodeConfig(&config), *complexity)
+	}
+	return status
+}
+
+// printLint prints the lint warnings of a config and, when complexity is
+// set, the complexity of its expressions
+func printLint(report *router.LintReport, complexity bool) {
+	for _, w := range report.Warnings {
+		fmt.Printf("  warning: %s\n", w.Error())
+	}
+	if !complexity {
+		return
+	}
+	for _, e := range report.Expressions {
+		cost := fmt.Sprintf("%d-%d", e.CostMin, e.CostMax)
+		if e.CostMax == math.MaxUint64 {
+			cost = fmt.Sprintf("%d-unbounded", e.CostMin)
+		}
+		fmt.Printf("  %s: %d nodes, cost %s\n", e.Field, e.Nodes, cost)
+	}
+}
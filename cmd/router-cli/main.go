@@ -0,0 +1,163 @@
+// Command router-cli tests routing rules, prompt templates and node configs
+// locally, without Redis or a running worker.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/llmmock"
+	"github.com/aescanero/dago-node-router/internal/llmsim"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"go.uber.org/zap"
+)
+
+const usage = `Usage: router-cli <command> [flags] [args]
+
+Commands:
+  eval      Evaluate a CEL expression against a state file
+  render    Render a prompt template against a state file
+  route     Route a state file with a NodeConfig
+  validate  Validate a NodeConfig
+
+Run router-cli <command> -h for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "eval":
+		os.Exit(runEval(args))
+	case "render":
+		os.Exit(runRender(args))
+	case "route":
+		os.Exit(runRoute(args))
+	case "validate":
+		os.Exit(runValidate(args))
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+// readJSON decodes a JSON file, or stdin when path is "-"
+func readJSON(path string, v interface{}) error {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// loadState reads a graph state file, returning it with a context carrying
+// its execution metadata; an empty path is an empty state
+func loadState(path string) (context.Context, *domain.GraphState, error) {
+	ctx := context.Background()
+	state := &domain.GraphState{Inputs: map[string]interface{}{}}
+	if path == "" {
+		return ctx, state, nil
+	}
+	var data json.RawMessage
+	if err := readJSON(path, &data); err != nil {
+		return nil, nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	var raw struct {
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, nil, fmt.Errorf("failed to read state: %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("failed to read state: %s: %w", path, err)
+	}
+	return router.WithExecutionMetadata(ctx, raw.Metadata), state, nil
+}
+
+// writeJSON prints v as indented JSON
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// llmFlags selects the offline LLM answering route commands
+type llmFlags struct {
+	answer     string
+	mock       string
+	simulation string
+}
+
+// register adds the LLM flags to fs
+func (f *llmFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.answer, "llm-answer", "", "answer every LLM classification with this text")
+	fs.StringVar(&f.mock, "llm-mock", "", "answer LLM classifications as described by an LLM mock file")
+	fs.StringVar(&f.simulation, "llm-sim", "", "answer LLM classifications as described by an LLM simulation file")
+}
+
+// client returns the offline LLM client, or nil when no flag is set, in
+// which case LLM routing fails as on a worker without an LLM
+func (f *llmFlags) client() (ports.LLMClient, error) {
+	set := 0
+	for _, value := range []string{f.answer, f.mock, f.simulation} {
+		if value != "" {
+			set++
+		}
+	}
+
+	switch {
+	case set > 1:
+		return nil, fmt.Errorf("-llm-answer, -llm-mock and -llm-sim are mutually exclusive")
+	case f.answer != "":
+		return llmmock.NewClient(llmmock.Config{Default: f.answer}), nil
+	case f.mock != "":
+		cfg, err := llmmock.LoadConfig(f.mock)
+		if err != nil {
+			return nil, err
+		}
+		return llmmock.NewClient(cfg), nil
+	case f.simulation != "":
+		cfg, err := llmsim.LoadConfig(f.simulation)
+		if err != nil {
+			return nil, err
+		}
+		return llmsim.NewClient(cfg), nil
+	default:
+		return nil, nil
+	}
+}
+
+// newRouter creates the router of a command, logging to stderr when verbose
+func newRouter(client ports.LLMClient, verbose bool, opts ...router.Option) (*router.Router, error) {
+	logger := zap.NewNop()
+	if verbose {
+		var err error
+		if logger, err = zap.NewDevelopment(); err != nil {
+			return nil, err
+		}
+	}
+	return router.NewRouter(client, logger, opts...), nil
+}
@@ -0,0 +1,769 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/audit"
+	"github.com/aescanero/dago-node-router/internal/cache"
+	"github.com/aescanero/dago-node-router/internal/chaos"
+	"github.com/aescanero/dago-node-router/internal/classifier"
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/aescanero/dago-node-router/internal/embedding"
+	"github.com/aescanero/dago-node-router/internal/eval/cel"
+	"github.com/aescanero/dago-node-router/internal/eval/template"
+	"github.com/aescanero/dago-node-router/internal/flags"
+	"github.com/aescanero/dago-node-router/internal/grpcserver"
+	"github.com/aescanero/dago-node-router/internal/kafka"
+	"github.com/aescanero/dago-node-router/internal/llmmock"
+	"github.com/aescanero/dago-node-router/internal/llmsim"
+	"github.com/aescanero/dago-node-router/internal/lookup"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/ollama"
+	"github.com/aescanero/dago-node-router/internal/policies"
+	"github.com/aescanero/dago-node-router/internal/prompts"
+	"github.com/aescanero/dago-node-router/internal/redact"
+	"github.com/aescanero/dago-node-router/internal/redisclient"
+	"github.com/aescanero/dago-node-router/internal/resources"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/aescanero/dago-node-router/internal/secrets"
+	"github.com/aescanero/dago-node-router/internal/staleness"
+	"github.com/aescanero/dago-node-router/internal/statestore"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	"github.com/aescanero/dago-node-router/internal/worker"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// redisPingTimeout bounds the Redis connection check at startup
+	redisPingTimeout = 5 * time.Second
+	// startupLoadTimeout bounds each read of Vault, the prompt template
+	// library and the rule sets at startup
+	startupLoadTimeout = 30 * time.Second
+	// shutdownTimeout bounds the graceful shutdown
+	shutdownTimeout = 10 * time.Second
+)
+
+// app holds the components of the router worker, wired by init and run
+// until shutdown
+type app struct {
+	cfg    *config.Config
+	logger *zap.Logger
+
+	runtimeReport   resources.Report
+	shutdownTracing func(context.Context) error
+	redisClient     redis.UniversalClient
+	injector        *chaos.Injector
+	vault           *secrets.Vault
+
+	// LLM clients; llmClient wraps the others when set
+	llmClient    ports.LLMClient
+	simulatedLLM *llmsim.Client
+	mockLLM      *llmmock.Client
+	rotatingLLM  *rotatingLLMClient
+	localLLM     *ollama.Probe
+
+	eventBus     *RedisEventBus
+	staleChecker *staleness.Checker
+	stateStore   statestore.Store
+	stateCache   *statestore.Cached
+
+	// Router dependencies also reported on or reloaded by the worker
+	llmCache        *cache.LRU[string]
+	decisionCache   *cache.LRU[router.RoutingResult]
+	redactor        *redact.Redactor
+	templateLibrary *prompts.Library
+	ruleSets        *policies.Registry
+
+	router        *router.Router
+	auditLog      audit.Log
+	worker        *worker.Worker
+	kafkaConsumer *kafka.Consumer
+	healthServer  *worker.HealthServer
+	grpcServer    *grpcserver.Server
+
+	// stopBackground cancels the background reloads and checks
+	stopBackground []context.CancelFunc
+}
+
+// init wires the components from the configuration, exiting on failure
+func (a *app) init() {
+	// Tune the runtime and cache sizes to the container limits
+	a.runtimeReport = tuneRuntime(a.cfg, a.logger)
+	metrics.SetLabelLimit(a.cfg.MetricsLabelLimit)
+
+	a.initTracing()
+	a.initRedis()
+
+	// Inject faults into Redis reads, LLM calls and state loads in staging
+	if a.cfg.ChaosEnabled {
+		a.initChaos()
+	}
+	if a.cfg.VaultEnabled() {
+		a.readVaultLLMKey()
+	}
+	a.initLLM()
+
+	// Initialize event bus (Redis Streams implementation)
+	a.eventBus = NewRedisEventBus(a.redisClient, a.logger)
+
+	// Watch configuration loaded once at startup for staleness
+	a.staleChecker = staleness.NewChecker(a.cfg.StaleConfigMaxAge, a.eventBus, a.cfg.StaleConfigTopic, a.logger)
+
+	a.initStateStore()
+
+	// Initialize router
+	a.router = router.NewRouter(a.llmClient, a.logger, a.routerOptions()...)
+	a.logger.Info("router initialized")
+
+	if a.cfg.AuditEnabled {
+		a.initAuditLog()
+	}
+	a.initWorker()
+}
+
+// initTracing starts exporting spans when tracing is enabled
+func (a *app) initTracing() {
+	cfg := a.cfg
+	var err error
+	a.shutdownTracing, err = tracing.Init(context.Background(), tracing.Config{
+		Enabled:     cfg.TracingEnabled,
+		Endpoint:    cfg.OTLPEndpoint,
+		Insecure:    cfg.OTLPInsecure,
+		SampleRatio: cfg.TraceSampleRatio,
+		ServiceName: "dago-node-router",
+		Version:     Version,
+	})
+	if err != nil {
+		a.logger.Fatal("failed to initialize tracing", zap.Error(err))
+	}
+	if cfg.TracingEnabled {
+		a.logger.Info("tracing enabled",
+			zap.String("endpoint", cfg.OTLPEndpoint),
+			zap.Float64("sample_ratio", cfg.TraceSampleRatio),
+		)
+	}
+}
+
+// initRedis connects to Redis and checks the connection
+func (a *app) initRedis() {
+	cfg := a.cfg
+	var err error
+	a.redisClient, err = redisclient.New(cfg)
+	if err != nil {
+		a.logger.Fatal("failed to configure redis", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisPingTimeout)
+	defer cancel()
+	if err := a.redisClient.Ping(ctx).Err(); err != nil {
+		a.logger.Fatal("failed to connect to redis", zap.Error(err))
+	}
+	a.logger.Info("connected to redis",
+		zap.String("addr", cfg.RedisEndpoint()),
+		zap.String("mode", cfg.RedisMode),
+		zap.Bool("tls", cfg.RedisTLSEnabled),
+	)
+}
+
+// initChaos hooks the fault injector into Redis; LLM calls and state loads
+// are wrapped as they are built
+func (a *app) initChaos() {
+	cfg := a.cfg
+	a.injector = initChaos(cfg)
+	a.redisClient.AddHook(a.injector.RedisHook())
+	a.logger.Warn("chaos fault injection enabled, do not run this worker in production",
+		zap.Float64("redis_error_rate", cfg.ChaosRedisErrorRate),
+		zap.Float64("llm_error_rate", cfg.ChaosLLMErrorRate),
+		zap.Duration("llm_latency", cfg.ChaosLLMLatency),
+		zap.Float64("llm_latency_rate", cfg.ChaosLLMLatencyRate),
+		zap.Float64("state_corruption_rate", cfg.ChaosStateCorruptionRate),
+	)
+}
+
+// readVaultLLMKey reads the LLM API key from Vault into the configuration
+func (a *app) readVaultLLMKey() {
+	cfg := a.cfg
+	a.vault = initVault(cfg, a.logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), startupLoadTimeout)
+	defer cancel()
+	key, err := a.vault.Read(ctx, cfg.VaultLLMKeyPath, cfg.VaultLLMKeyField)
+	if err != nil {
+		a.logger.Fatal("failed to read llm api key from vault", zap.Error(err))
+	}
+	cfg.LLMAPIKey = key
+	a.logger.Info("llm api key read from vault", zap.String("path", cfg.VaultLLMKeyPath))
+}
+
+// initLLM builds the default LLM client (optional for deterministic-only
+// mode)
+func (a *app) initLLM() {
+	cfg := a.cfg
+	logger := a.logger
+	var err error
+	switch {
+	case cfg.LLMProvider == config.LLMProviderSimulated:
+		a.simulatedLLM, err = initSimulatedLLM(cfg)
+		if err != nil {
+			logger.Fatal("failed to initialize simulated llm", zap.Error(err))
+		}
+		a.llmClient = a.simulatedLLM
+		logger.Warn("using simulated llm, decisions are not made by a real model",
+			zap.String("simulation_file", cfg.LLMSimulationFile),
+		)
+	case cfg.LLMProvider == config.LLMProviderMock:
+		a.mockLLM, err = initMockLLM(cfg)
+		if err != nil {
+			logger.Fatal("failed to initialize mock llm", zap.Error(err))
+		}
+		a.llmClient = a.mockLLM
+		logger.Warn("using mock llm, decisions are not made by a real model",
+			zap.String("mock_file", cfg.LLMMockFile),
+			zap.Int("mock_routes", len(cfg.LLMMockRoutes)),
+		)
+	case cfg.LLMAPIKey != "" || cfg.LLMLocal():
+		a.llmClient, err = initLLMClient(cfg)
+		if err != nil {
+			logger.Warn("failed to initialize llm client (llm routing will not be available)",
+				zap.Error(err),
+			)
+		} else {
+			if a.vault != nil {
+				a.rotatingLLM = newRotatingLLMClient(a.llmClient)
+				a.llmClient = a.rotatingLLM
+			}
+			logger.Info("llm client initialized",
+				zap.String("provider", cfg.LLMProvider),
+				zap.String("model", cfg.LLMModel),
+			)
+		}
+		if cfg.LLMLocal() {
+			a.localLLM = ollama.NewProbe(cfg.LLMBaseURL, cfg.LLMModel)
+			warmupLocalLLM(cfg, a.localLLM, logger)
+		}
+	default:
+		logger.Warn("llm api key not provided (llm routing will not be available)")
+	}
+	if a.injector != nil {
+		a.llmClient = a.injector.LLMClient(a.llmClient)
+	}
+}
+
+// initStateStore builds the state store of the configured backend, behind
+// the local cache when enabled
+func (a *app) initStateStore() {
+	cfg := a.cfg
+	var err error
+	a.stateStore, err = statestore.New(context.Background(), cfg.StateBackend, cfg, statestore.Deps{
+		Redis:  a.redisClient,
+		Logger: a.logger,
+	})
+	if err != nil {
+		a.logger.Fatal("failed to initialize state store", zap.Error(err))
+	}
+	a.logger.Info("state store initialized", zap.String("backend", cfg.StateBackend))
+	if cfg.StateCacheEnabled {
+		a.stateCache = statestore.NewCached(a.stateStore, a.redisClient, cfg.StateCacheChannel, cfg.StateCacheSize, cfg.StateCacheTTL, a.logger)
+		a.stateStore = a.stateCache
+		a.logger.Info("local state cache enabled",
+			zap.Int("size", cfg.StateCacheSize),
+			zap.Duration("ttl", cfg.StateCacheTTL),
+			zap.String("channel", cfg.StateCacheChannel),
+		)
+	}
+	if a.injector != nil {
+		// Outside the cache, so corrupted states are never cached
+		a.stateStore = a.injector.StateStore(a.stateStore)
+	}
+}
+
+// routerOptions builds the router options from the configuration
+func (a *app) routerOptions() []router.Option {
+	cfg := a.cfg
+	opts := []router.Option{
+		router.WithLLMModel(cfg.LLMModel),
+		router.WithLLMEndpoint(cfg.LLMProvider, cfg.LLMBaseURL),
+		router.WithLLMTimeout(cfg.LLMTimeout),
+		router.WithMaxLLMRoutes(cfg.LLMMaxRoutes),
+		router.WithMaxPromptTokens(cfg.LLMMaxPromptTokens),
+		router.WithStreaming(cfg.LLMStreaming),
+		router.WithCELLimits(cel.Limits{
+			Timeout:   cfg.EvalTimeout,
+			CostLimit: cfg.CELCostLimit,
+		}),
+		router.WithNumberMode(cel.NumberMode(cfg.CELNumberMode)),
+		router.WithShadowLimits(cfg.ShadowMaxInFlight, cfg.ShadowTimeout),
+		router.WithRuleReordering(cfg.RuleReorderEnabled, cfg.RuleReorderSamples),
+	}
+	opts = append(opts, a.llmOptions()...)
+	opts = append(opts, a.promptOptions()...)
+	opts = append(opts, a.ruleOptions()...)
+	return append(opts, a.tenantOptions()...)
+}
+
+// llmOptions configures the circuit breaker, rate limits and caches of LLM
+// routing
+func (a *app) llmOptions() []router.Option {
+	cfg := a.cfg
+	logger := a.logger
+	var opts []router.Option
+	if cfg.LLMBreakerEnabled {
+		opts = append(opts, router.WithCircuitBreaker(router.BreakerConfig{
+			FailureThreshold: cfg.LLMBreakerThreshold,
+			OpenDuration:     cfg.LLMBreakerOpenTime,
+			HalfOpenProbes:   cfg.LLMBreakerProbes,
+		}))
+	}
+	if cfg.LLMRateLimited() {
+		opts = append(opts, router.WithLLMLimiter(router.LimiterConfig{
+			MaxRPS:        cfg.LLMMaxRPS,
+			Burst:         cfg.LLMBurst,
+			NodeMaxRPS:    cfg.LLMNodeMaxRPS,
+			MaxConcurrent: cfg.LLMMaxConcurrent,
+			MaxWait:       cfg.LLMLimitWait,
+		}))
+		logger.Info("llm rate limiting enabled",
+			zap.Float64("max_rps", cfg.LLMMaxRPS),
+			zap.Float64("node_max_rps", cfg.LLMNodeMaxRPS),
+			zap.Int("max_concurrent", cfg.LLMMaxConcurrent),
+			zap.Duration("wait", cfg.LLMLimitWait),
+		)
+	}
+	if cfg.LLMCacheEnabled {
+		var llmCache cache.Cache
+		llmCache, a.llmCache = initLLMCache(cfg, a.redisClient, logger)
+		opts = append(opts, router.WithLLMCache(llmCache))
+		logger.Info("llm response cache enabled",
+			zap.Int("size", cfg.LLMCacheSize),
+			zap.Duration("ttl", cfg.LLMCacheTTL),
+			zap.Bool("redis", cfg.LLMCacheRedis),
+		)
+	}
+	if cfg.DecisionCacheEnabled {
+		a.decisionCache = cache.NewLRU[router.RoutingResult](cfg.DecisionCacheSize, cfg.DecisionCacheTTL)
+		opts = append(opts, router.WithDecisionCache(a.decisionCache))
+		logger.Info("decision cache enabled",
+			zap.Int("size", cfg.DecisionCacheSize),
+			zap.Duration("ttl", cfg.DecisionCacheTTL),
+		)
+	}
+	return opts
+}
+
+// promptOptions configures the prompt guard, redaction, template sandbox and
+// prompt template library
+func (a *app) promptOptions() []router.Option {
+	cfg := a.cfg
+	logger := a.logger
+	var opts []router.Option
+	if cfg.PromptGuardEnabled() {
+		opts = append(opts, router.WithPromptGuard(router.PromptGuard{
+			StripControl:   cfg.PromptGuardStripControl,
+			MaxValueLength: cfg.PromptGuardMaxValueLength,
+			Delimit:        cfg.PromptGuardDelimit,
+			StrictAnswers:  cfg.PromptGuardStrictAnswers,
+		}))
+		logger.Info("prompt guard enabled",
+			zap.Bool("strip_control", cfg.PromptGuardStripControl),
+			zap.Int("max_value_length", cfg.PromptGuardMaxValueLength),
+			zap.Bool("delimit", cfg.PromptGuardDelimit),
+			zap.Bool("strict_answers", cfg.PromptGuardStrictAnswers),
+		)
+	}
+	if cfg.RedactionEnabled() {
+		var err error
+		a.redactor, err = redact.New(redact.Config{
+			Fields:   cfg.RedactFields,
+			Patterns: cfg.RedactPatterns,
+			Regexes:  cfg.RedactRegexes,
+			Mask:     cfg.RedactMask,
+		})
+		if err != nil {
+			logger.Fatal("failed to initialize redaction", zap.Error(err))
+		}
+		opts = append(opts, router.WithRedaction(a.redactor, cfg.RedactPrompts))
+		logger.Info("redaction enabled",
+			zap.Strings("fields", cfg.RedactFields),
+			zap.Strings("patterns", cfg.RedactPatterns),
+			zap.Int("regexes", len(cfg.RedactRegexes)),
+			zap.Bool("prompts", cfg.RedactPrompts),
+		)
+	}
+	if cfg.TemplateSandbox {
+		opts = append(opts, router.WithTemplateSandbox(template.Sandbox{
+			AllowedHelpers: cfg.TemplateAllowedHelpers,
+			DeniedPaths:    cfg.TemplateDeniedPaths,
+			MaxDepth:       cfg.TemplateMaxDepth,
+			MaxLoopItems:   cfg.TemplateMaxLoopItems,
+			MaxOutputBytes: cfg.TemplateMaxOutputBytes,
+		}))
+		logger.Info("template sandbox enabled",
+			zap.Strings("denied_paths", cfg.TemplateDeniedPaths),
+			zap.Int("max_depth", cfg.TemplateMaxDepth),
+		)
+	}
+	if cfg.TemplateLibraryEnabled() {
+		a.templateLibrary = initTemplateLibrary(cfg, a.redisClient, logger)
+		ctx, cancel := context.WithTimeout(context.Background(), startupLoadTimeout)
+		defer cancel()
+		if err := a.templateLibrary.Reload(ctx); err != nil {
+			logger.Fatal("failed to load prompt template library", zap.Error(err))
+		}
+		opts = append(opts, router.WithTemplateLibrary(a.templateLibrary))
+		logger.Info("prompt template library loaded",
+			zap.Int("templates", len(a.templateLibrary.List())),
+			zap.Duration("reload_interval", cfg.TemplateLibraryReloadInterval),
+		)
+	}
+	return opts
+}
+
+// ruleOptions configures what rules can read and reference: feature flags,
+// lookups, message histories, rule sets and classifier models
+func (a *app) ruleOptions() []router.Option {
+	cfg := a.cfg
+	logger := a.logger
+	var opts []router.Option
+	if cfg.FlagsProvider != "" {
+		flagSet := flags.NewCached(initFlagsProvider(cfg, a.redisClient), cfg.FlagsCacheTTL, cfg.FlagsEvalTimeout, logger)
+		opts = append(opts, router.WithFeatureFlags(flagSet.Enabled))
+		logger.Info("feature flags enabled",
+			zap.String("provider", cfg.FlagsProvider),
+			zap.Duration("cache_ttl", cfg.FlagsCacheTTL),
+		)
+	}
+	if cfg.LookupsEnabled {
+		lookupClient := lookup.NewClient(a.redisClient,
+			lookup.WithAllowedHosts(cfg.LookupAllowedHosts),
+			lookup.WithMaxBodyBytes(cfg.LookupMaxBytes),
+		)
+		opts = append(opts, router.WithLookups(lookupClient, router.LookupConfig{
+			Timeout:   cfg.LookupTimeout,
+			CacheSize: cfg.LookupCacheSize,
+			CacheTTL:  cfg.LookupCacheTTL,
+		}))
+		logger.Info("node config lookups enabled",
+			zap.Duration("timeout", cfg.LookupTimeout),
+			zap.Strings("allowed_hosts", cfg.LookupAllowedHosts),
+		)
+	}
+	// Message histories held in Redis lists are read like Redis lookups
+	opts = append(opts, router.WithHistorySource(lookup.NewClient(a.redisClient,
+		lookup.WithMaxBodyBytes(cfg.LookupMaxBytes),
+	)))
+	if cfg.RuleSetsEnabled() {
+		a.ruleSets = initRuleSets(cfg, a.redisClient, logger)
+		ctx, cancel := context.WithTimeout(context.Background(), startupLoadTimeout)
+		defer cancel()
+		if err := a.ruleSets.Reload(ctx); err != nil {
+			logger.Fatal("failed to load rule sets", zap.Error(err))
+		}
+		opts = append(opts, router.WithRuleSets(a.ruleSets))
+		logger.Info("rule sets loaded",
+			zap.Int("rule_sets", len(a.ruleSets.List())),
+			zap.Duration("reload_interval", cfg.RuleSetsReloadInterval),
+		)
+	}
+	if cfg.ClassifierModelsDir != "" {
+		models, err := classifier.LoadDir(cfg.ClassifierModelsDir)
+		if err != nil {
+			logger.Fatal("failed to load classifier models", zap.Error(err))
+		}
+		opts = append(opts, router.WithClassifierModels(models))
+		logger.Info("classifier models loaded",
+			zap.String("dir", cfg.ClassifierModelsDir),
+			zap.Int("models", len(models)),
+		)
+	}
+	if cfg.EmbeddingBaseURL != "" {
+		embedder := embedding.NewClient(cfg.EmbeddingBaseURL, cfg.EmbeddingModel,
+			embedding.WithAPIKey(cfg.EmbeddingAPIKey),
+			embedding.WithHTTPClient(&http.Client{Timeout: cfg.EmbeddingTimeout}),
+		)
+		opts = append(opts, router.WithEmbedder(embedder))
+		logger.Info("embedding routing enabled",
+			zap.String("base_url", cfg.EmbeddingBaseURL),
+			zap.String("model", cfg.EmbeddingModel),
+		)
+	}
+	return opts
+}
+
+// tenantOptions configures the per-tenant LLM clients and quotas
+func (a *app) tenantOptions() []router.Option {
+	cfg := a.cfg
+	logger := a.logger
+	var opts []router.Option
+	if cfg.TenantLLMFile != "" {
+		tenantLLMs, err := initTenantLLMs(cfg)
+		if err != nil {
+			logger.Fatal("failed to initialize tenant llm clients", zap.Error(err))
+		}
+		a.staleChecker.Track("tenant_llm_file", time.Now(), staleness.FileProbe(cfg.TenantLLMFile))
+		for tenant := range tenantLLMs {
+			metrics.Tenants.Declare(tenant)
+		}
+		opts = append(opts, router.WithTenantLLMs(cfg.TenantField, tenantLLMs))
+		logger.Info("tenant llm clients initialized",
+			zap.String("tenant_field", cfg.TenantField),
+			zap.Int("tenants", len(tenantLLMs)),
+		)
+	}
+
+	opts = append(opts, router.WithTenantField(cfg.TenantField))
+	if cfg.TenantQuotasEnabled() {
+		quotas, err := initTenantQuotas(cfg)
+		if err != nil {
+			logger.Fatal("failed to load tenant quotas", zap.Error(err))
+		}
+		if cfg.TenantQuotasFile != "" {
+			a.staleChecker.Track("tenant_quotas_file", time.Now(), staleness.FileProbe(cfg.TenantQuotasFile))
+		}
+		for tenant := range quotas.Tenants {
+			metrics.Tenants.Declare(tenant)
+		}
+		opts = append(opts, router.WithTenantQuotas(quotas))
+		logger.Info("tenant quotas enabled",
+			zap.Int("max_concurrent", cfg.TenantMaxConcurrent),
+			zap.Float64("max_rps", cfg.TenantMaxRPS),
+			zap.Int64("llm_token_budget", cfg.TenantLLMTokenBudget),
+			zap.Int("tenant_overrides", len(quotas.Tenants)),
+		)
+	}
+	return opts
+}
+
+// initAuditLog opens the audit log of the configured backend
+func (a *app) initAuditLog() {
+	var err error
+	a.auditLog, err = audit.New(a.cfg, a.redisClient)
+	if err != nil {
+		a.logger.Fatal("failed to initialize audit log", zap.Error(err))
+	}
+	a.logger.Info("audit log enabled",
+		zap.String("backend", a.cfg.AuditBackend),
+		zap.Duration("retention", a.cfg.AuditRetention),
+	)
+}
+
+// initWorker builds the worker that routes work requests
+func (a *app) initWorker() {
+	workerOpts := []worker.Option{worker.WithVersion(Version)}
+	if a.cfg.GRPCEnabled {
+		workerOpts = append(workerOpts, worker.WithTransport(grpcserver.Transport))
+	}
+	if a.auditLog != nil {
+		workerOpts = append(workerOpts, worker.WithAuditLog(a.auditLog))
+	}
+	if a.redactor != nil {
+		workerOpts = append(workerOpts, worker.WithRedaction(a.redactor))
+	}
+	a.worker = worker.NewWorker(a.cfg, a.redisClient, a.router, a.eventBus, a.stateStore, a.logger, workerOpts...)
+}
+
+// start consumes work from the configured transport, then starts the
+// background tasks, the health server and the gRPC server
+func (a *app) start() {
+	cfg := a.cfg
+	if cfg.WorkTransport == kafka.Transport {
+		a.kafkaConsumer = kafka.NewConsumer(cfg, a.worker, a.logger)
+		if err := a.kafkaConsumer.Start(); err != nil {
+			a.logger.Fatal("failed to start kafka consumer", zap.Error(err))
+		}
+	} else if err := a.worker.Start(); err != nil {
+		a.logger.Fatal("failed to start worker", zap.Error(err))
+	}
+
+	a.startBackground()
+
+	a.healthServer = worker.NewHealthServer(cfg.HealthPort, a.redisClient, a.logger, a.healthOptions()...)
+	if err := a.healthServer.Start(); err != nil {
+		a.logger.Fatal("failed to start health server", zap.Error(err))
+	}
+
+	// Start gRPC server, backed by the same router as the worker
+	if cfg.GRPCEnabled {
+		var grpcOpts []grpcserver.Option
+		if a.auditLog != nil {
+			grpcOpts = append(grpcOpts, grpcserver.WithAuditLog(a.auditLog, cfg.WorkerID))
+		}
+		a.grpcServer = grpcserver.NewServer(a.router, a.stateStore, a.logger, grpcOpts...)
+		if err := a.grpcServer.Start(net.JoinHostPort(cfg.GRPCHost, fmt.Sprint(cfg.GRPCPort))); err != nil {
+			a.logger.Fatal("failed to start grpc server", zap.Error(err))
+		}
+	}
+}
+
+// startBackground runs the staleness checks, the reloads and the Vault key
+// watch until shutdown
+func (a *app) startBackground() {
+	cfg := a.cfg
+	run := func(task func(ctx context.Context)) {
+		ctx, cancel := context.WithCancel(context.Background())
+		a.stopBackground = append(a.stopBackground, cancel)
+		go task(ctx)
+	}
+
+	run(func(ctx context.Context) { a.staleChecker.Run(ctx, cfg.StaleConfigCheckInterval) })
+	if a.templateLibrary != nil && cfg.TemplateLibraryReloadInterval > 0 {
+		run(func(ctx context.Context) { a.templateLibrary.Run(ctx, cfg.TemplateLibraryReloadInterval) })
+	}
+	if a.ruleSets != nil && cfg.RuleSetsReloadInterval > 0 {
+		run(func(ctx context.Context) { a.ruleSets.Run(ctx, cfg.RuleSetsReloadInterval) })
+	}
+	if a.rotatingLLM != nil && cfg.VaultRefreshInterval > 0 {
+		run(func(ctx context.Context) { watchVaultLLMKey(ctx, a.vault, cfg, a.rotatingLLM, a.logger) })
+	}
+}
+
+// healthOptions registers the health, readiness and diagnostics endpoints
+func (a *app) healthOptions() []worker.HealthOption {
+	cfg := a.cfg
+	w := a.worker
+	routerInstance := a.router
+	opts := []worker.HealthOption{
+		worker.WithHealthHost(cfg.HealthHost),
+		worker.WithHealthSocket(cfg.HealthSocket),
+		worker.WithHealthCheck("worker", w.Health),
+		worker.WithReadinessCheck("worker", w.Ready),
+		worker.WithCapabilities(w.Capabilities),
+		worker.WithConfigValidation(routerInstance.ValidateNodeConfig),
+		worker.WithConfigLint(routerInstance.LintNodeConfig),
+		worker.WithRuleStats(routerInstance.RuleStats),
+		worker.WithRuleOrders(routerInstance.RuleOrders, routerInstance.ResetRuleOrder),
+		worker.WithHealthDetail("llm_circuits", func() interface{} {
+			return routerInstance.CircuitStates()
+		}),
+		worker.WithHealthDetail("stale_config", func() interface{} {
+			return a.staleChecker.Stale()
+		}),
+	}
+	if a.kafkaConsumer != nil {
+		opts = append(opts, worker.WithHealthCheck("kafka", a.kafkaConsumer.Health))
+	}
+	if cfg.ReadinessRequireLLM {
+		opts = append(opts, worker.WithReadinessCheck("llm", func(context.Context) error {
+			return routerInstance.LLMReady()
+		}))
+	}
+	if localLLM := a.localLLM; localLLM != nil {
+		if cfg.ReadinessRequireLLM {
+			opts = append(opts, worker.WithReadinessCheck("local_llm", localLLM.Health))
+		} else {
+			opts = append(opts, worker.WithHealthDetail("local_llm", func() interface{} {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				defer cancel()
+				if err := localLLM.Health(ctx); err != nil {
+					return map[string]interface{}{"healthy": false, "error": err.Error()}
+				}
+				return map[string]interface{}{"healthy": true}
+			}))
+		}
+	}
+	if cfg.StateBackend != config.StateBackendRedis {
+		opts = append(opts, worker.WithHealthCheck("state_store", a.stateStore.Ping))
+	}
+	if a.auditLog != nil {
+		opts = append(opts, worker.WithAuditQuery(a.auditLog.Query))
+	}
+	if a.templateLibrary != nil {
+		opts = append(opts, worker.WithTemplateLibrary(a.templateLibrary))
+	}
+	if a.ruleSets != nil {
+		opts = append(opts, worker.WithRuleSets(a.ruleSets))
+	}
+	if cfg.LagEndpointEnabled {
+		opts = append(opts, worker.WithLagReport(w.LagReport))
+	}
+	if cfg.PprofEnabled {
+		opts = append(opts, worker.WithPprof())
+	}
+	opts = append(opts, diagnosticsOptions(cfg, a.runtimeReport, routerInstance, w, a.llmCache, a.decisionCache, a.stateCache)...)
+	if simulatedLLM := a.simulatedLLM; simulatedLLM != nil {
+		opts = append(opts, worker.WithHealthDetail("llm_simulation", func() interface{} {
+			return simulatedLLM.Stats()
+		}))
+	}
+	if injector := a.injector; injector != nil {
+		opts = append(opts, worker.WithHealthDetail("chaos", func() interface{} {
+			return injector.Stats()
+		}))
+	}
+	if mockLLM := a.mockLLM; mockLLM != nil {
+		opts = append(opts, worker.WithHealthDetail("llm_mock", func() interface{} {
+			return map[string]int64{"calls": mockLLM.Calls()}
+		}))
+	}
+	return opts
+}
+
+// shutdown stops the servers and the worker, then closes the stores and
+// connections, within shutdownTimeout
+func (a *app) shutdown() {
+	logger := a.logger
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Stop gRPC server
+	if a.grpcServer != nil {
+		a.grpcServer.Stop()
+	}
+
+	// Stop consuming Kafka work
+	if a.kafkaConsumer != nil {
+		if err := a.kafkaConsumer.Stop(); err != nil {
+			logger.Error("failed to stop kafka consumer", zap.Error(err))
+		}
+	}
+
+	// Stop worker; /ready reports not ready while it drains
+	if err := a.worker.Stop(); err != nil {
+		logger.Error("failed to stop worker", zap.Error(err))
+	}
+
+	// Stop health server
+	if err := a.healthServer.Stop(); err != nil {
+		logger.Error("failed to stop health server", zap.Error(err))
+	}
+
+	// Stop background reloads and checks before their stores close
+	for _, stop := range a.stopBackground {
+		stop()
+	}
+
+	// Flush pending spans
+	if err := a.shutdownTracing(ctx); err != nil {
+		logger.Error("failed to shut down tracing", zap.Error(err))
+	}
+
+	// Close audit log
+	if a.auditLog != nil {
+		if err := a.auditLog.Close(); err != nil {
+			logger.Error("failed to close audit log", zap.Error(err))
+		}
+	}
+
+	// Close state store
+	if err := a.stateStore.Close(); err != nil {
+		logger.Error("failed to close state store", zap.Error(err))
+	}
+
+	// Close Redis connection
+	if err := a.redisClient.Close(); err != nil {
+		logger.Error("failed to close redis connection", zap.Error(err))
+	}
+
+	select {
+	case <-ctx.Done():
+		logger.Warn("shutdown timeout exceeded, forcing exit")
+	default:
+		logger.Info("worker stopped gracefully")
+	}
+}
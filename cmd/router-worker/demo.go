@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/aescanero/dago-node-router/internal/logging"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/aescanero/dago-node-router/internal/statestore"
+	"github.com/aescanero/dago-node-router/internal/worker"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// demoStates are the sample graph states seeded by the demo, by execution ID
+var demoStates = map[string]state.State{
+	"demo-outage": {
+		"graph_id": "support-triage",
+		"status":   "running",
+		"inputs": map[string]interface{}{
+			"priority": "high",
+			"message":  "Production API returns 500 errors after the last deploy",
+		},
+	},
+	"demo-refund": {
+		"graph_id": "support-triage",
+		"status":   "running",
+		"inputs": map[string]interface{}{
+			"priority": "normal",
+			"message":  "I was charged twice, please refund the second invoice",
+		},
+	},
+	"demo-question": {
+		"graph_id": "support-triage",
+		"status":   "running",
+		"inputs": map[string]interface{}{
+			"priority": "low",
+			"message":  "How do I change the avatar on my profile?",
+		},
+	},
+}
+
+// demoConfigs are routing configs for the three modes, by mode
+var demoConfigs = map[string]map[string]interface{}{
+	"deterministic": {
+		"mode": "deterministic",
+		"rules": []map[string]interface{}{
+			{"condition": `state.inputs.priority == "high"`, "target": "incident_response"},
+			{"condition": `state.inputs.message.contains("refund")`, "target": "billing_agent"},
+		},
+		"fallback": "general_agent",
+	},
+	"llm": {
+		"mode": "llm",
+		"llm_config": map[string]interface{}{
+			"prompt_template": "Classify this support message as billing, technical or general: {{state.inputs.message}}",
+			"routes": map[string]string{
+				"billing":   "billing_agent",
+				"technical": "tech_support",
+				"general":   "general_agent",
+			},
+		},
+		"fallback": "general_agent",
+	},
+	"hybrid": {
+		"mode": "hybrid",
+		"fast_rules": []map[string]interface{}{
+			{"condition": `state.inputs.priority == "high"`, "target": "incident_response"},
+		},
+		"llm_fallback": map[string]interface{}{
+			"prompt_template": "Classify this support message as billing, technical or general: {{state.inputs.message}}",
+			"routes": map[string]string{
+				"billing":   "billing_agent",
+				"technical": "tech_support",
+				"general":   "general_agent",
+			},
+		},
+		"fallback": "general_agent",
+	},
+}
+
+// demoScript is the sequence of work requests run by the demo
+var demoScript = []struct {
+	ExecutionID string
+	Mode        string
+}{
+	{"demo-outage", "deterministic"},
+	{"demo-refund", "deterministic"},
+	{"demo-question", "deterministic"},
+	{"demo-outage", "llm"},
+	{"demo-refund", "llm"},
+	{"demo-question", "llm"},
+	{"demo-outage", "hybrid"},
+	{"demo-refund", "hybrid"},
+	{"demo-question", "hybrid"},
+}
+
+// runDemo seeds Redis with sample states and configs, routes a script of
+// work requests through a worker and prints the decisions
+func runDemo(args []string) int {
+	fs := flag.NewFlagSet("demo", flag.ContinueOnError)
+	redisAddr := fs.String("redis", "", "Redis address to use instead of an embedded in-memory Redis")
+	timeout := fs.Duration("timeout", 30*time.Second, "time to wait for all decisions")
+	verbose := fs.Bool("verbose", false, "log worker activity")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	// Keep demo traffic apart from real streams
+	cfg.WorkerID = "demo-worker"
+	cfg.StreamKey = "demo.router.work"
+	cfg.ConsumerGroup = "demo-router"
+	cfg.ResultStream = "demo.router.decided"
+	cfg.StreamShards = 0
+	cfg.PriorityLanes = nil
+	cfg.ClaimEnabled = false
+	cfg.WorkTransport = config.WorkTransportRedisStreams
+
+	if *redisAddr == "" {
+		embedded, err := miniredis.Run()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start embedded redis: %v\n", err)
+			return 1
+		}
+		defer embedded.Close()
+		*redisAddr = embedded.Addr()
+		fmt.Printf("Using embedded Redis at %s\n", *redisAddr)
+	} else {
+		fmt.Printf("Using Redis at %s (streams prefixed with demo.)\n", *redisAddr)
+	}
+	cfg.RedisAddr = *redisAddr
+
+	logger := zap.NewNop()
+	if *verbose {
+		if logger, err = logging.New(logging.Config{Level: "info"}); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+			return 1
+		}
+	}
+
+	redisClient := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr})
+	defer redisClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := seedDemo(ctx, redisClient, cfg, logger); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to seed demo: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Seeded %d graph states and %d work requests\n\n", len(demoStates), len(demoScript))
+
+	routerInstance := router.NewRouter(demoLLM{}, logger)
+	w := worker.NewWorker(cfg, redisClient, routerInstance,
+		NewRedisEventBus(redisClient, logger), statestore.NewRedis(redisClient, logger), logger)
+	if err := w.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start worker: %v\n", err)
+		return 1
+	}
+	defer func() { _ = w.Stop() }()
+
+	decisions, err := collectDemoDecisions(ctx, redisClient, cfg, len(demoScript))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Demo incomplete: %v\n", err)
+		return 1
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EXECUTION\tMODE\tTARGET\tPATH\tREASONING")
+	for _, d := range decisions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", d["execution_id"], d["mode"], d["target_node"], d["path_taken"], d["reasoning"])
+	}
+	if err := tw.Flush(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// seedDemo stores the demo states and enqueues the demo work requests
+func seedDemo(ctx context.Context, client redis.UniversalClient, cfg *config.Config, logger *zap.Logger) error {
+	store := statestore.NewRedis(client, logger)
+	for executionID, st := range demoStates {
+		if err := store.Save(ctx, executionID, st); err != nil {
+			return err
+		}
+	}
+
+	for i, step := range demoScript {
+		request, err := json.Marshal(map[string]interface{}{
+			"execution_id": step.ExecutionID,
+			"node_id":      fmt.Sprintf("router-%d", i+1),
+			"config":       demoConfigs[step.Mode],
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal work request: %w", err)
+		}
+		if err := client.XAdd(ctx, &redis.XAddArgs{
+			Stream: cfg.StreamKey,
+			Values: map[string]interface{}{"data": string(request)},
+		}).Err(); err != nil {
+			return fmt.Errorf("failed to enqueue work request: %w", err)
+		}
+	}
+	return nil
+}
+
+// collectDemoDecisions reads decisions and error events until n outcomes arrived
+func collectDemoDecisions(ctx context.Context, client redis.UniversalClient, cfg *config.Config, n int) ([]map[string]interface{}, error) {
+	errorStream := cfg.ResultStream + ".errors"
+	last := map[string]string{cfg.ResultStream: "0", errorStream: "0"}
+	var outcomes []map[string]interface{}
+
+	for len(outcomes) < n {
+		streams, err := client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{cfg.ResultStream, errorStream, last[cfg.ResultStream], last[errorStream]},
+			Block:   time.Second,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return outcomes, fmt.Errorf("received %d of %d outcomes: %w", len(outcomes), n, err)
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				last[stream.Stream] = message.ID
+				var outcome map[string]interface{}
+				data, _ := message.Values["data"].(string)
+				if err := json.Unmarshal([]byte(data), &outcome); err != nil {
+					continue
+				}
+				if stream.Stream == errorStream {
+					outcome["target_node"] = "(error)"
+					outcome["reasoning"] = outcome["error"]
+				}
+				outcomes = append(outcomes, outcome)
+			}
+		}
+	}
+	return outcomes, nil
+}
+
+// demoLLM is an offline stand-in for a real LLM that classifies support
+// messages by keyword, so the demo runs without an API key
+type demoLLM struct{}
+
+// demoKeywords maps message keywords to the demo classification labels
+var demoKeywords = []struct {
+	label    string
+	keywords []string
+}{
+	{"billing", []string{"refund", "invoice", "charged", "payment"}},
+	{"technical", []string{"error", "crash", "500", "deploy", "bug"}},
+}
+
+// GenerateCompletion answers the routing prompt with a keyword classification
+func (demoLLM) GenerateCompletion(ctx context.Context, req interface{}) (interface{}, error) {
+	request, ok := req.(*domain.LLMRequest)
+	if !ok || len(request.Messages) == 0 {
+		return nil, fmt.Errorf("demo llm: unsupported request")
+	}
+
+	prompt := strings.ToLower(request.Messages[len(request.Messages)-1].Content)
+	label := "general"
+	for _, candidate := range demoKeywords {
+		for _, keyword := range candidate.keywords {
+			if strings.Contains(prompt, keyword) {
+				label = candidate.label
+				break
+			}
+		}
+		if label != "general" {
+			break
+		}
+	}
+
+	return &domain.LLMResponse{Content: label, Model: "demo"}, nil
+}
+
+// Complete is not supported by the demo LLM
+func (demoLLM) Complete(ctx context.Context, req ports.CompletionRequest) (*ports.CompletionResponse, error) {
+	return nil, fmt.Errorf("demo llm: Complete not supported")
+}
+
+// CompleteWithTools is not supported by the demo LLM
+func (demoLLM) CompleteWithTools(ctx context.Context, req ports.CompletionRequest, tools []ports.Tool) (*ports.CompletionResponse, error) {
+	return nil, fmt.Errorf("demo llm: CompleteWithTools not supported")
+}
+
+// CompleteStructured is not supported by the demo LLM
+func (demoLLM) CompleteStructured(ctx context.Context, req ports.CompletionRequest, schema ports.JSONSchema) (*ports.StructuredResponse, error) {
+	return nil, fmt.Errorf("demo llm: CompleteStructured not supported")
+}
@@ -10,15 +10,25 @@ import (
 	"time"
 
 	"github.com/aescanero/dago-adapters/pkg/llm"
-	"github.com/aescanero/dago-libs/pkg/domain/state"
 	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/cache"
+	"github.com/aescanero/dago-node-router/internal/chaos"
 	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/aescanero/dago-node-router/internal/eval/regexcache"
+	"github.com/aescanero/dago-node-router/internal/flags"
+	"github.com/aescanero/dago-node-router/internal/llmmock"
+	"github.com/aescanero/dago-node-router/internal/llmsim"
+	"github.com/aescanero/dago-node-router/internal/logging"
+	"github.com/aescanero/dago-node-router/internal/ollama"
+	"github.com/aescanero/dago-node-router/internal/policies"
+	"github.com/aescanero/dago-node-router/internal/prompts"
+	"github.com/aescanero/dago-node-router/internal/resources"
 	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/aescanero/dago-node-router/internal/statestore"
 	"github.com/aescanero/dago-node-router/internal/worker"
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
 var (
@@ -29,6 +39,15 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "demo":
+			os.Exit(runDemo(os.Args[2:]))
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -37,7 +56,7 @@ func main() {
 	}
 
 	// Initialize logger
-	logger, err := initLogger(cfg.LogLevel)
+	logger, err := initLogger(cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
@@ -53,145 +72,324 @@ func main() {
 	// Log configuration (without sensitive data)
 	logger.Info("configuration loaded", zap.String("config", cfg.String()))
 
-	// Initialize Redis client
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPassword,
-		DB:       cfg.RedisDB,
+	a := &app{cfg: cfg, logger: logger}
+	a.init()
+	a.start()
+
+	// Wait for shutdown signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	logger.Info("router worker running, press Ctrl+C to stop")
+	<-sigChan
+
+	logger.Info("shutdown signal received, stopping worker")
+	a.shutdown()
+}
+
+// initLogger initializes the logger
+func initLogger(cfg *config.Config) (*zap.Logger, error) {
+	return logging.New(logging.Config{
+		Level:              cfg.LogLevel,
+		Encoding:           cfg.LogFormat,
+		SamplingInitial:    cfg.LogSamplingInitial,
+		SamplingThereafter: cfg.LogSamplingThereafter,
 	})
+}
 
-	// Test Redis connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := redisClient.Ping(ctx).Err(); err != nil {
-		logger.Fatal("failed to connect to redis", zap.Error(err))
-	}
-	logger.Info("connected to redis", zap.String("addr", cfg.RedisAddr))
+// initLLMClient initializes the LLM client using dago-adapters
+func initLLMClient(cfg *config.Config) (ports.LLMClient, error) {
+	logger, _ := zap.NewProduction()
+	return llm.NewClient(&llm.Config{
+		Provider: cfg.LLMProvider,
+		APIKey:   cfg.LLMAPIKey,
+		BaseURL:  cfg.LLMBaseURL,
+		Logger:   logger,
+	})
+}
 
-	// Initialize LLM client (optional for deterministic-only mode)
-	var llmClient ports.LLMClient
-	if cfg.LLMAPIKey != "" {
-		llmClient, err = initLLMClient(cfg)
-		if err != nil {
-			logger.Warn("failed to initialize llm client (llm routing will not be available)",
-				zap.Error(err),
-			)
-		} else {
-			logger.Info("llm client initialized",
-				zap.String("provider", cfg.LLMProvider),
-				zap.String("model", cfg.LLMModel),
-			)
-		}
-	} else {
-		logger.Warn("llm api key not provided (llm routing will not be available)")
+// warmupLocalLLM loads the model of a local inference server into memory so
+// the first routed message does not wait for it
+func warmupLocalLLM(cfg *config.Config, probe *ollama.Probe, logger *zap.Logger) {
+	if cfg.LLMWarmupTimeout == 0 {
+		return
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.LLMWarmupTimeout)
+	defer cancel()
 
-	// Initialize event bus (Redis Streams implementation)
-	eventBus := NewRedisEventBus(redisClient, logger)
-
-	// Initialize state store (Redis JSON implementation)
-	stateStore := NewRedisStateStore(redisClient, logger)
+	start := time.Now()
+	if err := probe.Warmup(ctx); err != nil {
+		logger.Warn("failed to warm up local llm model",
+			zap.String("base_url", cfg.LLMBaseURL),
+			zap.String("model", cfg.LLMModel),
+			zap.Error(err),
+		)
+		return
+	}
+	logger.Info("local llm model warmed up",
+		zap.String("model", cfg.LLMModel),
+		zap.Duration("duration", time.Since(start)),
+	)
+}
 
-	// Initialize router
-	routerInstance := router.NewRouter(llmClient, logger)
-	logger.Info("router initialized")
+// initSimulatedLLM builds the simulated LLM from LLM_SIMULATION_FILE
+func initSimulatedLLM(cfg *config.Config) (*llmsim.Client, error) {
+	simulation, err := llmsim.LoadConfig(cfg.LLMSimulationFile)
+	if err != nil {
+		return nil, err
+	}
+	return llmsim.NewClient(simulation), nil
+}
 
-	// Initialize worker
-	w := worker.NewWorker(cfg, redisClient, routerInstance, eventBus, stateStore, logger)
+// initChaos builds the fault injector from the CHAOS_ settings
+func initChaos(cfg *config.Config) *chaos.Injector {
+	return chaos.NewInjector(chaos.Config{
+		Seed:                cfg.ChaosSeed,
+		RedisReadErrorRate:  cfg.ChaosRedisErrorRate,
+		LLMErrorRate:        cfg.ChaosLLMErrorRate,
+		LLMLatency:          cfg.ChaosLLMLatency,
+		LLMLatencyRate:      cfg.ChaosLLMLatencyRate,
+		StateCorruptionRate: cfg.ChaosStateCorruptionRate,
+	})
+}
 
-	// Start worker
-	if err := w.Start(); err != nil {
-		logger.Fatal("failed to start worker", zap.Error(err))
+// initMockLLM builds the mock LLM from LLM_MOCK_FILE, followed by the routes
+// and responses of LLM_MOCK_ROUTES and LLM_MOCK_RESPONSES
+func initMockLLM(cfg *config.Config) (*llmmock.Client, error) {
+	var mock llmmock.Config
+	if cfg.LLMMockFile != "" {
+		var err error
+		if mock, err = llmmock.LoadConfig(cfg.LLMMockFile); err != nil {
+			return nil, err
+		}
+	}
+	routes, err := llmmock.ParseRoutes(cfg.LLMMockRoutes)
+	if err != nil {
+		return nil, err
 	}
+	mock.Routes = append(mock.Routes, routes...)
+	mock.Responses = append(mock.Responses, cfg.LLMMockResponses...)
+	if cfg.LLMMockDefault != "" {
+		mock.Default = cfg.LLMMockDefault
+	}
+	if err := mock.Validate(); err != nil {
+		return nil, err
+	}
+	return llmmock.NewClient(mock), nil
+}
 
-	// Start health server
-	healthServer := worker.NewHealthServer(cfg.HealthPort, redisClient, logger)
-	if err := healthServer.Start(); err != nil {
-		logger.Fatal("failed to start health server", zap.Error(err))
+// diagnosticsOptions registers the /diagnostics sections
+func diagnosticsOptions(cfg *config.Config, runtimeReport resources.Report, routerInstance *router.Router, w *worker.Worker, llmCache *cache.LRU[string], decisionCache *cache.LRU[router.RoutingResult], stateCache *statestore.Cached) []worker.HealthOption {
+	startedAt := time.Now().UTC()
+	opts := []worker.HealthOption{
+		worker.WithDiagnostics("version", func(context.Context) interface{} {
+			return map[string]interface{}{
+				"version":    Version,
+				"build_time": BuildTime,
+				"started_at": startedAt,
+				"uptime":     time.Since(startedAt).Round(time.Second).String(),
+			}
+		}),
+		worker.WithDiagnostics("config", func(context.Context) interface{} {
+			return cfg.Summary()
+		}),
+		worker.WithDiagnostics("llm_circuits", func(context.Context) interface{} {
+			return routerInstance.CircuitStates()
+		}),
+		worker.WithDiagnostics("llm_usage", func(context.Context) interface{} {
+			return routerInstance.Usage()
+		}),
+		worker.WithDiagnostics("worker", func(ctx context.Context) interface{} {
+			return w.Diagnostics(ctx)
+		}),
+		worker.WithDiagnostics("runtime", func(context.Context) interface{} {
+			return runtimeReport
+		}),
+		worker.WithDiagnostics("cel_cache", func(context.Context) interface{} {
+			return routerInstance.ExpressionCacheStats()
+		}),
+		worker.WithDiagnostics("regex_cache", func(context.Context) interface{} {
+			return regexcache.Stats()
+		}),
+	}
+	if llmCache != nil {
+		opts = append(opts, worker.WithDiagnostics("llm_cache", func(context.Context) interface{} {
+			return llmCache.Stats()
+		}))
+	}
+	if decisionCache != nil {
+		opts = append(opts, worker.WithDiagnostics("decision_cache", func(context.Context) interface{} {
+			return decisionCache.Stats()
+		}))
 	}
+	if stateCache != nil {
+		opts = append(opts, worker.WithDiagnostics("state_cache", func(context.Context) interface{} {
+			return stateCache.Stats()
+		}))
+	}
+	return opts
+}
 
-	// Wait for shutdown signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+// Estimated sizes of one entry of each in-memory cache, for CACHE_MEMORY_RATIO
+const (
+	llmCacheEntryBytes      = 2 << 10
+	decisionCacheEntryBytes = 1 << 10
+	stateCacheEntryBytes    = 16 << 10
+	lookupCacheEntryBytes   = 4 << 10
+)
 
-	logger.Info("router worker running, press Ctrl+C to stop")
-	<-sigChan
+// tuneRuntime adjusts GOMAXPROCS, the soft memory limit and the cache sizes
+// of cfg to the container limits
+func tuneRuntime(cfg *config.Config, logger *zap.Logger) resources.Report {
+	limits := resources.Detect()
+	report := resources.Tune(limits, resources.Config{
+		AutoMaxProcs:     cfg.AutoMaxProcs,
+		MemoryLimitRatio: cfg.MemoryLimitRatio,
+	})
 
-	logger.Info("shutdown signal received, stopping worker")
+	if cfg.CacheMemoryRatio > 0 && limits.Memory > 0 {
+		var caches []resources.Cache
+		if cfg.LLMCacheEnabled {
+			caches = append(caches, resources.Cache{Name: "llm_cache", Entries: &cfg.LLMCacheSize, EntryBytes: llmCacheEntryBytes})
+		}
+		if cfg.DecisionCacheEnabled {
+			caches = append(caches, resources.Cache{Name: "decision_cache", Entries: &cfg.DecisionCacheSize, EntryBytes: decisionCacheEntryBytes})
+		}
+		if cfg.StateCacheEnabled {
+			caches = append(caches, resources.Cache{Name: "state_cache", Entries: &cfg.StateCacheSize, EntryBytes: stateCacheEntryBytes})
+		}
+		if cfg.LookupsEnabled {
+			caches = append(caches, resources.Cache{Name: "lookup_cache", Entries: &cfg.LookupCacheSize, EntryBytes: lookupCacheEntryBytes})
+		}
+		report.Caches = resources.CapCaches(int64(cfg.CacheMemoryRatio*float64(limits.Memory)), caches)
+		for name, entries := range report.Caches {
+			logger.Warn("cache size capped to the memory budget",
+				zap.String("cache", name),
+				zap.Int("entries", entries),
+			)
+		}
+	}
 
-	// Graceful shutdown
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
+	logger.Info("runtime tuned to container limits",
+		zap.Float64("cpu_limit", limits.CPU),
+		zap.Int64("memory", limits.Memory),
+		zap.Bool("memory_limited", limits.MemoryLimited),
+		zap.Int("gomaxprocs", report.GOMAXPROCS),
+		zap.Int64("gomemlimit", report.GOMEMLIMIT),
+	)
+	return report
+}
 
-	// Stop health server
-	if err := healthServer.Stop(); err != nil {
-		logger.Error("failed to stop health server", zap.Error(err))
+// initLLMCache builds the LLM response cache, backed by Redis when configured.
+// The in-memory layer is also returned for its stats.
+func initLLMCache(cfg *config.Config, redisClient redis.UniversalClient, logger *zap.Logger) (cache.Cache, *cache.LRU[string]) {
+	memory := cache.NewLRU[string](cfg.LLMCacheSize, cfg.LLMCacheTTL)
+	if !cfg.LLMCacheRedis {
+		return memory, memory
 	}
+	return cache.NewTiered(memory, cache.NewRedis(redisClient, "router:llm-cache:", cfg.LLMCacheTTL, logger)), memory
+}
 
-	// Stop worker
-	if err := w.Stop(); err != nil {
-		logger.Error("failed to stop worker", zap.Error(err))
+// initFlagsProvider builds the feature flag provider selected by
+// FLAGS_PROVIDER
+func initFlagsProvider(cfg *config.Config, redisClient redis.UniversalClient) flags.Provider {
+	if cfg.FlagsProvider == config.FlagsProviderOFREP {
+		headers := map[string]string{}
+		if cfg.FlagsOFREPToken != "" {
+			headers["Authorization"] = "Bearer " + cfg.FlagsOFREPToken
+		}
+		return flags.NewOFREPProvider(cfg.FlagsOFREPURL, headers)
 	}
+	return flags.NewRedisProvider(redisClient, cfg.FlagsRedisKey)
+}
 
-	// Close Redis connection
-	if err := redisClient.Close(); err != nil {
-		logger.Error("failed to close redis connection", zap.Error(err))
+// initTemplateLibrary builds the prompt template library from its directory
+// or Redis hash
+func initTemplateLibrary(cfg *config.Config, redisClient redis.UniversalClient, logger *zap.Logger) *prompts.Library {
+	if cfg.TemplateLibraryDir != "" {
+		return prompts.NewLibrary(prompts.NewDirSource(cfg.TemplateLibraryDir), logger)
 	}
+	return prompts.NewLibrary(prompts.NewRedisSource(redisClient, cfg.TemplateLibraryRedisKey), logger)
+}
 
-	select {
-	case <-shutdownCtx.Done():
-		logger.Warn("shutdown timeout exceeded, forcing exit")
-	default:
-		logger.Info("worker stopped gracefully")
+// initRuleSets builds the rule set registry from its directory or Redis hash
+func initRuleSets(cfg *config.Config, redisClient redis.UniversalClient, logger *zap.Logger) *policies.Registry {
+	if cfg.RuleSetsDir != "" {
+		return policies.NewRegistry(policies.NewDirSource(cfg.RuleSetsDir), logger)
 	}
+	return policies.NewRegistry(policies.NewRedisSource(redisClient, cfg.RuleSetsRedisKey), logger)
 }
 
-// initLogger initializes the logger
-func initLogger(level string) (*zap.Logger, error) {
-	var zapLevel zapcore.Level
-	switch level {
-	case "debug":
-		zapLevel = zapcore.DebugLevel
-	case "info":
-		zapLevel = zapcore.InfoLevel
-	case "warn":
-		zapLevel = zapcore.WarnLevel
-	case "error":
-		zapLevel = zapcore.ErrorLevel
-	default:
-		zapLevel = zapcore.InfoLevel
+// initTenantQuotas builds the router tenant quotas from the TENANT_* defaults
+// and the per-tenant overrides of TENANT_QUOTAS_FILE
+func initTenantQuotas(cfg *config.Config) (router.TenantQuotaConfig, error) {
+	toRouter := func(q config.TenantQuota) router.TenantQuota {
+		return router.TenantQuota{
+			MaxConcurrent:  q.MaxConcurrent,
+			MaxRPS:         q.MaxRPS,
+			Burst:          q.Burst,
+			LLMTokenBudget: q.LLMTokenBudget,
+			BudgetWindow:   cfg.TenantBudgetWindow,
+		}
 	}
 
-	config := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zapLevel),
-		Development:      false,
-		Encoding:         "json",
-		EncoderConfig:    zap.NewProductionEncoderConfig(),
-		OutputPaths:      []string{"stdout"},
-		ErrorOutputPaths: []string{"stderr"},
+	quotas := router.TenantQuotaConfig{
+		Default: toRouter(cfg.DefaultTenantQuota()),
+		Tenants: make(map[string]router.TenantQuota),
+		MaxWait: cfg.TenantQuotaWait,
+	}
+	if cfg.TenantQuotasFile == "" {
+		return quotas, nil
 	}
 
-	return config.Build()
+	tenants, err := config.LoadTenantQuotas(cfg.TenantQuotasFile, cfg.DefaultTenantQuota())
+	if err != nil {
+		return quotas, err
+	}
+	for tenant, q := range tenants {
+		quotas.Tenants[tenant] = toRouter(q)
+	}
+	return quotas, nil
 }
 
-// initLLMClient initializes the LLM client using dago-adapters
-func initLLMClient(cfg *config.Config) (ports.LLMClient, error) {
+// initTenantLLMs initializes the LLM clients for each mapped tenant
+func initTenantLLMs(cfg *config.Config) (map[string]*router.LLMBinding, error) {
+	tenants, err := config.LoadTenantLLMs(cfg.TenantLLMFile)
+	if err != nil {
+		return nil, err
+	}
+
 	logger, _ := zap.NewProduction()
-	return llm.NewClient(&llm.Config{
-		Provider: cfg.LLMProvider,
-		APIKey:   cfg.LLMAPIKey,
-		Logger:   logger,
-	})
+	bindings := make(map[string]*router.LLMBinding, len(tenants))
+	for tenant, t := range tenants {
+		client, err := llm.NewClient(&llm.Config{
+			Provider: t.Provider,
+			APIKey:   t.APIKey,
+			BaseURL:  t.BaseURL,
+			Logger:   logger,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+		bindings[tenant] = &router.LLMBinding{
+			Provider: t.Provider,
+			BaseURL:  t.BaseURL,
+			Model:    t.Model,
+			Client:   client,
+		}
+	}
+
+	return bindings, nil
 }
 
 // RedisEventBus implements ports.EventBus using Redis Streams
 type RedisEventBus struct {
-	client *redis.Client
+	client redis.UniversalClient
 	logger *zap.Logger
 }
 
 // NewRedisEventBus creates a new Redis event bus
-func NewRedisEventBus(client *redis.Client, logger *zap.Logger) *RedisEventBus {
+func NewRedisEventBus(client redis.UniversalClient, logger *zap.Logger) *RedisEventBus {
 	return &RedisEventBus{
 		client: client,
 		logger: logger,
@@ -239,134 +437,3 @@ func (e *RedisEventBus) Unsubscribe(ctx context.Context, topic string) error {
 func (e *RedisEventBus) Close() error {
 	return nil
 }
-
-// RedisStateStore implements ports.StateStorage using Redis JSON
-type RedisStateStore struct {
-	client *redis.Client
-	logger *zap.Logger
-}
-
-// NewRedisStateStore creates a new Redis state store
-func NewRedisStateStore(client *redis.Client, logger *zap.Logger) *RedisStateStore {
-	return &RedisStateStore{
-		client: client,
-		logger: logger,
-	}
-}
-
-// Save saves graph state
-func (s *RedisStateStore) Save(ctx context.Context, executionID string, st state.State) error {
-	key := fmt.Sprintf("graph:state:%s", executionID)
-
-	// Marshal state to JSON
-	data, err := json.Marshal(st)
-	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
-	}
-
-	// Save to Redis
-	if err := s.client.Set(ctx, key, data, 0).Err(); err != nil {
-		return fmt.Errorf("failed to save state: %w", err)
-	}
-
-	return nil
-}
-
-// Load loads graph state
-func (s *RedisStateStore) Load(ctx context.Context, executionID string) (state.State, error) {
-	key := fmt.Sprintf("graph:state:%s", executionID)
-
-	// Get state from Redis
-	data, err := s.client.Get(ctx, key).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return nil, fmt.Errorf("state not found for execution %s", executionID)
-		}
-		return nil, fmt.Errorf("failed to load state: %w", err)
-	}
-
-	// Unmarshal JSON to state.State (which is map[string]interface{})
-	var st state.State
-	if err := json.Unmarshal([]byte(data), &st); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
-	}
-
-	return st, nil
-}
-
-// Delete deletes graph state
-func (s *RedisStateStore) Delete(ctx context.Context, executionID string) error {
-	key := fmt.Sprintf("graph:state:%s", executionID)
-
-	if err := s.client.Del(ctx, key).Err(); err != nil {
-		return fmt.Errorf("failed to delete state: %w", err)
-	}
-
-	return nil
-}
-
-// Exists checks if state exists for an execution
-func (s *RedisStateStore) Exists(ctx context.Context, executionID string) (bool, error) {
-	key := fmt.Sprintf("graph:state:%s", executionID)
-
-	result, err := s.client.Exists(ctx, key).Result()
-	if err != nil {
-		return false, fmt.Errorf("failed to check existence: %w", err)
-	}
-
-	return result > 0, nil
-}
-
-// SetTTL sets a time-to-live for state data
-func (s *RedisStateStore) SetTTL(ctx context.Context, executionID string, ttl time.Duration) error {
-	key := fmt.Sprintf("graph:state:%s", executionID)
-
-	if err := s.client.Expire(ctx, key, ttl).Err(); err != nil {
-		return fmt.Errorf("failed to set TTL: %w", err)
-	}
-
-	return nil
-}
-
-// List returns all execution IDs that have stored state
-func (s *RedisStateStore) List(ctx context.Context) ([]string, error) {
-	keys, err := s.client.Keys(ctx, "graph:state:*").Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to list keys: %w", err)
-	}
-
-	// Extract execution IDs from keys
-	executionIDs := make([]string, 0, len(keys))
-	prefix := "graph:state:"
-	for _, key := range keys {
-		if len(key) > len(prefix) {
-			executionIDs = append(executionIDs, key[len(prefix):])
-		}
-	}
-
-	return executionIDs, nil
-}
-
-// SaveState persists graph state (compatibility method)
-func (s *RedisStateStore) SaveState(ctx context.Context, st interface{}) error {
-	// Extract execution ID from state
-	stateMap, ok := st.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("expected map[string]interface{}, got %T", st)
-	}
-
-	executionID, ok := stateMap["graph_id"].(string)
-	if !ok {
-		executionID, ok = stateMap["execution_id"].(string)
-		if !ok {
-			return fmt.Errorf("state missing graph_id or execution_id field")
-		}
-	}
-
-	return s.Save(ctx, executionID, state.State(stateMap))
-}
-
-// GetState retrieves graph state (compatibility method)
-func (s *RedisStateStore) GetState(ctx context.Context, graphID string) (interface{}, error) {
-	return s.Load(ctx, graphID)
-}
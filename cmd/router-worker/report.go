@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/aescanero/dago-node-router/internal/redisclient"
+	"github.com/aescanero/dago-node-router/internal/report"
+)
+
+// runReport prints the A/B analysis report of experiment decisions and feedback
+func runReport(args []string) int {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	since := fs.Duration("since", 24*time.Hour, "time window to analyze")
+	format := fs.String("format", "table", "output format: table or json")
+	decisions := fs.String("decisions", cfg.ResultStream, "decision stream")
+	feedback := fs.String("feedback", cfg.FeedbackStream, "feedback stream")
+	experiment := fs.String("experiment", "", "only report decisions of this experiment ID")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	redisClient, err := redisclient.New(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to configure redis: %v\n", err)
+		return 1
+	}
+	defer redisClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	r, err := report.Build(ctx, redisClient, report.Options{
+		DecisionStream: *decisions,
+		FeedbackStream: *feedback,
+		Since:          *since,
+		Experiment:     *experiment,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to build report: %v\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "json":
+		err = r.WriteJSON(os.Stdout)
+	case "table":
+		err = r.WriteTable(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format %q\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
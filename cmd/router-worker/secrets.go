@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/aescanero/dago-node-router/internal/secrets"
+	"go.uber.org/zap"
+)
+
+// initVault builds the Vault client from the VAULT_* settings
+func initVault(cfg *config.Config, logger *zap.Logger) *secrets.Vault {
+	return secrets.NewVault(secrets.VaultConfig{
+		Addr:  cfg.VaultAddr,
+		Token: cfg.VaultToken,
+		Role:  cfg.VaultK8sRole,
+		Mount: cfg.VaultK8sMount,
+	}, logger)
+}
+
+// watchVaultLLMKey rebuilds the LLM client whenever the API key in Vault
+// rotates, until ctx is cancelled
+func watchVaultLLMKey(ctx context.Context, vault *secrets.Vault, cfg *config.Config, client *rotatingLLMClient, logger *zap.Logger) {
+	vault.Watch(ctx, cfg.VaultLLMKeyPath, cfg.VaultLLMKeyField, cfg.VaultRefreshInterval, cfg.LLMAPIKey, func(key string) {
+		rotated := *cfg
+		rotated.LLMAPIKey = key
+		next, err := initLLMClient(&rotated)
+		if err != nil {
+			logger.Error("failed to rebuild llm client with the rotated api key", zap.Error(err))
+			return
+		}
+		client.swap(next)
+		logger.Info("llm client rebuilt with the rotated api key")
+	})
+}
+
+// rotatingLLMClient forwards to an LLM client that can be replaced at
+// runtime, e.g. after its API key rotates
+type rotatingLLMClient struct {
+	current atomic.Pointer[ports.LLMClient]
+}
+
+// newRotatingLLMClient wraps an LLM client
+func newRotatingLLMClient(client ports.LLMClient) *rotatingLLMClient {
+	r := &rotatingLLMClient{}
+	r.swap(client)
+	return r
+}
+
+// swap replaces the client used by subsequent calls
+func (r *rotatingLLMClient) swap(client ports.LLMClient) {
+	r.current.Store(&client)
+}
+
+// client returns the current client
+func (r *rotatingLLMClient) client() ports.LLMClient {
+	return *r.current.Load()
+}
+
+// Complete forwards to the current client
+func (r *rotatingLLMClient) Complete(ctx context.Context, req ports.CompletionRequest) (*ports.CompletionResponse, error) {
+	return r.client().Complete(ctx, req)
+}
+
+// CompleteWithTools forwards to the current client
+func (r *rotatingLLMClient) CompleteWithTools(ctx context.Context, req ports.CompletionRequest, tools []ports.Tool) (*ports.CompletionResponse, error) {
+	return r.client().CompleteWithTools(ctx, req, tools)
+}
+
+// CompleteStructured forwards to the current client
+func (r *rotatingLLMClient) CompleteStructured(ctx context.Context, req ports.CompletionRequest, schema ports.JSONSchema) (*ports.StructuredResponse, error) {
+	return r.client().CompleteStructured(ctx, req, schema)
+}
+
+// GenerateCompletion forwards to the current client
+func (r *rotatingLLMClient) GenerateCompletion(ctx context.Context, req interface{}) (interface{}, error) {
+	return r.client().GenerateCompletion(ctx, req)
+}
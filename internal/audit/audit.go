@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/redis/go-redis/v9"
+)
+
+// Record explains one routing decision: the inputs it was made from, by
+// hash, and how the route was chosen
+type Record struct {
+	ExecutionID string `json:"execution_id"`
+	NodeID      string `json:"node_id,omitempty"`
+	WorkerID    string `json:"worker_id"`
+	// Tenant is the tenant the request was routed and accounted for
+	Tenant    string    `json:"tenant,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// StateHash and ConfigHash are SHA-256 digests of the canonical JSON of
+	// the state the router saw (after any state_paths projection) and of the
+	// node config
+	StateHash  string `json:"state_hash"`
+	ConfigHash string `json:"config_hash"`
+	TargetNode string `json:"target_node,omitempty"`
+	Mode       string `json:"mode,omitempty"`
+	PathTaken  string `json:"path_taken,omitempty"`
+	Reasoning  string `json:"reasoning,omitempty"`
+	// RuleIndex and Condition identify the matched rule for rule decisions
+	RuleIndex *int   `json:"rule_index,omitempty"`
+	Condition string `json:"condition,omitempty"`
+	// LLMResponse is the raw LLM answer for LLM decisions
+	LLMResponse string  `json:"llm_response,omitempty"`
+	PromptHash  string  `json:"prompt_hash,omitempty"`
+	Confidence  float64 `json:"confidence,omitempty"`
+	// Experiment and Variant identify the experiment arm that decided
+	Experiment string `json:"experiment,omitempty"`
+	Variant    string `json:"variant,omitempty"`
+	// Error is set instead of the decision fields when routing failed
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// NewRecord builds the audit record of a routing decision, or of a routing
+// failure when routeErr is set
+func NewRecord(executionID, nodeID, workerID, stateHash, configHash string, result *router.RoutingResult, routeErr error, latency time.Duration) *Record {
+	record := &Record{
+		ExecutionID: executionID,
+		NodeID:      nodeID,
+		WorkerID:    workerID,
+		Timestamp:   time.Now().UTC(),
+		StateHash:   stateHash,
+		ConfigHash:  configHash,
+		LatencyMS:   latency.Milliseconds(),
+	}
+	if routeErr != nil {
+		record.Error = routeErr.Error()
+		return record
+	}
+	if result != nil {
+		record.Tenant = result.Tenant
+		record.TargetNode = result.TargetNode
+		record.Mode = result.Mode
+		record.PathTaken = result.PathTaken
+		record.Reasoning = result.Reasoning
+		record.RuleIndex = result.RuleIndex
+		record.Condition = result.Condition
+		record.LLMResponse = result.LLMResponse
+		record.PromptHash = result.PromptHash
+		record.Confidence = result.Confidence
+		record.Experiment = result.Experiment
+		record.Variant = result.Variant
+	}
+	return record
+}
+
+// Hash returns the SHA-256 hex digest of the JSON encoding of v. Map keys are
+// encoded in sorted order, so equal states and configs hash equally.
+func Hash(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Log is an append-only store of audit records
+type Log interface {
+	// Append records a decision
+	Append(ctx context.Context, record *Record) error
+	// Query returns the records of an execution, oldest first
+	Query(ctx context.Context, executionID string) ([]*Record, error)
+	// Close releases the log
+	Close() error
+}
+
+// New creates the audit log selected by AUDIT_BACKEND
+func New(cfg *config.Config, redisClient redis.UniversalClient) (Log, error) {
+	switch cfg.AuditBackend {
+	case config.AuditBackendRedis:
+		return NewRedis(redisClient, cfg.AuditRedisPrefix, cfg.AuditRetention), nil
+	case config.AuditBackendFile:
+		return NewFile(cfg.AuditDir, cfg.AuditRetention)
+	default:
+		return nil, fmt.Errorf("unknown audit backend %q", cfg.AuditBackend)
+	}
+}
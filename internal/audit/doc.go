@@ -0,0 +1,26 @@
+// Package audit records every routing decision in an append-only log, so
+// compliance can explain why an execution took a route.
+//
+// Each Record holds hashes of the state and node config the router saw, the
+// matched rule index or raw LLM answer, the latency and the worker ID. Two
+// backends are selected with AUDIT_BACKEND:
+//
+//   - redis: one stream per execution, expiring AUDIT_RETENTION after its
+//     last record (the default)
+//   - file: JSON lines in one file per UTC day under AUDIT_DIR, files older
+//     than AUDIT_RETENTION are deleted
+//
+// Example usage:
+//
+//	auditLog, err := audit.New(cfg, redisClient)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer auditLog.Close()
+//
+//	record := audit.NewRecord(executionID, nodeID, cfg.WorkerID,
+//	    audit.Hash(state), audit.Hash(config), result, nil, latency)
+//	err = auditLog.Append(ctx, record)
+//
+//	records, err := auditLog.Query(ctx, executionID)
+package audit
@@ -0,0 +1,221 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File names are audit-<date>.jsonl, one file per UTC day
+const (
+	filePrefix     = "audit-"
+	fileSuffix     = ".jsonl"
+	fileDateLayout = "2006-01-02"
+)
+
+// maxRecordBytes bounds the length of one JSON line read back by Query
+const maxRecordBytes = 1 << 20
+
+// File appends audit records as JSON lines to one file per UTC day in a
+// directory. Each record is synced to disk before Append returns. Files older
+// than Retention are deleted when the day rolls over.
+type File struct {
+	dir       string
+	retention time.Duration
+
+	mu      sync.Mutex
+	current *os.File
+	day     string
+}
+
+// NewFile creates a file audit log in dir, creating the directory if needed.
+// A zero retention keeps files forever.
+func NewFile(dir string, retention time.Duration) (*File, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	l := &File{
+		dir:       dir,
+		retention: retention,
+	}
+	if err := l.prune(time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Append writes the record to the file of the current day
+func (l *File) Append(_ context.Context, record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.rotate(time.Now().UTC()); err != nil {
+		return err
+	}
+	if _, err := l.current.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to append audit record: %w", err)
+	}
+	if err := l.current.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit file: %w", err)
+	}
+
+	return nil
+}
+
+// Query scans the retained files for the records of an execution
+func (l *File) Query(ctx context.Context, executionID string) ([]*Record, error) {
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*Record{}
+	for _, name := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		found, err := scanFile(filepath.Join(l.dir, name), executionID)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, found...)
+	}
+
+	return records, nil
+}
+
+// Close closes the current file
+func (l *File) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.current == nil {
+		return nil
+	}
+	err := l.current.Close()
+	l.current = nil
+	return err
+}
+
+// rotate opens the file of the day of now, pruning old files when the day
+// changes; the caller must hold the lock
+func (l *File) rotate(now time.Time) error {
+	day := now.Format(fileDateLayout)
+	if l.current != nil && l.day == day {
+		return nil
+	}
+
+	if l.current != nil {
+		if err := l.current.Close(); err != nil {
+			return fmt.Errorf("failed to close audit file: %w", err)
+		}
+		l.current = nil
+		if err := l.prune(now); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(l.dir, filePrefix+day+fileSuffix)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	l.current = f
+	l.day = day
+
+	return nil
+}
+
+// prune deletes the files of days that ended more than Retention before now
+func (l *File) prune(now time.Time) error {
+	if l.retention <= 0 {
+		return nil
+	}
+
+	files, err := l.files()
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		day, err := time.Parse(fileDateLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			continue
+		}
+		if now.Sub(day.Add(24*time.Hour)) > l.retention {
+			if err := os.Remove(filepath.Join(l.dir, name)); err != nil {
+				return fmt.Errorf("failed to delete expired audit file: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// files lists the audit files in date order
+func (l *File) files() ([]string, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit files: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// scanFile returns the records of an execution in one file
+func scanFile(path, executionID string) ([]*Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Pruned since it was listed
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer f.Close()
+
+	var records []*Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordBytes)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			// A torn final line from a crash is skipped
+			continue
+		}
+		if record.ExecutionID == executionID {
+			records = append(records, &record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit file: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keeps the audit records of each execution in its own stream, which
+// expires Retention after the last record
+type Redis struct {
+	client    redis.UniversalClient
+	prefix    string
+	retention time.Duration
+}
+
+// NewRedis creates a Redis audit log with streams under prefix. A zero
+// retention keeps records forever.
+func NewRedis(client redis.UniversalClient, prefix string, retention time.Duration) *Redis {
+	return &Redis{
+		client:    client,
+		prefix:    prefix,
+		retention: retention,
+	}
+}
+
+// Append adds the record to the execution stream and extends its retention
+func (l *Redis) Append(ctx context.Context, record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	key := l.prefix + record.ExecutionID
+	pipe := l.client.TxPipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		Values: map[string]interface{}{"data": string(data)},
+	})
+	if l.retention > 0 {
+		pipe.Expire(ctx, key, l.retention)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append audit record: %w", err)
+	}
+
+	return nil
+}
+
+// Query reads the execution stream
+func (l *Redis) Query(ctx context.Context, executionID string) ([]*Record, error) {
+	messages, err := l.client.XRange(ctx, l.prefix+executionID, "-", "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit records: %w", err)
+	}
+
+	records := make([]*Record, 0, len(messages))
+	for _, message := range messages {
+		data, ok := message.Values["data"].(string)
+		if !ok {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit record %s: %w", message.ID, err)
+		}
+		records = append(records, &record)
+	}
+
+	return records, nil
+}
+
+// Close is a no-op; the Redis client is shared and closed by its owner
+func (l *Redis) Close() error {
+	return nil
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Cache stores LLM responses by key
+type Cache interface {
+	Get(ctx context.Context, key string) (string, bool)
+	Set(ctx context.Context, key, value string)
+}
+
+// Key returns the cache key of a prompt, hashed with the parts identifying
+// who sends it where (tenant, provider, endpoint, model), so responses are
+// never shared across them
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Tiered checks caches in order and back-fills faster layers on a hit
+type Tiered struct {
+	layers []Cache
+}
+
+// NewTiered creates a cache over the given layers, fastest first
+func NewTiered(layers ...Cache) *Tiered {
+	return &Tiered{layers: layers}
+}
+
+// Get returns the value from the first layer holding key
+func (t *Tiered) Get(ctx context.Context, key string) (string, bool) {
+	for i, layer := range t.layers {
+		if value, ok := layer.Get(ctx, key); ok {
+			for _, faster := range t.layers[:i] {
+				faster.Set(ctx, key, value)
+			}
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Set stores the value in every layer
+func (t *Tiered) Set(ctx context.Context, key, value string) {
+	for _, layer := range t.layers {
+		layer.Set(ctx, key, value)
+	}
+}
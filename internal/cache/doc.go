@@ -0,0 +1,18 @@
+// Package cache provides response caches for LLM routing classifications.
+//
+// Identical rendered prompts of one tenant sent to one provider, endpoint
+// and model reuse the previous LLM answer for a configurable TTL. An in-memory LRU serves hot entries; an optional Redis layer shares
+// entries between workers.
+//
+// Example usage:
+//
+//	memory := cache.NewLRU[string](1000, 10*time.Minute)
+//	shared := cache.NewRedis(redisClient, "router:llm-cache:", 10*time.Minute, logger)
+//	c := cache.NewTiered(memory, shared)
+//
+//	key := cache.Key(tenant, "anthropic", baseURL, "claude-sonnet-4-20250514", prompt)
+//	if response, ok := c.Get(ctx, key); ok {
+//	    return response, nil
+//	}
+//	c.Set(ctx, key, response)
+package cache
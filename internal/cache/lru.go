@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LRU is an in-memory least-recently-used cache with per-entry expiry. An
+// LRU[string] implements Cache.
+type LRU[V any] struct {
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+	mu       sync.Mutex
+
+	hits, misses atomic.Int64
+}
+
+// LRUStats reports the size and hit rate of an LRU cache
+type LRUStats struct {
+	Entries  int   `json:"entries"`
+	Capacity int   `json:"capacity"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+}
+
+// lruEntry is a cached value and its expiry time
+type lruEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// NewLRU creates an in-memory cache holding up to capacity entries for ttl
+func NewLRU[V any](capacity int, ttl time.Duration) *LRU[V] {
+	return &LRU[V]{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the cached value for key if present and not expired
+func (c *LRU[V]) Get(_ context.Context, key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return zero, false
+	}
+
+	entry := elem.Value.(*lruEntry[V])
+	if time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+		c.misses.Add(1)
+		return zero, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits.Add(1)
+	return entry.value, true
+}
+
+// Set stores value for key, evicting the least recently used entry when full
+func (c *LRU[V]) Set(_ context.Context, key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry[V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// Remove deletes the entry for key, if any
+func (c *LRU[V]) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Purge deletes all entries
+func (c *LRU[V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted
+func (c *LRU[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats returns the entry count and the hits and misses since creation
+func (c *LRU[V]) Stats() LRUStats {
+	return LRUStats{
+		Entries:  c.Len(),
+		Capacity: c.capacity,
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+	}
+}
+
+// remove deletes an entry; the caller must hold the lock
+func (c *LRU[V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry[V]).key)
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Redis is a cache shared between workers through Redis string keys
+type Redis struct {
+	client redis.Cmdable
+	prefix string
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+// NewRedis creates a Redis-backed cache storing keys under prefix for ttl
+func NewRedis(client redis.Cmdable, prefix string, ttl time.Duration, logger *zap.Logger) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// Get returns the cached value for key. Redis errors are logged and treated as misses.
+func (c *Redis) Get(ctx context.Context, key string) (string, bool) {
+	value, err := c.client.Get(ctx, c.prefix+key).Result()
+	if err != nil {
+		if err != redis.Nil {
+			c.logger.Warn("failed to read llm cache", zap.Error(err))
+		}
+		return "", false
+	}
+	return value, true
+}
+
+// Set stores value for key. Redis errors are logged and ignored.
+func (c *Redis) Set(ctx context.Context, key, value string) {
+	if err := c.client.Set(ctx, c.prefix+key, value, c.ttl).Err(); err != nil {
+		c.logger.Warn("failed to write llm cache", zap.Error(err))
+	}
+}
@@ -0,0 +1,88 @@
+package chaos
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+)
+
+// Faults injected by the chaos layer, as reported in metrics and stats
+const (
+	FaultRedisRead       = "redis_read"
+	FaultLLMError        = "llm_error"
+	FaultLLMLatency      = "llm_latency"
+	FaultStateCorruption = "state_corruption"
+)
+
+// Config sets the probability of each fault. Rates are between 0 and 1 (as
+// checked by the worker config); a zero rate disables the fault.
+type Config struct {
+	// Seed makes runs reproducible; 0 seeds from the clock
+	Seed int64
+	// RedisReadErrorRate fails Redis read commands with a transient error
+	RedisReadErrorRate float64
+	// LLMErrorRate fails LLM calls like a provider 5xx response
+	LLMErrorRate float64
+	// LLMLatency is added to the LLM calls selected by LLMLatencyRate
+	LLMLatency     time.Duration
+	LLMLatencyRate float64
+	// StateCorruptionRate returns loaded states without their inputs, like
+	// a truncated write
+	StateCorruptionRate float64
+}
+
+// Stats counts injected faults by fault
+type Stats map[string]int64
+
+// Injector decides which operations fail and wraps the worker dependencies
+type Injector struct {
+	config Config
+
+	rng   *rand.Rand
+	rngMu sync.Mutex
+
+	redisReads, llmErrors, llmDelays, corruptions atomic.Int64
+}
+
+// NewInjector creates an injector from a validated config
+func NewInjector(config Config) *Injector {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Injector{
+		config: config,
+		rng:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Stats returns the faults injected since the injector was created
+func (i *Injector) Stats() Stats {
+	return Stats{
+		FaultRedisRead:       i.redisReads.Load(),
+		FaultLLMError:        i.llmErrors.Load(),
+		FaultLLMLatency:      i.llmDelays.Load(),
+		FaultStateCorruption: i.corruptions.Load(),
+	}
+}
+
+// inject reports whether to inject fault with probability rate, counting it
+func (i *Injector) inject(fault string, rate float64, counter *atomic.Int64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	i.rngMu.Lock()
+	sample := i.rng.Float64()
+	i.rngMu.Unlock()
+	if sample >= rate {
+		return false
+	}
+
+	counter.Add(1)
+	metrics.ChaosFaults.WithLabelValues(fault).Inc()
+	return true
+}
@@ -0,0 +1,20 @@
+// Package chaos injects faults into the worker for staging tests.
+//
+// When CHAOS_ENABLED is set, the injector fails Redis read commands, delays
+// or fails LLM calls and corrupts loaded graph states at configurable rates,
+// so fallback routes, retries, circuit breakers and alerts can be verified
+// without breaking the real dependencies. Every injected fault is counted in
+// dago_router_chaos_faults_total. Never enable it in production.
+//
+// Example usage:
+//
+//	injector := chaos.NewInjector(chaos.Config{
+//	    RedisReadErrorRate: 0.05,
+//	    LLMErrorRate:       0.1,
+//	    LLMLatency:         2 * time.Second,
+//	    LLMLatencyRate:     0.2,
+//	})
+//	redisClient.AddHook(injector.RedisHook())
+//	llmClient = injector.LLMClient(llmClient)
+//	stateStore = injector.StateStore(stateStore)
+package chaos
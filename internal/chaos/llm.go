@@ -0,0 +1,77 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// ErrLLMInjected is the injected LLM failure, shaped like a provider 5xx
+var ErrLLMInjected = errors.New("chaos: injected llm error: 500 internal server error")
+
+// LLMClient wraps client so its calls are delayed and failed at the
+// configured rates. A nil client stays nil.
+func (i *Injector) LLMClient(client ports.LLMClient) ports.LLMClient {
+	if client == nil {
+		return nil
+	}
+	return &llmClient{LLMClient: client, injector: i}
+}
+
+// llmClient delays and fails the calls of an LLM client
+type llmClient struct {
+	ports.LLMClient
+	injector *Injector
+}
+
+// Complete injects faults before a completion
+func (c *llmClient) Complete(ctx context.Context, req ports.CompletionRequest) (*ports.CompletionResponse, error) {
+	if err := c.fault(ctx); err != nil {
+		return nil, err
+	}
+	return c.LLMClient.Complete(ctx, req)
+}
+
+// CompleteWithTools injects faults before a completion
+func (c *llmClient) CompleteWithTools(ctx context.Context, req ports.CompletionRequest, tools []ports.Tool) (*ports.CompletionResponse, error) {
+	if err := c.fault(ctx); err != nil {
+		return nil, err
+	}
+	return c.LLMClient.CompleteWithTools(ctx, req, tools)
+}
+
+// CompleteStructured injects faults before a structured completion
+func (c *llmClient) CompleteStructured(ctx context.Context, req ports.CompletionRequest, schema ports.JSONSchema) (*ports.StructuredResponse, error) {
+	if err := c.fault(ctx); err != nil {
+		return nil, err
+	}
+	return c.LLMClient.CompleteStructured(ctx, req, schema)
+}
+
+// GenerateCompletion injects faults before a generation
+func (c *llmClient) GenerateCompletion(ctx context.Context, req interface{}) (interface{}, error) {
+	if err := c.fault(ctx); err != nil {
+		return nil, err
+	}
+	return c.LLMClient.GenerateCompletion(ctx, req)
+}
+
+// fault waits for injected latency, then returns an injected error, if any
+func (c *llmClient) fault(ctx context.Context) error {
+	cfg := c.injector.config
+	if c.injector.inject(FaultLLMLatency, cfg.LLMLatencyRate, &c.injector.llmDelays) {
+		timer := time.NewTimer(cfg.LLMLatency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if c.injector.inject(FaultLLMError, cfg.LLMErrorRate, &c.injector.llmErrors) {
+		return ErrLLMInjected
+	}
+	return nil
+}
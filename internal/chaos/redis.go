@@ -0,0 +1,89 @@
+package chaos
+
+import (
+	"context"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// readCommands are the Redis commands failed by injected read errors: work
+// stream reads, claims and state, dedup and lookup reads
+var readCommands = map[string]bool{
+	"xread":      true,
+	"xreadgroup": true,
+	"xautoclaim": true,
+	"xclaim":     true,
+	"xrange":     true,
+	"xrevrange":  true,
+	"get":        true,
+	"mget":       true,
+	"hget":       true,
+	"hmget":      true,
+	"hgetall":    true,
+	"lrange":     true,
+	"exists":     true,
+}
+
+// RedisError is the injected Redis read error. It reads as a TRYAGAIN reply,
+// which the worker treats as transient.
+type RedisError struct{}
+
+// Error implements error
+func (RedisError) Error() string {
+	return "TRYAGAIN chaos: injected redis read error"
+}
+
+// RedisError marks the error as a Redis reply
+func (RedisError) RedisError() {}
+
+// RedisHook returns a go-redis hook failing read commands at the configured
+// rate. Pipelines fail as a whole when one of their commands is selected.
+func (i *Injector) RedisHook() redis.Hook {
+	return redisHook{injector: i}
+}
+
+// redisHook fails Redis read commands
+type redisHook struct {
+	injector *Injector
+}
+
+// DialHook leaves connections alone
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook fails selected read commands before they are sent
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if h.fail(cmd) {
+			cmd.SetErr(RedisError{})
+			return RedisError{}
+		}
+		return next(ctx, cmd)
+	}
+}
+
+// ProcessPipelineHook fails pipelines holding a selected read command
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if !h.fail(cmd) {
+				continue
+			}
+			for _, failed := range cmds {
+				failed.SetErr(RedisError{})
+			}
+			return RedisError{}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// fail reports whether to fail cmd
+func (h redisHook) fail(cmd redis.Cmder) bool {
+	return readCommands[cmd.Name()] &&
+		h.injector.inject(FaultRedisRead, h.injector.config.RedisReadErrorRate, &h.injector.redisReads)
+}
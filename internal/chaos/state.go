@@ -0,0 +1,58 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-node-router/internal/statestore"
+)
+
+// StateStore wraps store so loaded states are corrupted at the configured
+// rate
+func (i *Injector) StateStore(store statestore.Store) statestore.Store {
+	return &stateStore{Store: store, injector: i}
+}
+
+// stateStore corrupts the states loaded from a store
+type stateStore struct {
+	statestore.Store
+	injector *Injector
+}
+
+// Load loads a state, corrupting it when selected
+func (s *stateStore) Load(ctx context.Context, executionID string) (state.State, error) {
+	st, err := s.Store.Load(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.corrupt(st), nil
+}
+
+// LoadPaths loads the projection of a state, corrupting it when selected
+func (s *stateStore) LoadPaths(ctx context.Context, executionID string, paths []string) (state.State, error) {
+	st, err := statestore.LoadPaths(ctx, s.Store, executionID, paths)
+	if err != nil {
+		return nil, err
+	}
+	return s.corrupt(st), nil
+}
+
+// Update applies writes through the wrapped store; writes are not faulted
+func (s *stateStore) Update(ctx context.Context, executionID string, writes []statestore.StateWrite) error {
+	return statestore.Update(ctx, s.Store, executionID, writes)
+}
+
+// corrupt returns a copy of st without its inputs when selected. States may
+// be shared with a cache, so st itself is never modified.
+func (s *stateStore) corrupt(st state.State) state.State {
+	if !s.injector.inject(FaultStateCorruption, s.injector.config.StateCorruptionRate, &s.injector.corruptions) {
+		return st
+	}
+	corrupted := make(state.State, len(st))
+	for key, value := range st {
+		if key != "inputs" {
+			corrupted[key] = value
+		}
+	}
+	return corrupted
+}
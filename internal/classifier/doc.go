@@ -0,0 +1,32 @@
+// Package classifier runs small text classification models trained offline,
+// for routing stable categories at deterministic-like latency without LLM
+// calls.
+//
+// A Model is a bag-of-words multinomial logistic regression: text is
+// lowercased, split into words and word n-grams, weighted by term frequency
+// (times the inverse document frequency when the model has one),
+// L2-normalized and scored against one weight vector per label. Softmax
+// turns the scores into label probabilities.
+//
+// Models are JSON files, as exported from a scikit-learn TfidfVectorizer and
+// LogisticRegression pipeline:
+//
+//	{
+//	    "name": "ticket_triage",
+//	    "labels": ["billing", "technical"],
+//	    "ngrams": 2,
+//	    "vocabulary": {"refund": 0, "invoice": 1, "crash": 2, "login failed": 3},
+//	    "idf": [1.7, 2.1, 1.9, 2.4],
+//	    "weights": [[2.3, 1.8, -1.1, -0.7], [-2.3, -1.8, 1.1, 0.7]],
+//	    "bias": [0.1, -0.1]
+//	}
+//
+// Example usage:
+//
+//	models, err := classifier.LoadDir("/etc/dago/classifiers")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	prediction := models["ticket_triage"].Predict("I want a refund")
+//	fmt.Println(prediction.Label, prediction.Probability)
+package classifier
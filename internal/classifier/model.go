@@ -0,0 +1,197 @@
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Model is a bag-of-words logistic regression text classifier
+type Model struct {
+	// Name identifies the model in node configs; it defaults to the file
+	// name without extension
+	Name string `json:"name"`
+	// Labels are the classes the model predicts
+	Labels []string `json:"labels"`
+	// Ngrams is the longest word n-gram of the vocabulary (1 when unset)
+	Ngrams int `json:"ngrams,omitempty"`
+	// Vocabulary maps each word or space-separated n-gram to its feature
+	// index
+	Vocabulary map[string]int `json:"vocabulary"`
+	// IDF optionally weights each feature by its inverse document frequency
+	IDF []float64 `json:"idf,omitempty"`
+	// Weights holds one weight per feature for each label, in label order
+	Weights [][]float64 `json:"weights"`
+	// Bias holds the intercept of each label
+	Bias []float64 `json:"bias,omitempty"`
+}
+
+// Prediction is the most likely label of a text
+type Prediction struct {
+	Label       string
+	Probability float64
+	// Known is the number of words and n-grams of the text in the
+	// vocabulary; with none, the prediction only reflects the bias
+	Known int
+}
+
+// Load reads a model from a JSON file
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var model Model
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if model.Name == "" {
+		model.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := model.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &model, nil
+}
+
+// LoadDir reads every .json model of a directory, keyed by model name
+func LoadDir(dir string) (map[string]*Model, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	models := make(map[string]*Model, len(paths))
+	for _, path := range paths {
+		model, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := models[model.Name]; ok {
+			return nil, fmt.Errorf("%s: duplicate model name %s", path, model.Name)
+		}
+		models[model.Name] = model
+	}
+	return models, nil
+}
+
+// Validate checks that the dimensions of the model agree
+func (m *Model) Validate() error {
+	if len(m.Labels) < 2 {
+		return fmt.Errorf("model needs at least two labels")
+	}
+	if len(m.Vocabulary) == 0 {
+		return fmt.Errorf("model vocabulary is empty")
+	}
+	features := 0
+	for term, index := range m.Vocabulary {
+		if index < 0 {
+			return fmt.Errorf("vocabulary term %q has a negative index", term)
+		}
+		if index >= features {
+			features = index + 1
+		}
+	}
+	if len(m.Weights) != len(m.Labels) {
+		return fmt.Errorf("model has %d weight vectors for %d labels", len(m.Weights), len(m.Labels))
+	}
+	for i, weights := range m.Weights {
+		if len(weights) != features {
+			return fmt.Errorf("label %s has %d weights for %d features", m.Labels[i], len(weights), features)
+		}
+	}
+	if m.IDF != nil && len(m.IDF) != features {
+		return fmt.Errorf("model has %d idf values for %d features", len(m.IDF), features)
+	}
+	if m.Bias != nil && len(m.Bias) != len(m.Labels) {
+		return fmt.Errorf("model has %d biases for %d labels", len(m.Bias), len(m.Labels))
+	}
+	return nil
+}
+
+// HasLabel reports whether the model predicts label
+func (m *Model) HasLabel(label string) bool {
+	for _, l := range m.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// Predict returns the most likely label of text
+func (m *Model) Predict(text string) Prediction {
+	features, known := m.features(text)
+
+	scores := make([]float64, len(m.Labels))
+	for i, weights := range m.Weights {
+		if m.Bias != nil {
+			scores[i] = m.Bias[i]
+		}
+		for index, value := range features {
+			scores[i] += weights[index] * value
+		}
+	}
+
+	best, top := 0, scores[0]
+	for i, score := range scores {
+		if score > top {
+			best, top = i, score
+		}
+	}
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - top)
+	}
+
+	return Prediction{
+		Label:       m.Labels[best],
+		Probability: 1 / sum,
+		Known:       known,
+	}
+}
+
+// features returns the L2-normalized TF-IDF vector of text, sparse by feature
+// index, and the number of terms of text in the vocabulary
+func (m *Model) features(text string) (map[int]float64, int) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	ngrams := m.Ngrams
+	if ngrams < 1 {
+		ngrams = 1
+	}
+
+	features := make(map[int]float64)
+	known := 0
+	for n := 1; n <= ngrams; n++ {
+		for i := 0; i+n <= len(words); i++ {
+			if index, ok := m.Vocabulary[strings.Join(words[i:i+n], " ")]; ok {
+				features[index]++
+				known++
+			}
+		}
+	}
+
+	norm := 0.0
+	for index, value := range features {
+		if m.IDF != nil {
+			value *= m.IDF[index]
+			features[index] = value
+		}
+		norm += value * value
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for index := range features {
+			features[index] /= norm
+		}
+	}
+	return features, known
+}
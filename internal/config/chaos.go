@@ -0,0 +1,28 @@
+package config
+
+import "fmt"
+
+// validateChaos checks the fault injection rates
+func (c *Config) validateChaos() error {
+	rates := []struct {
+		name string
+		rate float64
+	}{
+		{"CHAOS_REDIS_ERROR_RATE", c.ChaosRedisErrorRate},
+		{"CHAOS_LLM_ERROR_RATE", c.ChaosLLMErrorRate},
+		{"CHAOS_LLM_LATENCY_RATE", c.ChaosLLMLatencyRate},
+		{"CHAOS_STATE_CORRUPTION_RATE", c.ChaosStateCorruptionRate},
+	}
+	for _, r := range rates {
+		if r.rate < 0 || r.rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", r.name)
+		}
+	}
+	if c.ChaosLLMLatency < 0 {
+		return fmt.Errorf("CHAOS_LLM_LATENCY must not be negative")
+	}
+	if c.ChaosLLMLatencyRate > 0 && c.ChaosLLMLatency == 0 {
+		return fmt.Errorf("CHAOS_LLM_LATENCY_RATE requires CHAOS_LLM_LATENCY")
+	}
+	return nil
+}
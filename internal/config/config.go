@@ -2,13 +2,60 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v10"
 )
 
+// Work transports selectable with WORK_TRANSPORT
+const (
+	WorkTransportRedisStreams = "redis-streams"
+	WorkTransportKafka        = "kafka"
+)
+
+// State backends selectable with STATE_BACKEND
+const (
+	StateBackendRedis    = "redis"
+	StateBackendPostgres = "postgres"
+	StateBackendEtcd     = "etcd"
+)
+
+// Audit log backends selectable with AUDIT_BACKEND
+const (
+	AuditBackendRedis = "redis"
+	AuditBackendFile  = "file"
+)
+
+// Redis deployment modes selectable with REDIS_MODE
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+// Feature flag providers selectable with FLAGS_PROVIDER
+const (
+	FlagsProviderRedis = "redis"
+	FlagsProviderOFREP = "ofrep"
+)
+
+// LLMProviderSimulated selects the simulated LLM used for load tests
+const LLMProviderSimulated = "simulated"
+
+// LLMProviderMock selects the deterministic mock LLM used for integration
+// tests and local development, which needs no API key
+const LLMProviderMock = "mock"
+
+// LLMProviderOllama selects a local Ollama server, which needs no API key
+const LLMProviderOllama = "ollama"
+
 // Config holds all configuration for the router worker
 type Config struct {
+	// ConfigFile is a YAML or TOML file providing settings; environment
+	// variables override its values
+	ConfigFile string `env:"CONFIG_FILE"`
+
 	// Worker configuration
 	WorkerID string `env:"WORKER_ID" envDefault:"router-1"`
 
@@ -16,37 +63,398 @@ type Config struct {
 	RedisAddr     string `env:"REDIS_ADDR" envDefault:"localhost:6379"`
 	RedisPassword string `env:"REDIS_PASS" envDefault:""`
 	RedisDB       int    `env:"REDIS_DB" envDefault:"0"`
+	// RedisPasswordFile holds the Redis password, e.g. a mounted secret
+	RedisPasswordFile string `env:"REDIS_PASS_FILE"`
+	RedisUsername     string `env:"REDIS_USERNAME"`
+
+	// RedisMode selects a single Redis server (REDIS_ADDR), a Sentinel
+	// managed master or a Redis Cluster (seeded from REDIS_ADDRS)
+	RedisMode             string   `env:"REDIS_MODE" envDefault:"standalone"`
+	RedisAddrs            []string `env:"REDIS_ADDRS" envSeparator:","`
+	RedisMasterName       string   `env:"REDIS_MASTER_NAME"`
+	RedisSentinelPassword string   `env:"REDIS_SENTINEL_PASS"`
+
+	// Redis TLS: client certificates are optional, and a custom CA replaces
+	// the system roots
+	RedisTLSEnabled    bool   `env:"REDIS_TLS_ENABLED" envDefault:"false"`
+	RedisTLSCAFile     string `env:"REDIS_TLS_CA_FILE"`
+	RedisTLSCertFile   string `env:"REDIS_TLS_CERT_FILE"`
+	RedisTLSKeyFile    string `env:"REDIS_TLS_KEY_FILE"`
+	RedisTLSServerName string `env:"REDIS_TLS_SERVER_NAME"`
+	RedisTLSSkipVerify bool   `env:"REDIS_TLS_SKIP_VERIFY" envDefault:"false"`
+
+	// Redis reconnection backoff
+	RedisBackoffMin time.Duration `env:"REDIS_BACKOFF_MIN" envDefault:"500ms"`
+	RedisBackoffMax time.Duration `env:"REDIS_BACKOFF_MAX" envDefault:"30s"`
+
+	// WorkTransport selects where work is read and decisions are published.
+	// Redis remains the state store with either transport.
+	WorkTransport string `env:"WORK_TRANSPORT" envDefault:"redis-streams"`
+
+	// KafkaBrokers are the bootstrap brokers of the Kafka transport. The
+	// stream names below are used as topic names and CONSUMER_GROUP as the
+	// Kafka consumer group.
+	KafkaBrokers []string `env:"KAFKA_BROKERS" envSeparator:"," envDefault:"localhost:9092"`
+
+	// StateBackend selects the graph state store; Redis is still used for
+	// the work streams
+	StateBackend string `env:"STATE_BACKEND" envDefault:"redis"`
+
+	// PostgreSQL state store (STATE_BACKEND=postgres)
+	StatePostgresDSN   string `env:"STATE_POSTGRES_DSN"`
+	StatePostgresTable string `env:"STATE_POSTGRES_TABLE" envDefault:"graph_states"`
+
+	// etcd state store (STATE_BACKEND=etcd)
+	StateEtcdEndpoints   []string      `env:"STATE_ETCD_ENDPOINTS" envSeparator:"," envDefault:"localhost:2379"`
+	StateEtcdPrefix      string        `env:"STATE_ETCD_PREFIX" envDefault:"/dago/graph-state/"`
+	StateEtcdDialTimeout time.Duration `env:"STATE_ETCD_DIAL_TIMEOUT" envDefault:"5s"`
+	StateEtcdUsername    string        `env:"STATE_ETCD_USERNAME"`
+	StateEtcdPassword    string        `env:"STATE_ETCD_PASSWORD"`
+
+	// Local state cache: recently loaded states are kept in memory for
+	// StateCacheTTL and dropped when an execution ID is published on
+	// StateCacheChannel
+	StateCacheEnabled bool          `env:"STATE_CACHE_ENABLED" envDefault:"false"`
+	StateCacheSize    int           `env:"STATE_CACHE_SIZE" envDefault:"1000"`
+	StateCacheTTL     time.Duration `env:"STATE_CACHE_TTL" envDefault:"5s"`
+	StateCacheChannel string        `env:"STATE_CACHE_CHANNEL" envDefault:"graph:state:invalidate"`
 
 	// Stream configuration
-	StreamKey      string `env:"STREAM_KEY" envDefault:"router.work"`
-	ConsumerGroup  string `env:"CONSUMER_GROUP" envDefault:"router-workers"`
-	ResultStream   string `env:"RESULT_STREAM" envDefault:"router.decided"`
-	BlockTime      time.Duration `env:"BLOCK_TIME" envDefault:"1s"`
-	MaxRetries     int    `env:"MAX_RETRIES" envDefault:"3"`
+	StreamKey     string        `env:"STREAM_KEY" envDefault:"router.work"`
+	ConsumerGroup string        `env:"CONSUMER_GROUP" envDefault:"router-workers"`
+	ResultStream  string        `env:"RESULT_STREAM" envDefault:"router.decided"`
+	BlockTime     time.Duration `env:"BLOCK_TIME" envDefault:"1s"`
+	MaxRetries    int           `env:"MAX_RETRIES" envDefault:"3"`
+	// RetryDelay is how long a request that failed with a retryable error
+	// stays pending before it is delivered again, up to MaxRetries deliveries
+	RetryDelay time.Duration `env:"RETRY_DELAY" envDefault:"5s"`
+	// RequestTimeout bounds the routing of each work request: state load,
+	// conditions, templates and LLM calls (0 disables)
+	RequestTimeout time.Duration `env:"REQUEST_TIMEOUT" envDefault:"60s"`
+	// MaxRequestBytes bounds the encoded size of each work request; larger
+	// requests are dead-lettered as invalid (0 disables)
+	MaxRequestBytes int `env:"MAX_REQUEST_BYTES" envDefault:"1048576"`
+
+	// Batch reading: up to BatchSize messages are read per XREADGROUP,
+	// handled by WorkerConcurrency goroutines and acked in one pipeline
+	BatchSize         int `env:"BATCH_SIZE" envDefault:"1"`
+	WorkerConcurrency int `env:"WORKER_CONCURRENCY" envDefault:"1"`
+
+	// Stream sharding: STREAM_SHARDS > 0 consumes STREAM_KEY.<n> shards,
+	// assigned statically (WORKER_SHARDS) or through Redis leases
+	StreamShards    int           `env:"STREAM_SHARDS" envDefault:"0"`
+	ShardAssignment string        `env:"SHARD_ASSIGNMENT" envDefault:"static"`
+	WorkerShards    []int         `env:"WORKER_SHARDS" envSeparator:","`
+	ShardLeaseTTL   time.Duration `env:"SHARD_LEASE_TTL" envDefault:"15s"`
+
+	// Priority lanes: PRIORITY_LANES consumes STREAM_KEY.<lane> streams,
+	// highest priority first, read in weighted order (see Lanes)
+	PriorityLanes []string `env:"PRIORITY_LANES" envSeparator:","`
+
+	// Autoscaling signal: consumer group lag is measured every
+	// LagReportInterval (0 disables) and, with LagEndpointEnabled, served
+	// under /lag for KEDA's metrics-api scaler
+	LagReportInterval  time.Duration `env:"LAG_REPORT_INTERVAL" envDefault:"15s"`
+	LagEndpointEnabled bool          `env:"LAG_ENDPOINT_ENABLED" envDefault:"false"`
+
+	// Decision publishing retries and dead letter stream
+	PublishBackoffMin time.Duration `env:"PUBLISH_BACKOFF_MIN" envDefault:"100ms"`
+	PublishBackoffMax time.Duration `env:"PUBLISH_BACKOFF_MAX" envDefault:"2s"`
+	DeadLetterStream  string        `env:"DEAD_LETTER_STREAM" envDefault:"router.work.dlq"`
+
+	// Idempotency: published outcomes are marked in Redis for IdempotencyTTL,
+	// keyed on execution, node and message ID, so redelivered messages do not
+	// publish duplicate decisions
+	IdempotencyEnabled bool          `env:"IDEMPOTENCY_ENABLED" envDefault:"true"`
+	IdempotencyTTL     time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+
+	// Loop guard: decisions published per execution are counted in Redis,
+	// expiring HopCountTTL after the last one, and executions that reached
+	// MaxHops are refused with a loop_detected error (0 disables)
+	MaxHops     int           `env:"MAX_HOPS" envDefault:"0"`
+	HopCountTTL time.Duration `env:"HOP_COUNT_TTL" envDefault:"24h"`
+
+	// Audit log: every routing decision is recorded for AuditRetention (0
+	// keeps records forever) and served under /audit on the health port
+	AuditEnabled     bool          `env:"AUDIT_ENABLED" envDefault:"false"`
+	AuditBackend     string        `env:"AUDIT_BACKEND" envDefault:"redis"`
+	AuditRedisPrefix string        `env:"AUDIT_REDIS_PREFIX" envDefault:"router:audit:"`
+	AuditDir         string        `env:"AUDIT_DIR" envDefault:"/var/lib/dago-router/audit"`
+	AuditRetention   time.Duration `env:"AUDIT_RETENTION" envDefault:"720h"`
+
+	// Decision batching: outcomes are pipelined in batches of up to
+	// PublishBatchSize XADDs, flushed at least every PublishBatchInterval
+	PublishBatchSize     int           `env:"PUBLISH_BATCH_SIZE" envDefault:"1"`
+	PublishBatchInterval time.Duration `env:"PUBLISH_BATCH_INTERVAL" envDefault:"5ms"`
+
+	// DecisionFields selects the fields of published decisions (see DecisionFieldSet)
+	DecisionFields []string `env:"DECISION_FIELDS" envSeparator:","`
+
+	// FeedbackStream holds outcome events joined with decisions by the report command
+	FeedbackStream string `env:"FEEDBACK_STREAM" envDefault:"router.feedback"`
+
+	// ExperimentStream receives a copy of every experiment decision; empty
+	// disables it
+	ExperimentStream string `env:"EXPERIMENT_STREAM" envDefault:"router.experiments"`
+
+	// Pending message reclaim configuration
+	ClaimEnabled   bool          `env:"CLAIM_ENABLED" envDefault:"true"`
+	ClaimInterval  time.Duration `env:"CLAIM_INTERVAL" envDefault:"30s"`
+	ClaimMinIdle   time.Duration `env:"CLAIM_MIN_IDLE" envDefault:"5m"`
+	ClaimBatchSize int64         `env:"CLAIM_BATCH_SIZE" envDefault:"100"`
 
 	// LLM configuration
-	LLMProvider string `env:"LLM_PROVIDER" envDefault:"anthropic"`
-	LLMAPIKey   string `env:"LLM_API_KEY"`
-	LLMModel    string `env:"LLM_MODEL" envDefault:"claude-sonnet-4-20250514"`
+	LLMProvider string        `env:"LLM_PROVIDER" envDefault:"anthropic"`
+	LLMAPIKey   string        `env:"LLM_API_KEY"`
+	LLMModel    string        `env:"LLM_MODEL" envDefault:"claude-sonnet-4-20250514"`
 	LLMTimeout  time.Duration `env:"LLM_TIMEOUT" envDefault:"30s"`
+	// LLMAPIKeyFile holds the LLM API key, e.g. a mounted secret
+	LLMAPIKeyFile string `env:"LLM_API_KEY_FILE"`
+	// LLMBaseURL is the address of a local inference server
+	// (LLM_PROVIDER=ollama), http://localhost:11434 when unset
+	LLMBaseURL string `env:"LLM_BASE_URL"`
+	// LLMWarmupTimeout bounds the startup request that loads the model of a
+	// local server into memory (0 skips the warm-up)
+	LLMWarmupTimeout time.Duration `env:"LLM_WARMUP_TIMEOUT" envDefault:"2m"`
+	// LLMMaxRoutes is the route count above which LLM classification degrades
+	LLMMaxRoutes int `env:"LLM_MAX_ROUTES" envDefault:"15"`
+	// LLMMaxPromptTokens is the estimated token budget of each prompt;
+	// larger prompts are trimmed (0 disables)
+	LLMMaxPromptTokens int `env:"LLM_MAX_PROMPT_TOKENS" envDefault:"0"`
+	// LLMStreaming streams label answers from providers that support it and
+	// stops generating once the route is determined
+	LLMStreaming bool `env:"LLM_STREAMING" envDefault:"false"`
+
+	// RuleReorderEnabled reorders the rules of node configs that set
+	// reorder_rules by how often each decides the target
+	RuleReorderEnabled bool `env:"RULE_REORDER_ENABLED" envDefault:"false"`
+	// RuleReorderSamples is the number of decisions between optimizations
+	RuleReorderSamples int `env:"RULE_REORDER_SAMPLES" envDefault:"1000"`
+
+	// Prompt injection guard applied to LLM configs without their own guard
+	PromptGuardStripControl   bool `env:"PROMPT_GUARD_STRIP_CONTROL" envDefault:"false"`
+	PromptGuardMaxValueLength int  `env:"PROMPT_GUARD_MAX_VALUE_LENGTH" envDefault:"0"`
+	PromptGuardDelimit        bool `env:"PROMPT_GUARD_DELIMIT" envDefault:"false"`
+	PromptGuardStrictAnswers  bool `env:"PROMPT_GUARD_STRICT_ANSWERS" envDefault:"false"`
+
+	// LLM circuit breaker configuration
+	LLMBreakerEnabled   bool          `env:"LLM_BREAKER_ENABLED" envDefault:"true"`
+	LLMBreakerThreshold int           `env:"LLM_BREAKER_THRESHOLD" envDefault:"5"`
+	LLMBreakerOpenTime  time.Duration `env:"LLM_BREAKER_OPEN_TIME" envDefault:"30s"`
+	LLMBreakerProbes    int           `env:"LLM_BREAKER_PROBES" envDefault:"1"`
+
+	// LLM rate limiting; zero limits are disabled. Calls over a limit wait up
+	// to LLMLimitWait, then take the fallback route.
+	LLMMaxRPS        float64       `env:"LLM_MAX_RPS" envDefault:"0"`
+	LLMBurst         int           `env:"LLM_BURST" envDefault:"0"`
+	LLMNodeMaxRPS    float64       `env:"LLM_NODE_MAX_RPS" envDefault:"0"`
+	LLMMaxConcurrent int           `env:"LLM_MAX_CONCURRENT" envDefault:"0"`
+	LLMLimitWait     time.Duration `env:"LLM_LIMIT_WAIT" envDefault:"1s"`
+
+	// LLM response cache configuration
+	LLMCacheEnabled bool          `env:"LLM_CACHE_ENABLED" envDefault:"false"`
+	LLMCacheSize    int           `env:"LLM_CACHE_SIZE" envDefault:"1000"`
+	LLMCacheTTL     time.Duration `env:"LLM_CACHE_TTL" envDefault:"10m"`
+	LLMCacheRedis   bool          `env:"LLM_CACHE_REDIS" envDefault:"false"`
+
+	// Deterministic decision cache configuration
+	DecisionCacheEnabled bool          `env:"DECISION_CACHE_ENABLED" envDefault:"false"`
+	DecisionCacheSize    int           `env:"DECISION_CACHE_SIZE" envDefault:"10000"`
+	DecisionCacheTTL     time.Duration `env:"DECISION_CACHE_TTL" envDefault:"5s"`
+
+	// Vault: the LLM API key is read from VaultLLMKeyPath at startup with
+	// a token or Kubernetes auth login, then re-read every
+	// VaultRefreshInterval while the token is renewed
+	VaultAddr            string        `env:"VAULT_ADDR"`
+	VaultToken           string        `env:"VAULT_TOKEN"`
+	VaultK8sRole         string        `env:"VAULT_K8S_ROLE"`
+	VaultK8sMount        string        `env:"VAULT_K8S_MOUNT" envDefault:"kubernetes"`
+	VaultLLMKeyPath      string        `env:"VAULT_LLM_KEY_PATH"`
+	VaultLLMKeyField     string        `env:"VAULT_LLM_KEY_FIELD" envDefault:"api_key"`
+	VaultRefreshInterval time.Duration `env:"VAULT_REFRESH_INTERVAL" envDefault:"5m"`
+
+	// Tenant configuration
+	TenantField   string `env:"TENANT_FIELD" envDefault:"tenant_id"`
+	TenantLLMFile string `env:"TENANT_LLM_FILE"`
+
+	// Tenant quotas; zero limits are disabled. The TENANT_* limits apply to
+	// each tenant separately; TenantQuotasFile overrides them per tenant.
+	// Requests over a limit wait up to TenantQuotaWait, then fail.
+	TenantMaxConcurrent  int           `env:"TENANT_MAX_CONCURRENT" envDefault:"0"`
+	TenantMaxRPS         float64       `env:"TENANT_MAX_RPS" envDefault:"0"`
+	TenantBurst          int           `env:"TENANT_BURST" envDefault:"0"`
+	TenantLLMTokenBudget int64         `env:"TENANT_LLM_TOKEN_BUDGET" envDefault:"0"`
+	TenantBudgetWindow   time.Duration `env:"TENANT_BUDGET_WINDOW" envDefault:"24h"`
+	TenantQuotaWait      time.Duration `env:"TENANT_QUOTA_WAIT" envDefault:"1s"`
+	TenantQuotasFile     string        `env:"TENANT_QUOTAS_FILE"`
+
+	// LLMSimulationFile configures the simulated provider used with
+	// LLM_PROVIDER=simulated for load tests
+	LLMSimulationFile string `env:"LLM_SIMULATION_FILE"`
+
+	// Mock LLM answers used with LLM_PROVIDER=mock: LLM_MOCK_FILE is a JSON
+	// mock config, LLM_MOCK_ROUTES keyword=response pairs checked in order,
+	// LLM_MOCK_RESPONSES canned answers returned in rotation when no route
+	// matches and LLM_MOCK_DEFAULT the answer when there are none (the last
+	// message is echoed when unset)
+	LLMMockFile      string   `env:"LLM_MOCK_FILE"`
+	LLMMockRoutes    []string `env:"LLM_MOCK_ROUTES" envSeparator:","`
+	LLMMockResponses []string `env:"LLM_MOCK_RESPONSES" envSeparator:","`
+	LLMMockDefault   string   `env:"LLM_MOCK_DEFAULT"`
+
+	// Fault injection for staging tests (never enable in production):
+	// Redis read errors, LLM errors and latency, and loaded states stripped
+	// of their inputs, each at a rate between 0 and 1
+	ChaosEnabled             bool          `env:"CHAOS_ENABLED" envDefault:"false"`
+	ChaosSeed                int64         `env:"CHAOS_SEED" envDefault:"0"`
+	ChaosRedisErrorRate      float64       `env:"CHAOS_REDIS_ERROR_RATE" envDefault:"0"`
+	ChaosLLMErrorRate        float64       `env:"CHAOS_LLM_ERROR_RATE" envDefault:"0"`
+	ChaosLLMLatency          time.Duration `env:"CHAOS_LLM_LATENCY" envDefault:"0"`
+	ChaosLLMLatencyRate      float64       `env:"CHAOS_LLM_LATENCY_RATE" envDefault:"0"`
+	ChaosStateCorruptionRate float64       `env:"CHAOS_STATE_CORRUPTION_RATE" envDefault:"0"`
+
+	// Stale configuration detection for sources loaded once at startup
+	StaleConfigMaxAge        time.Duration `env:"STALE_CONFIG_MAX_AGE" envDefault:"24h"`
+	StaleConfigCheckInterval time.Duration `env:"STALE_CONFIG_CHECK_INTERVAL" envDefault:"1m"`
+	StaleConfigTopic         string        `env:"STALE_CONFIG_TOPIC" envDefault:"router.events"`
 
 	// CEL configuration
 	CELEnabled bool `env:"CEL_ENABLED" envDefault:"true"`
+	// EvalTimeout and CELCostLimit bound each condition evaluation (0 disables)
+	EvalTimeout  time.Duration `env:"EVAL_TIMEOUT" envDefault:"100ms"`
+	CELCostLimit uint64        `env:"CEL_COST_LIMIT" envDefault:"1000000"`
+	// CELNumberMode presents whole JSON numbers to conditions as ints
+	// ("integral") or keeps every number a double ("double")
+	CELNumberMode string `env:"CEL_NUMBER_MODE" envDefault:"integral"`
+
+	// Node config lookups: each lookup is bounded by LookupTimeout and its
+	// result cached for LookupCacheTTL. HTTP lookups may only reach
+	// LookupAllowedHosts, when set.
+	LookupsEnabled     bool          `env:"LOOKUPS_ENABLED" envDefault:"false"`
+	LookupTimeout      time.Duration `env:"LOOKUP_TIMEOUT" envDefault:"2s"`
+	LookupCacheSize    int           `env:"LOOKUP_CACHE_SIZE" envDefault:"1000"`
+	LookupCacheTTL     time.Duration `env:"LOOKUP_CACHE_TTL" envDefault:"1m"`
+	LookupAllowedHosts []string      `env:"LOOKUP_ALLOWED_HOSTS" envSeparator:","`
+	LookupMaxBytes     int64         `env:"LOOKUP_MAX_BYTES" envDefault:"1048576"`
+
+	// Feature flags for the flag() CEL function and template helper, read
+	// from a Redis hash or an OpenFeature remote evaluation (OFREP) service
+	// and cached for FlagsCacheTTL; empty FlagsProvider disables them
+	FlagsProvider    string        `env:"FLAGS_PROVIDER"`
+	FlagsRedisKey    string        `env:"FLAGS_REDIS_KEY" envDefault:"router:flags"`
+	FlagsOFREPURL    string        `env:"FLAGS_OFREP_URL"`
+	FlagsOFREPToken  string        `env:"FLAGS_OFREP_TOKEN"`
+	FlagsCacheTTL    time.Duration `env:"FLAGS_CACHE_TTL" envDefault:"10s"`
+	FlagsEvalTimeout time.Duration `env:"FLAGS_EVAL_TIMEOUT" envDefault:"500ms"`
+
+	// Shadow configs are evaluated in the background, at most
+	// ShadowMaxInFlight at a time for up to ShadowTimeout each
+	ShadowMaxInFlight int           `env:"SHADOW_MAX_IN_FLIGHT" envDefault:"16"`
+	ShadowTimeout     time.Duration `env:"SHADOW_TIMEOUT" envDefault:"30s"`
+
+	// Template sandbox configuration for untrusted prompt templates
+	TemplateSandbox        bool     `env:"TEMPLATE_SANDBOX" envDefault:"false"`
+	TemplateAllowedHelpers []string `env:"TEMPLATE_ALLOWED_HELPERS" envSeparator:","`
+	TemplateDeniedPaths    []string `env:"TEMPLATE_DENIED_PATHS" envSeparator:","`
+	TemplateMaxDepth       int      `env:"TEMPLATE_MAX_DEPTH" envDefault:"4"`
+	TemplateMaxLoopItems   int      `env:"TEMPLATE_MAX_LOOP_ITEMS" envDefault:"100"`
+	TemplateMaxOutputBytes int      `env:"TEMPLATE_MAX_OUTPUT_BYTES" envDefault:"65536"`
+
+	// Prompt template library: templates referenced by template_ref are
+	// loaded from a directory or a Redis hash and reloaded every
+	// TemplateLibraryReloadInterval (0 disables reloading)
+	TemplateLibraryDir            string        `env:"TEMPLATE_LIBRARY_DIR"`
+	TemplateLibraryRedisKey       string        `env:"TEMPLATE_LIBRARY_REDIS_KEY"`
+	TemplateLibraryReloadInterval time.Duration `env:"TEMPLATE_LIBRARY_RELOAD_INTERVAL" envDefault:"30s"`
+
+	// Rule set registry: rule sets referenced by rules_ref and
+	// fast_rules_ref are loaded from a directory or a Redis hash and
+	// reloaded every RuleSetsReloadInterval (0 disables reloading)
+	RuleSetsDir            string        `env:"RULE_SETS_DIR"`
+	RuleSetsRedisKey       string        `env:"RULE_SETS_REDIS_KEY"`
+	RuleSetsReloadInterval time.Duration `env:"RULE_SETS_RELOAD_INTERVAL" envDefault:"30s"`
+
+	// ClassifierModelsDir holds the classifier mode models, one JSON file
+	// per model, loaded at startup
+	ClassifierModelsDir string `env:"CLASSIFIER_MODELS_DIR"`
+
+	// Embedding mode: inputs and route examples are embedded by the
+	// OpenAI-compatible server at EmbeddingBaseURL (empty disables the mode)
+	EmbeddingBaseURL string        `env:"EMBEDDING_BASE_URL"`
+	EmbeddingModel   string        `env:"EMBEDDING_MODEL" envDefault:"nomic-embed-text"`
+	EmbeddingAPIKey  string        `env:"EMBEDDING_API_KEY"`
+	EmbeddingTimeout time.Duration `env:"EMBEDDING_TIMEOUT" envDefault:"10s"`
+
+	// Tracing configuration
+	TracingEnabled   bool    `env:"TRACING_ENABLED" envDefault:"false"`
+	OTLPEndpoint     string  `env:"OTLP_ENDPOINT" envDefault:"localhost:4318"`
+	OTLPInsecure     bool    `env:"OTLP_INSECURE" envDefault:"true"`
+	TraceSampleRatio float64 `env:"TRACE_SAMPLE_RATIO" envDefault:"1.0"`
+
+	// ReadinessRequireLLM makes /ready fail while no LLM client is available
+	// or its circuit breaker is open, for deployments routing LLM-mode nodes
+	ReadinessRequireLLM bool `env:"READINESS_REQUIRE_LLM" envDefault:"false"`
 
 	// Health check configuration
-	HealthPort int `env:"HEALTH_PORT" envDefault:"8082"`
+	HealthPort   int    `env:"HEALTH_PORT" envDefault:"8082"`
+	HealthHost   string `env:"HEALTH_HOST" envDefault:""`
+	HealthSocket string `env:"HEALTH_SOCKET"`
+	// PprofEnabled serves the net/http/pprof profiles under /debug/pprof/ on
+	// the health server
+	PprofEnabled bool `env:"PPROF_ENABLED" envDefault:"false"`
+	// MetricsLabelLimit bounds the distinct tenant, node_id and rule metric
+	// label values; later values are reported as "other"
+	MetricsLabelLimit int `env:"METRICS_LABEL_LIMIT" envDefault:"100"`
+
+	// Runtime tuning to the container limits: GOMAXPROCS is lowered to the
+	// cgroup CPU limit, the soft memory limit set to MemoryLimitRatio of the
+	// memory and the in-memory caches capped to CacheMemoryRatio of it (0
+	// disables either). GOMAXPROCS and GOMEMLIMIT take precedence.
+	AutoMaxProcs     bool    `env:"AUTO_GOMAXPROCS" envDefault:"true"`
+	MemoryLimitRatio float64 `env:"MEMORY_LIMIT_RATIO" envDefault:"0"`
+	CacheMemoryRatio float64 `env:"CACHE_MEMORY_RATIO" envDefault:"0"`
+
+	// gRPC routing service, served alongside the Redis Streams worker
+	GRPCEnabled bool   `env:"GRPC_ENABLED" envDefault:"false"`
+	GRPCPort    int    `env:"GRPC_PORT" envDefault:"9090"`
+	GRPCHost    string `env:"GRPC_HOST" envDefault:""`
 
-	// Logging configuration
-	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+	// Logging configuration: level, json or console encoding, and sampling of
+	// identical lines (LOG_SAMPLING_INITIAL per second, then every
+	// LOG_SAMPLING_THEREAFTER-th; 0 disables sampling)
+	LogLevel              string `env:"LOG_LEVEL" envDefault:"info"`
+	LogFormat             string `env:"LOG_FORMAT" envDefault:"json"`
+	LogSamplingInitial    int    `env:"LOG_SAMPLING_INITIAL" envDefault:"0"`
+	LogSamplingThereafter int    `env:"LOG_SAMPLING_THEREAFTER" envDefault:"100"`
+
+	// Redaction: state fields (dotted paths) and built-in or custom patterns
+	// masked in debug logs, traces and audit records, and in LLM prompts
+	// when RedactPrompts is set. Custom regexes are separated by ";" since
+	// they may contain commas.
+	RedactFields   []string `env:"REDACT_FIELDS" envSeparator:","`
+	RedactPatterns []string `env:"REDACT_PATTERNS" envSeparator:","`
+	RedactRegexes  []string `env:"REDACT_REGEXES" envSeparator:";"`
+	RedactMask     string   `env:"REDACT_MASK" envDefault:"[REDACTED]"`
+	RedactPrompts  bool     `env:"REDACT_PROMPTS" envDefault:"false"`
 }
 
-// Load loads configuration from environment variables
+// Load loads configuration from environment variables, on top of the
+// settings of CONFIG_FILE when set
 func Load() (*Config, error) {
+	environment, err := environment()
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{}
-	if err := env.Parse(cfg); err != nil {
+	if err := env.ParseWithOptions(cfg, env.Options{Environment: environment}); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	if err := cfg.loadSecretFiles(); err != nil {
+		return nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
@@ -61,8 +469,16 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("WORKER_ID is required")
 	}
 
-	if c.RedisAddr == "" {
-		return fmt.Errorf("REDIS_ADDR is required")
+	if err := c.validateRedis(); err != nil {
+		return err
+	}
+
+	if err := c.validateTransport(); err != nil {
+		return err
+	}
+
+	if err := c.validateStateBackend(); err != nil {
+		return err
 	}
 
 	if c.StreamKey == "" {
@@ -81,9 +497,27 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("LLM_PROVIDER is required")
 	}
 
+	if c.LLMProvider == LLMProviderSimulated && c.LLMSimulationFile == "" {
+		return fmt.Errorf("LLM_SIMULATION_FILE is required with LLM_PROVIDER=%s", LLMProviderSimulated)
+	}
+
+	for _, route := range c.LLMMockRoutes {
+		if keyword, _, ok := strings.Cut(route, "="); !ok || strings.TrimSpace(keyword) == "" {
+			return fmt.Errorf("LLM_MOCK_ROUTES entry %q must be keyword=response", route)
+		}
+	}
+
+	if c.LLMWarmupTimeout < 0 {
+		return fmt.Errorf("LLM_WARMUP_TIMEOUT must not be negative")
+	}
+
 	// LLM_API_KEY is optional - only required when using LLM mode
 	// It will be validated at runtime if LLM routing is attempted
 
+	if err := c.validateVault(); err != nil {
+		return err
+	}
+
 	if c.LLMModel == "" {
 		return fmt.Errorf("LLM_MODEL is required")
 	}
@@ -92,22 +526,309 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("LLM_TIMEOUT must be positive")
 	}
 
+	if c.EmbeddingBaseURL != "" {
+		if c.EmbeddingModel == "" {
+			return fmt.Errorf("EMBEDDING_MODEL is required when EMBEDDING_BASE_URL is set")
+		}
+		if c.EmbeddingTimeout <= 0 {
+			return fmt.Errorf("EMBEDDING_TIMEOUT must be positive")
+		}
+	}
+
+	if c.LLMMaxPromptTokens < 0 {
+		return fmt.Errorf("LLM_MAX_PROMPT_TOKENS must not be negative")
+	}
+	if c.RuleReorderEnabled && c.RuleReorderSamples <= 0 {
+		return fmt.Errorf("RULE_REORDER_SAMPLES must be positive")
+	}
+
+	if c.PromptGuardMaxValueLength < 0 {
+		return fmt.Errorf("PROMPT_GUARD_MAX_VALUE_LENGTH must not be negative")
+	}
+
+	if c.LLMMaxRoutes <= 0 {
+		return fmt.Errorf("LLM_MAX_ROUTES must be positive")
+	}
+
+	if c.LLMBreakerEnabled {
+		if c.LLMBreakerThreshold <= 0 {
+			return fmt.Errorf("LLM_BREAKER_THRESHOLD must be positive")
+		}
+		if c.LLMBreakerOpenTime <= 0 {
+			return fmt.Errorf("LLM_BREAKER_OPEN_TIME must be positive")
+		}
+		if c.LLMBreakerProbes <= 0 {
+			return fmt.Errorf("LLM_BREAKER_PROBES must be positive")
+		}
+	}
+
+	if c.LLMMaxRPS < 0 || c.LLMNodeMaxRPS < 0 {
+		return fmt.Errorf("LLM_MAX_RPS and LLM_NODE_MAX_RPS must not be negative")
+	}
+	if c.LLMBurst < 0 {
+		return fmt.Errorf("LLM_BURST must not be negative")
+	}
+	if c.LLMMaxConcurrent < 0 {
+		return fmt.Errorf("LLM_MAX_CONCURRENT must not be negative")
+	}
+	if c.LLMLimitWait < 0 {
+		return fmt.Errorf("LLM_LIMIT_WAIT must not be negative")
+	}
+
+	if err := c.validateTenantQuotas(); err != nil {
+		return err
+	}
+
+	if c.LLMCacheEnabled {
+		if c.LLMCacheSize <= 0 {
+			return fmt.Errorf("LLM_CACHE_SIZE must be positive")
+		}
+		if c.LLMCacheTTL <= 0 {
+			return fmt.Errorf("LLM_CACHE_TTL must be positive")
+		}
+	}
+
+	if c.DecisionCacheEnabled {
+		if c.DecisionCacheSize <= 0 {
+			return fmt.Errorf("DECISION_CACHE_SIZE must be positive")
+		}
+		if c.DecisionCacheTTL <= 0 {
+			return fmt.Errorf("DECISION_CACHE_TTL must be positive")
+		}
+	}
+
+	if c.EvalTimeout < 0 {
+		return fmt.Errorf("EVAL_TIMEOUT must not be negative")
+	}
+
+	if c.CELNumberMode != "integral" && c.CELNumberMode != "double" {
+		return fmt.Errorf("CEL_NUMBER_MODE must be integral or double")
+	}
+
+	switch c.FlagsProvider {
+	case "":
+	case FlagsProviderRedis:
+		if c.FlagsRedisKey == "" {
+			return fmt.Errorf("FLAGS_REDIS_KEY is required with FLAGS_PROVIDER=redis")
+		}
+	case FlagsProviderOFREP:
+		if c.FlagsOFREPURL == "" {
+			return fmt.Errorf("FLAGS_OFREP_URL is required with FLAGS_PROVIDER=ofrep")
+		}
+	default:
+		return fmt.Errorf("FLAGS_PROVIDER must be redis or ofrep")
+	}
+	if c.FlagsProvider != "" && (c.FlagsCacheTTL <= 0 || c.FlagsEvalTimeout <= 0) {
+		return fmt.Errorf("FLAGS_CACHE_TTL and FLAGS_EVAL_TIMEOUT must be positive")
+	}
+
+	if c.LookupsEnabled {
+		if c.LookupTimeout <= 0 {
+			return fmt.Errorf("LOOKUP_TIMEOUT must be positive")
+		}
+		if c.LookupCacheSize <= 0 {
+			return fmt.Errorf("LOOKUP_CACHE_SIZE must be positive")
+		}
+		if c.LookupCacheTTL <= 0 {
+			return fmt.Errorf("LOOKUP_CACHE_TTL must be positive")
+		}
+		if c.LookupMaxBytes <= 0 {
+			return fmt.Errorf("LOOKUP_MAX_BYTES must be positive")
+		}
+	}
+
+	if c.ShadowMaxInFlight <= 0 {
+		return fmt.Errorf("SHADOW_MAX_IN_FLIGHT must be positive")
+	}
+	if c.ShadowTimeout <= 0 {
+		return fmt.Errorf("SHADOW_TIMEOUT must be positive")
+	}
+
+	if c.TemplateSandbox {
+		if c.TemplateMaxDepth < 0 {
+			return fmt.Errorf("TEMPLATE_MAX_DEPTH must not be negative")
+		}
+		if c.TemplateMaxLoopItems < 0 {
+			return fmt.Errorf("TEMPLATE_MAX_LOOP_ITEMS must not be negative")
+		}
+		if c.TemplateMaxOutputBytes < 0 {
+			return fmt.Errorf("TEMPLATE_MAX_OUTPUT_BYTES must not be negative")
+		}
+	}
+
+	if c.TemplateLibraryDir != "" && c.TemplateLibraryRedisKey != "" {
+		return fmt.Errorf("TEMPLATE_LIBRARY_DIR and TEMPLATE_LIBRARY_REDIS_KEY are mutually exclusive")
+	}
+	if c.TemplateLibraryReloadInterval < 0 {
+		return fmt.Errorf("TEMPLATE_LIBRARY_RELOAD_INTERVAL must not be negative")
+	}
+
+	if c.RuleSetsDir != "" && c.RuleSetsRedisKey != "" {
+		return fmt.Errorf("RULE_SETS_DIR and RULE_SETS_REDIS_KEY are mutually exclusive")
+	}
+	if c.RuleSetsReloadInterval < 0 {
+		return fmt.Errorf("RULE_SETS_RELOAD_INTERVAL must not be negative")
+	}
+
+	if c.StaleConfigMaxAge < 0 {
+		return fmt.Errorf("STALE_CONFIG_MAX_AGE must not be negative")
+	}
+
+	if c.StaleConfigCheckInterval <= 0 {
+		return fmt.Errorf("STALE_CONFIG_CHECK_INTERVAL must be positive")
+	}
+
+	if c.TenantLLMFile != "" && c.TenantField == "" {
+		return fmt.Errorf("TENANT_FIELD is required when TENANT_LLM_FILE is set")
+	}
+
 	if c.BlockTime <= 0 {
 		return fmt.Errorf("BLOCK_TIME must be positive")
 	}
 
+	if c.BatchSize < 1 {
+		return fmt.Errorf("BATCH_SIZE must be at least 1")
+	}
+
+	if c.WorkerConcurrency < 1 {
+		return fmt.Errorf("WORKER_CONCURRENCY must be at least 1")
+	}
+
+	if c.RedisBackoffMin <= 0 {
+		return fmt.Errorf("REDIS_BACKOFF_MIN must be positive")
+	}
+
+	if c.RedisBackoffMax < c.RedisBackoffMin {
+		return fmt.Errorf("REDIS_BACKOFF_MAX must be greater than or equal to REDIS_BACKOFF_MIN")
+	}
+
+	if c.ClaimEnabled {
+		if c.ClaimInterval <= 0 {
+			return fmt.Errorf("CLAIM_INTERVAL must be positive")
+		}
+		if c.ClaimMinIdle <= 0 {
+			return fmt.Errorf("CLAIM_MIN_IDLE must be positive")
+		}
+		if c.ClaimBatchSize <= 0 {
+			return fmt.Errorf("CLAIM_BATCH_SIZE must be positive")
+		}
+	}
+
 	if c.MaxRetries < 0 {
 		return fmt.Errorf("MAX_RETRIES must be non-negative")
 	}
+	if c.MaxRetries > 0 && c.RetryDelay < 10*time.Millisecond {
+		return fmt.Errorf("RETRY_DELAY must be at least 10ms")
+	}
+
+	if c.RequestTimeout < 0 {
+		return fmt.Errorf("REQUEST_TIMEOUT must be non-negative")
+	}
+
+	if c.MaxRequestBytes < 0 {
+		return fmt.Errorf("MAX_REQUEST_BYTES must be non-negative")
+	}
+
+	if err := c.validateSharding(); err != nil {
+		return err
+	}
+
+	if err := c.validateLanes(); err != nil {
+		return err
+	}
+
+	if err := c.validateChaos(); err != nil {
+		return err
+	}
+
+	if c.LagReportInterval < 0 {
+		return fmt.Errorf("LAG_REPORT_INTERVAL must be non-negative")
+	}
+	if c.LagEndpointEnabled && c.LagReportInterval == 0 {
+		return fmt.Errorf("LAG_ENDPOINT_ENABLED requires LAG_REPORT_INTERVAL")
+	}
+	if c.LagEndpointEnabled && c.WorkTransport != WorkTransportRedisStreams {
+		return fmt.Errorf("LAG_ENDPOINT_ENABLED requires WORK_TRANSPORT=%s", WorkTransportRedisStreams)
+	}
+
+	if _, err := c.DecisionFieldSet(); err != nil {
+		return fmt.Errorf("DECISION_FIELDS: %w", err)
+	}
+
+	if c.PublishBackoffMin <= 0 {
+		return fmt.Errorf("PUBLISH_BACKOFF_MIN must be positive")
+	}
+
+	if c.PublishBackoffMax < c.PublishBackoffMin {
+		return fmt.Errorf("PUBLISH_BACKOFF_MAX must be greater than or equal to PUBLISH_BACKOFF_MIN")
+	}
+
+	if c.IdempotencyEnabled && c.IdempotencyTTL <= 0 {
+		return fmt.Errorf("IDEMPOTENCY_TTL must be positive when IDEMPOTENCY_ENABLED is set")
+	}
+
+	if c.MaxHops < 0 {
+		return fmt.Errorf("MAX_HOPS must be non-negative")
+	}
+	if c.MaxHops > 0 && c.HopCountTTL <= 0 {
+		return fmt.Errorf("HOP_COUNT_TTL must be positive when MAX_HOPS is set")
+	}
+
+	if err := c.validateAudit(); err != nil {
+		return err
+	}
+
+	if c.PublishBatchSize < 1 {
+		return fmt.Errorf("PUBLISH_BATCH_SIZE must be at least 1")
+	}
+	if c.MetricsLabelLimit < 1 {
+		return fmt.Errorf("METRICS_LABEL_LIMIT must be at least 1")
+	}
+
+	if c.PublishBatchSize > 1 && c.PublishBatchInterval <= 0 {
+		return fmt.Errorf("PUBLISH_BATCH_INTERVAL must be positive when batching")
+	}
+
+	if c.TracingEnabled && c.OTLPEndpoint == "" {
+		return fmt.Errorf("OTLP_ENDPOINT is required when TRACING_ENABLED is set")
+	}
+
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		return fmt.Errorf("TRACE_SAMPLE_RATIO must be between 0 and 1")
+	}
 
 	if c.HealthPort <= 0 || c.HealthPort > 65535 {
 		return fmt.Errorf("HEALTH_PORT must be between 1 and 65535")
 	}
 
+	if c.MemoryLimitRatio < 0 || c.MemoryLimitRatio > 1 {
+		return fmt.Errorf("MEMORY_LIMIT_RATIO must be between 0 and 1")
+	}
+
+	if c.CacheMemoryRatio < 0 || c.CacheMemoryRatio > 1 {
+		return fmt.Errorf("CACHE_MEMORY_RATIO must be between 0 and 1")
+	}
+
+	if c.GRPCEnabled && (c.GRPCPort <= 0 || c.GRPCPort > 65535) {
+		return fmt.Errorf("GRPC_PORT must be between 1 and 65535")
+	}
+
 	if !isValidLogLevel(c.LogLevel) {
 		return fmt.Errorf("LOG_LEVEL must be one of: debug, info, warn, error")
 	}
 
+	if c.LogFormat != "json" && c.LogFormat != "console" {
+		return fmt.Errorf("LOG_FORMAT must be one of: json, console")
+	}
+
+	if c.LogSamplingInitial < 0 || c.LogSamplingThereafter < 0 {
+		return fmt.Errorf("LOG_SAMPLING_INITIAL and LOG_SAMPLING_THEREAFTER must be non-negative")
+	}
+
+	if c.RedactPrompts && !c.RedactionEnabled() {
+		return fmt.Errorf("REDACT_PROMPTS requires REDACT_FIELDS, REDACT_PATTERNS or REDACT_REGEXES")
+	}
+
 	return nil
 }
 
@@ -131,6 +852,164 @@ func (c *Config) RedisOptions() map[string]interface{} {
 	}
 }
 
+// validateRedis checks the Redis connection settings
+func (c *Config) validateRedis() error {
+	switch c.RedisMode {
+	case RedisModeStandalone:
+		if c.RedisAddr == "" {
+			return fmt.Errorf("REDIS_ADDR is required")
+		}
+	case RedisModeSentinel:
+		if len(c.RedisAddrs) == 0 {
+			return fmt.Errorf("REDIS_ADDRS (sentinel addresses) is required with REDIS_MODE=%s", RedisModeSentinel)
+		}
+		if c.RedisMasterName == "" {
+			return fmt.Errorf("REDIS_MASTER_NAME is required with REDIS_MODE=%s", RedisModeSentinel)
+		}
+	case RedisModeCluster:
+		if len(c.RedisAddrs) == 0 {
+			return fmt.Errorf("REDIS_ADDRS (cluster seed nodes) is required with REDIS_MODE=%s", RedisModeCluster)
+		}
+		if c.RedisDB != 0 {
+			return fmt.Errorf("REDIS_DB must be 0 with REDIS_MODE=%s", RedisModeCluster)
+		}
+	default:
+		return fmt.Errorf("REDIS_MODE must be %s, %s or %s, got %q",
+			RedisModeStandalone, RedisModeSentinel, RedisModeCluster, c.RedisMode)
+	}
+
+	if (c.RedisTLSCertFile == "") != (c.RedisTLSKeyFile == "") {
+		return fmt.Errorf("REDIS_TLS_CERT_FILE and REDIS_TLS_KEY_FILE must be set together")
+	}
+	if !c.RedisTLSEnabled && (c.RedisTLSCAFile != "" || c.RedisTLSCertFile != "" || c.RedisTLSServerName != "") {
+		return fmt.Errorf("REDIS_TLS_* files require REDIS_TLS_ENABLED")
+	}
+	return nil
+}
+
+// RedisEndpoint describes the configured Redis deployment for logs
+func (c *Config) RedisEndpoint() string {
+	switch c.RedisMode {
+	case RedisModeSentinel:
+		return fmt.Sprintf("sentinel %s via %s", c.RedisMasterName, strings.Join(c.RedisAddrs, ","))
+	case RedisModeCluster:
+		return fmt.Sprintf("cluster %s", strings.Join(c.RedisAddrs, ","))
+	default:
+		return c.RedisAddr
+	}
+}
+
+// validateTransport checks the work transport configuration
+func (c *Config) validateTransport() error {
+	switch c.WorkTransport {
+	case WorkTransportRedisStreams:
+	case WorkTransportKafka:
+		if len(c.KafkaBrokers) == 0 {
+			return fmt.Errorf("KAFKA_BROKERS is required with WORK_TRANSPORT=%s", WorkTransportKafka)
+		}
+		// Kafka partitions the work topic itself
+		if c.StreamShards > 0 {
+			return fmt.Errorf("STREAM_SHARDS is not supported with WORK_TRANSPORT=%s, partition the work topic instead", WorkTransportKafka)
+		}
+	default:
+		return fmt.Errorf("WORK_TRANSPORT must be %s or %s, got %q", WorkTransportRedisStreams, WorkTransportKafka, c.WorkTransport)
+	}
+
+	return nil
+}
+
+// validateStateBackend checks the settings of the built-in state backends.
+// Other names are left to the state store registry.
+func (c *Config) validateStateBackend() error {
+	switch c.StateBackend {
+	case "":
+		return fmt.Errorf("STATE_BACKEND is required")
+	case StateBackendPostgres:
+		if c.StatePostgresDSN == "" {
+			return fmt.Errorf("STATE_POSTGRES_DSN is required with STATE_BACKEND=%s", StateBackendPostgres)
+		}
+		if c.StatePostgresTable == "" {
+			return fmt.Errorf("STATE_POSTGRES_TABLE is required with STATE_BACKEND=%s", StateBackendPostgres)
+		}
+	case StateBackendEtcd:
+		if len(c.StateEtcdEndpoints) == 0 {
+			return fmt.Errorf("STATE_ETCD_ENDPOINTS is required with STATE_BACKEND=%s", StateBackendEtcd)
+		}
+		if c.StateEtcdDialTimeout <= 0 {
+			return fmt.Errorf("STATE_ETCD_DIAL_TIMEOUT must be positive")
+		}
+	}
+
+	if c.StateCacheEnabled {
+		if c.StateCacheSize <= 0 {
+			return fmt.Errorf("STATE_CACHE_SIZE must be positive")
+		}
+		if c.StateCacheTTL <= 0 {
+			return fmt.Errorf("STATE_CACHE_TTL must be positive")
+		}
+		if c.StateCacheChannel == "" {
+			return fmt.Errorf("STATE_CACHE_CHANNEL is required when STATE_CACHE_ENABLED is set")
+		}
+	}
+
+	return nil
+}
+
+// validateAudit checks the audit log settings
+func (c *Config) validateAudit() error {
+	if !c.AuditEnabled {
+		return nil
+	}
+	if c.AuditRetention < 0 {
+		return fmt.Errorf("AUDIT_RETENTION must be non-negative")
+	}
+
+	switch c.AuditBackend {
+	case AuditBackendRedis:
+		if c.AuditRedisPrefix == "" {
+			return fmt.Errorf("AUDIT_REDIS_PREFIX is required with AUDIT_BACKEND=%s", AuditBackendRedis)
+		}
+	case AuditBackendFile:
+		if c.AuditDir == "" {
+			return fmt.Errorf("AUDIT_DIR is required with AUDIT_BACKEND=%s", AuditBackendFile)
+		}
+	default:
+		return fmt.Errorf("AUDIT_BACKEND must be %s or %s, got %q", AuditBackendRedis, AuditBackendFile, c.AuditBackend)
+	}
+
+	return nil
+}
+
+// validateSharding checks the stream sharding configuration
+func (c *Config) validateSharding() error {
+	if c.StreamShards < 0 {
+		return fmt.Errorf("STREAM_SHARDS must be non-negative")
+	}
+	if c.StreamShards == 0 {
+		return nil
+	}
+
+	switch c.ShardAssignment {
+	case "static":
+		if len(c.WorkerShards) == 0 {
+			return fmt.Errorf("WORKER_SHARDS is required with static shard assignment")
+		}
+		for _, shard := range c.WorkerShards {
+			if shard < 0 || shard >= c.StreamShards {
+				return fmt.Errorf("WORKER_SHARDS entry %d is outside [0, %d)", shard, c.StreamShards)
+			}
+		}
+	case "redis":
+		if c.ShardLeaseTTL < time.Second {
+			return fmt.Errorf("SHARD_LEASE_TTL must be at least 1s")
+		}
+	default:
+		return fmt.Errorf("SHARD_ASSIGNMENT must be static or redis, got %q", c.ShardAssignment)
+	}
+
+	return nil
+}
+
 // LLMOptions returns LLM client options
 func (c *Config) LLMOptions() map[string]interface{} {
 	return map[string]interface{}{
@@ -141,6 +1020,107 @@ func (c *Config) LLMOptions() map[string]interface{} {
 	}
 }
 
+// LLMRateLimited reports whether any LLM rate or concurrency limit is set
+func (c *Config) LLMRateLimited() bool {
+	return c.LLMMaxRPS > 0 || c.LLMNodeMaxRPS > 0 || c.LLMMaxConcurrent > 0
+}
+
+// LLMLocal reports whether LLM calls go to a local inference server
+func (c *Config) LLMLocal() bool {
+	return c.LLMProvider == LLMProviderOllama || c.LLMProvider == "local"
+}
+
+// VaultEnabled reports whether the LLM API key is read from Vault
+func (c *Config) VaultEnabled() bool {
+	return c.VaultLLMKeyPath != ""
+}
+
+// TemplateLibraryEnabled reports whether a prompt template library source is set
+func (c *Config) TemplateLibraryEnabled() bool {
+	return c.TemplateLibraryDir != "" || c.TemplateLibraryRedisKey != ""
+}
+
+// PromptGuardEnabled reports whether any prompt guard setting is enabled
+func (c *Config) PromptGuardEnabled() bool {
+	return c.PromptGuardStripControl || c.PromptGuardMaxValueLength > 0 || c.PromptGuardDelimit || c.PromptGuardStrictAnswers
+}
+
+// RedactionEnabled reports whether any field or pattern is redacted
+func (c *Config) RedactionEnabled() bool {
+	return len(c.RedactFields) > 0 || len(c.RedactPatterns) > 0 || len(c.RedactRegexes) > 0
+}
+
+// RuleSetsEnabled reports whether a rule set registry source is set
+func (c *Config) RuleSetsEnabled() bool {
+	return c.RuleSetsDir != "" || c.RuleSetsRedisKey != ""
+}
+
+// Summary returns the settings an operator checks first during an incident,
+// without credentials
+func (c *Config) Summary() map[string]interface{} {
+	return map[string]interface{}{
+		"worker_id":          c.WorkerID,
+		"config_file":        c.ConfigFile,
+		"work_transport":     c.WorkTransport,
+		"redis_addr":         c.RedisEndpoint(),
+		"redis_tls":          c.RedisTLSEnabled,
+		"redis_db":           c.RedisDB,
+		"state_backend":      c.StateBackend,
+		"state_cache":        c.StateCacheEnabled,
+		"chaos":              c.ChaosEnabled,
+		"audit":              c.AuditEnabled,
+		"stream_key":         c.StreamKey,
+		"consumer_group":     c.ConsumerGroup,
+		"result_stream":      c.ResultStream,
+		"experiment_stream":  c.ExperimentStream,
+		"dead_letter_stream": c.DeadLetterStream,
+		"stream_shards":      c.StreamShards,
+		"priority_lanes":     c.PriorityLanes,
+		"lag_endpoint":       c.LagEndpointEnabled,
+		"batch_size":         c.BatchSize,
+		"worker_concurrency": c.WorkerConcurrency,
+		"max_retries":        c.MaxRetries,
+		"request_timeout":    c.RequestTimeout.String(),
+		"max_request_bytes":  c.MaxRequestBytes,
+		"max_hops":           c.MaxHops,
+		"llm_provider":       c.LLMProvider,
+		"llm_model":          c.LLMModel,
+		"llm_base_url":       c.LLMBaseURL,
+		"llm_timeout":        c.LLMTimeout.String(),
+		"llm_prompt_tokens":  c.LLMMaxPromptTokens,
+		"llm_streaming":      c.LLMStreaming,
+		"rule_reorder":       c.RuleReorderEnabled,
+		"llm_breaker":        c.LLMBreakerEnabled,
+		"llm_rate_limited":   c.LLMRateLimited(),
+		"llm_cache":          c.LLMCacheEnabled,
+		"decision_cache":     c.DecisionCacheEnabled,
+		"prompt_guard":       c.PromptGuardEnabled(),
+		"vault":              c.VaultEnabled(),
+		"tenant_llm_file":    c.TenantLLMFile,
+		"tenant_quotas":      c.TenantQuotasEnabled(),
+		"cel_enabled":        c.CELEnabled,
+		"eval_timeout":       c.EvalTimeout.String(),
+		"lookups":            c.LookupsEnabled,
+		"flags_provider":     c.FlagsProvider,
+		"template_sandbox":   c.TemplateSandbox,
+		"template_library":   c.TemplateLibraryEnabled(),
+		"rule_sets":          c.RuleSetsEnabled(),
+		"classifier_models":  c.ClassifierModelsDir,
+		"embedding_base_url": c.EmbeddingBaseURL,
+		"grpc_enabled":       c.GRPCEnabled,
+		"pprof":              c.PprofEnabled,
+		"auto_gomaxprocs":    c.AutoMaxProcs,
+		"memory_limit_ratio": c.MemoryLimitRatio,
+		"cache_memory_ratio": c.CacheMemoryRatio,
+		"tracing_enabled":    c.TracingEnabled,
+		"log_level":          c.LogLevel,
+		"log_format":         c.LogFormat,
+		"log_sampling":       c.LogSamplingInitial > 0,
+		"redaction":          c.RedactionEnabled(),
+		"redact_prompts":     c.RedactPrompts,
+	}
+}
+
 // String returns a string representation of the config (without sensitive data)
 func (c *Config) String() string {
 	return fmt.Sprintf(
@@ -3,6 +3,12 @@
 // Configuration is loaded from environment variables and validated on startup.
 // All configuration options have sensible defaults for development.
 //
+// Deployments with many settings can put them in a YAML or TOML file named by
+// CONFIG_FILE. File keys are the environment variable names, in any case,
+// and nested tables are joined with underscores, so [redis] addr sets
+// REDIS_ADDR. Non-empty environment variables override the file, and unknown
+// keys are rejected.
+//
 // Example usage:
 //
 //	cfg, err := config.Load()
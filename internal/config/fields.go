@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultDecisionFields are published when DECISION_FIELDS is not set.
+// "trace" controls the W3C trace context fields of the stream entry.
+var DefaultDecisionFields = []string{
+	"execution_id", "node_id", "target_node", "reasoning", "mode", "path_taken",
+	"timestamp", "processing_ms", "queue_wait_ms", "confidence", "stages",
+	"variant", "experiment_id", "tenant", "rule_index", "condition", "rule_sets", "pipeline_stage", "timings",
+	"worker_version", "trace",
+}
+
+// optionalDecisionFields are only published when requested
+var optionalDecisionFields = []string{"prompt_hash"}
+
+// requiredDecisionFields are always published so decisions can be correlated
+var requiredDecisionFields = []string{"execution_id", "node_id", "target_node"}
+
+// DecisionFieldSet resolves DECISION_FIELDS into the set of published fields.
+// A plain list replaces the defaults; entries prefixed with + or - add to or
+// remove from them (e.g. "-reasoning,-trace,+prompt_hash").
+func (c *Config) DecisionFieldSet() (map[string]bool, error) {
+	known := make(map[string]bool)
+	for _, field := range append(append([]string(nil), DefaultDecisionFields...), optionalDecisionFields...) {
+		known[field] = true
+	}
+
+	relative := len(c.DecisionFields) > 0
+	for _, entry := range c.DecisionFields {
+		if !strings.HasPrefix(entry, "+") && !strings.HasPrefix(entry, "-") {
+			relative = false
+		}
+	}
+
+	fields := make(map[string]bool)
+	if len(c.DecisionFields) == 0 || relative {
+		for _, field := range DefaultDecisionFields {
+			fields[field] = true
+		}
+	}
+
+	for _, entry := range c.DecisionFields {
+		entry = strings.TrimSpace(entry)
+		name := strings.TrimLeft(entry, "+-")
+		if !known[name] {
+			return nil, fmt.Errorf("unknown decision field %q", name)
+		}
+		if relative && strings.HasPrefix(entry, "-") {
+			delete(fields, name)
+			continue
+		}
+		fields[name] = true
+	}
+
+	for _, field := range requiredDecisionFields {
+		fields[field] = true
+	}
+
+	return fields, nil
+}
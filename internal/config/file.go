@@ -0,0 +1,178 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/caarlos0/env/v10"
+	"gopkg.in/yaml.v3"
+)
+
+// loadFileVars reads a YAML or TOML configuration file into environment
+// variable form. Keys are matched case-insensitively against the variable
+// names and nested tables are joined with underscores, so
+//
+//	redis:
+//	  addr: redis:6379
+//	llm_breaker_threshold: 5
+//
+// sets REDIS_ADDR and LLM_BREAKER_THRESHOLD. Lists are joined with commas.
+func loadFileVars(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	case ".toml":
+		if _, err := toml.Decode(string(data), &values); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q (use .yaml, .yml or .toml)", ext)
+	}
+
+	known, err := envKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+	if err := flattenFileValues("", values, vars); err != nil {
+		return nil, err
+	}
+
+	var unknown []string
+	for key := range vars {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown settings in config file: %s", strings.Join(unknown, ", "))
+	}
+
+	return vars, nil
+}
+
+// flattenFileValues converts nested file values into environment variables
+func flattenFileValues(prefix string, values map[string]interface{}, vars map[string]string) error {
+	for name, value := range values {
+		key := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flattenFileValues(key, v, vars); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				s, err := fileScalar(key, item)
+				if err != nil {
+					return err
+				}
+				items[i] = s
+			}
+			vars[key] = strings.Join(items, listSeparator(key))
+		default:
+			s, err := fileScalar(key, v)
+			if err != nil {
+				return err
+			}
+			vars[key] = s
+		}
+	}
+	return nil
+}
+
+// fileScalar formats a scalar file value the way it would be written in the
+// environment
+func fileScalar(key string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	default:
+		return "", fmt.Errorf("config file setting %s: unsupported value %v", key, value)
+	}
+}
+
+// envKeys returns the environment variable names of the Config fields
+func envKeys() (map[string]bool, error) {
+	params, err := env.GetFieldParams(&Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list config settings: %w", err)
+	}
+	keys := make(map[string]bool, len(params))
+	for _, p := range params {
+		keys[p.Key] = true
+	}
+	return keys, nil
+}
+
+// listSeparator returns the envSeparator of the list setting key, so file
+// lists of settings whose items may contain commas are joined correctly
+func listSeparator(key string) string {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("env") == key {
+			if sep := field.Tag.Get("envSeparator"); sep != "" {
+				return sep
+			}
+		}
+	}
+	return ","
+}
+
+// environment returns the process environment, overlaid on the settings of
+// the file named by CONFIG_FILE when set. Environment variables that are
+// empty do not override the file.
+func environment() (map[string]string, error) {
+	vars := make(map[string]string)
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		fileVars, err := loadFileVars(path)
+		if err != nil {
+			return nil, err
+		}
+		vars = fileVars
+	}
+
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if value != "" || vars[key] == "" {
+			vars[key] = value
+		}
+	}
+	return vars, nil
+}
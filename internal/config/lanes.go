@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PriorityLane is a work stream read ahead of lower priority lanes
+type PriorityLane struct {
+	// Name identifies the lane in metrics and logs (e.g. "high")
+	Name string
+	// Stream is the work stream of the lane, STREAM_KEY.<name>
+	Stream string
+	// Weight is the lane's share of reads while several lanes have work
+	Weight int
+}
+
+// Lanes resolves PRIORITY_LANES into priority lanes, highest priority first.
+// Entries are name:weight pairs (e.g. "high:5,normal:3,low:1"); the weight
+// defaults to 1. It returns nil when priority lanes are disabled.
+func (c *Config) Lanes() ([]PriorityLane, error) {
+	lanes := make([]PriorityLane, 0, len(c.PriorityLanes))
+	seen := make(map[string]bool)
+	for _, entry := range c.PriorityLanes {
+		name, rawWeight, hasWeight := strings.Cut(strings.TrimSpace(entry), ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("priority lane %q has no name", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate priority lane %q", name)
+		}
+		seen[name] = true
+
+		weight := 1
+		if hasWeight {
+			var err error
+			weight, err = strconv.Atoi(strings.TrimSpace(rawWeight))
+			if err != nil || weight < 1 {
+				return nil, fmt.Errorf("priority lane %q weight must be a positive integer", name)
+			}
+		}
+
+		lanes = append(lanes, PriorityLane{
+			Name:   name,
+			Stream: c.StreamKey + "." + name,
+			Weight: weight,
+		})
+	}
+
+	if len(lanes) == 0 {
+		return nil, nil
+	}
+	return lanes, nil
+}
+
+// validateLanes checks the priority lane configuration
+func (c *Config) validateLanes() error {
+	if len(c.PriorityLanes) == 0 {
+		return nil
+	}
+	if _, err := c.Lanes(); err != nil {
+		return fmt.Errorf("invalid PRIORITY_LANES: %w", err)
+	}
+	if c.WorkTransport != WorkTransportRedisStreams {
+		return fmt.Errorf("PRIORITY_LANES requires WORK_TRANSPORT=%s", WorkTransportRedisStreams)
+	}
+	if c.StreamShards > 0 {
+		return fmt.Errorf("PRIORITY_LANES cannot be combined with STREAM_SHARDS")
+	}
+	return nil
+}
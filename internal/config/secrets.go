@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadSecretFiles reads the secrets named by the *_FILE settings, such as
+// Kubernetes secret mounts. Trailing newlines are removed.
+func (c *Config) loadSecretFiles() error {
+	secrets := []struct {
+		name, fileName string
+		file           string
+		value          *string
+	}{
+		{"LLM_API_KEY", "LLM_API_KEY_FILE", c.LLMAPIKeyFile, &c.LLMAPIKey},
+		{"REDIS_PASS", "REDIS_PASS_FILE", c.RedisPasswordFile, &c.RedisPassword},
+	}
+
+	for _, s := range secrets {
+		if s.file == "" {
+			continue
+		}
+		if *s.value != "" {
+			return fmt.Errorf("set only one of %s and %s", s.name, s.fileName)
+		}
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", s.fileName, err)
+		}
+		*s.value = strings.TrimRight(string(data), "\r\n")
+	}
+	return nil
+}
+
+// validateVault checks the Vault settings
+func (c *Config) validateVault() error {
+	if !c.VaultEnabled() {
+		return nil
+	}
+	if c.VaultAddr == "" {
+		return fmt.Errorf("VAULT_ADDR is required with VAULT_LLM_KEY_PATH")
+	}
+	if c.VaultToken == "" && c.VaultK8sRole == "" {
+		return fmt.Errorf("VAULT_TOKEN or VAULT_K8S_ROLE is required with VAULT_LLM_KEY_PATH")
+	}
+	if c.VaultK8sRole != "" && c.VaultK8sMount == "" {
+		return fmt.Errorf("VAULT_K8S_MOUNT is required with VAULT_K8S_ROLE")
+	}
+	if c.VaultLLMKeyField == "" {
+		return fmt.Errorf("VAULT_LLM_KEY_FIELD is required with VAULT_LLM_KEY_PATH")
+	}
+	if c.LLMAPIKey != "" {
+		return fmt.Errorf("set only one of LLM_API_KEY (or LLM_API_KEY_FILE) and VAULT_LLM_KEY_PATH")
+	}
+	if c.VaultRefreshInterval < 0 {
+		return fmt.Errorf("VAULT_REFRESH_INTERVAL must be non-negative")
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// TenantLLM holds the LLM provider settings for a single tenant
+type TenantLLM struct {
+	Provider string `json:"provider"`
+	APIKey   string `json:"api_key,omitempty"`
+	// APIKeyEnv names an environment variable holding the API key
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+	Model     string `json:"model"`
+	BaseURL   string `json:"base_url,omitempty"`
+}
+
+// LoadTenantLLMs loads the tenant to LLM mapping from a JSON file.
+//
+// The file maps tenant identifiers to provider settings:
+//
+//	{
+//	    "tenant-a": {"provider": "openai", "api_key_env": "TENANT_A_KEY", "model": "gpt-4o"},
+//	    "tenant-b": {"provider": "ollama", "base_url": "http://ollama:11434", "model": "llama3.1"}
+//	}
+func LoadTenantLLMs(path string) (map[string]TenantLLM, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant llm file: %w", err)
+	}
+
+	var tenants map[string]TenantLLM
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("failed to parse tenant llm file: %w", err)
+	}
+
+	for tenant, t := range tenants {
+		if t.Provider == "" {
+			return nil, fmt.Errorf("tenant %s: provider is required", tenant)
+		}
+		if t.Model == "" {
+			return nil, fmt.Errorf("tenant %s: model is required", tenant)
+		}
+		if t.APIKeyEnv != "" {
+			t.APIKey = os.Getenv(t.APIKeyEnv)
+			tenants[tenant] = t
+		}
+	}
+
+	return tenants, nil
+}
+
+// TenantQuota holds the quota limits of a tenant; zero limits are disabled
+type TenantQuota struct {
+	MaxConcurrent  int     `json:"max_concurrent,omitempty"`
+	MaxRPS         float64 `json:"max_rps,omitempty"`
+	Burst          int     `json:"burst,omitempty"`
+	LLMTokenBudget int64   `json:"llm_token_budget,omitempty"`
+}
+
+// TenantQuotasEnabled reports whether any tenant quota is configured
+func (c *Config) TenantQuotasEnabled() bool {
+	return c.TenantQuotasFile != "" || c.DefaultTenantQuota() != TenantQuota{}
+}
+
+// DefaultTenantQuota returns the TENANT_* limits applied to each tenant
+// without its own entry in TENANT_QUOTAS_FILE
+func (c *Config) DefaultTenantQuota() TenantQuota {
+	return TenantQuota{
+		MaxConcurrent:  c.TenantMaxConcurrent,
+		MaxRPS:         c.TenantMaxRPS,
+		Burst:          c.TenantBurst,
+		LLMTokenBudget: c.TenantLLMTokenBudget,
+	}
+}
+
+// LoadTenantQuotas loads per-tenant quota overrides from a JSON file. Limits
+// an entry leaves unset (zero) are inherited from defaults.
+//
+// The file maps tenant identifiers to limits:
+//
+//	{
+//	    "tenant-a": {"max_concurrent": 20, "max_rps": 50},
+//	    "tenant-b": {"max_rps": 5, "llm_token_budget": 200000}
+//	}
+func LoadTenantQuotas(path string, defaults TenantQuota) (map[string]TenantQuota, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant quotas file: %w", err)
+	}
+
+	var tenants map[string]TenantQuota
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("failed to parse tenant quotas file: %w", err)
+	}
+
+	for tenant, q := range tenants {
+		if q.MaxConcurrent < 0 || q.MaxRPS < 0 || q.Burst < 0 || q.LLMTokenBudget < 0 {
+			return nil, fmt.Errorf("tenant %s: quota limits must not be negative", tenant)
+		}
+		if q.MaxConcurrent == 0 {
+			q.MaxConcurrent = defaults.MaxConcurrent
+		}
+		if q.MaxRPS == 0 {
+			q.MaxRPS = defaults.MaxRPS
+		}
+		if q.Burst == 0 {
+			q.Burst = defaults.Burst
+		}
+		if q.LLMTokenBudget == 0 {
+			q.LLMTokenBudget = defaults.LLMTokenBudget
+		}
+		tenants[tenant] = q
+	}
+
+	return tenants, nil
+}
+
+// validateTenantQuotas checks the tenant quota settings
+func (c *Config) validateTenantQuotas() error {
+	if c.TenantMaxConcurrent < 0 || c.TenantMaxRPS < 0 || c.TenantBurst < 0 || c.TenantLLMTokenBudget < 0 {
+		return fmt.Errorf("TENANT_MAX_CONCURRENT, TENANT_MAX_RPS, TENANT_BURST and TENANT_LLM_TOKEN_BUDGET must not be negative")
+	}
+	if c.TenantQuotaWait < 0 {
+		return fmt.Errorf("TENANT_QUOTA_WAIT must not be negative")
+	}
+	if c.TenantQuotasEnabled() && c.TenantBudgetWindow <= 0 {
+		return fmt.Errorf("TENANT_BUDGET_WINDOW must be positive")
+	}
+	return nil
+}
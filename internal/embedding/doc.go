@@ -0,0 +1,13 @@
+// Package embedding turns texts into embedding vectors for the embedding
+// routing mode.
+//
+// The Client calls the /embeddings endpoint of an OpenAI-compatible server,
+// which OpenAI, Ollama (under /v1) and vLLM all serve, embedding every text
+// of a call in one request. Cosine compares the vectors.
+//
+// Example usage:
+//
+//	client := embedding.NewClient("http://localhost:11434/v1", "nomic-embed-text")
+//	vectors, err := client.Embed(ctx, []string{"refund my order", "billing question"})
+//	similarity := embedding.Cosine(vectors[0], vectors[1])
+package embedding
@@ -0,0 +1,359 @@
+package cel
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/parser"
+)
+
+// assumedSize is the size cost estimates assume for state strings, lists and
+// maps, whose real size is only known at evaluation time
+const assumedSize = 1000
+
+// impureFunctions depend on the time or on feature flags, so expressions
+// calling them are never constant
+var impureFunctions = map[string]bool{
+	"now": true, "duration_since": true, "hour": true, "weekday": true,
+	"in_business_hours": true, "flag": true,
+}
+
+// CallsImpure reports whether an expression calls a function that depends on
+// the time or on feature flags, so it may evaluate differently for the same
+// variables. Expressions that do not parse report false; they never match.
+func (e *Evaluator) CallsImpure(expression string) bool {
+	if impure, ok := e.impure.Get(context.Background(), expression); ok {
+		return impure
+	}
+	impure := false
+	if parsed, issues := e.env.Parse(expression); issues == nil || issues.Err() == nil {
+		ast.PreOrderVisit(parsed.NativeRep().Expr(), ast.NewExprVisitor(func(expr ast.Expr) {
+			if expr.Kind() == ast.CallKind && impureFunctions[expr.AsCall().FunctionName()] {
+				impure = true
+			}
+		}))
+	}
+	e.impure.Set(context.Background(), expression, impure)
+	return impure
+}
+
+// References returns the variable paths an expression reads, each a variable
+// name followed by the field names and constant keys or indexes selected
+// from it, e.g. [state inputs priority] for state.inputs["priority"]. A path
+// stops where the next selection is computed, so it covers every value the
+// expression can read. Expressions that do not parse read nothing; they never
+// match.
+func (e *Evaluator) References(expression string) [][]string {
+	if paths, ok := e.references.Get(context.Background(), expression); ok {
+		return paths
+	}
+	var paths [][]string
+	if parsed, issues := e.env.Parse(expression); issues == nil || issues.Err() == nil {
+		paths = collectReferences(parsed.NativeRep().Expr(), nil)
+	}
+	e.references.Set(context.Background(), expression, paths)
+	return paths
+}
+
+// collectReferences appends the variable paths read by expr to paths
+func collectReferences(expr ast.Expr, paths [][]string) [][]string {
+	if path, ok := staticPath(expr); ok {
+		return append(paths, path)
+	}
+	switch expr.Kind() {
+	case ast.SelectKind:
+		return collectReferences(expr.AsSelect().Operand(), paths)
+	case ast.CallKind:
+		call := expr.AsCall()
+		if call.IsMemberFunction() {
+			paths = collectReferences(call.Target(), paths)
+		}
+		for _, arg := range call.Args() {
+			paths = collectReferences(arg, paths)
+		}
+	case ast.ListKind:
+		for _, element := range expr.AsList().Elements() {
+			paths = collectReferences(element, paths)
+		}
+	case ast.MapKind:
+		for _, entry := range expr.AsMap().Entries() {
+			paths = collectReferences(entry.AsMapEntry().Key(), paths)
+			paths = collectReferences(entry.AsMapEntry().Value(), paths)
+		}
+	case ast.StructKind:
+		for _, field := range expr.AsStruct().Fields() {
+			paths = collectReferences(field.AsStructField().Value(), paths)
+		}
+	case ast.ComprehensionKind:
+		comprehension := expr.AsComprehension()
+		for _, part := range []ast.Expr{
+			comprehension.IterRange(), comprehension.AccuInit(), comprehension.LoopCondition(),
+			comprehension.LoopStep(), comprehension.Result(),
+		} {
+			paths = collectReferences(part, paths)
+		}
+	}
+	return paths
+}
+
+// staticPath returns the variable path of an identifier followed by field
+// selections and constant indexes, or false for any other expression
+func staticPath(expr ast.Expr) ([]string, bool) {
+	switch expr.Kind() {
+	case ast.IdentKind:
+		return []string{expr.AsIdent()}, true
+	case ast.SelectKind:
+		path, ok := staticPath(expr.AsSelect().Operand())
+		if !ok {
+			return nil, false
+		}
+		return append(path, expr.AsSelect().FieldName()), true
+	case ast.CallKind:
+		call := expr.AsCall()
+		if call.FunctionName() != operators.Index || len(call.Args()) != 2 || call.Args()[1].Kind() != ast.LiteralKind {
+			return nil, false
+		}
+		var key string
+		switch value := call.Args()[1].AsLiteral().(type) {
+		case types.String:
+			key = string(value)
+		case types.Int:
+			key = strconv.FormatInt(int64(value), 10)
+		case types.Uint:
+			key = strconv.FormatUint(uint64(value), 10)
+		default:
+			return nil, false
+		}
+		path, ok := staticPath(call.Args()[0])
+		if !ok {
+			return nil, false
+		}
+		return append(path, key), true
+	}
+	return nil, false
+}
+
+// Analysis describes the complexity of an expression and what can be known
+// about its outcome without evaluating it
+type Analysis struct {
+	// Nodes is the number of nodes of the expression tree, macros expanded
+	Nodes int `json:"nodes"`
+	// CostMin and CostMax bound the estimated runtime cost, assuming state
+	// values of up to 1000 elements (CostMax is the maximum uint64 when the
+	// cost is unbounded)
+	CostMin uint64 `json:"cost_min"`
+	CostMax uint64 `json:"cost_max"`
+	// OverCostLimit is set when CostMax exceeds the cost limit of the
+	// evaluator
+	OverCostLimit bool `json:"over_cost_limit,omitempty"`
+	// Constant is the outcome of a boolean expression that does not depend
+	// on state, context or time, nil otherwise
+	Constant *bool `json:"constant,omitempty"`
+
+	// disjuncts are the top-level || terms of the expression, each a set of
+	// its top-level && terms in normalized form
+	disjuncts []map[string]bool
+}
+
+// Implies reports whether every state matching the expression of a also
+// matches the expression of other, judged by their top-level terms: each ||
+// term of a must contain every && term of some || term of other. It does not
+// look inside the terms, so it may miss implications but never reports a
+// false one.
+func (a *Analysis) Implies(other *Analysis) bool {
+	if len(a.disjuncts) == 0 || len(other.disjuncts) == 0 {
+		return false
+	}
+	for _, terms := range a.disjuncts {
+		implied := false
+		for _, otherTerms := range other.disjuncts {
+			if containsAll(terms, otherTerms) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAll reports whether set holds every key of subset
+func containsAll(set, subset map[string]bool) bool {
+	for key := range subset {
+		if !set[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// Analyze reports the complexity of an expression that may use the declared
+// variables and, for boolean expressions, whether it is constant
+func (e *Evaluator) Analyze(declared Declarations, expression string) (*Analysis, error) {
+	env, err := e.declaredEnv(declared)
+	if err != nil {
+		return nil, err
+	}
+	// Macro calls are tracked so terms using them can be unparsed
+	env, err = env.Extend(cel.EnableMacroCallTracking())
+	if err != nil {
+		return nil, err
+	}
+
+	checked, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	native := checked.NativeRep()
+
+	analysis := &Analysis{}
+	ast.PostOrderVisit(native.Expr(), ast.NewExprVisitor(func(ast.Expr) {
+		analysis.Nodes++
+	}))
+
+	cost, err := env.EstimateCost(checked, sizeEstimator{})
+	if err != nil {
+		return nil, err
+	}
+	analysis.CostMin, analysis.CostMax = cost.Min, cost.Max
+	analysis.OverCostLimit = e.limits.CostLimit > 0 && cost.Max > e.limits.CostLimit
+
+	if checked.OutputType().IsExactType(cel.BoolType) {
+		analysis.Constant = constantBool(env, native.Expr(), native.SourceInfo())
+	}
+	analysis.disjuncts = disjuncts(native.Expr(), native.SourceInfo())
+	return analysis, nil
+}
+
+// sizeEstimator assumes state values hold up to assumedSize elements and
+// leaves call costs to the defaults
+type sizeEstimator struct{}
+
+// EstimateSize implements checker.CostEstimator
+func (sizeEstimator) EstimateSize(checker.AstNode) *checker.SizeEstimate {
+	return &checker.SizeEstimate{Min: 0, Max: assumedSize}
+}
+
+// EstimateCallCost implements checker.CostEstimator
+func (sizeEstimator) EstimateCallCost(string, string, *checker.AstNode, []checker.AstNode) *checker.CallEstimate {
+	return nil
+}
+
+// constantBool returns the outcome of a boolean expression that does not
+// depend on its variables, following || and && so that a constant term
+// decides the whole expression, or nil
+func constantBool(env *cel.Env, expr ast.Expr, info *ast.SourceInfo) *bool {
+	if expr.Kind() == ast.CallKind {
+		call := expr.AsCall()
+		switch call.FunctionName() {
+		case operators.LogicalOr, operators.LogicalAnd:
+			// A true term decides ||, a false term decides &&
+			deciding := call.FunctionName() == operators.LogicalOr
+			allConstant := true
+			for _, arg := range call.Args() {
+				value := constantBool(env, arg, info)
+				if value != nil && *value == deciding {
+					return value
+				}
+				allConstant = allConstant && value != nil
+			}
+			if allConstant {
+				result := !deciding
+				return &result
+			}
+			return nil
+		case operators.LogicalNot:
+			if value := constantBool(env, call.Args()[0], info); value != nil {
+				negated := !*value
+				return &negated
+			}
+			return nil
+		}
+	}
+
+	if !isConstant(expr) {
+		return nil
+	}
+	source, err := parser.Unparse(expr, info)
+	if err != nil {
+		return nil
+	}
+	checked, issues := env.Compile(source)
+	if issues != nil && issues.Err() != nil {
+		return nil
+	}
+	program, err := env.Program(checked)
+	if err != nil {
+		return nil
+	}
+	out, _, err := program.Eval(cel.NoVars())
+	if err != nil {
+		return nil
+	}
+	if value, ok := out.(types.Bool); ok {
+		result := bool(value)
+		return &result
+	}
+	return nil
+}
+
+// isConstant reports whether expr reads no variables and calls no impure
+// functions
+func isConstant(expr ast.Expr) bool {
+	local := make(map[string]bool)
+	constant := true
+	ast.PreOrderVisit(expr, ast.NewExprVisitor(func(e ast.Expr) {
+		switch e.Kind() {
+		case ast.ComprehensionKind:
+			local[e.AsComprehension().IterVar()] = true
+			local[e.AsComprehension().AccuVar()] = true
+		case ast.IdentKind:
+			if !local[e.AsIdent()] {
+				constant = false
+			}
+		case ast.CallKind:
+			if impureFunctions[e.AsCall().FunctionName()] {
+				constant = false
+			}
+		}
+	}))
+	return constant
+}
+
+// disjuncts splits an expression into its top-level || terms, each split
+// into its top-level && terms in normalized form
+func disjuncts(expr ast.Expr, info *ast.SourceInfo) []map[string]bool {
+	var result []map[string]bool
+	for _, term := range splitCall(expr, operators.LogicalOr) {
+		terms := make(map[string]bool)
+		for _, conjunct := range splitCall(term, operators.LogicalAnd) {
+			source, err := parser.Unparse(conjunct, info)
+			if err != nil {
+				return nil
+			}
+			terms[source] = true
+		}
+		result = append(result, terms)
+	}
+	return result
+}
+
+// splitCall flattens nested calls of a binary logical operator into their
+// operands
+func splitCall(expr ast.Expr, function string) []ast.Expr {
+	if expr.Kind() != ast.CallKind || expr.AsCall().FunctionName() != function {
+		return []ast.Expr{expr}
+	}
+	var operands []ast.Expr
+	for _, arg := range expr.AsCall().Args() {
+		operands = append(operands, splitCall(arg, function)...)
+	}
+	return operands
+}
@@ -0,0 +1,225 @@
+package cel
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// Bind returns value ready to be bound to an expression variable, with its
+// numbers converted like Apply but only when an expression reads them: maps
+// and lists are wrapped instead of copied, so binding a large state costs the
+// same as binding a small one. path is the dotted path of value matched
+// against Types ("" for a variable root).
+func (c NumberCoercion) Bind(value interface{}, path string) interface{} {
+	if c.Mode == NumbersDouble && len(c.Types) == 0 {
+		return value
+	}
+	return c.bind(value, path)
+}
+
+// bind wraps maps and lists and converts other values at path
+func (c NumberCoercion) bind(value interface{}, path string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return &boundMap{
+			Mapper:   types.DefaultTypeAdapter.NativeToValue(v).(traits.Mapper),
+			coercion: c,
+			path:     path,
+			raw:      v,
+		}
+	case []interface{}:
+		return &boundList{
+			Lister:   types.DefaultTypeAdapter.NativeToValue(v).(traits.Lister),
+			coercion: c,
+			path:     path,
+			raw:      v,
+		}
+	default:
+		return c.apply(v, path)
+	}
+}
+
+// Resolve returns the value at a path returned by References in the
+// variables of an evaluation, and false when a map along the path lacks the
+// key or a list the index. A path through a value that is neither a map nor
+// a list resolves to that value.
+func Resolve(vars map[string]interface{}, path []string) (interface{}, bool) {
+	var value interface{} = vars
+	for _, key := range path {
+		switch v := value.(type) {
+		case *boundMap:
+			value = v.raw
+		case *boundList:
+			value = v.raw
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			child, found := v[key]
+			if !found {
+				return nil, false
+			}
+			value = child
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return value, true
+		}
+	}
+	return value, true
+}
+
+// childPath returns the path of key under path
+func childPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// boundMap is a bound state map. Lookups convert the value read; iteration,
+// sizes and equality use the underlying CEL map, whose keys and numeric
+// equality are unaffected by conversion.
+type boundMap struct {
+	traits.Mapper
+	coercion NumberCoercion
+	path     string
+	raw      map[string]interface{}
+}
+
+// Find returns the converted value of key
+func (m *boundMap) Find(key ref.Val) (ref.Val, bool) {
+	k, ok := key.(types.String)
+	if !ok {
+		return m.Mapper.Find(key)
+	}
+	value, found := m.raw[string(k)]
+	if !found {
+		return nil, false
+	}
+	return types.DefaultTypeAdapter.NativeToValue(m.coercion.bind(value, childPath(m.path, string(k)))), true
+}
+
+// Get returns the converted value of key, or an error when it is missing
+func (m *boundMap) Get(key ref.Val) ref.Val {
+	value, found := m.Find(key)
+	if !found {
+		return types.ValOrErr(value, "no such key: %v", key)
+	}
+	return value
+}
+
+// Value returns a converted copy of the map
+func (m *boundMap) Value() interface{} {
+	return m.coercion.apply(m.raw, m.path)
+}
+
+// ConvertToNative converts a converted copy of the map
+func (m *boundMap) ConvertToNative(typeDesc reflect.Type) (interface{}, error) {
+	return types.DefaultTypeAdapter.NativeToValue(m.Value()).ConvertToNative(typeDesc)
+}
+
+// MarshalJSON encodes the map as it is in the state
+func (m *boundMap) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.raw)
+}
+
+// boundList is a bound state list. Elements share the path of the list.
+type boundList struct {
+	traits.Lister
+	coercion NumberCoercion
+	path     string
+	raw      []interface{}
+}
+
+// Get returns the converted element at index
+func (l *boundList) Get(index ref.Val) ref.Val {
+	i, err := types.IndexOrError(index)
+	if err != nil {
+		return types.ValOrErr(index, "%v", err)
+	}
+	if i < 0 || i >= len(l.raw) {
+		return types.NewErr("index '%d' out of range in list size '%d'", i, len(l.raw))
+	}
+	return types.DefaultTypeAdapter.NativeToValue(l.coercion.bind(l.raw[i], l.path))
+}
+
+// Add concatenates a converted copy of the list with other
+func (l *boundList) Add(other ref.Val) ref.Val {
+	return types.DefaultTypeAdapter.NativeToValue(l.Value()).(traits.Lister).Add(other)
+}
+
+// Iterator iterates over the converted elements
+func (l *boundList) Iterator() traits.Iterator {
+	return &listIterator{list: l}
+}
+
+// Value returns a converted copy of the list
+func (l *boundList) Value() interface{} {
+	return l.coercion.apply(l.raw, l.path)
+}
+
+// ConvertToNative converts a converted copy of the list
+func (l *boundList) ConvertToNative(typeDesc reflect.Type) (interface{}, error) {
+	return types.DefaultTypeAdapter.NativeToValue(l.Value()).ConvertToNative(typeDesc)
+}
+
+// MarshalJSON encodes the list as it is in the state
+func (l *boundList) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.raw)
+}
+
+// listIterator iterates over a bound list in comprehensions
+type listIterator struct {
+	list *boundList
+	next int
+}
+
+// HasNext reports whether elements remain
+func (it *listIterator) HasNext() ref.Val {
+	return types.Bool(it.next < len(it.list.raw))
+}
+
+// Next returns the next converted element
+func (it *listIterator) Next() ref.Val {
+	if it.next >= len(it.list.raw) {
+		return nil
+	}
+	value := it.list.Get(types.Int(it.next))
+	it.next++
+	return value
+}
+
+// ConvertToNative is not supported by iterators
+func (it *listIterator) ConvertToNative(typeDesc reflect.Type) (interface{}, error) {
+	return nil, fmt.Errorf("type conversion on iterators not supported")
+}
+
+// ConvertToType is not supported by iterators
+func (it *listIterator) ConvertToType(typeVal ref.Type) ref.Val {
+	return types.NewErr("no such overload")
+}
+
+// Equal is not supported by iterators
+func (it *listIterator) Equal(other ref.Val) ref.Val {
+	return types.NewErr("no such overload")
+}
+
+// Type returns the iterator type
+func (it *listIterator) Type() ref.Type {
+	return types.IteratorType
+}
+
+// Value returns nil; iterators have no Go value
+func (it *listIterator) Value() interface{} {
+	return nil
+}
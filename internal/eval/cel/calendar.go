@@ -0,0 +1,82 @@
+package cel
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// Business hours checked by in_business_hours: Monday to Friday, 09:00 to
+// 17:00 local time
+const (
+	businessHoursStart = 9
+	businessHoursEnd   = 17
+)
+
+// locations caches loaded time zones by name
+var locations sync.Map
+
+// LoadLocation returns the time zone with an IANA name such as
+// "Europe/Madrid", or a fixed zone such as "UTC" or "CET", caching it
+func LoadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// localNow returns the current time in the time zone named by a CEL string,
+// or in UTC without arguments
+func localNow(args ...ref.Val) (time.Time, ref.Val) {
+	if len(args) == 0 {
+		return time.Now().UTC(), nil
+	}
+	name, ok := args[0].(types.String)
+	if !ok {
+		return time.Time{}, types.MaybeNoSuchOverloadErr(args[0])
+	}
+	loc, err := LoadLocation(string(name))
+	if err != nil {
+		return time.Time{}, types.NewErr("%v", err)
+	}
+	return time.Now().In(loc), nil
+}
+
+// hourOf implements hour
+func hourOf(args ...ref.Val) ref.Val {
+	now, errVal := localNow(args...)
+	if errVal != nil {
+		return errVal
+	}
+	return types.Int(now.Hour())
+}
+
+// weekdayOf implements weekday
+func weekdayOf(args ...ref.Val) ref.Val {
+	now, errVal := localNow(args...)
+	if errVal != nil {
+		return errVal
+	}
+	return types.Int(now.Weekday())
+}
+
+// inBusinessHours implements in_business_hours
+func inBusinessHours(tz ref.Val) ref.Val {
+	now, errVal := localNow(tz)
+	if errVal != nil {
+		return errVal
+	}
+	weekday := now.Weekday()
+	if weekday == time.Saturday || weekday == time.Sunday {
+		return types.False
+	}
+	return types.Bool(now.Hour() >= businessHoursStart && now.Hour() < businessHoursEnd)
+}
@@ -0,0 +1,211 @@
+package cel
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+)
+
+// VarType is the CEL type of a declared variable
+type VarType string
+
+// Supported variable types; lists and maps hold values of any type
+const (
+	VarString    VarType = "string"
+	VarInt       VarType = "int"
+	VarDouble    VarType = "double"
+	VarBool      VarType = "bool"
+	VarTimestamp VarType = "timestamp"
+	VarList      VarType = "list"
+	VarMap       VarType = "map"
+	VarDyn       VarType = "dyn"
+)
+
+// identifierPattern matches the names CEL accepts for variables
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedWords cannot name variables
+var reservedWords = map[string]bool{
+	"true": true, "false": true, "null": true, "in": true,
+	"as": true, "break": true, "const": true, "continue": true, "else": true,
+	"for": true, "function": true, "if": true, "import": true, "let": true,
+	"loop": true, "package": true, "namespace": true, "return": true,
+	"var": true, "void": true, "while": true,
+}
+
+// Declarations are typed variables declared in addition to the standard
+// ones, by name. Expressions using them are type-checked against the
+// declared types when they are validated or compiled.
+type Declarations map[string]VarType
+
+// Validate checks the variable names and types
+func (d Declarations) Validate() error {
+	for _, name := range d.names() {
+		switch {
+		case !identifierPattern.MatchString(name) || reservedWords[name]:
+			return fmt.Errorf("variable name '%s' is not a valid identifier", name)
+		case isStandardVariable(name):
+			return fmt.Errorf("variable '%s' is already declared", name)
+		}
+		if _, ok := d[name].celType(); !ok {
+			return fmt.Errorf("type '%s' of variable '%s' is not supported (use string, int, double, bool, timestamp, list, map or dyn)", d[name], name)
+		}
+	}
+	return nil
+}
+
+// names returns the declared names in order
+func (d Declarations) names() []string {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// key identifies the declarations in the environment and program caches;
+// "" is the standard environment
+func (d Declarations) key() string {
+	var b strings.Builder
+	for _, name := range d.names() {
+		fmt.Fprintf(&b, "%s:%s;", name, d[name])
+	}
+	return b.String()
+}
+
+// options returns the environment options declaring the variables
+func (d Declarations) options() []cel.EnvOption {
+	opts := make([]cel.EnvOption, 0, len(d))
+	for _, name := range d.names() {
+		celType, _ := d[name].celType()
+		opts = append(opts, cel.Variable(name, celType))
+	}
+	return opts
+}
+
+// isStandardVariable reports whether name is declared in every environment
+func isStandardVariable(name string) bool {
+	for _, standard := range variables {
+		if name == standard {
+			return true
+		}
+	}
+	return false
+}
+
+// celType returns the CEL type of t
+func (t VarType) celType() (*cel.Type, bool) {
+	switch t {
+	case VarString:
+		return cel.StringType, true
+	case VarInt:
+		return cel.IntType, true
+	case VarDouble:
+		return cel.DoubleType, true
+	case VarBool:
+		return cel.BoolType, true
+	case VarTimestamp:
+		return cel.TimestampType, true
+	case VarList:
+		return cel.ListType(cel.DynType), true
+	case VarMap:
+		return cel.MapType(cel.StringType, cel.DynType), true
+	case VarDyn:
+		return cel.DynType, true
+	}
+	return nil, false
+}
+
+// Convert returns value as the Go value of type t: numbers are converted
+// between ints and doubles when no precision is lost, and timestamps are
+// parsed from RFC 3339 strings
+func (t VarType) Convert(value interface{}) (interface{}, error) {
+	if n, ok := value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			value = i
+		} else if f, err := n.Float64(); err == nil {
+			value = f
+		}
+	}
+
+	switch t {
+	case VarString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case VarInt:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= maxExactInt {
+				return int64(v), nil
+			}
+		}
+	case VarDouble:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		}
+	case VarBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case VarTimestamp:
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' is not an RFC 3339 timestamp", v)
+			}
+			return parsed, nil
+		}
+	case VarList:
+		if l, ok := value.([]interface{}); ok {
+			return l, nil
+		}
+	case VarMap:
+		if m, ok := value.(map[string]interface{}); ok {
+			return m, nil
+		}
+	case VarDyn:
+		return value, nil
+	}
+	return nil, fmt.Errorf("%s value %v is not a %s", typeName(value), value, t)
+}
+
+// typeName describes the type of a state value in conversion errors
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int, int64:
+		return "int"
+	case float64:
+		return "double"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -28,4 +28,33 @@
 //   - Arithmetic: +, -, *, /, %
 //   - List operations: in, size
 //   - Map access: state.field, state["field"]
+//
+// Routing functions:
+//   - text.matchesCached(pattern), matchesCached(text, pattern) - matches() with the pattern compiled once and cached
+//   - regex_extract(text, pattern) - First capture group (or whole match) of a regex, "" if none
+//   - jsonpath(value, path) - Nested value by path (e.g. "$.items[0].sku") from a map, list or JSON string; null if missing
+//   - now() - Current timestamp
+//   - duration_since(t) - Time elapsed since a timestamp or RFC 3339 string
+//   - hour(tz), weekday(tz) - Current hour (0-23) and day of the week (0 is Sunday) in a time zone; UTC without tz
+//   - in_business_hours(tz) - Whether it is Monday to Friday, 09:00 to 17:00, in a time zone
+//   - lower(s), upper(s) - Case conversion
+//   - has_key(map, key) - Whether a map has a key (dynamic alternative to has())
+//   - len_of(value) - Size of a string, list or map; 0 for null
+//   - flag(name) - Whether a feature flag is enabled (see WithFlags)
+//
+// Evaluations can be bounded with WithLimits: a timeout (checked between
+// comprehension iterations, reported as ErrEvalTimeout), a runtime cost limit
+// and a nesting limit applied at parse time.
+//
+// Declared variables:
+//   - state - graph state (graph_id, status, inputs, node_states, metadata)
+//   - ctx - execution context from the work request headers (tenant,
+//     environment, locale, experiment_bucket)
+//   - lookup - results of the node config lookups by name
+//   - inputs - shortcut for state.inputs
+//   - prev - output of the node that ran before the routed node
+//
+// Callers may declare further typed variables (see Declarations) with
+// EvaluateDeclared and ValidateDeclaredExpression; expressions using them
+// are type-checked against the declared types.
 package cel
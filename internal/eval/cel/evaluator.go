@@ -2,117 +2,305 @@ package cel
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"time"
 
+	"github.com/aescanero/dago-node-router/internal/cache"
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/checker/decls"
 )
 
+// variables are the names declared in the CEL environment
+var variables = []string{"state", "ctx", "lookup", "inputs", "prev"}
+
+// macros are the standard CEL macros available to expressions
+var macros = []string{"has", "all", "exists", "exists_one", "map", "filter"}
+
+// expressionCacheSize and expressionCacheTTL bound the caches of compiled
+// programs, validations and environments, keyed by expressions and variable
+// declarations that come from requests and /validate bodies
+const (
+	expressionCacheSize = 10000
+	expressionCacheTTL  = 24 * time.Hour
+)
+
+// defaultRecursionLimit bounds expression nesting at parse time
+const defaultRecursionLimit = 64
+
+// interruptCheckFrequency is how many comprehension iterations run between
+// checks for cancellation
+const interruptCheckFrequency = 100
+
+// ErrEvalTimeout is returned when an evaluation exceeds its timeout
+var ErrEvalTimeout = errors.New("cel evaluation timed out")
+
+// Limits bounds the resources a single evaluation may use
+type Limits struct {
+	// Timeout cancels evaluations running longer than this; 0 disables it
+	Timeout time.Duration
+	// CostLimit aborts evaluations whose runtime cost exceeds it; 0 disables it
+	CostLimit uint64
+	// RecursionLimit bounds expression nesting; 0 uses the default
+	RecursionLimit int
+}
+
+// Option configures an evaluator
+type Option func(*Evaluator)
+
+// WithLimits bounds evaluation time, cost and nesting so a pathological
+// expression can't stall the caller
+func WithLimits(limits Limits) Option {
+	return func(e *Evaluator) {
+		e.limits = limits
+	}
+}
+
+// FlagFunc reports whether a feature flag is enabled
+type FlagFunc func(name string) bool
+
+// WithFlags evaluates the flag() function with flags; without it, flag()
+// fails and rules using it do not match
+func WithFlags(flags FlagFunc) Option {
+	return func(e *Evaluator) {
+		e.flags = flags
+	}
+}
+
+// cacheKey identifies an expression, the declarations it is compiled with
+// and, for validations, its expected output type
+func cacheKey(declared Declarations, expression string, output string) string {
+	return declared.key() + "\x00" + output + "\x00" + expression
+}
+
 // Evaluator evaluates CEL expressions
 type Evaluator struct {
-	env   *cel.Env
-	cache map[string]cel.Program
-	mu    sync.RWMutex
+	env        *cel.Env
+	envs       *cache.LRU[*cel.Env]
+	programs   *cache.LRU[cel.Program]
+	validated  *cache.LRU[error]
+	impure     *cache.LRU[bool]
+	references *cache.LRU[[][]string]
+	extensions []string
+	limits     Limits
+	flags      FlagFunc
 }
 
 // NewEvaluator creates a new CEL evaluator
-func NewEvaluator() *Evaluator {
-	// Create CEL environment with standard declarations
-	env, err := cel.NewEnv(
+func NewEvaluator(opts ...Option) *Evaluator {
+	e := &Evaluator{
+		envs:       cache.NewLRU[*cel.Env](expressionCacheSize, expressionCacheTTL),
+		programs:   cache.NewLRU[cel.Program](expressionCacheSize, expressionCacheTTL),
+		validated:  cache.NewLRU[error](expressionCacheSize, expressionCacheTTL),
+		impure:     cache.NewLRU[bool](expressionCacheSize, expressionCacheTTL),
+		references: cache.NewLRU[[][]string](expressionCacheSize, expressionCacheTTL),
+		extensions: functions,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	recursionLimit := e.limits.RecursionLimit
+	if recursionLimit <= 0 {
+		recursionLimit = defaultRecursionLimit
+	}
+
+	// Create CEL environment with standard declarations and routing functions
+	envOpts := append([]cel.EnvOption{
 		cel.Declarations(
 			decls.NewVar("state", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("ctx", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("lookup", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("inputs", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("prev", decls.Dyn),
 		),
-	)
+		cel.ParserRecursionLimit(recursionLimit),
+	}, routingFunctions()...)
+	envOpts = append(envOpts, e.flagFunction())
+	env, err := cel.NewEnv(envOpts...)
 	if err != nil {
 		panic(fmt.Sprintf("failed to create CEL environment: %v", err))
 	}
+	e.env = env
 
-	return &Evaluator{
-		env:   env,
-		cache: make(map[string]cel.Program),
-	}
+	return e
+}
+
+// Variables returns the variable names available to expressions
+func (e *Evaluator) Variables() []string {
+	return append([]string(nil), variables...)
+}
+
+// Macros returns the CEL macros available to expressions
+func (e *Evaluator) Macros() []string {
+	return append([]string(nil), macros...)
+}
+
+// Extensions returns the names of custom functions available beyond standard CEL
+func (e *Evaluator) Extensions() []string {
+	return append([]string{}, e.extensions...)
 }
 
 // Evaluate evaluates a CEL expression with the given variables
 func (e *Evaluator) Evaluate(ctx context.Context, expression string, vars map[string]interface{}) (interface{}, error) {
+	return e.EvaluateDeclared(ctx, nil, expression, vars)
+}
+
+// EvaluateDeclared evaluates a CEL expression that may use the declared
+// variables, whose values are part of vars
+func (e *Evaluator) EvaluateDeclared(ctx context.Context, declared Declarations, expression string, vars map[string]interface{}) (interface{}, error) {
 	// Get or compile program
-	program, err := e.getProgram(expression)
+	program, err := e.getProgram(declared, expression)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile expression: %w", err)
 	}
 
-	// Evaluate the program
-	out, _, err := program.Eval(vars)
-	if err != nil {
-		return nil, fmt.Errorf("evaluation failed: %w", err)
+	evalCtx := ctx
+	if e.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		evalCtx, cancel = context.WithTimeout(ctx, e.limits.Timeout)
+		defer cancel()
 	}
 
-	// Convert CEL value to Go value
-	result, err := out.ConvertToNative(nil)
+	// Evaluate the program, honoring cancellation. Only the evaluation
+	// timeout is ErrEvalTimeout; a done caller context is reported as is.
+	out, _, err := program.ContextEval(evalCtx, vars)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert result: %w", err)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("evaluation interrupted: %w", ctx.Err())
+		}
+		if errors.Is(evalCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w after %s", ErrEvalTimeout, e.limits.Timeout)
+		}
+		return nil, fmt.Errorf("evaluation failed: %w", err)
 	}
 
-	return result, nil
+	// Convert CEL value to Go value
+	return out.Value(), nil
 }
 
-// getProgram gets a compiled program from cache or compiles it
-func (e *Evaluator) getProgram(expression string) (cel.Program, error) {
-	// Check cache first (read lock)
-	e.mu.RLock()
-	if program, ok := e.cache[expression]; ok {
-		e.mu.RUnlock()
+// getProgram gets a compiled program from cache or compiles it. Concurrent
+// misses may compile the same expression twice; both programs are equivalent.
+func (e *Evaluator) getProgram(declared Declarations, expression string) (cel.Program, error) {
+	key := cacheKey(declared, expression, "")
+	if program, ok := e.programs.Get(context.Background(), key); ok {
 		return program, nil
 	}
-	e.mu.RUnlock()
-
-	// Compile the expression (write lock)
-	e.mu.Lock()
-	defer e.mu.Unlock()
 
-	// Check again in case another goroutine compiled it
-	if program, ok := e.cache[expression]; ok {
-		return program, nil
+	env, err := e.declaredEnv(declared)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse the expression
-	ast, issues := e.env.Compile(expression)
+	ast, issues := env.Compile(expression)
 	if issues != nil && issues.Err() != nil {
 		return nil, fmt.Errorf("parse error: %w", issues.Err())
 	}
 
-	// Generate the program
-	program, err := e.env.Program(ast)
+	// Generate the program with interrupt support and the cost limit
+	progOpts := []cel.ProgramOption{cel.InterruptCheckFrequency(interruptCheckFrequency)}
+	if e.limits.CostLimit > 0 {
+		progOpts = append(progOpts, cel.CostLimit(e.limits.CostLimit))
+	}
+	program, err := env.Program(ast, progOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("program generation error: %w", err)
 	}
 
 	// Cache the program
-	e.cache[expression] = program
+	e.programs.Set(context.Background(), key, program)
 
 	return program, nil
 }
 
-// ValidateExpression validates a CEL expression without evaluating it
+// declaredEnv returns the environment extended with declared, creating it on
+// first use
+func (e *Evaluator) declaredEnv(declared Declarations) (*cel.Env, error) {
+	key := declared.key()
+	if key == "" {
+		return e.env, nil
+	}
+	if env, ok := e.envs.Get(context.Background(), key); ok {
+		return env, nil
+	}
+
+	if err := declared.Validate(); err != nil {
+		return nil, err
+	}
+	env, err := e.env.Extend(declared.options()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to declare variables: %w", err)
+	}
+	e.envs.Set(context.Background(), key, env)
+	return env, nil
+}
+
+// ValidateExpression validates a CEL expression without evaluating it. The
+// expression must type-check to bool; expressions over dynamic state fields
+// (type dyn) are accepted and checked when evaluated.
 func (e *Evaluator) ValidateExpression(expression string) error {
-	ast, issues := e.env.Compile(expression)
+	return e.validate(nil, expression, cel.BoolType)
+}
+
+// ValidateStringExpression validates a CEL expression that computes a string,
+// such as a dynamic rule target, like ValidateExpression
+func (e *Evaluator) ValidateStringExpression(expression string) error {
+	return e.validate(nil, expression, cel.StringType)
+}
+
+// ValidateDeclaredExpression validates a boolean CEL expression that may use
+// the declared variables, checking their uses against the declared types
+func (e *Evaluator) ValidateDeclaredExpression(declared Declarations, expression string) error {
+	return e.validate(declared, expression, cel.BoolType)
+}
+
+// ValidateDeclaredStringExpression validates a CEL expression computing a
+// string that may use the declared variables
+func (e *Evaluator) ValidateDeclaredStringExpression(declared Declarations, expression string) error {
+	return e.validate(declared, expression, cel.StringType)
+}
+
+// validate checks an expression against an output type, caching the outcome
+func (e *Evaluator) validate(declared Declarations, expression string, output *cel.Type) error {
+	key := cacheKey(declared, expression, output.String())
+	if err, ok := e.validated.Get(context.Background(), key); ok {
+		return err
+	}
+
+	env, err := e.declaredEnv(declared)
+	if err == nil {
+		err = checkExpression(env, expression, output)
+	}
+	e.validated.Set(context.Background(), key, err)
+
+	return err
+}
+
+// checkExpression compiles an expression and checks its output type
+func checkExpression(env *cel.Env, expression string, output *cel.Type) error {
+	ast, issues := env.Compile(expression)
 	if issues != nil && issues.Err() != nil {
 		return issues.Err()
 	}
 
-	// Check that the expression returns a boolean
-	// Note: OutputType() replaces deprecated ResultType() in newer CEL versions
 	outputType := ast.OutputType()
-	_ = outputType // Type checking temporarily disabled due to CEL API changes
-	// TODO: Update to proper type checking with new CEL API when stable
+	if !outputType.IsExactType(output) && !outputType.IsExactType(cel.DynType) {
+		return fmt.Errorf("expression must return %s, got %s", output, outputType)
+	}
 
 	return nil
 }
 
-// ClearCache clears the compiled program cache
+// ClearCache clears the compiled program and validation caches
 func (e *Evaluator) ClearCache() {
-	e.mu.Lock()
-	defer e.mu.Unlock()
-	e.cache = make(map[string]cel.Program)
+	e.programs.Purge()
+	e.validated.Purge()
+	e.impure.Purge()
+	e.references.Purge()
+}
+
+// CacheStats returns the size and hit rate of the compiled program cache
+func (e *Evaluator) CacheStats() cache.LRUStats {
+	return e.programs.Stats()
 }
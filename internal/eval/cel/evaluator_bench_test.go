@@ -0,0 +1,24 @@
+package cel
+
+import (
+	"context"
+	"testing"
+)
+
+// BenchmarkEvaluate measures the evaluation of a cached rule condition
+func BenchmarkEvaluate(b *testing.B) {
+	evaluator := NewEvaluator()
+	ctx := context.Background()
+	vars := map[string]interface{}{"state": map[string]interface{}{"inputs": map[string]interface{}{
+		"priority": "high",
+		"message":  "Production API returns 500 errors after the last deploy",
+	}}}
+	expression := `state.inputs.priority == "high" && state.inputs.message.contains("deploy")`
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := evaluator.Evaluate(ctx, expression, vars); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
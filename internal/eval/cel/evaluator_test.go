@@ -0,0 +1,78 @@
+package cel
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// slowExpression iterates long enough to outlast short timeouts
+const slowExpression = `[1, 2, 3, 4, 5, 6, 7, 8, 9, 10].all(a, [1, 2, 3, 4, 5, 6, 7, 8, 9, 10].all(b, [1, 2, 3, 4, 5, 6, 7, 8, 9, 10].all(c, [1, 2, 3, 4, 5, 6, 7, 8, 9, 10].all(d, a + b + c + d > 0))))`
+
+func TestEvaluateTimeout(t *testing.T) {
+	evaluator := NewEvaluator(WithLimits(Limits{Timeout: time.Nanosecond}))
+
+	_, err := evaluator.Evaluate(context.Background(), slowExpression, nil)
+	if !errors.Is(err, ErrEvalTimeout) {
+		t.Fatalf("expected ErrEvalTimeout, got %v", err)
+	}
+}
+
+func TestEvaluateCallerDeadline(t *testing.T) {
+	for _, limits := range []Limits{{}, {Timeout: time.Hour}} {
+		evaluator := NewEvaluator(WithLimits(limits))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		<-ctx.Done()
+
+		_, err := evaluator.Evaluate(ctx, slowExpression, nil)
+		cancel()
+		if errors.Is(err, ErrEvalTimeout) {
+			t.Fatalf("timeout %s: caller deadline reported as ErrEvalTimeout: %v", limits.Timeout, err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("timeout %s: expected context.DeadlineExceeded, got %v", limits.Timeout, err)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	evaluator := NewEvaluator()
+	cases := map[string][][]string{
+		`state.inputs.priority == "high"`:                             {{"state", "inputs", "priority"}},
+		`state.node_states["triage"].output.target_node == "billing"`: {{"state", "node_states", "triage", "output", "target_node"}},
+		`state.inputs.message.contains("refund")`:                     {{"state", "inputs", "message"}},
+		`state.inputs.items[0].sku == ctx.tenant`:                     {{"state", "inputs", "items", "0", "sku"}, {"ctx", "tenant"}},
+		`state.inputs[ctx.field] > 1`:                                 {{"state", "inputs"}, {"ctx", "field"}},
+		`has(lookup.customer.tier)`:                                   {{"lookup", "customer", "tier"}},
+		`not valid (`:                                                 nil,
+	}
+	for expression, want := range cases {
+		if got := evaluator.References(expression); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected references %v, got %v", expression, want, got)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	state := map[string]interface{}{
+		"inputs": map[string]interface{}{
+			"priority": "high",
+			"items":    []interface{}{map[string]interface{}{"sku": "A1"}},
+		},
+	}
+	vars := map[string]interface{}{
+		"state": NumberCoercion{Mode: NumbersIntegral}.Bind(state, ""),
+	}
+
+	if value, ok := Resolve(vars, []string{"state", "inputs", "items", "0", "sku"}); !ok || value != "A1" {
+		t.Fatalf("expected A1, got %v (found %t)", value, ok)
+	}
+	if _, ok := Resolve(vars, []string{"state", "inputs", "region"}); ok {
+		t.Fatal("expected a missing key not to resolve")
+	}
+	if value, ok := Resolve(vars, []string{"state", "inputs", "priority", "length"}); !ok || value != "high" {
+		t.Fatalf("expected a path through a string to resolve to it, got %v (found %t)", value, ok)
+	}
+}
@@ -0,0 +1,285 @@
+package cel
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/eval/regexcache"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// functions are the routing-oriented functions added to the CEL environment
+var functions = []string{
+	"matchesCached", "regex_extract", "jsonpath", "now", "duration_since",
+	"hour", "weekday", "in_business_hours",
+	"lower", "upper", "has_key", "len_of", "flag",
+}
+
+// routingFunctions declares the routing function library
+func routingFunctions() []cel.EnvOption {
+	return []cel.EnvOption{
+		// text.matchesCached(pattern) and matchesCached(text, pattern) are
+		// matches() with the pattern compiled once and cached, for patterns
+		// that are not literals or rules evaluated without optimization
+		cel.Function("matchesCached",
+			cel.MemberOverload("string_matches_cached_string",
+				[]*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(matchesCached),
+			),
+			cel.Overload("matches_cached_string_string",
+				[]*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(matchesCached),
+			),
+		),
+
+		// regex_extract(text, pattern) returns the first capture group of the
+		// first match, the whole match without groups, or "" when nothing matches
+		cel.Function("regex_extract",
+			cel.Overload("regex_extract_string_string",
+				[]*cel.Type{cel.StringType, cel.StringType}, cel.StringType,
+				cel.BinaryBinding(regexExtract),
+			),
+		),
+
+		// jsonpath(value, path) reads a nested value such as "$.items[0].sku"
+		// from a map, list or JSON string, returning null when it is missing
+		cel.Function("jsonpath",
+			cel.Overload("jsonpath_dyn_string",
+				[]*cel.Type{cel.DynType, cel.StringType}, cel.DynType,
+				cel.BinaryBinding(jsonPath),
+			),
+		),
+
+		// now() returns the current time
+		cel.Function("now",
+			cel.Overload("now",
+				[]*cel.Type{}, cel.TimestampType,
+				cel.FunctionBinding(func(...ref.Val) ref.Val {
+					return types.Timestamp{Time: time.Now().UTC()}
+				}),
+			),
+		),
+
+		// duration_since(t) returns the time elapsed since a timestamp or RFC 3339 string
+		cel.Function("duration_since",
+			cel.Overload("duration_since_timestamp",
+				[]*cel.Type{cel.TimestampType}, cel.DurationType,
+				cel.UnaryBinding(durationSince),
+			),
+			cel.Overload("duration_since_string",
+				[]*cel.Type{cel.StringType}, cel.DurationType,
+				cel.UnaryBinding(durationSince),
+			),
+		),
+
+		// hour(tz) returns the current hour (0-23) in a time zone such as
+		// "Europe/Madrid"; hour() uses UTC
+		cel.Function("hour",
+			cel.Overload("hour_utc",
+				[]*cel.Type{}, cel.IntType,
+				cel.FunctionBinding(hourOf),
+			),
+			cel.Overload("hour_string",
+				[]*cel.Type{cel.StringType}, cel.IntType,
+				cel.FunctionBinding(hourOf),
+			),
+		),
+
+		// weekday(tz) returns the current day of the week in a time zone,
+		// from 0 (Sunday) to 6 (Saturday); weekday() uses UTC
+		cel.Function("weekday",
+			cel.Overload("weekday_utc",
+				[]*cel.Type{}, cel.IntType,
+				cel.FunctionBinding(weekdayOf),
+			),
+			cel.Overload("weekday_string",
+				[]*cel.Type{cel.StringType}, cel.IntType,
+				cel.FunctionBinding(weekdayOf),
+			),
+		),
+
+		// in_business_hours(tz) reports whether it is Monday to Friday,
+		// 09:00 to 17:00, in a time zone
+		cel.Function("in_business_hours",
+			cel.Overload("in_business_hours_string",
+				[]*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(inBusinessHours),
+			),
+		),
+
+		cel.Function("lower",
+			cel.Overload("lower_string",
+				[]*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(func(value ref.Val) ref.Val {
+					return types.String(strings.ToLower(string(value.(types.String))))
+				}),
+			),
+		),
+
+		cel.Function("upper",
+			cel.Overload("upper_string",
+				[]*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(func(value ref.Val) ref.Val {
+					return types.String(strings.ToUpper(string(value.(types.String))))
+				}),
+			),
+		),
+
+		// has_key(map, key) reports whether a map has a key, without the
+		// static field selection has() requires
+		cel.Function("has_key",
+			cel.Overload("has_key_dyn_string",
+				[]*cel.Type{cel.DynType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(hasKey),
+			),
+		),
+
+		// len_of(value) returns the size of a string, list or map, and 0 for null
+		cel.Function("len_of",
+			cel.Overload("len_of_dyn",
+				[]*cel.Type{cel.DynType}, cel.IntType,
+				cel.UnaryBinding(lenOf),
+			),
+		),
+	}
+}
+
+// flagFunction declares flag(name), which reports whether a feature flag is
+// enabled
+func (e *Evaluator) flagFunction() cel.EnvOption {
+	return cel.Function("flag",
+		cel.Overload("flag_string",
+			[]*cel.Type{cel.StringType}, cel.BoolType,
+			cel.UnaryBinding(func(name ref.Val) ref.Val {
+				if e.flags == nil {
+					return types.NewErr("flag: feature flags are not configured")
+				}
+				return types.Bool(e.flags(string(name.(types.String))))
+			}),
+		),
+	)
+}
+
+// matchesCached implements matchesCached
+func matchesCached(text, pattern ref.Val) ref.Val {
+	matched, err := regexcache.MatchString(string(pattern.(types.String)), string(text.(types.String)))
+	if err != nil {
+		return types.NewErr("matchesCached: invalid pattern: %v", err)
+	}
+	return types.Bool(matched)
+}
+
+// regexExtract implements regex_extract
+func regexExtract(text, pattern ref.Val) ref.Val {
+	re, err := regexcache.Compile(string(pattern.(types.String)))
+	if err != nil {
+		return types.NewErr("regex_extract: invalid pattern: %v", err)
+	}
+
+	match := re.FindStringSubmatch(string(text.(types.String)))
+	switch {
+	case match == nil:
+		return types.String("")
+	case len(match) > 1:
+		return types.String(match[1])
+	default:
+		return types.String(match[0])
+	}
+}
+
+// jsonPath implements jsonpath
+func jsonPath(value, path ref.Val) ref.Val {
+	current := value.Value()
+	if raw, ok := current.(string); ok {
+		if err := json.Unmarshal([]byte(raw), &current); err != nil {
+			return types.NewErr("jsonpath: invalid JSON: %v", err)
+		}
+	}
+
+	segments, err := parseJSONPath(string(path.(types.String)))
+	if err != nil {
+		return types.NewErr("jsonpath: %v", err)
+	}
+
+	for _, segment := range segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[segment]
+			if !ok {
+				return types.NullValue
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return types.NullValue
+			}
+			current = node[index]
+		default:
+			return types.NullValue
+		}
+	}
+
+	return types.DefaultTypeAdapter.NativeToValue(current)
+}
+
+// parseJSONPath splits a path like "$.items[0].sku" into its segments
+func parseJSONPath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+
+	var segments []string
+	for _, segment := range strings.Split(path, ".") {
+		if segment != "" {
+			segments = append(segments, strings.Trim(segment, `'"`))
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return segments, nil
+}
+
+// durationSince implements duration_since
+func durationSince(value ref.Val) ref.Val {
+	switch v := value.(type) {
+	case types.Timestamp:
+		return types.Duration{Duration: time.Since(v.Time)}
+	case types.String:
+		t, err := time.Parse(time.RFC3339, string(v))
+		if err != nil {
+			return types.NewErr("duration_since: %v", err)
+		}
+		return types.Duration{Duration: time.Since(t)}
+	default:
+		return types.MaybeNoSuchOverloadErr(value)
+	}
+}
+
+// hasKey implements has_key
+func hasKey(value, key ref.Val) ref.Val {
+	mapper, ok := value.(traits.Mapper)
+	if !ok {
+		return types.False
+	}
+	_, found := mapper.Find(key)
+	return types.Bool(found)
+}
+
+// lenOf implements len_of
+func lenOf(value ref.Val) ref.Val {
+	if value == types.NullValue {
+		return types.Int(0)
+	}
+	if sizer, ok := value.(traits.Sizer); ok {
+		return sizer.Size()
+	}
+	return types.NewErr("len_of: unsupported type %s", value.Type())
+}
@@ -0,0 +1,112 @@
+package cel
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// NumberMode selects how untyped JSON numbers are presented to expressions
+type NumberMode string
+
+const (
+	// NumbersIntegral turns whole numbers into ints and keeps the rest as
+	// doubles, so state.inputs.count % 2 == 0 works on decoded JSON (default)
+	NumbersIntegral NumberMode = "integral"
+	// NumbersDouble keeps every JSON number a double, as encoding/json decodes it
+	NumbersDouble NumberMode = "double"
+)
+
+// NumberType is the CEL type a declared field is coerced to
+type NumberType string
+
+const (
+	// NumberInt coerces a field to int, truncating any fraction
+	NumberInt NumberType = "int"
+	// NumberDouble keeps a field a double even when it holds a whole number
+	NumberDouble NumberType = "double"
+)
+
+// maxExactInt is the largest magnitude a double holds as an exact integer
+const maxExactInt = 1 << 53
+
+// NumberCoercion converts decoded JSON numbers to the types expressions
+// expect. Types declares the type of individual fields by dotted path
+// (e.g. "inputs.count"); array elements share the path of their array.
+// Fields without a declared type follow Mode.
+type NumberCoercion struct {
+	Mode  NumberMode
+	Types map[string]NumberType
+}
+
+// ValidateNumberTypes checks the declared field types
+func ValidateNumberTypes(types map[string]NumberType) error {
+	for path, numberType := range types {
+		if numberType != NumberInt && numberType != NumberDouble {
+			return fmt.Errorf("number type of '%s' must be %q or %q, got %q", path, NumberInt, NumberDouble, numberType)
+		}
+	}
+	return nil
+}
+
+// Apply returns a copy of value with its numbers converted; maps and arrays
+// are copied, other values are returned unchanged
+func (c NumberCoercion) Apply(value interface{}) interface{} {
+	if c.Mode == NumbersDouble && len(c.Types) == 0 {
+		return value
+	}
+	return c.apply(value, "")
+}
+
+// apply converts one value found at the given dotted path
+func (c NumberCoercion) apply(value interface{}, path string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			out[key] = c.apply(child, childPath)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = c.apply(child, path)
+		}
+		return out
+	case float64:
+		return c.number(v, path)
+	case json.Number:
+		if i, err := v.Int64(); err == nil && c.Types[path] != NumberDouble {
+			return i
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return c.number(f, path)
+	default:
+		return value
+	}
+}
+
+// number converts a double according to the declared type or the mode
+func (c NumberCoercion) number(f float64, path string) interface{} {
+	switch c.Types[path] {
+	case NumberInt:
+		if math.Abs(f) < math.MaxInt64 {
+			return int64(f)
+		}
+		return f
+	case NumberDouble:
+		return f
+	}
+
+	if c.Mode == NumbersIntegral && f == math.Trunc(f) && math.Abs(f) <= maxExactInt {
+		return int64(f)
+	}
+	return f
+}
@@ -0,0 +1,17 @@
+// Package regexcache compiles regular expressions once and shares them
+// between CEL rules and prompt templates.
+//
+// CEL's matches() compiles its pattern on every evaluation unless the
+// pattern is a literal the program can precompile; matchesCached() and the
+// regex template helpers look patterns up here instead. Compiled patterns,
+// and the errors of invalid ones, are kept in a bounded LRU, so patterns
+// built from state cannot grow memory without limit.
+//
+// Example usage:
+//
+//	re, err := regexcache.Compile(`^[A-Z]{3}-\d{4}$`)
+//	if err != nil {
+//	    return err
+//	}
+//	matched := re.MatchString(code)
+package regexcache
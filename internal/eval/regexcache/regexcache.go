@@ -0,0 +1,51 @@
+package regexcache
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/cache"
+)
+
+const (
+	// capacity bounds the number of cached patterns
+	capacity = 1000
+	// ttl only evicts patterns no longer used; compiled patterns never change
+	ttl = 24 * time.Hour
+)
+
+// compiled is a cached pattern or the reason it does not compile
+type compiled struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// patterns is the process-wide pattern cache
+var patterns = cache.NewLRU[compiled](capacity, ttl)
+
+// Compile returns the compiled pattern, compiling it only on its first use
+// (or after eviction). Compiled patterns are safe for concurrent use.
+func Compile(pattern string) (*regexp.Regexp, error) {
+	ctx := context.Background()
+	if c, ok := patterns.Get(ctx, pattern); ok {
+		return c.re, c.err
+	}
+	re, err := regexp.Compile(pattern)
+	patterns.Set(ctx, pattern, compiled{re: re, err: err})
+	return re, err
+}
+
+// MatchString reports whether text contains a match of pattern
+func MatchString(pattern, text string) (bool, error) {
+	re, err := Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(text), nil
+}
+
+// Stats returns the size and hit rate of the pattern cache
+func Stats() cache.LRUStats {
+	return patterns.Stats()
+}
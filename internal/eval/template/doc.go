@@ -1,4 +1,6 @@
-// Package template provides a Handlebars template engine for rendering LLM prompts.
+// Package template provides the template engines for rendering LLM prompts:
+// Handlebars (Engine), Go text/template (GoEngine) and a Jinja-compatible
+// syntax (JinjaEngine), all implementing Renderer.
 //
 // The engine supports Handlebars syntax with custom helpers for common operations.
 //
@@ -28,11 +30,20 @@
 //   - default - Return default value if first arg is empty
 //   - eq - Equality comparison
 //   - ne - Inequality comparison
-//   - gt - Greater than (for numbers)
-//   - lt - Less than (for numbers)
+//   - gt - Greater than (for numbers, JSON or literal)
+//   - lt - Less than (for numbers, JSON or literal)
 //   - contains - Check if string contains substring
 //   - join - Join array elements with separator
 //   - len - Get length of array/string/map
+//   - json - Pretty-print a value as JSON (not HTML-escaped)
+//   - slice - Elements or characters [start, end) of an array or string
+//   - first, last - First or last element or character
+//   - truncate - Shorten a string to n characters (suffix="..." by default)
+//   - add, sub, mul - Arithmetic on numbers
+//   - round - Round a number (places=0 by default)
+//   - formatDate - Format an RFC 3339 string or Unix seconds with a Go layout (tz="UTC" by default)
+//   - replace - Replace every occurrence of a substring
+//   - split - Split a string into an array by separator
 //
 // Example with helpers:
 //
@@ -42,4 +53,39 @@
 //	{{#if (eq status "active")}}...{{/if}} # Conditional
 //	{{#if (gt score 0.8)}}...{{/if}}       # Numeric comparison
 //	{{join items ", "}}                    # "a, b, c"
+//	{{json state.inputs}}                  # indented JSON of the inputs
+//	{{join (slice tags 0 3) ", "}}         # first three tags
+//	{{truncate message 200}}               # at most 200 characters + "..."
+//	{{round (mul score 100) places=1}}     # 87.5
+//	{{formatDate created_at "2006-01-02" tz="Europe/Madrid"}}
+//	{{#each (split path "/")}}{{this}} {{/each}}
+//
+// Custom helpers:
+//
+// Helpers are registered per engine, on each template it compiles, so
+// engines never share or clash over helpers. Embedders add their own with
+// RegisterHelper:
+//
+//	err := engine.RegisterHelper("currency", func(amount float64, code string) string {
+//	    return fmt.Sprintf("%.2f %s", amount, code)
+//	})
+//
+// Other syntaxes:
+//
+// GoEngine renders Go text/template with the same helpers, taking the piped
+// value last ({{.message | truncate 200}}). JinjaEngine parses a Jinja subset
+// itself: if/elif/else, for loops with loop variables, set, raw, comments,
+// whitespace control, filters, tests and inline if. Its output is not
+// autoescaped and undefined variables render empty.
+//
+//	{% for k, v in order.items() if v %}{{ k | title }}: {{ v }}
+//	{% endfor %}{{ message | truncate(200) }}
+//
+// Sandbox:
+//
+// Engines created with WithSandbox treat templates as untrusted. Templates may
+// only call allowed helpers, may not reference denied data paths (which are
+// also stripped from the render data), may not use partials and are limited
+// in block nesting and output size. Arrays are truncated to bound loops.
+// Violations are reported as *SandboxError, matching ErrSandboxViolation.
 package template
@@ -2,30 +2,102 @@ package template
 
 import (
 	"fmt"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/aymerick/raymond"
 )
 
-// Engine renders Handlebars templates
+// Renderer renders and validates templates of one syntax
+type Renderer interface {
+	Render(templateStr string, data interface{}) (string, error)
+	ValidateTemplate(templateStr string) error
+}
+
+// helper is a named Handlebars helper function
+type helper struct {
+	name string
+	fn   interface{}
+}
+
+// Engine renders Handlebars templates. Helpers are registered on each
+// compiled template rather than globally, so engines with different helpers
+// can coexist.
 type Engine struct {
-	cache map[string]*raymond.Template
-	mu    sync.RWMutex
+	cache   map[string]*raymond.Template
+	sandbox *Sandbox
+	// helpers holds the helper functions by name; names keeps their
+	// registration order
+	helpers map[string]interface{}
+	names   []string
+	mu      sync.RWMutex
+}
+
+// Option configures a template engine
+type Option func(*Engine)
+
+// WithSandbox enforces a sandbox profile on every template
+func WithSandbox(sandbox Sandbox) Option {
+	return func(e *Engine) {
+		e.sandbox = &sandbox
+	}
 }
 
 // NewEngine creates a new template engine
-func NewEngine() *Engine {
+func NewEngine(opts ...Option) *Engine {
 	engine := &Engine{
-		cache: make(map[string]*raymond.Template),
+		cache:   make(map[string]*raymond.Template),
+		helpers: make(map[string]interface{}),
+	}
+
+	// Register the built-in helpers
+	for _, h := range append(defaultHelpers(), valueHelpers()...) {
+		engine.helpers[h.name] = h.fn
+		engine.names = append(engine.names, h.name)
 	}
 
-	// Register custom helpers
-	engine.registerHelpers()
+	for _, opt := range opts {
+		opt(engine)
+	}
 
 	return engine
 }
 
+// RegisterHelper adds a helper available to the templates of this engine
+// only. fn must be a function returning one value; a final *raymond.Options
+// parameter receives hash arguments. Registering a helper clears the
+// compiled template cache. In sandboxed engines the helper must also be
+// listed in AllowedHelpers.
+func (e *Engine) RegisterHelper(name string, fn interface{}) error {
+	if name == "" {
+		return fmt.Errorf("helper name is required")
+	}
+	if slices.Contains(builtinHelpers, name) {
+		return fmt.Errorf("helper %q is a built-in block helper", name)
+	}
+	fnType := reflect.TypeOf(fn)
+	if fnType == nil || fnType.Kind() != reflect.Func {
+		return fmt.Errorf("helper %q must be a function", name)
+	}
+	if fnType.NumOut() != 1 {
+		return fmt.Errorf("helper %q must return exactly one value", name)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.helpers[name]; ok {
+		return fmt.Errorf("helper %q is already registered", name)
+	}
+	e.helpers[name] = fn
+	e.names = append(e.names, name)
+	e.cache = make(map[string]*raymond.Template)
+
+	return nil
+}
+
 // Render renders a template with the given data
 func (e *Engine) Render(templateStr string, data interface{}) (string, error) {
 	// Get or compile template
@@ -34,12 +106,20 @@ func (e *Engine) Render(templateStr string, data interface{}) (string, error) {
 		return "", fmt.Errorf("failed to compile template: %w", err)
 	}
 
+	if e.sandbox != nil {
+		data = e.sandbox.sanitize(data)
+	}
+
 	// Execute the template
 	result, err := tmpl.Exec(data)
 	if err != nil {
 		return "", fmt.Errorf("template execution failed: %w", err)
 	}
 
+	if e.sandbox != nil && e.sandbox.MaxOutputBytes > 0 && len(result) > e.sandbox.MaxOutputBytes {
+		return "", &SandboxError{Reason: fmt.Sprintf("rendered output exceeds %d bytes", e.sandbox.MaxOutputBytes)}
+	}
+
 	return result, nil
 }
 
@@ -62,11 +142,19 @@ func (e *Engine) getTemplate(templateStr string) (*raymond.Template, error) {
 		return tmpl, nil
 	}
 
+	// Reject sandbox violations before compiling
+	if e.sandbox != nil {
+		if err := e.sandbox.check(templateStr, e.names); err != nil {
+			return nil, err
+		}
+	}
+
 	// Parse and compile the template
 	tmpl, err := raymond.Parse(templateStr)
 	if err != nil {
 		return nil, fmt.Errorf("parse error: %w", err)
 	}
+	tmpl.RegisterHelpers(e.helpers)
 
 	// Cache the template
 	e.cache[templateStr] = tmpl
@@ -76,6 +164,12 @@ func (e *Engine) getTemplate(templateStr string) (*raymond.Template, error) {
 
 // ValidateTemplate validates a template without rendering it
 func (e *Engine) ValidateTemplate(templateStr string) error {
+	if e.sandbox != nil {
+		e.mu.RLock()
+		names := e.names
+		e.mu.RUnlock()
+		return e.sandbox.check(templateStr, names)
+	}
 	_, err := raymond.Parse(templateStr)
 	return err
 }
@@ -87,76 +181,99 @@ func (e *Engine) ClearCache() {
 	e.cache = make(map[string]*raymond.Template)
 }
 
-// registerHelpers registers custom Handlebars helpers
-func (e *Engine) registerHelpers() {
-	// uppercase helper
-	raymond.RegisterHelper("uppercase", func(str string) string {
-		return strings.ToUpper(str)
-	})
-
-	// lowercase helper
-	raymond.RegisterHelper("lowercase", func(str string) string {
-		return strings.ToLower(str)
-	})
-
-	// trim helper
-	raymond.RegisterHelper("trim", func(str string) string {
-		return strings.TrimSpace(str)
-	})
-
-	// default helper - return default value if first arg is empty
-	raymond.RegisterHelper("default", func(value interface{}, defaultValue interface{}) interface{} {
-		if value == nil || value == "" {
-			return defaultValue
-		}
-		return value
-	})
-
-	// eq helper - equality comparison
-	raymond.RegisterHelper("eq", func(a, b interface{}) bool {
-		return a == b
-	})
-
-	// ne helper - inequality comparison
-	raymond.RegisterHelper("ne", func(a, b interface{}) bool {
-		return a != b
-	})
-
-	// gt helper - greater than (for numbers)
-	raymond.RegisterHelper("gt", func(a, b float64) bool {
-		return a > b
-	})
-
-	// lt helper - less than (for numbers)
-	raymond.RegisterHelper("lt", func(a, b float64) bool {
-		return a < b
-	})
-
-	// contains helper - check if string contains substring
-	raymond.RegisterHelper("contains", func(str, substr string) bool {
-		return strings.Contains(str, substr)
-	})
-
-	// join helper - join array elements with separator
-	raymond.RegisterHelper("join", func(arr []interface{}, sep string) string {
-		strs := make([]string, len(arr))
-		for i, v := range arr {
-			strs[i] = fmt.Sprint(v)
+// Helpers returns the names of the helpers available to templates
+func (e *Engine) Helpers() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.sandbox != nil {
+		allowed := toSet(e.sandbox.AllowedHelpers)
+		if len(allowed) == 0 {
+			allowed = toSet(DefaultSandboxHelpers)
 		}
-		return strings.Join(strs, sep)
-	})
-
-	// len helper - get length of array/string
-	raymond.RegisterHelper("len", func(value interface{}) int {
-		switch v := value.(type) {
-		case string:
-			return len(v)
-		case []interface{}:
-			return len(v)
-		case map[string]interface{}:
-			return len(v)
-		default:
-			return 0
+		var names []string
+		for _, name := range e.names {
+			if allowed[name] {
+				names = append(names, name)
+			}
 		}
-	})
+		return names
+	}
+	return append([]string(nil), e.names...)
+}
+
+// defaultHelpers returns the string and comparison helpers
+func defaultHelpers() []helper {
+	return []helper{
+		// uppercase helper
+		{"uppercase", func(str string) string {
+			return strings.ToUpper(str)
+		}},
+
+		// lowercase helper
+		{"lowercase", func(str string) string {
+			return strings.ToLower(str)
+		}},
+
+		// trim helper
+		{"trim", func(str string) string {
+			return strings.TrimSpace(str)
+		}},
+
+		// default helper - return default value if first arg is empty
+		{"default", func(value interface{}, defaultValue interface{}) interface{} {
+			if value == nil || value == "" {
+				return defaultValue
+			}
+			return value
+		}},
+
+		// eq helper - equality comparison
+		{"eq", func(a, b interface{}) bool {
+			return a == b
+		}},
+
+		// ne helper - inequality comparison
+		{"ne", func(a, b interface{}) bool {
+			return a != b
+		}},
+
+		// gt helper - greater than (for numbers)
+		{"gt", func(a, b interface{}) bool {
+			return toFloat(a) > toFloat(b)
+		}},
+
+		// lt helper - less than (for numbers)
+		{"lt", func(a, b interface{}) bool {
+			return toFloat(a) < toFloat(b)
+		}},
+
+		// contains helper - check if string contains substring
+		{"contains", func(str, substr string) bool {
+			return strings.Contains(str, substr)
+		}},
+
+		// join helper - join array elements with separator
+		{"join", func(arr []interface{}, sep string) string {
+			strs := make([]string, len(arr))
+			for i, v := range arr {
+				strs[i] = fmt.Sprint(v)
+			}
+			return strings.Join(strs, sep)
+		}},
+
+		// len helper - get length of array/string
+		{"len", func(value interface{}) int {
+			switch v := value.(type) {
+			case string:
+				return len(v)
+			case []interface{}:
+				return len(v)
+			case map[string]interface{}:
+				return len(v)
+			default:
+				return 0
+			}
+		}},
+	}
 }
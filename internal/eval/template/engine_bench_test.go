@@ -0,0 +1,20 @@
+package template
+
+import "testing"
+
+// BenchmarkRender measures the rendering of a routing prompt
+func BenchmarkRender(b *testing.B) {
+	engine := NewEngine()
+	data := map[string]interface{}{"state": map[string]interface{}{"inputs": map[string]interface{}{
+		"priority": "high",
+		"message":  "Production API returns 500 errors after the last deploy",
+	}}}
+	tmpl := "Classify this support message ({{state.inputs.priority}}): {{state.inputs.message}}"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.Render(tmpl, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
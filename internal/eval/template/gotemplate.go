@@ -0,0 +1,164 @@
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	gotemplate "text/template"
+
+	"github.com/aescanero/dago-node-router/internal/cache"
+	"github.com/aescanero/dago-node-router/internal/eval/regexcache"
+)
+
+// GoEngine renders Go text/template templates, with helpers mirroring the
+// Handlebars ones. Helper arguments follow Go template conventions: the
+// piped value comes last, as in {{.message | truncate 200}}.
+type GoEngine struct {
+	cache *cache.LRU[*gotemplate.Template]
+	funcs gotemplate.FuncMap
+}
+
+// NewGoEngine creates a Go text/template engine
+func NewGoEngine() *GoEngine {
+	return &GoEngine{
+		cache: cache.NewLRU[*gotemplate.Template](templateCacheSize, templateCacheTTL),
+		funcs: goFuncs(),
+	}
+}
+
+// Render renders a template with the given data
+func (e *GoEngine) Render(templateStr string, data interface{}) (string, error) {
+	tmpl, err := e.getTemplate(templateStr)
+	if err != nil {
+		return "", fmt.Errorf("failed to compile template: %w", err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("template execution failed: %w", err)
+	}
+	return out.String(), nil
+}
+
+// ValidateTemplate validates a template without rendering it
+func (e *GoEngine) ValidateTemplate(templateStr string) error {
+	_, err := e.parse(templateStr)
+	return err
+}
+
+// getTemplate gets a compiled template from cache or compiles it
+func (e *GoEngine) getTemplate(templateStr string) (*gotemplate.Template, error) {
+	if tmpl, ok := e.cache.Get(context.Background(), templateStr); ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := e.parse(templateStr)
+	if err != nil {
+		return nil, err
+	}
+	e.cache.Set(context.Background(), templateStr, tmpl)
+
+	return tmpl, nil
+}
+
+// parse compiles a template with the engine helpers
+func (e *GoEngine) parse(templateStr string) (*gotemplate.Template, error) {
+	tmpl, err := gotemplate.New("prompt").Funcs(e.funcs).Parse(templateStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Helpers returns the names of the helpers available to Go templates, in
+// addition to the text/template built-in functions
+func (e *GoEngine) Helpers() []string {
+	names := make([]string, 0, len(e.funcs))
+	for name := range e.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// goFuncs returns the helpers of Go templates. text/template already
+// provides eq, ne, lt, gt, len, slice, index, and, or, not and printf.
+func goFuncs() gotemplate.FuncMap {
+	return gotemplate.FuncMap{
+		"uppercase": strings.ToUpper,
+		"lowercase": strings.ToLower,
+		"trim":      strings.TrimSpace,
+		"default": func(defaultValue, value interface{}) interface{} {
+			if value == nil || value == "" {
+				return defaultValue
+			}
+			return value
+		},
+		"contains": func(substr, str string) bool {
+			return strings.Contains(str, substr)
+		},
+		"join": func(sep string, arr []interface{}) string {
+			strs := make([]string, len(arr))
+			for i, v := range arr {
+				strs[i] = fmt.Sprint(v)
+			}
+			return strings.Join(strs, sep)
+		},
+		"json": func(value interface{}) (string, error) {
+			data, err := json.MarshalIndent(value, "", "  ")
+			return string(data), err
+		},
+		"first": func(arr []interface{}) interface{} {
+			if len(arr) == 0 {
+				return nil
+			}
+			return arr[0]
+		},
+		"last": func(arr []interface{}) interface{} {
+			if len(arr) == 0 {
+				return nil
+			}
+			return arr[len(arr)-1]
+		},
+		"truncate": func(n interface{}, str string) string {
+			limit := toInt(n)
+			runes := []rune(str)
+			if limit < 0 || len(runes) <= limit {
+				return str
+			}
+			return string(runes[:limit]) + defaultTruncateSuffix
+		},
+		"add": func(a, b interface{}) float64 { return toFloat(a) + toFloat(b) },
+		"sub": func(a, b interface{}) float64 { return toFloat(a) - toFloat(b) },
+		"mul": func(a, b interface{}) float64 { return toFloat(a) * toFloat(b) },
+		"round": func(places, value interface{}) float64 {
+			scale := math.Pow(10, float64(toInt(places)))
+			return math.Round(toFloat(value)*scale) / scale
+		},
+		"formatDate": func(layout string, value interface{}) string {
+			t, ok := toTime(value)
+			if !ok {
+				return fmt.Sprint(value)
+			}
+			return t.UTC().Format(layout)
+		},
+		"replace": func(old, replacement, str string) string {
+			return strings.ReplaceAll(str, old, replacement)
+		},
+		"split": func(sep, str string) []interface{} {
+			parts := strings.Split(str, sep)
+			values := make([]interface{}, len(parts))
+			for i, part := range parts {
+				values[i] = part
+			}
+			return values
+		},
+		"regex": func(pattern, str string) bool {
+			matched, _ := regexcache.MatchString(pattern, str)
+			return matched
+		},
+	}
+}
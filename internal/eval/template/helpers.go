@@ -0,0 +1,205 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/eval/regexcache"
+	"github.com/aymerick/raymond"
+)
+
+// defaultTruncateSuffix is appended to truncated strings
+const defaultTruncateSuffix = "..."
+
+// valueHelpers returns the JSON, slicing, math, date and string helpers.
+// Numbers may be JSON numbers from state or template literals.
+func valueHelpers() []helper {
+	return []helper{
+		// json helper - pretty-print a value as JSON, not HTML-escaped
+		{"json", func(value interface{}) raymond.SafeString {
+			data, err := json.MarshalIndent(value, "", "  ")
+			if err != nil {
+				return raymond.SafeString(fmt.Sprint(value))
+			}
+			return raymond.SafeString(data)
+		}},
+
+		// slice helper - elements or characters [start, end) of an array or
+		// string; negative indexes count from the end
+		{"slice", func(value, start, end interface{}) interface{} {
+			switch v := value.(type) {
+			case string:
+				runes := []rune(v)
+				from, to := sliceBounds(len(runes), toInt(start), toInt(end))
+				return string(runes[from:to])
+			case []interface{}:
+				from, to := sliceBounds(len(v), toInt(start), toInt(end))
+				return v[from:to]
+			default:
+				return nil
+			}
+		}},
+
+		// first helper - first element of an array or character of a string
+		{"first", func(value interface{}) interface{} {
+			switch v := value.(type) {
+			case string:
+				for _, r := range v {
+					return string(r)
+				}
+			case []interface{}:
+				if len(v) > 0 {
+					return v[0]
+				}
+			}
+			return nil
+		}},
+
+		// last helper - last element of an array or character of a string
+		{"last", func(value interface{}) interface{} {
+			switch v := value.(type) {
+			case string:
+				if runes := []rune(v); len(runes) > 0 {
+					return string(runes[len(runes)-1])
+				}
+			case []interface{}:
+				if len(v) > 0 {
+					return v[len(v)-1]
+				}
+			}
+			return nil
+		}},
+
+		// truncate helper - shorten a string to n characters, appending the
+		// suffix hash parameter ("..." by default) when it was cut
+		{"truncate", func(str string, n interface{}, options *raymond.Options) string {
+			limit := toInt(n)
+			runes := []rune(str)
+			if limit < 0 || len(runes) <= limit {
+				return str
+			}
+			suffix := defaultTruncateSuffix
+			if s, ok := options.HashProp("suffix").(string); ok {
+				suffix = s
+			}
+			return string(runes[:limit]) + suffix
+		}},
+
+		// add, sub and mul helpers - arithmetic on numbers
+		{"add", func(a, b interface{}) float64 {
+			return toFloat(a) + toFloat(b)
+		}},
+		{"sub", func(a, b interface{}) float64 {
+			return toFloat(a) - toFloat(b)
+		}},
+		{"mul", func(a, b interface{}) float64 {
+			return toFloat(a) * toFloat(b)
+		}},
+
+		// round helper - round a number to the places hash parameter (0 by default)
+		{"round", func(value interface{}, options *raymond.Options) float64 {
+			scale := math.Pow(10, float64(toInt(options.HashProp("places"))))
+			return math.Round(toFloat(value)*scale) / scale
+		}},
+
+		// formatDate helper - format an RFC 3339 string or Unix seconds with a Go
+		// layout, in the tz hash parameter time zone (UTC by default)
+		{"formatDate", func(value interface{}, layout string, options *raymond.Options) string {
+			t, ok := toTime(value)
+			if !ok {
+				return fmt.Sprint(value)
+			}
+			loc := time.UTC
+			if tz, ok := options.HashProp("tz").(string); ok && tz != "" {
+				if l, err := time.LoadLocation(tz); err == nil {
+					loc = l
+				}
+			}
+			return t.In(loc).Format(layout)
+		}},
+
+		// replace helper - replace every occurrence of old with replacement
+		{"replace", func(str, old, replacement string) string {
+			return strings.ReplaceAll(str, old, replacement)
+		}},
+
+		// split helper - split a string into an array by separator
+		{"split", func(str, sep string) []interface{} {
+			parts := strings.Split(str, sep)
+			values := make([]interface{}, len(parts))
+			for i, part := range parts {
+				values[i] = part
+			}
+			return values
+		}},
+
+		// regex helper - whether a string contains a match of a regular
+		// expression, compiled once and cached; false for invalid patterns
+		{"regex", func(str, pattern string) bool {
+			matched, _ := regexcache.MatchString(pattern, str)
+			return matched
+		}},
+	}
+}
+
+// sliceBounds resolves [start, end) against length, counting negative
+// indexes from the end and clamping to the valid range
+func sliceBounds(length, start, end int) (int, int) {
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	start = min(max(start, 0), length)
+	end = min(max(end, start), length)
+	return start, end
+}
+
+// toFloat converts a JSON or template number, or a numeric string, to a
+// float64; anything else is 0
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f
+	default:
+		return 0
+	}
+}
+
+// toInt converts a number to an int, truncating fractions
+func toInt(value interface{}) int {
+	return int(toFloat(value))
+}
+
+// toTime converts an RFC 3339 string or Unix seconds to a time
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		return t, err == nil
+	case float64, int, int64, json.Number:
+		sec, frac := math.Modf(toFloat(v))
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	default:
+		return time.Time{}, false
+	}
+}
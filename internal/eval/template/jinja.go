@@ -0,0 +1,829 @@
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/cache"
+)
+
+// templateCacheSize and templateCacheTTL bound the parsed template caches of
+// the Jinja and Go engines, keyed by templates that come from requests and
+// /validate bodies
+const (
+	templateCacheSize = 10000
+	templateCacheTTL  = 24 * time.Hour
+)
+
+// maxRepeatBytes bounds the output of string repetition ("ab" * n) and of
+// filters padding with repeated spaces, which take their count from state
+const maxRepeatBytes = 1 << 20
+
+// JinjaEngine renders templates in a Jinja-compatible syntax: {{ expr }}
+// outputs, {% if %}, {% for %} and {% set %} tags, {# comments #}, filters,
+// tests and "-" whitespace control. Output is not autoescaped, and undefined
+// variables render as empty strings rather than failing. Macros, includes
+// and template inheritance are not supported.
+type JinjaEngine struct {
+	cache *cache.LRU[[]jinjaNode]
+}
+
+// NewJinjaEngine creates a Jinja-compatible template engine
+func NewJinjaEngine() *JinjaEngine {
+	return &JinjaEngine{
+		cache: cache.NewLRU[[]jinjaNode](templateCacheSize, templateCacheTTL),
+	}
+}
+
+// Render renders a template with the given data. A panic while rendering is
+// returned as an error so one bad template cannot crash the worker.
+func (e *JinjaEngine) Render(templateStr string, data interface{}) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = "", fmt.Errorf("template execution failed: %v", r)
+		}
+	}()
+
+	nodes, err := e.getTemplate(templateStr)
+	if err != nil {
+		return "", fmt.Errorf("failed to compile template: %w", err)
+	}
+
+	root := &jinjaScope{vars: map[string]interface{}{}}
+	if vars, ok := jinjaMap(data); ok {
+		root.vars = vars
+	}
+	scope := &jinjaScope{vars: map[string]interface{}{}, parent: root}
+
+	var out strings.Builder
+	if err := execJinja(&out, nodes, scope); err != nil {
+		return "", fmt.Errorf("template execution failed: %w", err)
+	}
+	return out.String(), nil
+}
+
+// ValidateTemplate validates a template without rendering it
+func (e *JinjaEngine) ValidateTemplate(templateStr string) error {
+	if _, err := parseJinja(templateStr); err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+	return nil
+}
+
+// getTemplate gets a parsed template from cache or parses it
+func (e *JinjaEngine) getTemplate(templateStr string) ([]jinjaNode, error) {
+	if nodes, ok := e.cache.Get(context.Background(), templateStr); ok {
+		return nodes, nil
+	}
+
+	nodes, err := parseJinja(templateStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	e.cache.Set(context.Background(), templateStr, nodes)
+
+	return nodes, nil
+}
+
+// Helpers returns the names of the filters available to Jinja templates
+func (e *JinjaEngine) Helpers() []string {
+	names := make([]string, 0, len(jinjaFilters))
+	for name := range jinjaFilters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// undefined is the value of variables and attributes that do not exist
+type undefined struct{}
+
+// jinjaScope holds the variables of a template, loop iteration or set
+type jinjaScope struct {
+	vars   map[string]interface{}
+	parent *jinjaScope
+}
+
+// lookup resolves a variable through the enclosing scopes
+func (s *jinjaScope) lookup(name string) interface{} {
+	for scope := s; scope != nil; scope = scope.parent {
+		if value, ok := scope.vars[name]; ok {
+			return value
+		}
+	}
+	return undefined{}
+}
+
+// execJinja renders nodes to out
+func execJinja(out *strings.Builder, nodes []jinjaNode, scope *jinjaScope) error {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *textNode:
+			out.WriteString(n.text)
+		case *outputNode:
+			value, err := n.expr.eval(scope)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n.line, err)
+			}
+			out.WriteString(jinjaString(value))
+		case *setNode:
+			value, err := n.expr.eval(scope)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n.line, err)
+			}
+			scope.vars[n.name] = value
+		case *ifNode:
+			body := n.elseBody
+			for i, cond := range n.conds {
+				value, err := cond.eval(scope)
+				if err != nil {
+					return err
+				}
+				if truthy(value) {
+					body = n.bodies[i]
+					break
+				}
+			}
+			if err := execJinja(out, body, scope); err != nil {
+				return err
+			}
+		case *forNode:
+			if err := execFor(out, n, scope); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// execFor renders a for loop. Each iteration runs in its own scope holding
+// the loop variables and the loop object.
+func execFor(out *strings.Builder, n *forNode, scope *jinjaScope) error {
+	value, err := n.iter.eval(scope)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", n.line, err)
+	}
+	items, err := jinjaIter(value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", n.line, err)
+	}
+
+	var iterations []map[string]interface{}
+	for _, item := range items {
+		vars := make(map[string]interface{}, len(n.vars)+1)
+		if err := bindLoopVars(vars, n.vars, item); err != nil {
+			return fmt.Errorf("line %d: %w", n.line, err)
+		}
+		if n.filter != nil {
+			keep, err := n.filter.eval(&jinjaScope{vars: vars, parent: scope})
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n.line, err)
+			}
+			if !truthy(keep) {
+				continue
+			}
+		}
+		iterations = append(iterations, vars)
+	}
+
+	if len(iterations) == 0 {
+		return execJinja(out, n.elseBody, scope)
+	}
+	length := len(iterations)
+	for i, vars := range iterations {
+		vars["loop"] = map[string]interface{}{
+			"index":     float64(i + 1),
+			"index0":    float64(i),
+			"revindex":  float64(length - i),
+			"revindex0": float64(length - i - 1),
+			"first":     i == 0,
+			"last":      i == length-1,
+			"length":    float64(length),
+		}
+		if err := execJinja(out, n.body, &jinjaScope{vars: vars, parent: scope}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindLoopVars assigns a loop item to the loop variables, unpacking pairs
+// when there are two
+func bindLoopVars(vars map[string]interface{}, names []string, item interface{}) error {
+	if len(names) == 1 {
+		vars[names[0]] = item
+		return nil
+	}
+	pair, ok := jinjaList(item)
+	if !ok || len(pair) != len(names) {
+		return fmt.Errorf("cannot unpack %s into %d variables", typeName(item), len(names))
+	}
+	for i, name := range names {
+		vars[name] = pair[i]
+	}
+	return nil
+}
+
+// jinjaExpr is a Jinja expression
+type jinjaExpr interface {
+	eval(scope *jinjaScope) (interface{}, error)
+}
+
+// Jinja expression nodes
+type (
+	literalExpr struct {
+		value interface{}
+	}
+
+	nameExpr struct {
+		name string
+	}
+
+	listExpr struct {
+		items []jinjaExpr
+	}
+
+	indexExpr struct {
+		expr  jinjaExpr
+		index jinjaExpr
+	}
+
+	unaryExpr struct {
+		op   string
+		expr jinjaExpr
+	}
+
+	binaryExpr struct {
+		op    string
+		left  jinjaExpr
+		right jinjaExpr
+	}
+
+	condExpr struct {
+		cond      jinjaExpr
+		then      jinjaExpr
+		otherwise jinjaExpr
+	}
+
+	filterExpr struct {
+		expr   jinjaExpr
+		name   string
+		filter jinjaFilter
+		args   []jinjaExpr
+		kwargs map[string]jinjaExpr
+	}
+
+	testExpr struct {
+		expr   jinjaExpr
+		name   string
+		test   jinjaTest
+		args   []jinjaExpr
+		negate bool
+	}
+
+	methodExpr struct {
+		expr   jinjaExpr
+		name   string
+		method jinjaMethod
+		args   []jinjaExpr
+	}
+
+	callExpr struct {
+		name string
+		fn   jinjaFunction
+		args []jinjaExpr
+	}
+)
+
+func (e *literalExpr) eval(*jinjaScope) (interface{}, error) {
+	return e.value, nil
+}
+
+func (e *nameExpr) eval(scope *jinjaScope) (interface{}, error) {
+	return scope.lookup(e.name), nil
+}
+
+func (e *listExpr) eval(scope *jinjaScope) (interface{}, error) {
+	return evalArgs(e.items, scope)
+}
+
+func (e *indexExpr) eval(scope *jinjaScope) (interface{}, error) {
+	value, err := e.expr.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	index, err := e.index.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	// Lists and strings are only indexed by integers; 1.5 is not rounded
+	if n, ok := jinjaNumber(index); ok && n != math.Trunc(n) {
+		if _, isMap := jinjaMap(value); !isMap {
+			return nil, fmt.Errorf("%s indices must be integers, not %v", typeName(value), index)
+		}
+	}
+	return jinjaItem(value, index), nil
+}
+
+func (e *unaryExpr) eval(scope *jinjaScope) (interface{}, error) {
+	value, err := e.expr.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == "not" {
+		return !truthy(value), nil
+	}
+	n, ok := jinjaNumber(value)
+	if !ok {
+		return nil, fmt.Errorf("bad operand type for unary -: %s", typeName(value))
+	}
+	return -n, nil
+}
+
+func (e *binaryExpr) eval(scope *jinjaScope) (interface{}, error) {
+	left, err := e.left.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	// and and or short-circuit, returning the deciding operand as Jinja does
+	switch e.op {
+	case "and":
+		if !truthy(left) {
+			return left, nil
+		}
+		return e.right.eval(scope)
+	case "or":
+		if truthy(left) {
+			return left, nil
+		}
+		return e.right.eval(scope)
+	}
+	right, err := e.right.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	return jinjaBinary(e.op, left, right)
+}
+
+func (e *condExpr) eval(scope *jinjaScope) (interface{}, error) {
+	cond, err := e.cond.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(cond) {
+		return e.then.eval(scope)
+	}
+	return e.otherwise.eval(scope)
+}
+
+func (e *filterExpr) eval(scope *jinjaScope) (interface{}, error) {
+	value, err := e.expr.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	args, err := evalArgs(e.args, scope)
+	if err != nil {
+		return nil, err
+	}
+	kwargs := make(map[string]interface{}, len(e.kwargs))
+	for name, expr := range e.kwargs {
+		if kwargs[name], err = expr.eval(scope); err != nil {
+			return nil, err
+		}
+	}
+	result, err := e.filter(value, jinjaArgs{pos: args, kw: kwargs})
+	if err != nil {
+		return nil, fmt.Errorf("filter %s: %w", e.name, err)
+	}
+	return result, nil
+}
+
+func (e *testExpr) eval(scope *jinjaScope) (interface{}, error) {
+	value, err := e.expr.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	args, err := evalArgs(e.args, scope)
+	if err != nil {
+		return nil, err
+	}
+	return e.test(value, args) != e.negate, nil
+}
+
+func (e *methodExpr) eval(scope *jinjaScope) (interface{}, error) {
+	value, err := e.expr.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	args, err := evalArgs(e.args, scope)
+	if err != nil {
+		return nil, err
+	}
+	result, err := e.method(value, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s.%s(): %w", typeName(value), e.name, err)
+	}
+	return result, nil
+}
+
+func (e *callExpr) eval(scope *jinjaScope) (interface{}, error) {
+	args, err := evalArgs(e.args, scope)
+	if err != nil {
+		return nil, err
+	}
+	result, err := e.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", e.name, err)
+	}
+	return result, nil
+}
+
+// evalArgs evaluates a list of expressions
+func evalArgs(exprs []jinjaExpr, scope *jinjaScope) ([]interface{}, error) {
+	values := make([]interface{}, len(exprs))
+	for i, expr := range exprs {
+		value, err := expr.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// jinjaBinary applies a comparison, membership or arithmetic operator
+func jinjaBinary(op string, left, right interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return jinjaEqual(left, right), nil
+	case "!=":
+		return !jinjaEqual(left, right), nil
+	case "in", "not in":
+		found, err := jinjaContains(right, left)
+		return found == (op == "in"), err
+	case "~":
+		return jinjaString(left) + jinjaString(right), nil
+	}
+
+	ls, lstr := left.(string)
+	rs, rstr := right.(string)
+	ln, lnum := jinjaNumber(left)
+	rn, rnum := jinjaNumber(right)
+
+	switch op {
+	case "<", ">", "<=", ">=":
+		var cmp int
+		switch {
+		case lnum && rnum:
+			cmp = compareFloats(ln, rn)
+		case lstr && rstr:
+			cmp = strings.Compare(ls, rs)
+		default:
+			return nil, fmt.Errorf("cannot compare %s and %s", typeName(left), typeName(right))
+		}
+		switch op {
+		case "<":
+			return cmp < 0, nil
+		case ">":
+			return cmp > 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case "+":
+		if lstr && rstr {
+			return ls + rs, nil
+		}
+		ll, llist := jinjaList(left)
+		rl, rlist := jinjaList(right)
+		if llist && rlist {
+			return append(append([]interface{}{}, ll...), rl...), nil
+		}
+	case "*":
+		if lstr && rnum {
+			return repeatString(ls, rn)
+		}
+	}
+
+	if !lnum || !rnum {
+		return nil, fmt.Errorf("unsupported operand types for %s: %s and %s", op, typeName(left), typeName(right))
+	}
+	switch op {
+	case "+":
+		return ln + rn, nil
+	case "-":
+		return ln - rn, nil
+	case "*":
+		return ln * rn, nil
+	}
+	if rn == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	switch op {
+	case "/":
+		return ln / rn, nil
+	case "//":
+		return math.Floor(ln / rn), nil
+	default:
+		// Python modulo takes the sign of the divisor
+		m := math.Mod(ln, rn)
+		if m != 0 && (m < 0) != (rn < 0) {
+			m += rn
+		}
+		return m, nil
+	}
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// jinjaEqual compares values, treating all numeric types alike
+func jinjaEqual(a, b interface{}) bool {
+	an, aok := jinjaNumber(a)
+	bn, bok := jinjaNumber(b)
+	if aok && bok {
+		return an == bn
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// jinjaContains reports whether item is a substring, element or key of
+// container. Undefined and none contain nothing.
+func jinjaContains(container, item interface{}) (bool, error) {
+	switch c := container.(type) {
+	case undefined, nil:
+		return false, nil
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("'in <string>' requires a string, not %s", typeName(item))
+		}
+		return strings.Contains(c, s), nil
+	}
+	if list, ok := jinjaList(container); ok {
+		for _, element := range list {
+			if jinjaEqual(element, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	if m, ok := jinjaMap(container); ok {
+		_, found := m[jinjaString(item)]
+		return found, nil
+	}
+	return false, fmt.Errorf("%s is not a container", typeName(container))
+}
+
+// repeatString repeats s count times, nothing when count is not positive. It
+// fails rather than producing more than maxRepeatBytes.
+func repeatString(s string, count float64) (string, error) {
+	if s == "" || !(count >= 1) {
+		return "", nil
+	}
+	if count > float64(maxRepeatBytes/len(s)) {
+		return "", fmt.Errorf("repeated string exceeds %d bytes", maxRepeatBytes)
+	}
+	return strings.Repeat(s, int(count)), nil
+}
+
+// jinjaItem returns an attribute, key or index of value, or undefined
+func jinjaItem(value, key interface{}) interface{} {
+	if m, ok := jinjaMap(value); ok {
+		if item, found := m[jinjaString(key)]; found {
+			return item
+		}
+		return undefined{}
+	}
+
+	index, ok := jinjaNumber(key)
+	if !ok {
+		return undefined{}
+	}
+	i := int(index)
+	if s, isString := value.(string); isString {
+		runes := []rune(s)
+		if i < 0 {
+			i += len(runes)
+		}
+		if i < 0 || i >= len(runes) {
+			return undefined{}
+		}
+		return string(runes[i])
+	}
+	if list, isList := jinjaList(value); isList {
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return undefined{}
+		}
+		return list[i]
+	}
+	return undefined{}
+}
+
+// jinjaIter returns the items a for loop iterates: list elements, sorted
+// mapping keys or string characters. Undefined and none iterate nothing.
+func jinjaIter(value interface{}) ([]interface{}, error) {
+	switch v := value.(type) {
+	case undefined, nil:
+		return nil, nil
+	case string:
+		items := make([]interface{}, 0, len(v))
+		for _, r := range v {
+			items = append(items, string(r))
+		}
+		return items, nil
+	}
+	if list, ok := jinjaList(value); ok {
+		return list, nil
+	}
+	if m, ok := jinjaMap(value); ok {
+		keys := sortedMapKeys(m)
+		items := make([]interface{}, len(keys))
+		for i, key := range keys {
+			items[i] = key
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%s is not iterable", typeName(value))
+}
+
+// truthy reports whether a value is true in a condition
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case undefined, nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	if n, ok := jinjaNumber(value); ok {
+		return n != 0
+	}
+	if list, ok := jinjaList(value); ok {
+		return len(list) > 0
+	}
+	if m, ok := jinjaMap(value); ok {
+		return len(m) > 0
+	}
+	return true
+}
+
+// jinjaNumber converts any numeric value to a float64
+func jinjaNumber(value interface{}) (float64, bool) {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// jinjaList converts any slice or array to a []interface{}
+func jinjaList(value interface{}) ([]interface{}, bool) {
+	if list, ok := value.([]interface{}); ok {
+		return list, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list, true
+}
+
+// jinjaMap converts any map with string keys to a map[string]interface{}
+func jinjaMap(value interface{}) (map[string]interface{}, bool) {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	m := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		m[iter.Key().String()] = iter.Value().Interface()
+	}
+	return m, true
+}
+
+// sortedMapKeys returns the keys of a map in a stable order
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jinjaString converts a value to its rendered text, as Python's str would
+func jinjaString(value interface{}) string {
+	switch v := value.(type) {
+	case undefined:
+		return ""
+	case nil:
+		return "None"
+	case string:
+		return v
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	}
+	if n, ok := jinjaNumber(value); ok {
+		return formatNumber(n)
+	}
+	if list, ok := jinjaList(value); ok {
+		parts := make([]string, len(list))
+		for i, item := range list {
+			parts[i] = jinjaRepr(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	if m, ok := jinjaMap(value); ok {
+		keys := sortedMapKeys(m)
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = jinjaRepr(key) + ": " + jinjaRepr(m[key])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return fmt.Sprint(value)
+}
+
+// jinjaRepr converts a value to text as it appears inside lists and
+// mappings, quoting strings
+func jinjaRepr(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(s) + "'"
+	}
+	return jinjaString(value)
+}
+
+// formatNumber renders whole numbers without a fractional part, since JSON
+// state does not distinguish integers from floats
+func formatNumber(n float64) string {
+	if n == math.Trunc(n) && math.Abs(n) < 1e15 {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// typeName names the Jinja type of a value in error messages
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case undefined:
+		return "undefined"
+	case nil:
+		return "none"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	}
+	if _, ok := jinjaNumber(value); ok {
+		return "number"
+	}
+	if _, ok := jinjaList(value); ok {
+		return "list"
+	}
+	if _, ok := jinjaMap(value); ok {
+		return "mapping"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,531 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aescanero/dago-node-router/internal/eval/regexcache"
+)
+
+// jinjaArgs are the evaluated arguments of a filter call
+type jinjaArgs struct {
+	pos []interface{}
+	kw  map[string]interface{}
+}
+
+// get returns the argument at position i or named name, or def when absent
+func (a jinjaArgs) get(i int, name string, def interface{}) interface{} {
+	if i < len(a.pos) {
+		return a.pos[i]
+	}
+	if value, ok := a.kw[name]; ok {
+		return value
+	}
+	return def
+}
+
+// Filter, test, method and global function signatures
+type (
+	jinjaFilter   func(value interface{}, args jinjaArgs) (interface{}, error)
+	jinjaTest     func(value interface{}, args []interface{}) bool
+	jinjaMethod   func(value interface{}, args []interface{}) (interface{}, error)
+	jinjaFunction func(args []interface{}) (interface{}, error)
+)
+
+// maxRangeItems bounds the lists built by range()
+const maxRangeItems = 100000
+
+// jinjaFilters are the filters available to Jinja templates
+var jinjaFilters = map[string]jinjaFilter{
+	"upper": stringFilter(strings.ToUpper),
+	"lower": stringFilter(strings.ToLower),
+	"title": stringFilter(func(s string) string {
+		runes := []rune(strings.ToLower(s))
+		for i, r := range runes {
+			if i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '-' {
+				runes[i] = unicode.ToUpper(r)
+			}
+		}
+		return string(runes)
+	}),
+	"capitalize": stringFilter(func(s string) string {
+		runes := []rune(strings.ToLower(s))
+		if len(runes) > 0 {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+		return string(runes)
+	}),
+	"trim": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		if chars, ok := args.get(0, "chars", nil).(string); ok {
+			return strings.Trim(jinjaString(value), chars), nil
+		}
+		return strings.TrimSpace(jinjaString(value)), nil
+	},
+	"default": filterDefault,
+	"d":       filterDefault,
+	"join": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		items, err := jinjaIter(value)
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = jinjaString(item)
+		}
+		return strings.Join(parts, jinjaString(args.get(0, "d", ""))), nil
+	},
+	"length": filterLength,
+	"count":  filterLength,
+	"tojson": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		if _, ok := value.(undefined); ok {
+			value = nil
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if indent := toFloat(args.get(0, "indent", nil)); indent > 0 {
+			prefix, err := repeatString(" ", indent)
+			if err != nil {
+				return nil, err
+			}
+			enc.SetIndent("", prefix)
+		}
+		if err := enc.Encode(value); err != nil {
+			return nil, err
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	},
+	"first": func(value interface{}, _ jinjaArgs) (interface{}, error) {
+		items, err := jinjaIter(value)
+		if err != nil || len(items) == 0 {
+			return undefined{}, err
+		}
+		return items[0], nil
+	},
+	"last": func(value interface{}, _ jinjaArgs) (interface{}, error) {
+		items, err := jinjaIter(value)
+		if err != nil || len(items) == 0 {
+			return undefined{}, err
+		}
+		return items[len(items)-1], nil
+	},
+	"truncate": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		runes := []rune(jinjaString(value))
+		length := toInt(args.get(0, "length", 255.0))
+		killwords := truthy(args.get(1, "killwords", false))
+		end := jinjaString(args.get(2, "end", defaultTruncateSuffix))
+		leeway := toInt(args.get(3, "leeway", 5.0))
+		if len(runes) <= length+leeway {
+			return string(runes), nil
+		}
+		cut := string(runes[:max(length-len([]rune(end)), 0)])
+		if !killwords {
+			if i := strings.LastIndex(cut, " "); i >= 0 {
+				cut = cut[:i]
+			}
+		}
+		return cut + end, nil
+	},
+	"round": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		n, ok := jinjaNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("cannot round %s", typeName(value))
+		}
+		scale := math.Pow(10, float64(toInt(args.get(0, "precision", 0.0))))
+		switch method := jinjaString(args.get(1, "method", "common")); method {
+		case "common":
+			return math.Round(n*scale) / scale, nil
+		case "floor":
+			return math.Floor(n*scale) / scale, nil
+		case "ceil":
+			return math.Ceil(n*scale) / scale, nil
+		default:
+			return nil, fmt.Errorf("unknown rounding method %q", method)
+		}
+	},
+	"int": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		if n, ok := numberOf(value); ok {
+			return math.Trunc(n), nil
+		}
+		return args.get(0, "default", 0.0), nil
+	},
+	"float": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		if n, ok := numberOf(value); ok {
+			return n, nil
+		}
+		return args.get(0, "default", 0.0), nil
+	},
+	"abs": func(value interface{}, _ jinjaArgs) (interface{}, error) {
+		n, ok := jinjaNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("bad operand type for abs: %s", typeName(value))
+		}
+		return math.Abs(n), nil
+	},
+	"string": func(value interface{}, _ jinjaArgs) (interface{}, error) {
+		return jinjaString(value), nil
+	},
+	"replace": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		old := jinjaString(args.get(0, "old", ""))
+		replacement := jinjaString(args.get(1, "new", ""))
+		count := -1
+		if c := args.get(2, "count", nil); c != nil {
+			count = toInt(c)
+		}
+		return strings.Replace(jinjaString(value), old, replacement, count), nil
+	},
+	"split": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		var parts []string
+		if sep, ok := args.get(0, "sep", nil).(string); ok {
+			parts = strings.Split(jinjaString(value), sep)
+		} else {
+			parts = strings.Fields(jinjaString(value))
+		}
+		items := make([]interface{}, len(parts))
+		for i, part := range parts {
+			items[i] = part
+		}
+		return items, nil
+	},
+	"reverse": func(value interface{}, _ jinjaArgs) (interface{}, error) {
+		if s, ok := value.(string); ok {
+			runes := []rune(s)
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			return string(runes), nil
+		}
+		items, err := jinjaIter(value)
+		if err != nil {
+			return nil, err
+		}
+		reversed := make([]interface{}, len(items))
+		for i, item := range items {
+			reversed[len(items)-1-i] = item
+		}
+		return reversed, nil
+	},
+	"sort": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		items, err := jinjaIter(value)
+		if err != nil {
+			return nil, err
+		}
+		sorted := append([]interface{}(nil), items...)
+		reverse := truthy(args.get(0, "reverse", false))
+		caseSensitive := truthy(args.get(1, "case_sensitive", false))
+		var sortErr error
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			if !caseSensitive {
+				a, b = foldCase(a), foldCase(b)
+			}
+			less, err := jinjaBinary("<", a, b)
+			if err != nil {
+				sortErr = err
+				return false
+			}
+			if reverse {
+				greater, _ := jinjaBinary(">", a, b)
+				return greater == true
+			}
+			return less == true
+		})
+		return sorted, sortErr
+	},
+	"items": func(value interface{}, _ jinjaArgs) (interface{}, error) {
+		return methodItems(value, nil)
+	},
+	"list": func(value interface{}, _ jinjaArgs) (interface{}, error) {
+		return jinjaIter(value)
+	},
+	"wordcount": func(value interface{}, _ jinjaArgs) (interface{}, error) {
+		return float64(len(strings.Fields(jinjaString(value)))), nil
+	},
+	"indent": func(value interface{}, args jinjaArgs) (interface{}, error) {
+		width := toFloat(args.get(0, "width", 4.0))
+		if width < 0 {
+			return nil, fmt.Errorf("width must not be negative")
+		}
+		prefix, err := repeatString(" ", width)
+		if err != nil {
+			return nil, err
+		}
+		lines := strings.Split(jinjaString(value), "\n")
+		if len(prefix)*len(lines) > maxRepeatBytes {
+			return nil, fmt.Errorf("indented text exceeds %d bytes", maxRepeatBytes)
+		}
+		for i, line := range lines {
+			if (i > 0 || truthy(args.get(1, "first", false))) && line != "" {
+				lines[i] = prefix + line
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	},
+	"escape": stringFilter(html.EscapeString),
+	"e":      stringFilter(html.EscapeString),
+	// safe marks autoescaped output as safe in Jinja; nothing is
+	// autoescaped here, so it is the identity
+	"safe": func(value interface{}, _ jinjaArgs) (interface{}, error) {
+		return value, nil
+	},
+}
+
+// stringFilter adapts a string function to a filter
+func stringFilter(fn func(string) string) jinjaFilter {
+	return func(value interface{}, _ jinjaArgs) (interface{}, error) {
+		return fn(jinjaString(value)), nil
+	}
+}
+
+// filterDefault returns the default value for undefined values, or for any
+// false value when its boolean argument is true
+func filterDefault(value interface{}, args jinjaArgs) (interface{}, error) {
+	_, isUndefined := value.(undefined)
+	if isUndefined || (truthy(args.get(1, "boolean", false)) && !truthy(value)) {
+		return args.get(0, "default_value", ""), nil
+	}
+	return value, nil
+}
+
+// filterLength returns the number of characters, elements or keys
+func filterLength(value interface{}, _ jinjaArgs) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		return float64(len([]rune(s))), nil
+	}
+	items, err := jinjaIter(value)
+	return float64(len(items)), err
+}
+
+// numberOf converts numbers and numeric strings to a float64
+func numberOf(value interface{}) (float64, bool) {
+	if s, ok := value.(string); ok {
+		var n float64
+		_, err := fmt.Sscan(strings.TrimSpace(s), &n)
+		return n, err == nil
+	}
+	return jinjaNumber(value)
+}
+
+// foldCase lowercases strings for case-insensitive sorting
+func foldCase(value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		return strings.ToLower(s)
+	}
+	return value
+}
+
+// jinjaTests are the tests available to "is" expressions
+var jinjaTests = map[string]jinjaTest{
+	"defined": func(value interface{}, _ []interface{}) bool {
+		_, isUndefined := value.(undefined)
+		return !isUndefined
+	},
+	"undefined": func(value interface{}, _ []interface{}) bool {
+		_, isUndefined := value.(undefined)
+		return isUndefined
+	},
+	"none": func(value interface{}, _ []interface{}) bool {
+		return value == nil
+	},
+	"boolean": func(value interface{}, _ []interface{}) bool {
+		_, ok := value.(bool)
+		return ok
+	},
+	"number": func(value interface{}, _ []interface{}) bool {
+		_, ok := jinjaNumber(value)
+		return ok
+	},
+	"string": func(value interface{}, _ []interface{}) bool {
+		_, ok := value.(string)
+		return ok
+	},
+	"mapping": func(value interface{}, _ []interface{}) bool {
+		_, ok := jinjaMap(value)
+		return ok
+	},
+	"sequence": func(value interface{}, _ []interface{}) bool {
+		_, isString := value.(string)
+		_, isList := jinjaList(value)
+		return isString || isList
+	},
+	"iterable": func(value interface{}, _ []interface{}) bool {
+		switch value.(type) {
+		case undefined, nil:
+			return false
+		}
+		_, err := jinjaIter(value)
+		return err == nil
+	},
+	"even": func(value interface{}, _ []interface{}) bool {
+		n, ok := jinjaNumber(value)
+		return ok && math.Mod(n, 2) == 0
+	},
+	"odd": func(value interface{}, _ []interface{}) bool {
+		n, ok := jinjaNumber(value)
+		return ok && math.Abs(math.Mod(n, 2)) == 1
+	},
+	"divisibleby": func(value interface{}, args []interface{}) bool {
+		n, ok := jinjaNumber(value)
+		if !ok || len(args) == 0 {
+			return false
+		}
+		d, ok := jinjaNumber(args[0])
+		return ok && d != 0 && math.Mod(n, d) == 0
+	},
+	// regex(pattern) - the string contains a match of the cached pattern
+	"regex": func(value interface{}, args []interface{}) bool {
+		text, ok := value.(string)
+		if !ok || len(args) == 0 {
+			return false
+		}
+		pattern, ok := args[0].(string)
+		if !ok {
+			return false
+		}
+		matched, _ := regexcache.MatchString(pattern, text)
+		return matched
+	},
+}
+
+// jinjaMethods are the Python methods templates may call on values
+var jinjaMethods = map[string]jinjaMethod{
+	"items": methodItems,
+	"keys": func(value interface{}, _ []interface{}) (interface{}, error) {
+		m, ok := jinjaMap(value)
+		if !ok {
+			return nil, fmt.Errorf("not a mapping")
+		}
+		keys := sortedMapKeys(m)
+		items := make([]interface{}, len(keys))
+		for i, key := range keys {
+			items[i] = key
+		}
+		return items, nil
+	},
+	"values": func(value interface{}, _ []interface{}) (interface{}, error) {
+		m, ok := jinjaMap(value)
+		if !ok {
+			return nil, fmt.Errorf("not a mapping")
+		}
+		keys := sortedMapKeys(m)
+		items := make([]interface{}, len(keys))
+		for i, key := range keys {
+			items[i] = m[key]
+		}
+		return items, nil
+	},
+	"get": func(value interface{}, args []interface{}) (interface{}, error) {
+		m, ok := jinjaMap(value)
+		if !ok {
+			return nil, fmt.Errorf("not a mapping")
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("missing key argument")
+		}
+		if item, found := m[jinjaString(args[0])]; found {
+			return item, nil
+		}
+		if len(args) > 1 {
+			return args[1], nil
+		}
+		return nil, nil
+	},
+	"upper": stringMethod(func(s string, _ []interface{}) interface{} {
+		return strings.ToUpper(s)
+	}),
+	"lower": stringMethod(func(s string, _ []interface{}) interface{} {
+		return strings.ToLower(s)
+	}),
+	"strip": stringMethod(func(s string, args []interface{}) interface{} {
+		if len(args) > 0 {
+			return strings.Trim(s, jinjaString(args[0]))
+		}
+		return strings.TrimSpace(s)
+	}),
+	"startswith": stringMethod(func(s string, args []interface{}) interface{} {
+		return len(args) > 0 && strings.HasPrefix(s, jinjaString(args[0]))
+	}),
+	"endswith": stringMethod(func(s string, args []interface{}) interface{} {
+		return len(args) > 0 && strings.HasSuffix(s, jinjaString(args[0]))
+	}),
+	"split": func(value interface{}, args []interface{}) (interface{}, error) {
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("not a string")
+		}
+		return jinjaFilters["split"](value, jinjaArgs{pos: args})
+	},
+	"replace": func(value interface{}, args []interface{}) (interface{}, error) {
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("not a string")
+		}
+		return jinjaFilters["replace"](value, jinjaArgs{pos: args})
+	},
+}
+
+// stringMethod adapts a string function to a method
+func stringMethod(fn func(string, []interface{}) interface{}) jinjaMethod {
+	return func(value interface{}, args []interface{}) (interface{}, error) {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("not a string")
+		}
+		return fn(s, args), nil
+	}
+}
+
+// methodItems returns the key, value pairs of a mapping sorted by key
+func methodItems(value interface{}, _ []interface{}) (interface{}, error) {
+	m, ok := jinjaMap(value)
+	if !ok {
+		return nil, fmt.Errorf("not a mapping")
+	}
+	keys := sortedMapKeys(m)
+	items := make([]interface{}, len(keys))
+	for i, key := range keys {
+		items[i] = []interface{}{key, m[key]}
+	}
+	return items, nil
+}
+
+// jinjaFunctions are the global functions available to templates
+var jinjaFunctions = map[string]jinjaFunction{
+	// range(stop) or range(start, stop[, step]), as in Python
+	"range": func(args []interface{}) (interface{}, error) {
+		bounds := make([]int, len(args))
+		for i, arg := range args {
+			n, ok := jinjaNumber(arg)
+			if !ok {
+				return nil, fmt.Errorf("arguments must be numbers")
+			}
+			bounds[i] = int(n)
+		}
+		start, stop, step := 0, 0, 1
+		switch len(bounds) {
+		case 1:
+			stop = bounds[0]
+		case 2:
+			start, stop = bounds[0], bounds[1]
+		case 3:
+			start, stop, step = bounds[0], bounds[1], bounds[2]
+		default:
+			return nil, fmt.Errorf("expected 1 to 3 arguments, got %d", len(args))
+		}
+		if step == 0 {
+			return nil, fmt.Errorf("step must not be zero")
+		}
+		var items []interface{}
+		for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+			if len(items) == maxRangeItems {
+				return nil, fmt.Errorf("range exceeds %d items", maxRangeItems)
+			}
+			items = append(items, float64(i))
+		}
+		return items, nil
+	},
+}
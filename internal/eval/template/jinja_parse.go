@@ -0,0 +1,860 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Segment kinds of a Jinja template
+const (
+	segText = iota
+	segOutput
+	segBlock
+)
+
+// jinjaSegment is literal text, an {{ output }} or a {% block %} tag
+type jinjaSegment struct {
+	kind int
+	text string
+	line int
+}
+
+// rawEnd matches the tag closing a {% raw %} section
+var rawEnd = regexp.MustCompile(`\{%-?\s*endraw\s*-?%\}`)
+
+// jinjaDelims maps opening tag delimiters to their closing ones
+var jinjaDelims = map[string]string{"{{": "}}", "{%": "%}", "{#": "#}"}
+
+// lexJinja splits a template into segments, dropping comments and applying
+// the "-" whitespace control of tags
+func lexJinja(src string) ([]jinjaSegment, error) {
+	var segs []jinjaSegment
+	line := 1
+	trimNext := false
+
+	addText := func(text string, trimPrev bool) {
+		if trimNext {
+			text = strings.TrimLeft(text, " \t\r\n")
+		}
+		if trimPrev {
+			text = strings.TrimRight(text, " \t\r\n")
+		}
+		if text != "" {
+			segs = append(segs, jinjaSegment{kind: segText, text: text, line: line})
+		}
+	}
+
+	for {
+		i := indexTag(src)
+		if i < 0 {
+			addText(src, false)
+			return segs, nil
+		}
+
+		open := src[i : i+2]
+		rest := src[i+2:]
+		trimPrev := strings.HasPrefix(rest, "-")
+		if trimPrev {
+			rest = rest[1:]
+		}
+		end := strings.Index(rest, jinjaDelims[open])
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unclosed %s tag", line+strings.Count(src[:i], "\n"), open)
+		}
+		inner := rest[:end]
+		trimAfter := strings.HasSuffix(inner, "-")
+		if trimAfter {
+			inner = inner[:len(inner)-1]
+		}
+
+		addText(src[:i], trimPrev)
+		line += strings.Count(src[:i], "\n")
+		tagLine := line
+		consumed := len(src) - len(rest) + end + 2
+		line += strings.Count(src[i:consumed], "\n")
+		src = src[consumed:]
+		trimNext = trimAfter
+
+		switch {
+		case open == "{#":
+			continue
+		case open == "{{":
+			segs = append(segs, jinjaSegment{kind: segOutput, text: inner, line: tagLine})
+		case strings.TrimSpace(inner) == "raw":
+			loc := rawEnd.FindStringIndex(src)
+			if loc == nil {
+				return nil, fmt.Errorf("line %d: unclosed raw block", tagLine)
+			}
+			endTag := src[loc[0]:loc[1]]
+			addText(src[:loc[0]], strings.HasPrefix(endTag, "{%-"))
+			line += strings.Count(src[:loc[1]], "\n")
+			src = src[loc[1]:]
+			trimNext = strings.HasSuffix(endTag, "-%}")
+		default:
+			segs = append(segs, jinjaSegment{kind: segBlock, text: inner, line: tagLine})
+		}
+	}
+}
+
+// indexTag returns the index of the first opening tag delimiter, or -1
+func indexTag(src string) int {
+	offset := 0
+	for {
+		i := strings.IndexByte(src[offset:], '{')
+		if i < 0 || offset+i+1 >= len(src) {
+			return -1
+		}
+		offset += i
+		if _, ok := jinjaDelims[src[offset:offset+2]]; ok {
+			return offset
+		}
+		offset++
+	}
+}
+
+// Jinja AST nodes
+type (
+	jinjaNode interface{}
+
+	textNode struct {
+		text string
+	}
+
+	outputNode struct {
+		expr jinjaExpr
+		line int
+	}
+
+	ifNode struct {
+		conds    []jinjaExpr
+		bodies   [][]jinjaNode
+		elseBody []jinjaNode
+	}
+
+	forNode struct {
+		vars     []string
+		iter     jinjaExpr
+		filter   jinjaExpr
+		body     []jinjaNode
+		elseBody []jinjaNode
+		line     int
+	}
+
+	setNode struct {
+		name string
+		expr jinjaExpr
+		line int
+	}
+)
+
+// jinjaParser builds the AST of a segmented template
+type jinjaParser struct {
+	segs []jinjaSegment
+	pos  int
+}
+
+// parseJinja parses a template into its AST
+func parseJinja(src string) ([]jinjaNode, error) {
+	segs, err := lexJinja(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &jinjaParser{segs: segs}
+	nodes, _, _, err := p.parseBody()
+	return nodes, err
+}
+
+// parseBody parses nodes until one of the ends tags, returning the name of
+// the tag that ended the body and a parser over its remaining tokens. With
+// no ends the body runs to the end of the template.
+func (p *jinjaParser) parseBody(ends ...string) ([]jinjaNode, string, *exprParser, error) {
+	var nodes []jinjaNode
+	for p.pos < len(p.segs) {
+		seg := p.segs[p.pos]
+		p.pos++
+
+		switch seg.kind {
+		case segText:
+			nodes = append(nodes, &textNode{text: seg.text})
+		case segOutput:
+			ep, err := newExprParser(seg.text, seg.line)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			expr, err := ep.parseExpr()
+			if err == nil {
+				err = ep.expectEnd()
+			}
+			if err != nil {
+				return nil, "", nil, err
+			}
+			nodes = append(nodes, &outputNode{expr: expr, line: seg.line})
+		case segBlock:
+			ep, err := newExprParser(seg.text, seg.line)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			tag := ep.next()
+			if tag.kind != tokName {
+				return nil, "", nil, ep.errorf("expected a tag name")
+			}
+			if slices.Contains(ends, tag.val) {
+				return nodes, tag.val, ep, nil
+			}
+			node, err := p.parseTag(tag.val, ep)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	if len(ends) > 0 {
+		return nil, "", nil, fmt.Errorf("unexpected end of template, expected {%% %s %%}", ends[len(ends)-1])
+	}
+	return nodes, "", nil, nil
+}
+
+// parseTag parses the block tag named tag
+func (p *jinjaParser) parseTag(tag string, ep *exprParser) (jinjaNode, error) {
+	switch tag {
+	case "if":
+		return p.parseIf(ep)
+	case "for":
+		return p.parseFor(ep)
+	case "set":
+		name := ep.next()
+		if name.kind != tokName || jinjaKeywords[name.val] {
+			return nil, ep.errorf("expected a variable name after set")
+		}
+		if err := ep.expectOp("="); err != nil {
+			return nil, err
+		}
+		expr, err := ep.parseExpr()
+		if err == nil {
+			err = ep.expectEnd()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &setNode{name: name.val, expr: expr, line: ep.line}, nil
+	case "elif", "else", "endif", "endfor":
+		return nil, ep.errorf("unexpected {%% %s %%}", tag)
+	default:
+		return nil, ep.errorf("unsupported tag %q", tag)
+	}
+}
+
+// parseIf parses an if tag through its endif
+func (p *jinjaParser) parseIf(ep *exprParser) (jinjaNode, error) {
+	node := &ifNode{}
+	for {
+		cond, err := ep.parseExpr()
+		if err == nil {
+			err = ep.expectEnd()
+		}
+		if err != nil {
+			return nil, err
+		}
+		body, end, next, err := p.parseBody("elif", "else", "endif")
+		if err != nil {
+			return nil, err
+		}
+		node.conds = append(node.conds, cond)
+		node.bodies = append(node.bodies, body)
+
+		switch end {
+		case "elif":
+			ep = next
+			continue
+		case "else":
+			if err := next.expectEnd(); err != nil {
+				return nil, err
+			}
+			node.elseBody, _, next, err = p.parseBody("endif")
+			if err != nil {
+				return nil, err
+			}
+		}
+		return node, next.expectEnd()
+	}
+}
+
+// parseFor parses a for tag through its endfor
+func (p *jinjaParser) parseFor(ep *exprParser) (jinjaNode, error) {
+	node := &forNode{line: ep.line}
+	for {
+		name := ep.next()
+		if name.kind != tokName || jinjaKeywords[name.val] {
+			return nil, ep.errorf("expected a loop variable name")
+		}
+		node.vars = append(node.vars, name.val)
+		if !ep.acceptOp(",") {
+			break
+		}
+	}
+	if len(node.vars) > 2 {
+		return nil, ep.errorf("for loops unpack at most two variables")
+	}
+	if !ep.acceptName("in") {
+		return nil, ep.errorf("expected 'in' in for loop")
+	}
+
+	var err error
+	if node.iter, err = ep.parseOr(); err != nil {
+		return nil, err
+	}
+	if ep.acceptName("if") {
+		if node.filter, err = ep.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if err := ep.expectEnd(); err != nil {
+		return nil, err
+	}
+
+	body, end, next, err := p.parseBody("else", "endfor")
+	if err != nil {
+		return nil, err
+	}
+	node.body = body
+	if end == "else" {
+		if err := next.expectEnd(); err != nil {
+			return nil, err
+		}
+		node.elseBody, _, next, err = p.parseBody("endfor")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, next.expectEnd()
+}
+
+// Expression token kinds
+const (
+	tokEOF = iota
+	tokName
+	tokString
+	tokNumber
+	tokOp
+)
+
+// exprToken is a token of a Jinja expression
+type exprToken struct {
+	kind int
+	val  string
+}
+
+// jinjaKeywords may not be used as variable names
+var jinjaKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "is": true,
+	"if": true, "else": true,
+}
+
+// jinjaOps are the operators, longest first
+var jinjaOps = []string{
+	"==", "!=", "<=", ">=", "//",
+	"<", ">", "+", "-", "*", "/", "%", "~",
+	"(", ")", "[", "]", ",", ".", "|", "=", ":",
+}
+
+// tokenizeExpr splits an expression into tokens
+func tokenizeExpr(src string, line int) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || isLetter(c):
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || isLetter(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			tokens = append(tokens, exprToken{tokName, src[i:j]})
+			i = j
+		case isDigit(c):
+			j := i + 1
+			for j < len(src) && isDigit(src[j]) {
+				j++
+			}
+			if j+1 < len(src) && src[j] == '.' && isDigit(src[j+1]) {
+				j += 2
+				for j < len(src) && isDigit(src[j]) {
+					j++
+				}
+			}
+			tokens = append(tokens, exprToken{tokNumber, src[i:j]})
+			i = j
+		case c == '"' || c == '\'':
+			str, n, err := unquoteJinja(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			tokens = append(tokens, exprToken{tokString, str})
+			i += n
+		default:
+			op := ""
+			for _, candidate := range jinjaOps {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+			}
+			tokens = append(tokens, exprToken{tokOp, op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// unquoteJinja reads the string literal at the start of src, returning its
+// value and length
+func unquoteJinja(src string) (string, int, error) {
+	quote := src[0]
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// exprParser is a recursive descent parser of Jinja expressions. Operator
+// precedence, lowest first: inline if, or, and, not, comparisons and in,
+// ~, + and -, * / // and %, unary minus, then filters and tests.
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	line   int
+}
+
+// newExprParser tokenizes an expression found at line
+func newExprParser(src string, line int) (*exprParser, error) {
+	tokens, err := tokenizeExpr(src, line)
+	if err != nil {
+		return nil, err
+	}
+	return &exprParser{tokens: tokens, line: line}, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return exprToken{kind: tokEOF}
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.peek()
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// acceptOp consumes the operator op if it is next
+func (p *exprParser) acceptOp(op string) bool {
+	if tok := p.peek(); tok.kind == tokOp && tok.val == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// acceptName consumes the name or keyword name if it is next
+func (p *exprParser) acceptName(name string) bool {
+	if tok := p.peek(); tok.kind == tokName && tok.val == name {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		return p.errorf("expected %q", op)
+	}
+	return nil
+}
+
+// expectEnd reports tokens left over after an expression or tag
+func (p *exprParser) expectEnd() error {
+	if tok := p.peek(); tok.kind != tokEOF {
+		return p.errorf("unexpected %q", tok.val)
+	}
+	return nil
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// parseExpr parses a full expression, including inline if
+func (p *exprParser) parseExpr() (jinjaExpr, error) {
+	expr, err := p.parseOr()
+	if err != nil || !p.acceptName("if") {
+		return expr, err
+	}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	var otherwise jinjaExpr = &literalExpr{value: undefined{}}
+	if p.acceptName("else") {
+		if otherwise, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	return &condExpr{cond: cond, then: expr, otherwise: otherwise}, nil
+}
+
+func (p *exprParser) parseOr() (jinjaExpr, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.acceptName("or") {
+		var right jinjaExpr
+		if right, err = p.parseAnd(); err == nil {
+			left = &binaryExpr{op: "or", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (jinjaExpr, error) {
+	left, err := p.parseNot()
+	for err == nil && p.acceptName("and") {
+		var right jinjaExpr
+		if right, err = p.parseNot(); err == nil {
+			left = &binaryExpr{op: "and", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseNot() (jinjaExpr, error) {
+	if p.acceptName("not") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "not", expr: expr}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (jinjaExpr, error) {
+	left, err := p.parseConcat()
+	for err == nil {
+		op := ""
+		tok := p.peek()
+		switch {
+		case tok.kind == tokOp && slices.Contains([]string{"==", "!=", "<", ">", "<=", ">="}, tok.val):
+			op = tok.val
+			p.pos++
+		case tok.kind == tokName && tok.val == "in":
+			op = "in"
+			p.pos++
+		case tok.kind == tokName && tok.val == "not" && p.pos+1 < len(p.tokens) &&
+			p.tokens[p.pos+1].kind == tokName && p.tokens[p.pos+1].val == "in":
+			op = "not in"
+			p.pos += 2
+		default:
+			return left, nil
+		}
+		var right jinjaExpr
+		if right, err = p.parseConcat(); err == nil {
+			left = &binaryExpr{op: op, left: left, right: right}
+		}
+	}
+	return nil, err
+}
+
+func (p *exprParser) parseConcat() (jinjaExpr, error) {
+	left, err := p.parseAdditive()
+	for err == nil && p.acceptOp("~") {
+		var right jinjaExpr
+		if right, err = p.parseAdditive(); err == nil {
+			left = &binaryExpr{op: "~", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAdditive() (jinjaExpr, error) {
+	left, err := p.parseMultiplicative()
+	for err == nil {
+		tok := p.peek()
+		if tok.kind != tokOp || (tok.val != "+" && tok.val != "-") {
+			return left, nil
+		}
+		p.pos++
+		var right jinjaExpr
+		if right, err = p.parseMultiplicative(); err == nil {
+			left = &binaryExpr{op: tok.val, left: left, right: right}
+		}
+	}
+	return nil, err
+}
+
+func (p *exprParser) parseMultiplicative() (jinjaExpr, error) {
+	left, err := p.parseUnary()
+	for err == nil {
+		tok := p.peek()
+		if tok.kind != tokOp || !slices.Contains([]string{"*", "/", "//", "%"}, tok.val) {
+			return left, nil
+		}
+		p.pos++
+		var right jinjaExpr
+		if right, err = p.parseUnary(); err == nil {
+			left = &binaryExpr{op: tok.val, left: left, right: right}
+		}
+	}
+	return nil, err
+}
+
+// parseUnary parses unary minus and the filters and tests applied to it.
+// As in Jinja, filters apply to the negated operand: -4 | abs is 4.
+func (p *exprParser) parseUnary() (jinjaExpr, error) {
+	expr, err := p.parseNegation()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.acceptOp("|"):
+			name := p.next()
+			if name.kind != tokName {
+				return nil, p.errorf("expected a filter name")
+			}
+			filter, ok := jinjaFilters[name.val]
+			if !ok {
+				return nil, p.errorf("unknown filter %q", name.val)
+			}
+			node := &filterExpr{expr: expr, name: name.val, filter: filter}
+			if p.acceptOp("(") {
+				if node.args, node.kwargs, err = p.parseArgs(); err != nil {
+					return nil, err
+				}
+			}
+			expr = node
+		case p.acceptName("is"):
+			negate := p.acceptName("not")
+			name := p.next()
+			test, ok := jinjaTests[strings.ToLower(name.val)]
+			if name.kind != tokName || !ok {
+				return nil, p.errorf("unknown test %q", name.val)
+			}
+			node := &testExpr{expr: expr, name: name.val, test: test, negate: negate}
+			if p.acceptOp("(") {
+				if node.args, _, err = p.parseArgs(); err != nil {
+					return nil, err
+				}
+			}
+			expr = node
+		default:
+			return expr, nil
+		}
+	}
+}
+
+// parseNegation parses unary minus applied to a postfix expression
+func (p *exprParser) parseNegation() (jinjaExpr, error) {
+	if !p.acceptOp("-") {
+		return p.parsePostfix()
+	}
+	expr, err := p.parseNegation()
+	if err != nil {
+		return nil, err
+	}
+	return &unaryExpr{op: "-", expr: expr}, nil
+}
+
+// parsePostfix parses a primary expression with attribute, subscript and
+// method call suffixes
+func (p *exprParser) parsePostfix() (jinjaExpr, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.acceptOp("."):
+			name := p.next()
+			if name.kind != tokName && name.kind != tokNumber {
+				return nil, p.errorf("expected an attribute name after '.'")
+			}
+			if name.kind == tokName && p.acceptOp("(") {
+				method, ok := jinjaMethods[name.val]
+				if !ok {
+					return nil, p.errorf("unknown method %q", name.val)
+				}
+				args, _, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				expr = &methodExpr{expr: expr, name: name.val, method: method, args: args}
+				continue
+			}
+			expr = &indexExpr{expr: expr, index: &literalExpr{value: attrKey(name)}}
+		case p.acceptOp("["):
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			expr = &indexExpr{expr: expr, index: index}
+		default:
+			return expr, nil
+		}
+	}
+}
+
+// attrKey returns the lookup key of an attribute token; numeric attributes
+// such as items.0 index arrays
+func attrKey(tok exprToken) interface{} {
+	if tok.kind == tokNumber {
+		n, _ := strconv.ParseFloat(tok.val, 64)
+		return n
+	}
+	return tok.val
+}
+
+func (p *exprParser) parsePrimary() (jinjaExpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		return &literalExpr{value: tok.val}, nil
+	case tokNumber:
+		n, err := strconv.ParseFloat(tok.val, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok.val)
+		}
+		return &literalExpr{value: n}, nil
+	case tokName:
+		switch tok.val {
+		case "true", "True":
+			return &literalExpr{value: true}, nil
+		case "false", "False":
+			return &literalExpr{value: false}, nil
+		case "none", "None":
+			return &literalExpr{value: nil}, nil
+		}
+		if jinjaKeywords[tok.val] {
+			return nil, p.errorf("unexpected %q", tok.val)
+		}
+		if p.acceptOp("(") {
+			fn, ok := jinjaFunctions[tok.val]
+			if !ok {
+				return nil, p.errorf("unknown function %q", tok.val)
+			}
+			args, _, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return &callExpr{name: tok.val, fn: fn, args: args}, nil
+		}
+		return &nameExpr{name: tok.val}, nil
+	case tokOp:
+		switch tok.val {
+		case "(":
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expectOp(")")
+		case "[":
+			list := &listExpr{}
+			for !p.acceptOp("]") {
+				if len(list.items) > 0 {
+					if err := p.expectOp(","); err != nil {
+						return nil, err
+					}
+					if p.acceptOp("]") {
+						break
+					}
+				}
+				item, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+			}
+			return list, nil
+		}
+	case tokEOF:
+		return nil, p.errorf("unexpected end of expression")
+	}
+	return nil, p.errorf("unexpected %q", tok.val)
+}
+
+// parseArgs parses call arguments after the opening parenthesis, through
+// the closing one. Keyword arguments follow positional ones.
+func (p *exprParser) parseArgs() ([]jinjaExpr, map[string]jinjaExpr, error) {
+	var args []jinjaExpr
+	var kwargs map[string]jinjaExpr
+	for first := true; !p.acceptOp(")"); first = false {
+		if !first {
+			if err := p.expectOp(","); err != nil {
+				return nil, nil, err
+			}
+			if p.acceptOp(")") {
+				break
+			}
+		}
+		if tok := p.peek(); tok.kind == tokName && p.pos+1 < len(p.tokens) &&
+			p.tokens[p.pos+1].kind == tokOp && p.tokens[p.pos+1].val == "=" {
+			p.pos += 2
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, nil, err
+			}
+			if kwargs == nil {
+				kwargs = make(map[string]jinjaExpr)
+			}
+			kwargs[tok.val] = value
+			continue
+		}
+		if kwargs != nil {
+			return nil, nil, p.errorf("positional argument after keyword argument")
+		}
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, kwargs, nil
+}
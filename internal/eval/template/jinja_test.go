@@ -0,0 +1,222 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+// jinjaData is the data rendered by the Jinja tests
+var jinjaData = map[string]interface{}{
+	"state": map[string]interface{}{
+		"inputs": map[string]interface{}{
+			"priority": "high",
+			"message":  "  Refund my order  ",
+			"amount":   42.5,
+			"count":    3,
+			"tags":     []interface{}{"billing", "urgent"},
+			"items": []interface{}{
+				map[string]interface{}{"sku": "a-1", "qty": 2},
+				map[string]interface{}{"sku": "b-2", "qty": 1},
+			},
+			"meta":  map[string]interface{}{"channel": "email", "locale": "en"},
+			"empty": []interface{}{},
+		},
+	},
+}
+
+func TestJinjaRender(t *testing.T) {
+	engine := NewJinjaEngine()
+	cases := []struct {
+		name, template, want string
+	}{
+		{"text", "plain text", "plain text"},
+		{"output", "{{ state.inputs.priority }}", "high"},
+		{"subscript", `{{ state["inputs"]["meta"]["channel"] }}`, "email"},
+		{"numeric attribute", "{{ state.inputs.items.0.sku }}", "a-1"},
+		{"negative index", "{{ state.inputs.tags[-1] }}", "urgent"},
+		{"undefined", "[{{ state.inputs.missing.deeper }}]", "[]"},
+		{"none", "{{ none }}", "None"},
+		{"booleans", "{{ true }} {{ false }}", "True False"},
+		{"whole number", "{{ state.inputs.count }}", "3"},
+		{"float", "{{ state.inputs.amount }}", "42.5"},
+		{"list", "{{ state.inputs.tags }}", "['billing', 'urgent']"},
+		{"mapping", "{{ state.inputs.meta }}", "{'channel': 'email', 'locale': 'en'}"},
+		{"comment", "a{# ignored #}b", "ab"},
+		{"raw", "{% raw %}{{ kept }}{% endraw %}", "{{ kept }}"},
+		{"whitespace control", "a  {{- 'b' -}}  c", "abc"},
+		{"block whitespace control", "a\n{%- if true -%}\nb\n{%- endif -%}\nc", "abc"},
+
+		{"arithmetic", "{{ 7 + 3 * 2 - 1 }}", "12"},
+		{"division", "{{ 7 / 2 }} {{ 7 // 2 }} {{ -7 % 3 }}", "3.5 3 2"},
+		{"unary minus", "{{ -state.inputs.count }}", "-3"},
+		{"concat", "{{ state.inputs.priority ~ '-' ~ state.inputs.count }}", "high-3"},
+		{"string plus", "{{ 'a' + 'b' }}", "ab"},
+		{"list plus", "{{ [1] + [2] }}", "[1, 2]"},
+		{"repeat", "{{ 'ab' * 3 }}", "ababab"},
+		{"comparison", "{{ state.inputs.amount > 40 and state.inputs.count <= 3 }}", "True"},
+		{"string comparison", "{{ 'a' < 'b' }}", "True"},
+		{"mixed equality", "{{ state.inputs.count == 3.0 }}", "True"},
+		{"in list", "{{ 'urgent' in state.inputs.tags }}", "True"},
+		{"not in", "{{ 'spam' not in state.inputs.tags }}", "True"},
+		{"in string", "{{ 'fund' in state.inputs.message }}", "True"},
+		{"in mapping", "{{ 'channel' in state.inputs.meta }}", "True"},
+		{"or returns operand", "{{ state.inputs.missing or 'fallback' }}", "fallback"},
+		{"and returns operand", "{{ state.inputs.priority and 'yes' }}", "yes"},
+		{"not", "{{ not state.inputs.empty }}", "True"},
+		{"inline if", "{{ 'big' if state.inputs.amount > 10 else 'small' }}", "big"},
+		{"inline if without else", "[{{ 'x' if false }}]", "[]"},
+
+		{"if", "{% if state.inputs.priority == 'high' %}urgent{% endif %}", "urgent"},
+		{"elif", "{% if state.inputs.count > 5 %}many{% elif state.inputs.count > 1 %}some{% else %}one{% endif %}", "some"},
+		{"else", "{% if state.inputs.empty %}items{% else %}none{% endif %}", "none"},
+		{"for", "{% for tag in state.inputs.tags %}{{ tag }};{% endfor %}", "billing;urgent;"},
+		{"for else", "{% for tag in state.inputs.empty %}{{ tag }}{% else %}no tags{% endfor %}", "no tags"},
+		{"for filter", "{% for item in state.inputs.items if item.qty > 1 %}{{ item.sku }}{% endfor %}", "a-1"},
+		{"for unpack", "{% for key, value in state.inputs.meta.items() %}{{ key }}={{ value }} {% endfor %}", "channel=email locale=en "},
+		{"for mapping keys", "{% for key in state.inputs.meta %}{{ key }} {% endfor %}", "channel locale "},
+		{"loop variables", "{% for tag in state.inputs.tags %}{{ loop.index }}/{{ loop.length }}{% if not loop.last %},{% endif %}{% endfor %}", "1/2,2/2"},
+		{"nested loops", "{% for i in range(2) %}{% for j in range(2) %}{{ i }}{{ j }} {% endfor %}{% endfor %}", "00 01 10 11 "},
+		{"set", "{% set total = state.inputs.count * 2 %}{{ total }}", "6"},
+		{"loop scope", "{% set x = 1 %}{% for i in range(3) %}{% set x = i %}{% endfor %}{{ x }}", "1"},
+		{"range step", "{{ range(10, 0, -3) | join(',') }}", "10,7,4,1"},
+
+		{"upper", "{{ state.inputs.priority | upper }}", "HIGH"},
+		{"title", "{{ 'hello big-world' | title }}", "Hello Big-World"},
+		{"capitalize", "{{ 'hELLO' | capitalize }}", "Hello"},
+		{"trim", "[{{ state.inputs.message | trim }}]", "[Refund my order]"},
+		{"trim chars", "{{ 'xxaxx' | trim('x') }}", "a"},
+		{"default", "{{ state.inputs.missing | default('n/a') }}", "n/a"},
+		{"default defined", "{{ state.inputs.priority | d('n/a') }}", "high"},
+		{"default boolean", "{{ '' | default('empty', true) }}", "empty"},
+		{"join", "{{ state.inputs.tags | join(', ') }}", "billing, urgent"},
+		{"length", "{{ state.inputs.tags | length }} {{ 'héllo' | count }}", "2 5"},
+		{"tojson", "{{ state.inputs.meta | tojson }}", `{"channel":"email","locale":"en"}`},
+		{"tojson undefined", "{{ state.inputs.missing | tojson }}", "null"},
+		{"first last", "{{ state.inputs.tags | first }} {{ state.inputs.tags | last }}", "billing urgent"},
+		{"truncate", "{{ 'the quick brown fox jumps' | truncate(12, leeway=0) }}", "the..."},
+		{"truncate killwords", "{{ 'the quick brown fox jumps' | truncate(12, true, '!', 0) }}", "the quick b!"},
+		{"truncate within leeway", "{{ 'short text' | truncate(8) }}", "short text"},
+		{"round", "{{ 2.567 | round(2) }} {{ 2.5 | round(0, 'floor') }}", "2.57 2"},
+		{"int float", "{{ '42' | int + 1 }} {{ 'x' | float(1.5) }}", "43 1.5"},
+		{"abs", "{{ -4 | abs }}", "4"},
+		{"replace", "{{ 'a-b-c' | replace('-', '+', 1) }}", "a+b-c"},
+		{"split", "{{ 'a,b,c' | split(',') | length }}", "3"},
+		{"reverse", "{{ 'abc' | reverse }} {{ [1, 2, 3] | reverse | join }}", "cba 321"},
+		{"sort", "{{ ['b', 'A', 'c'] | sort | join }}", "Abc"},
+		{"sort reverse", "{{ [3, 1, 2] | sort(reverse=true) | join }}", "321"},
+		{"wordcount", "{{ state.inputs.message | wordcount }}", "3"},
+		{"indent", "{{ 'a\nb' | indent(2) }}", "a\n  b"},
+		{"escape", "{{ '<b>' | e }} {{ '<b>' | safe }}", "&lt;b&gt; <b>"},
+		{"chained filters", "{{ state.inputs.message | trim | lower | replace(' ', '_') }}", "refund_my_order"},
+
+		{"test defined", "{{ state.inputs.priority is defined }} {{ state.inputs.missing is defined }}", "True False"},
+		{"test not", "{{ state.inputs.missing is not defined }}", "True"},
+		{"test types", "{{ state.inputs.count is number }} {{ state.inputs.meta is mapping }} {{ state.inputs.tags is sequence }}", "True True True"},
+		{"test parity", "{{ 4 is even }} {{ 3 is odd }} {{ 9 is divisibleby(3) }}", "True True True"},
+		{"test regex", `{{ state.inputs.message is regex("(?i)refund") }}`, "True"},
+
+		{"method get", "{{ state.inputs.meta.get('missing', 'none') }}", "none"},
+		{"method keys", "{{ state.inputs.meta.keys() | join(',') }}", "channel,locale"},
+		{"method strings", "{{ state.inputs.priority.upper() }} {{ state.inputs.priority.startswith('hi') }}", "HIGH True"},
+		{"method split", "{{ 'a b'.split() | length }}", "2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := engine.Render(tc.template, jinjaData)
+			if err != nil {
+				t.Fatalf("render %q: %v", tc.template, err)
+			}
+			if got != tc.want {
+				t.Fatalf("render %q: expected %q, got %q", tc.template, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestJinjaParseErrors(t *testing.T) {
+	engine := NewJinjaEngine()
+	cases := map[string]string{
+		"{{ state.inputs":                            "unclosed {{ tag",
+		"{% if true %}never closed":                  "expected {% endif %}",
+		"{% for x in y %}never closed":               "expected {% endfor %}",
+		"{% endif %}":                                "unexpected {% endif %}",
+		"{% macro m() %}{% endmacro %}":              `unsupported tag "macro"`,
+		"{{ x | nosuchfilter }}":                     `unknown filter "nosuchfilter"`,
+		"{{ x is nosuchtest }}":                      `unknown test "nosuchtest"`,
+		"{{ x.nosuchmethod() }}":                     `unknown method "nosuchmethod"`,
+		"{{ nosuchfunction() }}":                     `unknown function "nosuchfunction"`,
+		"{{ 'unterminated }}":                        "unterminated string literal",
+		"{{ 1 + }}":                                  "unexpected end of expression",
+		"{{ a b }}":                                  `unexpected "b"`,
+		"{{ x @ y }}":                                "unexpected character",
+		"{% set = 1 %}":                              "expected a variable name after set",
+		"{% for a, b, c in x %}{% endfor %}":         "at most two variables",
+		"{% for x y %}{% endfor %}":                  "expected 'in' in for loop",
+		"{{ f(a=1, 2) }}":                            "unknown function",
+		"{{ x | default(a=1, 2) }}":                  "positional argument after keyword argument",
+		"{% raw %}never closed":                      "unclosed raw block",
+		"line one\nline two\n{{ x | nosuchfilter }}": "line 3:",
+	}
+	for template, want := range cases {
+		err := engine.ValidateTemplate(template)
+		if err == nil {
+			t.Errorf("%q: expected a parse error", template)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", template, want, err)
+		}
+		if _, renderErr := engine.Render(template, jinjaData); renderErr == nil {
+			t.Errorf("%q: render succeeded on an invalid template", template)
+		}
+	}
+}
+
+func TestJinjaRenderErrors(t *testing.T) {
+	engine := NewJinjaEngine()
+	cases := map[string]string{
+		"{{ 1 / 0 }}":                                     "division by zero",
+		"{{ state.inputs.tags[1.5] }}":                    "indices must be integers",
+		"{{ 'a' < 1 }}":                                   "cannot compare string and number",
+		"{{ state.inputs.meta - 1 }}":                     "unsupported operand types",
+		"{{ 'x' * 10000000 }}":                            "exceeds",
+		"{{ 'x' | indent(10000000) }}":                    "exceeds",
+		"{{ range(1000000) | length }}":                   "range exceeds",
+		"{{ range(1, 5, 0) }}":                            "step must not be zero",
+		"{{ 1 in 2 }}":                                    "is not a container",
+		"{% for x in 3 %}{% endfor %}":                    "is not iterable",
+		"{% for a, b in state.inputs.tags %}{% endfor %}": "cannot unpack",
+		"{{ 2.5 | round(0, 'bankers') }}":                 "unknown rounding method",
+		"{{ state.inputs.count.upper() }}":                "not a string",
+	}
+	for template, want := range cases {
+		if err := engine.ValidateTemplate(template); err != nil {
+			t.Errorf("%q: unexpected parse error %v", template, err)
+			continue
+		}
+		_, err := engine.Render(template, jinjaData)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", template, want, err)
+		}
+	}
+}
+
+func TestJinjaTemplateCache(t *testing.T) {
+	engine := NewJinjaEngine()
+	for i := 0; i < 3; i++ {
+		if _, err := engine.Render("{{ state.inputs.priority }}", jinjaData); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := engine.Render("{{ bad | nosuchfilter }}", jinjaData); err == nil {
+		t.Fatal("expected a parse error")
+	}
+
+	stats := engine.cache.Stats()
+	if stats.Entries != 1 || stats.Hits != 2 {
+		t.Fatalf("expected one cached template hit twice, got %+v", stats)
+	}
+	if stats.Capacity != templateCacheSize {
+		t.Fatalf("expected the cache bounded to %d templates, got %d", templateCacheSize, stats.Capacity)
+	}
+}
@@ -0,0 +1,237 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aymerick/raymond/ast"
+	"github.com/aymerick/raymond/parser"
+)
+
+// ErrSandboxViolation is the error class of templates rejected by the sandbox
+var ErrSandboxViolation = errors.New("template sandbox violation")
+
+// SandboxError describes why the sandbox rejected a template
+type SandboxError struct {
+	Reason string
+	Line   int
+}
+
+// Error returns the violation message
+func (e *SandboxError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s: line %d: %s", ErrSandboxViolation, e.Line, e.Reason)
+	}
+	return fmt.Sprintf("%s: %s", ErrSandboxViolation, e.Reason)
+}
+
+// Unwrap makes SandboxError match ErrSandboxViolation with errors.Is
+func (e *SandboxError) Unwrap() error {
+	return ErrSandboxViolation
+}
+
+// builtinHelpers are the block and expression helpers raymond provides
+var builtinHelpers = []string{"if", "unless", "each", "with", "lookup", "equal", "log"}
+
+// DefaultSandboxHelpers are the helpers allowed in sandboxed templates when
+// no explicit list is configured
+var DefaultSandboxHelpers = []string{
+	"if", "unless", "each", "with",
+	"uppercase", "lowercase", "trim", "default", "eq", "ne",
+	"gt", "lt", "contains", "join", "len",
+	"json", "slice", "first", "last", "truncate",
+	"add", "sub", "mul", "round", "formatDate", "replace", "split",
+	"regex",
+}
+
+// Sandbox restricts what untrusted templates may do. Partials are never
+// allowed in sandboxed templates.
+type Sandbox struct {
+	// AllowedHelpers lists the helpers templates may call; empty uses DefaultSandboxHelpers
+	AllowedHelpers []string
+	// DeniedPaths are dotted data paths templates may not read. They are
+	// rejected when referenced and removed from the data before rendering.
+	DeniedPaths []string
+	// MaxDepth bounds block nesting (#if, #each, ...); 0 means unlimited
+	MaxDepth int
+	// MaxLoopItems truncates arrays in the data so loops are bounded; 0 means unlimited
+	MaxLoopItems int
+	// MaxOutputBytes bounds the rendered output size; 0 means unlimited
+	MaxOutputBytes int
+}
+
+// check parses a template and reports the first sandbox violation. helpers
+// are the names of the helpers registered on the engine.
+func (s *Sandbox) check(templateStr string, helpers []string) error {
+	program, err := parser.Parse(templateStr)
+	if err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+
+	allowed := s.AllowedHelpers
+	if len(allowed) == 0 {
+		allowed = DefaultSandboxHelpers
+	}
+	c := &sandboxChecker{
+		sandbox: s,
+		allowed: toSet(allowed),
+		known:   toSet(append(append([]string(nil), helpers...), builtinHelpers...)),
+	}
+	return c.program(program, 0)
+}
+
+// sandboxChecker walks a template AST looking for violations
+type sandboxChecker struct {
+	sandbox *Sandbox
+	allowed map[string]bool
+	known   map[string]bool
+}
+
+// program checks every statement of a program at the given block depth
+func (c *sandboxChecker) program(program *ast.Program, depth int) error {
+	if program == nil {
+		return nil
+	}
+	for _, node := range program.Body {
+		if err := c.node(node, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// node checks a single AST node
+func (c *sandboxChecker) node(node ast.Node, depth int) error {
+	switch n := node.(type) {
+	case *ast.MustacheStatement:
+		return c.expression(n.Expression, n.Line, false)
+	case *ast.BlockStatement:
+		if c.sandbox.MaxDepth > 0 && depth+1 > c.sandbox.MaxDepth {
+			return &SandboxError{Reason: fmt.Sprintf("block nesting exceeds maximum depth %d", c.sandbox.MaxDepth), Line: n.Line}
+		}
+		if err := c.expression(n.Expression, n.Line, true); err != nil {
+			return err
+		}
+		if err := c.program(n.Program, depth+1); err != nil {
+			return err
+		}
+		return c.program(n.Inverse, depth+1)
+	case *ast.PartialStatement:
+		return &SandboxError{Reason: "partials are not allowed", Line: n.Line}
+	case *ast.SubExpression:
+		return c.expression(n.Expression, n.Line, true)
+	case *ast.Expression:
+		return c.expression(n, n.Line, false)
+	case *ast.PathExpression:
+		return c.path(n)
+	}
+	return nil
+}
+
+// expression checks the helper called by an expression and its arguments
+func (c *sandboxChecker) expression(expr *ast.Expression, line int, block bool) error {
+	if expr == nil {
+		return nil
+	}
+
+	name := expr.HelperName()
+	isCall := block || len(expr.Params) > 0 || expr.Hash != nil || c.known[name]
+	if name != "" && isCall {
+		if !c.allowed[name] {
+			return &SandboxError{Reason: fmt.Sprintf("helper '%s' is not allowed", name), Line: line}
+		}
+	} else if path, ok := expr.Path.(*ast.PathExpression); ok {
+		if err := c.path(path); err != nil {
+			return err
+		}
+	}
+
+	for _, param := range expr.Params {
+		if err := c.node(param, expr.Line); err != nil {
+			return err
+		}
+	}
+	if expr.Hash != nil {
+		for _, pair := range expr.Hash.Pairs {
+			if err := c.node(pair.Val, expr.Line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// path rejects references to denied data paths
+func (c *sandboxChecker) path(path *ast.PathExpression) error {
+	parts := path.Parts
+	if path.IsDataRoot() {
+		parts = parts[1:]
+	} else if path.Data {
+		return nil
+	}
+
+	ref := strings.Join(parts, ".")
+	for _, denied := range c.sandbox.DeniedPaths {
+		if ref == denied || strings.HasPrefix(ref, denied+".") {
+			return &SandboxError{Reason: fmt.Sprintf("path '%s' is not accessible", path.Original), Line: path.Line}
+		}
+	}
+	return nil
+}
+
+// sanitize returns a copy of data without denied paths and with arrays
+// truncated to MaxLoopItems. Relative references inside #with and #each
+// blocks cannot be checked statically, so denied values are removed as well.
+func (s *Sandbox) sanitize(data interface{}) interface{} {
+	return s.sanitizeValue(data, "")
+}
+
+// sanitizeValue copies one value found at the given dotted path
+func (s *Sandbox) sanitizeValue(value interface{}, path string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			if s.denied(childPath) {
+				continue
+			}
+			out[key] = s.sanitizeValue(child, childPath)
+		}
+		return out
+	case []interface{}:
+		if s.MaxLoopItems > 0 && len(v) > s.MaxLoopItems {
+			v = v[:s.MaxLoopItems]
+		}
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = s.sanitizeValue(child, path)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// denied reports whether a dotted data path is denied
+func (s *Sandbox) denied(path string) bool {
+	for _, denied := range s.DeniedPaths {
+		if path == denied {
+			return true
+		}
+	}
+	return false
+}
+
+// toSet builds a lookup set from names
+func toSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
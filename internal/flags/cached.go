@@ -0,0 +1,85 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Flag evaluation results, as reported by the flag_evaluations_total metric
+const (
+	resultCached   = "cached"
+	resultFetched  = "fetched"
+	resultNotFound = "not_found"
+	resultError    = "error"
+)
+
+// cachedFlag is the last known value of a flag
+type cachedFlag struct {
+	enabled   bool
+	fetchedAt time.Time
+}
+
+// Cached serves the flags of a provider, evaluating each flag at most once
+// per TTL. Provider calls are bounded by a timeout; when they fail, the last
+// known value is kept, or the flag is disabled.
+type Cached struct {
+	provider Provider
+	ttl      time.Duration
+	timeout  time.Duration
+	logger   *zap.Logger
+
+	flags map[string]cachedFlag
+	mu    sync.RWMutex
+}
+
+// NewCached serves the flags of provider for ttl, bounding each provider
+// call by timeout
+func NewCached(provider Provider, ttl, timeout time.Duration, logger *zap.Logger) *Cached {
+	return &Cached{
+		provider: provider,
+		ttl:      ttl,
+		timeout:  timeout,
+		logger:   logger,
+		flags:    make(map[string]cachedFlag),
+	}
+}
+
+// Enabled reports whether a flag is enabled
+func (c *Cached) Enabled(name string) bool {
+	c.mu.RLock()
+	flag, ok := c.flags[name]
+	c.mu.RUnlock()
+	if ok && time.Since(flag.fetchedAt) < c.ttl {
+		metrics.FlagEvaluations.WithLabelValues(resultCached).Inc()
+		return flag.enabled
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	enabled, err := c.provider.Bool(ctx, name)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		metrics.FlagEvaluations.WithLabelValues(resultNotFound).Inc()
+		enabled = false
+	case err != nil:
+		metrics.FlagEvaluations.WithLabelValues(resultError).Inc()
+		c.logger.Warn("failed to evaluate feature flag, using last known value",
+			zap.String("flag", name),
+			zap.Bool("enabled", flag.enabled),
+			zap.Error(err),
+		)
+		return flag.enabled
+	default:
+		metrics.FlagEvaluations.WithLabelValues(resultFetched).Inc()
+	}
+
+	c.mu.Lock()
+	c.flags[name] = cachedFlag{enabled: enabled, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return enabled
+}
@@ -0,0 +1,21 @@
+// Package flags evaluates feature flags for routing rules and prompt
+// templates, so operators can flip routing behavior without editing graph
+// definitions.
+//
+// A Provider evaluates boolean flags:
+//
+//   - RedisProvider reads the fields of a Redis hash ("true", "1", "on" and
+//     "yes" are enabled)
+//   - OFREPProvider calls a service implementing the OpenFeature Remote
+//     Evaluation Protocol (OFREP), such as flagd or any OpenFeature
+//     compatible flag service exposing it
+//
+// Cached serves flag values for a TTL and keeps the last known value when
+// the provider fails, so rule evaluation never waits on a slow provider for
+// long. Flags that were never evaluated successfully are disabled.
+//
+// Example usage:
+//
+//	flagSet := flags.NewCached(flags.NewRedisProvider(redisClient, "router:flags"), 10*time.Second, time.Second, logger)
+//	enabled := flagSet.Enabled("new_billing_flow")
+package flags
@@ -0,0 +1,16 @@
+// Package grpcserver serves synchronous routing decisions over gRPC.
+//
+// The server implements the RouterService defined in proto/router/v1 and
+// is backed by the same Router instance as the Redis Streams worker, so
+// both transports make identical decisions. Callers pass the node config
+// and either the graph state itself or an execution ID whose state is
+// loaded from the state store.
+//
+// Example usage:
+//
+//	server := grpcserver.NewServer(routerInstance, stateStore, logger)
+//	if err := server.Start(":9090"); err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer server.Stop()
+package grpcserver
@@ -0,0 +1,258 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/audit"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/aescanero/dago-node-router/internal/statestore"
+	routerv1 "github.com/aescanero/dago-node-router/proto/router/v1"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Transport identifies the gRPC transport in worker capabilities
+const Transport = "grpc"
+
+// stopTimeout bounds how long Stop waits for in-flight requests
+const stopTimeout = 5 * time.Second
+
+// Server serves routing decisions over gRPC
+type Server struct {
+	routerv1.UnimplementedRouterServiceServer
+
+	router     *router.Router
+	stateStore ports.StateStorage
+	logger     *zap.Logger
+	server     *grpc.Server
+
+	// auditLog records every decision; nil when auditing is disabled
+	auditLog audit.Log
+	workerID string
+}
+
+// Option configures optional server behavior
+type Option func(*Server)
+
+// WithAuditLog records every routing decision and failure in log, attributed
+// to workerID
+func WithAuditLog(log audit.Log, workerID string) Option {
+	return func(s *Server) {
+		s.auditLog = log
+		s.workerID = workerID
+	}
+}
+
+// NewServer creates a gRPC server backed by the given router. The state
+// store loads graph states for requests that carry only an execution ID.
+func NewServer(routerInstance *router.Router, stateStore ports.StateStorage, logger *zap.Logger, opts ...Option) *Server {
+	s := &Server{
+		router:     routerInstance,
+		stateStore: stateStore,
+		logger:     logger,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start listens on addr and serves requests in the background
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s.server = grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	routerv1.RegisterRouterServiceServer(s.server, s)
+
+	s.logger.Info("starting grpc server", zap.String("addr", listener.Addr().String()))
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil {
+			s.logger.Error("grpc server error", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
+
+// Stop drains in-flight requests, then closes the server
+func (s *Server) Stop() {
+	if s.server == nil {
+		return
+	}
+
+	s.logger.Info("stopping grpc server")
+
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(stopTimeout):
+		s.server.Stop()
+	}
+}
+
+// Route decides the next node for an execution
+func (s *Server) Route(ctx context.Context, req *routerv1.RouteRequest) (*routerv1.RouteResponse, error) {
+	resp, err := s.route(ctx, req)
+	metrics.GRPCRequests.WithLabelValues(status.Code(err).String()).Inc()
+	if err != nil {
+		s.logger.Warn("grpc routing request failed",
+			zap.String("execution_id", req.GetExecutionId()),
+			zap.String("node_id", req.GetNodeId()),
+			zap.Error(err),
+		)
+	}
+	return resp, err
+}
+
+// route resolves the request state and config and routes it
+func (s *Server) route(ctx context.Context, req *routerv1.RouteRequest) (*routerv1.RouteResponse, error) {
+	if req.GetConfig() == nil {
+		return nil, status.Error(codes.InvalidArgument, "config is required")
+	}
+	if req.GetState() == nil && req.GetExecutionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "execution_id is required when state is not provided")
+	}
+
+	nodeConfig, err := decode[router.NodeConfig](req.GetConfig().AsMap())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to parse node config: %v", err)
+	}
+	if errs := s.router.ValidateNodeConfig(nodeConfig); len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, e := range errs {
+			messages[i] = e.Error()
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "invalid config: %s", strings.Join(messages, "; "))
+	}
+
+	graphState, metadata, stateHash, err := s.loadState(ctx, req, nodeConfig.RequiredStatePaths())
+	if err != nil {
+		return nil, err
+	}
+
+	var headers *router.ExecutionContext
+	if h := req.GetHeaders(); h != nil {
+		headers = &router.ExecutionContext{
+			Tenant:           h.GetTenant(),
+			Environment:      h.GetEnvironment(),
+			Locale:           h.GetLocale(),
+			ExperimentBucket: h.GetExperimentBucket(),
+		}
+	}
+
+	ctx = router.WithExecutionContext(ctx, headers)
+	ctx = router.WithNodeID(ctx, req.GetNodeId())
+	ctx = router.WithExecutionMetadata(ctx, metadata)
+	start := time.Now()
+	result, err := s.router.Route(ctx, graphState, nodeConfig)
+	s.recordAudit(ctx, req, stateHash, result, err, time.Since(start))
+	if errors.Is(err, router.ErrTenantQuota) {
+		return nil, status.Errorf(codes.ResourceExhausted, "routing failed: %v", err)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "routing failed: %v", err)
+	}
+
+	resp := &routerv1.RouteResponse{
+		ExecutionId: req.GetExecutionId(),
+		NodeId:      req.GetNodeId(),
+		TargetNode:  result.TargetNode,
+		Reasoning:   result.Reasoning,
+		Mode:        result.Mode,
+		PathTaken:   result.PathTaken,
+		PromptHash:  result.PromptHash,
+		Confidence:  result.Confidence,
+	}
+	if result.Variant != "" {
+		resp.Variant = result.Variant
+	} else if headers != nil {
+		resp.Variant = headers.ExperimentBucket
+	}
+	return resp, nil
+}
+
+// loadState returns the inline request state, or loads the state paths from
+// the state store, with its execution metadata and the state hash when
+// auditing
+func (s *Server) loadState(ctx context.Context, req *routerv1.RouteRequest, paths []string) (*domain.GraphState, map[string]interface{}, string, error) {
+	var stateData map[string]interface{}
+	if inline := req.GetState(); inline != nil {
+		stateData = inline.AsMap()
+	} else {
+		loaded, err := statestore.LoadPaths(ctx, s.stateStore, req.GetExecutionId(), paths)
+		if err != nil {
+			return nil, nil, "", status.Errorf(codes.Unavailable, "failed to load state: %v", err)
+		}
+		stateData = loaded
+	}
+
+	var stateHash string
+	if s.auditLog != nil {
+		stateHash = audit.Hash(stateData)
+	}
+
+	graphState, err := decode[domain.GraphState](stateData)
+	if err != nil {
+		return nil, nil, "", status.Errorf(codes.InvalidArgument, "failed to convert state: %v", err)
+	}
+	if graphState.GraphID == "" {
+		graphState.GraphID = req.GetExecutionId()
+	}
+	metadata, _ := stateData["metadata"].(map[string]interface{})
+	return graphState, metadata, stateHash, nil
+}
+
+// recordAudit appends the audit record of a decision. A failed append is
+// logged and counted; it never fails the request.
+func (s *Server) recordAudit(ctx context.Context, req *routerv1.RouteRequest, stateHash string, result *router.RoutingResult, routeErr error, latency time.Duration) {
+	if s.auditLog == nil {
+		return
+	}
+
+	record := audit.NewRecord(req.GetExecutionId(), req.GetNodeId(), s.workerID,
+		stateHash, audit.Hash(req.GetConfig().AsMap()), result, routeErr, latency)
+	if record.Tenant == "" {
+		record.Tenant = req.GetHeaders().GetTenant()
+	}
+	if err := s.auditLog.Append(ctx, record); err != nil {
+		metrics.AuditErrors.Inc()
+		s.logger.Error("failed to record audit entry",
+			zap.String("execution_id", req.GetExecutionId()),
+			zap.Error(err),
+		)
+	}
+}
+
+// decode converts a generic map to T through its JSON encoding
+func decode[T any](data map[string]interface{}) (*T, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var out T
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
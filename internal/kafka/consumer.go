@@ -0,0 +1,289 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/aescanero/dago-node-router/internal/logging"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	"github.com/aescanero/dago-node-router/internal/worker"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Transport identifies the Kafka transport in worker capabilities
+const Transport = config.WorkTransportKafka
+
+// errUncommitted stops the reader so an unpublished request is redelivered
+var errUncommitted = errors.New("outcome not published, offset left uncommitted")
+
+// Consumer reads work requests from a Kafka topic and produces their outcomes
+type Consumer struct {
+	config *config.Config
+	worker *worker.Worker
+	logger *zap.Logger
+	writer *kafkago.Writer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// lastErr holds the error that stopped the reader until it recovers
+	lastErr error
+	mu      sync.RWMutex
+}
+
+// NewConsumer creates a Kafka consumer that routes through the given worker
+func NewConsumer(cfg *config.Config, w *worker.Worker, logger *zap.Logger) *Consumer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Consumer{
+		config: cfg,
+		worker: w,
+		logger: logger,
+		writer: &kafkago.Writer{
+			Addr:         kafkago.TCP(cfg.KafkaBrokers...),
+			Balancer:     &kafkago.Hash{},
+			RequiredAcks: kafkago.RequireAll,
+			// Outcomes are produced one at a time; retries are ours
+			BatchSize:   1,
+			MaxAttempts: 1,
+		},
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+// Start starts consuming work in the background
+func (c *Consumer) Start() error {
+	c.logger.Info("starting kafka consumer",
+		zap.Strings("brokers", c.config.KafkaBrokers),
+		zap.String("topic", c.config.StreamKey),
+		zap.String("consumer_group", c.config.ConsumerGroup),
+	)
+
+	go c.run()
+	return nil
+}
+
+// Stop stops consuming, waits for the in-flight request and closes the producer
+func (c *Consumer) Stop() error {
+	c.logger.Info("stopping kafka consumer")
+
+	c.cancel()
+	<-c.done
+
+	if err := c.writer.Close(); err != nil {
+		return fmt.Errorf("failed to close kafka producer: %w", err)
+	}
+	return nil
+}
+
+// Health reports the error that stopped the reader, if it has not recovered
+func (c *Consumer) Health(ctx context.Context) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.lastErr != nil {
+		return fmt.Errorf("kafka consumer: %w", c.lastErr)
+	}
+	return nil
+}
+
+// setErr records the error that stopped the reader; nil marks it recovered
+func (c *Consumer) setErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastErr = err
+}
+
+// run consumes until stopped, rejoining the group from the last committed
+// offset whenever the reader stops
+func (c *Consumer) run() {
+	defer close(c.done)
+
+	delay := c.config.RedisBackoffMin
+	for {
+		reader := kafkago.NewReader(kafkago.ReaderConfig{
+			Brokers:     c.config.KafkaBrokers,
+			GroupID:     c.config.ConsumerGroup,
+			Topic:       c.config.StreamKey,
+			MaxWait:     c.config.BlockTime,
+			StartOffset: kafkago.FirstOffset,
+			// Commit synchronously, after each outcome is published
+			CommitInterval: 0,
+		})
+
+		consumed, err := c.consume(reader)
+		if closeErr := reader.Close(); closeErr != nil {
+			c.logger.Warn("failed to close kafka reader", zap.Error(closeErr))
+		}
+		if c.ctx.Err() != nil {
+			c.logger.Info("kafka consumer stopped")
+			return
+		}
+
+		if consumed {
+			delay = c.config.RedisBackoffMin
+		}
+		c.setErr(err)
+		c.logger.Warn("kafka reader stopped, rejoining from the last committed offset",
+			zap.Duration("retry_in", delay),
+			zap.Error(err),
+		)
+		if !sleepContext(c.ctx, delay) {
+			c.logger.Info("kafka consumer stopped")
+			return
+		}
+		delay = min(2*delay, c.config.RedisBackoffMax)
+	}
+}
+
+// consume handles messages until the reader fails or an outcome cannot be
+// published. It reports whether any message was committed.
+func (c *Consumer) consume(reader *kafkago.Reader) (bool, error) {
+	var consumed bool
+	for {
+		message, err := reader.FetchMessage(c.ctx)
+		if err != nil {
+			return consumed, fmt.Errorf("failed to fetch message: %w", err)
+		}
+		c.setErr(nil)
+
+		if !c.handleMessage(message) {
+			return consumed, errUncommitted
+		}
+
+		// Commits are cumulative, so messages are committed in order, one
+		// by one, once their outcome is settled. The commit outlives Stop so
+		// a published outcome is not redelivered.
+		if err := reader.CommitMessages(context.Background(), message); err != nil {
+			return consumed, fmt.Errorf("failed to commit offset: %w", err)
+		}
+		metrics.MessagesAcked.Inc()
+		consumed = true
+	}
+}
+
+// handleMessage routes a single work request and publishes its outcome. It
+// reports whether the offset may be committed.
+func (c *Consumer) handleMessage(message kafkago.Message) bool {
+	receivedAt := time.Now()
+	messageID := fmt.Sprintf("%s/%d/%d", message.Topic, message.Partition, message.Offset)
+	if !message.Time.IsZero() {
+		metrics.StreamLag.Set(receivedAt.Sub(message.Time).Seconds())
+	}
+
+	// Headers carry the trace context, like Redis Streams message fields
+	values := map[string]interface{}{
+		"data": string(message.Value),
+	}
+	for _, header := range message.Headers {
+		if header.Key != "data" {
+			values[header.Key] = string(header.Value)
+		}
+	}
+
+	// Continue the trace started by the orchestrator, if any. Routing stops
+	// when the consumer stops.
+	ctx := tracing.Extract(c.ctx, values)
+	ctx, span := tracing.Tracer().Start(ctx, "kafka.handleMessage",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.message.id", messageID),
+			attribute.String("messaging.destination.name", message.Topic),
+		),
+	)
+	defer span.End()
+	ctx = c.worker.WithMessageLogger(ctx, messageID)
+	logging.FromContext(ctx, c.logger).Info("processing routing request")
+
+	request, err := c.worker.ParseWorkRequest(values, message.Time, receivedAt)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid work request")
+		logging.FromContext(ctx, c.logger).Error("failed to parse work request",
+			zap.Error(err),
+		)
+		metrics.MessagesProcessed.WithLabelValues("invalid").Inc()
+		c.worker.RecordError("", "parse", err)
+		// Malformed requests fail the same way on every delivery, so they are
+		// kept in the dead letter topic (when enabled) and committed
+		if c.config.DeadLetterStream != "" && !c.deadLetter(context.WithoutCancel(ctx), message, messageID, err) {
+			return false
+		}
+		return true
+	}
+
+	span.SetAttributes(
+		attribute.String("execution_id", request.ExecutionID),
+		attribute.String("node_id", request.NodeID),
+	)
+	ctx = c.worker.WithRequestLogger(ctx, request)
+
+	// A redelivered message whose outcome was already produced is only committed
+	if c.worker.IsDuplicate(ctx, request, messageID) {
+		return true
+	}
+
+	outcome, err := c.worker.Process(ctx, request)
+	if outcome.Interrupted {
+		return false
+	}
+	// A produced outcome is published even if the consumer is stopping
+	ctx = context.WithoutCancel(ctx)
+	if outcome.Result != nil {
+		ctx = logging.With(ctx, c.logger, zap.String("tenant", outcome.Result.Tenant))
+	}
+	if err == nil {
+		// Kafka cannot share a transaction with the state store: the state
+		// is written first and rewritten if the message is redelivered
+		err = c.worker.WriteState(ctx, request, outcome)
+	}
+	if err == nil {
+		topic := c.config.ResultStream
+		if outcome.Failed() {
+			// Error events go to a separate topic
+			topic += ".errors"
+		}
+		err = c.publish(ctx, topic, request.ExecutionID, outcome.Values)
+	}
+
+	// Only commit once the outcome is published or dead-lettered
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "publish failed")
+		c.worker.RecordError(request.ExecutionID, "publish", err)
+		if !c.deadLetter(ctx, message, messageID, err) {
+			logging.FromContext(ctx, c.logger).Error("outcome not published, leaving offset uncommitted",
+				zap.Error(err),
+			)
+			return false
+		}
+	} else {
+		c.worker.MarkPublished(ctx, request, messageID)
+		if outcome.Result != nil {
+			c.worker.RecordHop(ctx, request)
+			logging.FromContext(ctx, c.logger).Info("published routing decision",
+				zap.String("target_node", outcome.Result.TargetNode),
+			)
+		}
+		// Experiment results are best effort and never hold back the commit
+		if outcome.ExperimentValues != nil {
+			if err := c.publish(ctx, c.config.ExperimentStream, request.ExecutionID, outcome.ExperimentValues); err != nil {
+				c.worker.ExperimentPublishFailed(request.ExecutionID, err)
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,22 @@
+// Package kafka provides a Kafka work transport for the router worker.
+//
+// The consumer joins CONSUMER_GROUP on the STREAM_KEY topic, routes each
+// work request through the worker and produces the decision to the
+// RESULT_STREAM topic (errors to RESULT_STREAM.errors), with the same
+// message payloads and trace headers as the Redis Streams transport.
+// Experiment results are also produced to the EXPERIMENT_STREAM topic.
+//
+// Offsets are committed with the same semantics as Redis Streams acks: only
+// once the outcome is produced or the request is dead-lettered to the
+// DEAD_LETTER_STREAM topic. When neither succeeds the offset is left
+// uncommitted and the consumer rejoins the group from the last committed
+// offset, so the request is delivered again.
+//
+// Example usage:
+//
+//	consumer := kafka.NewConsumer(cfg, w, logger)
+//	if err := consumer.Start(); err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer consumer.Stop()
+package kafka
@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/logging"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	"github.com/aescanero/dago-node-router/internal/worker"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// publish produces values to topic, retrying failures with backoff up to
+// MaxRetries times. The "data" field becomes the message value and the other
+// fields headers; messages are keyed by execution ID to keep executions
+// ordered within a partition.
+func (c *Consumer) publish(ctx context.Context, topic, key string, values map[string]interface{}) error {
+	message := kafkago.Message{
+		Topic: topic,
+		Key:   []byte(key),
+	}
+	for field, value := range values {
+		text := fmt.Sprint(value)
+		if field == "data" {
+			message.Value = []byte(text)
+			continue
+		}
+		message.Headers = append(message.Headers, kafkago.Header{Key: field, Value: []byte(text)})
+	}
+
+	delay := c.config.PublishBackoffMin
+	var err error
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			metrics.PublishRetries.Inc()
+			c.logger.Warn("failed to publish to topic, retrying",
+				zap.String("topic", topic),
+				zap.Int("attempt", attempt),
+				zap.Duration("retry_in", delay),
+				zap.Error(err),
+			)
+			if !sleepContext(c.ctx, delay) {
+				return fmt.Errorf("publish to %s cancelled: %w", topic, err)
+			}
+			delay = min(2*delay, c.config.PublishBackoffMax)
+		}
+
+		if err = c.writer.WriteMessages(ctx, message); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("failed to publish to %s: %w", topic, err)
+}
+
+// deadLetter moves a request whose outcome could not be published, or that is
+// not a valid work request, to the dead letter topic.
+// It reports whether the request was stored and may be committed.
+func (c *Consumer) deadLetter(ctx context.Context, message kafkago.Message, messageID string, cause error) bool {
+	if c.config.DeadLetterStream == "" {
+		return false
+	}
+
+	values := map[string]interface{}{
+		"message_id": messageID,
+		"stream":     message.Topic,
+		"error":      cause.Error(),
+		"timestamp":  time.Now().UTC().Format(time.RFC3339Nano),
+		"data":       string(message.Value),
+	}
+	var validationErr *worker.ValidationError
+	if errors.As(cause, &validationErr) {
+		problems, _ := json.Marshal(validationErr.Problems)
+		values["validation_errors"] = string(problems)
+	}
+	tracing.Inject(ctx, values)
+
+	if err := c.publish(ctx, c.config.DeadLetterStream, string(message.Key), values); err != nil {
+		logging.FromContext(ctx, c.logger).Error("failed to dead-letter message",
+			zap.Error(err),
+		)
+		return false
+	}
+
+	metrics.MessagesDeadLettered.Inc()
+	logging.FromContext(ctx, c.logger).Warn("message moved to dead letter topic",
+		zap.String("dead_letter_topic", c.config.DeadLetterStream),
+		zap.Error(cause),
+	)
+	return true
+}
+
+// sleepContext sleeps for d, returning false if ctx is cancelled first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
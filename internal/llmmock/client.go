@@ -0,0 +1,133 @@
+package llmmock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/tokens"
+)
+
+// defaultModel is reported when the config names no model
+const defaultModel = "mock"
+
+// Client is a deterministic mock LLM implementing ports.LLMClient
+type Client struct {
+	config Config
+	model  string
+
+	calls atomic.Int64
+	// next is the index of the next canned response
+	next atomic.Int64
+}
+
+// NewClient creates a mock LLM from a validated config
+func NewClient(config Config) *Client {
+	model := config.Model
+	if model == "" {
+		model = defaultModel
+	}
+	return &Client{config: config, model: model}
+}
+
+// Calls returns the number of calls answered since the client was created
+func (c *Client) Calls() int64 {
+	return c.calls.Load()
+}
+
+// GenerateCompletion answers a *domain.LLMRequest
+func (c *Client) GenerateCompletion(ctx context.Context, req interface{}) (interface{}, error) {
+	request, ok := req.(*domain.LLMRequest)
+	if !ok {
+		return nil, fmt.Errorf("mock llm: unsupported request type %T", req)
+	}
+
+	messages := make([]string, 0, len(request.Messages)+1)
+	messages = append(messages, request.System)
+	for _, message := range request.Messages {
+		messages = append(messages, message.Content)
+	}
+
+	content := c.answer(messages)
+	return &domain.LLMResponse{
+		Content: content,
+		Model:   c.model,
+		Usage:   domain.Usage{InputTokens: tokens.Estimate(strings.Join(messages, "")), OutputTokens: tokens.Estimate(content)},
+	}, nil
+}
+
+// Complete answers a chat completion
+func (c *Client) Complete(ctx context.Context, req ports.CompletionRequest) (*ports.CompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	messages := make([]string, len(req.Messages))
+	for i, message := range req.Messages {
+		messages[i] = message.Content
+	}
+
+	content := c.answer(messages)
+	inputTokens, outputTokens := tokens.Estimate(strings.Join(messages, "")), tokens.Estimate(content)
+	return &ports.CompletionResponse{
+		ID:           fmt.Sprintf("mock-%d", c.calls.Load()),
+		Model:        c.model,
+		Message:      ports.Message{Role: "assistant", Content: content},
+		FinishReason: "stop",
+		Usage: ports.UsageInfo{
+			PromptTokens:     inputTokens,
+			CompletionTokens: outputTokens,
+			TotalTokens:      inputTokens + outputTokens,
+		},
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// CompleteWithTools answers a chat completion; tools are ignored
+func (c *Client) CompleteWithTools(ctx context.Context, req ports.CompletionRequest, tools []ports.Tool) (*ports.CompletionResponse, error) {
+	return c.Complete(ctx, req)
+}
+
+// CompleteStructured answers a structured completion whose answer is
+// returned under "route" when it is not itself a JSON object
+func (c *Client) CompleteStructured(ctx context.Context, req ports.CompletionRequest, schema ports.JSONSchema) (*ports.StructuredResponse, error) {
+	resp, err := c.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(resp.Message.Content), &data); err != nil {
+		data = map[string]interface{}{"route": resp.Message.Content}
+	}
+
+	return &ports.StructuredResponse{Data: data, Usage: resp.Usage, CreatedAt: resp.CreatedAt}, nil
+}
+
+// answer picks the response to the messages of a request
+func (c *Client) answer(messages []string) string {
+	c.calls.Add(1)
+
+	prompt := strings.ToLower(strings.Join(messages, "\n"))
+	for _, route := range c.config.Routes {
+		if strings.Contains(prompt, strings.ToLower(route.Keyword)) {
+			return route.Response
+		}
+	}
+
+	if n := int64(len(c.config.Responses)); n > 0 {
+		return c.config.Responses[(c.next.Add(1)-1)%n]
+	}
+	if c.config.Default != "" {
+		return c.config.Default
+	}
+	if len(messages) == 0 {
+		return ""
+	}
+	return messages[len(messages)-1]
+}
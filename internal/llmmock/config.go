@@ -0,0 +1,71 @@
+package llmmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Route answers prompts containing Keyword with Response
+type Route struct {
+	Keyword  string `json:"keyword"`
+	Response string `json:"response"`
+}
+
+// Config describes the mock answers
+type Config struct {
+	// Model is reported in responses; empty uses "mock"
+	Model string `json:"model,omitempty"`
+	// Routes are checked in order against the prompt
+	Routes []Route `json:"routes,omitempty"`
+	// Responses answer prompts no route matches, one after another, starting
+	// over after the last
+	Responses []string `json:"responses,omitempty"`
+	// Default answers prompts no route matches when there are no responses;
+	// empty echoes the last message of the request
+	Default string `json:"default,omitempty"`
+}
+
+// LoadConfig reads and validates a mock config file
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read llm mock file: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse llm mock file: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid llm mock file: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// ParseRoutes parses keyword=response entries (e.g. "refund=billing") into
+// routes, keeping their order
+func ParseRoutes(entries []string) ([]Route, error) {
+	routes := make([]Route, 0, len(entries))
+	for _, entry := range entries {
+		keyword, response, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("llm mock route %q must be keyword=response", entry)
+		}
+		routes = append(routes, Route{Keyword: strings.TrimSpace(keyword), Response: strings.TrimSpace(response)})
+	}
+	return routes, nil
+}
+
+// Validate checks the mock config
+func (c *Config) Validate() error {
+	for i, route := range c.Routes {
+		if route.Keyword == "" {
+			return fmt.Errorf("route %d: keyword is required", i)
+		}
+	}
+	return nil
+}
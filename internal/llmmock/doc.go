@@ -0,0 +1,29 @@
+// Package llmmock provides a deterministic mock LLM client for integration
+// tests and local development.
+//
+// The mock client implements ports.LLMClient without calling a provider or
+// needing an API key. Each call is answered, in order of precedence, by the
+// first route whose keyword appears in the prompt (case-insensitive), by the
+// next canned response in rotation, by the default answer, or by echoing the
+// last message of the request. Unlike the simulated client of package
+// llmsim, it never waits, fails or answers at random.
+//
+// Example config:
+//
+//	{
+//	    "routes": [
+//	        {"keyword": "refund", "response": "billing"},
+//	        {"keyword": "crash", "response": "technical"}
+//	    ],
+//	    "responses": ["general"]
+//	}
+//
+// Example usage:
+//
+//	cfg, err := llmmock.LoadConfig("mock.json")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	client := llmmock.NewClient(cfg)
+//	routerInstance := router.NewRouter(client, logger)
+package llmmock
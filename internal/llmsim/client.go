@@ -0,0 +1,356 @@
+package llmsim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/tokens"
+)
+
+// Errors returned by the simulated provider
+var (
+	// ErrRateLimited mimics a provider 429 response
+	ErrRateLimited = errors.New("simulated llm: 429 rate limit exceeded")
+	// ErrServerError mimics a provider 5xx response
+	ErrServerError = errors.New("simulated llm: 500 internal server error")
+	// ErrTimeout is returned by simulated timeouts when the caller set no deadline
+	ErrTimeout = errors.New("simulated llm: request timed out")
+)
+
+// defaultModel is reported when the config names no model
+const defaultModel = "simulated"
+
+// defaultTimeout bounds simulated timeouts without a caller deadline or Latency.Max
+const defaultTimeout = 30 * time.Second
+
+// Stats counts simulated calls by outcome
+type Stats struct {
+	Calls       int64 `json:"calls"`
+	Succeeded   int64 `json:"succeeded"`
+	RateLimited int64 `json:"rate_limited"`
+	Errors      int64 `json:"errors"`
+	Timeouts    int64 `json:"timeouts"`
+	Partial     int64 `json:"partial"`
+}
+
+// Client is a simulated LLM implementing ports.LLMClient
+type Client struct {
+	config Config
+	model  string
+
+	rng   *rand.Rand
+	rngMu sync.Mutex
+
+	bucket   float64
+	bucketAt time.Time
+	bucketMu sync.Mutex
+
+	calls, succeeded, rateLimited, serverErrors, timeouts, partial atomic.Int64
+}
+
+// NewClient creates a simulated LLM from a validated config
+func NewClient(config Config) *Client {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	model := config.Model
+	if model == "" {
+		model = defaultModel
+	}
+
+	return &Client{
+		config:   config,
+		model:    model,
+		rng:      rand.New(rand.NewSource(seed)),
+		bucket:   float64(burst(config.RateLimit)),
+		bucketAt: time.Now(),
+	}
+}
+
+// Stats returns the call counters since the client was created
+func (c *Client) Stats() Stats {
+	return Stats{
+		Calls:       c.calls.Load(),
+		Succeeded:   c.succeeded.Load(),
+		RateLimited: c.rateLimited.Load(),
+		Errors:      c.serverErrors.Load(),
+		Timeouts:    c.timeouts.Load(),
+		Partial:     c.partial.Load(),
+	}
+}
+
+// GenerateCompletion simulates a completion for a *domain.LLMRequest
+func (c *Client) GenerateCompletion(ctx context.Context, req interface{}) (interface{}, error) {
+	request, ok := req.(*domain.LLMRequest)
+	if !ok {
+		return nil, fmt.Errorf("simulated llm: unsupported request type %T", req)
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(request.System)
+	for _, message := range request.Messages {
+		prompt.WriteString(message.Content)
+	}
+
+	content, inputTokens, outputTokens, err := c.complete(ctx, prompt.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LLMResponse{
+		Content: content,
+		Model:   c.model,
+		Usage:   domain.Usage{InputTokens: inputTokens, OutputTokens: outputTokens},
+	}, nil
+}
+
+// Complete simulates a chat completion
+func (c *Client) Complete(ctx context.Context, req ports.CompletionRequest) (*ports.CompletionResponse, error) {
+	var prompt strings.Builder
+	for _, message := range req.Messages {
+		prompt.WriteString(message.Content)
+	}
+
+	content, inputTokens, outputTokens, err := c.complete(ctx, prompt.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ports.CompletionResponse{
+		ID:           fmt.Sprintf("sim-%d", c.calls.Load()),
+		Model:        c.model,
+		Message:      ports.Message{Role: "assistant", Content: content},
+		FinishReason: "stop",
+		Usage: ports.UsageInfo{
+			PromptTokens:     inputTokens,
+			CompletionTokens: outputTokens,
+			TotalTokens:      inputTokens + outputTokens,
+		},
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// CompleteWithTools simulates a chat completion; tools are ignored
+func (c *Client) CompleteWithTools(ctx context.Context, req ports.CompletionRequest, tools []ports.Tool) (*ports.CompletionResponse, error) {
+	return c.Complete(ctx, req)
+}
+
+// CompleteStructured simulates a structured completion whose answer is
+// returned under "route" when it is not itself a JSON object
+func (c *Client) CompleteStructured(ctx context.Context, req ports.CompletionRequest, schema ports.JSONSchema) (*ports.StructuredResponse, error) {
+	resp, err := c.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(resp.Message.Content), &data); err != nil {
+		data = map[string]interface{}{"route": resp.Message.Content}
+	}
+
+	return &ports.StructuredResponse{Data: data, Usage: resp.Usage, CreatedAt: resp.CreatedAt}, nil
+}
+
+// complete runs one simulated call: rate limit, latency, failures and answer
+func (c *Client) complete(ctx context.Context, prompt string) (string, int, int, error) {
+	c.calls.Add(1)
+
+	if !c.allow() {
+		c.rateLimited.Add(1)
+		return "", 0, 0, ErrRateLimited
+	}
+
+	outcome := c.float()
+	failures := c.config.Failures
+
+	if outcome < failures.TimeoutRate {
+		c.timeouts.Add(1)
+		return "", 0, 0, c.hang(ctx)
+	}
+	outcome -= failures.TimeoutRate
+
+	if err := c.wait(ctx, c.latency()); err != nil {
+		return "", 0, 0, err
+	}
+
+	if outcome < failures.ErrorRate {
+		c.serverErrors.Add(1)
+		return "", 0, 0, ErrServerError
+	}
+	outcome -= failures.ErrorRate
+
+	content := c.answer(prompt)
+	switch {
+	case outcome < failures.EmptyRate:
+		c.partial.Add(1)
+		content = ""
+	case outcome < failures.EmptyRate+failures.TruncateRate:
+		c.partial.Add(1)
+		runes := []rune(content)
+		content = string(runes[:len(runes)/2])
+	default:
+		c.succeeded.Add(1)
+	}
+
+	if c.config.Tokens.OmitUsage {
+		return content, 0, 0, nil
+	}
+	return content, tokens.Estimate(prompt), c.outputTokens(content), nil
+}
+
+// allow takes a token from the rate limit bucket
+func (c *Client) allow() bool {
+	limit := c.config.RateLimit
+	if limit.RequestsPerSecond <= 0 {
+		return true
+	}
+
+	c.bucketMu.Lock()
+	defer c.bucketMu.Unlock()
+
+	now := time.Now()
+	c.bucket = math.Min(float64(burst(limit)), c.bucket+now.Sub(c.bucketAt).Seconds()*limit.RequestsPerSecond)
+	c.bucketAt = now
+
+	if c.bucket < 1 {
+		return false
+	}
+	c.bucket--
+	return true
+}
+
+// burst returns the bucket size, at least one request
+func burst(limit RateLimitConfig) int {
+	if limit.Burst < 1 {
+		return 1
+	}
+	return limit.Burst
+}
+
+// latency samples the latency distribution
+func (c *Client) latency() time.Duration {
+	cfg := c.config.Latency
+	mean, stddev := float64(cfg.Mean), float64(cfg.StdDev)
+
+	var sample float64
+	switch cfg.Distribution {
+	case DistributionUniform:
+		sample = float64(cfg.Min) + c.float()*float64(cfg.Max-cfg.Min)
+	case DistributionNormal:
+		sample = mean + stddev*c.normal()
+	case DistributionLogNormal:
+		if mean > 0 {
+			// Parameters of the underlying normal giving the configured mean and stddev
+			sigma2 := math.Log(1 + (stddev*stddev)/(mean*mean))
+			sample = math.Exp(math.Log(mean) - sigma2/2 + math.Sqrt(sigma2)*c.normal())
+		}
+	default:
+		sample = mean
+	}
+
+	sample = math.Max(sample, float64(cfg.Min))
+	if cfg.Max > 0 {
+		sample = math.Min(sample, float64(cfg.Max))
+	}
+	return time.Duration(sample)
+}
+
+// outputTokens samples the output token count, at least the answer's estimate
+func (c *Client) outputTokens(content string) int {
+	cfg := c.config.Tokens
+	if cfg.OutputMean == 0 {
+		return tokens.Estimate(content)
+	}
+	n := int(math.Round(cfg.OutputMean + cfg.OutputStdDev*c.normal()))
+	return max(n, tokens.Estimate(content), 1)
+}
+
+// answer picks the response content for a prompt
+func (c *Client) answer(prompt string) string {
+	lower := strings.ToLower(prompt)
+	var total float64
+	for _, answer := range c.config.Answers {
+		if answer.Contains != "" {
+			if strings.Contains(lower, strings.ToLower(answer.Contains)) {
+				return answer.Content
+			}
+			continue
+		}
+		total += weight(answer)
+	}
+
+	pick := c.float() * total
+	var last string
+	for _, answer := range c.config.Answers {
+		if answer.Contains != "" {
+			continue
+		}
+		last = answer.Content
+		if pick < weight(answer) {
+			return answer.Content
+		}
+		pick -= weight(answer)
+	}
+	return last
+}
+
+// weight returns an answer's weight; unweighted answers weigh 1
+func weight(answer Answer) float64 {
+	if answer.Weight == 0 {
+		return 1
+	}
+	return answer.Weight
+}
+
+// hang blocks like an unresponsive provider until the caller gives up
+func (c *Client) hang(ctx context.Context) error {
+	timeout := time.Duration(c.config.Latency.Max)
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	if err := c.wait(ctx, timeout); err != nil {
+		return err
+	}
+	return ErrTimeout
+}
+
+// wait sleeps for d or until ctx is done
+func (c *Client) wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// float returns a uniform sample in [0, 1)
+func (c *Client) float() float64 {
+	c.rngMu.Lock()
+	defer c.rngMu.Unlock()
+	return c.rng.Float64()
+}
+
+// normal returns a standard normal sample
+func (c *Client) normal() float64 {
+	c.rngMu.Lock()
+	defer c.rngMu.Unlock()
+	return c.rng.NormFloat64()
+}
@@ -0,0 +1,173 @@
+package llmsim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Latency distributions
+const (
+	DistributionConstant  = "constant"
+	DistributionUniform   = "uniform"
+	DistributionNormal    = "normal"
+	DistributionLogNormal = "lognormal"
+)
+
+// Duration is a time.Duration read from a JSON string such as "250ms"
+type Duration time.Duration
+
+// UnmarshalJSON parses a Go duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"250ms\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON formats the duration as a Go duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Config describes the simulated provider behavior
+type Config struct {
+	// Seed makes runs reproducible; 0 seeds from the clock
+	Seed int64 `json:"seed,omitempty"`
+	// Model is reported in responses; empty uses "simulated"
+	Model     string          `json:"model,omitempty"`
+	Latency   LatencyConfig   `json:"latency"`
+	Tokens    TokenConfig     `json:"tokens"`
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	Failures  FailureConfig   `json:"failures"`
+	Answers   []Answer        `json:"answers"`
+}
+
+// LatencyConfig is the response latency distribution. Samples are clamped
+// to [Min, Max]; a zero Max leaves them unbounded.
+type LatencyConfig struct {
+	Distribution string   `json:"distribution,omitempty"`
+	Mean         Duration `json:"mean,omitempty"`
+	StdDev       Duration `json:"stddev,omitempty"`
+	Min          Duration `json:"min,omitempty"`
+	Max          Duration `json:"max,omitempty"`
+}
+
+// TokenConfig is the reported token usage. Input tokens are estimated from
+// the prompt; output tokens are drawn from a normal distribution.
+type TokenConfig struct {
+	OutputMean   float64 `json:"output_mean,omitempty"`
+	OutputStdDev float64 `json:"output_stddev,omitempty"`
+	// OmitUsage reports zero usage, like providers that return none
+	OmitUsage bool `json:"omit_usage,omitempty"`
+}
+
+// RateLimitConfig is a token bucket; calls beyond it fail with ErrRateLimited.
+// A zero RequestsPerSecond disables rate limiting.
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `json:"requests_per_second,omitempty"`
+	Burst             int     `json:"burst,omitempty"`
+}
+
+// FailureConfig sets the probability of each failure per call
+type FailureConfig struct {
+	// ErrorRate fails calls with ErrServerError after the sampled latency
+	ErrorRate float64 `json:"error_rate,omitempty"`
+	// TimeoutRate hangs calls until the caller's deadline, or Latency.Max
+	TimeoutRate float64 `json:"timeout_rate,omitempty"`
+	// EmptyRate answers with empty content
+	EmptyRate float64 `json:"empty_rate,omitempty"`
+	// TruncateRate answers with the first half of the content
+	TruncateRate float64 `json:"truncate_rate,omitempty"`
+}
+
+// Answer is a possible response. Answers with Contains are returned when the
+// prompt contains that text (case-insensitive), in order; otherwise one of the
+// answers without Contains is chosen at random by Weight.
+type Answer struct {
+	Contains string  `json:"contains,omitempty"`
+	Content  string  `json:"content"`
+	Weight   float64 `json:"weight,omitempty"`
+}
+
+// LoadConfig reads and validates a simulation config file
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read llm simulation file: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse llm simulation file: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid llm simulation file: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the simulation config
+func (c *Config) Validate() error {
+	switch c.Latency.Distribution {
+	case "", DistributionConstant, DistributionUniform, DistributionNormal, DistributionLogNormal:
+	default:
+		return fmt.Errorf("latency.distribution must be constant, uniform, normal or lognormal")
+	}
+	if c.Latency.Mean < 0 || c.Latency.StdDev < 0 || c.Latency.Min < 0 || c.Latency.Max < 0 {
+		return fmt.Errorf("latency durations must not be negative")
+	}
+	if c.Latency.Max > 0 && c.Latency.Min > c.Latency.Max {
+		return fmt.Errorf("latency.min must not exceed latency.max")
+	}
+	if c.Latency.Distribution == DistributionUniform && c.Latency.Max == 0 {
+		return fmt.Errorf("latency.max is required for the uniform distribution")
+	}
+
+	if c.Tokens.OutputMean < 0 || c.Tokens.OutputStdDev < 0 {
+		return fmt.Errorf("tokens must not be negative")
+	}
+
+	if c.RateLimit.RequestsPerSecond < 0 || c.RateLimit.Burst < 0 {
+		return fmt.Errorf("rate_limit must not be negative")
+	}
+
+	rates := []float64{c.Failures.ErrorRate, c.Failures.TimeoutRate, c.Failures.EmptyRate, c.Failures.TruncateRate}
+	var total float64
+	for _, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("failure rates must be between 0 and 1")
+		}
+		total += rate
+	}
+	if total > 1 {
+		return fmt.Errorf("failure rates must not add up to more than 1")
+	}
+
+	if len(c.Answers) == 0 {
+		return fmt.Errorf("at least one answer is required")
+	}
+	var weighted bool
+	for i, answer := range c.Answers {
+		if answer.Weight < 0 {
+			return fmt.Errorf("answer %d: weight must not be negative", i)
+		}
+		if answer.Contains == "" {
+			weighted = true
+		}
+	}
+	if !weighted {
+		return fmt.Errorf("at least one answer without contains is required")
+	}
+
+	return nil
+}
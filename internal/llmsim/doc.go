@@ -0,0 +1,32 @@
+// Package llmsim provides a simulated LLM client for load tests.
+//
+// The simulated client implements ports.LLMClient without calling a
+// provider. It models response latency distributions, token usage,
+// provider rate limits, and full or partial failures as described by a JSON
+// config file, so capacity planning and circuit breaker tuning can be done
+// in a load test rig without spending money on a real provider.
+//
+// Example config:
+//
+//	{
+//	    "seed": 42,
+//	    "latency": {"distribution": "lognormal", "mean": "800ms", "stddev": "300ms", "max": "10s"},
+//	    "tokens": {"output_mean": 6, "output_stddev": 2},
+//	    "rate_limit": {"requests_per_second": 50, "burst": 10},
+//	    "failures": {"error_rate": 0.02, "timeout_rate": 0.01, "empty_rate": 0.01},
+//	    "answers": [
+//	        {"contains": "refund", "content": "billing"},
+//	        {"content": "technical", "weight": 2},
+//	        {"content": "general", "weight": 1}
+//	    ]
+//	}
+//
+// Example usage:
+//
+//	cfg, err := llmsim.LoadConfig("simulation.json")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	client := llmsim.NewClient(cfg)
+//	routerInstance := router.NewRouter(client, logger)
+package llmsim
@@ -0,0 +1,26 @@
+// Package logging builds the worker logger and carries request-scoped child
+// loggers in a context.Context.
+//
+// While a routing request is handled, its execution_id, node_id, tenant,
+// message_id and trace_id are added once to a child logger stored in the
+// request context, so every line logged for the request carries them
+// without each log call choosing its own fields. A field is only added once:
+// the first value set for a key wins, so nested calls never produce
+// duplicate keys.
+//
+// New builds the base logger in JSON or console encoding, optionally
+// sampling repeated lines.
+//
+// Example usage:
+//
+//	logger, err := logging.New(logging.Config{Level: "info", Encoding: "json"})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	ctx = logging.With(ctx, logger,
+//	    zap.String("execution_id", request.ExecutionID),
+//	    zap.String("node_id", request.NodeID),
+//	)
+//	logging.FromContext(ctx, logger).Info("routing request")
+package logging
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Config configures the base logger
+type Config struct {
+	// Level is debug, info, warn or error (info when unset)
+	Level string
+	// Encoding is json (the default) or console
+	Encoding string
+	// SamplingInitial is the number of identical lines (same level and
+	// message) logged each second before sampling starts; 0 disables sampling
+	SamplingInitial int
+	// SamplingThereafter logs every Nth identical line past SamplingInitial
+	// in the same second; 0 drops them
+	SamplingThereafter int
+}
+
+// New builds a logger writing to stdout, with errors of the logger itself
+// on stderr
+func New(cfg Config) (*zap.Logger, error) {
+	level := zapcore.InfoLevel
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+		}
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoding := cfg.Encoding
+	switch encoding {
+	case "", "json":
+		encoding = "json"
+	case "console":
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	default:
+		return nil, fmt.Errorf("invalid log encoding %q", cfg.Encoding)
+	}
+
+	config := zap.Config{
+		Level:            zap.NewAtomicLevelAt(level),
+		Development:      false,
+		Encoding:         encoding,
+		EncoderConfig:    encoderConfig,
+		OutputPaths:      []string{"stdout"},
+		ErrorOutputPaths: []string{"stderr"},
+	}
+	if cfg.SamplingInitial > 0 {
+		config.Sampling = &zap.SamplingConfig{
+			Initial:    cfg.SamplingInitial,
+			Thereafter: cfg.SamplingThereafter,
+		}
+	}
+	return config.Build()
+}
+
+// loggerKey is the context.Context key for the request logger
+type loggerKey struct{}
+
+// contextLogger is a child logger and the keys of the fields it adds
+type contextLogger struct {
+	logger *zap.Logger
+	keys   map[string]bool
+}
+
+// With returns a context carrying a child of the logger of ctx, or of
+// fallback when ctx has none, that adds fields to every line. Fields whose
+// key the logger already adds, or whose string value is empty, are skipped.
+func With(ctx context.Context, fallback *zap.Logger, fields ...zap.Field) context.Context {
+	current, _ := ctx.Value(loggerKey{}).(*contextLogger)
+	if current == nil {
+		current = &contextLogger{logger: fallback}
+	}
+
+	keys := make(map[string]bool, len(current.keys)+len(fields))
+	for key := range current.keys {
+		keys[key] = true
+	}
+	added := make([]zap.Field, 0, len(fields))
+	for _, field := range fields {
+		if keys[field.Key] || (field.Type == zapcore.StringType && field.String == "") {
+			continue
+		}
+		keys[field.Key] = true
+		added = append(added, field)
+	}
+	if len(added) == 0 || current.logger == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, loggerKey{}, &contextLogger{
+		logger: current.logger.With(added...),
+		keys:   keys,
+	})
+}
+
+// FromContext returns the logger carried by ctx, or fallback
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if current, ok := ctx.Value(loggerKey{}).(*contextLogger); ok {
+		return current.logger
+	}
+	return fallback
+}
@@ -0,0 +1,171 @@
+package lookup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultMaxBodyBytes bounds lookup responses when no limit is configured
+const defaultMaxBodyBytes = 1 << 20
+
+// ErrNotFound is returned for lookups whose key or URL holds no data
+var ErrNotFound = errors.New("lookup data not found")
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAllowedHosts restricts HTTP lookups to the given hosts (host or
+// host:port); no hosts allows any
+func WithAllowedHosts(hosts []string) Option {
+	return func(c *Client) {
+		for _, host := range hosts {
+			if host = strings.TrimSpace(strings.ToLower(host)); host != "" {
+				c.allowedHosts[host] = true
+			}
+		}
+	}
+}
+
+// WithMaxBodyBytes bounds the size of lookup responses
+func WithMaxBodyBytes(n int64) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxBodyBytes = n
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client of URL lookups
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.http = httpClient
+	}
+}
+
+// Client fetches lookup data over HTTP and from Redis. Callers bound each
+// fetch with the context deadline.
+type Client struct {
+	http         *http.Client
+	redis        redis.UniversalClient
+	allowedHosts map[string]bool
+	maxBodyBytes int64
+}
+
+// NewClient creates a lookup client; Redis lookups fail when redisClient is nil
+func NewClient(redisClient redis.UniversalClient, opts ...Option) *Client {
+	c := &Client{
+		http:         http.DefaultClient,
+		redis:        redisClient,
+		allowedHosts: make(map[string]bool),
+		maxBodyBytes: defaultMaxBodyBytes,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GetURL fetches rawURL with an HTTP GET and the given headers. A JSON body
+// is decoded; other bodies are returned as a string.
+func (c *Client) GetURL(ctx context.Context, rawURL string, headers map[string]string) (interface{}, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	if len(c.allowedHosts) > 0 && !c.allowedHosts[strings.ToLower(u.Host)] && !c.allowedHosts[strings.ToLower(u.Hostname())] {
+		return nil, fmt.Errorf("host %s is not an allowed lookup host", u.Host)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("lookup returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lookup response: %w", err)
+	}
+	if int64(len(body)) > c.maxBodyBytes {
+		return nil, fmt.Errorf("lookup response exceeds %d bytes", c.maxBodyBytes)
+	}
+	return decode(body), nil
+}
+
+// GetKey reads a Redis string key. A JSON value is decoded; other values are
+// returned as a string.
+func (c *Client) GetKey(ctx context.Context, key string) (interface{}, error) {
+	if c.redis == nil {
+		return nil, fmt.Errorf("redis lookups are not available")
+	}
+
+	data, err := c.redis.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > c.maxBodyBytes {
+		return nil, fmt.Errorf("lookup value exceeds %d bytes", c.maxBodyBytes)
+	}
+	return decode(data), nil
+}
+
+// GetList reads the last n entries of a Redis list, oldest first, decoding
+// each like GetKey. A missing list has no entries.
+func (c *Client) GetList(ctx context.Context, key string, n int) ([]interface{}, error) {
+	if c.redis == nil {
+		return nil, fmt.Errorf("redis lookups are not available")
+	}
+
+	entries, err := c.redis.LRange(ctx, key, int64(-n), -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, 0, len(entries))
+	var size int64
+	for _, entry := range entries {
+		if size += int64(len(entry)); size > c.maxBodyBytes {
+			return nil, fmt.Errorf("list %s exceeds %d bytes", key, c.maxBodyBytes)
+		}
+		values = append(values, decode([]byte(entry)))
+	}
+	return values, nil
+}
+
+// decode parses data as JSON, or returns it as a string when it is not JSON
+func decode(data []byte) interface{} {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return string(data)
+	}
+	return value
+}
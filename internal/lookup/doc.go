@@ -0,0 +1,21 @@
+// Package lookup fetches the external data node configs declare as lookups,
+// so routing can depend on data that is not part of the graph state, such as
+// a customer tier held by a CRM.
+//
+// A Client reads two kinds of sources:
+//
+//   - GetURL issues an HTTP GET and decodes a JSON response body, falling
+//     back to the body as a string
+//   - GetKey reads a Redis string key, decoded the same way
+//   - GetList reads the last entries of a Redis list, such as the message
+//     history of an execution
+//
+// URLs are restricted to the hosts the client was created with, when any, so
+// templated URLs cannot reach arbitrary services. Responses are bounded by
+// the client's maximum body size.
+//
+// Example usage:
+//
+//	client := lookup.NewClient(redisClient, lookup.WithAllowedHosts([]string{"crm.internal"}))
+//	tier, err := client.GetURL(ctx, "https://crm.internal/customers/42/tier", nil)
+package lookup
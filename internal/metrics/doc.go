@@ -0,0 +1,37 @@
+// Package metrics provides Prometheus metrics for the router worker.
+//
+// Metrics are registered on a dedicated registry and served by the health
+// server under /metrics:
+//
+//	mux.Handle("/metrics", metrics.Handler())
+//
+// Exposed metrics:
+//   - dago_router_routing_decisions_total{mode, path_taken, target}
+//   - dago_router_routing_errors_total{mode}
+//   - dago_router_cel_evaluation_duration_seconds
+//   - dago_router_cel_evaluation_errors_total
+//   - dago_router_llm_call_duration_seconds{model}
+//   - dago_router_llm_call_errors_total{model}
+//   - dago_router_llm_tokens_total{tenant, type, source}
+//   - dago_router_llm_cache_requests_total{result}
+//   - dago_router_llm_circuit_state{tenant}
+//   - dago_router_llm_circuit_rejections_total{tenant}
+//   - dago_router_stream_lag_seconds
+//   - dago_router_messages_processed_total{status}
+//   - dago_router_messages_claimed_total
+//   - dago_router_publish_retries_total
+//   - dago_router_publish_batch_size
+//   - dago_router_config_stale{source, reason}
+//   - dago_router_messages_dead_lettered_total
+//   - dago_router_messages_acked_total
+//
+// Labels taken from requests are bounded: LabelSet keeps the first values of
+// a label up to a limit (SetLabelLimit) and reports later ones as
+// OtherLabel.
+//
+// Example usage:
+//
+//	start := time.Now()
+//	result, err := evaluator.Evaluate(ctx, expression, vars)
+//	metrics.CELEvaluationDuration.Observe(metrics.Since(start))
+package metrics
@@ -0,0 +1,81 @@
+package metrics
+
+import "sync"
+
+// OtherLabel replaces label values beyond the bound of their label
+const OtherLabel = "other"
+
+// DefaultLabelLimit is the number of distinct values kept per bounded label
+const DefaultLabelLimit = 100
+
+// LabelSet bounds the values of a label that come from requests, such as
+// tenant or node IDs: the first values seen, up to the limit, are kept and
+// later ones are reported as OtherLabel, so no request can add series
+// without bound
+type LabelSet struct {
+	mu     sync.RWMutex
+	limit  int
+	values map[string]bool
+}
+
+// NewLabelSet creates a label set keeping up to limit values
+func NewLabelSet(limit int) *LabelSet {
+	return &LabelSet{limit: limit, values: make(map[string]bool)}
+}
+
+// Value returns value when the set keeps it, OtherLabel otherwise
+func (s *LabelSet) Value(value string) string {
+	s.mu.RLock()
+	kept := s.values[value]
+	s.mu.RUnlock()
+	if kept {
+		return value
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values[value] {
+		return value
+	}
+	if len(s.values) >= s.limit {
+		return OtherLabel
+	}
+	s.values[value] = true
+	return value
+}
+
+// Declare keeps values regardless of the limit, e.g. the tenants given their
+// own quotas; they count toward it
+func (s *LabelSet) Declare(values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, value := range values {
+		s.values[value] = true
+	}
+}
+
+// SetLimit changes the number of values kept; values already kept stay
+func (s *LabelSet) SetLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = limit
+}
+
+var (
+	// Tenants bounds the tenant label
+	Tenants = NewLabelSet(DefaultLabelLimit)
+	// Nodes bounds the node_id label
+	Nodes = NewLabelSet(DefaultLabelLimit)
+	// Rules bounds the rule label
+	Rules = NewLabelSet(DefaultLabelLimit)
+	// Stages bounds the pipeline stage label
+	Stages = NewLabelSet(DefaultLabelLimit)
+)
+
+// SetLabelLimit sets the number of values kept by the tenant, node_id, rule
+// and stage labels
+func SetLabelLimit(limit int) {
+	for _, set := range []*LabelSet{Tenants, Nodes, Rules, Stages} {
+		set.SetLimit(limit)
+	}
+}
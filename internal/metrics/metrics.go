@@ -0,0 +1,655 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace is the metric name prefix for all router metrics
+const namespace = "dago_router"
+
+// Registry holds all router metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// RoutingDecisions counts routing decisions by mode, path and target.
+	// Targets computed by target_expr rules are reported as OtherLabel
+	// unless the config declares them, which bounds the target label to the
+	// targets written in configs.
+	RoutingDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "routing_decisions_total",
+		Help:      "Routing decisions by mode, path taken and target.",
+	}, []string{"mode", "path_taken", "target"})
+
+	// RoutingErrors counts routing failures by mode
+	RoutingErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "routing_errors_total",
+		Help:      "Routing requests that failed without a decision.",
+	}, []string{"mode"})
+
+	// CELEvaluationDuration observes CEL condition evaluation latency
+	CELEvaluationDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cel_evaluation_duration_seconds",
+		Help:      "CEL condition evaluation latency.",
+		Buckets:   []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05},
+	})
+
+	// CELEvaluationErrors counts CEL evaluation failures
+	CELEvaluationErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cel_evaluation_errors_total",
+		Help:      "CEL condition evaluations that failed.",
+	})
+
+	// LLMCallDuration observes LLM call latency by model
+	LLMCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_call_duration_seconds",
+		Help:      "LLM call latency.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"model"})
+
+	// LLMCallErrors counts failed LLM calls by model
+	LLMCallErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_call_errors_total",
+		Help:      "LLM calls that failed.",
+	}, []string{"model"})
+
+	// LLMTimeouts counts LLM calls that exceeded their timeout by model; they
+	// are also counted in LLMCallErrors
+	LLMTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_call_timeouts_total",
+		Help:      "LLM calls that exceeded their timeout.",
+	}, []string{"model"})
+
+	// LLMTokens counts LLM tokens by tenant, type (input, output) and source
+	// (provider, or estimated locally when the provider reports no usage)
+	LLMTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "LLM tokens consumed by tenant, token type and count source.",
+	}, []string{"tenant", "type", "source"})
+
+	// LLMCacheRequests counts LLM response cache lookups by result (hit, miss)
+	LLMCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_cache_requests_total",
+		Help:      "LLM response cache lookups by result.",
+	}, []string{"result"})
+
+	// DecisionCacheRequests counts deterministic decision cache lookups by
+	// result (hit, miss, bypass)
+	DecisionCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decision_cache_requests_total",
+		Help:      "Deterministic decision cache lookups by result.",
+	}, []string{"result"})
+
+	// StateCacheRequests counts local state cache lookups by result (hit, miss)
+	StateCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "state_cache_requests_total",
+		Help:      "Local state cache lookups by result.",
+	}, []string{"result"})
+
+	// StateCacheInvalidations counts local state cache invalidations by source
+	// (message, write, resubscribe)
+	StateCacheInvalidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "state_cache_invalidations_total",
+		Help:      "Local state cache invalidations by source.",
+	}, []string{"source"})
+
+	// AuditErrors counts routing decisions that could not be recorded in the
+	// audit log
+	AuditErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_errors_total",
+		Help:      "Routing decisions that could not be recorded in the audit log.",
+	})
+
+	// ShadowDecisions counts shadow config evaluations by comparison with the
+	// primary decision (match, diverge, error, skipped)
+	ShadowDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shadow_decisions_total",
+		Help:      "Shadow config evaluations by comparison with the primary decision.",
+	}, []string{"result"})
+
+	// ShadowDivergences counts diverging shadow decisions by primary and
+	// shadow target
+	ShadowDivergences = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shadow_divergences_total",
+		Help:      "Diverging shadow decisions by primary and shadow target.",
+	}, []string{"primary_target", "shadow_target"})
+
+	// ExperimentDecisions counts experiment decisions by experiment, arm
+	// (control or variant) and target; failed decisions have target "error"
+	ExperimentDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "experiment_decisions_total",
+		Help:      "Experiment decisions by experiment, arm and target.",
+	}, []string{"experiment", "variant", "target"})
+
+	// ExperimentPublishErrors counts experiment results that could not be
+	// published to the experiment results stream
+	ExperimentPublishErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "experiment_publish_errors_total",
+		Help:      "Experiment results that could not be published.",
+	})
+
+	// LLMRateLimited counts LLM calls rejected by the rate limiter by the
+	// limit that rejected them (global, node, concurrency)
+	LLMRateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_rate_limited_total",
+		Help:      "LLM calls rejected by the rate limiter by limit.",
+	}, []string{"scope"})
+
+	// TenantRequests counts routing requests by tenant and outcome
+	// (success, error, throttled)
+	TenantRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tenant_requests_total",
+		Help:      "Routing requests by tenant and outcome.",
+	}, []string{"tenant", "status"})
+
+	// TenantInFlight reports routing requests in flight by tenant, under
+	// tenant quotas
+	TenantInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tenant_in_flight",
+		Help:      "Routing requests in flight by tenant under tenant quotas.",
+	}, []string{"tenant"})
+
+	// TenantQuotaRejections counts requests and LLM calls rejected by tenant
+	// quotas, by the limit that rejected them (rps, concurrency, llm_budget)
+	TenantQuotaRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tenant_quota_rejections_total",
+		Help:      "Routing requests and LLM calls rejected by tenant quotas by limit.",
+	}, []string{"tenant", "limit"})
+
+	// TenantLLMBudgetRemaining reports the LLM tokens left in each budgeted
+	// tenant's current window
+	TenantLLMBudgetRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tenant_llm_budget_remaining",
+		Help:      "LLM tokens left in the current budget window by tenant.",
+	}, []string{"tenant"})
+
+	// LLMLimiterWait measures how long LLM calls waited for the rate limiter
+	LLMLimiterWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_limiter_wait_seconds",
+		Help:      "Time LLM calls waited for the rate limiter.",
+		Buckets:   []float64{.001, .005, .01, .05, .1, .25, .5, 1, 2.5, 5},
+	})
+
+	// LLMInFlight reports the LLM calls holding a concurrency slot
+	LLMInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "llm_in_flight",
+		Help:      "LLM calls in flight under the concurrency limit.",
+	})
+
+	// LLMCircuitState reports the LLM circuit breaker state by tenant (0 closed, 1 open, 2 half-open)
+	LLMCircuitState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "llm_circuit_state",
+		Help:      "LLM circuit breaker state by tenant (0 closed, 1 open, 2 half-open).",
+	}, []string{"tenant"})
+
+	// LLMCircuitRejections counts LLM calls rejected by an open circuit breaker
+	LLMCircuitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_circuit_rejections_total",
+		Help:      "LLM calls rejected by an open circuit breaker.",
+	}, []string{"tenant"})
+
+	// StreamLag reports the age of the last message read from the work stream
+	StreamLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stream_lag_seconds",
+		Help:      "Age of the most recently read work stream message.",
+	})
+
+	// LaneLag reports the age of the last message read from each priority lane
+	LaneLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "lane_lag_seconds",
+		Help:      "Age of the most recently read message of each priority lane.",
+	}, []string{"lane"})
+
+	// LaneMessages counts work messages handled from each priority lane
+	LaneMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lane_messages_total",
+		Help:      "Work messages handled from each priority lane.",
+	}, []string{"lane"})
+
+	// ConsumerGroupLag reports the entries of each work stream not yet
+	// delivered to the consumer group
+	ConsumerGroupLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_group_lag",
+		Help:      "Work stream entries not yet delivered to the consumer group.",
+	}, []string{"stream"})
+
+	// ConsumerGroupPending reports the entries of each work stream delivered
+	// but not yet acked
+	ConsumerGroupPending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_group_pending",
+		Help:      "Work stream entries delivered to the consumer group but not yet acked.",
+	}, []string{"stream"})
+
+	// OldestPendingAge reports the age of the oldest pending entry of each
+	// work stream
+	OldestPendingAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "oldest_pending_seconds",
+		Help:      "Age of the oldest work stream entry delivered but not yet acked.",
+	}, []string{"stream"})
+
+	// RedisConnected is 1 while the work loop can reach Redis
+	RedisConnected = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "redis_connected",
+		Help:      "Whether the work loop can reach Redis (1) or is reconnecting (0).",
+	})
+
+	// RedisReconnects counts recoveries after Redis became unreachable
+	RedisReconnects = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_reconnects_total",
+		Help:      "Recoveries of the work loop after Redis became unreachable.",
+	})
+
+	// ConsumerGroupsRecreated counts consumer group re-creations after the
+	// work stream or group was deleted
+	ConsumerGroupsRecreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "consumer_groups_recreated_total",
+		Help:      "Consumer group re-creations after the work stream or group was deleted.",
+	})
+
+	// MessagesProcessed counts handled work messages by status
+	MessagesProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_processed_total",
+		Help:      "Work stream messages processed by status (success, error, invalid).",
+	}, []string{"status"})
+
+	// MessagesRetried counts work messages left pending for redelivery
+	// after a retryable failure
+	MessagesRetried = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_retried_total",
+		Help:      "Work stream messages left pending for redelivery after a retryable failure.",
+	})
+
+	// RequestTimeouts counts work requests whose routing exceeded the
+	// request timeout
+	RequestTimeouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "request_timeouts_total",
+		Help:      "Work requests whose routing exceeded the request timeout.",
+	})
+
+	// MessagesClaimed counts pending messages claimed from other consumers
+	MessagesClaimed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_claimed_total",
+		Help:      "Pending work stream messages claimed from idle consumers.",
+	})
+
+	// PublishRetries counts retried result stream publishes
+	PublishRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "publish_retries_total",
+		Help:      "Result stream publishes retried after a failure.",
+	})
+
+	// ConfigStale is 1 for configuration sources stale for a reason (deleted,
+	// modified, expired) and 0 otherwise
+	ConfigStale = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_stale",
+		Help:      "Whether a loaded configuration source is stale, by reason.",
+	}, []string{"source", "reason"})
+
+	// TemplateLibraryTemplates is the number of template versions in the
+	// prompt template library
+	TemplateLibraryTemplates = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "template_library_templates",
+		Help:      "Prompt template versions loaded in the template library.",
+	})
+
+	// TemplateLibraryReloads counts prompt template library reloads by
+	// result (success, error)
+	TemplateLibraryReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "template_library_reloads_total",
+		Help:      "Prompt template library reloads by result.",
+	}, []string{"result"})
+
+	// RuleSets is the number of rule set versions in the rule set registry
+	RuleSets = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rule_sets",
+		Help:      "Rule set versions loaded in the rule set registry.",
+	})
+
+	// RuleSetReloads counts rule set registry reloads by result (success,
+	// error)
+	RuleSetReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rule_set_reloads_total",
+		Help:      "Rule set registry reloads by result.",
+	}, []string{"result"})
+
+	// Redactions counts values masked before reaching logs, audit records or
+	// prompts, by rule (field, a built-in pattern name, regex)
+	Redactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redactions_total",
+		Help:      "Values masked by redaction, by rule.",
+	}, []string{"rule"})
+
+	// LLMAbstentions counts LLM answers routed to their abstain target for
+	// a confidence below the minimum, by mode
+	LLMAbstentions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_abstentions_total",
+		Help:      "LLM answers below the minimum confidence routed to the abstain target, by mode.",
+	}, []string{"mode"})
+
+	// HistoryTruncations counts message histories shortened to fit their
+	// token budget
+	HistoryTruncations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "history_truncations_total",
+		Help:      "Message histories truncated to their token budget.",
+	})
+
+	// LLMStreamEarlyStops counts streamed LLM answers canceled once they
+	// settled on a route key, by model
+	LLMStreamEarlyStops = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_stream_early_stops_total",
+		Help:      "Streamed LLM answers stopped early once their route was determined, by model.",
+	}, []string{"model"})
+
+	// ClassifierPredictions counts classifier mode predictions by model and
+	// result (matched, low_confidence, unrouted, unknown_input)
+	ClassifierPredictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "classifier_predictions_total",
+		Help:      "Classifier mode predictions by model and result.",
+	}, []string{"model", "result"})
+
+	// PipelineStages counts pipeline stage outcomes by node, stage label,
+	// stage type (rules, keyword, classifier, llm) and result (matched,
+	// no_match, error, skipped)
+	PipelineStages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pipeline_stages_total",
+		Help:      "Pipeline stage outcomes by node, stage, stage type and result.",
+	}, []string{"node_id", "stage", "type", "result"})
+
+	// RuleEvaluations counts rule evaluations by node, rule path (e.g.
+	// rules[0], fast_rules[2]) and result (matched, no_match, error, skipped)
+	RuleEvaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rule_evaluations_total",
+		Help:      "Rule evaluations by node, rule and result.",
+	}, []string{"node_id", "rule", "result"})
+
+	// RuleReorders counts rule order changes made by the rule optimizer, by
+	// node
+	RuleReorders = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rule_reorders_total",
+		Help:      "Rule order changes made by the rule optimizer by node.",
+	}, []string{"node_id"})
+
+	// EmbeddingRoutes counts embedding mode routings by result (matched,
+	// low_similarity, error)
+	EmbeddingRoutes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "embedding_routes_total",
+		Help:      "Embedding mode routings by result.",
+	}, []string{"result"})
+
+	// PromptTrims counts prompts over their token budget trimmed, by trim
+	// order section
+	PromptTrims = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "prompt_trims_total",
+		Help:      "Prompts over their token budget trimmed, by section.",
+	}, []string{"section"})
+
+	// PromptBudgetExceeded counts prompts still over their token budget after
+	// trimming, which take the fallback route
+	PromptBudgetExceeded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "prompt_budget_exceeded_total",
+		Help:      "Prompts over their token budget after trimming.",
+	})
+
+	// PromptGuardActions counts prompt guard interventions by action
+	// (stripped, truncated, rejected)
+	PromptGuardActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "prompt_guard_actions_total",
+		Help:      "Prompt injection guard interventions by action.",
+	}, []string{"action"})
+
+	// VaultRefreshes counts periodic re-reads of Vault secrets by result
+	// (success, error)
+	VaultRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "vault_refreshes_total",
+		Help:      "Periodic Vault secret refreshes by result.",
+	}, []string{"result"})
+
+	// GRPCRequests counts gRPC routing requests by status code
+	GRPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC routing requests by status code.",
+	}, []string{"code"})
+
+	// PublishBatchSize observes the number of outcomes flushed per publish batch
+	PublishBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "publish_batch_size",
+		Help:      "Outcomes flushed per pipelined publish batch.",
+		Buckets:   []float64{1, 2, 5, 10, 25, 50, 100, 250},
+	})
+
+	// ReadBatchSize observes the number of work messages read per XREADGROUP
+	ReadBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "read_batch_size",
+		Help:      "Work stream messages read and acked per batch.",
+		Buckets:   []float64{1, 2, 5, 10, 25, 50, 100, 250},
+	})
+
+	// MessagesDeadLettered counts work messages moved to the dead letter stream
+	MessagesDeadLettered = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_dead_lettered_total",
+		Help:      "Work stream messages moved to the dead letter stream.",
+	})
+
+	// LoopsDetected counts work requests refused by the MAX_HOPS loop guard
+	LoopsDetected = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "loops_detected_total",
+		Help:      "Work requests refused because their execution reached MAX_HOPS routing decisions.",
+	})
+
+	// RequestUnknownFields counts top-level work request fields outside the
+	// work request schema
+	RequestUnknownFields = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "request_unknown_fields_total",
+		Help:      "Top-level work request fields not in the work request schema.",
+	})
+
+	// Lookups counts node config lookups by source (http, redis) and result
+	// (cached, fetched, error)
+	Lookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lookups_total",
+		Help:      "Node config lookups by source and result.",
+	}, []string{"source", "result"})
+
+	// FlagEvaluations counts feature flag evaluations by result (cached,
+	// fetched, not_found, error)
+	FlagEvaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "flag_evaluations_total",
+		Help:      "Feature flag evaluations by result.",
+	}, []string{"result"})
+
+	// StickyLookups counts sticky routing lookups by result (hit, miss,
+	// state_changed)
+	StickyLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sticky_lookups_total",
+		Help:      "Sticky routing lookups by result.",
+	}, []string{"result"})
+
+	// DuplicatesSkipped counts redelivered messages whose outcome was already published
+	DuplicatesSkipped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicates_skipped_total",
+		Help:      "Redelivered work messages skipped because their outcome was already published.",
+	})
+
+	// MessagesAcked counts acknowledged work messages
+	MessagesAcked = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_acked_total",
+		Help:      "Work stream messages acknowledged.",
+	})
+
+	// StateWrites counts decisions written into execution states by mode
+	// (outbox: atomically with the publish, direct: before it)
+	StateWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "state_writes_total",
+		Help:      "Decisions written into execution states by mode.",
+	}, []string{"mode"})
+
+	// ChaosFaults counts faults injected by the chaos layer by fault
+	// (redis_read, llm_error, llm_latency, state_corruption)
+	ChaosFaults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chaos_faults_total",
+		Help:      "Faults injected by the chaos layer by fault.",
+	}, []string{"fault"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RoutingDecisions,
+		RoutingErrors,
+		CELEvaluationDuration,
+		CELEvaluationErrors,
+		LLMCallDuration,
+		LLMCallErrors,
+		LLMTimeouts,
+		LLMTokens,
+		LLMCacheRequests,
+		DecisionCacheRequests,
+		StateCacheRequests,
+		StateCacheInvalidations,
+		AuditErrors,
+		ShadowDecisions,
+		ShadowDivergences,
+		ExperimentDecisions,
+		ExperimentPublishErrors,
+		LLMRateLimited,
+		TenantRequests,
+		TenantInFlight,
+		TenantQuotaRejections,
+		TenantLLMBudgetRemaining,
+		LLMLimiterWait,
+		LLMInFlight,
+		LLMCircuitState,
+		LLMCircuitRejections,
+		StreamLag,
+		LaneLag,
+		LaneMessages,
+		ConsumerGroupLag,
+		ConsumerGroupPending,
+		OldestPendingAge,
+		RedisConnected,
+		RedisReconnects,
+		ConsumerGroupsRecreated,
+		MessagesProcessed,
+		MessagesRetried,
+		RequestTimeouts,
+		MessagesClaimed,
+		PublishRetries,
+		PublishBatchSize,
+		ReadBatchSize,
+		ConfigStale,
+		TemplateLibraryTemplates,
+		TemplateLibraryReloads,
+		RuleSets,
+		RuleSetReloads,
+		Redactions,
+		PromptGuardActions,
+		LLMAbstentions,
+		HistoryTruncations,
+		PromptTrims,
+		ClassifierPredictions,
+		EmbeddingRoutes,
+		PipelineStages,
+		RuleEvaluations,
+		RuleReorders,
+		LLMStreamEarlyStops,
+		PromptBudgetExceeded,
+		VaultRefreshes,
+		GRPCRequests,
+		MessagesDeadLettered,
+		RequestUnknownFields,
+		LoopsDetected,
+		StickyLookups,
+		Lookups,
+		FlagEvaluations,
+		MessagesAcked,
+		DuplicatesSkipped,
+		StateWrites,
+		ChaosFaults,
+	)
+}
+
+// Handler returns the HTTP handler serving the router metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Since returns the seconds elapsed since start, for histogram observations
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
@@ -0,0 +1,16 @@
+// Package ollama checks and warms up a local Ollama inference server.
+//
+// LLM calls to a local server go through the dago-adapters Ollama client.
+// This package covers what that client does not: a health probe that
+// verifies the server answers and has the routing model pulled, and a
+// warm-up request that loads the model into memory at startup, so the first
+// routed message does not wait for the model to load.
+//
+// Example usage:
+//
+//	probe := ollama.NewProbe("http://localhost:11434", "llama3.1")
+//	if err := probe.Warmup(ctx); err != nil {
+//	    logger.Warn("model warm-up failed", zap.Error(err))
+//	}
+//	err := probe.Health(ctx)
+package ollama
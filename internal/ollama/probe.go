@@ -0,0 +1,128 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the address of a local Ollama server
+const DefaultBaseURL = "http://localhost:11434"
+
+// defaultKeepAlive keeps the warmed-up model loaded between routing calls
+const defaultKeepAlive = "30m"
+
+// Probe checks the health of an Ollama server and warms up a model
+type Probe struct {
+	baseURL    string
+	model      string
+	keepAlive  string
+	httpClient *http.Client
+}
+
+// Option configures a Probe
+type Option func(*Probe)
+
+// WithHTTPClient sets the HTTP client of probe requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Probe) {
+		if client != nil {
+			p.httpClient = client
+		}
+	}
+}
+
+// WithKeepAlive sets how long the server keeps the model loaded after the
+// warm-up (an Ollama duration such as "30m", or "-1" for ever)
+func WithKeepAlive(keepAlive string) Option {
+	return func(p *Probe) {
+		if keepAlive != "" {
+			p.keepAlive = keepAlive
+		}
+	}
+}
+
+// NewProbe creates a probe of the server at baseURL for model
+func NewProbe(baseURL, model string, opts ...Option) *Probe {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	p := &Probe{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		keepAlive:  defaultKeepAlive,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Health reports whether the server answers and has the model pulled
+func (p *Probe) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama returned status %d", resp.StatusCode)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return fmt.Errorf("invalid ollama model list: %w", err)
+	}
+	for _, model := range tags.Models {
+		if model.Name == p.model || strings.TrimSuffix(model.Name, ":latest") == p.model {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %s is not pulled on the ollama server", p.model)
+}
+
+// Warmup loads the model into server memory. A generate request without a
+// prompt loads the model without generating anything.
+func (p *Probe) Warmup(ctx context.Context) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"model":      p.model,
+		"keep_alive": p.keepAlive,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Loading a large model can take longer than a health probe
+	client := *p.httpClient
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama warm-up failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("ollama warm-up returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
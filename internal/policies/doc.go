@@ -0,0 +1,34 @@
+// Package policies provides a registry of named, versioned rule sets, so
+// node configs can reference shared routing logic with rules_ref instead of
+// repeating the same rules in dozens of nodes.
+//
+// Rule sets are identified as name@version and stored as JSON documents with
+// the rules and groups of a node config:
+//
+//	{"rules": [{"condition": "state.inputs.tier == 'vip'", "target": "vip_agent"}], "groups": {}}
+//
+// A Source loads every rule set of the registry:
+//
+//   - DirSource reads one file per rule set version from a directory, named
+//     <name>@<version>.json
+//   - RedisSource reads a Redis hash whose fields are <name>@<version> and
+//     whose values are the rule sets
+//
+// A reference without a version, or with version "latest", resolves to the
+// highest version of the name. An entry <name>@latest whose value is a
+// version pins "latest" to that version instead, which rolls back every
+// node referencing the rule set without a version.
+//
+// A Registry serves the rule sets loaded last and reloads them periodically,
+// keeping the previous rule sets when a reload fails.
+//
+// Example usage:
+//
+//	registry := policies.NewRegistry(policies.NewRedisSource(redisClient, "router:policies"), logger)
+//	if err := registry.Reload(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//	go registry.Run(ctx, 30*time.Second)
+//
+//	ruleSet, err := registry.Resolve("vip-routing@v2")
+package policies
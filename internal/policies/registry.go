@@ -0,0 +1,284 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned for references to rule sets not in the registry
+var ErrNotFound = errors.New("rule set not found")
+
+// LatestVersion references the highest (or pinned) version of a rule set
+const LatestVersion = "latest"
+
+// Document is one stored entry: a rule set version, or the version a name's
+// "latest" is pinned to when Version is LatestVersion
+type Document struct {
+	Name    string
+	Version string
+	Content string
+}
+
+// RuleSet is one version of a named rule set. Rules and Groups hold the
+// JSON of the node config fields of the same names.
+type RuleSet struct {
+	Name    string          `json:"name"`
+	Version string          `json:"version"`
+	Rules   json.RawMessage `json:"rules"`
+	Groups  json.RawMessage `json:"groups,omitempty"`
+}
+
+// Ref returns the name@version of the rule set
+func (s *RuleSet) Ref() string {
+	return s.Name + "@" + s.Version
+}
+
+// Entry describes the versions of a rule set in the registry
+type Entry struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"`
+	Latest   string   `json:"latest"`
+	// Pinned is set when latest is pinned below the highest version
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+// Source loads every document of a registry
+type Source interface {
+	Load(ctx context.Context) ([]Document, error)
+}
+
+// Registry serves named, versioned rule sets loaded from a Source
+type Registry struct {
+	source Source
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	sets     map[string]map[string]*RuleSet
+	pins     map[string]string
+	loadedAt time.Time
+}
+
+// NewRegistry creates an empty registry; call Reload to load its rule sets
+func NewRegistry(source Source, logger *zap.Logger) *Registry {
+	return &Registry{
+		source: source,
+		logger: logger,
+		sets:   make(map[string]map[string]*RuleSet),
+		pins:   make(map[string]string),
+	}
+}
+
+// Reload replaces the rule sets with those of the source. On failure,
+// including a malformed rule set or a pin to a missing version, the previous
+// rule sets are kept.
+func (r *Registry) Reload(ctx context.Context) error {
+	sets, pins, err := r.load(ctx)
+	if err != nil {
+		metrics.RuleSetReloads.WithLabelValues("error").Inc()
+		return err
+	}
+
+	count := 0
+	for _, versions := range sets {
+		count += len(versions)
+	}
+
+	r.mu.Lock()
+	r.sets = sets
+	r.pins = pins
+	r.loadedAt = time.Now()
+	r.mu.Unlock()
+
+	metrics.RuleSetReloads.WithLabelValues("success").Inc()
+	metrics.RuleSets.Set(float64(count))
+	r.logger.Debug("rule sets loaded",
+		zap.Int("names", len(sets)),
+		zap.Int("rule_sets", count),
+		zap.Int("pins", len(pins)),
+	)
+	return nil
+}
+
+// load reads and checks the documents of the source
+func (r *Registry) load(ctx context.Context) (map[string]map[string]*RuleSet, map[string]string, error) {
+	docs, err := r.source.Load(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load rule sets: %w", err)
+	}
+
+	sets := make(map[string]map[string]*RuleSet)
+	pins := make(map[string]string)
+	for _, doc := range docs {
+		if doc.Version == LatestVersion {
+			pins[doc.Name] = doc.Content
+			continue
+		}
+
+		set := &RuleSet{Name: doc.Name, Version: doc.Version}
+		if err := json.Unmarshal([]byte(doc.Content), set); err != nil {
+			return nil, nil, fmt.Errorf("rule set %s@%s: %w", doc.Name, doc.Version, err)
+		}
+		set.Name, set.Version = doc.Name, doc.Version
+		if len(set.Rules) == 0 {
+			return nil, nil, fmt.Errorf("rule set %s@%s: rules are required", doc.Name, doc.Version)
+		}
+		if sets[doc.Name] == nil {
+			sets[doc.Name] = make(map[string]*RuleSet)
+		}
+		if _, ok := sets[doc.Name][doc.Version]; ok {
+			return nil, nil, fmt.Errorf("duplicate rule set %s@%s", doc.Name, doc.Version)
+		}
+		sets[doc.Name][doc.Version] = set
+	}
+
+	for name, version := range pins {
+		if _, ok := sets[name][version]; !ok {
+			return nil, nil, fmt.Errorf("rule set %s@latest is pinned to missing version %q", name, version)
+		}
+	}
+	return sets, pins, nil
+}
+
+// Run reloads the rule sets every interval until ctx is cancelled
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(ctx); err != nil {
+				r.logger.Warn("failed to reload rule sets, keeping previous rule sets",
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// Resolve returns the rule set a name@version reference points at. A
+// reference without a version resolves to the pinned or highest version.
+func (r *Registry) Resolve(ref string) (*RuleSet, error) {
+	name, version := ParseRef(ref)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions, ok := r.sets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, ref)
+	}
+	if version == LatestVersion {
+		version = r.latest(name)
+	}
+	set, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, ref)
+	}
+	return set, nil
+}
+
+// List returns the rule sets in the registry, sorted by name, with their
+// versions in ascending order
+func (r *Registry) List() []Entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(r.sets))
+	for name, versions := range r.sets {
+		entry := Entry{Name: name, Latest: r.latest(name)}
+		for version := range versions {
+			entry.Versions = append(entry.Versions, version)
+		}
+		sort.Slice(entry.Versions, func(i, j int) bool {
+			return compareVersions(entry.Versions[i], entry.Versions[j]) < 0
+		})
+		entry.Pinned = entry.Latest != entry.Versions[len(entry.Versions)-1]
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// LoadedAt returns when the rule sets were last loaded
+func (r *Registry) LoadedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.loadedAt
+}
+
+// latest returns the pinned or highest version of a name; the caller holds
+// the lock
+func (r *Registry) latest(name string) string {
+	if pinned, ok := r.pins[name]; ok {
+		return pinned
+	}
+	var best string
+	for version := range r.sets[name] {
+		if best == "" || compareVersions(version, best) > 0 {
+			best = version
+		}
+	}
+	return best
+}
+
+// ParseRef splits a name@version reference. A missing version is
+// LatestVersion.
+func ParseRef(ref string) (name, version string) {
+	name, version, ok := strings.Cut(ref, "@")
+	if !ok || version == "" {
+		return name, LatestVersion
+	}
+	return name, version
+}
+
+// parseID splits the name@version ID of a stored document
+func parseID(id string) (Document, error) {
+	name, version, ok := strings.Cut(id, "@")
+	if !ok || name == "" || version == "" {
+		return Document{}, fmt.Errorf("invalid rule set id %q, expected name@version", id)
+	}
+	return Document{Name: name, Version: version}, nil
+}
+
+// compareVersions orders versions such as v2, v10 and 1.2.3 by their dot
+// separated parts, numerically where both parts are numbers
+func compareVersions(a, b string) int {
+	ap := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bp := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(ap) && i < len(bp); i++ {
+		an, aerr := strconv.Atoi(ap[i])
+		bn, berr := strconv.Atoi(bp[i])
+		switch {
+		case aerr == nil && berr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aerr != nil || berr != nil) && ap[i] != bp[i]:
+			return strings.Compare(ap[i], bp[i])
+		}
+	}
+	switch {
+	case len(ap) < len(bp):
+		return -1
+	case len(ap) > len(bp):
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
@@ -0,0 +1,81 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DirSource loads rule sets from the files of a directory, one file per
+// version named <name>@<version> with any extension. Hidden files and
+// subdirectories are ignored.
+type DirSource struct {
+	dir string
+}
+
+// NewDirSource creates a source reading the rule set files of dir
+func NewDirSource(dir string) *DirSource {
+	return &DirSource{dir: dir}
+}
+
+// Load reads every rule set file of the directory
+func (d *DirSource) Load(ctx context.Context) ([]Document, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule set directory: %w", err)
+	}
+
+	var docs []Document
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		doc, err := parseID(id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		data, err := os.ReadFile(filepath.Join(d.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rule set: %w", err)
+		}
+		doc.Content = strings.TrimSpace(string(data))
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// RedisSource loads rule sets from a Redis hash whose fields are
+// <name>@<version> and whose values are the rule sets
+type RedisSource struct {
+	client redis.UniversalClient
+	key    string
+}
+
+// NewRedisSource creates a source reading the rule set hash at key
+func NewRedisSource(client redis.UniversalClient, key string) *RedisSource {
+	return &RedisSource{client: client, key: key}
+}
+
+// Load reads every field of the hash
+func (s *RedisSource) Load(ctx context.Context) ([]Document, error) {
+	fields, err := s.client.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule set hash %s: %w", s.key, err)
+	}
+
+	docs := make([]Document, 0, len(fields))
+	for id, content := range fields {
+		doc, err := parseID(id)
+		if err != nil {
+			return nil, err
+		}
+		doc.Content = strings.TrimSpace(content)
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
@@ -0,0 +1,27 @@
+// Package prompts provides a library of named, versioned prompt templates, so
+// node configs can reference a shared template with template_ref instead of
+// inlining it.
+//
+// Templates are identified as name@version. A Source loads every template of
+// the library:
+//
+//   - DirSource reads one file per template version from a directory, named
+//     <name>@<version> with any extension (e.g. classify_ticket@v3.hbs)
+//   - RedisSource reads a Redis hash whose fields are <name>@<version> and
+//     whose values are the templates
+//
+// A Library serves the templates loaded last and reloads them periodically,
+// keeping the previous templates when a reload fails. A reference without a
+// version, or with version "latest", resolves to the highest version of the
+// name, comparing numeric parts numerically (v10 > v9).
+//
+// Example usage:
+//
+//	library := prompts.NewLibrary(prompts.NewDirSource("/etc/dago/prompts"), logger)
+//	if err := library.Reload(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//	go library.Run(ctx, 30*time.Second)
+//
+//	tmpl, err := library.Resolve("classify_ticket@v3")
+package prompts
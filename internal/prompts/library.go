@@ -0,0 +1,245 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned for references to templates not in the library
+var ErrNotFound = errors.New("prompt template not found")
+
+// LatestVersion references the highest version of a template
+const LatestVersion = "latest"
+
+// Template is one version of a named prompt template
+type Template struct {
+	Name     string
+	Version  string
+	Template string
+}
+
+// Entry describes the versions of a template in the library
+type Entry struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"`
+	Latest   string   `json:"latest"`
+}
+
+// Source loads every template of a library
+type Source interface {
+	Load(ctx context.Context) ([]Template, error)
+}
+
+// Library serves named, versioned prompt templates loaded from a Source
+type Library struct {
+	source Source
+	logger *zap.Logger
+
+	mu        sync.RWMutex
+	templates map[string]map[string]string
+	loadedAt  time.Time
+}
+
+// NewLibrary creates an empty library; call Reload to load its templates
+func NewLibrary(source Source, logger *zap.Logger) *Library {
+	return &Library{
+		source:    source,
+		logger:    logger,
+		templates: make(map[string]map[string]string),
+	}
+}
+
+// Reload replaces the templates with those of the source. On failure the
+// previous templates are kept.
+func (l *Library) Reload(ctx context.Context) error {
+	loaded, err := l.source.Load(ctx)
+	if err != nil {
+		metrics.TemplateLibraryReloads.WithLabelValues("error").Inc()
+		return fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+
+	templates := make(map[string]map[string]string)
+	for _, t := range loaded {
+		if templates[t.Name] == nil {
+			templates[t.Name] = make(map[string]string)
+		}
+		if _, ok := templates[t.Name][t.Version]; ok {
+			metrics.TemplateLibraryReloads.WithLabelValues("error").Inc()
+			return fmt.Errorf("duplicate prompt template %s@%s", t.Name, t.Version)
+		}
+		templates[t.Name][t.Version] = t.Template
+	}
+
+	l.mu.Lock()
+	changed := !equalTemplates(l.templates, templates)
+	l.templates = templates
+	l.loadedAt = time.Now()
+	l.mu.Unlock()
+
+	metrics.TemplateLibraryReloads.WithLabelValues("success").Inc()
+	metrics.TemplateLibraryTemplates.Set(float64(len(loaded)))
+	if changed {
+		l.logger.Info("prompt templates loaded",
+			zap.Int("names", len(templates)),
+			zap.Int("templates", len(loaded)),
+		)
+	}
+	return nil
+}
+
+// Run reloads the templates every interval until ctx is cancelled
+func (l *Library) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Reload(ctx); err != nil {
+				l.logger.Warn("failed to reload prompt templates, keeping previous templates",
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// Resolve returns the template a name@version reference points at. A
+// reference without a version resolves to the latest version.
+func (l *Library) Resolve(ref string) (string, error) {
+	name, version := ParseRef(ref)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	versions, ok := l.templates[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, ref)
+	}
+	if version == LatestVersion {
+		version = latest(versions)
+	}
+	tmpl, ok := versions[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, ref)
+	}
+	return tmpl, nil
+}
+
+// List returns the templates in the library, sorted by name, with their
+// versions in ascending order
+func (l *Library) List() []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(l.templates))
+	for name, versions := range l.templates {
+		entry := Entry{Name: name, Latest: latest(versions)}
+		for version := range versions {
+			entry.Versions = append(entry.Versions, version)
+		}
+		sort.Slice(entry.Versions, func(i, j int) bool {
+			return compareVersions(entry.Versions[i], entry.Versions[j]) < 0
+		})
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// LoadedAt returns when the templates were last loaded
+func (l *Library) LoadedAt() time.Time {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.loadedAt
+}
+
+// ParseRef splits a name@version reference. A missing version is
+// LatestVersion.
+func ParseRef(ref string) (name, version string) {
+	name, version, ok := strings.Cut(ref, "@")
+	if !ok || version == "" {
+		return name, LatestVersion
+	}
+	return name, version
+}
+
+// parseID splits the name@version ID of a stored template
+func parseID(id string) (Template, error) {
+	name, version, ok := strings.Cut(id, "@")
+	if !ok || name == "" || version == "" || version == LatestVersion {
+		return Template{}, fmt.Errorf("invalid prompt template id %q, expected name@version", id)
+	}
+	return Template{Name: name, Version: version}, nil
+}
+
+// latest returns the highest of a set of versions
+func latest(versions map[string]string) string {
+	var best string
+	for version := range versions {
+		if best == "" || compareVersions(version, best) > 0 {
+			best = version
+		}
+	}
+	return best
+}
+
+// compareVersions orders versions such as v2, v10 and 1.2.3 by their dot
+// separated parts, numerically where both parts are numbers
+func compareVersions(a, b string) int {
+	ap := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bp := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(ap) && i < len(bp); i++ {
+		an, aerr := strconv.Atoi(ap[i])
+		bn, berr := strconv.Atoi(bp[i])
+		switch {
+		case aerr == nil && berr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aerr != nil || berr != nil) && ap[i] != bp[i]:
+			return strings.Compare(ap[i], bp[i])
+		}
+	}
+	switch {
+	case len(ap) < len(bp):
+		return -1
+	case len(ap) > len(bp):
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// equalTemplates reports whether two template sets are identical
+func equalTemplates(a, b map[string]map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, versions := range a {
+		other, ok := b[name]
+		if !ok || len(other) != len(versions) {
+			return false
+		}
+		for version, tmpl := range versions {
+			if o, ok := other[version]; !ok || o != tmpl {
+				return false
+			}
+		}
+	}
+	return true
+}
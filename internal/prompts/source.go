@@ -0,0 +1,81 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DirSource loads templates from the files of a directory, one file per
+// template version named <name>@<version> with any extension. Hidden files
+// and subdirectories are ignored.
+type DirSource struct {
+	dir string
+}
+
+// NewDirSource creates a source reading the template files of dir
+func NewDirSource(dir string) *DirSource {
+	return &DirSource{dir: dir}
+}
+
+// Load reads every template file of the directory
+func (d *DirSource) Load(ctx context.Context) ([]Template, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template directory: %w", err)
+	}
+
+	var templates []Template
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		t, err := parseID(id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		data, err := os.ReadFile(filepath.Join(d.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		t.Template = string(data)
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// RedisSource loads templates from a Redis hash whose fields are
+// <name>@<version> and whose values are the templates
+type RedisSource struct {
+	client redis.UniversalClient
+	key    string
+}
+
+// NewRedisSource creates a source reading the template hash at key
+func NewRedisSource(client redis.UniversalClient, key string) *RedisSource {
+	return &RedisSource{client: client, key: key}
+}
+
+// Load reads every field of the hash
+func (s *RedisSource) Load(ctx context.Context) ([]Template, error) {
+	fields, err := s.client.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template hash %s: %w", s.key, err)
+	}
+
+	templates := make([]Template, 0, len(fields))
+	for id, tmpl := range fields {
+		t, err := parseID(id)
+		if err != nil {
+			return nil, err
+		}
+		t.Template = tmpl
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
@@ -0,0 +1,23 @@
+// Package redact masks customer data before it reaches debug logs, audit
+// records and, optionally, LLM prompts, so debug logging can be enabled in
+// production.
+//
+// A Redactor masks two kinds of data:
+//
+//   - Fields: dotted state paths (e.g. "inputs.customer.email") whose values
+//     are replaced by the mask entirely, whatever their type
+//   - Patterns: built-in patterns ("email", "phone", "credit_card") and
+//     custom regular expressions, replaced by the mask wherever they match in
+//     a string
+//
+// A nil Redactor returns its input unchanged, so callers do not check whether
+// redaction is configured.
+//
+// Example usage:
+//
+//	redactor, err := redact.New(redact.Config{
+//	    Fields:   []string{"inputs.customer.email"},
+//	    Patterns: []string{"email", "phone"},
+//	})
+//	logger.Debug("calling llm", zap.String("prompt", redactor.String(prompt)))
+package redact
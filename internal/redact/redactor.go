@@ -0,0 +1,170 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+)
+
+// DefaultMask replaces redacted values when no mask is configured
+const DefaultMask = "[REDACTED]"
+
+// builtinPatterns are the patterns Config.Patterns can name
+var builtinPatterns = map[string]string{
+	"email":       `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+	"phone":       `\+?\d[\d\-. ()]{7,}\d`,
+	"credit_card": `\b(?:\d[ \-]?){12,18}\d\b`,
+}
+
+// BuiltinPatterns returns the names of the built-in patterns, sorted
+func BuiltinPatterns() []string {
+	names := make([]string, 0, len(builtinPatterns))
+	for name := range builtinPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Config selects what a Redactor masks
+type Config struct {
+	// Fields are dotted state paths masked entirely
+	Fields []string
+	// Patterns are built-in pattern names
+	Patterns []string
+	// Regexes are custom regular expressions
+	Regexes []string
+	// Mask replaces redacted values (DefaultMask when empty)
+	Mask string
+}
+
+// pattern is a compiled pattern and the name it is counted under
+type pattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Redactor masks configured fields and patterns
+type Redactor struct {
+	fields   [][]string
+	patterns []pattern
+	mask     string
+}
+
+// New creates a Redactor, failing on unknown built-in patterns and invalid
+// regular expressions
+func New(cfg Config) (*Redactor, error) {
+	r := &Redactor{mask: cfg.Mask}
+	if r.mask == "" {
+		r.mask = DefaultMask
+	}
+	for _, field := range cfg.Fields {
+		if field = strings.TrimSpace(field); field != "" {
+			r.fields = append(r.fields, strings.Split(field, "."))
+		}
+	}
+	for _, name := range cfg.Patterns {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		expr, ok := builtinPatterns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction pattern %q (valid: %s)", name, strings.Join(BuiltinPatterns(), ", "))
+		}
+		r.patterns = append(r.patterns, pattern{name: name, re: regexp.MustCompile(expr)})
+	}
+	for _, expr := range cfg.Regexes {
+		if expr = strings.TrimSpace(expr); expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction regex %q: %w", expr, err)
+		}
+		r.patterns = append(r.patterns, pattern{name: "regex", re: re})
+	}
+	return r, nil
+}
+
+// String returns s with every pattern match masked
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, p := range r.patterns {
+		masked := p.re.ReplaceAllString(s, r.mask)
+		if masked != s {
+			metrics.Redactions.WithLabelValues(p.name).Inc()
+			s = masked
+		}
+	}
+	return s
+}
+
+// Map returns a copy of data with the configured fields masked and patterns
+// masked in every string value. data itself is not modified.
+func (r *Redactor) Map(data map[string]interface{}) map[string]interface{} {
+	if r == nil || data == nil {
+		return data
+	}
+	redacted, _ := r.value(data).(map[string]interface{})
+	for _, path := range r.fields {
+		if maskPath(redacted, path, r.mask) {
+			metrics.Redactions.WithLabelValues("field").Inc()
+		}
+	}
+	return redacted
+}
+
+// value copies v, masking patterns in strings
+func (r *Redactor) value(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case string:
+		return r.String(typed)
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			copied[key] = r.value(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(typed))
+		for i, item := range typed {
+			copied[i] = r.value(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// maskPath replaces the value at path with mask, reporting whether it was
+// present. Lists along the path are masked in every element.
+func maskPath(node interface{}, path []string, mask string) bool {
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		value, ok := typed[path[0]]
+		if !ok {
+			return false
+		}
+		if len(path) == 1 {
+			typed[path[0]] = mask
+			return true
+		}
+		return maskPath(value, path[1:], mask)
+	case []interface{}:
+		masked := false
+		for _, item := range typed {
+			if maskPath(item, path, mask) {
+				masked = true
+			}
+		}
+		return masked
+	default:
+		return false
+	}
+}
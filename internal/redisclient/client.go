@@ -0,0 +1,82 @@
+package redisclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// New creates the Redis client for the configured REDIS_MODE
+func New(cfg *config.Config) (redis.UniversalClient, error) {
+	tlsConfig, err := TLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.RedisMode {
+	case config.RedisModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.RedisMasterName,
+			SentinelAddrs:    cfg.RedisAddrs,
+			SentinelPassword: cfg.RedisSentinelPassword,
+			Username:         cfg.RedisUsername,
+			Password:         cfg.RedisPassword,
+			DB:               cfg.RedisDB,
+			TLSConfig:        tlsConfig,
+		}), nil
+	case config.RedisModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     cfg.RedisAddrs,
+			Username:  cfg.RedisUsername,
+			Password:  cfg.RedisPassword,
+			TLSConfig: tlsConfig,
+		}), nil
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:      cfg.RedisAddr,
+			Username:  cfg.RedisUsername,
+			Password:  cfg.RedisPassword,
+			DB:        cfg.RedisDB,
+			TLSConfig: tlsConfig,
+		}), nil
+	}
+}
+
+// TLSConfig builds the Redis TLS settings, or returns nil when TLS is disabled
+func TLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if !cfg.RedisTLSEnabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.RedisTLSServerName,
+		InsecureSkipVerify: cfg.RedisTLSSkipVerify,
+	}
+
+	if cfg.RedisTLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.RedisTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis ca file %s contains no certificates", cfg.RedisTLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.RedisTLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.RedisTLSCertFile, cfg.RedisTLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
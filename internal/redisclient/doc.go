@@ -0,0 +1,19 @@
+// Package redisclient builds the Redis client from the worker configuration.
+//
+// REDIS_MODE selects a single server, a Sentinel managed master or a Redis
+// Cluster, and REDIS_TLS_* settings enable TLS with an optional custom CA and
+// client certificate. Every mode returns a redis.UniversalClient, so the
+// worker, state store, audit log and caches work unchanged in all of them.
+//
+// In cluster mode, keys used together by one command must hash to the same
+// slot. Give sharded streams a common hash tag, e.g. STREAM_KEY={router}.work,
+// so a worker can read its shards in a single XREADGROUP.
+//
+// Example usage:
+//
+//	client, err := redisclient.New(cfg)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer client.Close()
+package redisclient
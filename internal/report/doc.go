@@ -0,0 +1,25 @@
+// Package report builds A/B analysis reports for routing experiments.
+//
+// Decisions published by the worker carry the experiment variant: the arm of
+// the node config experiment, or else the `experiment_bucket` request header.
+// The report joins them by execution ID with outcome events from a feedback
+// stream and aggregates per-variant outcome statistics, so experiments can be
+// evaluated without a warehouse. Experiment decisions are also copied to the
+// experiment stream, which can be reported on instead of the result stream.
+//
+// Feedback events are stream entries whose `data` field holds JSON:
+//
+//	{"execution_id": "exec-123", "outcome": "converted"}
+//
+// Example usage:
+//
+//	r, err := report.Build(ctx, redisClient, report.Options{
+//	    DecisionStream: "router.decided",
+//	    FeedbackStream: "router.feedback",
+//	    Since:          24 * time.Hour,
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	r.WriteTable(os.Stdout)
+package report
@@ -0,0 +1,193 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Well-known feedback outcomes used for the summary rates
+const (
+	OutcomeConverted = "converted"
+	OutcomeEscalated = "escalated"
+)
+
+// noVariant groups decisions made outside an experiment
+const noVariant = "(none)"
+
+// pageSize is the number of stream entries read per XRANGE call
+const pageSize = 1000
+
+// Options configures which streams and time window a report covers
+type Options struct {
+	DecisionStream string
+	FeedbackStream string
+	Since          time.Duration
+	// Experiment restricts the report to the decisions of one experiment
+	Experiment string
+}
+
+// VariantStats holds the outcome statistics of one experiment variant
+type VariantStats struct {
+	Variant        string         `json:"variant"`
+	Decisions      int            `json:"decisions"`
+	Fallbacks      int            `json:"fallbacks"`
+	WithFeedback   int            `json:"with_feedback"`
+	Outcomes       map[string]int `json:"outcomes"`
+	Targets        map[string]int `json:"targets"`
+	ConversionRate float64        `json:"conversion_rate"`
+	EscalationRate float64        `json:"escalation_rate"`
+}
+
+// Report is the per-variant analysis of routing decisions in a time window
+type Report struct {
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Variants []*VariantStats `json:"variants"`
+}
+
+// decision is the subset of a published decision used by the report
+type decision struct {
+	ExecutionID string `json:"execution_id"`
+	TargetNode  string `json:"target_node"`
+	PathTaken   string `json:"path_taken"`
+	Variant     string `json:"variant"`
+	Experiment  string `json:"experiment_id"`
+}
+
+// feedback is an outcome event for an execution
+type feedback struct {
+	ExecutionID string `json:"execution_id"`
+	Outcome     string `json:"outcome"`
+}
+
+// Build reads decisions and feedback from the last opts.Since and aggregates
+// them per variant. An execution's latest feedback outcome counts once.
+func Build(ctx context.Context, client redis.Cmdable, opts Options) (*Report, error) {
+	to := time.Now()
+	from := to.Add(-opts.Since)
+
+	decisions, err := readStream[decision](ctx, client, opts.DecisionStream, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read decisions: %w", err)
+	}
+
+	events, err := readStream[feedback](ctx, client, opts.FeedbackStream, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feedback: %w", err)
+	}
+
+	outcomes := make(map[string]string, len(events))
+	for _, event := range events {
+		if event.ExecutionID != "" && event.Outcome != "" {
+			outcomes[event.ExecutionID] = event.Outcome
+		}
+	}
+
+	byVariant := make(map[string]*VariantStats)
+	counted := make(map[string]bool)
+	for _, d := range decisions {
+		if opts.Experiment != "" && d.Experiment != opts.Experiment {
+			continue
+		}
+		variant := d.Variant
+		if variant == "" {
+			variant = noVariant
+		}
+
+		stats, ok := byVariant[variant]
+		if !ok {
+			stats = &VariantStats{
+				Variant:  variant,
+				Outcomes: make(map[string]int),
+				Targets:  make(map[string]int),
+			}
+			byVariant[variant] = stats
+		}
+
+		stats.Decisions++
+		stats.Targets[d.TargetNode]++
+		if d.PathTaken == "fallback" {
+			stats.Fallbacks++
+		}
+
+		// An execution may pass several router nodes; attribute its outcome once
+		key := variant + "\x00" + d.ExecutionID
+		if outcome, ok := outcomes[d.ExecutionID]; ok && !counted[key] {
+			counted[key] = true
+			stats.WithFeedback++
+			stats.Outcomes[outcome]++
+		}
+	}
+
+	report := &Report{From: from, To: to}
+	for _, stats := range byVariant {
+		if stats.WithFeedback > 0 {
+			stats.ConversionRate = float64(stats.Outcomes[OutcomeConverted]) / float64(stats.WithFeedback)
+			stats.EscalationRate = float64(stats.Outcomes[OutcomeEscalated]) / float64(stats.WithFeedback)
+		}
+		report.Variants = append(report.Variants, stats)
+	}
+	sort.Slice(report.Variants, func(i, j int) bool {
+		return report.Variants[i].Variant < report.Variants[j].Variant
+	})
+
+	return report, nil
+}
+
+// readStream decodes the `data` JSON of all stream entries added since from
+func readStream[T any](ctx context.Context, client redis.Cmdable, stream string, from time.Time) ([]T, error) {
+	var items []T
+	start := fmt.Sprintf("%d-0", from.UnixMilli())
+
+	for {
+		messages, err := client.XRangeN(ctx, stream, start, "+", pageSize).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, message := range messages {
+			data, ok := message.Values["data"].(string)
+			if !ok {
+				continue
+			}
+			var item T
+			if err := json.Unmarshal([]byte(data), &item); err != nil {
+				continue
+			}
+			items = append(items, item)
+		}
+
+		if len(messages) < pageSize {
+			return items, nil
+		}
+		// Exclusive range start continues after the last entry read
+		start = "(" + messages[len(messages)-1].ID
+	}
+}
+
+// WriteTable writes the report as an aligned text table
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Window:\t%s - %s\n\n", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339))
+	fmt.Fprintln(tw, "VARIANT\tDECISIONS\tFALLBACKS\tFEEDBACK\tCONVERSION\tESCALATION")
+	for _, v := range r.Variants {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f%%\t%.1f%%\n",
+			v.Variant, v.Decisions, v.Fallbacks, v.WithFeedback,
+			v.ConversionRate*100, v.EscalationRate*100)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
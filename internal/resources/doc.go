@@ -0,0 +1,20 @@
+// Package resources detects the CPU and memory the worker may use and tunes
+// the Go runtime and in-memory caches to them.
+//
+// In Kubernetes the worker sees every CPU and all the memory of the node,
+// while its cgroup allows only a fraction: too many Ps contend for a small
+// CPU quota and the garbage collector lets the heap grow until the container
+// is OOM-killed. Detect reads the limits of the process cgroup (v2 or v1),
+// falling back to the host memory when there is no memory limit. Tune then
+// lowers GOMAXPROCS to the CPU limit and sets a soft memory limit, unless the
+// GOMAXPROCS or GOMEMLIMIT environment variables are set, and CapCaches
+// shrinks cache entry counts to a share of the memory.
+//
+// Example usage:
+//
+//	limits := resources.Detect()
+//	report := resources.Tune(limits, resources.Config{AutoMaxProcs: true, MemoryLimitRatio: 0.9})
+//	capped := resources.CapCaches(int64(0.2*float64(limits.Memory)), []resources.Cache{
+//	    {Name: "llm_cache", Entries: &cfg.LLMCacheSize, EntryBytes: 2 << 10},
+//	})
+package resources
@@ -0,0 +1,125 @@
+package resources
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot is where cgroup hierarchies are mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// Limits are the CPU and memory available to the process
+type Limits struct {
+	// CPU is the cgroup CPU quota in cores, 0 when unlimited
+	CPU float64 `json:"cpu,omitempty"`
+	// Memory is the cgroup memory limit in bytes or, when MemoryLimited is
+	// false, the host memory (0 when unknown)
+	Memory        int64 `json:"memory,omitempty"`
+	MemoryLimited bool  `json:"memory_limited"`
+}
+
+// Detect returns the limits of the cgroup the process runs in
+func Detect() Limits {
+	return detect(cgroupRoot, "/proc/self/cgroup", "/proc/meminfo")
+}
+
+// detect reads the limits from the given cgroup mount, cgroup membership
+// and meminfo files
+func detect(root, membership, meminfo string) Limits {
+	paths := cgroupPaths(membership)
+	var limits Limits
+
+	// cgroup v2: "max 100000" or "<quota> <period>" and "max" or bytes
+	if fields := strings.Fields(readCgroup(root, paths[""], "cpu.max")); len(fields) == 2 {
+		limits.CPU = quota(fields[0], fields[1])
+	} else {
+		limits.CPU = quota(readCgroup(filepath.Join(root, "cpu"), paths["cpu"], "cpu.cfs_quota_us"),
+			readCgroup(filepath.Join(root, "cpu"), paths["cpu"], "cpu.cfs_period_us"))
+	}
+
+	memory := readCgroup(root, paths[""], "memory.max")
+	if memory == "" {
+		memory = readCgroup(filepath.Join(root, "memory"), paths["memory"], "memory.limit_in_bytes")
+	}
+	host := hostMemory(meminfo)
+	if bytes, err := strconv.ParseInt(memory, 10, 64); err == nil && bytes > 0 && (host == 0 || bytes < host) {
+		// cgroup v1 reports no limit as a value near the maximum int64
+		limits.Memory, limits.MemoryLimited = bytes, true
+	} else {
+		limits.Memory = host
+	}
+	return limits
+}
+
+// quota returns the cores allowed by a CPU quota and period, 0 when unlimited
+func quota(quotaValue, periodValue string) float64 {
+	q, err := strconv.ParseFloat(quotaValue, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	period, err := strconv.ParseFloat(periodValue, 64)
+	if err != nil || period <= 0 {
+		return 0
+	}
+	return q / period
+}
+
+// cgroupPaths returns the cgroup path of the process by controller ("" for
+// cgroup v2) from /proc/self/cgroup lines such as "4:memory:/kubepods/..."
+func cgroupPaths(membership string) map[string]string {
+	paths := make(map[string]string)
+	file, err := os.Open(membership)
+	if err != nil {
+		return paths
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	return paths
+}
+
+// readCgroup reads a cgroup file of the process cgroup, or of the mount
+// root when the cgroup namespace hides the path, returning "" when missing
+func readCgroup(mount, path, name string) string {
+	for _, dir := range []string{filepath.Join(mount, path), mount} {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
+
+// hostMemory returns the MemTotal of meminfo in bytes, 0 when unknown
+func hostMemory(meminfo string) int64 {
+	file, err := os.Open(meminfo)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kib, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil || kib > math.MaxInt64/1024 {
+				return 0
+			}
+			return kib * 1024
+		}
+	}
+	return 0
+}
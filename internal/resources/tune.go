@@ -0,0 +1,81 @@
+package resources
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// Config selects the runtime tuning applied by Tune
+type Config struct {
+	// AutoMaxProcs lowers GOMAXPROCS to the CPU limit
+	AutoMaxProcs bool
+	// MemoryLimitRatio sets the soft memory limit to this share of the
+	// memory (0 leaves it unset)
+	MemoryLimitRatio float64
+}
+
+// Report describes the limits found and the runtime settings in effect
+type Report struct {
+	Limits     Limits `json:"limits"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	// GOMEMLIMIT is the soft memory limit in bytes, 0 when unset
+	GOMEMLIMIT int64 `json:"gomemlimit,omitempty"`
+	// Caches are the entry counts of the caches capped by CapCaches
+	Caches map[string]int `json:"caches,omitempty"`
+}
+
+// Tune applies config to the runtime and reports the resulting settings.
+// GOMAXPROCS is only lowered, to the CPU limit rounded up but not below the
+// runtime's own minimum of two (or the CPU count when lower), so it is a
+// no-op when the runtime already follows the limit, as Go 1.25 and later do.
+// The GOMAXPROCS and GOMEMLIMIT environment variables take precedence.
+func Tune(limits Limits, config Config) Report {
+	if config.AutoMaxProcs && limits.CPU > 0 && os.Getenv("GOMAXPROCS") == "" {
+		procs := max(int(math.Ceil(limits.CPU)), min(2, runtime.NumCPU()))
+		if procs < runtime.GOMAXPROCS(0) {
+			runtime.GOMAXPROCS(procs)
+		}
+	}
+	if config.MemoryLimitRatio > 0 && limits.MemoryLimited && os.Getenv("GOMEMLIMIT") == "" {
+		debug.SetMemoryLimit(int64(config.MemoryLimitRatio * float64(limits.Memory)))
+	}
+
+	report := Report{Limits: limits, GOMAXPROCS: runtime.GOMAXPROCS(0)}
+	// A negative input only reads the limit; MaxInt64 means none is set
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		report.GOMEMLIMIT = limit
+	}
+	return report
+}
+
+// Cache is an in-memory cache whose entry count can be capped
+type Cache struct {
+	Name string
+	// Entries is the configured entry count, lowered in place when capped
+	Entries *int
+	// EntryBytes is the estimated size of one entry
+	EntryBytes int64
+}
+
+// CapCaches scales the entry counts of caches down, all by the same factor,
+// so their estimated total size fits budget bytes, keeping at least one entry
+// each. It returns the new counts of the caches it capped, by name.
+func CapCaches(budget int64, caches []Cache) map[string]int {
+	var total float64
+	for _, c := range caches {
+		total += float64(*c.Entries) * float64(c.EntryBytes)
+	}
+	if budget <= 0 || total <= float64(budget) {
+		return nil
+	}
+
+	scale := float64(budget) / total
+	capped := make(map[string]int, len(caches))
+	for _, c := range caches {
+		*c.Entries = max(int(float64(*c.Entries)*scale), 1)
+		capped[c.Name] = *c.Entries
+	}
+	return capped
+}
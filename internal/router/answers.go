@@ -0,0 +1,183 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AnswerType is the kind of answer expected from the LLM
+type AnswerType string
+
+const (
+	// AnswerLabel answers name a route key (default)
+	AnswerLabel AnswerType = "label"
+	// AnswerBoolean answers are yes/no, mapped through the "yes" and "no" routes
+	AnswerBoolean AnswerType = "boolean"
+	// AnswerNumber answers are numbers, mapped through ranges
+	AnswerNumber AnswerType = "number"
+)
+
+// Route keys of boolean answers
+const (
+	routeYes = "yes"
+	routeNo  = "no"
+)
+
+// NumericRange maps numeric LLM answers between Min and Max (inclusive) to a target
+type NumericRange struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Target string  `json:"target"`
+}
+
+// numberPattern finds the first number in a free-text answer
+var numberPattern = regexp.MustCompile(`[-+]?\d+(?:\.\d+)?`)
+
+// isTyped reports whether answers are booleans or numbers rather than route labels
+func (c *LLMConfig) isTyped() bool {
+	return c.AnswerType == AnswerBoolean || c.AnswerType == AnswerNumber
+}
+
+// validateTypedAnswers checks boolean and numeric answer configuration
+func validateTypedAnswers(field string, llmConfig *LLMConfig) error {
+	if len(llmConfig.Categories) > 0 || llmConfig.AutoHierarchy {
+		return fmt.Errorf("%s: %s answers do not support categories or auto_hierarchy", field, llmConfig.AnswerType)
+	}
+
+	if llmConfig.AnswerType == AnswerBoolean {
+		for _, key := range []string{routeYes, routeNo} {
+			if llmConfig.Routes[key] == "" {
+				return fmt.Errorf("%s.routes.%s is required for boolean answers", field, key)
+			}
+		}
+		if len(llmConfig.Routes) != 2 {
+			return fmt.Errorf("%s.routes must only contain yes and no for boolean answers", field)
+		}
+		return nil
+	}
+
+	if len(llmConfig.Ranges) == 0 {
+		return fmt.Errorf("%s.ranges is required for number answers", field)
+	}
+	ranges := sortedRanges(llmConfig.Ranges)
+	for i, rng := range ranges {
+		if rng.Target == "" {
+			return fmt.Errorf("%s.ranges: range [%g, %g] has no target", field, rng.Min, rng.Max)
+		}
+		if rng.Min > rng.Max {
+			return fmt.Errorf("%s.ranges: range [%g, %g] has min greater than max", field, rng.Min, rng.Max)
+		}
+		if i > 0 && rng.Min <= ranges[i-1].Max {
+			return fmt.Errorf("%s.ranges: range [%g, %g] overlaps [%g, %g]", field, rng.Min, rng.Max, ranges[i-1].Min, ranges[i-1].Max)
+		}
+	}
+	return nil
+}
+
+// matchTypedAnswer maps a boolean or numeric answer to its target
+func matchTypedAnswer(answer string, llmConfig *LLMConfig) routeMatch {
+	if llmConfig.AnswerType == AnswerBoolean {
+		value, err := parseBooleanAnswer(answer)
+		if err != nil {
+			return routeMatch{Label: answer, Reason: err.Error()}
+		}
+		key := routeNo
+		if value {
+			key = routeYes
+		}
+		return routeMatch{Label: key, Target: llmConfig.Routes[key], Matched: true}
+	}
+
+	value, err := parseNumberAnswer(answer)
+	if err != nil {
+		return routeMatch{Label: answer, Reason: err.Error()}
+	}
+	label := strconv.FormatFloat(value, 'g', -1, 64)
+	for _, rng := range llmConfig.Ranges {
+		if value >= rng.Min && value <= rng.Max {
+			return routeMatch{Label: label, Target: rng.Target, Matched: true}
+		}
+	}
+	return routeMatch{Label: label, Reason: fmt.Sprintf("llm answer %s is outside the configured ranges", label)}
+}
+
+// parseBooleanAnswer reads a yes/no answer from its first word
+func parseBooleanAnswer(answer string) (bool, error) {
+	fields := strings.Fields(strings.ToLower(answer))
+	if len(fields) == 0 {
+		return false, fmt.Errorf("empty boolean answer")
+	}
+
+	switch strings.Trim(fields[0], `.,!:;"'`) {
+	case "yes", "y", "true":
+		return true, nil
+	case "no", "n", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("llm answer '%s' is not yes or no", answer)
+}
+
+// parseNumberAnswer reads the first number in an answer
+func parseNumberAnswer(answer string) (float64, error) {
+	match := numberPattern.FindString(answer)
+	if match == "" {
+		return 0, fmt.Errorf("llm answer '%s' is not a number", answer)
+	}
+	return strconv.ParseFloat(match, 64)
+}
+
+// answerChoices describes the accepted answers for structured output instructions
+func answerChoices(routes map[string]string, llmConfig *LLMConfig) string {
+	switch llmConfig.AnswerType {
+	case AnswerBoolean:
+		return fmt.Sprintf("%q or %q", routeYes, routeNo)
+	case AnswerNumber:
+		ranges := sortedRanges(llmConfig.Ranges)
+		return fmt.Sprintf("a number between %g and %g", ranges[0].Min, ranges[len(ranges)-1].Max)
+	}
+
+	keys := make([]string, 0, len(routes))
+	for key := range routes {
+		keys = append(keys, fmt.Sprintf("%q", key))
+	}
+	sort.Strings(keys)
+	return "one of: " + strings.Join(keys, ", ")
+}
+
+// sortedRanges returns the ranges ordered by their lower bound
+func sortedRanges(ranges []NumericRange) []NumericRange {
+	sorted := append([]NumericRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Min < sorted[j].Min })
+	return sorted
+}
+
+// answerValue is a structured output route that the LLM may emit as a
+// string, number or boolean
+type answerValue string
+
+// UnmarshalJSON accepts any JSON scalar
+func (a *answerValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = answerValue(s)
+		return nil
+	}
+
+	var scalar interface{}
+	if err := json.Unmarshal(data, &scalar); err != nil {
+		return err
+	}
+	switch scalar.(type) {
+	case float64, bool:
+		*a = answerValue(strings.TrimSpace(string(data)))
+		return nil
+	case nil:
+		*a = ""
+		return nil
+	}
+	return fmt.Errorf("route must be a string, number or boolean")
+}
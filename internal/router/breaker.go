@@ -0,0 +1,213 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen is returned for LLM calls rejected by an open circuit breaker
+var ErrCircuitOpen = errors.New("llm circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets calls through and counts consecutive failures
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects calls until the open duration elapses
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of probe calls through
+	BreakerHalfOpen
+)
+
+// String returns the state name
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig configures LLM circuit breakers
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit
+	FailureThreshold int
+	// OpenDuration is how long the circuit stays open before probing
+	OpenDuration time.Duration
+	// HalfOpenProbes is the number of successful probes needed to close the circuit
+	HalfOpenProbes int
+}
+
+// CircuitBreaker stops calling a failing dependency for a while, then probes
+// it before resuming normal traffic
+type CircuitBreaker struct {
+	config    BreakerConfig
+	state     BreakerState
+	failures  int
+	successes int
+	inFlight  int
+	openedAt  time.Time
+	mu        sync.Mutex
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	if config.HalfOpenProbes < 1 {
+		config.HalfOpenProbes = 1
+	}
+	return &CircuitBreaker{config: config}
+}
+
+// Allow reports whether a call may proceed, returning ErrCircuitOpen otherwise.
+// Every allowed call must be followed by Success or Failure.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.config.OpenDuration {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.successes = 0
+		b.inFlight = 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.inFlight >= b.config.HalfOpenProbes-b.successes {
+			return ErrCircuitOpen
+		}
+		b.inFlight++
+	}
+
+	return nil
+}
+
+// Success records a successful call
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.inFlight--
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.state = BreakerClosed
+			b.failures = 0
+		}
+	case BreakerClosed:
+		b.failures = 0
+	}
+}
+
+// Failure records a failed call, opening the circuit when the threshold is
+// reached or a probe fails
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.inFlight--
+		b.open()
+	case BreakerClosed:
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.open()
+		}
+	}
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// open trips the breaker; the caller must hold the lock
+func (b *CircuitBreaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = time.Now()
+	b.failures = 0
+}
+
+// breakerFor returns the circuit breaker guarding a tenant's LLM, or nil when
+// circuit breaking is disabled
+func (r *Router) breakerFor(tenant string) *CircuitBreaker {
+	if r.breakerConfig == nil {
+		return nil
+	}
+
+	r.breakersMu.Lock()
+	defer r.breakersMu.Unlock()
+
+	breaker, ok := r.breakers[tenant]
+	if !ok {
+		breaker = NewCircuitBreaker(*r.breakerConfig)
+		r.breakers[tenant] = breaker
+	}
+	return breaker
+}
+
+// recordBreaker reports a call outcome to the breaker and exports its state
+func (r *Router) recordBreaker(tenant string, breaker *CircuitBreaker, err error) {
+	if breaker == nil {
+		return
+	}
+
+	before := breaker.State()
+	if err != nil {
+		breaker.Failure()
+	} else {
+		breaker.Success()
+	}
+
+	after := breaker.State()
+	metrics.LLMCircuitState.WithLabelValues(metrics.Tenants.Value(tenant)).Set(float64(after))
+	if after != before {
+		r.logger.Warn("llm circuit breaker state changed",
+			zap.String("tenant", tenant),
+			zap.String("from", before.String()),
+			zap.String("to", after.String()),
+		)
+	}
+}
+
+// LLMReady reports an error when LLM routing cannot be served: no LLM client
+// is configured or the default client's circuit breaker is open
+func (r *Router) LLMReady() error {
+	if r.llmClient == nil && len(r.tenantLLMs) == 0 {
+		return fmt.Errorf("no llm client configured")
+	}
+
+	r.breakersMu.Lock()
+	breaker := r.breakers[defaultTenant]
+	r.breakersMu.Unlock()
+	if r.llmClient != nil && breaker != nil && breaker.State() == BreakerOpen {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// CircuitStates returns the LLM circuit breaker state per tenant
+func (r *Router) CircuitStates() map[string]string {
+	r.breakersMu.Lock()
+	defer r.breakersMu.Unlock()
+
+	states := make(map[string]string, len(r.breakers))
+	for tenant, breaker := range r.breakers {
+		states[tenant] = breaker.State().String()
+	}
+	return states
+}
@@ -0,0 +1,210 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/tokens"
+)
+
+// ErrPromptTooLarge is returned for prompts that exceed their token budget
+// after every trimmable section was trimmed
+var ErrPromptTooLarge = errors.New("prompt exceeds token budget")
+
+// trimHistory is the trim order section of the message history
+const trimHistory = "history"
+
+// defaultTrimOrder trims the message history first, then the longest input
+// values
+var defaultTrimOrder = []string{trimHistory, "inputs"}
+
+// WithMaxPromptTokens sets the token budget of prompts whose LLM config sets
+// no max_prompt_tokens (0 disables)
+func WithMaxPromptTokens(maxTokens int) Option {
+	return func(r *Router) {
+		if maxTokens > 0 {
+			r.maxPromptTokens = maxTokens
+		}
+	}
+}
+
+// maxPromptTokensFor returns the token budget of the prompts of llmConfig
+func (r *Router) maxPromptTokensFor(llmConfig *LLMConfig) int {
+	if llmConfig.MaxPromptTokens > 0 {
+		return llmConfig.MaxPromptTokens
+	}
+	return r.maxPromptTokens
+}
+
+// trimOrder returns the sections trimmed, in order, from prompts over budget
+func trimOrder(llmConfig *LLMConfig) []string {
+	if len(llmConfig.TrimOrder) > 0 {
+		return llmConfig.TrimOrder
+	}
+	return defaultTrimOrder
+}
+
+// promptTokens estimates the tokens sent for a rendered prompt, including
+// examples and output format instructions
+func promptTokens(prompt string, llmConfig *LLMConfig) int {
+	prompt = withOutputFormat(withExamples(prompt, llmConfig), llmConfig.Routes, llmConfig)
+	return tokens.Estimate(conversationText(exampleMessages(llmConfig), prompt))
+}
+
+// promptRenderer renders a prompt from a state and message history
+type promptRenderer func(state *domain.GraphState, history []interface{}) (string, error)
+
+// fitPrompt renders a prompt within the token budget of llmConfig. Prompts
+// over budget are re-rendered with the sections of the trim order trimmed
+// one at a time: the history loses its oldest messages, and state values are
+// cut short, longest first, until the prompt fits.
+func (r *Router) fitPrompt(state *domain.GraphState, history []interface{}, llmConfig *LLMConfig, guard *PromptGuard, render promptRenderer) (string, error) {
+	prompt, err := render(state, history)
+	if err != nil {
+		return "", err
+	}
+	budget := r.maxPromptTokensFor(llmConfig)
+	if budget <= 0 || promptTokens(prompt, llmConfig) <= budget {
+		return prompt, nil
+	}
+
+	over := func() bool {
+		return promptTokens(prompt, llmConfig) > budget
+	}
+	for _, section := range trimOrder(llmConfig) {
+		trimmed := false
+		if section == trimHistory {
+			for len(history) > 0 && over() {
+				history = history[1:]
+				trimmed = true
+				if prompt, err = render(state, history); err != nil {
+					return "", err
+				}
+			}
+		} else {
+			for _, path := range stringPaths(state, section) {
+				if !over() {
+					break
+				}
+				state, prompt, err = trimValue(state, history, path, budget, llmConfig, guard, render)
+				if err != nil {
+					return "", err
+				}
+				trimmed = true
+			}
+		}
+		if trimmed {
+			metrics.PromptTrims.WithLabelValues(section).Inc()
+		}
+		if !over() {
+			return prompt, nil
+		}
+	}
+
+	metrics.PromptBudgetExceeded.Inc()
+	return "", fmt.Errorf("%w: %d tokens after trimming, budget is %d",
+		ErrPromptTooLarge, promptTokens(prompt, llmConfig), budget)
+}
+
+// trimValue cuts the string value at path to the tokens left once the rest
+// of the prompt is rendered, or empties it when the rest alone is over
+// budget. Values delimited by the guard keep their delimiters.
+func trimValue(state *domain.GraphState, history []interface{}, path string, budget int, llmConfig *LLMConfig, guard *PromptGuard, render promptRenderer) (*domain.GraphState, string, error) {
+	value, _ := lookupStatePath(state, path).(string)
+	wrap := func(s string) string { return s }
+	if guard != nil && guard.Delimit && strings.HasPrefix(value, guardOpen) && strings.HasSuffix(value, guardClose) {
+		value = strings.TrimSuffix(strings.TrimPrefix(value, guardOpen), guardClose)
+		wrap = func(s string) string { return guardOpen + s + guardClose }
+	}
+
+	emptied := withStateValue(state, path, wrap(""))
+	rest, err := render(emptied, history)
+	if err != nil {
+		return nil, "", err
+	}
+	allowed := budget - promptTokens(rest, llmConfig)
+	if allowed <= 0 {
+		return emptied, rest, nil
+	}
+
+	trimmed := withStateValue(state, path, wrap(tokens.Truncate(value, allowed)))
+	prompt, err := render(trimmed, history)
+	if err != nil {
+		return nil, "", err
+	}
+	if promptTokens(prompt, llmConfig) > budget {
+		// Words merged across the cut can cost more than estimated apart
+		return emptied, rest, nil
+	}
+	return trimmed, prompt, nil
+}
+
+// stringPaths returns the paths of the string values at or under path,
+// longest value first
+func stringPaths(state *domain.GraphState, path string) []string {
+	lengths := make(map[string]int)
+	var collect func(path string, value interface{})
+	collect = func(path string, value interface{}) {
+		switch typed := value.(type) {
+		case string:
+			lengths[path] = len(typed)
+		case map[string]interface{}:
+			for key, item := range typed {
+				collect(path+"."+key, item)
+			}
+		}
+	}
+	collect(path, lookupStatePath(state, path))
+
+	paths := make([]string, 0, len(lengths))
+	for p := range lengths {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if lengths[paths[i]] != lengths[paths[j]] {
+			return lengths[paths[i]] > lengths[paths[j]]
+		}
+		return paths[i] < paths[j]
+	})
+	return paths
+}
+
+// withStateValue returns a copy of state with value set at a dotted path of
+// its inputs, copying the maps along the path
+func withStateValue(state *domain.GraphState, path string, value interface{}) *domain.GraphState {
+	copied := *state
+	copied.Inputs = setPath(state.Inputs, strings.Split(path, ".")[1:], value)
+	return &copied
+}
+
+// setPath returns a copy of m with value set at the path segments
+func setPath(m map[string]interface{}, segments []string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m)+1)
+	for key, item := range m {
+		copied[key] = item
+	}
+	if len(segments) == 1 {
+		copied[segments[0]] = value
+		return copied
+	}
+	child, _ := m[segments[0]].(map[string]interface{})
+	copied[segments[0]] = setPath(child, segments[1:], value)
+	return copied
+}
+
+// promptBudget checks the token budget settings of an LLM config
+func (v *configValidator) promptBudget(field string, llmConfig *LLMConfig) {
+	if llmConfig.MaxPromptTokens < 0 {
+		v.add(field+".max_prompt_tokens", "max_prompt_tokens must not be negative")
+	}
+	for i, section := range llmConfig.TrimOrder {
+		if section != trimHistory && section != "inputs" && !strings.HasPrefix(section, "inputs.") {
+			v.add(fmt.Sprintf("%s.trim_order[%d]", field, i),
+				fmt.Sprintf("'%s' is not history or a state path under inputs", section))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package router
+
+// NodeConfigSchemaVersion is the version of the NodeConfig schema understood by the router
+const NodeConfigSchemaVersion = "1"
+
+// Capabilities describes the routing features supported by this router build
+type Capabilities struct {
+	Modes           []string `json:"modes"`
+	LLMFeatures     []string `json:"llm_features"`
+	CELVariables    []string `json:"cel_variables"`
+	CELMacros       []string `json:"cel_macros"`
+	CELExtensions   []string `json:"cel_extensions"`
+	TemplateEngines []string `json:"template_engines"`
+	TemplateHelpers []string `json:"template_helpers"`
+	ConfigSchema    string   `json:"config_schema"`
+}
+
+// Capabilities returns the routing modes, expression and template features
+// supported by the router, for validating graph definitions before deployment
+func (r *Router) Capabilities() Capabilities {
+	modes := []string{string(ModeDeterministic), string(ModeLLM), string(ModeHybrid), string(ModeClassifier), string(ModeKeyword), string(ModeEmbedding), string(ModePipeline)}
+	for _, mode := range r.strategies.Modes() {
+		if !builtinModes[mode] {
+			modes = append(modes, string(mode))
+		}
+	}
+
+	return Capabilities{
+		Modes: modes,
+		LLMFeatures: []string{
+			"auto_hierarchy",
+			"categories",
+			"structured_output",
+			"min_confidence",
+			"abstain_target",
+			"examples",
+			"history",
+			"prompt_budget",
+			"streaming",
+			"boolean_answers",
+			"numeric_ranges",
+			"template_ref",
+			"guard",
+		},
+		CELVariables:    r.celEvaluator.Variables(),
+		CELMacros:       r.celEvaluator.Macros(),
+		CELExtensions:   r.celEvaluator.Extensions(),
+		TemplateEngines: r.templateEngines(),
+		TemplateHelpers: r.templateEngine.Helpers(),
+		ConfigSchema:    NodeConfigSchemaVersion,
+	}
+}
+
+// templateEngines returns the prompt template syntaxes nodes may select
+func (r *Router) templateEngines() []string {
+	if r.sandboxed {
+		return []string{string(TemplateHandlebars)}
+	}
+	return []string{string(TemplateHandlebars), string(TemplateGo), string(TemplateJinja)}
+}
@@ -0,0 +1,121 @@
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/classifier"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// ClassifierConfig routes with an offline-trained text classifier instead of
+// an LLM
+type ClassifierConfig struct {
+	// Model names a model of the classifier models directory
+	Model string `json:"model"`
+	// Input is a Handlebars template of the text classified, e.g.
+	// "{{state.inputs.subject}} {{state.inputs.body}}"
+	Input string `json:"input"`
+	// Routes maps model labels to target nodes; labels without a route take
+	// the fallback route
+	Routes map[string]string `json:"routes"`
+	// MinConfidence is the minimum probability of the predicted label;
+	// less likely predictions take the fallback route
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+}
+
+// WithClassifierModels sets the models classifier configs refer to by name
+func WithClassifierModels(models map[string]*classifier.Model) Option {
+	return func(r *Router) {
+		r.classifiers = models
+	}
+}
+
+// routeClassifier routes with the label a classifier model predicts for the
+// rendered input
+func (r *Router) routeClassifier(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error) {
+	if err := r.validateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	cfg := config.Classifier
+	input, err := r.templateEngine.Render(cfg.Input, promptData(ctx, state))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render classifier input: %w", err)
+	}
+
+	model := r.classifiers[cfg.Model]
+	prediction := model.Predict(input)
+	fallback := func(result, reasoning string) *RoutingResult {
+		metrics.ClassifierPredictions.WithLabelValues(cfg.Model, result).Inc()
+		r.log(ctx).Info("classifier prediction not routed, using fallback",
+			zap.String("model", cfg.Model),
+			zap.String("label", prediction.Label),
+			zap.Float64("probability", prediction.Probability),
+			zap.String("reason", reasoning),
+		)
+		return &RoutingResult{
+			TargetNode: config.Fallback,
+			Reasoning:  reasoning,
+			Mode:       string(ModeClassifier),
+			PathTaken:  "fallback",
+			Confidence: prediction.Probability,
+		}
+	}
+
+	if prediction.Known == 0 {
+		return fallback("unknown_input", fmt.Sprintf("classifier %s knows no word of the input", cfg.Model)), nil
+	}
+	if prediction.Probability < cfg.MinConfidence {
+		return fallback("low_confidence", fmt.Sprintf("classifier %s predicted %s with probability %.2f, below min_confidence %.2f",
+			cfg.Model, prediction.Label, prediction.Probability, cfg.MinConfidence)), nil
+	}
+	target, ok := cfg.Routes[prediction.Label]
+	if !ok {
+		return fallback("unrouted", fmt.Sprintf("classifier %s predicted %s, which has no route", cfg.Model, prediction.Label)), nil
+	}
+
+	metrics.ClassifierPredictions.WithLabelValues(cfg.Model, "matched").Inc()
+	return &RoutingResult{
+		TargetNode: target,
+		Reasoning:  fmt.Sprintf("classifier %s predicted %s with probability %.2f", cfg.Model, prediction.Label, prediction.Probability),
+		Mode:       string(ModeClassifier),
+		PathTaken:  "fast",
+		Confidence: prediction.Probability,
+	}, nil
+}
+
+// classifier checks a classifier config against its model
+func (v *configValidator) classifier(field string, cfg *ClassifierConfig) {
+	model, ok := v.router.classifiers[cfg.Model]
+	switch {
+	case cfg.Model == "":
+		v.add(field+".model", "model is required")
+	case !ok:
+		v.add(field+".model", fmt.Sprintf("classifier model '%s' is not loaded on this worker", cfg.Model))
+	}
+
+	if cfg.Input == "" {
+		v.add(field+".input", "input is required")
+	} else if err := v.router.templateEngine.ValidateTemplate(cfg.Input); err != nil {
+		v.add(field+".input", err.Error())
+	}
+
+	if len(cfg.Routes) == 0 {
+		v.add(field+".routes", "routes are required")
+	}
+	for _, label := range sortedKeys(cfg.Routes) {
+		if cfg.Routes[label] == "" {
+			v.add(field+".routes."+label, "target is required")
+		}
+		if model != nil && !model.HasLabel(label) {
+			v.add(field+".routes."+label, fmt.Sprintf("model %s does not predict label '%s'", cfg.Model, label))
+		}
+	}
+
+	if cfg.MinConfidence < 0 || cfg.MinConfidence > 1 {
+		v.add(field+".min_confidence", "min_confidence must be between 0 and 1")
+	}
+}
@@ -0,0 +1,90 @@
+package router
+
+import (
+	"context"
+
+	"github.com/aescanero/dago-node-router/internal/logging"
+	"go.uber.org/zap"
+)
+
+// ExecutionContext carries orchestrator-known request context that is not
+// part of the graph state. It is exposed to CEL and templates as `ctx`.
+type ExecutionContext struct {
+	Tenant           string `json:"tenant,omitempty"`
+	Environment      string `json:"environment,omitempty"`
+	Locale           string `json:"locale,omitempty"`
+	ExperimentBucket string `json:"experiment_bucket,omitempty"`
+}
+
+// executionContextKey is the context.Context key for the execution context
+type executionContextKey struct{}
+
+// WithExecutionContext returns a context carrying the execution context
+func WithExecutionContext(ctx context.Context, ec *ExecutionContext) context.Context {
+	if ec == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, executionContextKey{}, ec)
+}
+
+// ExecutionContextFrom returns the execution context carried by ctx, if any
+func ExecutionContextFrom(ctx context.Context) *ExecutionContext {
+	ec, _ := ctx.Value(executionContextKey{}).(*ExecutionContext)
+	return ec
+}
+
+// nodeIDKey is the context.Context key for the ID of the routed node
+type nodeIDKey struct{}
+
+// WithNodeID returns a context carrying the ID of the node being routed, used
+// to apply per-node LLM rate limits
+func WithNodeID(ctx context.Context, nodeID string) context.Context {
+	if nodeID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, nodeIDKey{}, nodeID)
+}
+
+// NodeIDFrom returns the node ID carried by ctx, or "" if none
+func NodeIDFrom(ctx context.Context) string {
+	nodeID, _ := ctx.Value(nodeIDKey{}).(string)
+	return nodeID
+}
+
+// tenantKey is the context.Context key for the tenant of the routed request
+type tenantKey struct{}
+
+// withTenant returns a context carrying the tenant resolved for the request
+func withTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant resolved by Route for the request being
+// routed, or "" outside of Route
+func TenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// log returns the logger of the request routed in ctx, which adds its tenant
+// and node (and, under the worker, its execution and message) to every line,
+// or the router logger
+func (r *Router) log(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, r.logger)
+}
+
+// contextVars converts the execution context of ctx to the `ctx` variable.
+// Unset fields are empty strings so expressions never fail on missing keys.
+func contextVars(ctx context.Context) map[string]interface{} {
+	ec := ExecutionContextFrom(ctx)
+	if ec == nil {
+		ec = &ExecutionContext{}
+	}
+
+	return map[string]interface{}{
+		"tenant":            ec.Tenant,
+		"environment":       ec.Environment,
+		"locale":            ec.Locale,
+		"experiment_bucket": ec.ExperimentBucket,
+	}
+}
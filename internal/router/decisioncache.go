@@ -0,0 +1,110 @@
+package router
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-node-router/internal/cache"
+	"github.com/aescanero/dago-node-router/internal/eval/cel"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+)
+
+// WithDecisionCache reuses the decisions of deterministic rules for states
+// they have already routed. Entries are keyed by the rules and the values at
+// the state, ctx, lookup and variable paths the rules read, so executions
+// that differ only in values no rule reads share decisions. Rules calling flag() or a time function
+// (now, hour, weekday, ...) are never cached, since their decision may change
+// for the same state.
+func WithDecisionCache(c *cache.LRU[RoutingResult]) Option {
+	return func(r *Router) {
+		r.decisions = c
+	}
+}
+
+// decisionInputs is what a deterministic decision depends on
+type decisionInputs struct {
+	NodeID      string                    `json:"node_id"`
+	Field       string                    `json:"field"`
+	Rules       []Rule                    `json:"rules"`
+	Groups      map[string]GroupMatch     `json:"groups,omitempty"`
+	Targets     []string                  `json:"targets,omitempty"`
+	Fallback    string                    `json:"fallback"`
+	NumberTypes map[string]cel.NumberType `json:"number_types,omitempty"`
+	Variables   map[string]Variable       `json:"variables,omitempty"`
+	Values      []decisionValue           `json:"values"`
+}
+
+// decisionValue is the value at a variable path a rule reads
+type decisionValue struct {
+	Path    []string    `json:"path"`
+	Value   interface{} `json:"value,omitempty"`
+	Missing bool        `json:"missing,omitempty"`
+}
+
+// decisionKey returns the decision cache key of the rules at field for the
+// given CEL variables, and whether the decision may be cached
+func (r *Router) decisionKey(ctx context.Context, config *NodeConfig, field string, celState map[string]interface{}) (string, bool) {
+	if r.decisions == nil {
+		return "", false
+	}
+	if config.BypassDecisionCache {
+		metrics.DecisionCacheRequests.WithLabelValues("bypass").Inc()
+		return "", false
+	}
+	for _, rule := range config.Rules {
+		if r.celEvaluator.CallsImpure(rule.Condition) || (rule.TargetExpr != "" && r.celEvaluator.CallsImpure(rule.TargetExpr)) {
+			metrics.DecisionCacheRequests.WithLabelValues("bypass").Inc()
+			return "", false
+		}
+	}
+
+	data, err := json.Marshal(decisionInputs{
+		NodeID:      NodeIDFrom(ctx),
+		Field:       field,
+		Rules:       config.Rules,
+		Groups:      config.Groups,
+		Targets:     config.Targets,
+		Fallback:    config.Fallback,
+		NumberTypes: config.NumberTypes,
+		Variables:   config.Variables,
+		Values:      r.decisionValues(config, celState),
+	})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// decisionValues returns the values at the variable paths the rules read, in
+// path order
+func (r *Router) decisionValues(config *NodeConfig, celState map[string]interface{}) []decisionValue {
+	paths := make(map[string][]string)
+	for _, rule := range config.Rules {
+		for _, expression := range []string{rule.Condition, rule.TargetExpr} {
+			if expression == "" {
+				continue
+			}
+			for _, path := range r.celEvaluator.References(expression) {
+				paths[strings.Join(path, "\x00")] = path
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(paths))
+	for key := range paths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]decisionValue, len(keys))
+	for i, key := range keys {
+		value, found := cel.Resolve(celState, paths[key])
+		values[i] = decisionValue{Path: paths[key], Value: value, Missing: !found}
+	}
+	return values
+}
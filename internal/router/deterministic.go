@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/eval/cel"
+	"github.com/aescanero/dago-node-router/internal/metrics"
 	"go.uber.org/zap"
 )
 
@@ -16,56 +18,84 @@ func (r *Router) routeDeterministic(ctx context.Context, state *domain.GraphStat
 	}
 
 	// Prepare state for CEL evaluation
-	celState := r.prepareStateForCEL(state)
+	ctx = withDeclarations(ctx, config)
+	celState := r.prepareStateForCEL(ctx, state, config)
 
-	// Evaluate rules in order
-	for i, rule := range config.Rules {
-		r.logger.Debug("evaluating rule",
-			zap.Int("rule_index", i),
-			zap.String("condition", rule.Condition),
+	field := config.rulesField
+	if field == "" {
+		field = "rules"
+	}
+
+	key, cacheable := r.decisionKey(ctx, config, field, celState)
+	if !cacheable {
+		return r.evaluateRules(ctx, config, field, celState), nil
+	}
+	if cached, ok := r.decisions.Get(ctx, key); ok {
+		metrics.DecisionCacheRequests.WithLabelValues("hit").Inc()
+		// Count the rule hits of the cached evaluation, as evaluating again
+		// would
+		r.replayHits(ctx, config, field, cached.hits)
+		cached.hits = nil
+		r.log(ctx).Debug("routing decision served from cache",
+			zap.String("target", cached.TargetNode),
 		)
+		return &cached, nil
+	}
+	metrics.DecisionCacheRequests.WithLabelValues("miss").Inc()
 
-		// Evaluate the condition
-		result, err := r.celEvaluator.Evaluate(ctx, rule.Condition, celState)
-		if err != nil {
-			r.logger.Warn("rule evaluation error",
-				zap.Int("rule_index", i),
-				zap.String("condition", rule.Condition),
-				zap.Error(err),
-			)
-			// Continue to next rule on error
+	traceCtx, trace := withHitTrace(ctx)
+	result := r.evaluateRules(traceCtx, config, field, celState)
+	entry := *result
+	entry.hits = trace
+	r.decisions.Set(ctx, key, entry)
+	return result, nil
+}
+
+// evaluateRules routes with the rules at field, in priority order
+func (r *Router) evaluateRules(ctx context.Context, config *NodeConfig, field string, celState map[string]interface{}) *RoutingResult {
+	evaluated := make([]bool, len(config.Rules))
+
+	// Evaluate rules in priority order
+	for _, unit := range r.planFor(ctx, config, field) {
+		i, matched := r.evaluateUnit(ctx, field, unit, config.Rules, celState, evaluated)
+		if !matched {
 			continue
 		}
+		r.recordSkipped(ctx, field, evaluated)
+		r.recordDecision(ctx, config, field, &unit)
 
-		// Check if condition is true
-		matched, ok := result.(bool)
-		if !ok {
-			r.logger.Warn("rule condition did not return boolean",
-				zap.Int("rule_index", i),
-				zap.String("condition", rule.Condition),
-				zap.Any("result", result),
-			)
-			continue
+		rule := config.Rules[i]
+		reasoning := fmt.Sprintf("matched rule %d: %s", i, rule.Condition)
+		if unit.group != "" {
+			reasoning = fmt.Sprintf("matched rule %d in group %s (%s): %s", i, unit.group, unit.match, rule.Condition)
 		}
 
-		if matched {
-			r.logger.Info("rule matched",
-				zap.Int("rule_index", i),
-				zap.String("condition", rule.Condition),
-				zap.String("target", rule.Target),
-			)
-
-			return &RoutingResult{
-				TargetNode: rule.Target,
-				Reasoning:  fmt.Sprintf("matched rule %d: %s", i, rule.Condition),
-				Mode:       string(ModeDeterministic),
-				PathTaken:  "fast",
-			}, nil
+		target, err := r.resolveTarget(ctx, rule, config, celState)
+		if err != nil {
+			return r.unresolvedTarget(ctx, ModeDeterministic, i, rule, config, err)
+		}
+
+		r.log(ctx).Info("rule matched",
+			zap.Int("rule_index", i),
+			zap.String("group", unit.group),
+			zap.String("condition", rule.Condition),
+			zap.String("target", target),
+		)
+
+		return &RoutingResult{
+			TargetNode: target,
+			Reasoning:  reasoning,
+			Mode:       string(ModeDeterministic),
+			PathTaken:  "fast",
+			RuleIndex:  &i,
+			Condition:  rule.Condition,
 		}
 	}
 
 	// No rules matched, use fallback
-	r.logger.Info("no rules matched, using fallback",
+	r.recordSkipped(ctx, field, evaluated)
+	r.recordDecision(ctx, config, field, nil)
+	r.log(ctx).Info("no rules matched, using fallback",
 		zap.String("fallback", config.Fallback),
 	)
 
@@ -74,19 +104,42 @@ func (r *Router) routeDeterministic(ctx context.Context, state *domain.GraphStat
 		Reasoning:  "no rules matched",
 		Mode:       string(ModeDeterministic),
 		PathTaken:  "fallback",
-	}, nil
+	}
 }
 
-// prepareStateForCEL converts GraphState to a map for CEL evaluation
-func (r *Router) prepareStateForCEL(state *domain.GraphState) map[string]interface{} {
-	return map[string]interface{}{
-		"state": map[string]interface{}{
-			"graph_id":    state.GraphID,
-			"status":      string(state.Status),
-			"inputs":      state.Inputs,
-			"node_states": r.convertNodeStates(state.NodeStates),
-		},
+// prepareStateForCEL binds GraphState to the CEL variables. Inputs, node
+// outputs and lookups are bound in place rather than copied: their JSON
+// numbers are coerced as rules read them, so comparisons and arithmetic
+// against int literals work. inputs and prev are shortcuts for state.inputs
+// and the output of the node that ran before the routed node; the variables
+// declared by config are projected from the state.
+func (r *Router) prepareStateForCEL(ctx context.Context, state *domain.GraphState, config *NodeConfig) map[string]interface{} {
+	numbers := cel.NumberCoercion{Mode: r.numberMode, Types: config.NumberTypes}
+	prevNode := previousNode(ctx, state)
+	raw := map[string]interface{}{
+		"graph_id":    state.GraphID,
+		"status":      string(state.Status),
+		"inputs":      state.Inputs,
+		"node_states": r.convertNodeStates(state.NodeStates),
+		"metadata":    metadataVars(ctx, state, prevNode),
+	}
+	if state.Inputs == nil {
+		raw["inputs"] = map[string]interface{}{}
+	}
+
+	celState := make(map[string]interface{}, len(raw))
+	for key, value := range raw {
+		celState[key] = numbers.Bind(value, key)
+	}
+	vars := map[string]interface{}{
+		"ctx":    contextVars(ctx),
+		"lookup": cel.NumberCoercion{Mode: r.numberMode}.Bind(lookupVars(ctx), ""),
+		"state":  celState,
+		"inputs": celState["inputs"],
+		"prev":   prevVar(numbers, state, prevNode),
 	}
+	r.projectVariables(ctx, config, numbers, raw, vars)
+	return vars
 }
 
 // convertNodeStates converts node states to a CEL-friendly format
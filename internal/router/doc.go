@@ -1,9 +1,16 @@
 // Package router implements routing strategies for graph execution flow.
 //
-// The router supports three routing modes:
+// The router supports seven routing modes:
 //   - Deterministic: Fast, rule-based routing using CEL expressions
 //   - LLM: Semantic routing using Large Language Models
 //   - Hybrid: Combines CEL rules with LLM fallback for optimal performance
+//   - Classifier: Semantic routing with an offline-trained text classifier
+//   - Keyword: Weighted keyword and regular expression scoring
+//   - Embedding: Similarity of embeddings to example texts of each route
+//   - Pipeline: An ordered chain of the other strategies
+//
+// Each mode is a Strategy. Embedders add modes with RegisterStrategy or the
+// WithStrategy option; built-in modes cannot be replaced.
 //
 // Example deterministic routing:
 //
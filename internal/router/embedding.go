@@ -0,0 +1,185 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/cache"
+	"github.com/aescanero/dago-node-router/internal/embedding"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultEmbeddingCacheSize is the number of example embeddings kept
+	defaultEmbeddingCacheSize = 10000
+	// defaultEmbeddingCacheTTL is how long an example embedding is kept
+	defaultEmbeddingCacheTTL = time.Hour
+)
+
+// EmbeddingConfig routes to the route whose examples are most similar to the
+// input, by the cosine similarity of their embeddings
+type EmbeddingConfig struct {
+	// Input is a Handlebars template of the text embedded, e.g.
+	// "{{state.inputs.subject}} {{state.inputs.body}}"
+	Input string `json:"input"`
+	// Routes are compared in order; the first of equally similar routes wins
+	Routes []EmbeddingRoute `json:"routes"`
+	// MinSimilarity is the minimum cosine similarity of the winning route;
+	// less similar inputs take the fallback route
+	MinSimilarity float64 `json:"min_similarity,omitempty"`
+}
+
+// EmbeddingRoute is a target with example texts of the inputs it receives
+type EmbeddingRoute struct {
+	Target string `json:"target"`
+	// Examples are texts routed to the target; the similarity of a route is
+	// that of its most similar example
+	Examples []string `json:"examples"`
+}
+
+// embeddings embeds the inputs of embedding configs and caches the
+// embeddings of their examples
+type embeddings struct {
+	embedder embedding.Embedder
+	examples *cache.LRU[[]float64]
+}
+
+// WithEmbedder sets the embedder of embedding configs. Configs in embedding
+// mode fail validation without it.
+func WithEmbedder(embedder embedding.Embedder) Option {
+	return func(r *Router) {
+		r.embeddings = &embeddings{
+			embedder: embedder,
+			examples: cache.NewLRU[[]float64](defaultEmbeddingCacheSize, defaultEmbeddingCacheTTL),
+		}
+	}
+}
+
+// embed returns the embedding of input and of every example of cfg, by route
+// and example. Input and uncached examples are embedded in one call.
+func (e *embeddings) embed(ctx context.Context, input string, cfg *EmbeddingConfig) ([]float64, [][][]float64, error) {
+	texts := []string{input}
+	examples := make([][][]float64, len(cfg.Routes))
+	var missing [][2]int
+	for i, route := range cfg.Routes {
+		examples[i] = make([][]float64, len(route.Examples))
+		for j, example := range route.Examples {
+			if vector, ok := e.examples.Get(ctx, example); ok {
+				examples[i][j] = vector
+				continue
+			}
+			missing = append(missing, [2]int{i, j})
+			texts = append(texts, example)
+		}
+	}
+
+	vectors, err := e.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, nil, fmt.Errorf("embedder returned %d embeddings for %d texts", len(vectors), len(texts))
+	}
+	for k, at := range missing {
+		vector := vectors[k+1]
+		examples[at[0]][at[1]] = vector
+		e.examples.Set(ctx, texts[k+1], vector)
+	}
+	return vectors[0], examples, nil
+}
+
+// routeEmbedding routes with the route whose examples are most similar to
+// the rendered input
+func (r *Router) routeEmbedding(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error) {
+	if err := r.validateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	cfg := config.Embedding
+	input, err := r.templateEngine.Render(cfg.Input, promptData(ctx, state))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render embedding input: %w", err)
+	}
+
+	fallback := func(result, reasoning string, similarity float64) *RoutingResult {
+		metrics.EmbeddingRoutes.WithLabelValues(result).Inc()
+		r.log(ctx).Info("embedding not routed, using fallback",
+			zap.Float64("similarity", similarity),
+			zap.String("reason", reasoning),
+		)
+		return &RoutingResult{
+			TargetNode: config.Fallback,
+			Reasoning:  reasoning,
+			Mode:       string(ModeEmbedding),
+			PathTaken:  "fallback",
+			Confidence: similarity,
+		}
+	}
+
+	inputVector, examples, err := r.embeddings.embed(ctx, input, cfg)
+	if err != nil {
+		r.log(ctx).Error("embedding call failed", zap.Error(err))
+		return fallback("error", fmt.Sprintf("embedding call failed: %v", err), 0), nil
+	}
+
+	best, bestSimilarity := -1, 0.0
+	for i := range cfg.Routes {
+		for _, vector := range examples[i] {
+			if similarity := embedding.Cosine(inputVector, vector); best < 0 || similarity > bestSimilarity {
+				best, bestSimilarity = i, similarity
+			}
+		}
+	}
+	if best < 0 || bestSimilarity < cfg.MinSimilarity {
+		return fallback("low_similarity", fmt.Sprintf("best embedding similarity %.2f is below min_similarity %.2f",
+			bestSimilarity, cfg.MinSimilarity), bestSimilarity), nil
+	}
+
+	target := cfg.Routes[best].Target
+	metrics.EmbeddingRoutes.WithLabelValues("matched").Inc()
+	return &RoutingResult{
+		TargetNode: target,
+		Reasoning:  fmt.Sprintf("input is most similar to the examples of %s (similarity %.2f)", target, bestSimilarity),
+		Mode:       string(ModeEmbedding),
+		PathTaken:  "fast",
+		Confidence: bestSimilarity,
+	}, nil
+}
+
+// embedding checks an embedding config
+func (v *configValidator) embedding(field string, cfg *EmbeddingConfig) {
+	if v.router.embeddings == nil {
+		v.add(field, "embedding routing is not configured on this worker (set EMBEDDING_BASE_URL)")
+	}
+
+	if cfg.Input == "" {
+		v.add(field+".input", "input is required")
+	} else if err := v.router.templateEngine.ValidateTemplate(cfg.Input); err != nil {
+		v.add(field+".input", err.Error())
+	}
+
+	if len(cfg.Routes) == 0 {
+		v.add(field+".routes", "routes are required")
+	}
+	for i, route := range cfg.Routes {
+		routeField := fmt.Sprintf("%s.routes[%d]", field, i)
+		if route.Target == "" {
+			v.add(routeField+".target", "target is required")
+		}
+		if len(route.Examples) == 0 {
+			v.add(routeField+".examples", "examples are required")
+		}
+		for j, example := range route.Examples {
+			if example == "" {
+				v.add(fmt.Sprintf("%s.examples[%d]", routeField, j), "example must not be empty")
+			}
+		}
+	}
+
+	if cfg.MinSimilarity < -1 || cfg.MinSimilarity > 1 {
+		v.add(field+".min_similarity", "min_similarity must be between -1 and 1")
+	}
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// ExamplesFormat selects how few-shot examples reach the LLM
+type ExamplesFormat string
+
+const (
+	// ExamplesPrompt appends the examples to the rendered prompt (default)
+	ExamplesPrompt ExamplesFormat = "prompt"
+	// ExamplesMessages sends each example as a user message answered by an
+	// assistant message, before the prompt
+	ExamplesMessages ExamplesFormat = "messages"
+)
+
+// FewShotExample is a labeled example of the classification the LLM performs
+type FewShotExample struct {
+	// Input is the example text; other values, such as a state snippet, are
+	// shown as JSON
+	Input interface{} `json:"input"`
+	// Route is the expected answer: a route key, yes/no for boolean answers
+	// or a number for numeric answers
+	Route string `json:"route"`
+	// Reasoning is the example explanation in structured output mode
+	Reasoning string `json:"reasoning,omitempty"`
+}
+
+// text returns the example input as shown to the LLM
+func (e FewShotExample) text() string {
+	if s, ok := e.Input.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(e.Input)
+	if err != nil {
+		return fmt.Sprint(e.Input)
+	}
+	return string(data)
+}
+
+// answer returns the example answer in the form the LLM is asked to answer
+func (e FewShotExample) answer(llmConfig *LLMConfig) string {
+	if !llmConfig.StructuredOutput {
+		return e.Route
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"route":      e.Route,
+		"confidence": 1.0,
+		"reasoning":  e.Reasoning,
+	})
+	return string(data)
+}
+
+// withExamples appends the examples of llmConfig to the prompt when they are
+// sent as part of it
+func withExamples(prompt string, llmConfig *LLMConfig) string {
+	if len(llmConfig.Examples) == 0 || llmConfig.ExamplesFormat == ExamplesMessages {
+		return prompt
+	}
+
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\nExamples:")
+	for _, example := range llmConfig.Examples {
+		fmt.Fprintf(&b, "\n\nInput: %s\nAnswer: %s", example.text(), example.answer(llmConfig))
+	}
+	return b.String()
+}
+
+// exampleMessages returns the examples of llmConfig as prior conversation
+// turns when they are sent as messages
+func exampleMessages(llmConfig *LLMConfig) []domain.Message {
+	if llmConfig.ExamplesFormat != ExamplesMessages {
+		return nil
+	}
+
+	messages := make([]domain.Message, 0, 2*len(llmConfig.Examples))
+	for _, example := range llmConfig.Examples {
+		messages = append(messages,
+			domain.Message{Role: "user", Content: example.text()},
+			domain.Message{Role: "assistant", Content: example.answer(llmConfig)},
+		)
+	}
+	return messages
+}
+
+// examples checks the examples of an LLM config against its answers
+func (v *configValidator) examples(field string, llmConfig *LLMConfig) {
+	switch llmConfig.ExamplesFormat {
+	case "", ExamplesPrompt, ExamplesMessages:
+	default:
+		v.add(field+".examples_format", fmt.Sprintf("examples_format '%s' is not supported (use prompt or messages)", llmConfig.ExamplesFormat))
+	}
+	if len(llmConfig.Examples) == 0 {
+		return
+	}
+	if len(llmConfig.Categories) > 0 || llmConfig.AutoHierarchy {
+		v.add(field+".examples", "examples do not support categories or auto_hierarchy")
+		return
+	}
+
+	for i, example := range llmConfig.Examples {
+		exampleField := fmt.Sprintf("%s.examples[%d]", field, i)
+		if example.Input == nil {
+			v.add(exampleField+".input", "input is required")
+		}
+		switch llmConfig.AnswerType {
+		case AnswerBoolean:
+			if example.Route != routeYes && example.Route != routeNo {
+				v.add(exampleField+".route", "route must be yes or no for boolean answers")
+			}
+		case AnswerNumber:
+			if _, err := strconv.ParseFloat(example.Route, 64); err != nil {
+				v.add(exampleField+".route", "route must be a number for number answers")
+			}
+		default:
+			if _, ok := llmConfig.Routes[example.Route]; !ok {
+				v.add(exampleField+".route", fmt.Sprintf("route '%s' is not a configured route", example.Route))
+			}
+		}
+	}
+}
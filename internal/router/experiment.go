@@ -0,0 +1,69 @@
+package router
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+)
+
+// Experiment arms, as reported in decisions and the
+// experiment_decisions_total metric
+const (
+	VariantControl   = "control"
+	VariantTreatment = "variant"
+)
+
+// Experiment splits traffic between the node config (the control) and a
+// variant config, so the downstream outcomes of a routing change can be
+// compared before rolling it out
+type Experiment struct {
+	// ID names the experiment in decisions and metrics
+	ID string `json:"id"`
+	// Split is the percentage of executions (0-100) routed with Variant
+	Split float64 `json:"split"`
+	// Variant is the config evaluated for executions in the variant arm
+	Variant *NodeConfig `json:"variant"`
+}
+
+// selectArm returns the config and arm an execution is routed with. The
+// `experiment_bucket` header forces an arm when it names one; otherwise the
+// execution is assigned by hashing its ID with the experiment ID, so retries
+// of an execution stay in the same arm.
+func selectArm(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*NodeConfig, string) {
+	experiment := config.Experiment
+	if ec := ExecutionContextFrom(ctx); ec != nil {
+		switch ec.ExperimentBucket {
+		case VariantControl:
+			return config, VariantControl
+		case VariantTreatment:
+			return experiment.Variant, VariantTreatment
+		}
+	}
+
+	if experimentBucket(experiment.ID, state.GraphID) < experiment.Split {
+		return experiment.Variant, VariantTreatment
+	}
+	return config, VariantControl
+}
+
+// experimentBucket maps an execution to a stable bucket in [0, 100) with a
+// resolution of 0.01
+func experimentBucket(experimentID, executionID string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(experimentID))
+	h.Write([]byte{0})
+	h.Write([]byte(executionID))
+	return float64(h.Sum64()%10000) / 100
+}
+
+// tagExperiment records the experiment arm on a decision made with the
+// routed config and meters it
+func tagExperiment(experiment *Experiment, arm string, routed *NodeConfig, result *RoutingResult) {
+	metrics.ExperimentDecisions.WithLabelValues(experiment.ID, arm, targetOf(routed, result)).Inc()
+	if result != nil {
+		result.Experiment = experiment.ID
+		result.Variant = arm
+	}
+}
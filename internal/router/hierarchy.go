@@ -0,0 +1,275 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// defaultRouteSeparator splits hierarchical route keys into category and sub-route
+const defaultRouteSeparator = "/"
+
+// Classification stage names
+const (
+	stageCategory = "category"
+	stageRoute    = "route"
+)
+
+// StageResult records one stage of a hierarchical LLM classification
+type StageResult struct {
+	Stage    string `json:"stage"`
+	Response string `json:"response"`
+	Choice   string `json:"choice,omitempty"`
+}
+
+// classification is the outcome of matching LLM output to routes
+type classification struct {
+	Target   string
+	Response string
+	Matched  bool
+	// Confidence and Reasoning are reported by the LLM in structured output mode
+	Confidence float64
+	Reasoning  string
+	// Reason explains why the response was rejected, when known
+	Reason string
+	// LowConfidence is set when the answer was rejected for its confidence
+	LowConfidence bool
+	Stages        []StageResult
+}
+
+// describe explains an accepted classification for the routing result
+func (c *classification) describe() string {
+	if c.Reasoning == "" {
+		return c.Response
+	}
+	return fmt.Sprintf("%s (confidence %.2f: %s)", c.Response, c.Confidence, c.Reasoning)
+}
+
+// rejection explains why a classification did not select a route
+func (c *classification) rejection() string {
+	if c.Reason != "" {
+		return c.Reason
+	}
+	return fmt.Sprintf("llm response '%s' did not match any route", c.Response)
+}
+
+// checkRouteCardinality warns when an LLM config defines more routes than a
+// single classification prompt handles reliably
+func (r *Router) checkRouteCardinality(field string, llmConfig *LLMConfig) {
+	if len(llmConfig.Categories) > 0 || len(llmConfig.Routes) <= r.maxLLMRoutes {
+		return
+	}
+
+	if llmConfig.AutoHierarchy {
+		r.logger.Debug("llm route count over threshold, using two-stage classification",
+			zap.String("field", field),
+			zap.Int("routes", len(llmConfig.Routes)),
+			zap.Int("max_routes", r.maxLLMRoutes),
+		)
+		return
+	}
+
+	r.logger.Warn("llm config defines more routes than recommended, classification quality may degrade",
+		zap.String("field", field),
+		zap.Int("routes", len(llmConfig.Routes)),
+		zap.Int("max_routes", r.maxLLMRoutes),
+		zap.String("hint", "set auto_hierarchy or categories to classify by category first"),
+	)
+}
+
+// classify calls the LLM with the rendered prompt and matches its answer to
+// the configured routes. Configs with categories, or large route sets with
+// auto_hierarchy enabled, are classified in two stages: category first, then
+// route within that category.
+func (r *Router) classify(ctx context.Context, state *domain.GraphState, tenant string, binding *LLMBinding, prompt string, llmConfig *LLMConfig) (*classification, error) {
+	switch {
+	case len(llmConfig.Categories) > 0:
+		return r.classifyCategories(ctx, state, tenant, binding, prompt, llmConfig)
+	case llmConfig.AutoHierarchy && len(llmConfig.Routes) > r.maxLLMRoutes:
+		return r.classifyAuto(ctx, tenant, binding, prompt, llmConfig)
+	}
+
+	prompt = withOutputFormat(withExamples(prompt, llmConfig), llmConfig.Routes, llmConfig)
+	response, err := r.callLLM(ctx, tenant, binding, exampleMessages(llmConfig), prompt, r.earlyStopRoutes(llmConfig.Routes, llmConfig), r.llmTimeoutFor(llmConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	r.log(ctx).Debug("llm response received",
+		zap.String("response", r.redactor.String(response)),
+	)
+
+	match := r.matchResponse(response, llmConfig.Routes, llmConfig)
+	return &classification{
+		Target:     match.Target,
+		Response:   match.Label,
+		Matched:    match.Matched,
+		Confidence: match.Confidence,
+		Reasoning:  match.Reasoning,
+		Reason:     match.Reason,
+
+		LowConfidence: match.LowConfidence,
+	}, nil
+}
+
+// classifyCategories selects a category with the top-level prompt, then a
+// target with the category's own prompt and routes
+func (r *Router) classifyCategories(ctx context.Context, state *domain.GraphState, tenant string, binding *LLMBinding, prompt string, llmConfig *LLMConfig) (*classification, error) {
+	categories := llmConfig.Categories
+	names := make(map[string]string, len(categories))
+	for name := range categories {
+		names[name] = name
+	}
+
+	return r.classifyTwoStage(ctx, tenant, binding, prompt, names, llmConfig, func(name string) (string, map[string]string, error) {
+		category := categories[name]
+		if category.PromptTemplate == "" && category.TemplateRef == "" {
+			return withChoices(prompt, "sub-category of "+name, category.Routes), category.Routes, nil
+		}
+
+		categoryPrompt, err := r.renderPrompt(ctx, state, llmConfig, category.PromptTemplate, category.TemplateRef)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to render prompt for category %s: %w", name, err)
+		}
+		return categoryPrompt, category.Routes, nil
+	})
+}
+
+// classifyAuto picks a category from the route key prefixes, then a
+// sub-route within that category
+func (r *Router) classifyAuto(ctx context.Context, tenant string, binding *LLMBinding, prompt string, llmConfig *LLMConfig) (*classification, error) {
+	categories := splitRoutes(llmConfig.Routes, llmConfig.Separator)
+
+	names := make(map[string]string, len(categories))
+	for name := range categories {
+		names[name] = name
+	}
+
+	return r.classifyTwoStage(ctx, tenant, binding, withChoices(prompt, "category", names), names, llmConfig, func(name string) (string, map[string]string, error) {
+		return withChoices(prompt, "sub-category of "+name, categories[name]), categories[name], nil
+	})
+}
+
+// classifyTwoStage runs the category stage, then the route stage built by
+// next for the chosen category. A category with a single route needs no
+// second call.
+func (r *Router) classifyTwoStage(ctx context.Context, tenant string, binding *LLMBinding, prompt string, names map[string]string, llmConfig *LLMConfig, next func(category string) (string, map[string]string, error)) (*classification, error) {
+	first, match, err := r.classifyStage(ctx, tenant, binding, stageCategory, prompt, names, llmConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &classification{
+		Response:   first.Response,
+		Confidence: match.Confidence,
+		Reasoning:  match.Reasoning,
+		Reason:     match.Reason,
+		Stages:     []StageResult{first},
+
+		LowConfidence: match.LowConfidence,
+	}
+	if !match.Matched {
+		return result, nil
+	}
+
+	category := first.Choice
+	routePrompt, routes, err := next(category)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(routes) == 1 {
+		for _, target := range routes {
+			result.Target = target
+			result.Response = category
+			result.Matched = true
+		}
+		return result, nil
+	}
+
+	second, match, err := r.classifyStage(ctx, tenant, binding, stageRoute, routePrompt, routes, llmConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Stages = append(result.Stages, second)
+	result.Target = second.Choice
+	result.Response = fmt.Sprintf("%s > %s", category, second.Response)
+	result.Matched = match.Matched
+	result.Confidence = match.Confidence
+	result.Reasoning = match.Reasoning
+	result.Reason = match.Reason
+	result.LowConfidence = match.LowConfidence
+	return result, nil
+}
+
+// classifyStage runs one classification stage in its own span
+func (r *Router) classifyStage(ctx context.Context, tenant string, binding *LLMBinding, stage, prompt string, routes map[string]string, llmConfig *LLMConfig) (StageResult, routeMatch, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "llm.stage",
+		trace.WithAttributes(attribute.String("llm.stage", stage)),
+	)
+	defer span.End()
+
+	response, err := r.callLLM(ctx, tenant, binding, nil, withOutputFormat(prompt, routes, llmConfig), r.earlyStopRoutes(routes, llmConfig), r.llmTimeoutFor(llmConfig))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "llm stage failed")
+		return StageResult{}, routeMatch{}, err
+	}
+
+	match := r.matchResponse(response, routes, llmConfig)
+	span.SetAttributes(
+		attribute.String("llm.response", r.redactor.String(response)),
+		attribute.String("llm.choice", match.Target),
+		attribute.Bool("llm.matched", match.Matched),
+	)
+
+	r.log(ctx).Debug("llm stage response received",
+		zap.String("stage", stage),
+		zap.String("response", r.redactor.String(response)),
+		zap.String("choice", match.Target),
+	)
+
+	return StageResult{Stage: stage, Response: match.Label, Choice: match.Target}, match, nil
+}
+
+// splitRoutes groups route keys by category. Keys without the separator form
+// a category of their own.
+func splitRoutes(routes map[string]string, separator string) map[string]map[string]string {
+	if separator == "" {
+		separator = defaultRouteSeparator
+	}
+
+	categories := make(map[string]map[string]string)
+	for key, target := range routes {
+		category, sub, found := strings.Cut(key, separator)
+		if !found {
+			sub = key
+		}
+		if categories[category] == nil {
+			categories[category] = make(map[string]string)
+		}
+		categories[category][sub] = target
+	}
+
+	return categories
+}
+
+// withChoices appends the allowed answers for one classification stage to the prompt
+func withChoices(prompt, kind string, choices map[string]string) string {
+	keys := make([]string, 0, len(choices))
+	for key := range choices {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return fmt.Sprintf("%s\n\nRespond with only the %s, one of: %s", prompt, kind, strings.Join(keys, ", "))
+}
@@ -0,0 +1,170 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/tokens"
+	"go.uber.org/zap"
+)
+
+// defaultHistoryLimit is the number of messages kept when a history config
+// sets no limit
+const defaultHistoryLimit = 20
+
+// HistoryConfig exposes the recent messages of an execution to prompt
+// templates as history, a list of {role, content} objects, oldest first
+type HistoryConfig struct {
+	// Path is a dotted state path of a message list, e.g. "inputs.messages"
+	Path string `json:"path,omitempty"`
+	// RedisKey is a Handlebars template of a Redis list holding the messages,
+	// newest last, e.g. "chat:{{state.graph_id}}:messages"
+	RedisKey string `json:"redis_key,omitempty"`
+	// Limit keeps the last Limit messages (20 when unset)
+	Limit int `json:"limit,omitempty"`
+	// MaxTokens drops the oldest messages until the estimated tokens of the
+	// rest fit the budget (0 disables)
+	MaxTokens int `json:"max_tokens,omitempty"`
+}
+
+// HistorySource reads the last n entries of a list
+type HistorySource interface {
+	GetList(ctx context.Context, key string, n int) ([]interface{}, error)
+}
+
+// WithHistorySource reads the Redis lists of history configs from source.
+// History configs with a redis_key fail validation without it.
+func WithHistorySource(source HistorySource) Option {
+	return func(r *Router) {
+		r.historySource = source
+	}
+}
+
+// limit returns the number of messages kept
+func (h *HistoryConfig) limit() int {
+	if h.Limit > 0 {
+		return h.Limit
+	}
+	return defaultHistoryLimit
+}
+
+// history returns the messages of config for a prompt, read from state (the
+// state the prompt is rendered with) or Redis. Failures are logged and yield
+// no history rather than failing the classification.
+func (r *Router) history(ctx context.Context, state *domain.GraphState, config *HistoryConfig, guard *PromptGuard) []interface{} {
+	var entries []interface{}
+	if config.Path != "" {
+		entries, _ = lookupStatePath(state, config.Path).([]interface{})
+	} else {
+		if r.historySource == nil {
+			return []interface{}{}
+		}
+		key, err := r.templateEngine.Render(config.RedisKey, promptData(ctx, state))
+		if err == nil {
+			entries, err = r.historySource.GetList(ctx, key, config.limit())
+		}
+		if err != nil {
+			r.log(ctx).Warn("failed to read message history",
+				zap.Error(err),
+			)
+			return []interface{}{}
+		}
+		if r.redactPrompts {
+			entries, _ = r.redactor.Map(map[string]interface{}{"history": entries})["history"].([]interface{})
+		}
+		if guard.sanitizes() {
+			entries, _ = guard.value(entries).([]interface{})
+		}
+	}
+
+	if len(entries) > config.limit() {
+		entries = entries[len(entries)-config.limit():]
+	}
+	messages := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		messages[i] = historyMessage(entry)
+	}
+	return truncateHistory(messages, config.MaxTokens)
+}
+
+// historyMessage normalizes a history entry to an object with role and
+// content; plain strings are user messages
+func historyMessage(entry interface{}) map[string]interface{} {
+	if message, ok := entry.(map[string]interface{}); ok {
+		normalized := map[string]interface{}{"role": "user"}
+		for key, value := range message {
+			normalized[key] = value
+		}
+		return normalized
+	}
+	if s, ok := entry.(string); ok {
+		return map[string]interface{}{"role": "user", "content": s}
+	}
+	return map[string]interface{}{"role": "user", "content": fmt.Sprint(entry)}
+}
+
+// truncateHistory drops the oldest messages until the estimated tokens of
+// the rest fit maxTokens
+func truncateHistory(messages []interface{}, maxTokens int) []interface{} {
+	if maxTokens <= 0 {
+		return messages
+	}
+
+	total := 0
+	start := len(messages)
+	for start > 0 {
+		message := messages[start-1].(map[string]interface{})
+		cost := tokens.Estimate(fmt.Sprint(message["role"], ": ", message["content"]))
+		if total+cost > maxTokens {
+			break
+		}
+		total += cost
+		start--
+	}
+	if start > 0 {
+		metrics.HistoryTruncations.Inc()
+	}
+	return messages[start:]
+}
+
+// lookupStatePath returns the value at a dotted path of the state inputs
+// ("inputs.messages"), or nil
+func lookupStatePath(state *domain.GraphState, path string) interface{} {
+	var node interface{} = map[string]interface{}{"inputs": state.Inputs}
+	for _, segment := range strings.Split(path, ".") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[segment]
+	}
+	return node
+}
+
+// history checks the history config of an LLM config
+func (v *configValidator) history(field string, config *HistoryConfig) {
+	switch {
+	case config.Path == "" && config.RedisKey == "":
+		v.add(field+".path", "path or redis_key is required")
+	case config.Path != "" && config.RedisKey != "":
+		v.add(field+".redis_key", "path and redis_key are mutually exclusive")
+	case config.Path != "" && !strings.HasPrefix(config.Path, "inputs."):
+		v.add(field+".path", "path must be a state path under inputs, e.g. inputs.messages")
+	case config.RedisKey != "":
+		if v.router.historySource == nil {
+			v.add(field+".redis_key", "redis history is not available on this worker")
+		}
+		if err := v.router.templateEngine.ValidateTemplate(config.RedisKey); err != nil {
+			v.add(field+".redis_key", err.Error())
+		}
+	}
+	if config.Limit < 0 {
+		v.add(field+".limit", "limit must not be negative")
+	}
+	if config.MaxTokens < 0 {
+		v.add(field+".max_tokens", "max_tokens must not be negative")
+	}
+}
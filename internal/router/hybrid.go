@@ -16,22 +16,24 @@ func (r *Router) routeHybrid(ctx context.Context, state *domain.GraphState, conf
 	}
 
 	// Phase 1: Try fast rules (CEL)
-	r.logger.Debug("trying fast rules",
+	r.log(ctx).Debug("trying fast rules",
 		zap.Int("num_rules", len(config.FastRules)),
 	)
 
-	celState := r.prepareStateForCEL(state)
+	ctx = withDeclarations(ctx, config)
+	celState := r.prepareStateForCEL(ctx, state, config)
 
 	for i, rule := range config.FastRules {
-		r.logger.Debug("evaluating fast rule",
+		r.log(ctx).Debug("evaluating fast rule",
 			zap.Int("rule_index", i),
 			zap.String("condition", rule.Condition),
 		)
 
 		// Evaluate the condition
-		result, err := r.celEvaluator.Evaluate(ctx, rule.Condition, celState)
+		result, err := r.evaluateCondition(ctx, rule.Condition, celState)
 		if err != nil {
-			r.logger.Warn("fast rule evaluation error",
+			r.recordRule(ctx, "fast_rules", i, hitError)
+			r.log(ctx).Warn("fast rule evaluation error",
 				zap.Int("rule_index", i),
 				zap.String("condition", rule.Condition),
 				zap.Error(err),
@@ -43,7 +45,8 @@ func (r *Router) routeHybrid(ctx context.Context, state *domain.GraphState, conf
 		// Check if condition is true
 		matched, ok := result.(bool)
 		if !ok {
-			r.logger.Warn("fast rule condition did not return boolean",
+			r.recordRule(ctx, "fast_rules", i, hitError)
+			r.log(ctx).Warn("fast rule condition did not return boolean",
 				zap.Int("rule_index", i),
 				zap.String("condition", rule.Condition),
 				zap.Any("result", result),
@@ -51,27 +54,42 @@ func (r *Router) routeHybrid(ctx context.Context, state *domain.GraphState, conf
 			continue
 		}
 
-		if matched {
-			r.logger.Info("fast rule matched",
-				zap.Int("rule_index", i),
-				zap.String("condition", rule.Condition),
-				zap.String("target", rule.Target),
-			)
+		if !matched {
+			r.recordRule(ctx, "fast_rules", i, hitNoMatch)
+			continue
+		}
 
-			return &RoutingResult{
-				TargetNode: rule.Target,
-				Reasoning:  fmt.Sprintf("matched fast rule %d: %s", i, rule.Condition),
-				Mode:       string(ModeHybrid),
-				PathTaken:  "fast",
-			}, nil
+		r.recordRule(ctx, "fast_rules", i, hitMatched)
+		for skipped := i + 1; skipped < len(config.FastRules); skipped++ {
+			r.recordRule(ctx, "fast_rules", skipped, hitSkipped)
 		}
+		target, err := r.resolveTarget(ctx, rule, config, celState)
+		if err != nil {
+			return r.unresolvedTarget(ctx, ModeHybrid, i, rule, config, err), nil
+		}
+
+		r.log(ctx).Info("fast rule matched",
+			zap.Int("rule_index", i),
+			zap.String("condition", rule.Condition),
+			zap.String("target", target),
+		)
+
+		return &RoutingResult{
+			TargetNode: target,
+			Reasoning:  fmt.Sprintf("matched fast rule %d: %s", i, rule.Condition),
+			Mode:       string(ModeHybrid),
+			PathTaken:  "fast",
+			RuleIndex:  &i,
+			Condition:  rule.Condition,
+		}, nil
 	}
 
 	// Phase 2: Fast rules didn't match, try LLM fallback
-	r.logger.Debug("fast rules did not match, trying llm fallback")
+	r.log(ctx).Debug("fast rules did not match, trying llm fallback")
 
-	if r.llmClient == nil {
-		r.logger.Warn("llm client not configured, using fallback route")
+	tenant, binding := r.resolveLLM(ctx, state)
+	if binding == nil {
+		r.log(ctx).Warn("llm client not configured, using fallback route")
 		return &RoutingResult{
 			TargetNode: config.Fallback,
 			Reasoning:  "fast rules did not match and llm client not configured",
@@ -81,9 +99,9 @@ func (r *Router) routeHybrid(ctx context.Context, state *domain.GraphState, conf
 	}
 
 	// Render prompt template
-	prompt, err := r.renderPrompt(state, config.LLMFallback.PromptTemplate)
+	prompt, err := r.renderPrompt(ctx, state, config.LLMFallback, config.LLMFallback.PromptTemplate, config.LLMFallback.TemplateRef)
 	if err != nil {
-		r.logger.Error("failed to render llm prompt",
+		r.log(ctx).Error("failed to render llm prompt",
 			zap.Error(err),
 		)
 		return &RoutingResult{
@@ -94,46 +112,53 @@ func (r *Router) routeHybrid(ctx context.Context, state *domain.GraphState, conf
 		}, nil
 	}
 
-	r.logger.Debug("calling llm for routing",
-		zap.String("prompt", prompt),
+	r.log(ctx).Debug("calling llm for routing",
+		zap.String("prompt", r.redactor.String(prompt)),
 	)
 
-	// Call LLM
-	response, err := r.callLLM(ctx, prompt)
+	// Call LLM and match its response to routes
+	classified, err := r.classify(ctx, state, tenant, binding, prompt, config.LLMFallback)
 	if err != nil {
-		r.logger.Error("llm call failed",
+		r.log(ctx).Error("llm call failed",
 			zap.Error(err),
 		)
 		return &RoutingResult{
 			TargetNode: config.Fallback,
-			Reasoning:  fmt.Sprintf("llm call failed: %v", err),
+			Reasoning:  llmFailureReasoning(err),
 			Mode:       string(ModeHybrid),
 			PathTaken:  "fallback",
+			PromptHash: promptHash(prompt),
 		}, nil
 	}
 
-	r.logger.Debug("llm response received",
-		zap.String("response", response),
-	)
+	if classified.LowConfidence && config.LLMFallback.AbstainTarget != "" {
+		return r.abstainResult(classified, config.LLMFallback, ModeHybrid, prompt), nil
+	}
 
-	// Parse LLM response and match to routes
-	target, matched := r.matchLLMResponse(response, config.LLMFallback.Routes)
-	if !matched {
-		r.logger.Warn("llm response did not match any route",
-			zap.String("response", response),
+	if !classified.Matched {
+		r.log(ctx).Warn("llm response did not match any route",
+			zap.String("response", r.redactor.String(classified.Response)),
+			zap.String("reason", classified.rejection()),
 		)
 		return &RoutingResult{
-			TargetNode: config.Fallback,
-			Reasoning:  fmt.Sprintf("llm response '%s' did not match any route", response),
-			Mode:       string(ModeHybrid),
-			PathTaken:  "fallback",
+			TargetNode:  config.Fallback,
+			Reasoning:   classified.rejection(),
+			Mode:        string(ModeHybrid),
+			PathTaken:   "fallback",
+			PromptHash:  promptHash(prompt),
+			Stages:      classified.Stages,
+			LLMResponse: classified.Response,
 		}, nil
 	}
 
 	return &RoutingResult{
-		TargetNode: target,
-		Reasoning:  fmt.Sprintf("llm classified as: %s (after fast rules failed)", response),
-		Mode:       string(ModeHybrid),
-		PathTaken:  "slow",
+		TargetNode:  classified.Target,
+		Confidence:  classified.Confidence,
+		Reasoning:   fmt.Sprintf("llm classified as: %s (after fast rules failed)", classified.describe()),
+		Mode:        string(ModeHybrid),
+		PathTaken:   "slow",
+		PromptHash:  promptHash(prompt),
+		Stages:      classified.Stages,
+		LLMResponse: classified.Response,
 	}, nil
 }
@@ -0,0 +1,178 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"go.uber.org/zap"
+)
+
+// KeywordConfig routes to the route whose keywords and patterns score
+// highest in the input text
+type KeywordConfig struct {
+	// Input is a Handlebars template of the text scored, e.g.
+	// "{{state.inputs.subject}} {{state.inputs.body}}"
+	Input string `json:"input"`
+	// Routes are scored in order; the first of equally scoring routes wins
+	Routes []KeywordRoute `json:"routes"`
+	// Threshold is the minimum score of the winning route; lower scores
+	// take the fallback route (any positive score when unset)
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// KeywordRoute is a target with the terms that vote for it
+type KeywordRoute struct {
+	Target string `json:"target"`
+	// Keywords maps words or phrases, matched as whole words ignoring case,
+	// to the weight each adds once present
+	Keywords map[string]float64 `json:"keywords,omitempty"`
+	// Patterns maps regular expressions to the weight each adds once
+	// matched
+	Patterns map[string]float64 `json:"patterns,omitempty"`
+}
+
+// keywordScore is the score of one route
+type keywordScore struct {
+	index   int
+	score   float64
+	matched []string
+}
+
+// routeKeyword routes with the highest scoring keyword route
+func (r *Router) routeKeyword(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error) {
+	if err := r.validateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	cfg := config.Keyword
+	input, err := r.templateEngine.Render(cfg.Input, promptData(ctx, state))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render keyword input: %w", err)
+	}
+
+	var best *keywordScore
+	for i, route := range cfg.Routes {
+		score, err := r.scoreKeywords(route, input)
+		if err != nil {
+			return nil, fmt.Errorf("keyword route %d: %w", i, err)
+		}
+		score.index = i
+		if best == nil || score.score > best.score {
+			best = score
+		}
+	}
+
+	if best == nil || best.score <= 0 || best.score < cfg.Threshold {
+		r.log(ctx).Info("no keyword route reached the threshold, using fallback",
+			zap.String("fallback", config.Fallback),
+		)
+		reasoning := "no keyword matched"
+		if best != nil && best.score > 0 {
+			reasoning = fmt.Sprintf("best keyword route %d scored %g, below threshold %g", best.index, best.score, cfg.Threshold)
+		}
+		return &RoutingResult{
+			TargetNode: config.Fallback,
+			Reasoning:  reasoning,
+			Mode:       string(ModeKeyword),
+			PathTaken:  "fallback",
+		}, nil
+	}
+
+	return &RoutingResult{
+		TargetNode: cfg.Routes[best.index].Target,
+		Reasoning:  fmt.Sprintf("keyword route %d scored %g: %s", best.index, best.score, strings.Join(best.matched, ", ")),
+		Mode:       string(ModeKeyword),
+		PathTaken:  "fast",
+		RuleIndex:  &best.index,
+	}, nil
+}
+
+// scoreKeywords adds the weights of the keywords and patterns of route
+// present in input
+func (r *Router) scoreKeywords(route KeywordRoute, input string) (*keywordScore, error) {
+	score := &keywordScore{}
+	add := func(term, pattern string, weight float64) error {
+		re, err := r.keywordPattern(pattern)
+		if err != nil {
+			return err
+		}
+		if re.MatchString(input) {
+			score.score += weight
+			score.matched = append(score.matched, term)
+		}
+		return nil
+	}
+
+	for _, keyword := range sortedKeys(route.Keywords) {
+		if err := add(keyword, keywordRegexp(keyword), route.Keywords[keyword]); err != nil {
+			return nil, err
+		}
+	}
+	for _, pattern := range sortedKeys(route.Patterns) {
+		if err := add(pattern, pattern, route.Patterns[pattern]); err != nil {
+			return nil, err
+		}
+	}
+	return score, nil
+}
+
+// keywordRegexp returns the expression matching a keyword as whole words,
+// ignoring case and runs of whitespace
+func keywordRegexp(keyword string) string {
+	words := strings.Fields(keyword)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	return `(?i)(^|\W)` + strings.Join(words, `\s+`) + `($|\W)`
+}
+
+// keywordPattern returns the compiled expression, compiling it once
+func (r *Router) keywordPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := r.keywordPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	r.keywordPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// keyword checks a keyword config
+func (v *configValidator) keyword(field string, cfg *KeywordConfig) {
+	if cfg.Input == "" {
+		v.add(field+".input", "input is required")
+	} else if err := v.router.templateEngine.ValidateTemplate(cfg.Input); err != nil {
+		v.add(field+".input", err.Error())
+	}
+	if len(cfg.Routes) == 0 {
+		v.add(field+".routes", "routes are required")
+	}
+	if cfg.Threshold < 0 {
+		v.add(field+".threshold", "threshold must not be negative")
+	}
+
+	for i, route := range cfg.Routes {
+		routeField := fmt.Sprintf("%s.routes[%d]", field, i)
+		if route.Target == "" {
+			v.add(routeField+".target", "target is required")
+		}
+		if len(route.Keywords) == 0 && len(route.Patterns) == 0 {
+			v.add(routeField, "keywords or patterns are required")
+		}
+		for _, keyword := range sortedKeys(route.Keywords) {
+			if strings.TrimSpace(keyword) == "" {
+				v.add(routeField+".keywords", "keywords must not be empty")
+			}
+		}
+		for _, pattern := range sortedKeys(route.Patterns) {
+			if _, err := v.router.keywordPattern(pattern); err != nil {
+				v.add(routeField+".patterns", fmt.Sprintf("invalid pattern %q: %v", pattern, err))
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+)
+
+// ErrRateLimited is returned for LLM calls rejected by the rate limiter
+var ErrRateLimited = errors.New("llm rate limit exceeded")
+
+// Rate limit scopes, as reported by the llm_rate_limited_total metric
+const (
+	limitScopeGlobal      = "global"
+	limitScopeNode        = "node"
+	limitScopeConcurrency = "concurrency"
+)
+
+// LimiterConfig configures the LLM rate limiter. Zero values disable the
+// corresponding limit.
+type LimiterConfig struct {
+	// MaxRPS bounds LLM calls per second across all nodes, with bursts of
+	// up to Burst calls
+	MaxRPS float64
+	Burst  int
+	// NodeMaxRPS bounds LLM calls per second of each node ID
+	NodeMaxRPS float64
+	// MaxConcurrent bounds LLM calls in flight
+	MaxConcurrent int
+	// MaxWait is how long a call may queue for the limiter before it is
+	// rejected and the decision takes the fallback route
+	MaxWait time.Duration
+}
+
+// Limiter bounds the rate and concurrency of LLM calls, globally and per node
+type Limiter struct {
+	config LimiterConfig
+	global *tokenBucket
+	slots  chan struct{}
+
+	mu    sync.Mutex
+	nodes map[string]*tokenBucket
+}
+
+// NewLimiter creates an LLM rate limiter
+func NewLimiter(config LimiterConfig) *Limiter {
+	l := &Limiter{
+		config: config,
+		nodes:  make(map[string]*tokenBucket),
+	}
+	if config.MaxRPS > 0 {
+		l.global = newTokenBucket(config.MaxRPS, config.Burst)
+	}
+	if config.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, config.MaxConcurrent)
+	}
+	return l
+}
+
+// Acquire waits up to MaxWait, or the context deadline if sooner, for the
+// limits of nodeID to admit a call. It returns a release func that must be
+// called once the call completes, or ErrRateLimited.
+func (l *Limiter) Acquire(ctx context.Context, nodeID string) (func(), error) {
+	start := time.Now()
+	deadline := start.Add(l.config.MaxWait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	defer func() {
+		metrics.LLMLimiterWait.Observe(metrics.Since(start))
+	}()
+
+	// Reserve tokens from every bucket, then wait for the latest of them
+	var wait time.Duration
+	var reserved []*tokenBucket
+	cancel := func() {
+		for _, b := range reserved {
+			b.cancel()
+		}
+	}
+	for _, scoped := range []struct {
+		scope  string
+		bucket *tokenBucket
+	}{
+		{limitScopeNode, l.nodeBucket(nodeID)},
+		{limitScopeGlobal, l.global},
+	} {
+		if scoped.bucket == nil {
+			continue
+		}
+		delay, ok := scoped.bucket.reserve(start, deadline.Sub(start))
+		if !ok {
+			cancel()
+			metrics.LLMRateLimited.WithLabelValues(scoped.scope).Inc()
+			return nil, ErrRateLimited
+		}
+		reserved = append(reserved, scoped.bucket)
+		wait = max(wait, delay)
+	}
+	if wait > 0 && !sleepUntil(ctx, wait) {
+		cancel()
+		return nil, ctx.Err()
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	if !acquireSlot(ctx, l.slots, deadline) {
+		cancel()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		metrics.LLMRateLimited.WithLabelValues(limitScopeConcurrency).Inc()
+		return nil, ErrRateLimited
+	}
+	metrics.LLMInFlight.Inc()
+	return func() {
+		<-l.slots
+		metrics.LLMInFlight.Dec()
+	}, nil
+}
+
+// acquireSlot takes a concurrency slot, waiting until deadline for one to
+// free up
+func acquireSlot(ctx context.Context, slots chan struct{}, deadline time.Time) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	wait := time.Until(deadline)
+	if wait <= 0 {
+		return false
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// nodeBucket returns the token bucket of a node, or nil when per-node limits
+// are disabled
+func (l *Limiter) nodeBucket(nodeID string) *tokenBucket {
+	if l.config.NodeMaxRPS <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.nodes[nodeID]
+	if !ok {
+		bucket = newTokenBucket(l.config.NodeMaxRPS, l.config.Burst)
+		l.nodes[nodeID] = bucket
+	}
+	return bucket
+}
+
+// tokenBucket admits rate calls per second with bursts of up to burst calls.
+// Tokens may be reserved ahead of time, leaving the balance negative.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full bucket. A burst below one defaults to the
+// rate rounded up.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := float64(burst)
+	if burst < 1 {
+		b = math.Max(1, math.Ceil(rate))
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token at now and returns how long to wait until it is
+// available. Nothing is taken when the wait would exceed maxWait.
+func (b *tokenBucket) reserve(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+
+	var wait time.Duration
+	if b.tokens < 1 {
+		wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	}
+	if wait > maxWait {
+		return 0, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+// cancel returns a reserved token that was not used
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// sleepUntil waits for d, reporting false when ctx is done first
+func sleepUntil(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,180 @@
+package router
+
+import (
+	"fmt"
+
+	"github.com/aescanero/dago-node-router/internal/eval/cel"
+)
+
+// ExpressionReport is the complexity of one CEL expression of a NodeConfig
+type ExpressionReport struct {
+	Field string `json:"field"`
+	// Nodes is the number of nodes of the expression tree, macros expanded
+	Nodes int `json:"nodes"`
+	// CostMin and CostMax bound the estimated evaluation cost, assuming state
+	// values of up to 1000 elements
+	CostMin uint64 `json:"cost_min"`
+	CostMax uint64 `json:"cost_max"`
+}
+
+// LintReport is the result of linting a NodeConfig: the complexity of its
+// rule expressions and the rules that can never decide a route as written
+type LintReport struct {
+	Expressions []ExpressionReport `json:"expressions,omitempty"`
+	Warnings    []ValidationError  `json:"warnings,omitempty"`
+}
+
+// LintNodeConfig analyzes the rule conditions and target expressions of a
+// routing configuration, including its shadow and experiment variant. It
+// warns about conditions that are always true or always false, rules that
+// are unreachable because an earlier rule matches every state they match,
+// and expressions whose estimated cost may exceed the evaluation cost limit.
+// Expressions that do not compile are skipped; ValidateNodeConfig reports
+// them. The config is not modified.
+func (r *Router) LintNodeConfig(config *NodeConfig) *LintReport {
+	report := &LintReport{}
+	if config == nil {
+		return report
+	}
+	r.lintConfig("", config, report)
+	return report
+}
+
+// lintConfig lints config into report, prefixing its fields with prefix
+func (r *Router) lintConfig(prefix string, config *NodeConfig, report *LintReport) {
+	if resolved, err := r.resolveRuleSets(config); err == nil {
+		config = resolved
+	}
+
+	// Only valid variables are declared, like during validation
+	variables := &configValidator{router: r}
+	variables.variables(config.Variables)
+	l := &configLinter{router: r, declared: variables.declared, report: report}
+
+	reorderable := r.reorder.enabled && config.ReorderRules
+	l.rules(prefix+"rules", config.Rules, planRules(config.Rules, config.Groups), reorderable)
+	l.rules(prefix+"fast_rules", config.FastRules, inOrder(config.FastRules), false)
+	for i, stage := range config.Pipeline {
+		field := fmt.Sprintf("%spipeline[%d].rules", prefix, i)
+		l.rules(field, stage.Rules, planRules(stage.Rules, stage.Groups), reorderable)
+	}
+
+	if config.Shadow != nil {
+		r.lintConfig(prefix+"shadow.", config.Shadow, report)
+	}
+	if config.Experiment != nil && config.Experiment.Variant != nil {
+		r.lintConfig(prefix+"experiment.variant.", config.Experiment.Variant, report)
+	}
+}
+
+// inOrder plans rules evaluated in array order, one unit per rule
+func inOrder(rules []Rule) []ruleUnit {
+	units := make([]ruleUnit, len(rules))
+	for i := range rules {
+		units[i] = ruleUnit{indexes: []int{i}}
+	}
+	return units
+}
+
+// configLinter accumulates the lint report of a NodeConfig
+type configLinter struct {
+	router   *Router
+	declared cel.Declarations
+	report   *LintReport
+}
+
+// warn records a lint warning
+func (l *configLinter) warn(field, message string) {
+	l.report.Warnings = append(l.report.Warnings, ValidationError{Field: field, Message: message})
+}
+
+// analyze records the complexity of an expression and warns when it may
+// exceed the cost limit. It returns nil when the expression does not compile.
+func (l *configLinter) analyze(field, expression string) *cel.Analysis {
+	if expression == "" {
+		return nil
+	}
+	analysis, err := l.router.celEvaluator.Analyze(l.declared, expression)
+	if err != nil {
+		return nil
+	}
+	l.report.Expressions = append(l.report.Expressions, ExpressionReport{
+		Field:   field,
+		Nodes:   analysis.Nodes,
+		CostMin: analysis.CostMin,
+		CostMax: analysis.CostMax,
+	})
+	if analysis.OverCostLimit {
+		l.warn(field, "estimated cost may exceed the evaluation cost limit")
+	}
+	return analysis
+}
+
+// rules lints the rules at field, evaluated as planned by units. When
+// reorderable is set, rules of equal priority may run in any order, so they
+// are not reported as shadowing each other.
+func (l *configLinter) rules(field string, rules []Rule, units []ruleUnit, reorderable bool) {
+	if len(rules) == 0 {
+		return
+	}
+	conditions := make([]*cel.Analysis, len(rules))
+	for i, rule := range rules {
+		ruleField := fmt.Sprintf("%s[%d]", field, i)
+		conditions[i] = l.analyze(ruleField+".condition", rule.Condition)
+		l.analyze(ruleField+".target_expr", rule.TargetExpr)
+	}
+
+	// Warnings are reported in rule order
+	warnings := make([][]string, len(rules))
+	for position, unit := range units {
+		for _, i := range unit.indexes {
+			condition := conditions[i]
+			if condition == nil || condition.Constant == nil {
+				continue
+			}
+			switch {
+			case !*condition.Constant:
+				warnings[i] = append(warnings[i], "condition is always false; the rule never matches")
+			case unit.group == "" && position < len(units)-1:
+				warnings[i] = append(warnings[i], "condition is always true; rules evaluated after it never match")
+			default:
+				warnings[i] = append(warnings[i], "condition is always true")
+			}
+		}
+
+		// A standalone rule is unreachable when an earlier standalone rule,
+		// or a rule of an earlier "any" group, matches every state it matches
+		if i := unit.indexes[0]; unit.group == "" && conditions[i] != nil {
+			if j, ok := shadowingRule(rules, conditions, units[:position], i, reorderable); ok {
+				warnings[i] = append(warnings[i], fmt.Sprintf("rule is unreachable: %s[%d] matches every state it matches", field, j))
+			}
+		}
+	}
+	for i, messages := range warnings {
+		for _, message := range messages {
+			l.warn(fmt.Sprintf("%s[%d].condition", field, i), message)
+		}
+	}
+}
+
+// shadowingRule returns the first rule of the earlier units whose condition
+// is implied by the condition of rule i
+func shadowingRule(rules []Rule, conditions []*cel.Analysis, earlier []ruleUnit, i int, reorderable bool) (int, bool) {
+	for _, unit := range earlier {
+		if unit.match == GroupMatchAll {
+			continue
+		}
+		for _, j := range unit.indexes {
+			if conditions[j] == nil {
+				continue
+			}
+			if reorderable && rules[j].Priority == rules[i].Priority {
+				continue
+			}
+			if conditions[i].Implies(conditions[j]) {
+				return j, true
+			}
+		}
+	}
+	return 0, false
+}
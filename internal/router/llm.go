@@ -2,13 +2,28 @@ package router
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/cache"
+	"github.com/aescanero/dago-node-router/internal/eval/template"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/tokens"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// ErrLLMTimeout is returned for LLM calls that exceed their timeout
+var ErrLLMTimeout = errors.New("llm call timed out")
+
 // routeLLM performs LLM-based routing
 func (r *Router) routeLLM(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error) {
 	// Validate configuration
@@ -16,64 +31,153 @@ func (r *Router) routeLLM(ctx context.Context, state *domain.GraphState, config
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
-	if r.llmClient == nil {
+	tenant, binding := r.resolveLLM(ctx, state)
+	if binding == nil {
 		return nil, fmt.Errorf("llm client not configured")
 	}
 
 	// Render prompt template
-	prompt, err := r.renderPrompt(state, config.LLMConfig.PromptTemplate)
+	prompt, err := r.renderPrompt(ctx, state, config.LLMConfig, config.LLMConfig.PromptTemplate, config.LLMConfig.TemplateRef)
+	if errors.Is(err, ErrPromptTooLarge) {
+		r.log(ctx).Warn("prompt exceeds token budget, using fallback route",
+			zap.Error(err),
+		)
+		return &RoutingResult{
+			TargetNode: config.Fallback,
+			Reasoning:  err.Error(),
+			Mode:       string(ModeLLM),
+			PathTaken:  "fallback",
+		}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to render prompt: %w", err)
 	}
 
-	r.logger.Debug("calling llm for routing",
-		zap.String("prompt", prompt),
+	r.log(ctx).Debug("calling llm for routing",
+		zap.String("prompt", r.redactor.String(prompt)),
 	)
 
-	// Call LLM
-	response, err := r.callLLM(ctx, prompt)
+	// Call LLM and match its response to routes
+	classified, err := r.classify(ctx, state, tenant, binding, prompt, config.LLMConfig)
 	if err != nil {
-		r.logger.Error("llm call failed",
+		r.log(ctx).Error("llm call failed",
 			zap.Error(err),
 		)
 		// Fall back to default route on LLM error
 		return &RoutingResult{
 			TargetNode: config.Fallback,
-			Reasoning:  fmt.Sprintf("llm call failed: %v", err),
+			Reasoning:  llmFailureReasoning(err),
 			Mode:       string(ModeLLM),
 			PathTaken:  "fallback",
+			PromptHash: promptHash(prompt),
 		}, nil
 	}
 
-	r.logger.Debug("llm response received",
-		zap.String("response", response),
-	)
+	if classified.LowConfidence && config.LLMConfig.AbstainTarget != "" {
+		return r.abstainResult(classified, config.LLMConfig, ModeLLM, prompt), nil
+	}
 
-	// Parse LLM response and match to routes
-	target, matched := r.matchLLMResponse(response, config.LLMConfig.Routes)
-	if !matched {
-		r.logger.Warn("llm response did not match any route",
-			zap.String("response", response),
+	if !classified.Matched {
+		r.log(ctx).Warn("llm response did not match any route",
+			zap.String("response", r.redactor.String(classified.Response)),
+			zap.String("reason", classified.rejection()),
 		)
 		return &RoutingResult{
-			TargetNode: config.Fallback,
-			Reasoning:  fmt.Sprintf("llm response '%s' did not match any route", response),
-			Mode:       string(ModeLLM),
-			PathTaken:  "fallback",
+			TargetNode:  config.Fallback,
+			Reasoning:   classified.rejection(),
+			Mode:        string(ModeLLM),
+			PathTaken:   "fallback",
+			PromptHash:  promptHash(prompt),
+			Stages:      classified.Stages,
+			LLMResponse: classified.Response,
 		}, nil
 	}
 
 	return &RoutingResult{
-		TargetNode: target,
-		Reasoning:  fmt.Sprintf("llm classified as: %s", response),
-		Mode:       string(ModeLLM),
-		PathTaken:  "slow",
+		TargetNode:  classified.Target,
+		Confidence:  classified.Confidence,
+		Reasoning:   fmt.Sprintf("llm classified as: %s", classified.describe()),
+		Mode:        string(ModeLLM),
+		PathTaken:   "slow",
+		PromptHash:  promptHash(prompt),
+		Stages:      classified.Stages,
+		LLMResponse: classified.Response,
 	}, nil
 }
 
-// renderPrompt renders a Handlebars template with state data
-func (r *Router) renderPrompt(state *domain.GraphState, template string) (string, error) {
+// promptHash returns the SHA-256 hex digest of a rendered prompt, for auditing
+// which prompt produced a decision without publishing its content
+func promptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// llmFailureReasoning explains a fallback caused by a failed LLM call,
+// distinguishing timeouts and oversized prompts from other failures
+func llmFailureReasoning(err error) string {
+	if errors.Is(err, ErrLLMTimeout) || errors.Is(err, ErrPromptTooLarge) {
+		return err.Error()
+	}
+	return fmt.Sprintf("llm call failed: %v", err)
+}
+
+// llmTimeoutFor returns the timeout of each LLM call of llmConfig
+func (r *Router) llmTimeoutFor(llmConfig *LLMConfig) time.Duration {
+	if llmConfig != nil && llmConfig.Timeout > 0 {
+		return time.Duration(llmConfig.Timeout)
+	}
+	return r.llmTimeout
+}
+
+// RenderPrompt renders the prompt template of llmConfig for state as an LLM
+// classification renders it, for tools that test prompts outside a worker
+func (r *Router) RenderPrompt(ctx context.Context, state *domain.GraphState, llmConfig *LLMConfig) (string, error) {
+	return r.renderPrompt(ctx, state, llmConfig, llmConfig.PromptTemplate, llmConfig.TemplateRef)
+}
+
+// renderPrompt renders a template in the syntax of llmConfig with state data,
+// guarded by its prompt guard. The template is given inline or as a template
+// library reference.
+func (r *Router) renderPrompt(ctx context.Context, state *domain.GraphState, llmConfig *LLMConfig, template, ref string) (string, error) {
+	renderer, err := r.renderer(llmConfig.TemplateEngine)
+	if err != nil {
+		return "", err
+	}
+	template, err = r.promptTemplate(template, ref)
+	if err != nil {
+		return "", err
+	}
+
+	if r.redactPrompts {
+		state = r.redactState(state)
+	}
+	guard := r.guardFor(llmConfig)
+	if guard.sanitizes() {
+		state = guard.guardState(state)
+	}
+	var history []interface{}
+	if llmConfig.History != nil {
+		history = r.history(ctx, state, llmConfig.History, guard)
+	}
+	render := func(state *domain.GraphState, history []interface{}) (string, error) {
+		data := promptData(ctx, state)
+		if history != nil {
+			data["history"] = history
+		}
+		prompt, err := renderer.Render(template, data)
+		if err != nil {
+			return "", err
+		}
+		return withGuardNotice(prompt, guard), nil
+	}
+	return r.fitPrompt(state, history, llmConfig, guard, render)
+}
+
+// promptData returns the data templates are rendered with
+func promptData(ctx context.Context, state *domain.GraphState) map[string]interface{} {
 	data := map[string]interface{}{
+		"ctx":    contextVars(ctx),
+		"lookup": lookupVars(ctx),
 		"state": map[string]interface{}{
 			"graph_id": state.GraphID,
 			"status":   string(state.Status),
@@ -86,37 +190,220 @@ func (r *Router) renderPrompt(state *domain.GraphState, template string) (string
 		data[key] = value
 	}
 
-	return r.templateEngine.Render(template, data)
+	return data
+}
+
+// promptTemplate returns an inline prompt template, or the template library
+// template ref names when set
+func (r *Router) promptTemplate(inline, ref string) (string, error) {
+	if ref == "" {
+		return inline, nil
+	}
+	if r.templates == nil {
+		return "", fmt.Errorf("template_ref %s requires a template library", ref)
+	}
+	return r.templates.Resolve(ref)
+}
+
+// validatePrompt checks that exactly one of an inline template and a library
+// reference is set, and that the template parses. It returns the field at
+// fault.
+func (r *Router) validatePrompt(renderer template.Renderer, inline, ref string) (string, error) {
+	if inline != "" && ref != "" {
+		return "template_ref", fmt.Errorf("prompt_template and template_ref are mutually exclusive")
+	}
+	field := "prompt_template"
+	if ref != "" {
+		field = "template_ref"
+	}
+	tmpl, err := r.promptTemplate(inline, ref)
+	if err != nil {
+		return field, err
+	}
+	return field, renderer.ValidateTemplate(tmpl)
 }
 
-// callLLM calls the LLM with the given prompt
-func (r *Router) callLLM(ctx context.Context, prompt string) (string, error) {
+// renderer returns the template engine of a prompt syntax. Only Handlebars
+// is available with the template sandbox, which it alone implements.
+func (r *Router) renderer(syntax TemplateEngineType) (template.Renderer, error) {
+	switch syntax {
+	case "", TemplateHandlebars:
+		return r.templateEngine, nil
+	case TemplateGo, TemplateJinja:
+		if r.sandboxed {
+			return nil, &template.SandboxError{Reason: fmt.Sprintf("template_engine '%s' is not allowed, sandboxed templates must use handlebars", syntax)}
+		}
+		if syntax == TemplateGo {
+			return r.goTemplates, nil
+		}
+		return r.jinjaTemplates, nil
+	default:
+		return nil, fmt.Errorf("template_engine '%s' is not supported", syntax)
+	}
+}
+
+// callLLM calls the LLM with the given prompt after the history messages,
+// bounded by timeout, and records tenant usage. Answers are streamed and
+// stopped early when earlyStop routes are given (see streamCompletion).
+func (r *Router) callLLM(ctx context.Context, tenant string, binding *LLMBinding, history []domain.Message, prompt string, earlyStop map[string]string, timeout time.Duration) (string, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "llm.call",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("llm.model", binding.Model),
+			attribute.String("tenant", tenant),
+		),
+	)
+	defer span.End()
+
+	var cacheKey string
+	if r.cache != nil {
+		// Scoped to the request tenant and the client, so tenants and
+		// providers never share responses, even through the Redis layer
+		cacheKey = cache.Key(TenantFrom(ctx), binding.Provider, binding.BaseURL, binding.Model, conversationText(history, prompt))
+		if response, ok := r.cache.Get(ctx, cacheKey); ok {
+			metrics.LLMCacheRequests.WithLabelValues("hit").Inc()
+			span.SetAttributes(attribute.Bool("llm.cache_hit", true))
+			r.log(ctx).Debug("llm response served from cache",
+				zap.String("model", binding.Model),
+			)
+			return response, nil
+		}
+		metrics.LLMCacheRequests.WithLabelValues("miss").Inc()
+	}
+
+	if r.limiter != nil {
+		release, err := r.limiter.Acquire(ctx, NodeIDFrom(ctx))
+		if err != nil {
+			span.SetStatus(codes.Error, "rate limited")
+			return "", err
+		}
+		defer release()
+	}
+
+	// Budgets are charged to the request tenant, whichever client serves it
+	budgetTenant := TenantFrom(ctx)
+	if r.quotas != nil && budgetTenant != "" {
+		if err := r.quotas.AllowLLM(budgetTenant); err != nil {
+			span.SetStatus(codes.Error, "llm budget exceeded")
+			return "", err
+		}
+	}
+
+	breaker := r.breakerFor(tenant)
+	if breaker != nil {
+		if err := breaker.Allow(); err != nil {
+			metrics.LLMCircuitRejections.WithLabelValues(metrics.Tenants.Value(tenant)).Inc()
+			span.SetStatus(codes.Error, "circuit open")
+			return "", err
+		}
+	}
+
 	// Use GenerateCompletion for compatibility with domain types
 	req := &domain.LLMRequest{
-		Model: "claude-sonnet-4-20250514", // Default model
-		Messages: []domain.Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
+		Model: binding.Model,
+		Messages: append(append([]domain.Message(nil), history...), domain.Message{
+			Role:    "user",
+			Content: prompt,
+		}),
 		MaxTokens: 1024,
 	}
 
-	respInterface, err := r.llmClient.GenerateCompletion(ctx, req)
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	respInterface, err := r.complete(callCtx, binding, req, earlyStop)
+	metrics.LLMCallDuration.WithLabelValues(binding.Model).Observe(metrics.Since(start))
+	timerFrom(ctx).addLLM(time.Since(start))
+	if err != nil && ctx.Err() != nil {
+		// The request itself timed out or was canceled; not a provider failure
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "llm call aborted")
+		return "", fmt.Errorf("llm call aborted: %w", ctx.Err())
+	}
+	if err != nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		metrics.LLMCallErrors.WithLabelValues(binding.Model).Inc()
+		metrics.LLMTimeouts.WithLabelValues(binding.Model).Inc()
+		r.recordBreaker(tenant, breaker, err)
+		r.usage.Record(tenant, 0, 0, false, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "llm call timed out")
+		return "", fmt.Errorf("%w after %s", ErrLLMTimeout, timeout)
+	}
 	if err != nil {
+		metrics.LLMCallErrors.WithLabelValues(binding.Model).Inc()
+		r.recordBreaker(tenant, breaker, err)
+		r.usage.Record(tenant, 0, 0, false, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "llm completion failed")
 		return "", fmt.Errorf("llm completion failed: %w", err)
 	}
 
 	// Type assert response
 	resp, ok := respInterface.(*domain.LLMResponse)
 	if !ok {
-		return "", fmt.Errorf("unexpected response type from LLM")
+		err := fmt.Errorf("unexpected response type from LLM")
+		metrics.LLMCallErrors.WithLabelValues(binding.Model).Inc()
+		r.recordBreaker(tenant, breaker, err)
+		r.usage.Record(tenant, 0, 0, false, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "unexpected response type")
+		return "", err
+	}
+
+	r.recordBreaker(tenant, breaker, nil)
+
+	// Estimate usage locally for providers that don't report it
+	inputTokens, outputTokens := resp.Usage.InputTokens, resp.Usage.OutputTokens
+	source := "provider"
+	if inputTokens == 0 && outputTokens == 0 {
+		inputTokens, outputTokens = tokens.Estimate(conversationText(history, prompt)), tokens.Estimate(resp.Content)
+		source = "estimated"
+	}
+
+	r.usage.Record(tenant, inputTokens, outputTokens, source == "estimated", nil)
+	if r.quotas != nil && budgetTenant != "" {
+		r.quotas.RecordLLMTokens(budgetTenant, inputTokens+outputTokens)
+	}
+	span.SetAttributes(
+		attribute.Int("llm.input_tokens", inputTokens),
+		attribute.Int("llm.output_tokens", outputTokens),
+		attribute.String("llm.token_source", source),
+	)
+	metrics.LLMTokens.WithLabelValues(metrics.Tenants.Value(tenant), "input", source).Add(float64(inputTokens))
+	metrics.LLMTokens.WithLabelValues(metrics.Tenants.Value(tenant), "output", source).Add(float64(outputTokens))
+	r.log(ctx).Debug("llm usage recorded",
+		zap.String("model", binding.Model),
+		zap.Int("input_tokens", inputTokens),
+		zap.Int("output_tokens", outputTokens),
+		zap.String("token_source", source),
+	)
+
+	if r.cache != nil {
+		r.cache.Set(ctx, cacheKey, resp.Content)
 	}
 
 	return resp.Content, nil
 }
 
+// conversationText joins the history messages and the prompt, for cache keys
+// and token estimates; without history it is the prompt itself
+func conversationText(history []domain.Message, prompt string) string {
+	if len(history) == 0 {
+		return prompt
+	}
+	var b strings.Builder
+	for _, message := range history {
+		b.WriteString(message.Role)
+		b.WriteString(": ")
+		b.WriteString(message.Content)
+		b.WriteString("\n")
+	}
+	b.WriteString("user: ")
+	b.WriteString(prompt)
+	return b.String()
+}
+
 // matchLLMResponse matches the LLM response to a route
 func (r *Router) matchLLMResponse(response string, routes map[string]string) (string, bool) {
 	// Normalize response: trim whitespace and convert to lowercase
@@ -138,7 +425,7 @@ func (r *Router) matchLLMResponse(response string, routes map[string]string) (st
 	for key, target := range routes {
 		if strings.Contains(normalized, strings.ToLower(key)) {
 			r.logger.Debug("matched route by partial match",
-				zap.String("response", response),
+				zap.String("response", r.redactor.String(response)),
 				zap.String("matched_key", key),
 			)
 			return target, true
@@ -0,0 +1,281 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/cache"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// Lookup results, as reported by the lookups_total metric
+const (
+	lookupCached  = "cached"
+	lookupFetched = "fetched"
+	lookupError   = "error"
+)
+
+// Lookup defaults
+const (
+	defaultLookupTimeout   = 2 * time.Second
+	defaultLookupCacheSize = 1000
+	defaultLookupCacheTTL  = time.Minute
+)
+
+// lookupNamePattern restricts lookup names to identifiers usable as
+// lookup.<name> in CEL and templates
+var lookupNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Lookup fetches external data before a config is routed. The result is
+// exposed to CEL conditions and prompt templates as lookup.<name>. URL,
+// headers and RedisKey are Handlebars templates over the same data as
+// prompts, e.g. "https://crm.internal/customers/{{state.inputs.customer_id}}".
+type Lookup struct {
+	Name     string            `json:"name"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	RedisKey string            `json:"redis_key,omitempty"`
+	// Timeout overrides LOOKUP_TIMEOUT for this lookup
+	Timeout Duration `json:"timeout,omitempty"`
+	// TTL caches results for less than LOOKUP_CACHE_TTL
+	TTL Duration `json:"ttl,omitempty"`
+	// Default is the value of the lookup when it fails, null when unset
+	Default interface{} `json:"default,omitempty"`
+	// Required fails the routing request when the lookup fails, instead of
+	// using Default
+	Required bool `json:"required,omitempty"`
+}
+
+// source returns the kind of source of the lookup, for metrics
+func (l *Lookup) source() string {
+	if l.RedisKey != "" {
+		return "redis"
+	}
+	return "http"
+}
+
+// LookupSource fetches the data of lookups
+type LookupSource interface {
+	GetURL(ctx context.Context, url string, headers map[string]string) (interface{}, error)
+	GetKey(ctx context.Context, key string) (interface{}, error)
+}
+
+// LookupConfig bounds and caches lookups
+type LookupConfig struct {
+	// Timeout bounds each lookup that does not set its own
+	Timeout time.Duration
+	// CacheSize and CacheTTL bound the cache of lookup results, keyed on the
+	// rendered URL or key
+	CacheSize int
+	CacheTTL  time.Duration
+}
+
+// lookupEntry is a cached lookup result
+type lookupEntry struct {
+	value     interface{}
+	fetchedAt time.Time
+}
+
+// lookups fetches and caches the lookups of configs
+type lookups struct {
+	source  LookupSource
+	timeout time.Duration
+	cache   *cache.LRU[lookupEntry]
+}
+
+// WithLookups fetches the lookups of node configs from source before they
+// are routed. Configs with lookups fail without it.
+func WithLookups(source LookupSource, config LookupConfig) Option {
+	return func(r *Router) {
+		if config.Timeout <= 0 {
+			config.Timeout = defaultLookupTimeout
+		}
+		if config.CacheSize <= 0 {
+			config.CacheSize = defaultLookupCacheSize
+		}
+		if config.CacheTTL <= 0 {
+			config.CacheTTL = defaultLookupCacheTTL
+		}
+		r.lookups = &lookups{
+			source:  source,
+			timeout: config.Timeout,
+			cache:   cache.NewLRU[lookupEntry](config.CacheSize, config.CacheTTL),
+		}
+	}
+}
+
+// lookupsKey is the context.Context key for the lookup results of a request
+type lookupsKey struct{}
+
+// lookupVars returns the lookup results carried by ctx, for the `lookup`
+// variable; empty when the config has no lookups
+func lookupVars(ctx context.Context) map[string]interface{} {
+	if results, ok := ctx.Value(lookupsKey{}).(map[string]interface{}); ok {
+		return results
+	}
+	return map[string]interface{}{}
+}
+
+// resolveLookups fetches the lookups of config in parallel and returns a
+// context carrying their results. Failed lookups take their default, unless
+// they are required.
+func (r *Router) resolveLookups(ctx context.Context, state *domain.GraphState, config *NodeConfig) (context.Context, error) {
+	if r.lookups == nil {
+		return nil, fmt.Errorf("lookups are not enabled")
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "router.lookups")
+	defer span.End()
+	span.SetAttributes(attribute.Int("lookup.count", len(config.Lookups)))
+
+	data := promptData(ctx, state)
+	results := make(map[string]interface{}, len(config.Lookups))
+	errs := make([]error, len(config.Lookups))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := range config.Lookups {
+		lookup := &config.Lookups[i]
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, err := r.fetchLookup(ctx, lookup, data)
+			if err != nil {
+				r.log(ctx).Warn("lookup failed",
+					zap.String("lookup", lookup.Name),
+					zap.Bool("required", lookup.Required),
+					zap.Error(err),
+				)
+				if lookup.Required {
+					errs[i] = fmt.Errorf("lookup %s: %w", lookup.Name, err)
+					return
+				}
+				value = lookup.Default
+			}
+			mu.Lock()
+			results[lookup.Name] = value
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, lookupsKey{}, results), nil
+}
+
+// fetchLookup renders a lookup and returns its cached or fetched value
+func (r *Router) fetchLookup(ctx context.Context, lookup *Lookup, data map[string]interface{}) (interface{}, error) {
+	source := lookup.source()
+	var key string
+	var headers map[string]string
+	var err error
+	if lookup.RedisKey != "" {
+		key, err = r.templateEngine.Render(lookup.RedisKey, data)
+	} else {
+		key, err = r.templateEngine.Render(lookup.URL, data)
+		if err == nil && len(lookup.Headers) > 0 {
+			headers = make(map[string]string, len(lookup.Headers))
+			for name, value := range lookup.Headers {
+				if headers[name], err = r.templateEngine.Render(value, data); err != nil {
+					break
+				}
+			}
+		}
+	}
+	if err != nil {
+		metrics.Lookups.WithLabelValues(source, lookupError).Inc()
+		return nil, fmt.Errorf("failed to render lookup: %w", err)
+	}
+
+	cacheKey := source + "\x00" + key
+	if entry, ok := r.lookups.cache.Get(ctx, cacheKey); ok &&
+		(lookup.TTL <= 0 || time.Since(entry.fetchedAt) < time.Duration(lookup.TTL)) {
+		metrics.Lookups.WithLabelValues(source, lookupCached).Inc()
+		return entry.value, nil
+	}
+
+	timeout := r.lookups.timeout
+	if lookup.Timeout > 0 {
+		timeout = time.Duration(lookup.Timeout)
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var value interface{}
+	if lookup.RedisKey != "" {
+		value, err = r.lookups.source.GetKey(fetchCtx, key)
+	} else {
+		value, err = r.lookups.source.GetURL(fetchCtx, key, headers)
+	}
+	if err != nil {
+		metrics.Lookups.WithLabelValues(source, lookupError).Inc()
+		if errors.Is(fetchCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return nil, fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+		return nil, err
+	}
+
+	metrics.Lookups.WithLabelValues(source, lookupFetched).Inc()
+	r.lookups.cache.Set(ctx, cacheKey, lookupEntry{value: value, fetchedAt: time.Now()})
+	return value, nil
+}
+
+// lookups checks the lookups of a node config
+func (v *configValidator) lookups(lookups []Lookup) {
+	if len(lookups) > 0 && v.router.lookups == nil {
+		v.add("lookups", "lookups are not enabled on this worker")
+	}
+
+	names := make(map[string]bool, len(lookups))
+	for i, lookup := range lookups {
+		field := fmt.Sprintf("lookups[%d]", i)
+		switch {
+		case !lookupNamePattern.MatchString(lookup.Name):
+			v.add(field+".name", fmt.Sprintf("name %q must be an identifier", lookup.Name))
+		case names[lookup.Name]:
+			v.add(field+".name", fmt.Sprintf("duplicate lookup name %q", lookup.Name))
+		}
+		names[lookup.Name] = true
+
+		switch {
+		case lookup.URL == "" && lookup.RedisKey == "":
+			v.add(field+".url", "url or redis_key is required")
+		case lookup.URL != "" && lookup.RedisKey != "":
+			v.add(field+".redis_key", "url and redis_key are mutually exclusive")
+		case lookup.RedisKey != "" && len(lookup.Headers) > 0:
+			v.add(field+".headers", "headers only apply to url lookups")
+		}
+		for _, tmpl := range append([]string{lookup.URL, lookup.RedisKey}, mapValues(lookup.Headers)...) {
+			if tmpl == "" {
+				continue
+			}
+			if err := v.router.templateEngine.ValidateTemplate(tmpl); err != nil {
+				v.add(field, err.Error())
+			}
+		}
+		if lookup.Timeout < 0 {
+			v.add(field+".timeout", "timeout must not be negative")
+		}
+		if lookup.TTL < 0 {
+			v.add(field+".ttl", "ttl must not be negative")
+		}
+	}
+}
+
+// mapValues returns the values of a map in key order
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, key := range sortedKeys(m) {
+		values = append(values, m[key])
+	}
+	return values
+}
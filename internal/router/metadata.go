@@ -0,0 +1,101 @@
+package router
+
+import (
+	"context"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/eval/cel"
+)
+
+// executionMetadataKey is the context.Context key for the execution metadata
+type executionMetadataKey struct{}
+
+// WithExecutionMetadata returns a context carrying the metadata object of the
+// execution state (retry_count, labels, annotations and any other fields the
+// orchestrator records), exposed to CEL as state.metadata
+func WithExecutionMetadata(ctx context.Context, metadata map[string]interface{}) context.Context {
+	if len(metadata) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, executionMetadataKey{}, metadata)
+}
+
+// ExecutionMetadataFrom returns the execution metadata carried by ctx, if any
+func ExecutionMetadataFrom(ctx context.Context) map[string]interface{} {
+	metadata, _ := ctx.Value(executionMetadataKey{}).(map[string]interface{})
+	return metadata
+}
+
+// metadataVars converts the execution metadata of ctx and the timestamps of
+// state to state.metadata. created_at, retry_count, labels and annotations
+// are always present so expressions never fail on missing keys.
+func metadataVars(ctx context.Context, state *domain.GraphState, prevNode string) map[string]interface{} {
+	vars := map[string]interface{}{
+		"created_at":   "",
+		"started_at":   formatTime(state.StartedAt),
+		"completed_at": formatTime(state.CompletedAt),
+		"retry_count":  int64(0),
+		"labels":       map[string]interface{}{},
+		"annotations":  map[string]interface{}{},
+	}
+	if !state.SubmittedAt.IsZero() {
+		vars["created_at"] = state.SubmittedAt.UTC().Format(time.RFC3339Nano)
+	}
+	for key, value := range ExecutionMetadataFrom(ctx) {
+		if value != nil {
+			vars[key] = value
+		}
+	}
+	vars["prev_node"] = prevNode
+	return vars
+}
+
+// formatTime returns t in RFC 3339, or "" when unset
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// previousNode returns the ID of the node that ran immediately before the
+// routed node: of its predecessors in the graph, the one that completed last,
+// or the node that completed last when the graph has no edges into it. Ties
+// go to the lowest node ID so the choice is stable.
+func previousNode(ctx context.Context, state *domain.GraphState) string {
+	nodeID := NodeIDFrom(ctx)
+	predecessors := make(map[string]bool)
+	if state.Graph != nil && nodeID != "" {
+		for _, edge := range state.Graph.Edges {
+			if edge != nil && edge.To == nodeID {
+				predecessors[edge.From] = true
+			}
+		}
+	}
+
+	var prev string
+	var latest time.Time
+	for id, nodeState := range state.NodeStates {
+		if id == nodeID || nodeState == nil || nodeState.CompletedAt == nil {
+			continue
+		}
+		if len(predecessors) > 0 && !predecessors[id] {
+			continue
+		}
+		completed := *nodeState.CompletedAt
+		if prev == "" || completed.After(latest) || (completed.Equal(latest) && id < prev) {
+			prev, latest = id, completed
+		}
+	}
+	return prev
+}
+
+// prevVar returns the bound output of the previous node, or an empty map
+// when no node has completed
+func prevVar(numbers cel.NumberCoercion, state *domain.GraphState, prevNode string) interface{} {
+	if nodeState := state.NodeStates[prevNode]; nodeState != nil && nodeState.Output != nil {
+		return numbers.Bind(nodeState.Output, "node_states."+prevNode+".output")
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,188 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"go.uber.org/zap"
+)
+
+// OnNoMatch selects what a pipeline does when a stage does not match
+type OnNoMatch string
+
+const (
+	// NoMatchContinue passes the execution to the next stage (default)
+	NoMatchContinue OnNoMatch = "continue"
+	// NoMatchFallback ends the pipeline at the fallback route
+	NoMatchFallback OnNoMatch = "fallback"
+)
+
+// PipelineStage is one routing strategy of a pipeline. Exactly one of Rules,
+// Keyword, Embedding, Classifier and LLM is set.
+type PipelineStage struct {
+	// Name identifies the stage in decisions (index and type when unset)
+	Name       string                `json:"name,omitempty"`
+	Rules      []Rule                `json:"rules,omitempty"`
+	Groups     map[string]GroupMatch `json:"groups,omitempty"`
+	Keyword    *KeywordConfig        `json:"keyword,omitempty"`
+	Embedding  *EmbeddingConfig      `json:"embedding,omitempty"`
+	Classifier *ClassifierConfig     `json:"classifier,omitempty"`
+	LLM        *LLMConfig            `json:"llm,omitempty"`
+	// OnNoMatch is "continue" (default) or "fallback"
+	OnNoMatch OnNoMatch `json:"on_no_match,omitempty"`
+}
+
+// kind returns the strategy of the stage, or "" when it does not set exactly
+// one
+func (s *PipelineStage) kind() RoutingMode {
+	var kinds []RoutingMode
+	if len(s.Rules) > 0 {
+		kinds = append(kinds, ModeDeterministic)
+	}
+	if s.Keyword != nil {
+		kinds = append(kinds, ModeKeyword)
+	}
+	if s.Embedding != nil {
+		kinds = append(kinds, ModeEmbedding)
+	}
+	if s.Classifier != nil {
+		kinds = append(kinds, ModeClassifier)
+	}
+	if s.LLM != nil {
+		kinds = append(kinds, ModeLLM)
+	}
+	if len(kinds) != 1 {
+		return ""
+	}
+	return kinds[0]
+}
+
+// label names the stage in decisions and logs
+func (s *PipelineStage) label(i int) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return fmt.Sprintf("%d:%s", i, stageType(s.kind()))
+}
+
+// stageType is the type of a stage in metrics and stage labels
+func stageType(kind RoutingMode) string {
+	if kind == ModeDeterministic {
+		return "rules"
+	}
+	return string(kind)
+}
+
+// stageConfig returns the single-strategy node config a stage is routed with
+func (s *PipelineStage) stageConfig(config *NodeConfig) *NodeConfig {
+	return &NodeConfig{
+		Mode:                s.kind(),
+		Rules:               s.Rules,
+		Groups:              s.Groups,
+		ReorderRules:        config.ReorderRules,
+		BypassDecisionCache: config.BypassDecisionCache,
+		Keyword:             s.Keyword,
+		Embedding:           s.Embedding,
+		Classifier:          s.Classifier,
+		LLMConfig:           s.LLM,
+		Fallback:            config.Fallback,
+		NumberTypes:         config.NumberTypes,
+		Variables:           config.Variables,
+	}
+}
+
+// routePipeline routes with each stage in order until one matches. A stage
+// that takes its fallback route did not match: the pipeline continues with
+// the next stage, or ends at the fallback route when the stage says so.
+func (r *Router) routePipeline(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error) {
+	if err := r.validateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	var reasons []string
+	for i := range config.Pipeline {
+		stage := &config.Pipeline[i]
+		label := stage.label(i)
+		stageConfig := stage.stageConfig(config)
+		stageConfig.rulesField = fmt.Sprintf("pipeline[%d].rules", i)
+		kind := stageType(stageConfig.Mode)
+
+		result, err := r.routeResolved(ctx, state, stageConfig)
+		if err != nil {
+			r.recordStage(ctx, label, kind, hitError)
+			return nil, fmt.Errorf("pipeline stage %s: %w", label, err)
+		}
+
+		if result.PathTaken != "fallback" {
+			r.recordStage(ctx, label, kind, hitMatched)
+			r.skipStages(ctx, config.Pipeline[i+1:], i+1)
+			r.log(ctx).Debug("pipeline stage matched",
+				zap.String("stage", label),
+				zap.String("target", result.TargetNode),
+			)
+			result.Mode = string(ModePipeline)
+			result.PipelineStage = label
+			result.Reasoning = fmt.Sprintf("stage %s: %s", label, result.Reasoning)
+			return result, nil
+		}
+
+		r.recordStage(ctx, label, kind, hitNoMatch)
+		reasons = append(reasons, fmt.Sprintf("stage %s: %s", label, result.Reasoning))
+		if stage.OnNoMatch == NoMatchFallback {
+			r.skipStages(ctx, config.Pipeline[i+1:], i+1)
+			break
+		}
+	}
+
+	r.log(ctx).Info("no pipeline stage matched, using fallback",
+		zap.String("fallback", config.Fallback),
+	)
+	return &RoutingResult{
+		TargetNode: config.Fallback,
+		Reasoning:  "no pipeline stage matched (" + strings.Join(reasons, "; ") + ")",
+		Mode:       string(ModePipeline),
+		PathTaken:  "fallback",
+	}, nil
+}
+
+// skipStages counts the stages from index first on as skipped
+func (r *Router) skipStages(ctx context.Context, stages []PipelineStage, first int) {
+	for i := range stages {
+		r.recordStage(ctx, stages[i].label(first+i), stageType(stages[i].kind()), hitSkipped)
+	}
+}
+
+// pipeline checks the stages of a pipeline
+func (v *configValidator) pipeline(stages []PipelineStage) {
+	if len(stages) == 0 {
+		v.add("pipeline", "pipeline mode requires stages")
+	}
+	for i := range stages {
+		stage := &stages[i]
+		field := fmt.Sprintf("pipeline[%d]", i)
+		switch stage.kind() {
+		case ModeDeterministic:
+			v.rules(field+".rules", stage.Rules)
+			if err := validateGroups(stage.Rules, stage.Groups); err != nil {
+				v.add(field+".groups", err.Error())
+			}
+		case ModeKeyword:
+			v.keyword(field+".keyword", stage.Keyword)
+		case ModeEmbedding:
+			v.embedding(field+".embedding", stage.Embedding)
+		case ModeClassifier:
+			v.classifier(field+".classifier", stage.Classifier)
+		case ModeLLM:
+			v.llmConfig(field+".llm", stage.LLM)
+		default:
+			v.add(field, "exactly one of rules, keyword, embedding, classifier and llm is required")
+		}
+		switch stage.OnNoMatch {
+		case "", NoMatchContinue, NoMatchFallback:
+		default:
+			v.add(field+".on_no_match", fmt.Sprintf("on_no_match '%s' is not supported (use continue or fallback)", stage.OnNoMatch))
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/cache"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+)
+
+// ErrTenantQuota is returned for routing requests rejected by their tenant's
+// concurrency or rate limit
+var ErrTenantQuota = errors.New("tenant quota exceeded")
+
+// ErrLLMBudgetExceeded is returned for LLM calls of a tenant whose LLM token
+// budget is spent for the current window
+var ErrLLMBudgetExceeded = errors.New("tenant llm token budget exceeded")
+
+// Tenant quota limits, as reported by the tenant_quota_rejections_total metric
+const (
+	quotaLimitRate        = "rps"
+	quotaLimitConcurrency = "concurrency"
+	quotaLimitLLMBudget   = "llm_budget"
+)
+
+const (
+	// tenantQuotaCacheSize is the number of tenants whose quota state is kept
+	tenantQuotaCacheSize = 10000
+	// tenantQuotaIdleTTL is how long the quota state of an idle tenant is
+	// kept, at least a budget window so forgetting a tenant never resets a
+	// budget before its window ends
+	tenantQuotaIdleTTL = time.Hour
+)
+
+// TenantQuota bounds the routing requests and LLM tokens of one tenant. Zero
+// values disable the corresponding limit.
+type TenantQuota struct {
+	// MaxConcurrent bounds the tenant's routing requests in flight
+	MaxConcurrent int
+	// MaxRPS bounds the tenant's routing requests per second, with bursts of
+	// up to Burst requests
+	MaxRPS float64
+	Burst  int
+	// LLMTokenBudget bounds the LLM tokens (input plus output) the tenant
+	// may use per BudgetWindow
+	LLMTokenBudget int64
+	BudgetWindow   time.Duration
+}
+
+// TenantQuotaConfig configures per-tenant quotas
+type TenantQuotaConfig struct {
+	// Default applies to every tenant without an entry in Tenants, each
+	// tenant counted separately
+	Default TenantQuota
+	Tenants map[string]TenantQuota
+	// MaxWait is how long a request may queue for its tenant's limits before
+	// it is rejected with ErrTenantQuota
+	MaxWait time.Duration
+}
+
+// tenantQuotas enforces TenantQuotaConfig, tracking each tenant on first use
+// and forgetting the least recently used tenants once tenantQuotaCacheSize
+// are tracked or a tenant is idle for the TTL
+type tenantQuotas struct {
+	config TenantQuotaConfig
+
+	mu      sync.Mutex
+	tenants *cache.LRU[*tenantLimits]
+}
+
+// tenantLimits is the quota state of one tenant
+type tenantLimits struct {
+	quota  TenantQuota
+	bucket *tokenBucket
+	slots  chan struct{}
+
+	// used counts LLM tokens spent since windowStart
+	used        int64
+	windowStart time.Time
+}
+
+// newTenantQuotas creates the quota state for config
+func newTenantQuotas(config TenantQuotaConfig) *tenantQuotas {
+	ttl := max(tenantQuotaIdleTTL, config.Default.BudgetWindow)
+	for _, quota := range config.Tenants {
+		ttl = max(ttl, quota.BudgetWindow)
+	}
+	return &tenantQuotas{
+		config:  config,
+		tenants: cache.NewLRU[*tenantLimits](tenantQuotaCacheSize, ttl),
+	}
+}
+
+// limitsFor returns the quota state of a tenant, creating it on first use.
+// Every use restarts the tenant's TTL.
+func (q *tenantQuotas) limitsFor(tenant string) *tenantLimits {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ctx := context.Background()
+	limits, ok := q.tenants.Get(ctx, tenant)
+	if !ok {
+		quota, ok := q.config.Tenants[tenant]
+		if !ok {
+			quota = q.config.Default
+		}
+		limits = &tenantLimits{quota: quota, windowStart: time.Now()}
+		if quota.MaxRPS > 0 {
+			limits.bucket = newTokenBucket(quota.MaxRPS, quota.Burst)
+		}
+		if quota.MaxConcurrent > 0 {
+			limits.slots = make(chan struct{}, quota.MaxConcurrent)
+		}
+	}
+	q.tenants.Set(ctx, tenant, limits)
+	return limits
+}
+
+// Acquire waits up to MaxWait, or the context deadline if sooner, for the
+// tenant's rate and concurrency limits to admit a request. It returns a
+// release func that must be called once the request completes, or
+// ErrTenantQuota.
+func (q *tenantQuotas) Acquire(ctx context.Context, tenant string) (func(), error) {
+	limits := q.limitsFor(tenant)
+	start := time.Now()
+	deadline := start.Add(q.config.MaxWait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	if limits.bucket != nil {
+		wait, ok := limits.bucket.reserve(start, deadline.Sub(start))
+		if !ok {
+			metrics.TenantQuotaRejections.WithLabelValues(metrics.Tenants.Value(tenant), quotaLimitRate).Inc()
+			return nil, ErrTenantQuota
+		}
+		if wait > 0 && !sleepUntil(ctx, wait) {
+			limits.bucket.cancel()
+			return nil, ctx.Err()
+		}
+	}
+
+	if limits.slots != nil && !acquireSlot(ctx, limits.slots, deadline) {
+		if limits.bucket != nil {
+			limits.bucket.cancel()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		metrics.TenantQuotaRejections.WithLabelValues(metrics.Tenants.Value(tenant), quotaLimitConcurrency).Inc()
+		return nil, ErrTenantQuota
+	}
+
+	metrics.TenantInFlight.WithLabelValues(metrics.Tenants.Value(tenant)).Inc()
+	return func() {
+		if limits.slots != nil {
+			<-limits.slots
+		}
+		metrics.TenantInFlight.WithLabelValues(metrics.Tenants.Value(tenant)).Dec()
+	}, nil
+}
+
+// AllowLLM reports ErrLLMBudgetExceeded when the tenant spent its LLM token
+// budget for the current window
+func (q *tenantQuotas) AllowLLM(tenant string) error {
+	limits := q.limitsFor(tenant)
+	if limits.quota.LLMTokenBudget <= 0 {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	limits.roll(time.Now())
+	if limits.used >= limits.quota.LLMTokenBudget {
+		metrics.TenantQuotaRejections.WithLabelValues(metrics.Tenants.Value(tenant), quotaLimitLLMBudget).Inc()
+		return ErrLLMBudgetExceeded
+	}
+	return nil
+}
+
+// RecordLLMTokens charges LLM tokens to the tenant's budget
+func (q *tenantQuotas) RecordLLMTokens(tenant string, tokens int) {
+	limits := q.limitsFor(tenant)
+	if limits.quota.LLMTokenBudget <= 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	limits.roll(time.Now())
+	limits.used += int64(tokens)
+	metrics.TenantLLMBudgetRemaining.WithLabelValues(metrics.Tenants.Value(tenant)).Set(float64(max(0, limits.quota.LLMTokenBudget-limits.used)))
+}
+
+// roll starts a new budget window once the current one has elapsed
+func (l *tenantLimits) roll(now time.Time) {
+	if l.quota.BudgetWindow > 0 && now.Sub(l.windowStart) >= l.quota.BudgetWindow {
+		l.used = 0
+		l.windowStart = now
+	}
+}
@@ -0,0 +1,25 @@
+package router
+
+import (
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/redact"
+)
+
+// WithRedaction masks customer data in the prompts and LLM responses written
+// to debug logs and traces. With prompts, state inputs are also redacted
+// before prompt templates are rendered, so the LLM never receives them.
+func WithRedaction(redactor *redact.Redactor, prompts bool) Option {
+	return func(r *Router) {
+		r.redactor = redactor
+		r.redactPrompts = prompts && redactor != nil
+	}
+}
+
+// redactState returns a copy of state whose inputs are redacted. Field paths
+// are relative to the state, e.g. "inputs.customer.email".
+func (r *Router) redactState(state *domain.GraphState) *domain.GraphState {
+	redacted := *state
+	data := r.redactor.Map(map[string]interface{}{"inputs": state.Inputs})
+	redacted.Inputs, _ = data["inputs"].(map[string]interface{})
+	return &redacted
+}
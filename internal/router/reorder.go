@@ -0,0 +1,243 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// defaultReorderSamples is the number of decisions between optimizations
+// when WithRuleReordering sets none
+const defaultReorderSamples = 1000
+
+// WithRuleReordering reorders the rules of configs that set reorder_rules by
+// how often each decides the target, re-optimizing every samples decisions
+// (1000 when samples <= 0). Only rules of equal priority change places, so
+// configs opting in must not depend on the array order of such rules.
+func WithRuleReordering(enabled bool, samples int) Option {
+	return func(r *Router) {
+		if samples <= 0 {
+			samples = defaultReorderSamples
+		}
+		r.reorder.enabled = enabled
+		r.reorder.samples = uint64(samples)
+	}
+}
+
+// RuleOrder is the evaluation order the optimizer chose for the rules of a
+// node
+type RuleOrder struct {
+	NodeID string `json:"node_id"`
+	// Rules is the path of the rules, e.g. "rules" or "pipeline[1].rules"
+	Rules string `json:"rules"`
+	// Order lists the rule indexes in evaluation order
+	Order []int `json:"order"`
+	// Decisions counts the decisions observed since the order was reset
+	Decisions   uint64    `json:"decisions"`
+	OptimizedAt time.Time `json:"optimized_at"`
+}
+
+// ruleReorderer tracks the decisions of each rule list and the evaluation
+// order chosen for it
+type ruleReorderer struct {
+	enabled bool
+	samples uint64
+
+	mu     sync.Mutex
+	orders map[string]*ruleOrder
+}
+
+// ruleOrder is the optimizer state of one rule list of a node. Decisions are
+// counted by the first rule index of the deciding unit.
+type ruleOrder struct {
+	nodeID    string
+	field     string
+	decisions map[int]uint64
+	pending   uint64
+	total     uint64
+	// units are the unit keys in evaluation order, nil until optimized
+	units       []int
+	optimizedAt time.Time
+}
+
+// orderKey identifies a rule list of a node. Rules are fingerprinted so a new
+// config version starts from its declared order.
+func orderKey(nodeID, field string, rules []Rule, groups map[string]GroupMatch) string {
+	h := fnv.New64a()
+	for _, rule := range rules {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00%d\x00%s\x00", rule.Condition, rule.Target, rule.TargetExpr, rule.Priority, rule.Group)
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s=%s\x00", name, groups[name])
+	}
+	return fmt.Sprintf("%s|%s|%x", nodeID, field, h.Sum64())
+}
+
+// planFor returns the evaluation plan of the rules at field, in the order
+// chosen by the optimizer when the config opts in
+func (r *Router) planFor(ctx context.Context, config *NodeConfig, field string) []ruleUnit {
+	units := planRules(config.Rules, config.Groups)
+	if !r.reorder.enabled || !config.ReorderRules {
+		return units
+	}
+
+	key := orderKey(NodeIDFrom(ctx), field, config.Rules, config.Groups)
+	r.reorder.mu.Lock()
+	order := r.reorder.orders[key]
+	var chosen []int
+	if order != nil {
+		chosen = order.units
+	}
+	r.reorder.mu.Unlock()
+	if chosen == nil {
+		return units
+	}
+
+	byKey := make(map[int]ruleUnit, len(units))
+	for _, unit := range units {
+		byKey[unit.indexes[0]] = unit
+	}
+	reordered := make([]ruleUnit, 0, len(units))
+	for _, k := range chosen {
+		reordered = append(reordered, byKey[k])
+	}
+	return reordered
+}
+
+// recordDecision counts the unit that decided the target of the rules at
+// field (nil when no rule matched) and re-optimizes their order every
+// r.reorder.samples decisions
+func (r *Router) recordDecision(ctx context.Context, config *NodeConfig, field string, unit *ruleUnit) {
+	if trace := hitTraceFrom(ctx); trace != nil {
+		trace.decided = true
+		if unit != nil {
+			traced := *unit
+			trace.unit = &traced
+		}
+	}
+	if !r.reorder.enabled || !config.ReorderRules {
+		return
+	}
+
+	nodeID := NodeIDFrom(ctx)
+	key := orderKey(nodeID, field, config.Rules, config.Groups)
+
+	r.reorder.mu.Lock()
+	defer r.reorder.mu.Unlock()
+
+	if r.reorder.orders == nil {
+		r.reorder.orders = make(map[string]*ruleOrder)
+	}
+	order := r.reorder.orders[key]
+	if order == nil {
+		order = &ruleOrder{nodeID: nodeID, field: field, decisions: make(map[int]uint64)}
+		r.reorder.orders[key] = order
+	}
+	if unit != nil {
+		order.decisions[unit.indexes[0]]++
+	}
+	order.pending++
+	order.total++
+	if order.pending < r.reorder.samples {
+		return
+	}
+	order.pending = 0
+
+	units := planRules(config.Rules, config.Groups)
+	sort.SliceStable(units, func(a, b int) bool {
+		pa, pb := config.Rules[units[a].indexes[0]].Priority, config.Rules[units[b].indexes[0]].Priority
+		if pa != pb {
+			return pa > pb
+		}
+		return order.decisions[units[a].indexes[0]] > order.decisions[units[b].indexes[0]]
+	})
+	chosen := make([]int, len(units))
+	for i, unit := range units {
+		chosen[i] = unit.indexes[0]
+	}
+	// Halve the counts so the order follows traffic that shifts over time
+	for k := range order.decisions {
+		order.decisions[k] /= 2
+	}
+
+	previous := order.units
+	if previous == nil {
+		for _, unit := range planRules(config.Rules, config.Groups) {
+			previous = append(previous, unit.indexes[0])
+		}
+	}
+	if slices.Equal(previous, chosen) {
+		return
+	}
+	order.units = chosen
+	order.optimizedAt = time.Now()
+	metrics.RuleReorders.WithLabelValues(metrics.Nodes.Value(nodeID)).Inc()
+	r.log(ctx).Info("rule order optimized",
+		zap.String("node_id", nodeID),
+		zap.String("rules", field),
+		zap.Ints("from", previous),
+		zap.Ints("to", chosen),
+		zap.Uint64("decisions", order.total),
+	)
+}
+
+// RuleOrders returns the rule orders chosen by the optimizer, by node and
+// rules path
+func (r *Router) RuleOrders() []RuleOrder {
+	r.reorder.mu.Lock()
+	defer r.reorder.mu.Unlock()
+
+	orders := make([]RuleOrder, 0, len(r.reorder.orders))
+	for _, order := range r.reorder.orders {
+		if order.units == nil {
+			continue
+		}
+		orders = append(orders, RuleOrder{
+			NodeID:      order.nodeID,
+			Rules:       order.field,
+			Order:       append([]int(nil), order.units...),
+			Decisions:   order.total,
+			OptimizedAt: order.optimizedAt,
+		})
+	}
+	sort.Slice(orders, func(a, b int) bool {
+		if orders[a].NodeID != orders[b].NodeID {
+			return orders[a].NodeID < orders[b].NodeID
+		}
+		return orders[a].Rules < orders[b].Rules
+	})
+	return orders
+}
+
+// ResetRuleOrder drops the observed decisions and chosen orders of a node
+// (every node when nodeID is ""), restoring the declared order. It returns the
+// number of rule lists reset.
+func (r *Router) ResetRuleOrder(nodeID string) int {
+	r.reorder.mu.Lock()
+	defer r.reorder.mu.Unlock()
+
+	reset := 0
+	for key, order := range r.reorder.orders {
+		if nodeID == "" || order.nodeID == nodeID {
+			delete(r.reorder.orders, key)
+			reset++
+		}
+	}
+	r.logger.Info("rule order reset",
+		zap.String("node_id", nodeID),
+		zap.Int("rule_lists", reset),
+	)
+	return reset
+}
@@ -2,12 +2,25 @@ package router
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/aescanero/dago-libs/pkg/domain"
 	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/cache"
+	"github.com/aescanero/dago-node-router/internal/classifier"
 	"github.com/aescanero/dago-node-router/internal/eval/cel"
 	"github.com/aescanero/dago-node-router/internal/eval/template"
+	"github.com/aescanero/dago-node-router/internal/logging"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/policies"
+	"github.com/aescanero/dago-node-router/internal/prompts"
+	"github.com/aescanero/dago-node-router/internal/redact"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.uber.org/zap"
 )
 
@@ -23,29 +36,201 @@ const (
 
 	// ModeHybrid uses CEL rules with LLM fallback
 	ModeHybrid RoutingMode = "hybrid"
+
+	// ModeClassifier uses an offline-trained text classifier
+	ModeClassifier RoutingMode = "classifier"
+
+	// ModeKeyword uses weighted keywords and regular expressions
+	ModeKeyword RoutingMode = "keyword"
+
+	// ModeEmbedding uses the similarity of embeddings to route examples
+	ModeEmbedding RoutingMode = "embedding"
+
+	// ModePipeline chains strategies, each stage handling what the previous
+	// stages did not match
+	ModePipeline RoutingMode = "pipeline"
 )
 
 // NodeConfig represents the routing configuration for a node
 type NodeConfig struct {
-	Mode       RoutingMode            `json:"mode"`
-	Rules      []Rule                 `json:"rules,omitempty"`
-	FastRules  []Rule                 `json:"fast_rules,omitempty"`
-	LLMConfig  *LLMConfig             `json:"llm_config,omitempty"`
+	Mode        RoutingMode           `json:"mode"`
+	Rules       []Rule                `json:"rules,omitempty"`
+	FastRules   []Rule                `json:"fast_rules,omitempty"`
+	LLMConfig   *LLMConfig            `json:"llm_config,omitempty"`
 	LLMFallback *LLMConfig            `json:"llm_fallback,omitempty"`
-	Fallback   string                 `json:"fallback"`
-	Config     map[string]interface{} `json:"config,omitempty"`
+	Classifier  *ClassifierConfig     `json:"classifier,omitempty"`
+	Keyword     *KeywordConfig        `json:"keyword,omitempty"`
+	Embedding   *EmbeddingConfig      `json:"embedding,omitempty"`
+	Pipeline    []PipelineStage       `json:"pipeline,omitempty"`
+	Groups      map[string]GroupMatch `json:"groups,omitempty"`
+	Fallback    string                `json:"fallback"`
+	// RulesRef and FastRulesRef name a rule set of the rule set registry
+	// (name@version) used instead of inline rules or fast_rules
+	RulesRef     string `json:"rules_ref,omitempty"`
+	FastRulesRef string `json:"fast_rules_ref,omitempty"`
+	// Targets optionally declares the nodes this router may route to; when
+	// set, every rule, route and fallback target must be one of them
+	Targets []string `json:"targets,omitempty"`
+	// NumberTypes declares the CEL type of numeric state fields by dotted
+	// path under state (e.g. "inputs.count": "int")
+	NumberTypes map[string]cel.NumberType `json:"number_types,omitempty"`
+	// Variables declares typed CEL variables projected from state paths
+	// (e.g. "score": "double" reads inputs.score), so rules using them are
+	// type-checked when the config is validated
+	Variables map[string]Variable `json:"variables,omitempty"`
+	// StatePaths optionally lists the dotted state paths the rules and
+	// prompts read (e.g. "inputs.priority"); when set, only those paths are
+	// loaded from the state store
+	StatePaths []string `json:"state_paths,omitempty"`
+	// Shadow is evaluated alongside this config for comparison only; its
+	// decisions are logged and metered but never published
+	Shadow *NodeConfig `json:"shadow,omitempty"`
+	// Experiment routes a share of executions with a variant config and tags
+	// decisions with the arm they were routed by
+	Experiment *Experiment `json:"experiment,omitempty"`
+	// Lookups fetch external data exposed to conditions and prompts as
+	// lookup.<name> before the config is routed
+	Lookups []Lookup `json:"lookups,omitempty"`
+	// Schedule routes to a fixed target within (or outside of) weekly time
+	// windows, before rules and LLM calls
+	Schedule []ScheduleWindow `json:"schedule,omitempty"`
+	// Sticky keeps routing an execution to the target chosen for it
+	Sticky *StickyConfig `json:"sticky,omitempty"`
+	// ReorderRules lets the worker reorder rules of equal priority by how
+	// often each decides the target (see WithRuleReordering)
+	ReorderRules bool `json:"reorder_rules,omitempty"`
+	// BypassDecisionCache always evaluates the rules, even when the worker
+	// caches decisions (see WithDecisionCache)
+	BypassDecisionCache bool `json:"bypass_decision_cache,omitempty"`
+	// RecordDecisionInState writes the decision into the execution state
+	// under node_states[node_id].output before it is published, so later
+	// nodes can condition on it
+	RecordDecisionInState bool                   `json:"record_decision_in_state,omitempty"`
+	Config                map[string]interface{} `json:"config,omitempty"`
+
+	// ruleSetRefs are the name@version of the rule sets resolved into the
+	// config
+	ruleSetRefs []string
+	// rulesField is the path of Rules in hit counters ("rules" when unset)
+	rulesField string
+}
+
+// RequiredStatePaths returns the state paths to load for this config, its
+// shadow and its experiment variant, or nil when any of them needs the full
+// state
+func (c *NodeConfig) RequiredStatePaths() []string {
+	configs := []*NodeConfig{c}
+	if c.Shadow != nil {
+		configs = append(configs, c.Shadow)
+	}
+	if c.Experiment != nil && c.Experiment.Variant != nil {
+		configs = append(configs, c.Experiment.Variant)
+	}
+
+	var paths []string
+	for _, config := range configs {
+		if len(config.StatePaths) == 0 {
+			return nil
+		}
+		paths = append(paths, config.StatePaths...)
+		paths = append(paths, config.variablePaths()...)
+	}
+	if c.Sticky != nil {
+		paths = append(paths, c.Sticky.Watch...)
+	}
+	return paths
 }
 
 // Rule represents a CEL-based routing rule
 type Rule struct {
 	Condition string `json:"condition"`
 	Target    string `json:"target"`
+	// TargetExpr computes the target from state with a CEL expression
+	// returning a string (e.g. "handler_" + state.inputs.region), instead of
+	// a fixed Target
+	TargetExpr string `json:"target_expr,omitempty"`
+	// Priority orders rule evaluation; higher values are evaluated first
+	Priority int `json:"priority,omitempty"`
+	// Group evaluates the rule together with other rules of the same group
+	Group string `json:"group,omitempty"`
 }
 
 // LLMConfig represents LLM routing configuration
 type LLMConfig struct {
 	PromptTemplate string            `json:"prompt_template"`
 	Routes         map[string]string `json:"routes"`
+	// TemplateRef names a template library template (name@version) used
+	// instead of PromptTemplate
+	TemplateRef string `json:"template_ref,omitempty"`
+	// TemplateEngine selects the syntax of PromptTemplate and of category
+	// prompts: Handlebars (default), Go text/template or Jinja
+	TemplateEngine TemplateEngineType `json:"template_engine,omitempty"`
+	// AutoHierarchy splits large route sets into a coarse category stage and a
+	// sub-route stage, grouping route keys by the text before Separator
+	AutoHierarchy bool   `json:"auto_hierarchy,omitempty"`
+	Separator     string `json:"separator,omitempty"`
+	// Categories enables explicit two-stage classification: the prompt
+	// selects a category, whose own prompt and routes select the target
+	Categories map[string]*LLMCategory `json:"categories,omitempty"`
+	// StructuredOutput requests a JSON answer with route, confidence and
+	// reasoning; answers below MinConfidence take the fallback route
+	StructuredOutput bool    `json:"structured_output,omitempty"`
+	MinConfidence    float64 `json:"min_confidence,omitempty"`
+	// AbstainTarget receives answers below MinConfidence instead of the
+	// fallback route, e.g. a human review node
+	AbstainTarget string `json:"abstain_target,omitempty"`
+	// AnswerType selects how answers map to targets: route labels (default),
+	// yes/no answers through the "yes" and "no" routes, or numbers through Ranges
+	AnswerType AnswerType     `json:"answer_type,omitempty"`
+	Ranges     []NumericRange `json:"ranges,omitempty"`
+	// Timeout overrides LLM_TIMEOUT for each LLM call of this config
+	// (e.g. "5s")
+	Timeout Duration `json:"timeout,omitempty"`
+	// Guard overrides the router's prompt injection guard
+	Guard *PromptGuard `json:"guard,omitempty"`
+	// History exposes recent messages of the execution to the prompt
+	// templates as history
+	History *HistoryConfig `json:"history,omitempty"`
+	// Examples are labeled few-shot examples added to the prompt, or sent
+	// as prior messages with ExamplesFormat "messages"
+	Examples       []FewShotExample `json:"examples,omitempty"`
+	ExamplesFormat ExamplesFormat   `json:"examples_format,omitempty"`
+	// MaxPromptTokens overrides LLM_MAX_PROMPT_TOKENS, the estimated token
+	// budget of each prompt including examples and history
+	MaxPromptTokens int `json:"max_prompt_tokens,omitempty"`
+	// TrimOrder lists the sections trimmed, in order, from prompts over
+	// budget: "history", or a state path under inputs whose string values
+	// are cut short, longest first (default: history, then inputs)
+	TrimOrder []string `json:"trim_order,omitempty"`
+}
+
+// Duration is a time.Duration read from a JSON string such as "5s"
+type Duration time.Duration
+
+// UnmarshalJSON parses a Go duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON formats the duration as a Go duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LLMCategory represents the second classification stage for one category
+type LLMCategory struct {
+	PromptTemplate string            `json:"prompt_template,omitempty"`
+	TemplateRef    string            `json:"template_ref,omitempty"`
+	Routes         map[string]string `json:"routes"`
 }
 
 // RoutingResult represents the result of a routing decision
@@ -54,65 +239,391 @@ type RoutingResult struct {
 	Reasoning  string `json:"reasoning"`
 	Mode       string `json:"mode"`
 	PathTaken  string `json:"path_taken"` // "fast", "slow", "fallback"
+	// PromptHash is the SHA-256 of the rendered prompt for LLM decisions
+	PromptHash string `json:"prompt_hash,omitempty"`
+	// Confidence is reported by the LLM in structured output mode
+	Confidence float64 `json:"confidence,omitempty"`
+	// Stages records each step of a hierarchical LLM classification
+	Stages []StageResult `json:"stages,omitempty"`
+	// RuleIndex and Condition identify the matched rule for rule decisions
+	RuleIndex *int   `json:"rule_index,omitempty"`
+	Condition string `json:"condition,omitempty"`
+	// LLMResponse is the raw LLM answer for LLM decisions
+	LLMResponse string `json:"llm_response,omitempty"`
+	// Experiment and Variant identify the experiment arm (control or
+	// variant) the decision was made by
+	Experiment string `json:"experiment,omitempty"`
+	Variant    string `json:"variant,omitempty"`
+	// Tenant is the tenant the request was routed for, when known
+	Tenant string `json:"tenant,omitempty"`
+	// PipelineStage names the pipeline stage that made the decision
+	PipelineStage string `json:"pipeline_stage,omitempty"`
+	// RuleSets are the name@version of the rule sets the config referenced
+	RuleSets []string `json:"rule_sets,omitempty"`
+	// Timings breaks down where the routing time went
+	Timings *Timings `json:"timings,omitempty"`
+
+	// hits are the rule results of the evaluation, kept with decisions in
+	// the decision cache
+	hits *hitTrace
 }
 
+// defaultLLMModel is used when no model is configured
+const defaultLLMModel = "claude-sonnet-4-20250514"
+
+// defaultLLMTimeout bounds each LLM call when no timeout is configured
+const defaultLLMTimeout = 30 * time.Second
+
+// defaultMaxLLMRoutes is the route count above which single-stage LLM
+// classification quality is known to collapse
+const defaultMaxLLMRoutes = 15
+
+// TemplateEngineType is the syntax prompt templates are written in
+type TemplateEngineType string
+
+const (
+	// TemplateHandlebars templates use Handlebars syntax (default)
+	TemplateHandlebars TemplateEngineType = "handlebars"
+	// TemplateGo templates use Go text/template syntax
+	TemplateGo TemplateEngineType = "go"
+	// TemplateJinja templates use a Jinja-compatible syntax
+	TemplateJinja TemplateEngineType = "jinja"
+)
+
 // Router handles routing decisions
 type Router struct {
-	celEvaluator     *cel.Evaluator
-	templateEngine   *template.Engine
-	llmClient        ports.LLMClient
-	logger           *zap.Logger
+	celEvaluator   *cel.Evaluator
+	celOptions     []cel.Option
+	flags          cel.FlagFunc
+	templateEngine *template.Engine
+	goTemplates    *template.GoEngine
+	jinjaTemplates *template.JinjaEngine
+	sandboxed      bool
+	templates      *prompts.Library
+	ruleSets       *policies.Registry
+	numberMode     cel.NumberMode
+	llmClient      ports.LLMClient
+	llmModel       string
+	llmProvider    string
+	llmBaseURL     string
+	llmTimeout     time.Duration
+	maxLLMRoutes   int
+	tenantField    string
+	tenantLLMs     map[string]*LLMBinding
+	cache          cache.Cache
+	breakerConfig  *BreakerConfig
+	breakers       map[string]*CircuitBreaker
+	breakersMu     sync.Mutex
+	limiter        *Limiter
+	quotas         *tenantQuotas
+	usage          *UsageTracker
+	lookups        *lookups
+	redactor       *redact.Redactor
+	redactPrompts  bool
+	promptGuard    *PromptGuard
+	historySource  HistorySource
+	classifiers    map[string]*classifier.Model
+	embeddings     *embeddings
+	// keywordPatterns caches compiled keyword mode expressions by pattern
+	keywordPatterns sync.Map
+	hits            hitCounters
+	reorder         ruleReorderer
+	decisions       *cache.LRU[RoutingResult]
+	// strategies route each mode; customStrategies are added by options
+	strategies       *StrategyRegistry
+	customStrategies []registeredStrategy
+	// maxPromptTokens is the default token budget of prompts (0 disables)
+	maxPromptTokens int
+	streaming       bool
+	// shadowSlots bounds in-flight shadow evaluations
+	shadowSlots   chan struct{}
+	shadowTimeout time.Duration
+	logger        *zap.Logger
+}
+
+// Option configures optional router behavior
+type Option func(*Router)
+
+// WithLLMModel sets the model used with the default LLM client
+func WithLLMModel(model string) Option {
+	return func(r *Router) {
+		if model != "" {
+			r.llmModel = model
+		}
+	}
+}
+
+// WithLLMEndpoint names the provider and base URL of the default LLM client,
+// which scope its cached responses
+func WithLLMEndpoint(provider, baseURL string) Option {
+	return func(r *Router) {
+		r.llmProvider = provider
+		r.llmBaseURL = baseURL
+	}
+}
+
+// WithLLMTimeout bounds each LLM call; an LLM config timeout overrides it
+func WithLLMTimeout(timeout time.Duration) Option {
+	return func(r *Router) {
+		if timeout > 0 {
+			r.llmTimeout = timeout
+		}
+	}
+}
+
+// WithMaxLLMRoutes sets the route count above which LLM configs are warned
+// about, or classified in two stages when auto_hierarchy is enabled
+func WithMaxLLMRoutes(n int) Option {
+	return func(r *Router) {
+		if n > 0 {
+			r.maxLLMRoutes = n
+		}
+	}
+}
+
+// WithTenantLLMs routes LLM calls to per-tenant clients. The tenant is read
+// from the given state input field; unmapped tenants use the default client.
+func WithTenantLLMs(field string, bindings map[string]*LLMBinding) Option {
+	return func(r *Router) {
+		r.tenantField = field
+		r.tenantLLMs = bindings
+	}
+}
+
+// WithTenantField reads the tenant of requests without a request tenant from
+// the given state input field
+func WithTenantField(field string) Option {
+	return func(r *Router) {
+		if field != "" {
+			r.tenantField = field
+		}
+	}
+}
+
+// WithTenantQuotas bounds the routing requests and LLM tokens of each tenant.
+// Requests over a limit queue for up to MaxWait, then fail with
+// ErrTenantQuota; LLM calls over the token budget take the fallback route.
+func WithTenantQuotas(config TenantQuotaConfig) Option {
+	return func(r *Router) {
+		r.quotas = newTenantQuotas(config)
+	}
+}
+
+// WithLLMCache reuses LLM responses for identical rendered prompts
+func WithLLMCache(c cache.Cache) Option {
+	return func(r *Router) {
+		r.cache = c
+	}
+}
+
+// WithCircuitBreaker guards each tenant's LLM with a circuit breaker so
+// sustained provider outages fall back immediately instead of timing out
+func WithCircuitBreaker(config BreakerConfig) Option {
+	return func(r *Router) {
+		r.breakerConfig = &config
+	}
+}
+
+// WithLLMLimiter bounds the rate and concurrency of LLM calls. Calls over the
+// limits queue for up to MaxWait, then take the fallback route.
+func WithLLMLimiter(config LimiterConfig) Option {
+	return func(r *Router) {
+		r.limiter = NewLimiter(config)
+	}
+}
+
+// WithCELLimits bounds the time and cost of each CEL condition evaluation
+func WithCELLimits(limits cel.Limits) Option {
+	return func(r *Router) {
+		r.celOptions = append(r.celOptions, cel.WithLimits(limits))
+	}
+}
+
+// WithFeatureFlags evaluates the flag() function of CEL conditions and the
+// flag helper of Handlebars prompts with flags
+func WithFeatureFlags(flags cel.FlagFunc) Option {
+	return func(r *Router) {
+		r.flags = flags
+		r.celOptions = append(r.celOptions, cel.WithFlags(flags))
+	}
+}
+
+// WithNumberMode sets how untyped JSON numbers in state are presented to
+// CEL conditions
+func WithNumberMode(mode cel.NumberMode) Option {
+	return func(r *Router) {
+		if mode != "" {
+			r.numberMode = mode
+		}
+	}
+}
+
+// WithTemplateSandbox renders prompt templates in a sandbox for untrusted
+// graph authors. DeniedPaths name state input fields, which templates can
+// reach both as state.inputs.<path> and flattened as <path>.
+func WithTemplateSandbox(sandbox template.Sandbox) Option {
+	return func(r *Router) {
+		var denied []string
+		for _, path := range sandbox.DeniedPaths {
+			denied = append(denied, path, "state.inputs."+path)
+		}
+		sandbox.DeniedPaths = denied
+		r.templateEngine = template.NewEngine(template.WithSandbox(sandbox))
+		r.sandboxed = true
+	}
+}
+
+// WithTemplateLibrary resolves the template_ref of LLM configs in a prompt
+// template library
+func WithTemplateLibrary(library *prompts.Library) Option {
+	return func(r *Router) {
+		r.templates = library
+	}
 }
 
 // NewRouter creates a new router
-func NewRouter(llmClient ports.LLMClient, logger *zap.Logger) *Router {
-	return &Router{
-		celEvaluator:   cel.NewEvaluator(),
+func NewRouter(llmClient ports.LLMClient, logger *zap.Logger, opts ...Option) *Router {
+	r := &Router{
 		templateEngine: template.NewEngine(),
+		goTemplates:    template.NewGoEngine(),
+		jinjaTemplates: template.NewJinjaEngine(),
+		numberMode:     cel.NumbersIntegral,
 		llmClient:      llmClient,
+		llmModel:       defaultLLMModel,
+		llmTimeout:     defaultLLMTimeout,
+		maxLLMRoutes:   defaultMaxLLMRoutes,
+		tenantLLMs:     make(map[string]*LLMBinding),
+		breakers:       make(map[string]*CircuitBreaker),
+		usage:          NewUsageTracker(),
+		shadowSlots:    make(chan struct{}, defaultShadowMaxInFlight),
+		shadowTimeout:  defaultShadowTimeout,
 		logger:         logger,
 	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.celEvaluator = cel.NewEvaluator(r.celOptions...)
+	r.initStrategies()
+
+	if r.flags != nil {
+		flags := r.flags
+		if err := r.templateEngine.RegisterHelper("flag", func(name string) bool {
+			return flags(name)
+		}); err != nil {
+			logger.Warn("failed to register the flag template helper", zap.Error(err))
+		}
+	}
+
+	return r
+}
+
+// RegisterTemplateHelper adds a helper to the Handlebars prompt templates of
+// this router
+func (r *Router) RegisterTemplateHelper(name string, fn interface{}) error {
+	return r.templateEngine.RegisterHelper(name, fn)
+}
+
+// ExpressionCacheStats returns the size and hit rate of the compiled CEL
+// program cache
+func (r *Router) ExpressionCacheStats() cache.LRUStats {
+	return r.celEvaluator.CacheStats()
 }
 
 // Route performs routing based on state and configuration
 func (r *Router) Route(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error) {
-	r.logger.Info("routing request",
+	ctx, span := tracing.Tracer().Start(ctx, "router.Route")
+	defer span.End()
+
+	// Quotas, metrics and logs are kept per tenant
+	resolved := r.tenantOf(ctx, state)
+	tenant := resolved
+	if tenant == "" {
+		tenant = defaultTenant
+	}
+	ctx = withTenant(ctx, tenant)
+	ctx = logging.With(ctx, r.logger,
+		zap.String("tenant", tenant),
+		zap.String("node_id", NodeIDFrom(ctx)),
+	)
+	span.SetAttributes(attribute.String("tenant", tenant))
+
+	r.log(ctx).Info("routing request",
 		zap.String("graph_id", state.GraphID),
 		zap.String("mode", string(config.Mode)),
 	)
 
-	// Detect mode if not specified
-	if config.Mode == "" {
-		config.Mode = r.detectMode(config)
+	if r.quotas != nil {
+		release, err := r.quotas.Acquire(ctx, tenant)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "tenant quota exceeded")
+			metrics.TenantRequests.WithLabelValues(metrics.Tenants.Value(tenant), "throttled").Inc()
+			r.log(ctx).Warn("routing request throttled",
+				zap.String("graph_id", state.GraphID),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+		defer release()
 	}
 
-	// Route based on mode
-	var result *RoutingResult
-	var err error
+	// Executions in the variant arm of an experiment are routed with the
+	// variant config
+	routed, arm := config, ""
+	if config.Experiment != nil {
+		routed, arm = selectArm(ctx, state, config)
+		span.SetAttributes(
+			attribute.String("experiment.id", config.Experiment.ID),
+			attribute.String("experiment.variant", arm),
+		)
+	}
 
-	switch config.Mode {
-	case ModeDeterministic:
-		result, err = r.routeDeterministic(ctx, state, config)
-	case ModeLLM:
-		result, err = r.routeLLM(ctx, state, config)
-	case ModeHybrid:
-		result, err = r.routeHybrid(ctx, state, config)
-	default:
-		return nil, fmt.Errorf("unknown routing mode: %s", config.Mode)
+	ctx, timer := withTimer(ctx)
+	result, err := r.route(ctx, state, routed)
+	if result != nil {
+		result.Timings = timer.timings()
+	}
+	// Rules and LLM calls cut short by the caller's deadline or cancellation
+	// fall through to the fallback route, which is not a decision
+	if err == nil && ctx.Err() != nil {
+		result, err = nil, fmt.Errorf("routing aborted: %w", ctx.Err())
+	}
+	span.SetAttributes(attribute.String("routing.mode", string(routed.Mode)))
+	if config.Experiment != nil {
+		tagExperiment(config.Experiment, arm, routed, result)
+	}
+
+	// The shadow config is evaluated in the background and never affects
+	// the returned decision. It is only compared with node config decisions.
+	if config.Shadow != nil && routed == config {
+		r.startShadow(ctx, state, config, result, err)
 	}
 
 	if err != nil {
-		r.logger.Error("routing failed",
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "routing failed")
+		metrics.RoutingErrors.WithLabelValues(string(routed.Mode)).Inc()
+		metrics.TenantRequests.WithLabelValues(metrics.Tenants.Value(tenant), "error").Inc()
+		r.log(ctx).Error("routing failed",
 			zap.String("graph_id", state.GraphID),
-			zap.String("mode", string(config.Mode)),
+			zap.String("mode", string(routed.Mode)),
 			zap.Error(err),
 		)
 		return nil, err
 	}
 
-	r.logger.Info("routing decision",
+	result.Tenant = resolved
+	metrics.TenantRequests.WithLabelValues(metrics.Tenants.Value(tenant), "success").Inc()
+	metrics.RoutingDecisions.WithLabelValues(result.Mode, result.PathTaken, targetLabel(routed, result.TargetNode)).Inc()
+	span.SetAttributes(
+		attribute.String("routing.target", result.TargetNode),
+		attribute.String("routing.path_taken", result.PathTaken),
+	)
+
+	r.log(ctx).Info("routing decision",
 		zap.String("graph_id", state.GraphID),
-		zap.String("mode", string(config.Mode)),
+		zap.String("mode", string(routed.Mode)),
 		zap.String("target", result.TargetNode),
 		zap.String("path", result.PathTaken),
 		zap.String("reasoning", result.Reasoning),
@@ -121,8 +632,59 @@ func (r *Router) Route(ctx context.Context, state *domain.GraphState, config *No
 	return result, nil
 }
 
+// route resolves the rule sets of config, detects its mode when unset and
+// routes with it
+func (r *Router) route(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error) {
+	resolved, err := r.resolveRuleSets(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Mode == "" {
+		config.Mode = r.detectMode(resolved)
+		resolved.Mode = config.Mode
+	}
+
+	result, err := r.routeResolved(ctx, state, resolved)
+	if result != nil {
+		result.RuleSets = resolved.ruleSetRefs
+	}
+	return result, err
+}
+
+// routeResolved routes with a config whose rule sets are resolved
+func (r *Router) routeResolved(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error) {
+	if len(config.Lookups) > 0 {
+		var err error
+		if ctx, err = r.resolveLookups(ctx, state, config); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(config.Schedule) > 0 {
+		if result := r.routeSchedule(config, time.Now()); result != nil {
+			return result, nil
+		}
+	}
+
+	strategy, ok := r.strategies.Lookup(config.Mode)
+	if !ok {
+		return nil, fmt.Errorf("unknown routing mode: %s", config.Mode)
+	}
+	if _, custom := r.customStrategy(config.Mode); custom {
+		if err := r.validateConfig(config); err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+	}
+	return strategy.Route(ctx, state, config)
+}
+
 // detectMode detects the routing mode from configuration
 func (r *Router) detectMode(config *NodeConfig) RoutingMode {
+	// Pipeline mode: has pipeline stages
+	if len(config.Pipeline) > 0 {
+		return ModePipeline
+	}
+
 	// Hybrid mode: has fast_rules and llm_fallback
 	if len(config.FastRules) > 0 && config.LLMFallback != nil {
 		return ModeHybrid
@@ -133,6 +695,21 @@ func (r *Router) detectMode(config *NodeConfig) RoutingMode {
 		return ModeLLM
 	}
 
+	// Classifier mode: has classifier
+	if config.Classifier != nil {
+		return ModeClassifier
+	}
+
+	// Keyword mode: has keyword
+	if config.Keyword != nil {
+		return ModeKeyword
+	}
+
+	// Embedding mode: has embedding
+	if config.Embedding != nil {
+		return ModeEmbedding
+	}
+
 	// Deterministic mode: has rules
 	if len(config.Rules) > 0 {
 		return ModeDeterministic
@@ -151,44 +728,170 @@ func (r *Router) validateConfig(config *NodeConfig) error {
 	if config.Fallback == "" {
 		return fmt.Errorf("fallback route is required")
 	}
+	if err := config.declarations().Validate(); err != nil {
+		return fmt.Errorf("variables: %w", err)
+	}
 
 	switch config.Mode {
 	case ModeDeterministic:
 		if len(config.Rules) == 0 {
 			return fmt.Errorf("deterministic mode requires rules")
 		}
-		for i, rule := range config.Rules {
-			if rule.Condition == "" {
-				return fmt.Errorf("rule %d: condition is required", i)
-			}
-			if rule.Target == "" {
-				return fmt.Errorf("rule %d: target is required", i)
-			}
+		if err := r.validateRules("rule", config.Rules, config.declarations()); err != nil {
+			return err
+		}
+		if err := validateGroups(config.Rules, config.Groups); err != nil {
+			return err
 		}
 
 	case ModeLLM:
 		if config.LLMConfig == nil {
 			return fmt.Errorf("llm mode requires llm_config")
 		}
-		if config.LLMConfig.PromptTemplate == "" {
-			return fmt.Errorf("llm_config.prompt_template is required")
-		}
-		if len(config.LLMConfig.Routes) == 0 {
-			return fmt.Errorf("llm_config.routes is required")
+		if err := r.validateLLMConfig("llm_config", config.LLMConfig); err != nil {
+			return err
 		}
 
 	case ModeHybrid:
 		if len(config.FastRules) == 0 {
 			return fmt.Errorf("hybrid mode requires fast_rules")
 		}
+		if err := r.validateRules("fast_rule", config.FastRules, config.declarations()); err != nil {
+			return err
+		}
 		if config.LLMFallback == nil {
 			return fmt.Errorf("hybrid mode requires llm_fallback")
 		}
-		if config.LLMFallback.PromptTemplate == "" {
-			return fmt.Errorf("llm_fallback.prompt_template is required")
+		if err := r.validateLLMConfig("llm_fallback", config.LLMFallback); err != nil {
+			return err
+		}
+
+	case ModeClassifier:
+		if config.Classifier == nil {
+			return fmt.Errorf("classifier mode requires classifier")
+		}
+		v := &configValidator{router: r}
+		v.classifier("classifier", config.Classifier)
+		if len(v.errors) > 0 {
+			return v.errors[0]
+		}
+
+	case ModeKeyword:
+		if config.Keyword == nil {
+			return fmt.Errorf("keyword mode requires keyword")
+		}
+		v := &configValidator{router: r}
+		v.keyword("keyword", config.Keyword)
+		if len(v.errors) > 0 {
+			return v.errors[0]
+		}
+
+	case ModeEmbedding:
+		if config.Embedding == nil {
+			return fmt.Errorf("embedding mode requires embedding")
+		}
+		v := &configValidator{router: r}
+		v.embedding("embedding", config.Embedding)
+		if len(v.errors) > 0 {
+			return v.errors[0]
+		}
+
+	case ModePipeline:
+		v := &configValidator{router: r}
+		v.pipeline(config.Pipeline)
+		if len(v.errors) > 0 {
+			return v.errors[0]
+		}
+
+	default:
+		if errs := r.validateCustom(config); len(errs) > 0 {
+			return errs[0]
+		}
+	}
+
+	if errs := undeclaredTargets(config); len(errs) > 0 {
+		return errs[0]
+	}
+
+	if err := cel.ValidateNumberTypes(config.NumberTypes); err != nil {
+		return fmt.Errorf("number_types: %w", err)
+	}
+
+	return nil
+}
+
+// validateRules checks that every rule has a target, or a target expression
+// that compiles to a string, and a condition that compiles to a boolean with
+// the declared variables
+func (r *Router) validateRules(kind string, rules []Rule, declared cel.Declarations) error {
+	for i, rule := range rules {
+		if rule.Condition == "" {
+			return fmt.Errorf("%s %d: condition is required", kind, i)
+		}
+		switch {
+		case rule.Target == "" && rule.TargetExpr == "":
+			return fmt.Errorf("%s %d: target is required", kind, i)
+		case rule.Target != "" && rule.TargetExpr != "":
+			return fmt.Errorf("%s %d: target and target_expr are mutually exclusive", kind, i)
+		}
+		if err := r.celEvaluator.ValidateDeclaredExpression(declared, rule.Condition); err != nil {
+			return fmt.Errorf("%s %d: invalid condition: %w", kind, i, err)
+		}
+		if rule.TargetExpr != "" {
+			if err := r.celEvaluator.ValidateDeclaredStringExpression(declared, rule.TargetExpr); err != nil {
+				return fmt.Errorf("%s %d: invalid target_expr: %w", kind, i, err)
+			}
 		}
-		if len(config.LLMFallback.Routes) == 0 {
-			return fmt.Errorf("llm_fallback.routes is required")
+	}
+	return nil
+}
+
+// validateLLMConfig checks an LLM classification config and warns about
+// route sets too large for a single prompt
+func (r *Router) validateLLMConfig(field string, llmConfig *LLMConfig) error {
+	if llmConfig.PromptTemplate == "" && llmConfig.TemplateRef == "" {
+		return fmt.Errorf("%s.prompt_template or %s.template_ref is required", field, field)
+	}
+
+	renderer, err := r.renderer(llmConfig.TemplateEngine)
+	if err != nil {
+		return fmt.Errorf("%s.template_engine: %w", field, err)
+	}
+	if promptField, err := r.validatePrompt(renderer, llmConfig.PromptTemplate, llmConfig.TemplateRef); err != nil {
+		return fmt.Errorf("%s.%s: %w", field, promptField, err)
+	}
+
+	if llmConfig.MinConfidence < 0 || llmConfig.MinConfidence > 1 {
+		return fmt.Errorf("%s.min_confidence must be between 0 and 1", field)
+	}
+	if llmConfig.MinConfidence > 0 && !llmConfig.StructuredOutput {
+		return fmt.Errorf("%s.min_confidence requires structured_output", field)
+	}
+
+	switch llmConfig.AnswerType {
+	case "", AnswerLabel:
+	case AnswerBoolean, AnswerNumber:
+		return validateTypedAnswers(field, llmConfig)
+	default:
+		return fmt.Errorf("%s.answer_type '%s' is not supported", field, llmConfig.AnswerType)
+	}
+
+	if len(llmConfig.Categories) == 0 {
+		if len(llmConfig.Routes) == 0 {
+			return fmt.Errorf("%s.routes is required", field)
+		}
+		r.checkRouteCardinality(field, llmConfig)
+		return nil
+	}
+
+	for name, category := range llmConfig.Categories {
+		if category == nil || len(category.Routes) == 0 {
+			return fmt.Errorf("%s.categories.%s.routes is required", field, name)
+		}
+		if category.PromptTemplate != "" || category.TemplateRef != "" {
+			if promptField, err := r.validatePrompt(renderer, category.PromptTemplate, category.TemplateRef); err != nil {
+				return fmt.Errorf("%s.categories.%s.%s: %w", field, name, promptField, err)
+			}
 		}
 	}
 
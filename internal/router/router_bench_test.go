@@ -0,0 +1,168 @@
+package router
+
+import (
+	"context"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/llmmock"
+	"go.uber.org/zap"
+)
+
+// latencySamples is the number of deterministic routes timed by
+// TestRouteLatencyBudget
+const latencySamples = 20000
+
+// benchLLM answers the classification prompts by keyword, offline
+var benchLLM = llmmock.Config{
+	Routes: []llmmock.Route{
+		{Keyword: "refund", Response: "billing"},
+		{Keyword: "invoice", Response: "billing"},
+		{Keyword: "error", Response: "technical"},
+		{Keyword: "deploy", Response: "technical"},
+	},
+	Default: "general",
+}
+
+// benchRoutes maps the classification labels to target nodes
+var benchRoutes = map[string]string{
+	"billing":   "billing_agent",
+	"technical": "tech_support",
+	"general":   "general_agent",
+}
+
+// benchConfigs are the routing configs benchmarked, by mode
+var benchConfigs = map[string]*NodeConfig{
+	"deterministic": {
+		Mode: ModeDeterministic,
+		Rules: []Rule{
+			{Condition: `state.inputs.priority == "high"`, Target: "incident_response"},
+			{Condition: `state.inputs.message.contains("refund")`, Target: "billing_agent"},
+		},
+		Fallback: "general_agent",
+	},
+	"llm": {
+		Mode: ModeLLM,
+		LLMConfig: &LLMConfig{
+			PromptTemplate: "Classify this support message as billing, technical or general: {{state.inputs.message}}",
+			Routes:         benchRoutes,
+		},
+		Fallback: "general_agent",
+	},
+	"hybrid": {
+		Mode:      ModeHybrid,
+		FastRules: []Rule{{Condition: `state.inputs.priority == "high"`, Target: "incident_response"}},
+		LLMFallback: &LLMConfig{
+			PromptTemplate: "Classify this support message as billing, technical or general: {{state.inputs.message}}",
+			Routes:         benchRoutes,
+		},
+		Fallback: "general_agent",
+	},
+	"keyword": {
+		Mode: ModeKeyword,
+		Keyword: &KeywordConfig{
+			Input: "{{state.inputs.message}}",
+			Routes: []KeywordRoute{
+				{Target: "billing_agent", Keywords: map[string]float64{"refund": 2, "invoice": 1, "charged": 1}},
+				{Target: "tech_support", Keywords: map[string]float64{"error": 1, "deploy": 1}, Patterns: map[string]float64{`\b5\d\d\b`: 2}},
+			},
+			Threshold: 1,
+		},
+		Fallback: "general_agent",
+	},
+	"pipeline": {
+		Mode: ModePipeline,
+		Pipeline: []PipelineStage{
+			{Name: "urgent", Rules: []Rule{{Condition: `state.inputs.priority == "high"`, Target: "incident_response"}}},
+			{Name: "keywords", Keyword: &KeywordConfig{
+				Input: "{{state.inputs.message}}",
+				Routes: []KeywordRoute{
+					{Target: "billing_agent", Keywords: map[string]float64{"refund": 1, "invoice": 1}},
+				},
+				Threshold: 1,
+			}},
+		},
+		Fallback: "general_agent",
+	},
+}
+
+// benchStates are the graph states routed in turn by the benchmarks
+var benchStates = []*domain.GraphState{
+	{GraphID: "support-triage", Status: "running", Inputs: map[string]interface{}{
+		"priority": "high",
+		"message":  "Production API returns 500 errors after the last deploy",
+	}},
+	{GraphID: "support-triage", Status: "running", Inputs: map[string]interface{}{
+		"priority": "normal",
+		"message":  "I was charged twice, please refund the second invoice",
+	}},
+	{GraphID: "support-triage", Status: "running", Inputs: map[string]interface{}{
+		"priority": "low",
+		"message":  "How do I change the avatar on my profile?",
+	}},
+}
+
+// BenchmarkRoute measures Route in every mode, with LLM calls answered
+// offline
+func BenchmarkRoute(b *testing.B) {
+	modes := make([]string, 0, len(benchConfigs))
+	for mode := range benchConfigs {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+
+	r := NewRouter(llmmock.NewClient(benchLLM), zap.NewNop())
+	ctx := WithNodeID(context.Background(), "bench-router")
+	for _, mode := range modes {
+		config := benchConfigs[mode]
+		b.Run(mode, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := r.Route(ctx, benchStates[i%len(benchStates)], config); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestRouteLatencyBudget fails when the p95 of deterministic routing exceeds
+// ROUTE_P95_BUDGET (e.g. 1ms), so CI catches latency regressions. It is
+// skipped when the budget is unset.
+func TestRouteLatencyBudget(t *testing.T) {
+	raw := os.Getenv("ROUTE_P95_BUDGET")
+	if raw == "" {
+		t.Skip("ROUTE_P95_BUDGET not set")
+	}
+	budget, err := time.ParseDuration(raw)
+	if err != nil {
+		t.Fatalf("invalid ROUTE_P95_BUDGET: %v", err)
+	}
+
+	r := NewRouter(llmmock.NewClient(benchLLM), zap.NewNop())
+	ctx := WithNodeID(context.Background(), "bench-router")
+	config := benchConfigs["deterministic"]
+
+	durations := make([]time.Duration, latencySamples)
+	for i := range durations {
+		start := time.Now()
+		if _, err := r.Route(ctx, benchStates[i%len(benchStates)], config); err != nil {
+			t.Fatal(err)
+		}
+		durations[i] = time.Since(start)
+	}
+	sort.Slice(durations, func(a, b int) bool { return durations[a] < durations[b] })
+
+	percentile := func(p float64) time.Duration {
+		return durations[int(p*float64(len(durations)-1))]
+	}
+	p95 := percentile(0.95)
+	t.Logf("deterministic routing over %d routes: p50 %s, p95 %s, p99 %s",
+		len(durations), percentile(0.50), p95, percentile(0.99))
+	if p95 > budget {
+		t.Fatalf("p95 deterministic routing latency %s exceeds the %s budget", p95, budget)
+	}
+}
@@ -0,0 +1,238 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// GroupMatch represents how the rules of a group are combined
+type GroupMatch string
+
+const (
+	// GroupMatchAny matches the group on the first matching rule (OR)
+	GroupMatchAny GroupMatch = "any"
+
+	// GroupMatchAll matches the group only when every rule matches (AND)
+	GroupMatchAll GroupMatch = "all"
+)
+
+// ruleUnit is a single step of the deterministic evaluation plan: either a
+// standalone rule or a group of rules evaluated together
+type ruleUnit struct {
+	group   string
+	match   GroupMatch
+	indexes []int
+}
+
+// planRules orders rules into evaluation units.
+//
+// Rules are sorted by descending priority; rules with equal priority keep their
+// array order. Grouped rules are evaluated together at the position of the
+// group's highest priority member.
+func planRules(rules []Rule, groups map[string]GroupMatch) []ruleUnit {
+	order := make([]int, len(rules))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rules[order[a]].Priority > rules[order[b]].Priority
+	})
+
+	units := make([]ruleUnit, 0, len(rules))
+	groupUnit := make(map[string]int)
+	for _, i := range order {
+		rule := rules[i]
+		if rule.Group == "" {
+			units = append(units, ruleUnit{indexes: []int{i}})
+			continue
+		}
+
+		if u, ok := groupUnit[rule.Group]; ok {
+			units[u].indexes = append(units[u].indexes, i)
+			continue
+		}
+
+		match := groups[rule.Group]
+		if match == "" {
+			match = GroupMatchAny
+		}
+		groupUnit[rule.Group] = len(units)
+		units = append(units, ruleUnit{
+			group:   rule.Group,
+			match:   match,
+			indexes: []int{i},
+		})
+	}
+
+	return units
+}
+
+// evaluateRule evaluates a single rule condition of the rules at field,
+// returning false on evaluation errors or non-boolean results
+func (r *Router) evaluateRule(ctx context.Context, field string, index int, rule Rule, celState map[string]interface{}) bool {
+	r.log(ctx).Debug("evaluating rule",
+		zap.Int("rule_index", index),
+		zap.String("condition", rule.Condition),
+	)
+
+	result, err := r.evaluateCondition(ctx, rule.Condition, celState)
+	if err != nil {
+		r.recordRule(ctx, field, index, hitError)
+		r.log(ctx).Warn("rule evaluation error",
+			zap.Int("rule_index", index),
+			zap.String("condition", rule.Condition),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	matched, ok := result.(bool)
+	if !ok {
+		r.recordRule(ctx, field, index, hitError)
+		r.log(ctx).Warn("rule condition did not return boolean",
+			zap.Int("rule_index", index),
+			zap.String("condition", rule.Condition),
+			zap.Any("result", result),
+		)
+		return false
+	}
+
+	if matched {
+		r.recordRule(ctx, field, index, hitMatched)
+	} else {
+		r.recordRule(ctx, field, index, hitNoMatch)
+	}
+	return matched
+}
+
+// resolveTarget returns the target of a matched rule, evaluating its
+// target_expr against the state when set. Computed targets must be non-empty
+// strings and, when the config declares targets, one of them.
+func (r *Router) resolveTarget(ctx context.Context, rule Rule, config *NodeConfig, celState map[string]interface{}) (string, error) {
+	if rule.TargetExpr == "" {
+		return rule.Target, nil
+	}
+
+	result, err := r.evaluateCondition(ctx, rule.TargetExpr, celState)
+	if err != nil {
+		return "", fmt.Errorf("target_expr: %w", err)
+	}
+	target, ok := result.(string)
+	if !ok || target == "" {
+		return "", fmt.Errorf("target_expr returned %v, want a non-empty string", result)
+	}
+	if len(config.Targets) > 0 && !toSet(config.Targets)[target] {
+		return "", fmt.Errorf("target_expr returned '%s', which is not a declared target", target)
+	}
+
+	return target, nil
+}
+
+// unresolvedTarget is the fallback result of a matched rule whose target
+// could not be resolved
+func (r *Router) unresolvedTarget(ctx context.Context, mode RoutingMode, index int, rule Rule, config *NodeConfig, err error) *RoutingResult {
+	r.log(ctx).Warn("rule matched but its target could not be resolved, using fallback",
+		zap.Int("rule_index", index),
+		zap.String("target_expr", rule.TargetExpr),
+		zap.String("fallback", config.Fallback),
+		zap.Error(err),
+	)
+
+	return &RoutingResult{
+		TargetNode: config.Fallback,
+		Reasoning:  fmt.Sprintf("matched rule %d but could not resolve its target: %v", index, err),
+		Mode:       string(mode),
+		PathTaken:  "fallback",
+		RuleIndex:  &index,
+		Condition:  rule.Condition,
+	}
+}
+
+// Evaluate evaluates a CEL expression against state as the rules of config
+// see it (config may be nil), for tools that test rules outside a worker
+func (r *Router) Evaluate(ctx context.Context, expression string, state *domain.GraphState, config *NodeConfig) (interface{}, error) {
+	if config == nil {
+		config = &NodeConfig{}
+	}
+	ctx = withDeclarations(ctx, config)
+	return r.evaluateCondition(ctx, expression, r.prepareStateForCEL(ctx, state, config))
+}
+
+// evaluateCondition evaluates a CEL condition and records evaluation metrics
+func (r *Router) evaluateCondition(ctx context.Context, condition string, vars map[string]interface{}) (interface{}, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "cel.Evaluate",
+		trace.WithAttributes(attribute.String("cel.expression", condition)),
+	)
+	defer span.End()
+
+	start := time.Now()
+	result, err := r.celEvaluator.EvaluateDeclared(ctx, declarationsFrom(ctx), condition, vars)
+	metrics.CELEvaluationDuration.Observe(metrics.Since(start))
+	timerFrom(ctx).addCEL(time.Since(start))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "evaluation failed")
+		metrics.CELEvaluationErrors.Inc()
+	}
+	return result, err
+}
+
+// evaluateUnit evaluates an evaluation unit of the rules at field,
+// short-circuiting as soon as the outcome is known and marking the rules it
+// evaluates. It returns the index of the rule that decides the target.
+func (r *Router) evaluateUnit(ctx context.Context, field string, unit ruleUnit, rules []Rule, celState map[string]interface{}, evaluated []bool) (int, bool) {
+	if unit.match == GroupMatchAll {
+		for _, i := range unit.indexes {
+			evaluated[i] = true
+			if !r.evaluateRule(ctx, field, i, rules[i], celState) {
+				return -1, false
+			}
+		}
+		return unit.indexes[0], true
+	}
+
+	for _, i := range unit.indexes {
+		evaluated[i] = true
+		if r.evaluateRule(ctx, field, i, rules[i], celState) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// validateGroups validates rule groups for a set of rules
+func validateGroups(rules []Rule, groups map[string]GroupMatch) error {
+	for name, match := range groups {
+		if match != GroupMatchAny && match != GroupMatchAll {
+			return fmt.Errorf("group %q: match must be %q or %q", name, GroupMatchAny, GroupMatchAll)
+		}
+	}
+
+	// All rules of an AND group lead to a single target
+	targets := make(map[string]string)
+	for i, rule := range rules {
+		if rule.Group == "" || groups[rule.Group] != GroupMatchAll {
+			continue
+		}
+		target := rule.Target
+		if rule.TargetExpr != "" {
+			target = "expr:" + rule.TargetExpr
+		}
+		if shared, ok := targets[rule.Group]; ok && shared != target {
+			return fmt.Errorf("rule %d: all rules in group %q must share the same target", i, rule.Group)
+		}
+		targets[rule.Group] = target
+	}
+
+	return nil
+}
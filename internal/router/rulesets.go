@@ -0,0 +1,84 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aescanero/dago-node-router/internal/policies"
+)
+
+// WithRuleSets resolves the rules_ref and fast_rules_ref of node configs in
+// a rule set registry
+func WithRuleSets(registry *policies.Registry) Option {
+	return func(r *Router) {
+		r.ruleSets = registry
+	}
+}
+
+// resolveRuleSets returns config with the rules and groups of its referenced
+// rule sets, or config itself when it references none. Groups declared by
+// the config take precedence over those of the rule sets.
+func (r *Router) resolveRuleSets(config *NodeConfig) (*NodeConfig, error) {
+	if config.RulesRef == "" && config.FastRulesRef == "" {
+		return config, nil
+	}
+	if r.ruleSets == nil {
+		return nil, fmt.Errorf("rule set references require a rule set registry")
+	}
+
+	resolved := *config
+	var refs []string
+	for _, ref := range []struct {
+		field string
+		ref   string
+		rules *[]Rule
+	}{
+		{"rules_ref", config.RulesRef, &resolved.Rules},
+		{"fast_rules_ref", config.FastRulesRef, &resolved.FastRules},
+	} {
+		if ref.ref == "" {
+			continue
+		}
+		if len(*ref.rules) > 0 {
+			return nil, fmt.Errorf("%s cannot be combined with inline rules", ref.field)
+		}
+		set, err := r.ruleSets.Resolve(ref.ref)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ref.field, err)
+		}
+		rules, groups, err := decodeRuleSet(set)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ref.field, err)
+		}
+		*ref.rules = rules
+		if len(groups) > 0 {
+			merged := make(map[string]GroupMatch, len(groups)+len(resolved.Groups))
+			for name, match := range groups {
+				merged[name] = match
+			}
+			for name, match := range resolved.Groups {
+				merged[name] = match
+			}
+			resolved.Groups = merged
+		}
+		refs = append(refs, set.Ref())
+	}
+	resolved.ruleSetRefs = refs
+
+	return &resolved, nil
+}
+
+// decodeRuleSet decodes the rules and groups of a rule set
+func decodeRuleSet(set *policies.RuleSet) ([]Rule, map[string]GroupMatch, error) {
+	var rules []Rule
+	if err := json.Unmarshal(set.Rules, &rules); err != nil {
+		return nil, nil, fmt.Errorf("rule set %s: invalid rules: %w", set.Ref(), err)
+	}
+	var groups map[string]GroupMatch
+	if len(set.Groups) > 0 {
+		if err := json.Unmarshal(set.Groups, &groups); err != nil {
+			return nil, nil, fmt.Errorf("rule set %s: invalid groups: %w", set.Ref(), err)
+		}
+	}
+	return rules, groups, nil
+}
@@ -0,0 +1,164 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+)
+
+// Rule and pipeline stage evaluation results
+const (
+	hitMatched = "matched"
+	hitNoMatch = "no_match"
+	hitError   = "error"
+	hitSkipped = "skipped"
+)
+
+// RuleHits counts the evaluation results of a rule or pipeline stage
+type RuleHits struct {
+	Matched uint64 `json:"matched"`
+	NoMatch uint64 `json:"no_match"`
+	Errors  uint64 `json:"errors"`
+	// Skipped counts evaluations where an earlier rule or stage matched
+	Skipped uint64 `json:"skipped"`
+}
+
+// RuleStats holds the hit counters of a node since the worker started
+type RuleStats struct {
+	// Rules are keyed by rule path, e.g. "rules[0]", "fast_rules[2]" or
+	// "pipeline[1].rules[0]"
+	Rules map[string]RuleHits `json:"rules,omitempty"`
+	// Stages are keyed by pipeline stage label (name, or index and type)
+	Stages map[string]RuleHits `json:"stages,omitempty"`
+}
+
+// hitCounters accumulates rule and stage hits by node
+type hitCounters struct {
+	mu     sync.Mutex
+	rules  map[string]map[string]*RuleHits
+	stages map[string]map[string]*RuleHits
+}
+
+// add records one result under node and key
+func (h *hitCounters) add(counters *map[string]map[string]*RuleHits, node, key, result string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if *counters == nil {
+		*counters = make(map[string]map[string]*RuleHits)
+	}
+	byKey := (*counters)[node]
+	if byKey == nil {
+		byKey = make(map[string]*RuleHits)
+		(*counters)[node] = byKey
+	}
+	hits := byKey[key]
+	if hits == nil {
+		hits = &RuleHits{}
+		byKey[key] = hits
+	}
+	switch result {
+	case hitMatched:
+		hits.Matched++
+	case hitNoMatch:
+		hits.NoMatch++
+	case hitError:
+		hits.Errors++
+	case hitSkipped:
+		hits.Skipped++
+	}
+}
+
+// recordRule counts the result of evaluating the rule at index of the rules
+// at field
+func (r *Router) recordRule(ctx context.Context, field string, index int, result string) {
+	node := NodeIDFrom(ctx)
+	key := fmt.Sprintf("%s[%d]", field, index)
+	metrics.RuleEvaluations.WithLabelValues(metrics.Nodes.Value(node), metrics.Rules.Value(key), result).Inc()
+	r.hits.add(&r.hits.rules, node, key, result)
+	if trace := hitTraceFrom(ctx); trace != nil {
+		trace.rules = append(trace.rules, tracedHit{field: field, index: index, result: result})
+	}
+}
+
+// recordSkipped counts the rules at field that were not evaluated
+func (r *Router) recordSkipped(ctx context.Context, field string, evaluated []bool) {
+	for i, done := range evaluated {
+		if !done {
+			r.recordRule(ctx, field, i, hitSkipped)
+		}
+	}
+}
+
+// recordStage counts the result of a pipeline stage
+func (r *Router) recordStage(ctx context.Context, stage, stageType, result string) {
+	node := NodeIDFrom(ctx)
+	metrics.PipelineStages.WithLabelValues(metrics.Nodes.Value(node), metrics.Stages.Value(stage), stageType, result).Inc()
+	r.hits.add(&r.hits.stages, node, stage, result)
+}
+
+// hitTrace records the rule results and the decision of one rules
+// evaluation, so a decision served from the decision cache counts them again
+type hitTrace struct {
+	rules   []tracedHit
+	decided bool
+	unit    *ruleUnit
+}
+
+// tracedHit is a rule result recorded by a hit trace
+type tracedHit struct {
+	field  string
+	index  int
+	result string
+}
+
+// hitTraceKey is the context.Context key for the hit trace of an evaluation
+type hitTraceKey struct{}
+
+// withHitTrace returns a context carrying a new hit trace
+func withHitTrace(ctx context.Context) (context.Context, *hitTrace) {
+	trace := &hitTrace{}
+	return context.WithValue(ctx, hitTraceKey{}, trace), trace
+}
+
+// hitTraceFrom returns the hit trace carried by ctx, or nil
+func hitTraceFrom(ctx context.Context) *hitTrace {
+	trace, _ := ctx.Value(hitTraceKey{}).(*hitTrace)
+	return trace
+}
+
+// replayHits counts the rule results and the decision of a trace again
+func (r *Router) replayHits(ctx context.Context, config *NodeConfig, field string, trace *hitTrace) {
+	if trace == nil {
+		return
+	}
+	for _, hit := range trace.rules {
+		r.recordRule(ctx, hit.field, hit.index, hit.result)
+	}
+	if trace.decided {
+		r.recordDecision(ctx, config, field, trace.unit)
+	}
+}
+
+// RuleStats returns the rule and pipeline stage hit counters of a node
+func (r *Router) RuleStats(nodeID string) *RuleStats {
+	r.hits.mu.Lock()
+	defer r.hits.mu.Unlock()
+
+	copyHits := func(byKey map[string]*RuleHits) map[string]RuleHits {
+		if len(byKey) == 0 {
+			return nil
+		}
+		copied := make(map[string]RuleHits, len(byKey))
+		for key, hits := range byKey {
+			copied[key] = *hits
+		}
+		return copied
+	}
+	return &RuleStats{
+		Rules:  copyHits(r.hits.rules[nodeID]),
+		Stages: copyHits(r.hits.stages[nodeID]),
+	}
+}
@@ -0,0 +1,208 @@
+package router
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/eval/cel"
+	"go.uber.org/zap"
+)
+
+// pathSchedule is the path_taken of decisions made by a schedule window
+const pathSchedule = "schedule"
+
+// weekdays maps day names to time.Weekday, for schedule windows
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ScheduleWindow routes to Target while the current time is within (or,
+// with Outside, outside of) a weekly time window, before any rule or LLM
+// call. For example, days "mon-fri", hours "09:00-17:00", timezone "CET" and
+// outside true route to Target after hours.
+type ScheduleWindow struct {
+	// Days lists day ranges such as "mon-fri" or "sat,sun"; empty means
+	// every day
+	Days string `json:"days,omitempty"`
+	// Hours is a "HH:MM-HH:MM" range, end excluded; ranges ending before they
+	// start span midnight. Empty means all day.
+	Hours string `json:"hours,omitempty"`
+	// Timezone is an IANA time zone name such as "Europe/Madrid"; empty
+	// means UTC
+	Timezone string `json:"timezone,omitempty"`
+	Outside  bool   `json:"outside,omitempty"`
+	Target   string `json:"target"`
+}
+
+// String describes the window, for decision reasoning
+func (w ScheduleWindow) String() string {
+	days, hours, tz := w.Days, w.Hours, w.Timezone
+	if days == "" {
+		days = "every day"
+	}
+	if hours == "" {
+		hours = "all day"
+	}
+	if tz == "" {
+		tz = "UTC"
+	}
+	desc := fmt.Sprintf("%s %s %s", days, hours, tz)
+	if w.Outside {
+		desc = "outside " + desc
+	}
+	return desc
+}
+
+// contains reports whether t is within the window, ignoring Outside
+func (w ScheduleWindow) contains(t time.Time) (bool, error) {
+	loc := time.UTC
+	if w.Timezone != "" {
+		var err error
+		if loc, err = cel.LoadLocation(w.Timezone); err != nil {
+			return false, err
+		}
+	}
+	t = t.In(loc)
+
+	days, err := parseDays(w.Days)
+	if err != nil {
+		return false, err
+	}
+	start, end, err := parseHours(w.Hours)
+	if err != nil {
+		return false, err
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if start > end && minute < end {
+		// Past midnight, the window started the day before
+		day = (day + 6) % 7
+	}
+	if !days[day] {
+		return false, nil
+	}
+	if start <= end {
+		return minute >= start && minute < end, nil
+	}
+	return minute >= start || minute < end, nil
+}
+
+// parseDays parses comma-separated days and day ranges; empty means every day
+func parseDays(spec string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool, 7)
+	if strings.TrimSpace(spec) == "" {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			days[day] = true
+		}
+		return days, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(strings.ToLower(strings.TrimSpace(part)), "-")
+		first, ok := weekdays[strings.TrimSpace(from)]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[strings.TrimSpace(to)]; !ok {
+				return nil, fmt.Errorf("unknown day %q", to)
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseHours parses a "HH:MM-HH:MM" range into minutes of the day; empty
+// means all day
+func parseHours(spec string) (int, int, error) {
+	if strings.TrimSpace(spec) == "" {
+		return 0, 24 * 60, nil
+	}
+	from, to, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("hours %q must be a HH:MM-HH:MM range", spec)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("hours %q is an empty range", spec)
+	}
+	return start, end, nil
+}
+
+// parseClock parses "HH:MM" (or "24:00") into minutes of the day
+func parseClock(clock string) (int, error) {
+	hh, mm, ok := strings.Cut(strings.TrimSpace(clock), ":")
+	hours, errH := strconv.Atoi(hh)
+	minutes, errM := strconv.Atoi(mm)
+	if !ok || errH != nil || errM != nil || hours < 0 || minutes < 0 || minutes > 59 ||
+		hours > 24 || (hours == 24 && minutes > 0) {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", clock)
+	}
+	return hours*60 + minutes, nil
+}
+
+// routeSchedule returns the decision of the first schedule window of config
+// that applies now, or nil when none does. Windows that cannot be evaluated
+// are skipped; configs are validated before they are routed.
+func (r *Router) routeSchedule(config *NodeConfig, now time.Time) *RoutingResult {
+	for i, window := range config.Schedule {
+		inside, err := window.contains(now)
+		if err != nil {
+			r.logger.Warn("invalid schedule window, skipping it",
+				zap.Int("window", i),
+				zap.Error(err),
+			)
+			continue
+		}
+		if inside == window.Outside {
+			continue
+		}
+
+		return &RoutingResult{
+			TargetNode: window.Target,
+			Reasoning:  fmt.Sprintf("schedule window %d: %s", i, window),
+			Mode:       string(config.Mode),
+			PathTaken:  pathSchedule,
+		}
+	}
+	return nil
+}
+
+// schedule checks the schedule windows of a node config
+func (v *configValidator) schedule(windows []ScheduleWindow) {
+	for i, window := range windows {
+		field := fmt.Sprintf("schedule[%d]", i)
+		if window.Target == "" {
+			v.add(field+".target", "target is required")
+		}
+		if _, err := parseDays(window.Days); err != nil {
+			v.add(field+".days", err.Error())
+		}
+		if _, _, err := parseHours(window.Hours); err != nil {
+			v.add(field+".hours", err.Error())
+		}
+		if window.Timezone != "" {
+			if _, err := cel.LoadLocation(window.Timezone); err != nil {
+				v.add(field+".timezone", err.Error())
+			}
+		}
+	}
+}
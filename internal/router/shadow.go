@@ -0,0 +1,134 @@
+package router
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// Shadow evaluation defaults
+const (
+	defaultShadowMaxInFlight = 16
+	defaultShadowTimeout     = 30 * time.Second
+)
+
+// Shadow comparison results, as reported by the shadow_decisions_total metric
+const (
+	shadowMatch   = "match"
+	shadowDiverge = "diverge"
+	shadowError   = "error"
+	shadowSkipped = "skipped"
+)
+
+// WithShadowLimits bounds shadow evaluations to maxInFlight concurrent
+// evaluations of at most timeout each. Shadows beyond the limit are skipped.
+func WithShadowLimits(maxInFlight int, timeout time.Duration) Option {
+	return func(r *Router) {
+		if maxInFlight > 0 {
+			r.shadowSlots = make(chan struct{}, maxInFlight)
+		}
+		if timeout > 0 {
+			r.shadowTimeout = timeout
+		}
+	}
+}
+
+// startShadow evaluates the shadow of config in the background and compares
+// its decision with the primary one. It does not wait for the evaluation.
+func (r *Router) startShadow(ctx context.Context, state *domain.GraphState, config *NodeConfig, primary *RoutingResult, primaryErr error) {
+	select {
+	case r.shadowSlots <- struct{}{}:
+	default:
+		metrics.ShadowDecisions.WithLabelValues(shadowSkipped).Inc()
+		r.log(ctx).Debug("shadow evaluation skipped, too many in flight",
+			zap.String("graph_id", state.GraphID),
+		)
+		return
+	}
+
+	// Detached from the request so publishing the primary decision does not
+	// cancel the shadow
+	shadowCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.shadowTimeout)
+	go func() {
+		defer func() { <-r.shadowSlots }()
+		defer cancel()
+		r.runShadow(shadowCtx, state, config, primary, primaryErr)
+	}()
+}
+
+// runShadow routes with the shadow config and records how it compares
+func (r *Router) runShadow(ctx context.Context, state *domain.GraphState, config *NodeConfig, primary *RoutingResult, primaryErr error) {
+	ctx, span := tracing.Tracer().Start(ctx, "router.Shadow")
+	defer span.End()
+
+	shadow, err := r.route(ctx, state, config.Shadow)
+	result := compareShadow(primary, primaryErr, shadow, err)
+	metrics.ShadowDecisions.WithLabelValues(result).Inc()
+	span.SetAttributes(attribute.String("shadow.result", result))
+
+	fields := []zap.Field{
+		zap.String("graph_id", state.GraphID),
+		zap.String("primary_mode", string(config.Mode)),
+		zap.String("shadow_mode", string(config.Shadow.Mode)),
+	}
+	if primary != nil {
+		fields = append(fields, zap.String("primary_target", primary.TargetNode), zap.String("primary_path", primary.PathTaken))
+	}
+	if shadow != nil {
+		fields = append(fields, zap.String("shadow_target", shadow.TargetNode), zap.String("shadow_path", shadow.PathTaken))
+	}
+
+	switch result {
+	case shadowMatch:
+		r.log(ctx).Debug("shadow routing matched", fields...)
+	case shadowDiverge:
+		metrics.ShadowDivergences.WithLabelValues(targetOf(config, primary), targetOf(config.Shadow, shadow)).Inc()
+		r.log(ctx).Warn("shadow routing diverged", append(fields, zap.String("shadow_reasoning", shadow.Reasoning))...)
+	default:
+		r.log(ctx).Warn("shadow routing failed", append(fields, zap.Error(err))...)
+	}
+}
+
+// compareShadow classifies a shadow decision against the primary one. A
+// shadow that succeeds where the primary failed diverges.
+func compareShadow(primary *RoutingResult, primaryErr error, shadow *RoutingResult, shadowErr error) string {
+	if shadowErr != nil || shadow == nil {
+		return shadowError
+	}
+	if primaryErr != nil || primary == nil || primary.TargetNode != shadow.TargetNode {
+		return shadowDiverge
+	}
+	return shadowMatch
+}
+
+// targetOf returns the target label of a decision made with config, or
+// "error" when there is none
+func targetOf(config *NodeConfig, result *RoutingResult) string {
+	if result == nil {
+		return shadowError
+	}
+	return targetLabel(config, result.TargetNode)
+}
+
+// targetLabel returns target as a metric label when config declares it or,
+// without declared targets, when it is one of the fixed targets of config.
+// Targets computed from requests (target_expr) are reported as
+// metrics.OtherLabel, so they cannot add series without bound.
+func targetLabel(config *NodeConfig, target string) string {
+	if len(config.Targets) > 0 {
+		if slices.Contains(config.Targets, target) {
+			return target
+		}
+		return metrics.OtherLabel
+	}
+	if fixedTarget(config, target) {
+		return target
+	}
+	return metrics.OtherLabel
+}
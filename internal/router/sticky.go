@@ -0,0 +1,28 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StickyConfig keeps routing an execution to the target a node chose for it.
+// Once the node has decided, later routing requests of the same execution
+// return the same target for TTL, unless the watched state changed.
+type StickyConfig struct {
+	TTL Duration `json:"ttl"`
+	// Watch lists the dotted state paths whose change ends stickiness (e.g.
+	// "inputs.topic"); when empty, any change to the loaded state does
+	Watch []string `json:"watch,omitempty"`
+}
+
+// sticky checks the sticky config of a node config
+func (v *configValidator) sticky(sticky *StickyConfig) {
+	if sticky.TTL <= 0 {
+		v.add("sticky.ttl", "ttl must be positive")
+	}
+	for i, path := range sticky.Watch {
+		if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") || strings.Contains(path, "..") {
+			v.add(fmt.Sprintf("sticky.watch[%d]", i), fmt.Sprintf("invalid state path %q", path))
+		}
+	}
+}
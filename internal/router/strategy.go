@@ -0,0 +1,163 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// Strategy routes the node configs of one routing mode. Custom strategies
+// read their settings from NodeConfig.Config and must return the fallback
+// route, with PathTaken "fallback", when they cannot decide.
+type Strategy interface {
+	Route(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error)
+}
+
+// StrategyValidator is implemented by strategies that check their node
+// configs before routing and in ValidateNodeConfig
+type StrategyValidator interface {
+	Validate(config *NodeConfig) []ValidationError
+}
+
+// StrategyFunc adapts a function to the Strategy interface
+type StrategyFunc func(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error)
+
+// Route calls f
+func (f StrategyFunc) Route(ctx context.Context, state *domain.GraphState, config *NodeConfig) (*RoutingResult, error) {
+	return f(ctx, state, config)
+}
+
+// StrategyRegistry holds routing strategies by mode
+type StrategyRegistry struct {
+	mu         sync.RWMutex
+	strategies map[RoutingMode]Strategy
+}
+
+// NewStrategyRegistry creates an empty strategy registry
+func NewStrategyRegistry() *StrategyRegistry {
+	return &StrategyRegistry{strategies: make(map[RoutingMode]Strategy)}
+}
+
+// DefaultStrategies is the registry every router created afterwards takes
+// its custom strategies from
+var DefaultStrategies = NewStrategyRegistry()
+
+// builtinModes are the modes implemented by the router itself, which
+// strategies cannot replace
+var builtinModes = map[RoutingMode]bool{
+	ModeDeterministic: true,
+	ModeLLM:           true,
+	ModeHybrid:        true,
+	ModeClassifier:    true,
+	ModeKeyword:       true,
+	ModeEmbedding:     true,
+	ModePipeline:      true,
+}
+
+// Register adds the strategy of a mode. Built-in modes and modes already
+// registered are rejected.
+func (s *StrategyRegistry) Register(mode RoutingMode, strategy Strategy) error {
+	if mode == "" {
+		return fmt.Errorf("strategy mode is required")
+	}
+	if strategy == nil {
+		return fmt.Errorf("strategy %s is nil", mode)
+	}
+	if builtinModes[mode] {
+		return fmt.Errorf("strategy %s: %s is a built-in routing mode", mode, mode)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.strategies[mode]; ok {
+		return fmt.Errorf("strategy %s is already registered", mode)
+	}
+	s.strategies[mode] = strategy
+	return nil
+}
+
+// Lookup returns the strategy of a mode
+func (s *StrategyRegistry) Lookup(mode RoutingMode) (Strategy, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	strategy, ok := s.strategies[mode]
+	return strategy, ok
+}
+
+// Modes returns the registered modes in name order
+func (s *StrategyRegistry) Modes() []RoutingMode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	modes := make([]RoutingMode, 0, len(s.strategies))
+	for mode := range s.strategies {
+		modes = append(modes, mode)
+	}
+	sort.Slice(modes, func(a, b int) bool { return modes[a] < modes[b] })
+	return modes
+}
+
+// RegisterStrategy adds the strategy of a mode to DefaultStrategies
+func RegisterStrategy(mode RoutingMode, strategy Strategy) error {
+	return DefaultStrategies.Register(mode, strategy)
+}
+
+// WithStrategy adds the strategy of a mode to this router only. It is
+// ignored, with a warning, when the mode is built in or already registered.
+func WithStrategy(mode RoutingMode, strategy Strategy) Option {
+	return func(r *Router) {
+		r.customStrategies = append(r.customStrategies, registeredStrategy{mode, strategy})
+	}
+}
+
+// registeredStrategy is a strategy added with WithStrategy
+type registeredStrategy struct {
+	mode     RoutingMode
+	strategy Strategy
+}
+
+// initStrategies registers the built-in strategies, then those of
+// DefaultStrategies and of WithStrategy options
+func (r *Router) initStrategies() {
+	r.strategies = NewStrategyRegistry()
+	r.strategies.strategies[ModeDeterministic] = StrategyFunc(r.routeDeterministic)
+	r.strategies.strategies[ModeLLM] = StrategyFunc(r.routeLLM)
+	r.strategies.strategies[ModeHybrid] = StrategyFunc(r.routeHybrid)
+	r.strategies.strategies[ModeClassifier] = StrategyFunc(r.routeClassifier)
+	r.strategies.strategies[ModeKeyword] = StrategyFunc(r.routeKeyword)
+	r.strategies.strategies[ModeEmbedding] = StrategyFunc(r.routeEmbedding)
+	r.strategies.strategies[ModePipeline] = StrategyFunc(r.routePipeline)
+
+	custom := make([]registeredStrategy, 0, len(r.customStrategies))
+	for _, mode := range DefaultStrategies.Modes() {
+		strategy, _ := DefaultStrategies.Lookup(mode)
+		custom = append(custom, registeredStrategy{mode, strategy})
+	}
+	for _, c := range append(custom, r.customStrategies...) {
+		if err := r.strategies.Register(c.mode, c.strategy); err != nil {
+			r.logger.Warn("ignoring routing strategy: " + err.Error())
+		}
+	}
+}
+
+// customStrategy returns the strategy of a mode that is not built in
+func (r *Router) customStrategy(mode RoutingMode) (Strategy, bool) {
+	if builtinModes[mode] {
+		return nil, false
+	}
+	return r.strategies.Lookup(mode)
+}
+
+// validateCustom checks a config of a custom mode with its strategy
+func (r *Router) validateCustom(config *NodeConfig) []ValidationError {
+	strategy, ok := r.customStrategy(config.Mode)
+	if !ok {
+		return nil
+	}
+	if validator, ok := strategy.(StrategyValidator); ok {
+		return validator.Validate(config)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+)
+
+// StreamingClient is implemented by LLM clients that stream completions.
+// Label answers from streaming clients are matched while they arrive, and the
+// generation is canceled once the answer has settled on a route key.
+type StreamingClient interface {
+	StreamComplete(ctx context.Context, req ports.CompletionRequest) (<-chan ports.CompletionChunk, error)
+}
+
+// WithStreaming streams label answers from LLM clients that support it and
+// stops each generation as soon as its route is determined
+func WithStreaming(enabled bool) Option {
+	return func(r *Router) {
+		r.streaming = enabled
+	}
+}
+
+// earlyStopRoutes returns the routes a streamed answer of llmConfig can be
+// settled against, or nil when the whole answer is needed: structured and
+// numeric answers are parsed as a whole, and strict answers must be exactly
+// one route key
+func (r *Router) earlyStopRoutes(routes map[string]string, llmConfig *LLMConfig) map[string]string {
+	if !r.streaming || llmConfig.StructuredOutput || llmConfig.AnswerType == AnswerNumber {
+		return nil
+	}
+	if guard := r.guardFor(llmConfig); guard != nil && guard.StrictAnswers {
+		return nil
+	}
+	return routes
+}
+
+// complete generates a completion, streaming it when routes can settle the
+// answer early and the client supports streaming
+func (r *Router) complete(ctx context.Context, binding *LLMBinding, req *domain.LLMRequest, routes map[string]string) (interface{}, error) {
+	client, ok := binding.Client.(StreamingClient)
+	if !ok || len(routes) == 0 {
+		return binding.Client.GenerateCompletion(ctx, req)
+	}
+	return streamCompletion(ctx, client, req, routes)
+}
+
+// streamCompletion reads a streamed completion until it ends or its answer
+// settles on a route key, then cancels the rest of the generation
+func streamCompletion(ctx context.Context, client StreamingClient, req *domain.LLMRequest, routes map[string]string) (*domain.LLMResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	messages := make([]ports.Message, len(req.Messages))
+	for i, message := range req.Messages {
+		messages[i] = ports.Message{Role: message.Role, Content: message.Content}
+	}
+	chunks, err := client.StreamComplete(ctx, ports.CompletionRequest{
+		Model:     req.Model,
+		Messages:  messages,
+		MaxTokens: req.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var answer strings.Builder
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				return &domain.LLMResponse{Content: answer.String(), Model: req.Model}, nil
+			}
+			answer.WriteString(chunk.Delta)
+			if chunk.IsFinal {
+				return &domain.LLMResponse{Content: answer.String(), Model: req.Model}, nil
+			}
+			if settled(answer.String(), routes) {
+				metrics.LLMStreamEarlyStops.WithLabelValues(req.Model).Inc()
+				return &domain.LLMResponse{Content: answer.String(), Model: req.Model}, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// settled reports whether a partial answer has settled on a route key: it
+// starts with a complete key followed by a word boundary, and no longer key
+// could still be the one being written
+func settled(partial string, routes map[string]string) bool {
+	answer := strings.ToLower(strings.TrimLeft(partial, " \t\r\n`\"'*"))
+	matched := false
+	for key := range routes {
+		key = strings.ToLower(key)
+		switch {
+		case len(answer) > len(key) && strings.HasPrefix(answer, key):
+			r := []rune(answer[len(key):])[0]
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+				matched = true
+			}
+		case strings.HasPrefix(key, answer):
+			// The answer may still grow into this key
+			return false
+		}
+	}
+	return matched
+}
@@ -0,0 +1,154 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// structuredResponse is the JSON object requested from the LLM in structured output mode
+type structuredResponse struct {
+	Route      answerValue `json:"route"`
+	Confidence *float64    `json:"confidence"`
+	Reasoning  string      `json:"reasoning"`
+}
+
+// routeMatch is the outcome of interpreting one LLM response
+type routeMatch struct {
+	// Label is the answer as reported: the raw response, or the route key in
+	// structured output mode
+	Label      string
+	Target     string
+	Matched    bool
+	Confidence float64
+	Reasoning  string
+	// Reason explains why a response was rejected
+	Reason string
+	// LowConfidence is set when a matching route was rejected for reporting
+	// a confidence below MinConfidence
+	LowConfidence bool
+}
+
+// withOutputFormat appends structured output instructions to the prompt when enabled
+func withOutputFormat(prompt string, routes map[string]string, llmConfig *LLMConfig) string {
+	if !llmConfig.StructuredOutput {
+		return prompt
+	}
+
+	return fmt.Sprintf("%s\n\nRespond with only a JSON object of the form "+
+		`{"route": "<route>", "confidence": <0.0-1.0>, "reasoning": "<short explanation>"}`+
+		" where route is %s", prompt, answerChoices(routes, llmConfig))
+}
+
+// matchResponse maps an LLM response to a route, parsing and validating it
+// as JSON in structured output mode
+func (r *Router) matchResponse(response string, routes map[string]string, llmConfig *LLMConfig) routeMatch {
+	if !llmConfig.StructuredOutput {
+		guard := r.guardFor(llmConfig)
+		strict := guard != nil && guard.StrictAnswers
+		if llmConfig.isTyped() {
+			if strict && !isSingleWord(response) {
+				metrics.PromptGuardActions.WithLabelValues("rejected").Inc()
+				return routeMatch{Label: response, Reason: fmt.Sprintf("llm answer '%s' is not a single %s answer", response, llmConfig.AnswerType)}
+			}
+			return matchTypedAnswer(response, llmConfig)
+		}
+		if strict {
+			return matchStrictAnswer(response, routes)
+		}
+		target, matched := r.matchLLMResponse(response, routes)
+		return routeMatch{Label: response, Target: target, Matched: matched}
+	}
+
+	parsed, err := parseStructuredResponse(response)
+	if err != nil {
+		return routeMatch{Label: response, Reason: fmt.Sprintf("invalid structured response: %v", err)}
+	}
+	route := string(parsed.Route)
+
+	var match routeMatch
+	if llmConfig.isTyped() {
+		match = matchTypedAnswer(route, llmConfig)
+		if !match.Matched {
+			match.Reasoning = parsed.Reasoning
+			return match
+		}
+	} else {
+		// Structured routes must match a key exactly (ignoring case); no substring matching
+		target, ok := routes[route]
+		if !ok {
+			for key, candidate := range routes {
+				if strings.EqualFold(key, route) {
+					target, ok = candidate, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return routeMatch{Label: route, Reasoning: parsed.Reasoning, Reason: fmt.Sprintf("llm route '%s' is not a configured route", route)}
+		}
+		match = routeMatch{Label: route, Target: target, Matched: true}
+	}
+
+	match.Confidence = *parsed.Confidence
+	match.Reasoning = parsed.Reasoning
+	if match.Confidence < llmConfig.MinConfidence {
+		match.Matched = false
+		match.LowConfidence = true
+		match.Reason = fmt.Sprintf("llm confidence %.2f for route '%s' is below minimum %.2f", match.Confidence, match.Label, llmConfig.MinConfidence)
+	}
+
+	return match
+}
+
+// parseStructuredResponse extracts and validates the JSON object in an LLM
+// response, tolerating surrounding text and Markdown code fences
+func parseStructuredResponse(response string) (*structuredResponse, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in response")
+	}
+
+	var parsed structuredResponse
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	parsed.Route = answerValue(strings.TrimSpace(string(parsed.Route)))
+	if parsed.Route == "" {
+		return nil, fmt.Errorf("route is required")
+	}
+	if parsed.Confidence == nil {
+		return nil, fmt.Errorf("confidence is required")
+	}
+	if *parsed.Confidence < 0 || *parsed.Confidence > 1 {
+		return nil, fmt.Errorf("confidence %v is outside [0, 1]", *parsed.Confidence)
+	}
+
+	return &parsed, nil
+}
+
+// abstainResult routes a classification rejected for its low confidence to
+// the abstain target of llmConfig instead of the fallback route
+func (r *Router) abstainResult(classified *classification, llmConfig *LLMConfig, mode RoutingMode, prompt string) *RoutingResult {
+	metrics.LLMAbstentions.WithLabelValues(string(mode)).Inc()
+	r.logger.Info("llm confidence below minimum, abstaining",
+		zap.String("abstain_target", llmConfig.AbstainTarget),
+		zap.Float64("confidence", classified.Confidence),
+		zap.Float64("min_confidence", llmConfig.MinConfidence),
+	)
+	return &RoutingResult{
+		TargetNode:  llmConfig.AbstainTarget,
+		Confidence:  classified.Confidence,
+		Reasoning:   classified.rejection(),
+		Mode:        string(mode),
+		PathTaken:   "abstain",
+		PromptHash:  promptHash(prompt),
+		Stages:      classified.Stages,
+		LLMResponse: classified.Response,
+	}
+}
@@ -0,0 +1,65 @@
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// defaultTenant is the usage accounting key for traffic without a tenant mapping
+const defaultTenant = "default"
+
+// LLMBinding binds an LLM client to the provider and model it is called with
+type LLMBinding struct {
+	Provider string
+	// BaseURL is the endpoint of the client, empty for the provider default
+	BaseURL string
+	Model   string
+	Client  ports.LLMClient
+}
+
+// tenantOf returns the tenant from the execution context, falling back to the
+// configured state field
+func (r *Router) tenantOf(ctx context.Context, state *domain.GraphState) string {
+	if ec := ExecutionContextFrom(ctx); ec != nil && ec.Tenant != "" {
+		return ec.Tenant
+	}
+
+	if r.tenantField == "" || state == nil {
+		return ""
+	}
+
+	value, ok := state.Inputs[r.tenantField]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// resolveLLM returns the LLM binding for the tenant of the given state,
+// falling back to the default client. It returns nil if no client is available.
+func (r *Router) resolveLLM(ctx context.Context, state *domain.GraphState) (string, *LLMBinding) {
+	if tenant := r.tenantOf(ctx, state); tenant != "" {
+		if binding, ok := r.tenantLLMs[tenant]; ok && binding.Client != nil {
+			return tenant, binding
+		}
+	}
+
+	if r.llmClient == nil {
+		return defaultTenant, nil
+	}
+
+	return defaultTenant, &LLMBinding{
+		Provider: r.llmProvider,
+		BaseURL:  r.llmBaseURL,
+		Model:    r.llmModel,
+		Client:   r.llmClient,
+	}
+}
+
+// Usage returns LLM usage accumulated per tenant
+func (r *Router) Usage() map[string]TenantUsage {
+	return r.usage.Snapshot()
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Timings breaks down the latency of a routing decision, in milliseconds
+type Timings struct {
+	// StateLoadMS is filled in by the caller that loaded the graph state
+	StateLoadMS float64 `json:"state_load_ms"`
+	// CELMS and LLMMS are the time spent evaluating CEL conditions and
+	// waiting for LLM calls
+	CELMS float64 `json:"cel_ms"`
+	LLMMS float64 `json:"llm_ms"`
+}
+
+// Milliseconds converts d to fractional milliseconds, as used by Timings
+func Milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// timer accumulates the CEL and LLM time of one Route call. A shadow
+// evaluation keeps adding to it in the background, so the sums are atomic.
+type timer struct {
+	cel atomic.Int64
+	llm atomic.Int64
+}
+
+// timerKey is the context.Context key for the timer of the routed request
+type timerKey struct{}
+
+// withTimer returns a context carrying a new timer
+func withTimer(ctx context.Context) (context.Context, *timer) {
+	t := &timer{}
+	return context.WithValue(ctx, timerKey{}, t), t
+}
+
+// timerFrom returns the timer carried by ctx, or nil outside of Route
+func timerFrom(ctx context.Context) *timer {
+	t, _ := ctx.Value(timerKey{}).(*timer)
+	return t
+}
+
+// addCEL records time spent evaluating CEL; a nil timer ignores it
+func (t *timer) addCEL(d time.Duration) {
+	if t != nil {
+		t.cel.Add(int64(d))
+	}
+}
+
+// addLLM records time spent waiting for an LLM call; a nil timer ignores it
+func (t *timer) addLLM(d time.Duration) {
+	if t != nil {
+		t.llm.Add(int64(d))
+	}
+}
+
+// timings returns the time recorded so far
+func (t *timer) timings() *Timings {
+	return &Timings{
+		CELMS: Milliseconds(time.Duration(t.cel.Load())),
+		LLMMS: Milliseconds(time.Duration(t.llm.Load())),
+	}
+}
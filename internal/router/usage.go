@@ -0,0 +1,64 @@
+package router
+
+import (
+	"sync"
+)
+
+// TenantUsage holds LLM usage counters for a tenant
+type TenantUsage struct {
+	Calls        int64 `json:"calls"`
+	Errors       int64 `json:"errors"`
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	// EstimatedCalls counts calls whose tokens were estimated locally because
+	// the provider reported no usage
+	EstimatedCalls int64 `json:"estimated_calls"`
+}
+
+// UsageTracker accumulates LLM usage per tenant
+type UsageTracker struct {
+	usage map[string]*TenantUsage
+	mu    sync.Mutex
+}
+
+// NewUsageTracker creates a new usage tracker
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		usage: make(map[string]*TenantUsage),
+	}
+}
+
+// Record records a single LLM call for a tenant
+func (u *UsageTracker) Record(tenant string, inputTokens, outputTokens int, estimated bool, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	usage, ok := u.usage[tenant]
+	if !ok {
+		usage = &TenantUsage{}
+		u.usage[tenant] = usage
+	}
+
+	usage.Calls++
+	if err != nil {
+		usage.Errors++
+		return
+	}
+	usage.InputTokens += int64(inputTokens)
+	usage.OutputTokens += int64(outputTokens)
+	if estimated {
+		usage.EstimatedCalls++
+	}
+}
+
+// Snapshot returns a copy of the current usage per tenant
+func (u *UsageTracker) Snapshot() map[string]TenantUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	snapshot := make(map[string]TenantUsage, len(u.usage))
+	for tenant, usage := range u.usage {
+		snapshot[tenant] = *usage
+	}
+	return snapshot
+}
@@ -0,0 +1,518 @@
+package router
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aescanero/dago-node-router/internal/eval/cel"
+)
+
+// ValidationError is one problem found in a NodeConfig
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error returns the problem prefixed with its field
+func (e ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidateNodeConfig checks a routing configuration without routing: it
+// compiles every CEL condition, parses every template and checks that every
+// target is set and, when the config declares targets, is one of them. All
+// problems are returned rather than only the first; nil means the config is
+// valid. The config is not modified.
+func (r *Router) ValidateNodeConfig(config *NodeConfig) []ValidationError {
+	if config == nil {
+		return []ValidationError{{Message: "config is nil"}}
+	}
+
+	v := &configValidator{router: r}
+	if resolved, err := r.resolveRuleSets(config); err != nil {
+		v.add("rules_ref", err.Error())
+	} else {
+		config = resolved
+	}
+	if config.Fallback == "" {
+		v.add("fallback", "fallback route is required")
+	}
+
+	v.variables(config.Variables)
+
+	mode := config.Mode
+	if mode == "" {
+		mode = r.detectMode(config)
+	}
+
+	switch mode {
+	case ModeDeterministic:
+		if len(config.Rules) == 0 {
+			v.add("rules", "deterministic mode requires rules")
+		}
+		v.rules("rules", config.Rules)
+		if err := validateGroups(config.Rules, config.Groups); err != nil {
+			v.add("groups", err.Error())
+		}
+
+	case ModeLLM:
+		if config.LLMConfig == nil {
+			v.add("llm_config", "llm mode requires llm_config")
+		} else {
+			v.llmConfig("llm_config", config.LLMConfig)
+		}
+
+	case ModeHybrid:
+		if len(config.FastRules) == 0 {
+			v.add("fast_rules", "hybrid mode requires fast_rules")
+		}
+		v.rules("fast_rules", config.FastRules)
+		if config.LLMFallback == nil {
+			v.add("llm_fallback", "hybrid mode requires llm_fallback")
+		} else {
+			v.llmConfig("llm_fallback", config.LLMFallback)
+		}
+
+	case ModeClassifier:
+		if config.Classifier == nil {
+			v.add("classifier", "classifier mode requires classifier")
+		} else {
+			v.classifier("classifier", config.Classifier)
+		}
+
+	case ModeKeyword:
+		if config.Keyword == nil {
+			v.add("keyword", "keyword mode requires keyword")
+		} else {
+			v.keyword("keyword", config.Keyword)
+		}
+
+	case ModeEmbedding:
+		if config.Embedding == nil {
+			v.add("embedding", "embedding mode requires embedding")
+		} else {
+			v.embedding("embedding", config.Embedding)
+		}
+
+	case ModePipeline:
+		v.pipeline(config.Pipeline)
+
+	default:
+		if _, ok := r.customStrategy(mode); !ok {
+			v.add("mode", fmt.Sprintf("unknown routing mode: %s", mode))
+			break
+		}
+		custom := *config
+		custom.Mode = mode
+		v.errors = append(v.errors, r.validateCustom(&custom)...)
+	}
+
+	if err := cel.ValidateNumberTypes(config.NumberTypes); err != nil {
+		v.add("number_types", err.Error())
+	}
+	for i, path := range config.StatePaths {
+		if !validStatePath(path) {
+			v.add(fmt.Sprintf("state_paths[%d]", i), fmt.Sprintf("invalid state path %q", path))
+		}
+	}
+
+	v.errors = append(v.errors, undeclaredTargets(config)...)
+
+	if config.Shadow != nil {
+		if config.Shadow.Shadow != nil {
+			v.add("shadow.shadow", "shadow configs cannot have their own shadow")
+		}
+		for _, e := range r.ValidateNodeConfig(config.Shadow) {
+			if e.Field == "" {
+				e.Field = "shadow"
+			} else {
+				e.Field = "shadow." + e.Field
+			}
+			v.errors = append(v.errors, e)
+		}
+	}
+
+	if config.Experiment != nil {
+		v.experiment(config.Experiment)
+	}
+	if len(config.Lookups) > 0 {
+		v.lookups(config.Lookups)
+	}
+	if len(config.Schedule) > 0 {
+		v.schedule(config.Schedule)
+	}
+	if config.Sticky != nil {
+		v.sticky(config.Sticky)
+	}
+
+	return v.errors
+}
+
+// configValidator accumulates the problems found in a NodeConfig
+type configValidator struct {
+	router *Router
+	// declared are the variables the config declares for its rules
+	declared cel.Declarations
+	errors   []ValidationError
+}
+
+// add records a problem
+func (v *configValidator) add(field, message string) {
+	v.errors = append(v.errors, ValidationError{Field: field, Message: message})
+}
+
+// experiment checks the experiment of a node config and its variant
+func (v *configValidator) experiment(experiment *Experiment) {
+	if experiment.ID == "" {
+		v.add("experiment.id", "experiment ID is required")
+	}
+	if experiment.Split < 0 || experiment.Split > 100 {
+		v.add("experiment.split", fmt.Sprintf("split %g must be between 0 and 100", experiment.Split))
+	}
+	if experiment.Variant == nil {
+		v.add("experiment.variant", "variant config is required")
+		return
+	}
+	if experiment.Variant.Experiment != nil {
+		v.add("experiment.variant.experiment", "variant configs cannot run their own experiment")
+	}
+	if experiment.Variant.Shadow != nil {
+		v.add("experiment.variant.shadow", "variant configs cannot have a shadow")
+	}
+	for _, e := range v.router.ValidateNodeConfig(experiment.Variant) {
+		if e.Field == "" {
+			e.Field = "experiment.variant"
+		} else {
+			e.Field = "experiment.variant." + e.Field
+		}
+		v.errors = append(v.errors, e)
+	}
+}
+
+// rules checks rule conditions and targets
+func (v *configValidator) rules(field string, rules []Rule) {
+	for i, rule := range rules {
+		ruleField := fmt.Sprintf("%s[%d]", field, i)
+		switch {
+		case rule.Target == "" && rule.TargetExpr == "":
+			v.add(ruleField+".target", "target or target_expr is required")
+		case rule.Target != "" && rule.TargetExpr != "":
+			v.add(ruleField+".target_expr", "target and target_expr are mutually exclusive")
+		case rule.TargetExpr != "":
+			if err := v.router.celEvaluator.ValidateDeclaredStringExpression(v.declared, rule.TargetExpr); err != nil {
+				v.add(ruleField+".target_expr", err.Error())
+			}
+		}
+		if rule.Condition == "" {
+			v.add(ruleField+".condition", "condition is required")
+			continue
+		}
+		if err := v.router.celEvaluator.ValidateDeclaredExpression(v.declared, rule.Condition); err != nil {
+			v.add(ruleField+".condition", err.Error())
+		}
+	}
+}
+
+// llmConfig checks an LLM classification config
+func (v *configValidator) llmConfig(field string, llmConfig *LLMConfig) {
+	renderer, err := v.router.renderer(llmConfig.TemplateEngine)
+	if err != nil {
+		v.add(field+".template_engine", err.Error())
+	}
+	if llmConfig.PromptTemplate == "" && llmConfig.TemplateRef == "" {
+		v.add(field+".prompt_template", "prompt_template or template_ref is required")
+	} else if renderer != nil {
+		if promptField, err := v.router.validatePrompt(renderer, llmConfig.PromptTemplate, llmConfig.TemplateRef); err != nil {
+			v.add(field+"."+promptField, err.Error())
+		}
+	}
+
+	if llmConfig.Timeout < 0 {
+		v.add(field+".timeout", "timeout must not be negative")
+	}
+	if llmConfig.Guard != nil {
+		llmConfig.Guard.validate(field+".guard", v)
+	}
+	v.examples(field, llmConfig)
+	v.promptBudget(field, llmConfig)
+	if llmConfig.History != nil {
+		v.history(field+".history", llmConfig.History)
+	}
+
+	if llmConfig.MinConfidence < 0 || llmConfig.MinConfidence > 1 {
+		v.add(field+".min_confidence", "min_confidence must be between 0 and 1")
+	}
+	if llmConfig.MinConfidence > 0 && !llmConfig.StructuredOutput {
+		v.add(field+".min_confidence", "min_confidence requires structured_output")
+	}
+	if llmConfig.AbstainTarget != "" && llmConfig.MinConfidence == 0 {
+		v.add(field+".abstain_target", "abstain_target requires min_confidence")
+	}
+
+	switch llmConfig.AnswerType {
+	case "", AnswerLabel:
+	case AnswerBoolean, AnswerNumber:
+		if err := validateTypedAnswers(field, llmConfig); err != nil {
+			v.add(field, err.Error())
+		}
+		return
+	default:
+		v.add(field+".answer_type", fmt.Sprintf("answer_type '%s' is not supported", llmConfig.AnswerType))
+		return
+	}
+
+	if len(llmConfig.Categories) == 0 {
+		if len(llmConfig.Routes) == 0 {
+			v.add(field+".routes", "routes is required")
+		}
+		v.routes(field+".routes", llmConfig.Routes)
+		return
+	}
+
+	for _, name := range sortedKeys(llmConfig.Categories) {
+		category := llmConfig.Categories[name]
+		categoryField := fmt.Sprintf("%s.categories.%s", field, name)
+		if category == nil || len(category.Routes) == 0 {
+			v.add(categoryField+".routes", "routes is required")
+			continue
+		}
+		if (category.PromptTemplate != "" || category.TemplateRef != "") && renderer != nil {
+			if promptField, err := v.router.validatePrompt(renderer, category.PromptTemplate, category.TemplateRef); err != nil {
+				v.add(categoryField+"."+promptField, err.Error())
+			}
+		}
+		v.routes(categoryField+".routes", category.Routes)
+	}
+}
+
+// routes checks that every LLM route has a target
+func (v *configValidator) routes(field string, routes map[string]string) {
+	for _, key := range sortedKeys(routes) {
+		if routes[key] == "" {
+			v.add(field+"."+key, "target is required")
+		}
+	}
+}
+
+// undeclaredTargets reports targets missing from the declared targets of a
+// config; configs without declared targets accept any target
+func undeclaredTargets(config *NodeConfig) []ValidationError {
+	if len(config.Targets) == 0 {
+		return nil
+	}
+	declared := toSet(config.Targets)
+
+	var errs []ValidationError
+	check := func(field, target string) {
+		if target != "" && !declared[target] {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("target '%s' is not a declared target", target),
+			})
+		}
+	}
+
+	check("fallback", config.Fallback)
+	for i, window := range config.Schedule {
+		check(fmt.Sprintf("schedule[%d].target", i), window.Target)
+	}
+	for i, rule := range config.Rules {
+		check(fmt.Sprintf("rules[%d].target", i), rule.Target)
+	}
+	for i, rule := range config.FastRules {
+		check(fmt.Sprintf("fast_rules[%d].target", i), rule.Target)
+	}
+	for field, llmConfig := range map[string]*LLMConfig{"llm_config": config.LLMConfig, "llm_fallback": config.LLMFallback} {
+		if llmConfig == nil {
+			continue
+		}
+		for _, key := range sortedKeys(llmConfig.Routes) {
+			check(field+".routes."+key, llmConfig.Routes[key])
+		}
+		for _, name := range sortedKeys(llmConfig.Categories) {
+			if category := llmConfig.Categories[name]; category != nil {
+				for _, key := range sortedKeys(category.Routes) {
+					check(fmt.Sprintf("%s.categories.%s.routes.%s", field, name, key), category.Routes[key])
+				}
+			}
+		}
+		for i, rng := range llmConfig.Ranges {
+			check(fmt.Sprintf("%s.ranges[%d].target", field, i), rng.Target)
+		}
+		check(field+".abstain_target", llmConfig.AbstainTarget)
+	}
+	if config.Classifier != nil {
+		for _, label := range sortedKeys(config.Classifier.Routes) {
+			check("classifier.routes."+label, config.Classifier.Routes[label])
+		}
+	}
+	if config.Keyword != nil {
+		for i, route := range config.Keyword.Routes {
+			check(fmt.Sprintf("keyword.routes[%d].target", i), route.Target)
+		}
+	}
+	if config.Embedding != nil {
+		for i, route := range config.Embedding.Routes {
+			check(fmt.Sprintf("embedding.routes[%d].target", i), route.Target)
+		}
+	}
+	for i, stage := range config.Pipeline {
+		stageField := fmt.Sprintf("pipeline[%d]", i)
+		for j, rule := range stage.Rules {
+			check(fmt.Sprintf("%s.rules[%d].target", stageField, j), rule.Target)
+		}
+		if stage.Keyword != nil {
+			for j, route := range stage.Keyword.Routes {
+				check(fmt.Sprintf("%s.keyword.routes[%d].target", stageField, j), route.Target)
+			}
+		}
+		if stage.Embedding != nil {
+			for j, route := range stage.Embedding.Routes {
+				check(fmt.Sprintf("%s.embedding.routes[%d].target", stageField, j), route.Target)
+			}
+		}
+		if stage.Classifier != nil {
+			for _, label := range sortedKeys(stage.Classifier.Routes) {
+				check(stageField+".classifier.routes."+label, stage.Classifier.Routes[label])
+			}
+		}
+		if stage.LLM != nil {
+			for _, key := range sortedKeys(stage.LLM.Routes) {
+				check(stageField+".llm.routes."+key, stage.LLM.Routes[key])
+			}
+			check(stageField+".llm.abstain_target", stage.LLM.AbstainTarget)
+		}
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// fixedTarget reports whether target is one of the targets written in config
+// (fallback, schedule, rule and route targets), as opposed to one computed by
+// a target_expr
+func fixedTarget(config *NodeConfig, target string) bool {
+	if target == config.Fallback {
+		return true
+	}
+	for _, window := range config.Schedule {
+		if window.Target == target {
+			return true
+		}
+	}
+	if ruleTarget(config.Rules, target) || ruleTarget(config.FastRules, target) {
+		return true
+	}
+	for _, llmConfig := range []*LLMConfig{config.LLMConfig, config.LLMFallback} {
+		if llmTarget(llmConfig, target) {
+			return true
+		}
+	}
+	if config.Classifier != nil && routeTarget(config.Classifier.Routes, target) {
+		return true
+	}
+	if config.Keyword != nil && keywordTarget(config.Keyword, target) {
+		return true
+	}
+	if config.Embedding != nil && embeddingTarget(config.Embedding, target) {
+		return true
+	}
+	for _, stage := range config.Pipeline {
+		if ruleTarget(stage.Rules, target) || llmTarget(stage.LLM, target) {
+			return true
+		}
+		if stage.Keyword != nil && keywordTarget(stage.Keyword, target) {
+			return true
+		}
+		if stage.Embedding != nil && embeddingTarget(stage.Embedding, target) {
+			return true
+		}
+		if stage.Classifier != nil && routeTarget(stage.Classifier.Routes, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleTarget reports whether a rule has the fixed target
+func ruleTarget(rules []Rule, target string) bool {
+	for _, rule := range rules {
+		if rule.Target == target && rule.TargetExpr == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// llmTarget reports whether an LLM config (may be nil) routes to target
+func llmTarget(llmConfig *LLMConfig, target string) bool {
+	if llmConfig == nil {
+		return false
+	}
+	if llmConfig.AbstainTarget == target || routeTarget(llmConfig.Routes, target) {
+		return true
+	}
+	for _, category := range llmConfig.Categories {
+		if category != nil && routeTarget(category.Routes, target) {
+			return true
+		}
+	}
+	for _, rng := range llmConfig.Ranges {
+		if rng.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// keywordTarget reports whether a keyword route has the target
+func keywordTarget(keyword *KeywordConfig, target string) bool {
+	for _, route := range keyword.Routes {
+		if route.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddingTarget reports whether an embedding route has the target
+func embeddingTarget(cfg *EmbeddingConfig, target string) bool {
+	for _, route := range cfg.Routes {
+		if route.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// routeTarget reports whether routes map a key to target
+func routeTarget(routes map[string]string, target string) bool {
+	for _, routed := range routes {
+		if routed == target {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a map in a stable order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toSet builds a lookup set from names
+func toSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
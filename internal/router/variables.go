@@ -0,0 +1,161 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-node-router/internal/eval/cel"
+	"go.uber.org/zap"
+)
+
+// Variable declares a typed CEL variable projected from the state. Rules
+// using it are type-checked against its type when the config is validated.
+type Variable struct {
+	Type cel.VarType `json:"type"`
+	// Path is the dotted state path of the value (inputs.<name> when unset)
+	Path string `json:"path,omitempty"`
+}
+
+// UnmarshalJSON accepts a type name alone ("double") as a variable read from
+// inputs.<name>
+func (v *Variable) UnmarshalJSON(data []byte) error {
+	var typeName string
+	if err := json.Unmarshal(data, &typeName); err == nil {
+		*v = Variable{Type: cel.VarType(typeName)}
+		return nil
+	}
+
+	type variable Variable
+	var decoded variable
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*v = Variable(decoded)
+	return nil
+}
+
+// path returns the state path of the variable named name
+func (v Variable) path(name string) string {
+	if v.Path != "" {
+		return v.Path
+	}
+	return "inputs." + name
+}
+
+// declarations returns the CEL declarations of the config variables
+func (c *NodeConfig) declarations() cel.Declarations {
+	if len(c.Variables) == 0 {
+		return nil
+	}
+	declared := make(cel.Declarations, len(c.Variables))
+	for name, variable := range c.Variables {
+		declared[name] = variable.Type
+	}
+	return declared
+}
+
+// variablePaths returns the state paths the config variables are read from
+func (c *NodeConfig) variablePaths() []string {
+	paths := make([]string, 0, len(c.Variables))
+	for name, variable := range c.Variables {
+		paths = append(paths, variable.path(name))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// declarationsKey is the context.Context key for the variables declared by
+// the config being routed
+type declarationsKey struct{}
+
+// withDeclarations returns a context carrying the variables declared by config
+func withDeclarations(ctx context.Context, config *NodeConfig) context.Context {
+	return context.WithValue(ctx, declarationsKey{}, config.declarations())
+}
+
+// declarationsFrom returns the declared variables carried by ctx, or nil
+func declarationsFrom(ctx context.Context) cel.Declarations {
+	declared, _ := ctx.Value(declarationsKey{}).(cel.Declarations)
+	return declared
+}
+
+// projectVariables adds the config variables to the CEL variables, read
+// from the state map. Values that are missing or cannot be converted to
+// their type are left unset, so only rules that use them fail.
+func (r *Router) projectVariables(ctx context.Context, config *NodeConfig, numbers cel.NumberCoercion, state map[string]interface{}, vars map[string]interface{}) {
+	for name, variable := range config.Variables {
+		path := variable.path(name)
+		value, ok := lookupPath(state, path)
+		if !ok {
+			r.log(ctx).Debug("declared variable not found in state",
+				zap.String("variable", name),
+				zap.String("path", path),
+			)
+			continue
+		}
+		converted, err := variable.Type.Convert(value)
+		if err != nil {
+			r.log(ctx).Warn("declared variable has the wrong type",
+				zap.String("variable", name),
+				zap.String("path", path),
+				zap.Error(err),
+			)
+			continue
+		}
+		switch variable.Type {
+		case cel.VarList, cel.VarMap, cel.VarDyn:
+			converted = numbers.Bind(converted, path)
+		}
+		vars[name] = converted
+	}
+}
+
+// lookupPath returns the value at a dotted path of nested maps
+func lookupPath(root interface{}, path string) (interface{}, bool) {
+	node := root
+	for _, segment := range strings.Split(path, ".") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if node, ok = m[segment]; !ok {
+			return nil, false
+		}
+	}
+	return node, node != nil
+}
+
+// variables checks the variables of a node config, declaring the valid ones
+// for the rules checked after it
+func (v *configValidator) variables(variables map[string]Variable) {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := fmt.Sprintf("variables.%s", name)
+		declared := cel.Declarations{name: variables[name].Type}
+		if err := declared.Validate(); err != nil {
+			v.add(field, err.Error())
+		} else {
+			if v.declared == nil {
+				v.declared = make(cel.Declarations, len(variables))
+			}
+			v.declared[name] = variables[name].Type
+		}
+		if path := variables[name].Path; path != "" && !validStatePath(path) {
+			v.add(field+".path", fmt.Sprintf("invalid state path %q", path))
+		}
+	}
+}
+
+// validStatePath reports whether path is a dotted state path without empty
+// segments
+func validStatePath(path string) bool {
+	return path != "" && !strings.HasPrefix(path, ".") && !strings.HasSuffix(path, ".") && !strings.Contains(path, "..")
+}
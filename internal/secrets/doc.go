@@ -0,0 +1,18 @@
+// Package secrets fetches secrets from HashiCorp Vault.
+//
+// A Vault client logs in with a static token or with Kubernetes auth, using
+// the pod service account token, and reads fields of KV version 1 or 2
+// secrets over the Vault HTTP API. Watch keeps the token renewed before it
+// expires, logging in again when renewal fails, and re-reads a secret
+// periodically so rotated values such as the LLM API key take effect
+// without a restart.
+//
+// Example usage:
+//
+//	vault := secrets.NewVault(secrets.VaultConfig{
+//	    Addr: "https://vault:8200",
+//	    Role: "dago-router",
+//	}, logger)
+//	key, err := vault.Read(ctx, "secret/data/dago-router", "api_key")
+//	go vault.Watch(ctx, "secret/data/dago-router", "api_key", 5*time.Minute, key, onRotate)
+package secrets
@@ -0,0 +1,319 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// DefaultServiceAccountTokenPath is where Kubernetes mounts the pod service
+// account token used for Kubernetes auth
+const DefaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultConfig configures the Vault client
+type VaultConfig struct {
+	// Addr is the Vault server address, e.g. https://vault:8200
+	Addr string
+	// Token is a static Vault token; ignored when Role is set
+	Token string
+	// Role is the Kubernetes auth role to log in with
+	Role string
+	// Mount is the Kubernetes auth mount path (default "kubernetes")
+	Mount string
+	// JWTPath is the service account token file (default
+	// DefaultServiceAccountTokenPath)
+	JWTPath string
+	// HTTPClient is used for requests (default a client with a 10s timeout)
+	HTTPClient *http.Client
+}
+
+// Vault reads secrets from Vault and keeps its token renewed
+type Vault struct {
+	config VaultConfig
+	client *http.Client
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	token     string
+	renewable bool
+	// renewAt is half way through the token lease; zero when it never expires
+	renewAt time.Time
+}
+
+// NewVault creates a Vault client. It logs in on the first request.
+func NewVault(config VaultConfig, logger *zap.Logger) *Vault {
+	if config.Mount == "" {
+		config.Mount = "kubernetes"
+	}
+	if config.JWTPath == "" {
+		config.JWTPath = DefaultServiceAccountTokenPath
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Vault{
+		config: config,
+		client: client,
+		logger: logger,
+	}
+}
+
+// authInfo is the auth section of Vault login and renewal responses
+type authInfo struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int    `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+// vaultResponse is the envelope of Vault API responses
+type vaultResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Auth   *authInfo              `json:"auth"`
+	Errors []string               `json:"errors"`
+}
+
+// Read returns a field of the secret at path, e.g. "secret/data/router" for
+// a KV version 2 mount named secret
+func (v *Vault) Read(ctx context.Context, path, field string) (string, error) {
+	if err := v.ensureToken(ctx); err != nil {
+		return "", err
+	}
+
+	resp, err := v.do(ctx, http.MethodGet, "/v1/"+strings.TrimPrefix(path, "/"), nil, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault secret %s: %w", path, err)
+	}
+
+	data := resp.Data
+	// KV version 2 nests the secret under data.data, next to its metadata
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = inner
+		}
+	}
+
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no field %s", path, field)
+	}
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("vault secret %s field %s is not a non-empty string", path, field)
+	}
+	return s, nil
+}
+
+// Watch re-reads a secret field every interval and calls onChange when it
+// differs from the last value, starting from current. Between reads the
+// token is renewed at half its lifetime, logging in again when renewal
+// fails. Watch returns when ctx is cancelled.
+func (v *Vault) Watch(ctx context.Context, path, field string, interval time.Duration, current string, onChange func(string)) {
+	nextRead := time.Now().Add(interval)
+	for {
+		wait := time.Until(nextRead)
+		if renewAt, ok := v.renewalDue(); ok && time.Until(renewAt) < wait {
+			wait = time.Until(renewAt)
+		}
+		if wait < time.Second {
+			wait = time.Second
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if renewAt, ok := v.renewalDue(); ok && !time.Now().Before(renewAt) {
+			v.renew(ctx)
+		}
+
+		if time.Now().Before(nextRead) {
+			continue
+		}
+		nextRead = time.Now().Add(interval)
+
+		value, err := v.Read(ctx, path, field)
+		if err != nil {
+			metrics.VaultRefreshes.WithLabelValues("error").Inc()
+			v.logger.Warn("failed to refresh vault secret, keeping the current value",
+				zap.String("path", path),
+				zap.Error(err),
+			)
+			continue
+		}
+		metrics.VaultRefreshes.WithLabelValues("success").Inc()
+		if value != current {
+			current = value
+			v.logger.Info("vault secret rotated", zap.String("path", path))
+			onChange(value)
+		}
+	}
+}
+
+// renewalDue returns when the token should be renewed, if it expires
+func (v *Vault) renewalDue() (time.Time, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.token == "" || v.renewAt.IsZero() {
+		return time.Time{}, false
+	}
+	if !v.renewable && v.config.Role == "" {
+		return time.Time{}, false
+	}
+	return v.renewAt, true
+}
+
+// renew extends the token lease, logging in again when the token cannot be
+// renewed
+func (v *Vault) renew(ctx context.Context) {
+	v.mu.Lock()
+	renewable := v.renewable
+	v.mu.Unlock()
+
+	if renewable {
+		resp, err := v.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", nil, true)
+		if err == nil && resp.Auth != nil {
+			v.setToken(resp.Auth)
+			v.logger.Debug("vault token renewed", zap.Int("lease_seconds", resp.Auth.LeaseDuration))
+			return
+		}
+		v.logger.Warn("failed to renew vault token", zap.Error(err))
+	}
+
+	if v.config.Role == "" {
+		return
+	}
+	if err := v.login(ctx); err != nil {
+		v.logger.Error("failed to log in to vault", zap.Error(err))
+	}
+}
+
+// ensureToken logs in when no token is held yet
+func (v *Vault) ensureToken(ctx context.Context) error {
+	v.mu.Lock()
+	hasToken := v.token != ""
+	v.mu.Unlock()
+	if hasToken {
+		return nil
+	}
+
+	if v.config.Role != "" {
+		return v.login(ctx)
+	}
+	if v.config.Token == "" {
+		return fmt.Errorf("vault token or kubernetes role is required")
+	}
+
+	v.mu.Lock()
+	v.token = v.config.Token
+	v.mu.Unlock()
+
+	// Look up the static token so Watch can renew it before it expires
+	resp, err := v.do(ctx, http.MethodGet, "/v1/auth/token/lookup-self", nil, true)
+	if err != nil {
+		v.logger.Warn("failed to look up vault token, it will not be renewed", zap.Error(err))
+		return nil
+	}
+	ttl, _ := resp.Data["ttl"].(float64)
+	renewable, _ := resp.Data["renewable"].(bool)
+	v.setToken(&authInfo{ClientToken: v.config.Token, LeaseDuration: int(ttl), Renewable: renewable})
+	return nil
+}
+
+// login authenticates with Kubernetes auth
+func (v *Vault) login(ctx context.Context) error {
+	jwt, err := os.ReadFile(v.config.JWTPath)
+	if err != nil {
+		return fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	body := map[string]string{
+		"role": v.config.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}
+	resp, err := v.do(ctx, http.MethodPost, "/v1/auth/"+v.config.Mount+"/login", body, false)
+	if err != nil {
+		return fmt.Errorf("vault kubernetes login failed: %w", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return fmt.Errorf("vault kubernetes login returned no token")
+	}
+
+	v.setToken(resp.Auth)
+	v.logger.Info("logged in to vault",
+		zap.String("role", v.config.Role),
+		zap.Int("lease_seconds", resp.Auth.LeaseDuration),
+	)
+	return nil
+}
+
+// setToken stores a token and its lease
+func (v *Vault) setToken(auth *authInfo) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if auth.ClientToken != "" {
+		v.token = auth.ClientToken
+	}
+	v.renewable = auth.Renewable
+	v.renewAt = time.Time{}
+	if auth.LeaseDuration > 0 {
+		v.renewAt = time.Now().Add(time.Duration(auth.LeaseDuration) * time.Second / 2)
+	}
+}
+
+// do sends a Vault API request and decodes the response
+func (v *Vault) do(ctx context.Context, method, path string, body interface{}, authenticated bool) (*vaultResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(v.config.Addr, "/")+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if authenticated {
+		v.mu.Lock()
+		req.Header.Set("X-Vault-Token", v.token)
+		v.mu.Unlock()
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp vaultResponse
+	if err := json.NewDecoder(io.LimitReader(httpResp.Body, 1<<20)).Decode(&resp); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid vault response (status %d): %w", httpResp.StatusCode, err)
+	}
+	if httpResp.StatusCode >= 300 {
+		if len(resp.Errors) > 0 {
+			return nil, fmt.Errorf("vault returned status %d: %s", httpResp.StatusCode, strings.Join(resp.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault returned status %d", httpResp.StatusCode)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,199 @@
+package staleness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// EventTypeConfigStale is the type of events published for stale sources
+const EventTypeConfigStale ports.EventType = "config.stale"
+
+// Reasons a source is stale
+const (
+	ReasonDeleted  = "deleted"
+	ReasonModified = "modified"
+	ReasonExpired  = "expired"
+)
+
+// reasons lists every reason, for resetting metrics
+var reasons = []string{ReasonDeleted, ReasonModified, ReasonExpired}
+
+// Probe reports whether a source still exists and when it last changed
+type Probe func(ctx context.Context) (exists bool, modified time.Time, err error)
+
+// FileProbe probes a file on disk
+func FileProbe(path string) Probe {
+	return func(ctx context.Context) (bool, time.Time, error) {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return false, time.Time{}, nil
+		}
+		if err != nil {
+			return false, time.Time{}, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		return true, info.ModTime(), nil
+	}
+}
+
+// source is a tracked configuration source
+type source struct {
+	name     string
+	loadedAt time.Time
+	probe    Probe
+	reason   string
+}
+
+// Checker reports configuration sources that went stale after loading
+type Checker struct {
+	maxAge  time.Duration
+	bus     ports.EventBus
+	topic   string
+	logger  *zap.Logger
+	sources map[string]*source
+	mu      sync.Mutex
+}
+
+// NewChecker creates a checker. A zero maxAge disables the age check; a nil
+// bus only logs and exports metrics.
+func NewChecker(maxAge time.Duration, bus ports.EventBus, topic string, logger *zap.Logger) *Checker {
+	return &Checker{
+		maxAge:  maxAge,
+		bus:     bus,
+		topic:   topic,
+		logger:  logger,
+		sources: make(map[string]*source),
+	}
+}
+
+// Track starts checking a source loaded at loadedAt. Tracking a name again
+// replaces it, e.g. after a reload.
+func (c *Checker) Track(name string, loadedAt time.Time, probe Probe) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sources[name] = &source{name: name, loadedAt: loadedAt, probe: probe}
+	setStale(name, "")
+}
+
+// Run checks all sources every interval until ctx is cancelled
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Check(ctx)
+		}
+	}
+}
+
+// Check probes every source once, reporting sources that became stale
+func (c *Checker) Check(ctx context.Context) {
+	c.mu.Lock()
+	sources := make([]*source, 0, len(c.sources))
+	for _, src := range c.sources {
+		sources = append(sources, src)
+	}
+	c.mu.Unlock()
+
+	for _, src := range sources {
+		exists, modified, err := src.probe(ctx)
+		if err != nil {
+			c.logger.Warn("failed to check config source",
+				zap.String("source", src.name),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		reason := ""
+		switch {
+		case !exists:
+			reason = ReasonDeleted
+		case modified.After(src.loadedAt):
+			reason = ReasonModified
+		case c.maxAge > 0 && time.Since(src.loadedAt) > c.maxAge:
+			reason = ReasonExpired
+		}
+
+		c.mu.Lock()
+		changed := src.reason != reason
+		src.reason = reason
+		c.mu.Unlock()
+
+		if changed {
+			setStale(src.name, reason)
+			if reason != "" {
+				c.report(ctx, src, reason)
+			}
+		}
+	}
+}
+
+// Stale returns the stale reason per stale source
+func (c *Checker) Stale() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stale := make(map[string]string)
+	for name, src := range c.sources {
+		if src.reason != "" {
+			stale[name] = src.reason
+		}
+	}
+	return stale
+}
+
+// report logs and publishes a source that became stale
+func (c *Checker) report(ctx context.Context, src *source, reason string) {
+	age := time.Since(src.loadedAt)
+	c.logger.Warn("configuration source is stale",
+		zap.String("source", src.name),
+		zap.String("reason", reason),
+		zap.Duration("loaded_ago", age),
+	)
+
+	if c.bus == nil {
+		return
+	}
+
+	event := ports.Event{
+		ID:        fmt.Sprintf("%s-%d", src.name, time.Now().UnixNano()),
+		Type:      EventTypeConfigStale,
+		Timestamp: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"source":    src.name,
+			"reason":    reason,
+			"loaded_at": src.loadedAt.UTC(),
+			"age_s":     int64(age.Seconds()),
+		},
+	}
+	if err := c.bus.Publish(ctx, c.topic, event); err != nil {
+		c.logger.Warn("failed to publish stale config event",
+			zap.String("source", src.name),
+			zap.Error(err),
+		)
+	}
+}
+
+// setStale exports the stale state of a source; an empty reason marks it fresh
+func setStale(name, reason string) {
+	for _, r := range reasons {
+		value := 0.0
+		if r == reason {
+			value = 1
+		}
+		metrics.ConfigStale.WithLabelValues(name, r).Set(value)
+	}
+}
@@ -0,0 +1,16 @@
+// Package staleness detects configuration that was loaded once and has since
+// gone stale.
+//
+// Configuration such as tenant LLM bindings is read at startup and never
+// reloaded. A Checker periodically probes each tracked source and reports it
+// as stale when the source was deleted, modified after it was loaded, or
+// loaded longer ago than the maximum age. Each transition to stale is logged,
+// published as a config.stale event and exported as a metric, so silently
+// frozen routing behavior becomes visible to operators.
+//
+// Example usage:
+//
+//	checker := staleness.NewChecker(24*time.Hour, eventBus, "router.events", logger)
+//	checker.Track("tenant_llm_file", time.Now(), staleness.FileProbe(path))
+//	go checker.Run(ctx, time.Minute)
+package staleness
@@ -0,0 +1,246 @@
+package statestore
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-node-router/internal/cache"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Cached is a Store keeping recently loaded states in an in-process LRU.
+// Entries expire after the cache TTL and are dropped when their execution ID
+// is published on the invalidation channel; Save and Delete through the
+// cache publish an invalidation for the other workers. Cached states are
+// shared between callers and must not be modified.
+type Cached struct {
+	Store
+
+	redis   redis.UniversalClient
+	channel string
+	pubsub  *redis.PubSub
+	entries *cache.LRU[*cachedState]
+	logger  *zap.Logger
+
+	// epoch is advanced by every invalidation; a load only fills the cache
+	// if no invalidation happened while it was in flight
+	epoch atomic.Uint64
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// cachedState holds the full state of an execution, or projections of it by
+// path set. Entries are replaced, never modified.
+type cachedState struct {
+	full        state.State
+	projections map[string]state.State
+}
+
+// NewCached wraps store with a cache of up to size states kept for ttl,
+// invalidated through the Redis pub/sub channel
+func NewCached(store Store, redisClient redis.UniversalClient, channel string, size int, ttl time.Duration, logger *zap.Logger) *Cached {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Cached{
+		Store:   store,
+		redis:   redisClient,
+		channel: channel,
+		pubsub:  redisClient.Subscribe(ctx, channel),
+		entries: cache.NewLRU[*cachedState](size, ttl),
+		logger:  logger,
+		cancel:  cancel,
+	}
+
+	c.wg.Add(1)
+	go c.listen(ctx)
+
+	return c
+}
+
+// Load returns the cached state, loading and caching it on a miss
+func (c *Cached) Load(ctx context.Context, executionID string) (state.State, error) {
+	if entry, ok := c.entries.Get(ctx, executionID); ok && entry.full != nil {
+		metrics.StateCacheRequests.WithLabelValues("hit").Inc()
+		return entry.full, nil
+	}
+	metrics.StateCacheRequests.WithLabelValues("miss").Inc()
+
+	epoch := c.epoch.Load()
+	st, err := c.Store.Load(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	c.fill(ctx, epoch, executionID, &cachedState{full: st})
+
+	return st, nil
+}
+
+// LoadPaths returns a cached full state or projection of the paths, loading
+// and caching the projection on a miss. States of stores that are not
+// Projectors are loaded and cached in full, as LoadPaths would return them.
+func (c *Cached) LoadPaths(ctx context.Context, executionID string, paths []string) (state.State, error) {
+	key := projectionKey(paths)
+	if _, projects := c.Store.(Projector); key == "" || !projects {
+		return c.Load(ctx, executionID)
+	}
+
+	entry, ok := c.entries.Get(ctx, executionID)
+	if ok && entry.full != nil {
+		metrics.StateCacheRequests.WithLabelValues("hit").Inc()
+		return entry.full, nil
+	}
+	if ok {
+		if projected, found := entry.projections[key]; found {
+			metrics.StateCacheRequests.WithLabelValues("hit").Inc()
+			return projected, nil
+		}
+	}
+	metrics.StateCacheRequests.WithLabelValues("miss").Inc()
+
+	epoch := c.epoch.Load()
+	projected, err := LoadPaths(ctx, c.Store, executionID, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	projections := map[string]state.State{key: projected}
+	if ok {
+		for k, v := range entry.projections {
+			projections[k] = v
+		}
+	}
+	c.fill(ctx, epoch, executionID, &cachedState{projections: projections})
+
+	return projected, nil
+}
+
+// Save saves graph state and invalidates it in every worker's cache
+func (c *Cached) Save(ctx context.Context, executionID string, st state.State) error {
+	if err := c.Store.Save(ctx, executionID, st); err != nil {
+		return err
+	}
+	c.invalidate(ctx, executionID)
+	return nil
+}
+
+// Delete deletes graph state and invalidates it in every worker's cache
+func (c *Cached) Delete(ctx context.Context, executionID string) error {
+	if err := c.Store.Delete(ctx, executionID); err != nil {
+		return err
+	}
+	c.invalidate(ctx, executionID)
+	return nil
+}
+
+// ListPage lists execution IDs from the wrapped store, bypassing the cache
+func (c *Cached) ListPage(ctx context.Context, opts ListOptions) (*Page, error) {
+	return List(ctx, c.Store, opts)
+}
+
+// SaveState persists graph state (compatibility method)
+func (c *Cached) SaveState(ctx context.Context, st interface{}) error {
+	return saveCompat(ctx, c, st)
+}
+
+// GetState retrieves graph state (compatibility method)
+func (c *Cached) GetState(ctx context.Context, graphID string) (interface{}, error) {
+	return c.Load(ctx, graphID)
+}
+
+// Stats returns the size and hit rate of the cache
+func (c *Cached) Stats() cache.LRUStats {
+	return c.entries.Stats()
+}
+
+// Close stops listening for invalidations and closes the wrapped store
+func (c *Cached) Close() error {
+	c.cancel()
+	// Closing the subscription unblocks a pending Receive
+	if err := c.pubsub.Close(); err != nil {
+		c.logger.Warn("failed to close state cache subscription", zap.Error(err))
+	}
+	c.wg.Wait()
+	return c.Store.Close()
+}
+
+// fill caches entry unless an invalidation happened since epoch was read
+func (c *Cached) fill(ctx context.Context, epoch uint64, executionID string, entry *cachedState) {
+	if c.epoch.Load() != epoch {
+		return
+	}
+	c.entries.Set(ctx, executionID, entry)
+}
+
+// forget drops the cached state of one execution
+func (c *Cached) forget(executionID string) {
+	c.epoch.Add(1)
+	c.entries.Remove(executionID)
+}
+
+// invalidate drops the local entry and publishes the execution ID for the
+// other workers. A failed publish is logged; their entries expire after the
+// cache TTL.
+func (c *Cached) invalidate(ctx context.Context, executionID string) {
+	c.forget(executionID)
+	metrics.StateCacheInvalidations.WithLabelValues("write").Inc()
+
+	if err := c.redis.Publish(ctx, c.channel, executionID).Err(); err != nil {
+		c.logger.Warn("failed to publish state cache invalidation",
+			zap.String("execution_id", executionID),
+			zap.Error(err),
+		)
+	}
+}
+
+// listen drops entries as invalidations arrive. Invalidations published while
+// unsubscribed are lost, so the whole cache is dropped on every (re)subscribe.
+func (c *Cached) listen(ctx context.Context) {
+	defer c.wg.Done()
+
+	for {
+		msg, err := c.pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.epoch.Add(1)
+			c.entries.Purge()
+			c.logger.Warn("state cache invalidation subscription failed, cache dropped",
+				zap.String("channel", c.channel),
+				zap.Error(err),
+			)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			c.epoch.Add(1)
+			c.entries.Purge()
+			metrics.StateCacheInvalidations.WithLabelValues("resubscribe").Inc()
+		case *redis.Message:
+			c.forget(m.Payload)
+			metrics.StateCacheInvalidations.WithLabelValues("message").Inc()
+		}
+	}
+}
+
+// projectionKey identifies a set of state paths independently of their order
+func projectionKey(paths []string) string {
+	normalized := normalizePaths(paths)
+	keys := make([]string, len(normalized))
+	for i, path := range normalized {
+		keys[i] = strings.Join(path, ".")
+	}
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,34 @@
+// Package statestore provides the graph state stores the router reads state
+// from, behind the ports.StateStorage interface.
+//
+// Backends are looked up by name in a registry, selected with STATE_BACKEND:
+//
+//   - redis: JSON values under graph:state:<execution_id> (the default)
+//   - postgres: JSONB rows of a configurable table
+//   - etcd: JSON values under a key prefix, with TTLs as leases
+//
+// Further backends can be added with Register.
+//
+// LoadPaths loads only selected state paths from stores implementing
+// Projector, and the full state from the others.
+//
+// List pages through execution IDs with an optional prefix, using SCAN on
+// Redis.
+//
+// Cached wraps any store with an in-process LRU of recently loaded states,
+// invalidated by execution IDs published on a Redis pub/sub channel.
+//
+// Example usage:
+//
+//	store, err := statestore.New(ctx, cfg.StateBackend, cfg, statestore.Deps{
+//	    Redis:  redisClient,
+//	    Logger: logger,
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer store.Close()
+//
+//	st, err := store.Load(ctx, executionID)
+//	st, err = statestore.LoadPaths(ctx, store, executionID, []string{"inputs.priority"})
+package statestore
@@ -0,0 +1,244 @@
+package statestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-node-router/internal/config"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Etcd implements ports.StateStorage using etcd keys under a prefix. TTLs are
+// implemented with leases.
+type Etcd struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// NewEtcd creates an etcd state store keeping state under prefix
+func NewEtcd(client *clientv3.Client, prefix string) *Etcd {
+	return &Etcd{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// newEtcdFromConfig connects to etcd from STATE_ETCD_*
+func newEtcdFromConfig(_ context.Context, cfg *config.Config, _ Deps) (Store, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   cfg.StateEtcdEndpoints,
+		DialTimeout: cfg.StateEtcdDialTimeout,
+		Username:    cfg.StateEtcdUsername,
+		Password:    cfg.StateEtcdPassword,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return NewEtcd(client, cfg.StateEtcdPrefix), nil
+}
+
+// Save saves graph state, clearing any TTL
+func (s *Etcd) Save(ctx context.Context, executionID string, st state.State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if _, err := s.client.Put(ctx, s.prefix+executionID, string(data)); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return nil
+}
+
+// Load loads graph state
+func (s *Etcd) Load(ctx context.Context, executionID string) (state.State, error) {
+	resp, err := s.client.Get(ctx, s.prefix+executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("%w for execution %s", ErrNotFound, executionID)
+	}
+
+	var st state.State
+	if err := json.Unmarshal(resp.Kvs[0].Value, &st); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	return st, nil
+}
+
+// Update applies writes to the state of an execution, rewriting it only if
+// it has not changed since it was read and retrying otherwise. The state
+// stays attached to its lease, so it keeps its TTL.
+func (s *Etcd) Update(ctx context.Context, executionID string, writes []StateWrite) error {
+	key := s.prefix + executionID
+
+	for attempt := 0; attempt < outboxAttempts; attempt++ {
+		resp, err := s.client.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to load state: %w", err)
+		}
+		if len(resp.Kvs) == 0 {
+			return fmt.Errorf("%w for execution %s", ErrNotFound, executionID)
+		}
+		kv := resp.Kvs[0]
+
+		var st state.State
+		if err := json.Unmarshal(kv.Value, &st); err != nil {
+			return fmt.Errorf("failed to unmarshal state: %w", err)
+		}
+		updated, err := json.Marshal(ApplyWrites(st, writes))
+		if err != nil {
+			return fmt.Errorf("failed to marshal state: %w", err)
+		}
+
+		var opts []clientv3.OpOption
+		if kv.Lease != 0 {
+			opts = append(opts, clientv3.WithLease(clientv3.LeaseID(kv.Lease)))
+		}
+		txn, err := s.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision)).
+			Then(clientv3.OpPut(key, string(updated), opts...)).
+			Commit()
+		if err != nil {
+			return fmt.Errorf("failed to update state: %w", err)
+		}
+		if txn.Succeeded {
+			return nil
+		}
+	}
+	return fmt.Errorf("state of execution %s kept changing, gave up after %d attempts", executionID, outboxAttempts)
+}
+
+// Delete deletes graph state
+func (s *Etcd) Delete(ctx context.Context, executionID string) error {
+	if _, err := s.client.Delete(ctx, s.prefix+executionID); err != nil {
+		return fmt.Errorf("failed to delete state: %w", err)
+	}
+
+	return nil
+}
+
+// Exists checks if state exists for an execution
+func (s *Etcd) Exists(ctx context.Context, executionID string) (bool, error) {
+	resp, err := s.client.Get(ctx, s.prefix+executionID, clientv3.WithCountOnly())
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence: %w", err)
+	}
+
+	return resp.Count > 0, nil
+}
+
+// SetTTL attaches the state to a new lease expiring after ttl. The value is
+// rewritten only if it has not changed since it was read.
+func (s *Etcd) SetTTL(ctx context.Context, executionID string, ttl time.Duration) error {
+	key := s.prefix + executionID
+
+	resp, err := s.client.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to set TTL: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return fmt.Errorf("failed to set TTL: %w for execution %s", ErrNotFound, executionID)
+	}
+	kv := resp.Kvs[0]
+
+	// etcd leases have a granularity of one second
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	lease, err := s.client.Grant(ctx, max(seconds, 1))
+	if err != nil {
+		return fmt.Errorf("failed to set TTL: %w", err)
+	}
+
+	txn, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision)).
+		Then(clientv3.OpPut(key, string(kv.Value), clientv3.WithLease(lease.ID))).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("failed to set TTL: %w", err)
+	}
+	if !txn.Succeeded {
+		return fmt.Errorf("failed to set TTL: state of execution %s changed concurrently", executionID)
+	}
+
+	return nil
+}
+
+// List returns all execution IDs that have stored state
+func (s *Etcd) List(ctx context.Context) ([]string, error) {
+	resp, err := s.client.Get(ctx, s.prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	executionIDs := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if id := strings.TrimPrefix(string(kv.Key), s.prefix); id != "" {
+			executionIDs = append(executionIDs, id)
+		}
+	}
+
+	return executionIDs, nil
+}
+
+// ListPage returns up to Limit execution IDs in key order, continuing after
+// the execution ID in Cursor
+func (s *Etcd) ListPage(ctx context.Context, opts ListOptions) (*Page, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+	prefix := s.prefix + opts.Prefix
+	start := prefix
+	if opts.Cursor != "" {
+		start = s.prefix + opts.Cursor + "\x00"
+	}
+
+	// One extra key tells whether another page follows
+	resp, err := s.client.Get(ctx, start,
+		clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)),
+		clientv3.WithLimit(int64(opts.Limit+1)),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+		clientv3.WithKeysOnly(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	page := &Page{ExecutionIDs: make([]string, 0, len(resp.Kvs))}
+	for _, kv := range resp.Kvs {
+		if len(page.ExecutionIDs) == opts.Limit {
+			page.Cursor = page.ExecutionIDs[opts.Limit-1]
+			break
+		}
+		page.ExecutionIDs = append(page.ExecutionIDs, strings.TrimPrefix(string(kv.Key), s.prefix))
+	}
+
+	return page, nil
+}
+
+// SaveState persists graph state (compatibility method)
+func (s *Etcd) SaveState(ctx context.Context, st interface{}) error {
+	return saveCompat(ctx, s, st)
+}
+
+// GetState retrieves graph state (compatibility method)
+func (s *Etcd) GetState(ctx context.Context, graphID string) (interface{}, error) {
+	return s.Load(ctx, graphID)
+}
+
+// Ping checks that the etcd cluster answers reads
+func (s *Etcd) Ping(ctx context.Context) error {
+	_, err := s.client.Get(ctx, s.prefix, clientv3.WithCountOnly())
+	return err
+}
+
+// Close closes the etcd client
+func (s *Etcd) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,68 @@
+package statestore
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// defaultPageSize is used when ListOptions.Limit is not set
+const defaultPageSize = 100
+
+// ListOptions selects one page of execution IDs
+type ListOptions struct {
+	// Prefix keeps only execution IDs starting with it
+	Prefix string
+	// Cursor continues from a previous page; empty starts from the beginning
+	Cursor string
+	// Limit is the page size. Redis treats it as a hint and may return fewer
+	// or more IDs per page.
+	Limit int
+}
+
+// Page is one page of execution IDs
+type Page struct {
+	ExecutionIDs []string `json:"execution_ids"`
+	// Cursor fetches the next page; empty when this is the last page
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// Pager is implemented by stores that can list execution IDs page by page
+type Pager interface {
+	ListPage(ctx context.Context, opts ListOptions) (*Page, error)
+}
+
+// List returns one page of execution IDs from store, paging in the backend
+// when the store is a Pager and paging the full List otherwise. Cursors are
+// only valid with the store that returned them.
+func List(ctx context.Context, store ports.StateStorage, opts ListOptions) (*Page, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+	if pager, ok := store.(Pager); ok {
+		return pager.ListPage(ctx, opts)
+	}
+
+	executionIDs, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(executionIDs)
+
+	// The cursor is the last execution ID of the previous page
+	page := &Page{ExecutionIDs: []string{}}
+	for _, id := range executionIDs {
+		if !strings.HasPrefix(id, opts.Prefix) || (opts.Cursor != "" && id <= opts.Cursor) {
+			continue
+		}
+		if len(page.ExecutionIDs) == opts.Limit {
+			page.Cursor = page.ExecutionIDs[len(page.ExecutionIDs)-1]
+			break
+		}
+		page.ExecutionIDs = append(page.ExecutionIDs, id)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,176 @@
+package statestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/redis/go-redis/v9"
+)
+
+// outboxAttempts bounds the optimistic transactions of Update and
+// UpdateAndPublish retried because the state changed while they were prepared
+const outboxAttempts = 5
+
+// StateWrite sets Value at the Path segments of an execution state (e.g.
+// node_states, router, output), creating intermediate objects as needed.
+// Segments may contain dots, as node IDs do.
+type StateWrite struct {
+	Path  []string
+	Value interface{}
+}
+
+// Outbox is implemented by stores that can update a state and add a stream
+// entry in one transaction, so a crash never leaves one without the other
+type Outbox interface {
+	UpdateAndPublish(ctx context.Context, executionID string, writes []StateWrite, stream string, values map[string]interface{}) error
+}
+
+// Updater is implemented by stores that can apply writes to a state in one
+// atomic read-modify-write that keeps its expiry
+type Updater interface {
+	Update(ctx context.Context, executionID string, writes []StateWrite) error
+}
+
+// Update applies writes to the state of an execution. Stores implementing
+// Updater apply them atomically and keep the state's expiry; other stores
+// load and save the state, which is not atomic with concurrent writers.
+func Update(ctx context.Context, store ports.StateStorage, executionID string, writes []StateWrite) error {
+	if updater, ok := store.(Updater); ok {
+		return updater.Update(ctx, executionID, writes)
+	}
+	st, err := store.Load(ctx, executionID)
+	if err != nil {
+		return err
+	}
+	return store.Save(ctx, executionID, ApplyWrites(st, writes))
+}
+
+// ApplyWrites returns a copy of st with writes applied. Only the objects on
+// the written paths are copied; st itself, which may be shared with a cache,
+// is never modified.
+func ApplyWrites(st state.State, writes []StateWrite) state.State {
+	updated := make(state.State, len(st)+1)
+	for key, value := range st {
+		updated[key] = value
+	}
+	for _, write := range writes {
+		path := write.Path
+		if len(path) == 0 {
+			continue
+		}
+		current := map[string]interface{}(updated)
+		for _, segment := range path[:len(path)-1] {
+			next := map[string]interface{}{}
+			if existing, ok := current[segment].(map[string]interface{}); ok {
+				for key, value := range existing {
+					next[key] = value
+				}
+			}
+			current[segment] = next
+			current = next
+		}
+		current[path[len(path)-1]] = write.Value
+	}
+	return updated
+}
+
+// Update applies writes to the state of an execution in a transaction
+// watching its key, so concurrent writes are not lost. The state keeps its
+// TTL. Only the state key is involved, so it works in cluster mode.
+func (s *Redis) Update(ctx context.Context, executionID string, writes []StateWrite) error {
+	return s.update(ctx, executionID, writes, nil)
+}
+
+// UpdateAndPublish applies writes to the state of an execution and adds values
+// to stream in one MULTI transaction, watching the state key so concurrent
+// writes are not lost. The state keeps its TTL. The state key and the stream
+// must be served by the same Redis node, so it is not usable in cluster mode.
+func (s *Redis) UpdateAndPublish(ctx context.Context, executionID string, writes []StateWrite, stream string, values map[string]interface{}) error {
+	return s.update(ctx, executionID, writes, func(pipe redis.Pipeliner) {
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: values})
+	})
+}
+
+// update applies writes to the state of an execution in a MULTI transaction
+// watching the state key, together with the commands queued by also, retrying
+// while the state changes concurrently
+func (s *Redis) update(ctx context.Context, executionID string, writes []StateWrite, also func(redis.Pipeliner)) error {
+	key := redisKeyPrefix + executionID
+
+	update := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Result()
+		if err != nil {
+			if err == redis.Nil {
+				return fmt.Errorf("%w for execution %s", ErrNotFound, executionID)
+			}
+			return fmt.Errorf("failed to load state: %w", err)
+		}
+		var st state.State
+		if err := json.Unmarshal([]byte(data), &st); err != nil {
+			return fmt.Errorf("failed to unmarshal state: %w", err)
+		}
+		updated, err := json.Marshal(ApplyWrites(st, writes))
+		if err != nil {
+			return fmt.Errorf("failed to marshal state: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, updated, redis.SetArgs{KeepTTL: true})
+			if also != nil {
+				also(pipe)
+			}
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < outboxAttempts; attempt++ {
+		err := s.client.Watch(ctx, update, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("state of execution %s kept changing, gave up after %d attempts", executionID, outboxAttempts)
+}
+
+// Update applies writes through the wrapped store, reading the state from it
+// rather than from the cache, and invalidates the state in every worker's
+// cache
+func (c *Cached) Update(ctx context.Context, executionID string, writes []StateWrite) error {
+	if err := Update(ctx, c.Store, executionID, writes); err != nil {
+		return err
+	}
+	c.invalidate(ctx, executionID)
+	return nil
+}
+
+// UpdateAndPublish updates the state and publishes through the wrapped store
+// when it is an Outbox, and invalidates the state in every worker's cache
+func (c *Cached) UpdateAndPublish(ctx context.Context, executionID string, writes []StateWrite, stream string, values map[string]interface{}) error {
+	outbox, ok := c.Store.(Outbox)
+	if !ok {
+		return fmt.Errorf("state store does not support atomic publishing")
+	}
+	if err := outbox.UpdateAndPublish(ctx, executionID, writes, stream, values); err != nil {
+		return err
+	}
+	c.invalidate(ctx, executionID)
+	return nil
+}
+
+// AsOutbox returns store as an Outbox when it and any store it wraps support
+// atomic publishing
+func AsOutbox(store ports.StateStorage) (Outbox, bool) {
+	if cached, ok := store.(*Cached); ok {
+		if _, ok := cached.Store.(Outbox); !ok {
+			return nil, false
+		}
+		return cached, true
+	}
+	outbox, ok := store.(Outbox)
+	return outbox, ok
+}
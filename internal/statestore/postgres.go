@@ -0,0 +1,283 @@
+package statestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Postgres implements ports.StateStorage using a PostgreSQL table with the
+// columns execution_id (primary key), state (jsonb) and expires_at
+// (timestamptz, null for no expiry)
+type Postgres struct {
+	pool *pgxpool.Pool
+	// table is the sanitized, possibly schema-qualified table name
+	table string
+}
+
+// NewPostgres connects to PostgreSQL and stores state in table, which may be
+// schema-qualified (e.g. "orchestrator.graph_states")
+func NewPostgres(ctx context.Context, dsn, table string) (*Postgres, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	return &Postgres{
+		pool:  pool,
+		table: pgx.Identifier(strings.Split(table, ".")).Sanitize(),
+	}, nil
+}
+
+// newPostgresFromConfig creates a PostgreSQL state store from STATE_POSTGRES_*
+func newPostgresFromConfig(ctx context.Context, cfg *config.Config, _ Deps) (Store, error) {
+	store, err := NewPostgres(ctx, cfg.StatePostgresDSN, cfg.StatePostgresTable)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Save saves graph state, clearing any expiry
+func (s *Postgres) Save(ctx context.Context, executionID string, st state.State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO %s (execution_id, state, expires_at) VALUES ($1, $2, NULL)
+		 ON CONFLICT (execution_id) DO UPDATE SET state = EXCLUDED.state, expires_at = NULL`, s.table),
+		executionID, data)
+	if err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return nil
+}
+
+// Load loads graph state; expired rows are treated as missing
+func (s *Postgres) Load(ctx context.Context, executionID string) (state.State, error) {
+	var data []byte
+	err := s.pool.QueryRow(ctx, fmt.Sprintf(
+		`SELECT state FROM %s WHERE execution_id = $1 AND (expires_at IS NULL OR expires_at > now())`, s.table),
+		executionID).Scan(&data)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w for execution %s", ErrNotFound, executionID)
+		}
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	var st state.State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	return st, nil
+}
+
+// Update applies writes to the state of an execution in a transaction
+// holding its row lock, so concurrent writes are not lost. The expiry of the
+// state is kept.
+func (s *Postgres) Update(ctx context.Context, executionID string, writes []StateWrite) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update state: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var data []byte
+	err = tx.QueryRow(ctx, fmt.Sprintf(
+		`SELECT state FROM %s WHERE execution_id = $1 AND (expires_at IS NULL OR expires_at > now()) FOR UPDATE`, s.table),
+		executionID).Scan(&data)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w for execution %s", ErrNotFound, executionID)
+		}
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+
+	var st state.State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	updated, err := json.Marshal(ApplyWrites(st, writes))
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE %s SET state = $2 WHERE execution_id = $1`, s.table),
+		executionID, updated); err != nil {
+		return fmt.Errorf("failed to update state: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to update state: %w", err)
+	}
+	return nil
+}
+
+// LoadPaths loads the values at the dotted state paths, extracting each with
+// the #> operator so only those values are transferred
+func (s *Postgres) LoadPaths(ctx context.Context, executionID string, paths []string) (state.State, error) {
+	normalized := normalizePaths(paths)
+	if len(normalized) == 0 {
+		return s.Load(ctx, executionID)
+	}
+
+	columns := make([]string, len(normalized))
+	args := make([]interface{}, 0, len(normalized)+1)
+	args = append(args, executionID)
+	for i, path := range normalized {
+		columns[i] = fmt.Sprintf("state #> $%d", i+2)
+		args = append(args, path)
+	}
+
+	// Missing paths scan as nil, JSON nulls as "null"
+	values := make([][]byte, len(normalized))
+	dest := make([]interface{}, len(normalized))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	err := s.pool.QueryRow(ctx, fmt.Sprintf(
+		`SELECT %s FROM %s WHERE execution_id = $1 AND (expires_at IS NULL OR expires_at > now())`,
+		strings.Join(columns, ", "), s.table),
+		args...).Scan(dest...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w for execution %s", ErrNotFound, executionID)
+		}
+		return nil, fmt.Errorf("failed to load state paths: %w", err)
+	}
+
+	projected := state.State{}
+	for i, path := range normalized {
+		if values[i] == nil {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(values[i], &value); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal state path %s: %w", strings.Join(path, "."), err)
+		}
+		setPath(projected, path, value)
+	}
+
+	return projected, nil
+}
+
+// Delete deletes graph state
+func (s *Postgres) Delete(ctx context.Context, executionID string) error {
+	_, err := s.pool.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE execution_id = $1`, s.table), executionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete state: %w", err)
+	}
+
+	return nil
+}
+
+// Exists checks if unexpired state exists for an execution
+func (s *Postgres) Exists(ctx context.Context, executionID string) (bool, error) {
+	var exists bool
+	err := s.pool.QueryRow(ctx, fmt.Sprintf(
+		`SELECT EXISTS (SELECT 1 FROM %s WHERE execution_id = $1 AND (expires_at IS NULL OR expires_at > now()))`, s.table),
+		executionID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+// SetTTL sets the expiry of state data. Expired rows are ignored by reads
+// but not deleted.
+func (s *Postgres) SetTTL(ctx context.Context, executionID string, ttl time.Duration) error {
+	_, err := s.pool.Exec(ctx, fmt.Sprintf(`UPDATE %s SET expires_at = $2 WHERE execution_id = $1`, s.table),
+		executionID, time.Now().Add(ttl))
+	if err != nil {
+		return fmt.Errorf("failed to set TTL: %w", err)
+	}
+
+	return nil
+}
+
+// List returns all execution IDs that have unexpired state
+func (s *Postgres) List(ctx context.Context) ([]string, error) {
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(
+		`SELECT execution_id FROM %s WHERE expires_at IS NULL OR expires_at > now() ORDER BY execution_id`, s.table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list states: %w", err)
+	}
+
+	executionIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list states: %w", err)
+	}
+
+	return executionIDs, nil
+}
+
+// ListPage returns up to Limit execution IDs with unexpired state in ID
+// order, continuing after the execution ID in Cursor
+func (s *Postgres) ListPage(ctx context.Context, opts ListOptions) (*Page, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+	// One extra row tells whether another page follows
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(
+		`SELECT execution_id FROM %s
+		 WHERE (expires_at IS NULL OR expires_at > now())
+		   AND execution_id LIKE $1 ESCAPE '\' AND execution_id > $2
+		 ORDER BY execution_id LIMIT $3`, s.table),
+		escapeLike(opts.Prefix)+"%", opts.Cursor, opts.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list states: %w", err)
+	}
+
+	executionIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list states: %w", err)
+	}
+
+	page := &Page{ExecutionIDs: executionIDs}
+	if len(executionIDs) > opts.Limit {
+		page.ExecutionIDs = executionIDs[:opts.Limit]
+		page.Cursor = page.ExecutionIDs[opts.Limit-1]
+	}
+
+	return page, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SaveState persists graph state (compatibility method)
+func (s *Postgres) SaveState(ctx context.Context, st interface{}) error {
+	return saveCompat(ctx, s, st)
+}
+
+// GetState retrieves graph state (compatibility method)
+func (s *Postgres) GetState(ctx context.Context, graphID string) (interface{}, error) {
+	return s.Load(ctx, graphID)
+}
+
+// Ping checks the database connection
+func (s *Postgres) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// Close closes the connection pool
+func (s *Postgres) Close() error {
+	s.pool.Close()
+	return nil
+}
@@ -0,0 +1,93 @@
+package statestore
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// Projector is implemented by stores that can fetch a subset of the state
+// without reading the whole document
+type Projector interface {
+	// LoadPaths loads only the values at the dotted state paths; missing
+	// paths are left out of the returned state
+	LoadPaths(ctx context.Context, executionID string, paths []string) (state.State, error)
+}
+
+// LoadPaths loads the values at the dotted state paths (e.g. "inputs.priority")
+// from store, fetching only those paths when the store is a Projector. Other
+// stores return the full state: once it has been read and decoded, pruning it
+// only adds work. No paths loads the full state.
+func LoadPaths(ctx context.Context, store ports.StateStorage, executionID string, paths []string) (state.State, error) {
+	if projector, ok := store.(Projector); ok && len(paths) > 0 {
+		return projector.LoadPaths(ctx, executionID, paths)
+	}
+	return store.Load(ctx, executionID)
+}
+
+// Project returns the subset of st at the dotted paths. Values are shared
+// with st, not copied.
+func Project(st state.State, paths []string) state.State {
+	projected := state.State{}
+	for _, path := range normalizePaths(paths) {
+		if value, ok := lookupPath(st, path); ok {
+			setPath(projected, path, value)
+		}
+	}
+	return projected
+}
+
+// normalizePaths splits dotted paths into segments, dropping paths already
+// covered by a shorter path
+func normalizePaths(paths []string) [][]string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	var normalized [][]string
+	var last string
+	for _, path := range sorted {
+		if path == "" {
+			continue
+		}
+		if last != "" && (path == last || strings.HasPrefix(path, last+".")) {
+			continue
+		}
+		normalized = append(normalized, strings.Split(path, "."))
+		last = path
+	}
+	return normalized
+}
+
+// lookupPath returns the value at the path segments of st
+func lookupPath(st map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = st
+	for _, segment := range path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[segment]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setPath sets value at the path segments of st, creating intermediate
+// objects as needed
+func setPath(st map[string]interface{}, path []string, value interface{}) {
+	current := st
+	for _, segment := range path[:len(path)-1] {
+		next, ok := current[segment].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			current[segment] = next
+		}
+		current = next
+	}
+	current[path[len(path)-1]] = value
+}
@@ -0,0 +1,198 @@
+package statestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// redisKeyPrefix prefixes the state key of each execution
+const redisKeyPrefix = "graph:state:"
+
+// redisScanCount is the SCAN page size used by List
+const redisScanCount = 1000
+
+// Redis implements ports.StateStorage using Redis JSON strings. States are
+// plain SET values rather than RedisJSON documents, so Redis is not a
+// Projector and LoadPaths returns the full state.
+type Redis struct {
+	client redis.UniversalClient
+	logger *zap.Logger
+}
+
+// NewRedis creates a Redis state store
+func NewRedis(client redis.UniversalClient, logger *zap.Logger) *Redis {
+	return &Redis{
+		client: client,
+		logger: logger,
+	}
+}
+
+// newRedisFromConfig creates a Redis state store on the shared Redis client
+func newRedisFromConfig(_ context.Context, _ *config.Config, deps Deps) (Store, error) {
+	if deps.Redis == nil {
+		return nil, fmt.Errorf("redis client is required")
+	}
+	return NewRedis(deps.Redis, deps.Logger), nil
+}
+
+// Save saves graph state
+func (s *Redis) Save(ctx context.Context, executionID string, st state.State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := s.client.Set(ctx, redisKeyPrefix+executionID, data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return nil
+}
+
+// Load loads graph state
+func (s *Redis) Load(ctx context.Context, executionID string) (state.State, error) {
+	data, err := s.client.Get(ctx, redisKeyPrefix+executionID).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("%w for execution %s", ErrNotFound, executionID)
+		}
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	var st state.State
+	if err := json.Unmarshal([]byte(data), &st); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	return st, nil
+}
+
+// Delete deletes graph state
+func (s *Redis) Delete(ctx context.Context, executionID string) error {
+	if err := s.client.Del(ctx, redisKeyPrefix+executionID).Err(); err != nil {
+		return fmt.Errorf("failed to delete state: %w", err)
+	}
+
+	return nil
+}
+
+// Exists checks if state exists for an execution
+func (s *Redis) Exists(ctx context.Context, executionID string) (bool, error) {
+	result, err := s.client.Exists(ctx, redisKeyPrefix+executionID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check existence: %w", err)
+	}
+
+	return result > 0, nil
+}
+
+// SetTTL sets a time-to-live for state data
+func (s *Redis) SetTTL(ctx context.Context, executionID string, ttl time.Duration) error {
+	if err := s.client.Expire(ctx, redisKeyPrefix+executionID, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set TTL: %w", err)
+	}
+
+	return nil
+}
+
+// List returns all execution IDs that have stored state. Keys are walked
+// with SCAN so a large keyspace does not block Redis.
+func (s *Redis) List(ctx context.Context) ([]string, error) {
+	seen := make(map[string]struct{})
+	executionIDs := []string{}
+
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, redisKeyPrefix+"*", redisScanCount).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys: %w", err)
+		}
+		// SCAN may return a key more than once
+		for _, key := range keys {
+			id := strings.TrimPrefix(key, redisKeyPrefix)
+			if _, ok := seen[id]; ok || id == "" {
+				continue
+			}
+			seen[id] = struct{}{}
+			executionIDs = append(executionIDs, id)
+		}
+		if next == 0 {
+			return executionIDs, nil
+		}
+		cursor = next
+	}
+}
+
+// ListPage returns the execution IDs of one SCAN call. As with SCAN, a page
+// may hold more or fewer than Limit IDs, even none, and an ID may appear in
+// more than one page; only an empty Cursor marks the end.
+func (s *Redis) ListPage(ctx context.Context, opts ListOptions) (*Page, error) {
+	var cursor uint64
+	if opts.Cursor != "" {
+		var err error
+		cursor, err = strconv.ParseUint(opts.Cursor, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor %q", opts.Cursor)
+		}
+	}
+
+	match := redisKeyPrefix + escapeGlob(opts.Prefix) + "*"
+	keys, next, err := s.client.Scan(ctx, cursor, match, int64(opts.Limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	page := &Page{ExecutionIDs: make([]string, 0, len(keys))}
+	for _, key := range keys {
+		if id := strings.TrimPrefix(key, redisKeyPrefix); id != "" {
+			page.ExecutionIDs = append(page.ExecutionIDs, id)
+		}
+	}
+	if next != 0 {
+		page.Cursor = strconv.FormatUint(next, 10)
+	}
+
+	return page, nil
+}
+
+// escapeGlob escapes the glob metacharacters of a SCAN MATCH pattern
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// SaveState persists graph state (compatibility method)
+func (s *Redis) SaveState(ctx context.Context, st interface{}) error {
+	return saveCompat(ctx, s, st)
+}
+
+// GetState retrieves graph state (compatibility method)
+func (s *Redis) GetState(ctx context.Context, graphID string) (interface{}, error) {
+	return s.Load(ctx, graphID)
+}
+
+// Ping checks the Redis connection
+func (s *Redis) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close is a no-op; the Redis client is shared and closed by its owner
+func (s *Redis) Close() error {
+	return nil
+}
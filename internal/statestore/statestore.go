@@ -0,0 +1,101 @@
+package statestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned when no state is stored for an execution
+var ErrNotFound = errors.New("state not found")
+
+// Store is a graph state store with a connection to check and release
+type Store interface {
+	ports.StateStorage
+
+	// Ping checks that the backend is reachable
+	Ping(ctx context.Context) error
+	// Close releases the backend connection
+	Close() error
+}
+
+// Deps are the shared clients available to backend factories
+type Deps struct {
+	Redis  redis.UniversalClient
+	Logger *zap.Logger
+}
+
+// Factory creates a store from the worker config
+type Factory func(ctx context.Context, cfg *config.Config, deps Deps) (Store, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{
+		config.StateBackendRedis:    newRedisFromConfig,
+		config.StateBackendPostgres: newPostgresFromConfig,
+		config.StateBackendEtcd:     newEtcdFromConfig,
+	}
+)
+
+// Register adds a backend, replacing any backend registered under name
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// Backends returns the registered backend names
+func Backends() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the named backend
+func New(ctx context.Context, name string, cfg *config.Config, deps Deps) (Store, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown state backend %q (available: %v)", name, Backends())
+	}
+
+	store, err := factory(ctx, cfg, deps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s state store: %w", name, err)
+	}
+	return store, nil
+}
+
+// saveCompat implements the SaveState compatibility method on top of Save,
+// taking the execution ID from the graph_id or execution_id field
+func saveCompat(ctx context.Context, store ports.StateStorage, st interface{}) error {
+	stateMap, ok := st.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected map[string]interface{}, got %T", st)
+	}
+
+	executionID, ok := stateMap["graph_id"].(string)
+	if !ok {
+		executionID, ok = stateMap["execution_id"].(string)
+		if !ok {
+			return fmt.Errorf("state missing graph_id or execution_id field")
+		}
+	}
+
+	return store.Save(ctx, executionID, state.State(stateMap))
+}
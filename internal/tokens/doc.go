@@ -0,0 +1,14 @@
+// Package tokens estimates LLM token counts locally.
+//
+// Some providers don't report usage. The estimator approximates BPE
+// tokenizers closely enough for cost metrics and budgets: words count one
+// token per four characters (rounded up), and punctuation and CJK characters
+// count one token each. Whitespace is free. Truncate cuts text to a token
+// budget with the same rules.
+//
+// Example usage:
+//
+//	inputTokens := tokens.Estimate(prompt)
+//	outputTokens := tokens.Estimate(response)
+//	excerpt := tokens.Truncate(document, 500)
+package tokens
@@ -0,0 +1,72 @@
+package tokens
+
+import "unicode"
+
+// charsPerToken is the average number of word characters per token
+const charsPerToken = 4
+
+// Estimate approximates the number of tokens in text
+func Estimate(text string) int {
+	count := 0
+	word := 0
+
+	flush := func() {
+		count += wordTokens(word)
+		word = 0
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case isCJK(r):
+			flush()
+			count++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+		default:
+			flush()
+			count++
+		}
+	}
+	flush()
+
+	return count
+}
+
+// Truncate returns the longest prefix of text whose estimated tokens fit
+// maxTokens
+func Truncate(text string, maxTokens int) string {
+	count := 0
+	word := 0
+
+	for i, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			count += wordTokens(word)
+			word = 0
+			continue
+		case isCJK(r), !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			count += wordTokens(word) + 1
+			word = 0
+		default:
+			word++
+		}
+		if count+wordTokens(word) > maxTokens {
+			return text[:i]
+		}
+	}
+
+	return text
+}
+
+// wordTokens returns the tokens of a word of n characters
+func wordTokens(n int) int {
+	return (n + charsPerToken - 1) / charsPerToken
+}
+
+// isCJK reports whether r is a Han, Hiragana, Katakana or Hangul character,
+// which tokenizers encode individually
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
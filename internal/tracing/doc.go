@@ -0,0 +1,24 @@
+// Package tracing provides OpenTelemetry tracing for the router worker.
+//
+// Trace context is read from the W3C `traceparent`/`tracestate` fields of
+// incoming stream entries and written to published decisions, so routing spans
+// join the orchestrator's trace.
+//
+// Example usage:
+//
+//	shutdown, err := tracing.Init(ctx, tracing.Config{
+//	    Enabled:     true,
+//	    Endpoint:    "otel-collector:4318",
+//	    Insecure:    true,
+//	    SampleRatio: 0.1,
+//	    ServiceName: "dago-node-router",
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer shutdown(ctx)
+//
+//	ctx = tracing.Extract(ctx, message.Values)
+//	ctx, span := tracing.Tracer().Start(ctx, "worker.handleMessage")
+//	defer span.End()
+package tracing
@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the router tracer
+const instrumentationName = "github.com/aescanero/dago-node-router"
+
+// Stream entry fields carrying W3C trace context
+const (
+	TraceParentField = "traceparent"
+	TraceStateField  = "tracestate"
+)
+
+// Config holds tracing exporter configuration
+type Config struct {
+	Enabled     bool
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+	ServiceName string
+	Version     string
+}
+
+// Init configures the global tracer provider and W3C trace context propagation.
+// When tracing is disabled spans are not recorded, but incoming trace context is
+// still propagated to published messages. The returned function flushes and
+// shuts down the exporter.
+func Init(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cfg.Version),
+	)
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the router tracer
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Extract returns a context carrying the trace context found in stream entry values
+func Extract(ctx context.Context, values map[string]interface{}) context.Context {
+	carrier := propagation.MapCarrier{}
+	for _, field := range []string{TraceParentField, TraceStateField} {
+		if value, ok := values[field].(string); ok && value != "" {
+			carrier[field] = value
+		}
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// Inject adds the trace context of ctx to stream entry values
+func Inject(ctx context.Context, values map[string]interface{}) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	for _, field := range []string{TraceParentField, TraceStateField} {
+		if value, ok := carrier[field]; ok {
+			values[field] = value
+		}
+	}
+}
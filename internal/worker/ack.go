@@ -0,0 +1,193 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ackBatch collects the IDs of the settled messages of one XREADGROUP batch
+// so they are acknowledged with a single pipelined XACK
+type ackBatch struct {
+	mu  sync.Mutex
+	ids map[string][]string
+
+	// pending counts messages not yet acked or left pending
+	pending sync.WaitGroup
+}
+
+// newAckBatch creates an ack batch expecting n messages to settle
+func newAckBatch(n int) *ackBatch {
+	b := &ackBatch{ids: make(map[string][]string)}
+	b.pending.Add(n)
+	return b
+}
+
+// wait blocks until every message has settled, or ctx is done. Messages whose
+// outcome is dropped during shutdown never settle.
+func (b *ackBatch) wait(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		b.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// settle records a handled message: acked at once when it was not read as
+// part of a batch (acks is nil), otherwise collected for the batch XACK. Messages
+// settled with ack false stay pending and are reclaimed later.
+func (w *Worker) settle(acks *ackBatch, stream, messageID string, ack bool) {
+	if acks == nil {
+		if ack {
+			w.acknowledgeMessage(stream, messageID)
+		}
+		return
+	}
+
+	if ack {
+		acks.mu.Lock()
+		acks.ids[stream] = append(acks.ids[stream], messageID)
+		acks.mu.Unlock()
+	}
+	acks.pending.Done()
+}
+
+// processBatch handles the messages of one read as a batch
+func (w *Worker) processBatch(streams []redis.XStream) {
+	if count := batchLen(streams); count > 0 {
+		metrics.ReadBatchSize.Observe(float64(count))
+		w.handleBatch(streams)
+	}
+}
+
+// handleBatch handles a batch of messages on up to WorkerConcurrency
+// goroutines, then acknowledges every settled message in one pipeline. The
+// messages of one execution are handled serially in stream order, so
+// concurrency never reorders the requests of an execution or runs its state
+// writes in parallel. Batches run one at a time.
+func (w *Worker) handleBatch(streams []redis.XStream) {
+	count := batchLen(streams)
+	if count == 0 {
+		return
+	}
+	w.batchMu.Lock()
+	defer w.batchMu.Unlock()
+
+	acks := newAckBatch(count)
+	slots := make(chan struct{}, w.config.WorkerConcurrency)
+dispatch:
+	for _, partition := range w.partitionBatch(streams) {
+		select {
+		case slots <- struct{}{}:
+		case <-w.ctx.Done():
+			// Unhandled messages stay pending for redelivery; the settled
+			// ones are still acked below
+			break dispatch
+		}
+		go func(partition []batchMessage) {
+			defer func() { <-slots }()
+			for _, m := range partition {
+				w.handleMessage(m.stream, m.message, acks)
+			}
+		}(partition)
+	}
+
+	if !acks.wait(w.ctx) {
+		w.logger.Warn("shutting down before batch settled, unacked messages stay pending")
+	}
+	w.flushAcks(acks)
+}
+
+// batchLen returns the number of messages of a batch
+func batchLen(streams []redis.XStream) int {
+	var count int
+	for _, stream := range streams {
+		count += len(stream.Messages)
+	}
+	return count
+}
+
+// batchMessage is a message of a read and the stream it was read from
+type batchMessage struct {
+	stream  string
+	message redis.XMessage
+}
+
+// partitionBatch groups the messages of a read by execution ID, keeping
+// stream order within each group. With a single worker goroutine the batch is
+// one group. Messages that are not valid work requests are groups of their
+// own; they are rejected when handled.
+func (w *Worker) partitionBatch(streams []redis.XStream) [][]batchMessage {
+	var partitions [][]batchMessage
+	if w.config.WorkerConcurrency <= 1 {
+		var all []batchMessage
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				all = append(all, batchMessage{stream: stream.Stream, message: message})
+			}
+		}
+		return append(partitions, all)
+	}
+
+	byExecution := make(map[string]int)
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			m := batchMessage{stream: stream.Stream, message: message}
+			request, _, err := parseWorkRequest(message.Values, w.config.MaxRequestBytes)
+			if err != nil {
+				partitions = append(partitions, []batchMessage{m})
+				continue
+			}
+			if i, ok := byExecution[request.ExecutionID]; ok {
+				partitions[i] = append(partitions[i], m)
+				continue
+			}
+			byExecution[request.ExecutionID] = len(partitions)
+			partitions = append(partitions, []batchMessage{m})
+		}
+	}
+	return partitions
+}
+
+// flushAcks acknowledges the collected message IDs with one XACK per stream,
+// sent in a single pipeline
+func (w *Worker) flushAcks(acks *ackBatch) {
+	acks.mu.Lock()
+	defer acks.mu.Unlock()
+	if len(acks.ids) == 0 {
+		return
+	}
+
+	// The acks must go out even while shutting down
+	ctx := context.Background()
+	pipe := w.redisClient.Pipeline()
+	cmds := make(map[string]*redis.IntCmd, len(acks.ids))
+	for stream, ids := range acks.ids {
+		cmds[stream] = pipe.XAck(ctx, stream, w.consumerGroup, ids...)
+	}
+	// Per-stream errors are inspected below
+	_, _ = pipe.Exec(ctx)
+
+	for stream, cmd := range cmds {
+		acked, err := cmd.Result()
+		if err != nil {
+			w.logger.Error("failed to acknowledge messages",
+				zap.String("stream", stream),
+				zap.Int("count", len(acks.ids[stream])),
+				zap.Error(err),
+			)
+			continue
+		}
+		metrics.MessagesAcked.Add(float64(acked))
+	}
+}
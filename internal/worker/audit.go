@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/audit"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/redact"
+	"go.uber.org/zap"
+)
+
+// WithAuditLog records every routing decision and failure in log
+func WithAuditLog(log audit.Log) Option {
+	return func(w *Worker) {
+		w.auditLog = log
+	}
+}
+
+// WithRedaction masks customer data in the reasoning, LLM response and error
+// of audit records
+func WithRedaction(redactor *redact.Redactor) Option {
+	return func(w *Worker) {
+		w.redactor = redactor
+	}
+}
+
+// recordAudit appends the audit record of an outcome. A failed append is
+// logged and counted; it never blocks the decision.
+func (w *Worker) recordAudit(ctx context.Context, request *WorkRequest, outcome *Outcome, latency time.Duration) {
+	if w.auditLog == nil {
+		return
+	}
+
+	record := audit.NewRecord(request.ExecutionID, request.NodeID, w.id,
+		request.stateHash, audit.Hash(request.Config), outcome.Result, outcome.Err, latency)
+	if record.Tenant == "" {
+		record.Tenant = request.tenant()
+	}
+	record.Reasoning = w.redactor.String(record.Reasoning)
+	record.LLMResponse = w.redactor.String(record.LLMResponse)
+	record.Error = w.redactor.String(record.Error)
+	if err := w.auditLog.Append(ctx, record); err != nil {
+		metrics.AuditErrors.Inc()
+		w.log(ctx).Error("failed to record audit entry",
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// backoff computes exponential retry delays with full jitter
+type backoff struct {
+	min     time.Duration
+	max     time.Duration
+	attempt int
+}
+
+// newBackoff creates a new backoff between the given delays
+func newBackoff(minDelay, maxDelay time.Duration) *backoff {
+	return &backoff{min: minDelay, max: maxDelay}
+}
+
+// Next returns the delay before the next attempt and advances the backoff
+func (b *backoff) Next() time.Duration {
+	ceiling := b.max
+	if b.attempt < 32 {
+		if d := b.min << uint(b.attempt); d > 0 && d < b.max {
+			ceiling = d
+		}
+	}
+	b.attempt++
+
+	// Full jitter: a random delay between min and the current ceiling
+	if ceiling <= b.min {
+		return b.min
+	}
+	return b.min + time.Duration(rand.Int63n(int64(ceiling-b.min)))
+}
+
+// Attempts returns the number of consecutive failures recorded
+func (b *backoff) Attempts() int {
+	return b.attempt
+}
+
+// Reset resets the backoff after a successful attempt
+func (b *backoff) Reset() {
+	b.attempt = 0
+}
+
+// minTickInterval is the shortest interval of the worker's periodic loops
+const minTickInterval = 10 * time.Millisecond
+
+// newTicker returns a ticker firing every d, or every minTickInterval when d
+// is shorter, since a non-positive interval would panic
+func newTicker(d time.Duration) *time.Ticker {
+	if d < minTickInterval {
+		d = minTickInterval
+	}
+	return time.NewTicker(d)
+}
+
+// sleepContext sleeps for the given duration or until the context is done,
+// reporting whether the full duration elapsed
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// fatalRedisErrors lists error prefixes that retrying cannot fix without
+// operator action (authentication, permissions, configuration)
+var fatalRedisErrors = []string{
+	"NOAUTH",
+	"WRONGPASS",
+	"NOPERM",
+	"ERR invalid password",
+	"ERR AUTH",
+	"ERR unknown command",
+	"WRONGTYPE",
+	"MISCONF",
+}
+
+// isFatalRedisError reports whether a Redis error is caused by authentication
+// or configuration rather than a transient network failure
+func isFatalRedisError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return false
+	}
+
+	msg := err.Error()
+	for _, prefix := range fatalRedisErrors {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
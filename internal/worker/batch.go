@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// pendingPublish is an outcome waiting for its batch to be flushed. done is
+// called with the publish result once the entry is flushed.
+type pendingPublish struct {
+	stream string
+	values map[string]interface{}
+	done   func(error)
+}
+
+// publishBatcher pipelines outcome XADDs, flushing when a batch is full or
+// the flush interval elapses
+type publishBatcher struct {
+	worker   *Worker
+	entries  chan pendingPublish
+	size     int
+	interval time.Duration
+}
+
+// newPublishBatcher creates a batcher flushing up to size entries at a time
+func newPublishBatcher(w *Worker, size int, interval time.Duration) *publishBatcher {
+	return &publishBatcher{
+		worker:   w,
+		entries:  make(chan pendingPublish, size),
+		size:     size,
+		interval: interval,
+	}
+}
+
+// add queues an entry, blocking while the queue is full. Entries queued
+// during shutdown are dropped without calling done, so their messages stay
+// pending and are redelivered.
+func (b *publishBatcher) add(entry pendingPublish) {
+	select {
+	case b.entries <- entry:
+	case <-b.worker.ctx.Done():
+	}
+}
+
+// run collects and flushes batches until the worker stops
+func (b *publishBatcher) run() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	batch := make([]pendingPublish, 0, b.size)
+	for {
+		select {
+		case <-b.worker.ctx.Done():
+			unflushed := len(batch) + len(b.entries)
+			if unflushed > 0 {
+				b.worker.logger.Warn("unflushed outcomes left pending for redelivery",
+					zap.Int("count", unflushed),
+				)
+			}
+			return
+		case entry := <-b.entries:
+			batch = append(batch, entry)
+			if len(batch) >= b.size {
+				b.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				b.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush publishes a batch in one pipeline and reports each entry's outcome.
+// Entries that fail in the pipeline fall back to individual retried publishes.
+func (b *publishBatcher) flush(batch []pendingPublish) {
+	w := b.worker
+	metrics.PublishBatchSize.Observe(float64(len(batch)))
+
+	pipe := w.redisClient.Pipeline()
+	cmds := make([]*redis.StringCmd, len(batch))
+	for i, entry := range batch {
+		cmds[i] = pipe.XAdd(w.ctx, &redis.XAddArgs{
+			Stream: entry.stream,
+			Values: entry.values,
+		})
+	}
+	// Per-entry errors are inspected below
+	_, _ = pipe.Exec(w.ctx)
+
+	for i, entry := range batch {
+		if err := cmds[i].Err(); err != nil {
+			entry.done(w.publish(entry.stream, entry.values))
+			continue
+		}
+		entry.done(nil)
+	}
+}
+
+// send publishes values to stream and reports the result to done. With
+// batching enabled the publish is queued and done runs after the flush.
+func (w *Worker) send(stream string, values map[string]interface{}, done func(error)) {
+	if w.batcher == nil {
+		done(w.publish(stream, values))
+		return
+	}
+	w.batcher.add(pendingPublish{stream: stream, values: values, done: done})
+}
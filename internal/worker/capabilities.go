@@ -0,0 +1,39 @@
+package worker
+
+import "github.com/aescanero/dago-node-router/internal/router"
+
+// Schema versions of the messages exchanged with the orchestrator
+const (
+	WorkRequestSchemaVersion = "1"
+	DecisionSchemaVersion    = "1"
+)
+
+// WithTransport advertises an additional transport served alongside the work
+// transport, such as gRPC, in the worker capabilities
+func WithTransport(name string) Option {
+	return func(w *Worker) {
+		w.transports = append(w.transports, name)
+	}
+}
+
+// Capabilities describes what this router worker supports, so a control plane
+// can validate graph definitions against the deployed fleet
+type Capabilities struct {
+	router.Capabilities
+	Transports     []string          `json:"transports"`
+	SchemaVersions map[string]string `json:"schema_versions"`
+}
+
+// Capabilities returns the routing features, transports and message schema
+// versions supported by the worker
+func (w *Worker) Capabilities() Capabilities {
+	return Capabilities{
+		Capabilities: w.router.Capabilities(),
+		Transports:   append([]string{w.config.WorkTransport}, w.transports...),
+		SchemaVersions: map[string]string{
+			"work_request": WorkRequestSchemaVersion,
+			"decision":     DecisionSchemaVersion,
+			"node_config":  router.NodeConfigSchemaVersion,
+		},
+	}
+}
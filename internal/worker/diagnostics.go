@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// recentErrorLimit is the number of errors kept for /diagnostics
+const recentErrorLimit = 20
+
+// ErrorRecord is a recent request failure reported by /diagnostics
+type ErrorRecord struct {
+	Time        time.Time `json:"time"`
+	ExecutionID string    `json:"execution_id,omitempty"`
+	Stage       string    `json:"stage"`
+	Error       string    `json:"error"`
+}
+
+// Diagnostics summarizes recent worker activity for on-call operators
+type Diagnostics struct {
+	// LastDecisionAt is when the last routing decision was made
+	LastDecisionAt *time.Time `json:"last_decision_at,omitempty"`
+	// QueueWaitSeconds is how long the last request waited in the work queue
+	QueueWaitSeconds float64 `json:"queue_wait_seconds"`
+	// Backlog is the consumer group state of each consumed stream
+	Backlog map[string]StreamBacklog `json:"backlog,omitempty"`
+	// PendingRetries is the number of requests waiting for redelivery
+	// after a retryable failure
+	PendingRetries int `json:"pending_retries"`
+	// RecentErrors are the latest failures, newest first
+	RecentErrors []ErrorRecord `json:"recent_errors"`
+}
+
+// StreamBacklog is the consumer group backlog of a work stream
+type StreamBacklog struct {
+	// Lag is the number of entries not yet delivered to the group
+	Lag int64 `json:"lag"`
+	// Pending is the number of delivered entries not yet acked
+	Pending int64  `json:"pending"`
+	Error   string `json:"error,omitempty"`
+}
+
+// activity records the facts reported by Diagnostics
+type activity struct {
+	mu             sync.Mutex
+	lastDecisionAt time.Time
+	queueWait      time.Duration
+	errors         []ErrorRecord
+}
+
+// recordRequest notes the queue wait of a request about to be routed
+func (a *activity) recordRequest(request *WorkRequest) {
+	if request.enqueuedAt.IsZero() || request.receivedAt.IsZero() {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.queueWait = request.receivedAt.Sub(request.enqueuedAt)
+}
+
+// recordDecision notes a successful routing decision
+func (a *activity) recordDecision() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastDecisionAt = time.Now()
+}
+
+// recordError keeps a failure, dropping the oldest beyond recentErrorLimit
+func (a *activity) recordError(executionID, stage string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.errors = append(a.errors, ErrorRecord{
+		Time:        time.Now().UTC(),
+		ExecutionID: executionID,
+		Stage:       stage,
+		Error:       err.Error(),
+	})
+	if len(a.errors) > recentErrorLimit {
+		a.errors = a.errors[len(a.errors)-recentErrorLimit:]
+	}
+}
+
+// RecordError reports a request failure seen by a transport, such as an
+// invalid message or an outcome that could not be published, in /diagnostics
+func (w *Worker) RecordError(executionID, stage string, err error) {
+	w.activity.recordError(executionID, stage, err)
+}
+
+// Diagnostics returns the recent worker activity and the backlog of the
+// consumed streams
+func (w *Worker) Diagnostics(ctx context.Context) Diagnostics {
+	diag := w.activity.snapshot()
+	diag.PendingRetries = w.retries.len()
+
+	streams := w.shards.Streams()
+	if len(streams) > 0 {
+		diag.Backlog = make(map[string]StreamBacklog, len(streams))
+		for _, stream := range streams {
+			diag.Backlog[stream] = w.backlog(ctx, stream)
+		}
+	}
+	return diag
+}
+
+// backlog reads the consumer group lag and pending count of a stream
+func (w *Worker) backlog(ctx context.Context, stream string) StreamBacklog {
+	groups, err := w.redisClient.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return StreamBacklog{Error: err.Error()}
+	}
+	for _, group := range groups {
+		if group.Name == w.consumerGroup {
+			return StreamBacklog{Lag: group.Lag, Pending: group.Pending}
+		}
+	}
+	return StreamBacklog{Error: "consumer group not found"}
+}
+
+// snapshot copies the recorded activity
+func (a *activity) snapshot() Diagnostics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	diag := Diagnostics{
+		QueueWaitSeconds: a.queueWait.Seconds(),
+		RecentErrors:     make([]ErrorRecord, 0, len(a.errors)),
+	}
+	if !a.lastDecisionAt.IsZero() {
+		last := a.lastDecisionAt.UTC()
+		diag.LastDecisionAt = &last
+	}
+	for i := len(a.errors) - 1; i >= 0; i-- {
+		diag.RecentErrors = append(diag.RecentErrors, a.errors[i])
+	}
+	return diag
+}
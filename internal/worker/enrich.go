@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/aescanero/dago-node-router/internal/router"
+	"go.uber.org/zap"
+)
+
+// Enricher adds computed fields (cost center, priority mapping, ...) to a
+// decision just before it is published. Enrichers run after the decision
+// field mask, so the fields they add are always published.
+type Enricher interface {
+	Enrich(ctx context.Context, request *WorkRequest, result *router.RoutingResult, decision map[string]interface{}) error
+}
+
+// EnricherFunc adapts a function to the Enricher interface
+type EnricherFunc func(ctx context.Context, request *WorkRequest, result *router.RoutingResult, decision map[string]interface{}) error
+
+// Enrich calls f
+func (f EnricherFunc) Enrich(ctx context.Context, request *WorkRequest, result *router.RoutingResult, decision map[string]interface{}) error {
+	return f(ctx, request, result, decision)
+}
+
+// Option configures optional worker behavior
+type Option func(*Worker)
+
+// WithEnrichers runs the given enrichers, in order, on every published decision
+func WithEnrichers(enrichers ...Enricher) Option {
+	return func(w *Worker) {
+		w.enrichers = append(w.enrichers, enrichers...)
+	}
+}
+
+// WithVersion tags every published decision with the worker version
+func WithVersion(version string) Option {
+	return func(w *Worker) {
+		w.version = version
+	}
+}
+
+// enrich applies the registered enrichers. A failing enricher is logged and
+// skipped; it never blocks the decision.
+func (w *Worker) enrich(ctx context.Context, request *WorkRequest, result *router.RoutingResult, decision map[string]interface{}) {
+	for i, enricher := range w.enrichers {
+		if err := enricher.Enrich(ctx, request, result, decision); err != nil {
+			w.log(ctx).Warn("decision enricher failed",
+				zap.Int("enricher", i),
+				zap.Error(err),
+			)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"go.uber.org/zap"
+)
+
+// experimentValues encodes the experiment result of a decision for the
+// experiment stream, or returns nil when the decision was not made within an
+// experiment or the stream is disabled. Unlike decisions, experiment results
+// always carry the same fields, so they can be analyzed whatever
+// DECISION_FIELDS publishes.
+func (w *Worker) experimentValues(request *WorkRequest, result *router.RoutingResult) (map[string]interface{}, error) {
+	if result == nil || result.Experiment == "" || w.config.ExperimentStream == "" {
+		return nil, nil
+	}
+
+	entry := map[string]interface{}{
+		"execution_id":  request.ExecutionID,
+		"node_id":       request.NodeID,
+		"experiment_id": result.Experiment,
+		"variant":       result.Variant,
+		"target_node":   result.TargetNode,
+		"mode":          result.Mode,
+		"path_taken":    result.PathTaken,
+		"timestamp":     time.Now().UTC(),
+	}
+	if result.Confidence > 0 {
+		entry["confidence"] = result.Confidence
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal experiment result: %w", err)
+	}
+
+	return map[string]interface{}{
+		"data": string(data),
+	}, nil
+}
+
+// sendExperiment publishes the experiment result of an outcome, if any. The
+// result is best effort: a failed publish is logged and counted but does not
+// hold back the ack of the request.
+func (w *Worker) sendExperiment(request *WorkRequest, outcome *Outcome) {
+	if outcome.ExperimentValues == nil {
+		return
+	}
+
+	w.send(w.config.ExperimentStream, outcome.ExperimentValues, func(err error) {
+		if err != nil {
+			w.ExperimentPublishFailed(request.ExecutionID, err)
+		}
+	})
+}
+
+// ExperimentPublishFailed records an experiment result that could not be
+// published by any transport
+func (w *Worker) ExperimentPublishFailed(executionID string, err error) {
+	metrics.ExperimentPublishErrors.Inc()
+	w.logger.Warn("failed to publish experiment result",
+		zap.String("execution_id", executionID),
+		zap.String("stream", w.config.ExperimentStream),
+		zap.Error(err),
+	)
+}
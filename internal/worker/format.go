@@ -0,0 +1,221 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/router"
+	routerv1 "github.com/aescanero/dago-node-router/proto/router/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Payload formats of stream entries, named by their "format" field. Decisions
+// and error events are published in the format of their work request.
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+)
+
+// payloadFormat returns the format named by the "format" field of message
+// values, JSON when unset
+func payloadFormat(values map[string]interface{}) (string, error) {
+	format, _ := values["format"].(string)
+	switch format {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatProtobuf:
+		return FormatProtobuf, nil
+	}
+	return "", fmt.Errorf("unsupported format %q (supported: %s, %s)", format, FormatJSON, FormatProtobuf)
+}
+
+// decodeProtoWorkRequest decodes a dago.router.v1.WorkRequest. It returns the
+// request and the numbers of its unknown fields, as "#<number>".
+func decodeProtoWorkRequest(data []byte) (*WorkRequest, []string, error) {
+	var message routerv1.WorkRequest
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return nil, nil, fmt.Errorf("malformed protobuf: %w", err)
+	}
+
+	request := &WorkRequest{
+		SchemaVersion: int(message.GetSchemaVersion()),
+		ExecutionID:   message.GetExecutionId(),
+		NodeID:        message.GetNodeId(),
+		TenantID:      message.GetTenantId(),
+		format:        FormatProtobuf,
+	}
+	if message.GetConfig() != nil {
+		request.Config = message.GetConfig().AsMap()
+	}
+	if h := message.GetHeaders(); h != nil {
+		request.Headers = &router.ExecutionContext{
+			Tenant:           h.GetTenant(),
+			Environment:      h.GetEnvironment(),
+			Locale:           h.GetLocale(),
+			ExperimentBucket: h.GetExperimentBucket(),
+		}
+	}
+
+	return request, unknownFieldNumbers(message.ProtoReflect().GetUnknown()), nil
+}
+
+// unknownFieldNumbers lists the distinct field numbers of raw unknown fields
+func unknownFieldNumbers(raw []byte) []string {
+	seen := make(map[protowire.Number]bool)
+	for len(raw) > 0 {
+		number, _, n := protowire.ConsumeField(raw)
+		if n < 0 {
+			break
+		}
+		seen[number] = true
+		raw = raw[n:]
+	}
+
+	numbers := make([]int, 0, len(seen))
+	for number := range seen {
+		numbers = append(numbers, int(number))
+	}
+	sort.Ints(numbers)
+	fields := make([]string, len(numbers))
+	for i, number := range numbers {
+		fields[i] = "#" + strconv.Itoa(number)
+	}
+	return fields
+}
+
+// encodeJSON encodes the fields of a decision or error event as the values
+// of a JSON stream entry
+func encodeJSON(fields map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"data": string(data)}, nil
+}
+
+// encodeProto encodes a decision or error event message as the values of a
+// protobuf stream entry
+func encodeProto(message proto.Message) (map[string]interface{}, error) {
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"data":   string(data),
+		"format": FormatProtobuf,
+	}, nil
+}
+
+// decisionMessage builds the protobuf decision of a routing result. Fields
+// masked by DECISION_FIELDS are left unset; extra holds the fields added by
+// decision enrichers.
+func decisionMessage(fields map[string]bool, request *WorkRequest, result *router.RoutingResult, now time.Time, version string, extra map[string]interface{}) (*routerv1.Decision, error) {
+	message := &routerv1.Decision{
+		ExecutionId: request.ExecutionID,
+		NodeId:      request.NodeID,
+		TargetNode:  result.TargetNode,
+	}
+	if fields["reasoning"] {
+		message.Reasoning = result.Reasoning
+	}
+	if fields["mode"] {
+		message.Mode = result.Mode
+	}
+	if fields["path_taken"] {
+		message.PathTaken = result.PathTaken
+	}
+	if fields["timestamp"] {
+		message.Timestamp = timestamppb.New(now)
+	}
+	if !request.receivedAt.IsZero() {
+		if fields["processing_ms"] {
+			message.ProcessingMs = now.Sub(request.receivedAt).Milliseconds()
+		}
+		if fields["queue_wait_ms"] && !request.enqueuedAt.IsZero() {
+			message.QueueWaitMs = request.receivedAt.Sub(request.enqueuedAt).Milliseconds()
+		}
+	}
+	if fields["confidence"] {
+		message.Confidence = result.Confidence
+	}
+	if fields["stages"] && len(result.Stages) > 0 {
+		message.Stages = stageList(result.Stages)
+	}
+	if fields["variant"] {
+		if result.Experiment != "" {
+			message.Variant = result.Variant
+		} else if request.Headers != nil {
+			message.Variant = request.Headers.ExperimentBucket
+		}
+	}
+	if fields["experiment_id"] {
+		message.ExperimentId = result.Experiment
+	}
+	if fields["tenant"] {
+		message.Tenant = result.Tenant
+	}
+	if fields["prompt_hash"] {
+		message.PromptHash = result.PromptHash
+	}
+	if result.RuleIndex != nil {
+		if fields["rule_index"] {
+			message.RuleIndex = proto.Int32(int32(*result.RuleIndex))
+		}
+		if fields["condition"] {
+			message.Condition = result.Condition
+		}
+	}
+	if fields["rule_sets"] {
+		message.RuleSets = result.RuleSets
+	}
+	if fields["pipeline_stage"] {
+		message.PipelineStage = result.PipelineStage
+	}
+	if fields["timings"] && result.Timings != nil {
+		message.Timings = &routerv1.Timings{
+			StateLoadMs: result.Timings.StateLoadMS,
+			CelMs:       result.Timings.CELMS,
+			LlmMs:       result.Timings.LLMMS,
+		}
+	}
+	if fields["worker_version"] {
+		message.WorkerVersion = version
+	}
+
+	if len(extra) > 0 {
+		// Enrichers add values of any type; JSON is how they are published
+		data, err := json.Marshal(extra)
+		if err != nil {
+			return nil, fmt.Errorf("extra fields: %w", err)
+		}
+		message.Extra = &structpb.Struct{}
+		if err := protojson.Unmarshal(data, message.Extra); err != nil {
+			return nil, fmt.Errorf("extra fields: %w", err)
+		}
+	}
+	return message, nil
+}
+
+// stageList converts the stages of a hierarchical LLM classification to the
+// list of objects they are published as
+func stageList(stages []router.StageResult) *structpb.ListValue {
+	list := &structpb.ListValue{Values: make([]*structpb.Value, len(stages))}
+	for i, stage := range stages {
+		object := map[string]*structpb.Value{
+			"stage":    structpb.NewStringValue(stage.Stage),
+			"response": structpb.NewStringValue(stage.Response),
+		}
+		if stage.Choice != "" {
+			object["choice"] = structpb.NewStringValue(stage.Choice)
+		}
+		list.Values[i] = structpb.NewStructValue(&structpb.Struct{Fields: object})
+	}
+	return list
+}
@@ -3,29 +3,203 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"os"
 	"time"
 
+	"github.com/aescanero/dago-node-router/internal/audit"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/policies"
+	"github.com/aescanero/dago-node-router/internal/prompts"
+	"github.com/aescanero/dago-node-router/internal/router"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
 // HealthServer provides HTTP health check endpoints
 type HealthServer struct {
-	port        int
-	redisClient *redis.Client
-	logger      *zap.Logger
-	server      *http.Server
+	port         int
+	host         string
+	socketPath   string
+	redisClient  redis.UniversalClient
+	checks       map[string]HealthCheckFunc
+	readiness    map[string]HealthCheckFunc
+	details      map[string]func() interface{}
+	capabilities func() Capabilities
+	validate     func(*router.NodeConfig) []router.ValidationError
+	lint         func(*router.NodeConfig) *router.LintReport
+	ruleStats    func(nodeID string) *router.RuleStats
+	ruleOrders   func() []router.RuleOrder
+	resetOrder   func(nodeID string) int
+	diagnostics  map[string]DiagnosticFunc
+	auditQuery   AuditQueryFunc
+	templates    *prompts.Library
+	ruleSets     *policies.Registry
+	lagReport    func() *LagReport
+	pprof        bool
+	logger       *zap.Logger
+	server       *http.Server
+}
+
+// HealthCheckFunc reports a component health error, or nil when healthy
+type HealthCheckFunc func(ctx context.Context) error
+
+// DiagnosticFunc reports a section of the /diagnostics response
+type DiagnosticFunc func(ctx context.Context) interface{}
+
+// AuditQueryFunc returns the audit records of an execution
+type AuditQueryFunc func(ctx context.Context, executionID string) ([]*audit.Record, error)
+
+// HealthOption configures optional health server behavior
+type HealthOption func(*HealthServer)
+
+// WithHealthHost binds the health server to a single host (e.g. 127.0.0.1)
+// instead of all interfaces
+func WithHealthHost(host string) HealthOption {
+	return func(hs *HealthServer) {
+		hs.host = host
+	}
+}
+
+// WithHealthSocket serves the health server on a Unix domain socket instead
+// of a TCP port
+func WithHealthSocket(path string) HealthOption {
+	return func(hs *HealthServer) {
+		hs.socketPath = path
+	}
+}
+
+// WithHealthCheck adds a named component check to the /health endpoint
+func WithHealthCheck(name string, check HealthCheckFunc) HealthOption {
+	return func(hs *HealthServer) {
+		hs.checks[name] = check
+	}
+}
+
+// WithReadinessCheck adds a named component check to the /ready endpoint
+func WithReadinessCheck(name string, check HealthCheckFunc) HealthOption {
+	return func(hs *HealthServer) {
+		hs.readiness[name] = check
+	}
+}
+
+// WithHealthDetail adds informational component state to the /health response
+// without affecting the health status
+func WithHealthDetail(name string, detail func() interface{}) HealthOption {
+	return func(hs *HealthServer) {
+		hs.details[name] = detail
+	}
+}
+
+// WithCapabilities serves the worker capabilities under /capabilities
+func WithCapabilities(capabilities func() Capabilities) HealthOption {
+	return func(hs *HealthServer) {
+		hs.capabilities = capabilities
+	}
+}
+
+// WithConfigValidation serves a pre-flight NodeConfig check under /validate
+func WithConfigValidation(validate func(*router.NodeConfig) []router.ValidationError) HealthOption {
+	return func(hs *HealthServer) {
+		hs.validate = validate
+	}
+}
+
+// WithConfigLint adds the expression complexity and lint warnings of the
+// checked NodeConfig to /validate responses
+func WithConfigLint(lint func(*router.NodeConfig) *router.LintReport) HealthOption {
+	return func(hs *HealthServer) {
+		hs.lint = lint
+	}
+}
+
+// WithRuleStats adds the rule and pipeline stage hit counters of the node
+// named by ?node_id= to /validate responses
+func WithRuleStats(stats func(nodeID string) *router.RuleStats) HealthOption {
+	return func(hs *HealthServer) {
+		hs.ruleStats = stats
+	}
+}
+
+// WithRuleOrders lists the rule orders chosen by the rule optimizer under
+// /rules/order and resets them on POST /rules/order/reset
+func WithRuleOrders(orders func() []router.RuleOrder, reset func(nodeID string) int) HealthOption {
+	return func(hs *HealthServer) {
+		hs.ruleOrders = orders
+		hs.resetOrder = reset
+	}
+}
+
+// WithDiagnostics adds a named section to the /diagnostics endpoint, which
+// bundles the runtime facts needed at the start of an incident
+func WithDiagnostics(name string, section DiagnosticFunc) HealthOption {
+	return func(hs *HealthServer) {
+		hs.diagnostics[name] = section
+	}
+}
+
+// WithAuditQuery serves the audit records of an execution under
+// /audit?execution_id=...
+func WithAuditQuery(query AuditQueryFunc) HealthOption {
+	return func(hs *HealthServer) {
+		hs.auditQuery = query
+	}
+}
+
+// WithTemplateLibrary lists the prompt template library under /templates and
+// reloads it on POST /templates/reload
+func WithTemplateLibrary(library *prompts.Library) HealthOption {
+	return func(hs *HealthServer) {
+		hs.templates = library
+	}
+}
+
+// WithRuleSets lists the rule set registry under /policies and reloads it on
+// POST /policies/reload
+func WithRuleSets(registry *policies.Registry) HealthOption {
+	return func(hs *HealthServer) {
+		hs.ruleSets = registry
+	}
+}
+
+// WithLagReport serves the consumer group lag under /lag, in a shape KEDA's
+// metrics-api scaler reads (valueLocation "backlog")
+func WithLagReport(report func() *LagReport) HealthOption {
+	return func(hs *HealthServer) {
+		hs.lagReport = report
+	}
+}
+
+// WithPprof serves the net/http/pprof profiles under /debug/pprof/. Profiles
+// expose stacks and memory contents, so the health server should not be
+// reachable from outside the cluster when it is enabled.
+func WithPprof() HealthOption {
+	return func(hs *HealthServer) {
+		hs.pprof = true
+	}
 }
 
 // NewHealthServer creates a new health server
-func NewHealthServer(port int, redisClient *redis.Client, logger *zap.Logger) *HealthServer {
-	return &HealthServer{
+func NewHealthServer(port int, redisClient redis.UniversalClient, logger *zap.Logger, opts ...HealthOption) *HealthServer {
+	hs := &HealthServer{
 		port:        port,
 		redisClient: redisClient,
+		checks:      make(map[string]HealthCheckFunc),
+		readiness:   make(map[string]HealthCheckFunc),
+		details:     make(map[string]func() interface{}),
+		diagnostics: make(map[string]DiagnosticFunc),
 		logger:      logger,
 	}
+
+	for _, opt := range opts {
+		opt(hs)
+	}
+
+	return hs
 }
 
 // Start starts the health check server
@@ -33,17 +207,60 @@ func (hs *HealthServer) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", hs.handleHealth)
 	mux.HandleFunc("/ready", hs.handleReady)
+	mux.HandleFunc("/live", hs.handleLive)
+	mux.Handle("/metrics", metrics.Handler())
+	if hs.capabilities != nil {
+		mux.HandleFunc("/capabilities", hs.handleCapabilities)
+	}
+	if hs.validate != nil {
+		mux.HandleFunc("/validate", hs.handleValidate)
+	}
+	if len(hs.diagnostics) > 0 {
+		mux.HandleFunc("/diagnostics", hs.handleDiagnostics)
+	}
+	if hs.auditQuery != nil {
+		mux.HandleFunc("/audit", hs.handleAudit)
+	}
+	if hs.templates != nil {
+		mux.HandleFunc("/templates", hs.handleTemplates)
+		mux.HandleFunc("/templates/reload", hs.handleTemplatesReload)
+	}
+	if hs.ruleSets != nil {
+		mux.HandleFunc("/policies", hs.handlePolicies)
+		mux.HandleFunc("/policies/reload", hs.handlePoliciesReload)
+	}
+
+	if hs.ruleOrders != nil {
+		mux.HandleFunc("/rules/order", hs.handleRuleOrders)
+		mux.HandleFunc("/rules/order/reset", hs.handleRuleOrderReset)
+	}
+
+	if hs.lagReport != nil {
+		mux.HandleFunc("/lag", hs.handleLag)
+	}
+
+	if hs.pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
 
 	hs.server = &http.Server{
-		Addr:              fmt.Sprintf(":%d", hs.port),
 		Handler:           mux,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
-	hs.logger.Info("starting health server", zap.Int("port", hs.port))
+	listener, err := hs.listen()
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	hs.logger.Info("starting health server", zap.String("addr", listener.Addr().String()))
 
 	go func() {
-		if err := hs.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := hs.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			hs.logger.Error("health server error", zap.Error(err))
 		}
 	}()
@@ -51,6 +268,30 @@ func (hs *HealthServer) Start() error {
 	return nil
 }
 
+// listen opens the Unix socket or TCP listener for the health server
+func (hs *HealthServer) listen() (net.Listener, error) {
+	if hs.socketPath == "" {
+		return net.Listen("tcp", net.JoinHostPort(hs.host, fmt.Sprint(hs.port)))
+	}
+
+	// Remove a stale socket left behind by a previous process
+	if err := os.Remove(hs.socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", hs.socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(hs.socketPath, 0o660); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+
+	return listener, nil
+}
+
 // Stop stops the health check server
 func (hs *HealthServer) Stop() error {
 	if hs.server == nil {
@@ -66,8 +307,9 @@ func (hs *HealthServer) Stop() error {
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status string            `json:"status"`
-	Checks map[string]string `json:"checks,omitempty"`
+	Status  string                 `json:"status"`
+	Checks  map[string]string      `json:"checks,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
 }
 
 // handleHealth handles the /health endpoint
@@ -88,10 +330,263 @@ func (hs *HealthServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	}
 	checks["redis"] = "healthy"
 
+	// Check registered components
+	healthy := true
+	for name, check := range hs.checks {
+		if err := check(ctx); err != nil {
+			checks[name] = fmt.Sprintf("unhealthy: %v", err)
+			healthy = false
+			continue
+		}
+		checks[name] = "healthy"
+	}
+
+	var details map[string]interface{}
+	if len(hs.details) > 0 {
+		details = make(map[string]interface{}, len(hs.details))
+		for name, detail := range hs.details {
+			details[name] = detail()
+		}
+	}
+
+	if !healthy {
+		hs.respondJSON(w, http.StatusServiceUnavailable, HealthResponse{
+			Status:  "unhealthy",
+			Checks:  checks,
+			Details: details,
+		})
+		return
+	}
+
 	// All checks passed
 	hs.respondJSON(w, http.StatusOK, HealthResponse{
-		Status: "healthy",
-		Checks: checks,
+		Status:  "healthy",
+		Checks:  checks,
+		Details: details,
+	})
+}
+
+// handleCapabilities handles the /capabilities endpoint
+func (hs *HealthServer) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	hs.respondJSON(w, http.StatusOK, hs.capabilities())
+}
+
+// maxValidateBodyBytes bounds the NodeConfig accepted by /validate
+const maxValidateBodyBytes = 1 << 20
+
+// ValidateResponse represents the /validate response
+type ValidateResponse struct {
+	Valid  bool                     `json:"valid"`
+	Errors []router.ValidationError `json:"errors,omitempty"`
+	// Hits are the hit counters of the node named by ?node_id=
+	Hits *router.RuleStats `json:"hits,omitempty"`
+	// Lint reports expression complexity and rules that can never decide a
+	// route; warnings do not make a config invalid
+	Lint *router.LintReport `json:"lint,omitempty"`
+}
+
+// handleValidate handles the /validate endpoint, checking a NodeConfig JSON body
+func (hs *HealthServer) handleValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var config router.NodeConfig
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxValidateBodyBytes))
+	if err := decoder.Decode(&config); err != nil {
+		hs.respondJSON(w, http.StatusBadRequest, ValidateResponse{
+			Errors: []router.ValidationError{{Message: fmt.Sprintf("invalid JSON: %v", err)}},
+		})
+		return
+	}
+
+	var hits *router.RuleStats
+	if nodeID := r.URL.Query().Get("node_id"); nodeID != "" && hs.ruleStats != nil {
+		hits = hs.ruleStats(nodeID)
+	}
+
+	var lint *router.LintReport
+	if hs.lint != nil {
+		lint = hs.lint(&config)
+	}
+
+	errs := hs.validate(&config)
+	if len(errs) > 0 {
+		hs.respondJSON(w, http.StatusUnprocessableEntity, ValidateResponse{Errors: errs, Hits: hits, Lint: lint})
+		return
+	}
+	hs.respondJSON(w, http.StatusOK, ValidateResponse{Valid: true, Hits: hits, Lint: lint})
+}
+
+// handleDiagnostics handles the /diagnostics endpoint
+func (hs *HealthServer) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	response := make(map[string]interface{}, len(hs.diagnostics)+1)
+	response["generated_at"] = time.Now().UTC()
+	for name, section := range hs.diagnostics {
+		response[name] = section(ctx)
+	}
+	hs.respondJSON(w, http.StatusOK, response)
+}
+
+// AuditResponse represents the /audit response
+type AuditResponse struct {
+	ExecutionID string          `json:"execution_id"`
+	Records     []*audit.Record `json:"records"`
+}
+
+// handleAudit handles the /audit endpoint
+func (hs *HealthServer) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	executionID := r.URL.Query().Get("execution_id")
+	if executionID == "" {
+		http.Error(w, "execution_id is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	records, err := hs.auditQuery(ctx, executionID)
+	if err != nil {
+		hs.logger.Error("failed to query audit log",
+			zap.String("execution_id", executionID),
+			zap.Error(err),
+		)
+		http.Error(w, "failed to query audit log", http.StatusInternalServerError)
+		return
+	}
+	hs.respondJSON(w, http.StatusOK, AuditResponse{ExecutionID: executionID, Records: records})
+}
+
+// TemplatesResponse represents the /templates response
+type TemplatesResponse struct {
+	LoadedAt  time.Time       `json:"loaded_at"`
+	Templates []prompts.Entry `json:"templates"`
+}
+
+// handleTemplates handles the /templates endpoint
+func (hs *HealthServer) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	hs.respondJSON(w, http.StatusOK, TemplatesResponse{
+		LoadedAt:  hs.templates.LoadedAt(),
+		Templates: hs.templates.List(),
+	})
+}
+
+// handleTemplatesReload handles the /templates/reload endpoint, reloading the
+// template library immediately instead of at its next interval
+func (hs *HealthServer) handleTemplatesReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := hs.templates.Reload(ctx); err != nil {
+		hs.logger.Error("failed to reload prompt templates", zap.Error(err))
+		http.Error(w, fmt.Sprintf("failed to reload prompt templates: %v", err), http.StatusInternalServerError)
+		return
+	}
+	hs.respondJSON(w, http.StatusOK, TemplatesResponse{
+		LoadedAt:  hs.templates.LoadedAt(),
+		Templates: hs.templates.List(),
+	})
+}
+
+// PoliciesResponse represents the /policies response
+type PoliciesResponse struct {
+	LoadedAt time.Time        `json:"loaded_at"`
+	RuleSets []policies.Entry `json:"rule_sets"`
+}
+
+// handlePolicies handles the /policies endpoint
+func (hs *HealthServer) handlePolicies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	hs.respondJSON(w, http.StatusOK, PoliciesResponse{
+		LoadedAt: hs.ruleSets.LoadedAt(),
+		RuleSets: hs.ruleSets.List(),
+	})
+}
+
+// handlePoliciesReload handles the /policies/reload endpoint, reloading the
+// rule set registry immediately, e.g. after pinning a rollback
+func (hs *HealthServer) handlePoliciesReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := hs.ruleSets.Reload(ctx); err != nil {
+		hs.logger.Error("failed to reload rule sets", zap.Error(err))
+		http.Error(w, fmt.Sprintf("failed to reload rule sets: %v", err), http.StatusInternalServerError)
+		return
+	}
+	hs.respondJSON(w, http.StatusOK, PoliciesResponse{
+		LoadedAt: hs.ruleSets.LoadedAt(),
+		RuleSets: hs.ruleSets.List(),
+	})
+}
+
+// RuleOrdersResponse represents the /rules/order response
+type RuleOrdersResponse struct {
+	Orders []router.RuleOrder `json:"orders"`
+	// Reset is the number of rule lists restored to their declared order
+	Reset int `json:"reset,omitempty"`
+}
+
+// handleRuleOrders handles the /rules/order endpoint
+func (hs *HealthServer) handleRuleOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	hs.respondJSON(w, http.StatusOK, RuleOrdersResponse{Orders: hs.ruleOrders()})
+}
+
+// handleRuleOrderReset handles the /rules/order/reset endpoint, restoring the
+// declared rule order of ?node_id= (every node when unset)
+func (hs *HealthServer) handleRuleOrderReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	reset := hs.resetOrder(r.URL.Query().Get("node_id"))
+	hs.respondJSON(w, http.StatusOK, RuleOrdersResponse{Orders: hs.ruleOrders(), Reset: reset})
+}
+
+// handleLive handles the /live endpoint. It only reports that the process
+// serves requests, so liveness probes do not restart workers during a Redis
+// outage.
+func (hs *HealthServer) handleLive(w http.ResponseWriter, r *http.Request) {
+	hs.respondJSON(w, http.StatusOK, HealthResponse{
+		Status: "alive",
 	})
 }
 
@@ -108,6 +603,25 @@ func (hs *HealthServer) handleReady(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check registered readiness components
+	checks := make(map[string]string)
+	ready := true
+	for name, check := range hs.readiness {
+		if err := check(ctx); err != nil {
+			checks[name] = fmt.Sprintf("not ready: %v", err)
+			ready = false
+			continue
+		}
+		checks[name] = "ready"
+	}
+	if !ready {
+		hs.respondJSON(w, http.StatusServiceUnavailable, HealthResponse{
+			Status: "not ready",
+			Checks: checks,
+		})
+		return
+	}
+
 	// Worker is ready
 	hs.respondJSON(w, http.StatusOK, HealthResponse{
 		Status: "ready",
@@ -123,3 +637,21 @@ func (hs *HealthServer) respondJSON(w http.ResponseWriter, statusCode int, data
 		hs.logger.Error("failed to encode response", zap.Error(err))
 	}
 }
+
+// handleLag handles the /lag endpoint, reporting the last consumer group lag
+// measurement. It answers 503 until the first measurement succeeds, so an
+// autoscaler falls back instead of scaling on a missing value.
+func (hs *HealthServer) handleLag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := hs.lagReport()
+	if report == nil {
+		http.Error(w, "lag not measured yet", http.StatusServiceUnavailable)
+		return
+	}
+	hs.respondJSON(w, http.StatusOK, report)
+}
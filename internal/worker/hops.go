@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrLoopDetected is reported for executions that already received MAX_HOPS
+// routing decisions, most likely from a cycle in their graph
+var ErrLoopDetected = errors.New("loop detected")
+
+// hopPrefix namespaces the per-execution hop counters in Redis
+const hopPrefix = "router:hops:"
+
+// Error codes of error events, for failures consumers handle specifically
+const (
+	errorCodeLoopDetected = "loop_detected"
+)
+
+// hopKey identifies the hop counter of an execution
+func hopKey(executionID string) string {
+	return hopPrefix + executionID
+}
+
+// checkHops refuses to route executions that reached MAX_HOPS published
+// decisions. Lookup failures are logged and the request is routed.
+func (w *Worker) checkHops(ctx context.Context, request *WorkRequest) error {
+	if w.config.MaxHops <= 0 {
+		return nil
+	}
+
+	hops, err := w.redisClient.Get(ctx, hopKey(request.ExecutionID)).Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		w.log(ctx).Warn("failed to read hop count, routing request",
+			zap.Error(err),
+		)
+		return nil
+	}
+	if hops < w.config.MaxHops {
+		return nil
+	}
+
+	metrics.LoopsDetected.Inc()
+	return fmt.Errorf("%w: execution %s was routed %d times (MAX_HOPS %d)",
+		ErrLoopDetected, request.ExecutionID, hops, w.config.MaxHops)
+}
+
+// RecordHop counts a published decision towards the execution's MAX_HOPS.
+// The counter expires HOP_COUNT_TTL after the last decision.
+func (w *Worker) RecordHop(ctx context.Context, request *WorkRequest) {
+	if w.config.MaxHops <= 0 {
+		return
+	}
+
+	key := hopKey(request.ExecutionID)
+	pipe := w.redisClient.TxPipeline()
+	pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, w.config.HopCountTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		w.log(ctx).Warn("failed to record hop, the loop guard may undercount",
+			zap.Error(err),
+		)
+	}
+}
+
+// errorCode returns the code of error events for failures consumers handle
+// specifically, or ""
+func errorCode(err error) string {
+	if errors.Is(err, ErrLoopDetected) {
+		return errorCodeLoopDetected
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// idempotencyPrefix namespaces the published outcome markers in Redis
+const idempotencyPrefix = "router:published:"
+
+// idempotencyKey identifies the outcome of one delivery of a work request
+func idempotencyKey(request *WorkRequest, messageID string) string {
+	return idempotencyPrefix + request.ExecutionID + ":" + request.NodeID + ":" + messageID
+}
+
+// IsDuplicate reports whether the outcome of this message was already
+// published, so a redelivered message must not publish it again. Lookup
+// failures are logged and treated as not published.
+func (w *Worker) IsDuplicate(ctx context.Context, request *WorkRequest, messageID string) bool {
+	if !w.config.IdempotencyEnabled {
+		return false
+	}
+
+	exists, err := w.redisClient.Exists(ctx, idempotencyKey(request, messageID)).Result()
+	if err != nil {
+		w.log(ctx).Warn("failed to check idempotency key, processing message",
+			zap.Error(err),
+		)
+		return false
+	}
+	if exists == 0 {
+		return false
+	}
+
+	metrics.DuplicatesSkipped.Inc()
+	w.log(ctx).Info("skipping redelivered message, outcome already published")
+	return true
+}
+
+// MarkPublished records that the outcome of this message was published, for
+// IdempotencyTTL
+func (w *Worker) MarkPublished(ctx context.Context, request *WorkRequest, messageID string) {
+	if !w.config.IdempotencyEnabled {
+		return
+	}
+
+	err := w.redisClient.Set(ctx, idempotencyKey(request, messageID), 1, w.config.IdempotencyTTL).Err()
+	if err != nil {
+		w.log(ctx).Warn("failed to record idempotency key, a redelivery may publish a duplicate",
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,156 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// StreamLag is the backlog of the consumer group on one work stream
+type StreamLag struct {
+	Stream string `json:"stream"`
+	// Lag counts entries not yet delivered to the group (XINFO GROUPS)
+	Lag int64 `json:"lag"`
+	// Pending counts entries delivered but not yet acked (XPENDING)
+	Pending int64 `json:"pending"`
+	// OldestPendingSeconds is the age of the oldest pending entry
+	OldestPendingSeconds float64 `json:"oldest_pending_seconds"`
+}
+
+// LagReport is the consumer group backlog across all work streams of the
+// deployment, which every worker reports alike so any of them can serve as
+// the autoscaling signal
+type LagReport struct {
+	Group string `json:"group"`
+	// Lag and Pending are the totals over Streams
+	Lag     int64 `json:"lag"`
+	Pending int64 `json:"pending"`
+	// Backlog is Lag plus Pending, the work a scaler should size workers for
+	Backlog    int64       `json:"backlog"`
+	Streams    []StreamLag `json:"streams"`
+	MeasuredAt time.Time   `json:"measured_at"`
+}
+
+// LagReport returns the last consumer group lag measurement, or nil before
+// the first one succeeded
+func (w *Worker) LagReport() *LagReport {
+	return w.lag.Load()
+}
+
+// reportLag measures consumer group lag every LAG_REPORT_INTERVAL and
+// publishes it through metrics and LagReport
+func (w *Worker) reportLag() {
+	ticker := time.NewTicker(w.config.LagReportInterval)
+	defer ticker.Stop()
+
+	for {
+		w.updateLag()
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateLag takes one lag measurement; failures keep the previous report
+func (w *Worker) updateLag() {
+	ctx, cancel := context.WithTimeout(w.ctx, w.config.LagReportInterval)
+	defer cancel()
+
+	report, err := w.measureLag(ctx)
+	if err != nil {
+		if w.ctx.Err() == nil {
+			w.logger.Warn("failed to measure consumer group lag", zap.Error(err))
+		}
+		return
+	}
+
+	w.lag.Store(report)
+	for _, stream := range report.Streams {
+		metrics.ConsumerGroupLag.WithLabelValues(stream.Stream).Set(float64(stream.Lag))
+		metrics.ConsumerGroupPending.WithLabelValues(stream.Stream).Set(float64(stream.Pending))
+		metrics.OldestPendingAge.WithLabelValues(stream.Stream).Set(stream.OldestPendingSeconds)
+	}
+}
+
+// measureLag reads the consumer group lag and pending entries of every work
+// stream of the deployment
+func (w *Worker) measureLag(ctx context.Context) (*LagReport, error) {
+	report := &LagReport{Group: w.consumerGroup, MeasuredAt: time.Now()}
+
+	for _, stream := range w.lagStreams() {
+		lag, err := w.streamLag(ctx, stream)
+		if err != nil {
+			return nil, fmt.Errorf("stream %s: %w", stream, err)
+		}
+		report.Lag += lag.Lag
+		report.Pending += lag.Pending
+		report.Streams = append(report.Streams, *lag)
+	}
+	report.Backlog = report.Lag + report.Pending
+
+	return report, nil
+}
+
+// streamLag reads the consumer group backlog of one stream. A stream or
+// group that does not exist yet has no backlog.
+func (w *Worker) streamLag(ctx context.Context, stream string) (*StreamLag, error) {
+	lag := &StreamLag{Stream: stream}
+
+	groups, err := w.redisClient.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		if isNoStreamError(err) {
+			return lag, nil
+		}
+		return nil, err
+	}
+	found := false
+	for _, group := range groups {
+		if group.Name == w.consumerGroup {
+			lag.Lag = group.Lag
+			found = true
+			break
+		}
+	}
+	if !found {
+		return lag, nil
+	}
+
+	pending, err := w.redisClient.XPending(ctx, stream, w.consumerGroup).Result()
+	if err != nil {
+		return nil, err
+	}
+	lag.Pending = pending.Count
+	if oldest, ok := messageTimestamp(pending.Lower); ok && pending.Count > 0 {
+		lag.OldestPendingSeconds = time.Since(oldest).Seconds()
+	}
+
+	return lag, nil
+}
+
+// lagStreams returns every work stream of the deployment: the priority lanes,
+// all shards (not only those this worker owns) or STREAM_KEY
+func (w *Worker) lagStreams() []string {
+	if w.lanes != nil {
+		return w.lanes.Streams()
+	}
+	if w.config.StreamShards > 0 {
+		streams := make([]string, w.config.StreamShards)
+		for shard := range streams {
+			streams[shard] = ShardStream(w.streamKey, shard)
+		}
+		return streams
+	}
+	return []string{w.streamKey}
+}
+
+// isNoStreamError reports whether err is Redis reporting a missing stream
+func isNoStreamError(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "ERR no such key")
+}
@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// laneScheduler orders priority lanes for each read with smooth weighted
+// round-robin: while several lanes have work, each lane is read first in
+// proportion to its weight. It is only used by the work loop.
+type laneScheduler struct {
+	lanes   []config.PriorityLane
+	current []int
+	total   int
+	// byStream maps lane streams to lane names, for metrics
+	byStream map[string]string
+}
+
+// newLaneScheduler creates a scheduler for lanes, highest priority first
+func newLaneScheduler(lanes []config.PriorityLane) *laneScheduler {
+	s := &laneScheduler{
+		lanes:    lanes,
+		current:  make([]int, len(lanes)),
+		byStream: make(map[string]string, len(lanes)),
+	}
+	for _, lane := range lanes {
+		s.total += lane.Weight
+		s.byStream[lane.Stream] = lane.Name
+	}
+	return s
+}
+
+// Streams returns the lane streams, highest priority first
+func (s *laneScheduler) Streams() []string {
+	streams := make([]string, len(s.lanes))
+	for i, lane := range s.lanes {
+		streams[i] = lane.Stream
+	}
+	return streams
+}
+
+// order returns the lane streams in the order to try them for the next read:
+// the lane picked by weight, then the others by priority
+func (s *laneScheduler) order() []string {
+	picked := 0
+	for i, lane := range s.lanes {
+		s.current[i] += lane.Weight
+		if s.current[i] > s.current[picked] {
+			picked = i
+		}
+	}
+	s.current[picked] -= s.total
+
+	order := make([]string, 0, len(s.lanes))
+	order = append(order, s.lanes[picked].Stream)
+	for i, lane := range s.lanes {
+		if i != picked {
+			order = append(order, lane.Stream)
+		}
+	}
+	return order
+}
+
+// laneOf returns the lane name of a stream, or "" when it is not a lane
+func (s *laneScheduler) laneOf(stream string) string {
+	if s == nil {
+		return ""
+	}
+	return s.byStream[stream]
+}
+
+// readLanes reads a batch from the first lane with work, in weighted order.
+// When every lane is empty it blocks on all of them for up to BlockTime.
+func (w *Worker) readLanes() ([]redis.XStream, error) {
+	for _, stream := range w.lanes.order() {
+		streams, err := w.redisClient.XReadGroup(w.ctx, &redis.XReadGroupArgs{
+			Group:    w.consumerGroup,
+			Consumer: w.id,
+			Streams:  []string{stream, ">"},
+			Count:    int64(w.config.BatchSize),
+			Block:    -1,
+		}).Result()
+		if err != redis.Nil {
+			return streams, err
+		}
+	}
+
+	lanes := w.lanes.Streams()
+	keys := append([]string(nil), lanes...)
+	for range lanes {
+		keys = append(keys, ">")
+	}
+	return w.redisClient.XReadGroup(w.ctx, &redis.XReadGroupArgs{
+		Group:    w.consumerGroup,
+		Consumer: w.id,
+		Streams:  keys,
+		Count:    int64(w.config.BatchSize),
+		Block:    w.config.BlockTime,
+	}).Result()
+}
@@ -0,0 +1,36 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/aescanero/dago-node-router/internal/logging"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// log returns the logger of the request handled in ctx, or the worker logger
+func (w *Worker) log(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, w.logger)
+}
+
+// WithMessageLogger returns a context whose logger adds the ID of the message
+// a request is read from, and the trace ID when ctx carries one, to every line
+func (w *Worker) WithMessageLogger(ctx context.Context, messageID string) context.Context {
+	fields := []zap.Field{zap.String("message_id", messageID)}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		fields = append(fields, zap.String("trace_id", spanContext.TraceID().String()))
+	}
+	return logging.With(ctx, w.logger, fields...)
+}
+
+// WithRequestLogger returns a context whose logger adds the execution, node
+// and tenant of request to every line. The tenant is left to the router when
+// the request does not name one. Process adds them itself; transports call
+// it once the request is parsed so their own lines carry them too.
+func (w *Worker) WithRequestLogger(ctx context.Context, request *WorkRequest) context.Context {
+	return logging.With(ctx, w.logger,
+		zap.String("execution_id", request.ExecutionID),
+		zap.String("node_id", request.NodeID),
+		zap.String("tenant", request.tenant()),
+	)
+}
@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/aescanero/dago-node-router/internal/statestore"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ErrRequestTimeout is reported when routing a work request exceeds
+// REQUEST_TIMEOUT
+var ErrRequestTimeout = errors.New("request timed out")
+
+// Outcome is the encoded result of a work request, ready to be published by
+// any transport
+type Outcome struct {
+	// Result is the routing decision; nil when routing failed
+	Result *router.RoutingResult
+	// Err is the routing failure reported by the error event
+	Err error
+	// Values holds the decision or error event in the message "data" field,
+	// with trace context
+	Values map[string]interface{}
+	// StateWrites are written into the execution state together with the
+	// decision, before or atomically with its publication
+	StateWrites []statestore.StateWrite
+	// ExperimentValues holds the experiment result of decisions made within
+	// an experiment, for the experiment stream; nil otherwise
+	ExperimentValues map[string]interface{}
+	// Interrupted is set when routing was cut short because the context
+	// passed to Process was canceled, on shutdown. Nothing is encoded: the
+	// message should be left for redelivery.
+	Interrupted bool
+}
+
+// Failed reports whether the outcome is an error event, published to the
+// ".errors" result stream or topic
+func (o *Outcome) Failed() bool {
+	return o.Err != nil
+}
+
+// Process routes a work request and encodes its outcome, independently of the
+// transport it was read from. Routing failures are encoded as error events;
+// the returned error is only set when the outcome could not be encoded.
+// Routing is bounded by REQUEST_TIMEOUT and stops when ctx is canceled.
+func (w *Worker) Process(ctx context.Context, request *WorkRequest) (*Outcome, error) {
+	outcome := &Outcome{}
+	ctx = w.WithRequestLogger(ctx, request)
+
+	w.activity.recordRequest(request)
+
+	var err error
+	start := time.Now()
+	if outcome.Err = w.checkHops(ctx, request); outcome.Err == nil {
+		outcome.Result, outcome.Err = w.routeWithTimeout(ctx, request)
+	}
+	if outcome.Err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		outcome.Interrupted = true
+		w.log(ctx).Warn("routing request interrupted by shutdown",
+			zap.Error(outcome.Err),
+		)
+		return outcome, nil
+	}
+	w.recordAudit(ctx, request, outcome, time.Since(start))
+	if outcome.Err != nil {
+		w.RecordError(request.ExecutionID, "route", outcome.Err)
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(outcome.Err)
+		span.SetStatus(codes.Error, "routing request failed")
+		w.log(ctx).Error("failed to process routing request",
+			zap.Error(outcome.Err),
+		)
+		outcome.Values, err = w.errorValues(ctx, request, outcome.Err)
+		metrics.MessagesProcessed.WithLabelValues("error").Inc()
+	} else {
+		w.activity.recordDecision()
+		outcome.Values, err = w.decisionValues(ctx, request, outcome.Result)
+		if request.recordDecision {
+			outcome.StateWrites = decisionStateWrites(request, outcome.Result)
+		}
+		if err == nil {
+			outcome.ExperimentValues, err = w.experimentValues(request, outcome.Result)
+		}
+		metrics.MessagesProcessed.WithLabelValues("success").Inc()
+	}
+
+	return outcome, err
+}
+
+// decisionStateWrites records a decision in the execution state under
+// node_states[node_id].output
+func decisionStateWrites(request *WorkRequest, result *router.RoutingResult) []statestore.StateWrite {
+	return []statestore.StateWrite{{
+		Path: []string{"node_states", request.NodeID, "output"},
+		Value: map[string]interface{}{
+			"target_node": result.TargetNode,
+			"reasoning":   result.Reasoning,
+			"mode":        result.Mode,
+			"timestamp":   time.Now().UTC().Format(time.RFC3339Nano),
+		},
+	}}
+}
+
+// routeWithTimeout routes a request within REQUEST_TIMEOUT, if set
+func (w *Worker) routeWithTimeout(ctx context.Context, request *WorkRequest) (*router.RoutingResult, error) {
+	timeout := w.config.RequestTimeout
+	if timeout <= 0 {
+		return w.processRoutingRequest(ctx, request)
+	}
+
+	routeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := w.processRoutingRequest(routeCtx, request)
+	if err != nil && errors.Is(routeCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		metrics.RequestTimeouts.Inc()
+		return nil, fmt.Errorf("%w after %s: %w", ErrRequestTimeout, timeout, err)
+	}
+	return result, err
+}
@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/statestore"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// publish adds values to stream, retrying transient failures with backoff up
+// to MaxRetries times
+func (w *Worker) publish(stream string, values map[string]interface{}) error {
+	return w.withPublishRetries(stream, func() error {
+		return w.redisClient.XAdd(w.ctx, &redis.XAddArgs{
+			Stream: stream,
+			Values: values,
+		}).Err()
+	})
+}
+
+// withPublishRetries runs a publish to stream, retrying transient failures
+// with backoff up to MaxRetries times
+func (w *Worker) withPublishRetries(stream string, publish func() error) error {
+	bo := newBackoff(w.config.PublishBackoffMin, w.config.PublishBackoffMax)
+
+	var err error
+	for attempt := 0; attempt <= w.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := bo.Next()
+			metrics.PublishRetries.Inc()
+			w.logger.Warn("failed to publish to stream, retrying",
+				zap.String("stream", stream),
+				zap.Int("attempt", attempt),
+				zap.Duration("retry_in", delay),
+				zap.Error(err),
+			)
+			if !sleepContext(w.ctx, delay) {
+				return fmt.Errorf("publish to %s cancelled: %w", stream, err)
+			}
+		}
+
+		err = publish()
+		if err == nil {
+			return nil
+		}
+		if isFatalRedisError(err) {
+			break
+		}
+	}
+
+	return fmt.Errorf("failed to publish to %s: %w", stream, err)
+}
+
+// publishWithState writes the state updates of an outcome and publishes it to
+// stream. With an outbox both happen in one transaction. Otherwise the state
+// is written first: a crash before the publish leaves the request pending,
+// and its redelivery writes the state again and publishes.
+func (w *Worker) publishWithState(ctx context.Context, request *WorkRequest, stream string, outcome *Outcome, done func(error)) {
+	if w.outbox == nil {
+		if err := w.WriteState(ctx, request, outcome); err != nil {
+			done(err)
+			return
+		}
+		w.send(stream, outcome.Values, done)
+		return
+	}
+
+	err := w.withPublishRetries(stream, func() error {
+		return w.outbox.UpdateAndPublish(w.ctx, request.ExecutionID, outcome.StateWrites, stream, outcome.Values)
+	})
+	if err == nil {
+		metrics.StateWrites.WithLabelValues("outbox").Inc()
+	}
+	done(err)
+}
+
+// WriteState writes the state updates of an outcome into the execution state
+// without publishing it, for transports that publish outside Redis
+func (w *Worker) WriteState(ctx context.Context, request *WorkRequest, outcome *Outcome) error {
+	if len(outcome.StateWrites) == 0 {
+		return nil
+	}
+	if err := statestore.Update(ctx, w.stateStore, request.ExecutionID, outcome.StateWrites); err != nil {
+		return fmt.Errorf("failed to write decision into state: %w", err)
+	}
+	metrics.StateWrites.WithLabelValues("direct").Inc()
+	return nil
+}
+
+// deadLetter moves a message whose result could not be published, or that is
+// not a valid work request, to the dead letter stream.
+// It reports whether the message was stored and may be acked.
+func (w *Worker) deadLetter(ctx context.Context, stream string, message redis.XMessage, cause error) bool {
+	if w.config.DeadLetterStream == "" {
+		return false
+	}
+
+	values := map[string]interface{}{
+		"message_id": message.ID,
+		"stream":     stream,
+		"error":      cause.Error(),
+		"timestamp":  time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if data, ok := message.Values["data"]; ok {
+		values["data"] = data
+	}
+	var validationErr *ValidationError
+	if errors.As(cause, &validationErr) {
+		problems, _ := json.Marshal(validationErr.Problems)
+		values["validation_errors"] = string(problems)
+	}
+	tracing.Inject(ctx, values)
+
+	if err := w.publish(w.config.DeadLetterStream, values); err != nil {
+		w.log(ctx).Error("failed to dead-letter message",
+			zap.Error(err),
+		)
+		return false
+	}
+
+	metrics.MessagesDeadLettered.Inc()
+	w.log(ctx).Warn("message moved to dead letter stream",
+		zap.String("dead_letter_stream", w.config.DeadLetterStream),
+		zap.Error(cause),
+	)
+	return true
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Ready reports whether the worker can consume work: the processing loop is
+// running, every consumed stream has its consumer group, Redis is reachable
+// and shutdown has not begun
+func (w *Worker) Ready(ctx context.Context) error {
+	w.mu.RLock()
+	started, looping, stopping := w.started, w.looping, w.stopping
+	fatalErr, disconnectedAt, disconnectErr := w.fatalErr, w.disconnectedAt, w.disconnectErr
+	w.mu.RUnlock()
+
+	switch {
+	case stopping:
+		return fmt.Errorf("shutting down")
+	case fatalErr != nil:
+		return fmt.Errorf("fatal redis error: %w", fatalErr)
+	case !disconnectedAt.IsZero():
+		return fmt.Errorf("redis disconnected since %s: %w",
+			disconnectedAt.UTC().Format(time.RFC3339), disconnectErr)
+	case !started:
+		// Work arrives through another transport
+		return nil
+	case !looping:
+		return fmt.Errorf("processing loop is not running")
+	}
+
+	for _, stream := range w.shards.Streams() {
+		if err := w.checkConsumerGroup(ctx, stream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkConsumerGroup reports an error unless the worker's consumer group
+// exists on stream
+func (w *Worker) checkConsumerGroup(ctx context.Context, stream string) error {
+	groups, err := w.redisClient.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return fmt.Errorf("failed to inspect consumer groups of %s: %w", stream, err)
+	}
+	for _, group := range groups {
+		if group.Name == w.consumerGroup {
+			return nil
+		}
+	}
+	return fmt.Errorf("consumer group %s does not exist on %s", w.consumerGroup, stream)
+}
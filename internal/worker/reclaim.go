@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// reclaimPending periodically takes over messages left pending by crashed
+// consumers once they have been idle longer than the configured threshold
+func (w *Worker) reclaimPending() {
+	w.logger.Info("starting pending message reclaim loop",
+		zap.Duration("interval", w.config.ClaimInterval),
+		zap.Duration("min_idle", w.config.ClaimMinIdle),
+	)
+
+	ticker := time.NewTicker(w.config.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			w.logger.Info("pending message reclaim loop stopped")
+			return
+		case <-ticker.C:
+			w.reclaimOnce()
+		}
+	}
+}
+
+// reclaimOnce claims and processes idle messages on every consumed stream
+func (w *Worker) reclaimOnce() {
+	for _, stream := range w.shards.Streams() {
+		w.reclaimStream(stream)
+	}
+}
+
+// reclaimStream claims and processes all messages idle for longer than the
+// threshold, walking the pending entries list with the XAUTOCLAIM cursor
+func (w *Worker) reclaimStream(stream string) {
+	start := "0-0"
+	claimed := 0
+
+	for {
+		messages, next, err := w.redisClient.XAutoClaim(w.ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    w.consumerGroup,
+			Consumer: w.id,
+			MinIdle:  w.config.ClaimMinIdle,
+			Start:    start,
+			Count:    w.config.ClaimBatchSize,
+		}).Result()
+		if err != nil {
+			if w.ctx.Err() == nil {
+				w.logger.Warn("failed to claim pending messages", zap.Error(err))
+			}
+			return
+		}
+
+		for _, message := range messages {
+			w.logger.Info("claimed pending message",
+				zap.String("message_id", message.ID),
+			)
+			metrics.MessagesClaimed.Inc()
+			w.handleMessage(stream, message, nil)
+		}
+		claimed += len(messages)
+
+		// A zero cursor means the whole pending entries list was scanned
+		if next == "0-0" || next == "" || w.ctx.Err() != nil {
+			break
+		}
+		start = next
+	}
+
+	if claimed > 0 {
+		w.logger.Info("reclaimed pending messages",
+			zap.String("stream", stream),
+			zap.Int("count", claimed),
+		)
+	}
+}
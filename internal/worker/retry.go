@@ -0,0 +1,205 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/aescanero/dago-node-router/internal/statestore"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// retryableRedisErrors lists error prefixes Redis returns while it is
+// temporarily unable to serve a command
+var retryableRedisErrors = []string{
+	"LOADING",
+	"BUSY",
+	"TRYAGAIN",
+	"CLUSTERDOWN",
+	"MASTERDOWN",
+	"READONLY",
+}
+
+// isRetryable reports whether a routing failure may succeed when the request
+// is delivered again: the state was not written yet, the store or Redis had a
+// transient failure, the request or an LLM call timed out, the tenant was over
+// its quota, or an LLM call was rate limited or rejected
+func isRetryable(err error) bool {
+	if err == nil || isFatalRedisError(err) {
+		return false
+	}
+
+	switch {
+	case errors.Is(err, statestore.ErrNotFound),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, ErrRequestTimeout),
+		errors.Is(err, router.ErrTenantQuota),
+		errors.Is(err, io.EOF),
+		errors.Is(err, router.ErrRateLimited),
+		errors.Is(err, router.ErrCircuitOpen),
+		errors.Is(err, router.ErrLLMTimeout):
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		msg := redisErr.Error()
+		for _, prefix := range retryableRedisErrors {
+			if strings.HasPrefix(msg, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryQueue holds the messages left pending after a retryable failure,
+// by stream and message ID, with the time they fail
+type retryQueue struct {
+	mu     sync.Mutex
+	failed map[string]map[string]time.Time
+}
+
+// add schedules a message for redelivery
+func (q *retryQueue) add(stream, messageID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.failed == nil {
+		q.failed = make(map[string]map[string]time.Time)
+	}
+	if q.failed[stream] == nil {
+		q.failed[stream] = make(map[string]time.Time)
+	}
+	q.failed[stream][messageID] = time.Now()
+}
+
+// due removes and returns the messages that failed before cutoff, by stream
+func (q *retryQueue) due(cutoff time.Time) map[string][]string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	due := make(map[string][]string)
+	for stream, messages := range q.failed {
+		for id, failedAt := range messages {
+			if failedAt.Before(cutoff) {
+				due[stream] = append(due[stream], id)
+				delete(messages, id)
+			}
+		}
+		if len(messages) == 0 {
+			delete(q.failed, stream)
+		}
+	}
+	return due
+}
+
+// len returns the number of messages waiting for redelivery
+func (q *retryQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, messages := range q.failed {
+		n += len(messages)
+	}
+	return n
+}
+
+// scheduleRetry leaves a message that failed with a retryable error pending
+// for redelivery, reporting false once it has been delivered MaxRetries times.
+// Exhausted messages are copied to the dead letter stream and then settled
+// like any other failure.
+func (w *Worker) scheduleRetry(ctx context.Context, stream string, message redis.XMessage, cause error) bool {
+	deliveries := w.deliveryCount(ctx, stream, message.ID)
+	if deliveries >= int64(w.config.MaxRetries) {
+		w.log(ctx).Warn("retries exhausted for routing request",
+			zap.Int64("deliveries", deliveries),
+			zap.Error(cause),
+		)
+		w.deadLetter(ctx, stream, message, fmt.Errorf("giving up after %d deliveries: %w", deliveries, cause))
+		return false
+	}
+
+	w.retries.add(stream, message.ID)
+	metrics.MessagesRetried.Inc()
+	w.log(ctx).Warn("retryable routing failure, leaving message pending",
+		zap.Int64("deliveries", deliveries),
+		zap.Duration("retry_in", w.config.RetryDelay),
+		zap.Error(cause),
+	)
+	return true
+}
+
+// deliveryCount returns how often a pending message was delivered, from
+// XPENDING; a failed lookup counts as the first delivery
+func (w *Worker) deliveryCount(ctx context.Context, stream, messageID string) int64 {
+	pending, err := w.redisClient.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  w.consumerGroup,
+		Start:  messageID,
+		End:    messageID,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		if err != nil {
+			w.log(ctx).Warn("failed to read delivery count", zap.Error(err))
+		}
+		return 1
+	}
+	return pending[0].RetryCount
+}
+
+// retryFailed redelivers messages left pending after retryable failures once
+// they have waited RetryDelay
+func (w *Worker) retryFailed() {
+	ticker := newTicker(w.config.RetryDelay / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.retryDue()
+		}
+	}
+}
+
+// retryDue claims the due messages back to this consumer, which increments
+// their delivery count, and handles them again as one batch, partitioned by
+// execution like the batches of the read loop
+func (w *Worker) retryDue() {
+	var streams []redis.XStream
+	for stream, ids := range w.retries.due(time.Now().Add(-w.config.RetryDelay)) {
+		messages, err := w.redisClient.XClaim(w.ctx, &redis.XClaimArgs{
+			Stream:   stream,
+			Group:    w.consumerGroup,
+			Consumer: w.id,
+			Messages: ids,
+		}).Result()
+		if err != nil {
+			if w.ctx.Err() == nil {
+				w.logger.Warn("failed to claim messages for retry, leaving them to the reclaimer",
+					zap.String("stream", stream),
+					zap.Error(err),
+				)
+			}
+			continue
+		}
+
+		streams = append(streams, redis.XStream{Stream: stream, Messages: messages})
+	}
+	w.handleBatch(streams)
+}
@@ -0,0 +1,258 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Shard assignment strategies
+const (
+	ShardAssignmentStatic = "static"
+	ShardAssignmentRedis  = "redis"
+)
+
+// ShardFor returns the shard of an execution. Producers publish work for an
+// execution to ShardStream(base, ShardFor(id, shards)) so all of its requests
+// land on one stream and keep their order.
+func ShardFor(executionID string, shards int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(executionID))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// ShardStream returns the stream name of a shard
+func ShardStream(base string, shard int) string {
+	return fmt.Sprintf("%s.%d", base, shard)
+}
+
+// shardSet holds the work streams the worker currently consumes and, when
+// sharded, the shard numbers they belong to
+type shardSet struct {
+	streams []string
+	owned   []int
+	mu      sync.RWMutex
+}
+
+// Streams returns a copy of the consumed streams
+func (s *shardSet) Streams() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.streams...)
+}
+
+// Owned returns a copy of the consumed shard numbers
+func (s *shardSet) Owned() []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]int(nil), s.owned...)
+}
+
+// set replaces the consumed streams and shard numbers
+func (s *shardSet) set(streams []string, owned []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams = streams
+	s.owned = owned
+}
+
+// renewLease extends a shard lease only if this worker still owns it
+var renewLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLease deletes a shard lease only if this worker owns it
+var releaseLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// initShards sets up the consumed streams for the configured priority lanes
+// or sharding mode
+func (w *Worker) initShards() error {
+	if w.lanes != nil {
+		for _, stream := range w.lanes.Streams() {
+			if err := w.ensureConsumerGroup(stream); err != nil {
+				return err
+			}
+		}
+		w.shards.set(w.lanes.Streams(), nil)
+		return nil
+	}
+
+	if w.config.StreamShards == 0 {
+		w.shards.set([]string{w.streamKey}, nil)
+		return w.ensureConsumerGroup(w.streamKey)
+	}
+
+	if w.config.ShardAssignment == ShardAssignmentRedis {
+		w.balanceShards()
+		go w.maintainShards()
+		return nil
+	}
+
+	for _, shard := range w.config.WorkerShards {
+		if err := w.ensureConsumerGroup(ShardStream(w.streamKey, shard)); err != nil {
+			return err
+		}
+	}
+	w.setOwnedShards(w.config.WorkerShards)
+
+	w.logger.Info("consuming static shards",
+		zap.Ints("shards", w.config.WorkerShards),
+		zap.Int("total_shards", w.config.StreamShards),
+	)
+	return nil
+}
+
+// maintainShards renews shard leases and rebalances as workers join and leave
+func (w *Worker) maintainShards() {
+	ticker := newTicker(w.config.ShardLeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			w.releaseShards()
+			return
+		case <-ticker.C:
+			w.balanceShards()
+		}
+	}
+}
+
+// balanceShards heartbeats worker membership, renews owned shard leases and
+// acquires or releases shards so each live worker owns its fair share
+func (w *Worker) balanceShards() {
+	ctx := w.ctx
+	ttl := w.config.ShardLeaseTTL
+	membersKey := w.streamKey + ":workers"
+	now := time.Now()
+
+	// Heartbeat and drop workers that stopped heartbeating
+	pipe := w.redisClient.TxPipeline()
+	pipe.ZAdd(ctx, membersKey, redis.Z{Score: float64(now.UnixMilli()), Member: w.id})
+	pipe.ZRemRangeByScore(ctx, membersKey, "-inf", fmt.Sprint(now.Add(-ttl).UnixMilli()))
+	alive := pipe.ZCard(ctx, membersKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		// Without a heartbeat the fair share is unknown; keep only the shards
+		// whose leases are renewed so none is consumed after its lease expired
+		w.logger.Warn("failed to heartbeat shard membership", zap.Error(err))
+		w.setOwnedShards(w.renewLeases(ctx, ttl))
+		return
+	}
+
+	workers := int(alive.Val())
+	if workers < 1 {
+		workers = 1
+	}
+	shards := w.config.StreamShards
+	fairShare := (shards + workers - 1) / workers
+
+	owned := w.renewLeases(ctx, ttl)
+
+	// Release shards above the fair share so new workers can take them
+	for len(owned) > fairShare {
+		shard := owned[len(owned)-1]
+		owned = owned[:len(owned)-1]
+		if err := releaseLease.Run(ctx, w.redisClient, []string{w.leaseKey(shard)}, w.id).Err(); err != nil {
+			w.logger.Warn("failed to release shard lease", zap.Int("shard", shard), zap.Error(err))
+		}
+	}
+
+	// Acquire free shards, starting at a worker-specific offset to spread contention
+	start := ShardFor(w.id, shards)
+	for i := 0; i < shards && len(owned) < fairShare; i++ {
+		shard := (start + i) % shards
+		if containsShard(owned, shard) {
+			continue
+		}
+		acquired, err := w.redisClient.SetNX(ctx, w.leaseKey(shard), w.id, ttl).Result()
+		if err != nil {
+			w.logger.Warn("failed to acquire shard lease", zap.Int("shard", shard), zap.Error(err))
+			break
+		}
+		if !acquired {
+			continue
+		}
+		if err := w.ensureConsumerGroup(ShardStream(w.streamKey, shard)); err != nil {
+			w.logger.Warn("failed to prepare shard stream", zap.Int("shard", shard), zap.Error(err))
+			_ = releaseLease.Run(ctx, w.redisClient, []string{w.leaseKey(shard)}, w.id).Err()
+			continue
+		}
+		owned = append(owned, shard)
+	}
+
+	previous := len(w.shards.Owned())
+	w.setOwnedShards(owned)
+	if len(owned) != previous {
+		w.logger.Info("shard assignment changed",
+			zap.Ints("shards", owned),
+			zap.Int("live_workers", workers),
+			zap.Int("fair_share", fairShare),
+		)
+	}
+}
+
+// renewLeases renews the leases of the owned shards and returns the shards
+// still owned. Shards lost to expiry or whose renewal failed are dropped, as
+// another worker may take them once the lease expires.
+func (w *Worker) renewLeases(ctx context.Context, ttl time.Duration) []int {
+	var owned []int
+	for _, shard := range w.shards.Owned() {
+		renewed, err := renewLease.Run(ctx, w.redisClient, []string{w.leaseKey(shard)}, w.id, ttl.Milliseconds()).Int()
+		if err == nil && renewed == 1 {
+			owned = append(owned, shard)
+		} else {
+			w.logger.Warn("lost shard lease", zap.Int("shard", shard), zap.Error(err))
+		}
+	}
+	return owned
+}
+
+// releaseShards gives up all shard leases and leaves the membership set on shutdown
+func (w *Worker) releaseShards() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	for _, shard := range w.shards.Owned() {
+		_ = releaseLease.Run(ctx, w.redisClient, []string{w.leaseKey(shard)}, w.id).Err()
+	}
+	_ = w.redisClient.ZRem(ctx, w.streamKey+":workers", w.id).Err()
+	w.setOwnedShards(nil)
+	w.logger.Info("released shard leases")
+}
+
+// leaseKey returns the Redis key holding the owner of a shard
+func (w *Worker) leaseKey(shard int) string {
+	return fmt.Sprintf("%s:shard:%d:owner", w.streamKey, shard)
+}
+
+// setOwnedShards records the consumed shard numbers and their streams
+func (w *Worker) setOwnedShards(owned []int) {
+	streams := make([]string, 0, len(owned))
+	for _, shard := range owned {
+		streams = append(streams, ShardStream(w.streamKey, shard))
+	}
+	w.shards.set(streams, owned)
+}
+
+// containsShard reports whether shard is in shards
+func containsShard(shards []int, shard int) bool {
+	for _, s := range shards {
+		if s == shard {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-node-router/internal/audit"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/aescanero/dago-node-router/internal/statestore"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// stickyPrefix namespaces the sticky routing entries in Redis
+const stickyPrefix = "router:sticky:"
+
+// pathSticky is the path_taken of decisions repeated by sticky routing
+const pathSticky = "sticky"
+
+// Sticky routing lookups, as reported by the sticky_lookups_total metric
+const (
+	stickyHit          = "hit"
+	stickyMiss         = "miss"
+	stickyStateChanged = "state_changed"
+)
+
+// stickyEntry is the decision remembered for an execution and node
+type stickyEntry struct {
+	Target    string    `json:"target"`
+	Mode      string    `json:"mode"`
+	StateHash string    `json:"state_hash"`
+	RoutedAt  time.Time `json:"routed_at"`
+}
+
+// stickyKey identifies the remembered decision of a node for an execution
+func stickyKey(request *WorkRequest) string {
+	return stickyPrefix + request.ExecutionID + ":" + request.NodeID
+}
+
+// stickyStateHash hashes the state watched by a sticky config
+func stickyStateHash(sticky *router.StickyConfig, stateData state.State) string {
+	if len(sticky.Watch) == 0 {
+		return audit.Hash(stateData)
+	}
+	return audit.Hash(statestore.Project(stateData, sticky.Watch))
+}
+
+// stickyResult returns the decision remembered for the request when its
+// watched state is unchanged, or nil. Lookup failures are logged and the
+// request is routed.
+func (w *Worker) stickyResult(ctx context.Context, request *WorkRequest, sticky *router.StickyConfig, stateHash string) *router.RoutingResult {
+	raw, err := w.redisClient.Get(ctx, stickyKey(request)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		metrics.StickyLookups.WithLabelValues(stickyMiss).Inc()
+		return nil
+	}
+	var entry stickyEntry
+	if err == nil {
+		err = json.Unmarshal(raw, &entry)
+	}
+	if err != nil {
+		w.log(ctx).Warn("failed to read sticky route, routing request",
+			zap.Error(err),
+		)
+		return nil
+	}
+	if entry.StateHash != stateHash {
+		metrics.StickyLookups.WithLabelValues(stickyStateChanged).Inc()
+		return nil
+	}
+
+	metrics.StickyLookups.WithLabelValues(stickyHit).Inc()
+	return &router.RoutingResult{
+		TargetNode: entry.Target,
+		Reasoning: fmt.Sprintf("sticky: routed to %s %s ago with unchanged state",
+			entry.Target, time.Since(entry.RoutedAt).Round(time.Second)),
+		Mode:      entry.Mode,
+		PathTaken: pathSticky,
+		Tenant:    request.tenant(),
+	}
+}
+
+// rememberResult stores a decision for sticky routing, for the sticky TTL.
+// Fallback decisions are not remembered, so a transient failure does not
+// pin the execution to the fallback route.
+func (w *Worker) rememberResult(ctx context.Context, request *WorkRequest, sticky *router.StickyConfig, stateHash string, result *router.RoutingResult) {
+	if result.PathTaken == "fallback" || result.PathTaken == pathSticky {
+		return
+	}
+
+	data, err := json.Marshal(stickyEntry{
+		Target:    result.TargetNode,
+		Mode:      result.Mode,
+		StateHash: stateHash,
+		RoutedAt:  time.Now().UTC(),
+	})
+	if err == nil {
+		err = w.redisClient.Set(ctx, stickyKey(request), data, time.Duration(sticky.TTL)).Err()
+	}
+	if err != nil {
+		w.log(ctx).Warn("failed to store sticky route",
+			zap.Error(err),
+		)
+	}
+}
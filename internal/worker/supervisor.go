@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// pingTimeout bounds the ping used to tell a lost connection from a failing
+// command
+const pingTimeout = 2 * time.Second
+
+// superviseReadError handles a failed XREADGROUP: a deleted stream or
+// consumer group is re-created right away, and other errors back off
+// exponentially, then re-ping Redis so the worker reports not ready while
+// the connection is down
+func (w *Worker) superviseReadError(err error, bo *backoff) {
+	if isNoGroupError(err) && w.recreateConsumerGroups(err) {
+		return
+	}
+
+	delay := bo.Next()
+	if isFatalRedisError(err) {
+		w.setFatal(err)
+		w.logger.Error("fatal redis error, worker unhealthy",
+			zap.Int("attempt", bo.Attempts()),
+			zap.Duration("retry_in", delay),
+			zap.Error(err),
+		)
+	} else {
+		w.logger.Warn("failed to read from stream, backing off",
+			zap.Int("attempt", bo.Attempts()),
+			zap.Duration("retry_in", delay),
+			zap.Error(err),
+		)
+	}
+	if !sleepContext(w.ctx, delay) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(w.ctx, pingTimeout)
+	defer cancel()
+	if pingErr := w.redisClient.Ping(ctx).Err(); pingErr != nil && w.ctx.Err() == nil {
+		w.setDisconnected(pingErr)
+	}
+}
+
+// readRecovered records a successful XREADGROUP after failures
+func (w *Worker) readRecovered(bo *backoff) {
+	if bo.Attempts() == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	disconnectedAt := w.disconnectedAt
+	w.disconnectedAt = time.Time{}
+	w.disconnectErr = nil
+	w.fatalErr = nil
+	w.mu.Unlock()
+
+	fields := []zap.Field{zap.Int("failed_attempts", bo.Attempts())}
+	if !disconnectedAt.IsZero() {
+		metrics.RedisConnected.Set(1)
+		metrics.RedisReconnects.Inc()
+		fields = append(fields, zap.Duration("downtime", time.Since(disconnectedAt)))
+	}
+	w.logger.Info("redis connection recovered", fields...)
+	bo.Reset()
+}
+
+// setDisconnected marks Redis unreachable, logging only the transition
+func (w *Worker) setDisconnected(err error) {
+	w.mu.Lock()
+	first := w.disconnectedAt.IsZero()
+	if first {
+		w.disconnectedAt = time.Now()
+	}
+	w.disconnectErr = err
+	w.mu.Unlock()
+
+	if first {
+		metrics.RedisConnected.Set(0)
+		w.logger.Error("redis unreachable, worker not ready", zap.Error(err))
+	}
+}
+
+// recreateConsumerGroups re-creates the consumer group of every consumed
+// stream after a NOGROUP error, reporting whether all succeeded
+func (w *Worker) recreateConsumerGroups(cause error) bool {
+	w.logger.Warn("work stream or consumer group deleted, re-creating consumer groups",
+		zap.String("group", w.consumerGroup),
+		zap.Error(cause),
+	)
+	for _, stream := range w.shards.Streams() {
+		if err := w.ensureConsumerGroup(stream); err != nil {
+			w.logger.Error("failed to re-create consumer group",
+				zap.String("stream", stream),
+				zap.Error(err),
+			)
+			return false
+		}
+	}
+	metrics.ConsumerGroupsRecreated.Inc()
+	return true
+}
+
+// isNoGroupError reports whether a stream command failed because the
+// stream or its consumer group no longer exists
+func isNoGroupError(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "NOGROUP")
+}
@@ -0,0 +1,151 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// SchemaVersion is the newest work request schema this worker understands.
+// Requests without a schema_version are read as version 1.
+const SchemaVersion = 1
+
+// maxIDLength bounds the execution, node and tenant IDs of a work request
+const maxIDLength = 256
+
+// workRequestFields are the top-level fields of the JSON work request schema
+var workRequestFields = map[string]bool{
+	"schema_version": true,
+	"execution_id":   true,
+	"node_id":        true,
+	"tenant_id":      true,
+	"config":         true,
+	"headers":        true,
+}
+
+// ValidationError lists every problem found in a malformed work request
+type ValidationError struct {
+	Problems []string
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	return "invalid work request: " + strings.Join(e.Problems, "; ")
+}
+
+// ParseWorkRequest decodes and validates a work request from the "data" field
+// of message values, logging a warning for fields outside the schema.
+// enqueuedAt (zero when unknown) and receivedAt feed the latency fields of the
+// published decision. Malformed requests return a *ValidationError.
+func (w *Worker) ParseWorkRequest(values map[string]interface{}, enqueuedAt, receivedAt time.Time) (*WorkRequest, error) {
+	request, unknown, err := parseWorkRequest(values, w.config.MaxRequestBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		metrics.RequestUnknownFields.Add(float64(len(unknown)))
+		w.logger.Warn("work request has unknown fields",
+			zap.String("execution_id", request.ExecutionID),
+			zap.Strings("fields", unknown),
+		)
+	}
+	request.enqueuedAt = enqueuedAt
+	request.receivedAt = receivedAt
+
+	return request, nil
+}
+
+// parseWorkRequest decodes the "data" field of message values, in the format
+// named by their "format" field, and checks it against the work request
+// schema. It returns the request and its unknown top-level fields. maxBytes
+// bounds the encoded request (0 disables).
+func parseWorkRequest(values map[string]interface{}, maxBytes int) (*WorkRequest, []string, error) {
+	dataStr, ok := values["data"].(string)
+	if !ok {
+		return nil, nil, &ValidationError{Problems: []string{"missing or invalid 'data' field"}}
+	}
+	if maxBytes > 0 && len(dataStr) > maxBytes {
+		return nil, nil, &ValidationError{Problems: []string{
+			fmt.Sprintf("request is %d bytes, over the %d byte limit", len(dataStr), maxBytes),
+		}}
+	}
+
+	format, err := payloadFormat(values)
+	if err != nil {
+		return nil, nil, &ValidationError{Problems: []string{err.Error()}}
+	}
+	decode := decodeJSONWorkRequest
+	if format == FormatProtobuf {
+		decode = decodeProtoWorkRequest
+	}
+	request, unknown, err := decode([]byte(dataStr))
+	if err != nil {
+		return nil, nil, &ValidationError{Problems: []string{err.Error()}}
+	}
+	if problems := request.validate(); len(problems) > 0 {
+		return nil, nil, &ValidationError{Problems: problems}
+	}
+	if request.SchemaVersion == 0 {
+		request.SchemaVersion = 1
+	}
+
+	return request, unknown, nil
+}
+
+// decodeJSONWorkRequest decodes a JSON work request. It returns the request
+// and its top-level fields outside the schema, sorted.
+func decodeJSONWorkRequest(data []byte) (*WorkRequest, []string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, fmt.Errorf("malformed JSON: %w", err)
+	}
+	var unknown []string
+	for field := range fields {
+		if !workRequestFields[field] {
+			unknown = append(unknown, field)
+		}
+	}
+	sort.Strings(unknown)
+
+	var request WorkRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal work request: %w", err)
+	}
+	request.format = FormatJSON
+
+	return &request, unknown, nil
+}
+
+// validate checks the required fields, ID lengths and schema version of a
+// decoded request
+func (r *WorkRequest) validate() []string {
+	var problems []string
+
+	if r.SchemaVersion < 0 || r.SchemaVersion > SchemaVersion {
+		problems = append(problems, fmt.Sprintf("unsupported schema_version %d (supported: 1 to %d)", r.SchemaVersion, SchemaVersion))
+	}
+	for _, id := range []struct{ name, value string }{
+		{"execution_id", r.ExecutionID},
+		{"node_id", r.NodeID},
+		{"tenant_id", r.TenantID},
+	} {
+		if id.value == "" && id.name != "tenant_id" {
+			problems = append(problems, id.name+" is required")
+		}
+		if len(id.value) > maxIDLength {
+			problems = append(problems, fmt.Sprintf("%s is longer than %d bytes", id.name, maxIDLength))
+		}
+	}
+	if r.Config == nil {
+		problems = append(problems, "config is required")
+	} else if len(r.Config) == 0 {
+		problems = append(problems, "config must be a non-empty object")
+	}
+
+	return problems
+}
@@ -4,21 +4,36 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aescanero/dago-libs/pkg/domain"
 	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/audit"
 	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/aescanero/dago-node-router/internal/logging"
+	"github.com/aescanero/dago-node-router/internal/metrics"
+	"github.com/aescanero/dago-node-router/internal/redact"
 	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/aescanero/dago-node-router/internal/statestore"
+	"github.com/aescanero/dago-node-router/internal/tracing"
+	routerv1 "github.com/aescanero/dago-node-router/proto/router/v1"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // Worker represents the router worker
 type Worker struct {
 	id            string
 	config        *config.Config
-	redisClient   *redis.Client
+	redisClient   redis.UniversalClient
 	router        *router.Router
 	eventBus      ports.EventBus
 	stateStore    ports.StateStorage
@@ -28,33 +43,111 @@ type Worker struct {
 	streamKey     string
 	consumerGroup string
 	resultStream  string
+
+	// shards holds the work streams consumed by this worker
+	shards shardSet
+	// lanes orders reads across priority lanes; nil when lanes are disabled
+	lanes *laneScheduler
+
+	// decisionFields is the set of fields included in published decisions
+	decisionFields map[string]bool
+	// enrichers add computed fields to decisions before publishing
+	enrichers []Enricher
+	// version is published as the worker_version of decisions
+	version string
+	// batcher pipelines outcome publishes; nil when batching is disabled
+	batcher *publishBatcher
+	// outbox writes decision state updates and publishes the decision in one
+	// transaction; nil when the state store cannot
+	outbox statestore.Outbox
+	// transports lists transports served alongside the work transport
+	transports []string
+	// activity records recent decisions and errors for /diagnostics
+	activity activity
+	// auditLog records every decision; nil when auditing is disabled
+	auditLog audit.Log
+	// redactor masks customer data in audit records
+	redactor *redact.Redactor
+	// retries holds messages left pending after retryable failures
+	retries retryQueue
+	// batchMu serializes the batches of the read loop and of retries, so a
+	// retry never runs beside newer requests of its execution
+	batchMu sync.Mutex
+	// lag holds the last consumer group lag measurement
+	lag atomic.Pointer[LagReport]
+
+	// fatalErr holds the last fatal Redis error; the worker reports unhealthy while set
+	fatalErr error
+	// disconnectedAt is when Redis stopped answering pings; the worker
+	// reports not ready until a read succeeds again
+	disconnectedAt time.Time
+	disconnectErr  error
+	// started, looping and stopping track the work loop for readiness
+	started  bool
+	looping  bool
+	stopping bool
+	mu       sync.RWMutex
 }
 
 // NewWorker creates a new worker
 func NewWorker(
 	cfg *config.Config,
-	redisClient *redis.Client,
+	redisClient redis.UniversalClient,
 	routerInstance *router.Router,
 	eventBus ports.EventBus,
 	stateStore ports.StateStorage,
 	logger *zap.Logger,
+	opts ...Option,
 ) *Worker {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Worker{
-		id:            cfg.WorkerID,
-		config:        cfg,
-		redisClient:   redisClient,
-		router:        routerInstance,
-		eventBus:      eventBus,
-		stateStore:    stateStore,
-		logger:        logger,
-		ctx:           ctx,
-		cancel:        cancel,
-		streamKey:     cfg.StreamKey,
-		consumerGroup: cfg.ConsumerGroup,
-		resultStream:  cfg.ResultStream,
+	// Validated by config.Validate; an invalid mask falls back to the defaults
+	decisionFields, err := cfg.DecisionFieldSet()
+	if err != nil {
+		logger.Warn("invalid decision fields, using defaults", zap.Error(err))
+		decisionFields = make(map[string]bool)
+		for _, field := range config.DefaultDecisionFields {
+			decisionFields[field] = true
+		}
+	}
+
+	w := &Worker{
+		id:             cfg.WorkerID,
+		config:         cfg,
+		redisClient:    redisClient,
+		router:         routerInstance,
+		eventBus:       eventBus,
+		stateStore:     stateStore,
+		logger:         logger,
+		ctx:            ctx,
+		cancel:         cancel,
+		streamKey:      cfg.StreamKey,
+		consumerGroup:  cfg.ConsumerGroup,
+		resultStream:   cfg.ResultStream,
+		decisionFields: decisionFields,
+	}
+
+	// Validated by config.Validate; invalid lanes fall back to STREAM_KEY
+	if lanes, err := cfg.Lanes(); err != nil {
+		logger.Warn("invalid priority lanes, consuming the work stream", zap.Error(err))
+	} else if lanes != nil {
+		w.lanes = newLaneScheduler(lanes)
+	}
+
+	if cfg.PublishBatchSize > 1 {
+		w.batcher = newPublishBatcher(w, cfg.PublishBatchSize, cfg.PublishBatchInterval)
 	}
+
+	// State keys and result streams may live on different cluster nodes
+	if outbox, ok := statestore.AsOutbox(stateStore); ok && cfg.RedisMode != config.RedisModeCluster {
+		w.outbox = outbox
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
 }
 
 // Start starts the worker
@@ -65,14 +158,39 @@ func (w *Worker) Start() error {
 		zap.String("consumer_group", w.consumerGroup),
 	)
 
-	// Create consumer group if it doesn't exist
-	if err := w.ensureConsumerGroup(); err != nil {
+	// Create consumer groups for the consumed streams (or shards)
+	if err := w.initShards(); err != nil {
 		return fmt.Errorf("failed to ensure consumer group: %w", err)
 	}
+	metrics.RedisConnected.Set(1)
+
+	// Start flushing batched outcomes before any work is processed
+	if w.batcher != nil {
+		go w.batcher.run()
+	}
 
 	// Start processing work
+	w.mu.Lock()
+	w.started = true
+	w.looping = true
+	w.mu.Unlock()
 	go w.processWork()
 
+	// Start reclaiming messages left pending by crashed consumers
+	if w.config.ClaimEnabled {
+		go w.reclaimPending()
+	}
+
+	// Start redelivering requests that failed with retryable errors
+	if w.config.MaxRetries > 0 {
+		go w.retryFailed()
+	}
+
+	// Start measuring consumer group lag for autoscaling
+	if w.config.LagReportInterval > 0 {
+		go w.reportLag()
+	}
+
 	w.logger.Info("router worker started", zap.String("worker_id", w.id))
 	return nil
 }
@@ -81,6 +199,10 @@ func (w *Worker) Start() error {
 func (w *Worker) Stop() error {
 	w.logger.Info("stopping router worker", zap.String("worker_id", w.id))
 
+	w.mu.Lock()
+	w.stopping = true
+	w.mu.Unlock()
+
 	// Cancel context to stop work processing
 	w.cancel()
 
@@ -91,10 +213,10 @@ func (w *Worker) Stop() error {
 	return nil
 }
 
-// ensureConsumerGroup creates the consumer group if it doesn't exist
-func (w *Worker) ensureConsumerGroup() error {
+// ensureConsumerGroup creates the consumer group on a stream if it doesn't exist
+func (w *Worker) ensureConsumerGroup(stream string) error {
 	// Try to create the group
-	err := w.redisClient.XGroupCreateMkStream(w.ctx, w.streamKey, w.consumerGroup, "0").Err()
+	err := w.redisClient.XGroupCreateMkStream(w.ctx, stream, w.consumerGroup, "0").Err()
 	if err != nil {
 		// BUSYGROUP error means the group already exists, which is fine
 		if err.Error() == "BUSYGROUP Consumer Group name already exists" {
@@ -108,14 +230,39 @@ func (w *Worker) ensureConsumerGroup() error {
 
 	w.logger.Info("created consumer group",
 		zap.String("group", w.consumerGroup),
-		zap.String("stream", w.streamKey),
+		zap.String("stream", stream),
 	)
 	return nil
 }
 
+// Health reports whether the worker is healthy
+func (w *Worker) Health(ctx context.Context) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.fatalErr != nil {
+		return fmt.Errorf("fatal redis error: %w", w.fatalErr)
+	}
+	return nil
+}
+
+// setFatal records (or clears, when err is nil) a fatal Redis error
+func (w *Worker) setFatal(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.fatalErr = err
+}
+
 // processWork processes work from the Redis stream
 func (w *Worker) processWork() {
 	w.logger.Info("starting work processing loop")
+	defer func() {
+		w.mu.Lock()
+		w.looping = false
+		w.mu.Unlock()
+	}()
+
+	bo := newBackoff(w.config.RedisBackoffMin, w.config.RedisBackoffMax)
 
 	for {
 		select {
@@ -123,126 +270,284 @@ func (w *Worker) processWork() {
 			w.logger.Info("work processing loop stopped")
 			return
 		default:
-			// Read from stream
-			streams, err := w.redisClient.XReadGroup(w.ctx, &redis.XReadGroupArgs{
-				Group:    w.consumerGroup,
-				Consumer: w.id,
-				Streams:  []string{w.streamKey, ">"},
-				Count:    1,
-				Block:    w.config.BlockTime,
-			}).Result()
-
-			if err != nil {
-				if err == redis.Nil {
-					// No messages available, continue
+			// Sharded workers may own no shards until others release some
+			consumed := w.shards.Streams()
+			if len(consumed) == 0 {
+				sleepContext(w.ctx, w.config.BlockTime)
+				continue
+			}
+
+			// Read from the consumed streams, or the priority lanes in
+			// weighted order
+			var streams []redis.XStream
+			var err error
+			if w.lanes != nil {
+				streams, err = w.readLanes()
+			} else {
+				keys := append([]string(nil), consumed...)
+				for range consumed {
+					keys = append(keys, ">")
+				}
+				streams, err = w.redisClient.XReadGroup(w.ctx, &redis.XReadGroupArgs{
+					Group:    w.consumerGroup,
+					Consumer: w.id,
+					Streams:  keys,
+					Count:    int64(w.config.BatchSize),
+					Block:    w.config.BlockTime,
+				}).Result()
+			}
+
+			if err != nil && err != redis.Nil {
+				if w.ctx.Err() != nil {
+					// Shutting down
 					continue
 				}
-				w.logger.Error("failed to read from stream",
-					zap.Error(err),
-				)
-				time.Sleep(time.Second)
+				w.superviseReadError(err, bo)
 				continue
 			}
+			w.readRecovered(bo)
 
-			// Process each message
-			for _, stream := range streams {
-				for _, message := range stream.Messages {
-					w.handleMessage(message)
-				}
+			if err == redis.Nil {
+				// No messages available, continue
+				continue
 			}
+
+			// Process the batch and ack it in one pipeline
+			w.processBatch(streams)
 		}
 	}
 }
 
-// handleMessage handles a single routing request message
-func (w *Worker) handleMessage(message redis.XMessage) {
+// handleMessage handles a single routing request message. The message is
+// settled through acks, or acked directly when acks is nil.
+func (w *Worker) handleMessage(stream string, message redis.XMessage, acks *ackBatch) {
+	receivedAt := time.Now()
 	messageID := message.ID
-	w.logger.Info("processing routing request",
-		zap.String("message_id", messageID),
+	enqueuedAt, hasEnqueuedAt := messageTimestamp(messageID)
+	lane := w.lanes.laneOf(stream)
+	if lane != "" {
+		metrics.LaneMessages.WithLabelValues(lane).Inc()
+	}
+	if hasEnqueuedAt {
+		metrics.StreamLag.Set(receivedAt.Sub(enqueuedAt).Seconds())
+		if lane != "" {
+			metrics.LaneLag.WithLabelValues(lane).Set(receivedAt.Sub(enqueuedAt).Seconds())
+		}
+	}
+
+	// Continue the trace started by the orchestrator, if any. Routing stops
+	// when the worker shuts down.
+	ctx := tracing.Extract(w.ctx, message.Values)
+	ctx, span := tracing.Tracer().Start(ctx, "worker.handleMessage",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.message.id", messageID),
+			attribute.String("messaging.destination.name", stream),
+		),
 	)
+	defer span.End()
+	ctx = w.WithMessageLogger(ctx, messageID)
+	w.log(ctx).Info("processing routing request")
 
 	// Parse the work request
-	workRequest, err := w.parseWorkRequest(message.Values)
+	workRequest, err := w.ParseWorkRequest(message.Values, enqueuedAt, receivedAt)
 	if err != nil {
-		w.logger.Error("failed to parse work request",
-			zap.String("message_id", messageID),
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid work request")
+		w.log(ctx).Error("failed to parse work request",
 			zap.Error(err),
 		)
-		w.acknowledgeMessage(messageID)
+		metrics.MessagesProcessed.WithLabelValues("invalid").Inc()
+		w.RecordError("", "parse", err)
+		// Malformed requests fail the same way on every delivery, so they are
+		// kept in the dead letter stream (when enabled) and acked
+		if w.config.DeadLetterStream != "" && !w.deadLetter(ctx, stream, message, err) {
+			w.settle(acks, stream, messageID, false)
+			return
+		}
+		w.settle(acks, stream, messageID, true)
 		return
 	}
 
-	// Process the routing request
-	if err := w.processRoutingRequest(workRequest); err != nil {
-		w.logger.Error("failed to process routing request",
-			zap.String("message_id", messageID),
-			zap.String("execution_id", workRequest.ExecutionID),
-			zap.Error(err),
-		)
-		// Publish error event
-		w.publishError(workRequest, err)
+	span.SetAttributes(
+		attribute.String("execution_id", workRequest.ExecutionID),
+		attribute.String("node_id", workRequest.NodeID),
+	)
+	ctx = w.WithRequestLogger(ctx, workRequest)
+
+	// A redelivered message whose outcome was already published is only acked
+	if w.IsDuplicate(ctx, workRequest, messageID) {
+		w.settle(acks, stream, messageID, true)
+		return
+	}
+
+	// Process the routing request and encode its outcome
+	outcome, encodeErr := w.Process(ctx, workRequest)
+	if outcome.Interrupted {
+		w.settle(acks, stream, messageID, false)
+		return
+	}
+	// A produced outcome is published even if the worker is shutting down
+	ctx = context.WithoutCancel(ctx)
+	if result := outcome.Result; result != nil {
+		ctx = logging.With(ctx, w.logger, zap.String("tenant", result.Tenant))
+	}
+	if outcome.Failed() && isRetryable(outcome.Err) && w.config.MaxRetries > 0 &&
+		w.scheduleRetry(ctx, stream, message, outcome.Err) {
+		w.settle(acks, stream, messageID, false)
+		return
+	}
+	result := outcome.Result
+	outStream := w.resultStream
+	if outcome.Failed() {
+		// Error events go to a separate stream
+		outStream = w.resultStream + ".errors"
 	}
 
-	// Acknowledge the message
-	w.acknowledgeMessage(messageID)
+	// Only ack once the outcome is published or dead-lettered; otherwise the
+	// message stays pending and is reclaimed later
+	finish := func(publishErr error) {
+		if publishErr != nil {
+			span.RecordError(publishErr)
+			span.SetStatus(codes.Error, "publish failed")
+			w.RecordError(workRequest.ExecutionID, "publish", publishErr)
+			if !w.deadLetter(ctx, stream, message, publishErr) {
+				w.log(ctx).Error("outcome not published, leaving message pending",
+					zap.Error(publishErr),
+				)
+				w.settle(acks, stream, messageID, false)
+				return
+			}
+		} else {
+			w.MarkPublished(ctx, workRequest, messageID)
+			if result != nil {
+				w.RecordHop(ctx, workRequest)
+				w.log(ctx).Info("published routing decision",
+					zap.String("target_node", result.TargetNode),
+				)
+			}
+		}
+
+		// Acknowledge the message
+		w.settle(acks, stream, messageID, true)
+	}
+
+	if encodeErr != nil {
+		finish(encodeErr)
+		return
+	}
+	if len(outcome.StateWrites) > 0 {
+		w.publishWithState(ctx, workRequest, outStream, outcome, finish)
+	} else {
+		w.send(outStream, outcome.Values, finish)
+	}
+	w.sendExperiment(workRequest, outcome)
 }
 
 // WorkRequest represents a routing work request
 type WorkRequest struct {
-	ExecutionID string                 `json:"execution_id"`
-	NodeID      string                 `json:"node_id"`
-	Config      map[string]interface{} `json:"config"`
+	// SchemaVersion is the version of the request and node config schema,
+	// 1 when unset
+	SchemaVersion int                    `json:"schema_version,omitempty"`
+	ExecutionID   string                 `json:"execution_id"`
+	NodeID        string                 `json:"node_id"`
+	Config        map[string]interface{} `json:"config"`
+	// Headers is optional orchestrator context exposed to rules and prompts as `ctx`
+	Headers *router.ExecutionContext `json:"headers,omitempty"`
+	// TenantID isolates the request under its tenant's quotas and labels.
+	// It takes precedence over headers.tenant.
+	TenantID string `json:"tenant_id,omitempty"`
+
+	// enqueuedAt is when the message was added to the stream (from its ID)
+	enqueuedAt time.Time
+	// receivedAt is when the worker started handling the message
+	receivedAt time.Time
+	// stateHash is the hash of the loaded state, set when auditing
+	stateHash string
+	// recordDecision is set when the node config records decisions in the
+	// execution state
+	recordDecision bool
+	// format is the payload format the request was read in, and its outcome
+	// is published in
+	format string
 }
 
-// parseWorkRequest parses a work request from Redis message
-func (w *Worker) parseWorkRequest(values map[string]interface{}) (*WorkRequest, error) {
-	dataStr, ok := values["data"].(string)
-	if !ok {
-		return nil, fmt.Errorf("missing or invalid 'data' field")
+// executionContext returns the headers of the request with TenantID applied
+func (r *WorkRequest) executionContext() *router.ExecutionContext {
+	if r.TenantID == "" {
+		return r.Headers
 	}
-
-	var request WorkRequest
-	if err := json.Unmarshal([]byte(dataStr), &request); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal work request: %w", err)
+	ec := router.ExecutionContext{}
+	if r.Headers != nil {
+		ec = *r.Headers
 	}
+	ec.Tenant = r.TenantID
+	return &ec
+}
 
-	return &request, nil
+// tenant returns the tenant named by the request itself, or ""
+func (r *WorkRequest) tenant() string {
+	if ec := r.executionContext(); ec != nil {
+		return ec.Tenant
+	}
+	return ""
 }
 
 // processRoutingRequest processes a routing request
-func (w *Worker) processRoutingRequest(request *WorkRequest) error {
-	ctx := context.Background()
+func (w *Worker) processRoutingRequest(ctx context.Context, request *WorkRequest) (*router.RoutingResult, error) {
+	// Parse routing configuration first, it selects the state paths to load
+	nodeConfig, err := w.parseNodeConfig(request.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse node config: %w", err)
+	}
+	request.recordDecision = nodeConfig.RecordDecisionInState
 
 	// Load graph state from store
-	stateData, err := w.stateStore.Load(ctx, request.ExecutionID)
+	loadStart := time.Now()
+	stateData, err := statestore.LoadPaths(ctx, w.stateStore, request.ExecutionID, nodeConfig.RequiredStatePaths())
 	if err != nil {
-		return fmt.Errorf("failed to load state: %w", err)
+		return nil, fmt.Errorf("failed to load state: %w", err)
 	}
+	stateLoad := time.Since(loadStart)
 
-	// Convert state.State (map) to domain.GraphState
-	graphState, err := w.convertToGraphState(request.ExecutionID, stateData)
-	if err != nil {
-		return fmt.Errorf("failed to convert state: %w", err)
+	if w.auditLog != nil {
+		request.stateHash = audit.Hash(stateData)
 	}
 
-	// Parse routing configuration
-	nodeConfig, err := w.parseNodeConfig(request.Config)
+	// Repeat the decision remembered for the execution while its watched
+	// state is unchanged
+	var stickyHash string
+	if nodeConfig.Sticky != nil {
+		stickyHash = stickyStateHash(nodeConfig.Sticky, stateData)
+		if result := w.stickyResult(ctx, request, nodeConfig.Sticky, stickyHash); result != nil {
+			return result, nil
+		}
+	}
+
+	// Convert state.State (map) to domain.GraphState
+	graphState, err := w.convertToGraphState(request.ExecutionID, stateData)
 	if err != nil {
-		return fmt.Errorf("failed to parse node config: %w", err)
+		return nil, fmt.Errorf("failed to convert state: %w", err)
 	}
 
 	// Perform routing
+	ctx = router.WithExecutionContext(ctx, request.executionContext())
+	ctx = router.WithNodeID(ctx, request.NodeID)
+	if metadata, ok := stateData["metadata"].(map[string]interface{}); ok {
+		ctx = router.WithExecutionMetadata(ctx, metadata)
+	}
 	result, err := w.router.Route(ctx, graphState, nodeConfig)
 	if err != nil {
-		return fmt.Errorf("routing failed: %w", err)
+		return nil, fmt.Errorf("routing failed: %w", err)
 	}
-
-	// Publish routing decision
-	if err := w.publishDecision(request, result); err != nil {
-		return fmt.Errorf("failed to publish decision: %w", err)
+	if result.Timings != nil {
+		result.Timings.StateLoadMS = router.Milliseconds(stateLoad)
+	}
+	if nodeConfig.Sticky != nil {
+		w.rememberResult(ctx, request, nodeConfig.Sticky, stickyHash, result)
 	}
 
-	return nil
+	return result, nil
 }
 
 // parseNodeConfig parses the node configuration into router.NodeConfig
@@ -261,8 +566,9 @@ func (w *Worker) parseNodeConfig(config map[string]interface{}) (*router.NodeCon
 	return &nodeConfig, nil
 }
 
-// publishDecision publishes the routing decision
-func (w *Worker) publishDecision(request *WorkRequest, result *router.RoutingResult) error {
+// decisionValues encodes the routing decision as result stream values
+func (w *Worker) decisionValues(ctx context.Context, request *WorkRequest, result *router.RoutingResult) (map[string]interface{}, error) {
+	now := time.Now().UTC()
 	decision := map[string]interface{}{
 		"execution_id": request.ExecutionID,
 		"node_id":      request.NodeID,
@@ -270,71 +576,151 @@ func (w *Worker) publishDecision(request *WorkRequest, result *router.RoutingRes
 		"reasoning":    result.Reasoning,
 		"mode":         result.Mode,
 		"path_taken":   result.PathTaken,
-		"timestamp":    time.Now().UTC(),
+		"timestamp":    now,
 	}
-
-	data, err := json.Marshal(decision)
-	if err != nil {
-		return fmt.Errorf("failed to marshal decision: %w", err)
+	if result.Experiment != "" {
+		decision["experiment_id"] = result.Experiment
+		decision["variant"] = result.Variant
+	} else if request.Headers != nil && request.Headers.ExperimentBucket != "" {
+		decision["variant"] = request.Headers.ExperimentBucket
+	}
+	if result.Tenant != "" {
+		decision["tenant"] = result.Tenant
+	}
+	if result.Confidence > 0 {
+		decision["confidence"] = result.Confidence
+	}
+	if result.RuleIndex != nil {
+		decision["rule_index"] = *result.RuleIndex
+		decision["condition"] = result.Condition
+	}
+	if len(result.RuleSets) > 0 {
+		decision["rule_sets"] = result.RuleSets
+	}
+	if result.PipelineStage != "" {
+		decision["pipeline_stage"] = result.PipelineStage
+	}
+	if result.Timings != nil {
+		decision["timings"] = result.Timings
+	}
+	if w.version != "" {
+		decision["worker_version"] = w.version
+	}
+	if len(result.Stages) > 0 {
+		decision["stages"] = result.Stages
+	}
+	if result.PromptHash != "" {
+		decision["prompt_hash"] = result.PromptHash
 	}
 
-	// Publish to result stream
-	_, err = w.redisClient.XAdd(w.ctx, &redis.XAddArgs{
-		Stream: w.resultStream,
-		Values: map[string]interface{}{
-			"data": string(data),
-		},
-	}).Result()
+	// Latency budget: time spent queued in the stream vs. in the router
+	if !request.receivedAt.IsZero() {
+		decision["processing_ms"] = now.Sub(request.receivedAt).Milliseconds()
+		if !request.enqueuedAt.IsZero() {
+			decision["queue_wait_ms"] = request.receivedAt.Sub(request.enqueuedAt).Milliseconds()
+		}
+	}
 
+	// Apply the configured field mask
+	for field := range decision {
+		if !w.decisionFields[field] {
+			delete(decision, field)
+		}
+	}
+	published := make(map[string]bool, len(decision))
+	for field := range decision {
+		published[field] = true
+	}
+	w.enrich(ctx, request, result, decision)
+
+	var values map[string]interface{}
+	var err error
+	if request.format == FormatProtobuf {
+		extra := make(map[string]interface{})
+		for field, value := range decision {
+			if !published[field] {
+				extra[field] = value
+			}
+		}
+		var message *routerv1.Decision
+		if message, err = decisionMessage(w.decisionFields, request, result, now, w.version, extra); err == nil {
+			values, err = encodeProto(message)
+		}
+	} else {
+		values, err = encodeJSON(decision)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to publish to stream: %w", err)
+		return nil, fmt.Errorf("failed to marshal decision: %w", err)
+	}
+	if w.decisionFields["trace"] {
+		tracing.Inject(ctx, values)
 	}
 
-	w.logger.Info("published routing decision",
-		zap.String("execution_id", request.ExecutionID),
-		zap.String("target_node", result.TargetNode),
-	)
-
-	return nil
+	return values, nil
 }
 
-// publishError publishes an error event
-func (w *Worker) publishError(request *WorkRequest, err error) {
-	errorEvent := map[string]interface{}{
-		"execution_id": request.ExecutionID,
-		"node_id":      request.NodeID,
-		"error":        err.Error(),
-		"timestamp":    time.Now().UTC(),
+// errorValues encodes an error event as error stream values
+func (w *Worker) errorValues(ctx context.Context, request *WorkRequest, err error) (map[string]interface{}, error) {
+	now := time.Now().UTC()
+	code := errorCode(err)
+
+	var values map[string]interface{}
+	var marshalErr error
+	if request.format == FormatProtobuf {
+		values, marshalErr = encodeProto(&routerv1.ErrorEvent{
+			ExecutionId: request.ExecutionID,
+			NodeId:      request.NodeID,
+			Error:       err.Error(),
+			Timestamp:   timestamppb.New(now),
+			Code:        code,
+		})
+	} else {
+		errorEvent := map[string]interface{}{
+			"execution_id": request.ExecutionID,
+			"node_id":      request.NodeID,
+			"error":        err.Error(),
+			"timestamp":    now,
+		}
+		if code != "" {
+			errorEvent["code"] = code
+		}
+		values, marshalErr = encodeJSON(errorEvent)
 	}
-
-	data, marshalErr := json.Marshal(errorEvent)
 	if marshalErr != nil {
-		w.logger.Error("failed to marshal error event", zap.Error(marshalErr))
-		return
+		return nil, fmt.Errorf("failed to marshal error event: %w", marshalErr)
 	}
+	tracing.Inject(ctx, values)
 
-	// Publish error to a separate stream
-	_, publishErr := w.redisClient.XAdd(w.ctx, &redis.XAddArgs{
-		Stream: w.resultStream + ".errors",
-		Values: map[string]interface{}{
-			"data": string(data),
-		},
-	}).Result()
-
-	if publishErr != nil {
-		w.logger.Error("failed to publish error event", zap.Error(publishErr))
-	}
+	return values, nil
 }
 
 // acknowledgeMessage acknowledges a message from the stream
-func (w *Worker) acknowledgeMessage(messageID string) {
-	err := w.redisClient.XAck(w.ctx, w.streamKey, w.consumerGroup, messageID).Err()
+func (w *Worker) acknowledgeMessage(stream, messageID string) {
+	err := w.redisClient.XAck(w.ctx, stream, w.consumerGroup, messageID).Err()
 	if err != nil {
 		w.logger.Error("failed to acknowledge message",
 			zap.String("message_id", messageID),
 			zap.Error(err),
 		)
+		return
+	}
+	metrics.MessagesAcked.Inc()
+}
+
+// messageTimestamp returns the time a stream message was added, taken from
+// the millisecond part of its ID
+func messageTimestamp(messageID string) (time.Time, bool) {
+	millis, _, found := strings.Cut(messageID, "-")
+	if !found {
+		return time.Time{}, false
 	}
+
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.UnixMilli(ms), true
 }
 
 // convertToGraphState converts state.State to domain.GraphState
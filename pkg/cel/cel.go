@@ -0,0 +1,46 @@
+package cel
+
+import (
+	"github.com/aescanero/dago-node-router/internal/eval/cel"
+)
+
+// Evaluator compiles, caches and evaluates CEL expressions
+type Evaluator = cel.Evaluator
+
+// Option configures an evaluator
+type Option = cel.Option
+
+// Limits bounds the resources a single evaluation may use
+type Limits = cel.Limits
+
+// FlagFunc reports whether a feature flag is enabled
+type FlagFunc = cel.FlagFunc
+
+// NumberMode selects how JSON numbers are typed for CEL
+type NumberMode = cel.NumberMode
+
+// Number modes
+const (
+	// NumbersIntegral types whole numbers as int and others as double
+	NumbersIntegral = cel.NumbersIntegral
+	// NumbersDouble types every number as double
+	NumbersDouble = cel.NumbersDouble
+)
+
+// ErrEvalTimeout is returned when an evaluation exceeds its timeout
+var ErrEvalTimeout = cel.ErrEvalTimeout
+
+// NewEvaluator creates a CEL evaluator
+func NewEvaluator(opts ...Option) *Evaluator {
+	return cel.NewEvaluator(opts...)
+}
+
+// WithLimits bounds evaluation time, cost and nesting
+func WithLimits(limits Limits) Option {
+	return cel.WithLimits(limits)
+}
+
+// WithFlags evaluates the flag() function with flags
+func WithFlags(flags FlagFunc) Option {
+	return cel.WithFlags(flags)
+}
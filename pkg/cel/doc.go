@@ -0,0 +1,20 @@
+// Package cel evaluates the CEL expressions of routing rules outside a
+// router worker.
+//
+// Expressions see the same variables (state, ctx, lookup), macros, extension
+// functions and resource limits as deterministic routing rules, so a rule
+// that evaluates here evaluates the same way in the router.
+//
+// Example usage:
+//
+//	evaluator := cel.NewEvaluator(cel.WithLimits(cel.Limits{Timeout: 10 * time.Millisecond}))
+//	if err := evaluator.ValidateExpression(`state.inputs.priority == "high"`); err != nil {
+//	    return err
+//	}
+//	result, err := evaluator.Evaluate(ctx, `state.inputs.priority == "high"`, map[string]interface{}{
+//	    "state": map[string]interface{}{"inputs": inputs},
+//	})
+//
+// The API of this package follows semantic versioning: exported names are
+// only removed or changed in a new major version.
+package cel
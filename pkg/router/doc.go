@@ -0,0 +1,31 @@
+// Package router embeds the routing engine of the router worker in other
+// services.
+//
+// A Router routes graph states with node configs in any routing mode
+// (deterministic, llm, hybrid, classifier, keyword, pipeline and modes added
+// with package strategy), exactly as the worker does for work requests, but
+// without Redis: callers load the state and act on the decision themselves.
+//
+// Example usage:
+//
+//	r := router.NewRouter(llmClient, logger, router.WithLLMTimeout(5*time.Second))
+//
+//	config := &router.NodeConfig{
+//	    Mode: router.ModeDeterministic,
+//	    Rules: []router.Rule{
+//	        {Condition: `state.inputs.priority == "high"`, Target: "urgent_handler"},
+//	    },
+//	    Fallback: "default_handler",
+//	}
+//	if errs := r.ValidateNodeConfig(config); len(errs) > 0 {
+//	    return errs[0]
+//	}
+//
+//	ctx = router.WithNodeID(ctx, "triage")
+//	result, err := r.Route(ctx, state, config)
+//
+// The API of this package follows semantic versioning: exported names are
+// only removed or changed in a new major version, and node configs accepted
+// by one minor version are accepted by the next. Config structs may gain
+// fields in minor versions.
+package router
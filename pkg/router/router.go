@@ -0,0 +1,168 @@
+package router
+
+import (
+	"context"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/embedding"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/aescanero/dago-node-router/pkg/cel"
+	"github.com/aescanero/dago-node-router/pkg/strategy"
+	"github.com/aescanero/dago-node-router/pkg/template"
+	"go.uber.org/zap"
+)
+
+// Router routes graph states with node configs
+type Router = router.Router
+
+// Option configures a router
+type Option = router.Option
+
+// Node config types
+type (
+	// NodeConfig is the routing config of a router node
+	NodeConfig = router.NodeConfig
+	// RoutingMode names a routing mode
+	RoutingMode = router.RoutingMode
+	// Rule is a CEL routing rule
+	Rule = router.Rule
+	// GroupMatch is how the rules of a group are combined
+	GroupMatch = router.GroupMatch
+	// LLMConfig is an LLM classification config
+	LLMConfig = router.LLMConfig
+	// KeywordConfig is a keyword routing config
+	KeywordConfig = router.KeywordConfig
+	// KeywordRoute is the keywords and patterns scored for a target
+	KeywordRoute = router.KeywordRoute
+	// EmbeddingConfig is an embedding routing config
+	EmbeddingConfig = router.EmbeddingConfig
+	// EmbeddingRoute is the examples compared for a target
+	EmbeddingRoute = router.EmbeddingRoute
+	// ClassifierConfig is a classifier routing config
+	ClassifierConfig = router.ClassifierConfig
+	// PipelineStage is one strategy of a routing pipeline
+	PipelineStage = router.PipelineStage
+	// PromptGuard hardens LLM routing against prompt injection
+	PromptGuard = router.PromptGuard
+)
+
+// Result and report types
+type (
+	// RoutingResult is a routing decision
+	RoutingResult = router.RoutingResult
+	// ValidationError is a problem found in a node config
+	ValidationError = router.ValidationError
+	// Capabilities lists the routing features of a router
+	Capabilities = router.Capabilities
+	// ExecutionContext carries the request attributes exposed to rules as ctx
+	ExecutionContext = router.ExecutionContext
+	// BreakerConfig configures the LLM circuit breaker
+	BreakerConfig = router.BreakerConfig
+)
+
+// Routing modes
+const (
+	ModeDeterministic = router.ModeDeterministic
+	ModeLLM           = router.ModeLLM
+	ModeHybrid        = router.ModeHybrid
+	ModeClassifier    = router.ModeClassifier
+	ModeKeyword       = router.ModeKeyword
+	ModeEmbedding     = router.ModeEmbedding
+	ModePipeline      = router.ModePipeline
+)
+
+// Group matches
+const (
+	GroupMatchAny = router.GroupMatchAny
+	GroupMatchAll = router.GroupMatchAll
+)
+
+// NewRouter creates a router. llmClient may be nil when no config uses LLM
+// classification.
+func NewRouter(llmClient ports.LLMClient, logger *zap.Logger, opts ...Option) *Router {
+	return router.NewRouter(llmClient, logger, opts...)
+}
+
+// WithNodeID returns a context carrying the ID of the routed node, which
+// labels metrics and hit counters
+func WithNodeID(ctx context.Context, nodeID string) context.Context {
+	return router.WithNodeID(ctx, nodeID)
+}
+
+// WithExecutionContext returns a context carrying the attributes exposed to
+// rules as ctx
+func WithExecutionContext(ctx context.Context, ec *ExecutionContext) context.Context {
+	return router.WithExecutionContext(ctx, ec)
+}
+
+// WithLLMModel sets the model of LLM classifications
+func WithLLMModel(model string) Option {
+	return router.WithLLMModel(model)
+}
+
+// WithLLMTimeout bounds each LLM call
+func WithLLMTimeout(timeout time.Duration) Option {
+	return router.WithLLMTimeout(timeout)
+}
+
+// WithMaxLLMRoutes sets the route count above which LLM classification
+// degrades
+func WithMaxLLMRoutes(n int) Option {
+	return router.WithMaxLLMRoutes(n)
+}
+
+// WithMaxPromptTokens sets the estimated token budget of each prompt
+func WithMaxPromptTokens(maxTokens int) Option {
+	return router.WithMaxPromptTokens(maxTokens)
+}
+
+// WithStreaming streams label answers and stops generating once the route is
+// determined, with LLM clients that support streaming
+func WithStreaming(enabled bool) Option {
+	return router.WithStreaming(enabled)
+}
+
+// Embedder turns texts into embedding vectors for embedding configs
+type Embedder = embedding.Embedder
+
+// WithEmbedder sets the embedder of embedding configs
+func WithEmbedder(embedder Embedder) Option {
+	return router.WithEmbedder(embedder)
+}
+
+// WithCircuitBreaker guards the LLM with a circuit breaker
+func WithCircuitBreaker(config BreakerConfig) Option {
+	return router.WithCircuitBreaker(config)
+}
+
+// WithPromptGuard sets the guard of LLM configs that do not declare their own
+func WithPromptGuard(guard PromptGuard) Option {
+	return router.WithPromptGuard(guard)
+}
+
+// WithCELLimits bounds the evaluation of rule conditions
+func WithCELLimits(limits cel.Limits) Option {
+	return router.WithCELLimits(limits)
+}
+
+// WithFeatureFlags evaluates the flag() function of rules and the flag
+// template helper with flags
+func WithFeatureFlags(flags cel.FlagFunc) Option {
+	return router.WithFeatureFlags(flags)
+}
+
+// WithNumberMode selects how JSON numbers of the state are typed for rules
+func WithNumberMode(mode cel.NumberMode) Option {
+	return router.WithNumberMode(mode)
+}
+
+// WithTemplateSandbox enforces a sandbox profile on prompt templates
+func WithTemplateSandbox(sandbox template.Sandbox) Option {
+	return router.WithTemplateSandbox(sandbox)
+}
+
+// WithStrategy adds the strategy of a custom mode to this router only
+func WithStrategy(mode RoutingMode, s strategy.Strategy) Option {
+	return router.WithStrategy(mode, s)
+}
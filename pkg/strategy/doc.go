@@ -0,0 +1,22 @@
+// Package strategy lets programs embedding the router add routing modes.
+//
+// A strategy routes the node configs whose mode it is registered under,
+// reading its settings from the free-form config field of the node config.
+// Strategies registered with Register are available to every router created
+// afterwards, in Route, /validate and the modes listed by /capabilities.
+// Built-in modes cannot be replaced.
+//
+// Example usage:
+//
+//	func init() {
+//	    strategy.MustRegister("round_robin", strategy.Func(
+//	        func(ctx context.Context, state *domain.GraphState, config *strategy.NodeConfig) (*strategy.RoutingResult, error) {
+//	            targets := config.Targets
+//	            return &strategy.RoutingResult{
+//	                TargetNode: targets[next()%len(targets)],
+//	                Mode:       "round_robin",
+//	                PathTaken:  "fast",
+//	            }, nil
+//	        }))
+//	}
+package strategy
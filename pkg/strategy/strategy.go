@@ -0,0 +1,58 @@
+package strategy
+
+import (
+	"github.com/aescanero/dago-node-router/internal/router"
+)
+
+// Strategy routes the node configs of one routing mode
+type Strategy = router.Strategy
+
+// Validator is implemented by strategies that check their node configs
+// before routing and under /validate
+type Validator = router.StrategyValidator
+
+// Func adapts a function to the Strategy interface
+type Func = router.StrategyFunc
+
+// Registry holds routing strategies by mode
+type Registry = router.StrategyRegistry
+
+// Types a strategy receives and returns
+type (
+	// Mode names a routing mode
+	Mode = router.RoutingMode
+	// NodeConfig is the routing config of a router node
+	NodeConfig = router.NodeConfig
+	// RoutingResult is a routing decision
+	RoutingResult = router.RoutingResult
+	// ValidationError is a problem found in a node config
+	ValidationError = router.ValidationError
+)
+
+// NewRegistry creates an empty strategy registry
+func NewRegistry() *Registry {
+	return router.NewStrategyRegistry()
+}
+
+// Register adds the strategy of a mode to the default registry. Built-in
+// modes and modes already registered are rejected.
+func Register(mode Mode, s Strategy) error {
+	return router.RegisterStrategy(mode, s)
+}
+
+// MustRegister is like Register but panics on error, for init functions
+func MustRegister(mode Mode, s Strategy) {
+	if err := Register(mode, s); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the strategy registered for a mode in the default registry
+func Lookup(mode Mode) (Strategy, bool) {
+	return router.DefaultStrategies.Lookup(mode)
+}
+
+// Modes returns the modes of the default registry in name order
+func Modes() []Mode {
+	return router.DefaultStrategies.Modes()
+}
@@ -0,0 +1,16 @@
+// Package template renders the Handlebars prompt templates of LLM routing
+// outside a router worker.
+//
+// Templates have the same helpers and sandbox rules as the prompts rendered
+// by the router.
+//
+// Example usage:
+//
+//	engine := template.NewEngine(template.WithSandbox(template.Sandbox{MaxOutputBytes: 8192}))
+//	prompt, err := engine.Render("Classify: {{state.inputs.message}}", map[string]interface{}{
+//	    "state": map[string]interface{}{"inputs": inputs},
+//	})
+//
+// The API of this package follows semantic versioning: exported names are
+// only removed or changed in a new major version.
+package template
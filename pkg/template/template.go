@@ -0,0 +1,27 @@
+package template
+
+import (
+	"github.com/aescanero/dago-node-router/internal/eval/template"
+)
+
+// Engine compiles, caches and renders Handlebars templates
+type Engine = template.Engine
+
+// Option configures a template engine
+type Option = template.Option
+
+// Sandbox restricts what templates may do
+type Sandbox = template.Sandbox
+
+// SandboxError reports a template that violates its sandbox
+type SandboxError = template.SandboxError
+
+// NewEngine creates a Handlebars template engine with the built-in helpers
+func NewEngine(opts ...Option) *Engine {
+	return template.NewEngine(opts...)
+}
+
+// WithSandbox enforces a sandbox profile on every template
+func WithSandbox(sandbox Sandbox) Option {
+	return template.WithSandbox(sandbox)
+}
@@ -0,0 +1,25 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// BenchmarkMessage measures the handling of deterministic work requests by a
+// worker, from enqueue to published decision
+func BenchmarkMessage(b *testing.B) {
+	h := newHarness(b)
+	h.startWorker(h.cfg.WorkerID)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.enqueue("demo-outage", "bench-router", testConfigs["deterministic"])
+	}
+	ctx, cancel := context.WithTimeout(h.ctx, time.Minute)
+	defer cancel()
+	if _, err := h.collect(ctx, b.N); err != nil {
+		b.Fatal(err)
+	}
+}
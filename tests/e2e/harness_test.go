@@ -0,0 +1,339 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-router/internal/config"
+	"github.com/aescanero/dago-node-router/internal/llmmock"
+	"github.com/aescanero/dago-node-router/internal/router"
+	"github.com/aescanero/dago-node-router/internal/statestore"
+	"github.com/aescanero/dago-node-router/internal/worker"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// outcomeTimeout is how long a test waits for the outcomes it expects
+const outcomeTimeout = 30 * time.Second
+
+// testLLM answers the classification prompts by keyword, as the mock LLM of
+// LLM_PROVIDER=mock would
+var testLLM = llmmock.Config{
+	Routes: []llmmock.Route{
+		{Keyword: "refund", Response: "billing"},
+		{Keyword: "invoice", Response: "billing"},
+		{Keyword: "error", Response: "technical"},
+		{Keyword: "crash", Response: "technical"},
+	},
+	Default: "general",
+}
+
+// testStates are the graph states seeded before each test, by execution ID
+var testStates = map[string]state.State{
+	"demo-outage": {
+		"graph_id": "support-triage",
+		"status":   "running",
+		"inputs": map[string]interface{}{
+			"priority": "high",
+			"message":  "Production API returns 500 errors after the last deploy",
+		},
+	},
+	"demo-refund": {
+		"graph_id": "support-triage",
+		"status":   "running",
+		"inputs": map[string]interface{}{
+			"priority": "normal",
+			"message":  "I was charged twice, please refund the second invoice",
+		},
+	},
+	"demo-question": {
+		"graph_id": "support-triage",
+		"status":   "running",
+		"inputs": map[string]interface{}{
+			"priority": "low",
+			"message":  "How do I change the avatar on my profile?",
+		},
+	},
+}
+
+// testConfigs are routing configs for the three modes, by mode
+var testConfigs = map[string]map[string]interface{}{
+	"deterministic": {
+		"mode": "deterministic",
+		"rules": []map[string]interface{}{
+			{"condition": `state.inputs.priority == "high"`, "target": "incident_response"},
+			{"condition": `state.inputs.message.contains("refund")`, "target": "billing_agent"},
+		},
+		"fallback": "general_agent",
+	},
+	"llm": {
+		"mode": "llm",
+		"llm_config": map[string]interface{}{
+			"prompt_template": "Classify this support message as billing, technical or general: {{state.inputs.message}}",
+			"routes": map[string]string{
+				"billing":   "billing_agent",
+				"technical": "tech_support",
+				"general":   "general_agent",
+			},
+		},
+		"fallback": "general_agent",
+	},
+	"hybrid": {
+		"mode": "hybrid",
+		"fast_rules": []map[string]interface{}{
+			{"condition": `state.inputs.priority == "high"`, "target": "incident_response"},
+		},
+		"llm_fallback": map[string]interface{}{
+			"prompt_template": "Classify this support message as billing, technical or general: {{state.inputs.message}}",
+			"routes": map[string]string{
+				"billing":   "billing_agent",
+				"technical": "tech_support",
+				"general":   "general_agent",
+			},
+		},
+		"fallback": "general_agent",
+	},
+}
+
+// expectation is the outcome expected for the work request of a node
+type expectation struct {
+	nodeID string
+	// target is the expected target node of a decision
+	target string
+	// err is a substring of the expected error event
+	err string
+}
+
+// harness runs a test or benchmark against one Redis on streams named after
+// it
+type harness struct {
+	t      testing.TB
+	ctx    context.Context
+	client redis.UniversalClient
+	store  *statestore.Redis
+	cfg    *config.Config
+	logger *zap.Logger
+}
+
+// newHarness connects to the Redis at E2E_REDIS_ADDR, or to an in-memory
+// Redis when unset, resets the streams of the test and seeds the states
+func newHarness(t testing.TB) *harness {
+	t.Helper()
+
+	addr := os.Getenv("E2E_REDIS_ADDR")
+	if addr == "" {
+		embedded := miniredis.RunT(t)
+		addr = embedded.Addr()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	name := strings.ToLower(strings.ReplaceAll(t.Name(), "/", "."))
+	cfg.RedisAddr = addr
+	cfg.WorkerID = "e2e-worker"
+	cfg.StreamKey = "e2e." + name + ".work"
+	cfg.ConsumerGroup = "e2e-router"
+	cfg.ResultStream = "e2e." + name + ".decided"
+	cfg.DeadLetterStream = "e2e." + name + ".dlq"
+	cfg.StreamShards = 0
+	cfg.PriorityLanes = nil
+	cfg.ClaimEnabled = false
+	cfg.MaxRetries = 0
+	cfg.WorkTransport = config.WorkTransportRedisStreams
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { _ = client.Close() })
+
+	logger := zap.NewNop()
+	h := &harness{
+		t:      t,
+		ctx:    context.Background(),
+		client: client,
+		store:  statestore.NewRedis(client, logger),
+		cfg:    cfg,
+		logger: logger,
+	}
+	if err := client.Del(h.ctx, cfg.StreamKey, cfg.ResultStream, cfg.ResultStream+".errors", cfg.DeadLetterStream).Err(); err != nil {
+		t.Fatalf("failed to reset streams: %v", err)
+	}
+	for executionID, st := range testStates {
+		if err := h.store.Save(h.ctx, executionID, st); err != nil {
+			t.Fatalf("failed to seed state %s: %v", executionID, err)
+		}
+	}
+	return h
+}
+
+// startWorker starts a worker with the given consumer name, stopped when the
+// test ends
+func (h *harness) startWorker(workerID string) *worker.Worker {
+	h.t.Helper()
+
+	workerCfg := *h.cfg
+	workerCfg.WorkerID = workerID
+	routerInstance := router.NewRouter(llmmock.NewClient(testLLM), h.logger)
+	w := worker.NewWorker(&workerCfg, h.client, routerInstance, nopEventBus{}, h.store, h.logger)
+	if err := w.Start(); err != nil {
+		h.t.Fatalf("failed to start worker: %v", err)
+	}
+	h.t.Cleanup(func() { _ = w.Stop() })
+	return w
+}
+
+// enqueue adds a work request for an execution to the work stream
+func (h *harness) enqueue(executionID, nodeID string, nodeConfig interface{}) {
+	h.t.Helper()
+
+	request, err := json.Marshal(map[string]interface{}{
+		"execution_id": executionID,
+		"node_id":      nodeID,
+		"config":       nodeConfig,
+	})
+	if err != nil {
+		h.t.Fatalf("failed to marshal work request: %v", err)
+	}
+	h.enqueueValues(map[string]interface{}{"data": string(request)})
+}
+
+// enqueueValues adds a raw message to the work stream
+func (h *harness) enqueueValues(values map[string]interface{}) {
+	h.t.Helper()
+
+	if err := h.client.XAdd(h.ctx, &redis.XAddArgs{Stream: h.cfg.StreamKey, Values: values}).Err(); err != nil {
+		h.t.Fatalf("failed to enqueue work request: %v", err)
+	}
+}
+
+// expect waits for the outcomes of the expected work requests and checks
+// them, then checks that no other outcome was published and nothing is left
+// pending
+func (h *harness) expect(expected ...expectation) {
+	h.t.Helper()
+
+	ctx, cancel := context.WithTimeout(h.ctx, outcomeTimeout)
+	defer cancel()
+
+	outcomes, err := h.collect(ctx, len(expected))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	byNode := make(map[string]map[string]interface{}, len(outcomes))
+	for _, outcome := range outcomes {
+		nodeID, _ := outcome["node_id"].(string)
+		if _, seen := byNode[nodeID]; seen {
+			h.t.Fatalf("node %s: outcome published twice", nodeID)
+		}
+		byNode[nodeID] = outcome
+	}
+
+	for _, want := range expected {
+		outcome, ok := byNode[want.nodeID]
+		if !ok {
+			h.t.Fatalf("node %s: no outcome published", want.nodeID)
+		}
+		target, _ := outcome["target_node"].(string)
+		switch {
+		case want.err != "":
+			message, _ := outcome["error"].(string)
+			if target != "(error)" || !strings.Contains(message, want.err) {
+				h.t.Fatalf("node %s: expected error event containing %q, got target %q (%v)", want.nodeID, want.err, target, outcome["reasoning"])
+			}
+		case target != want.target:
+			h.t.Fatalf("node %s: expected target %q, got %q (%v)", want.nodeID, want.target, target, outcome["reasoning"])
+		}
+	}
+
+	h.expectSettled(len(expected))
+}
+
+// expectSettled checks that exactly n outcomes were published and that no
+// work request is left pending in the consumer group
+func (h *harness) expectSettled(n int) {
+	h.t.Helper()
+
+	// Give acks and late duplicates time to land
+	time.Sleep(200 * time.Millisecond)
+
+	decisions, err := h.client.XLen(h.ctx, h.cfg.ResultStream).Result()
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	errorEvents, err := h.client.XLen(h.ctx, h.cfg.ResultStream+".errors").Result()
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if published := decisions + errorEvents; published != int64(n) {
+		h.t.Fatalf("expected %d outcomes, %d were published", n, published)
+	}
+
+	pending, err := h.client.XPending(h.ctx, h.cfg.StreamKey, h.cfg.ConsumerGroup).Result()
+	if err != nil {
+		h.t.Fatalf("failed to read pending entries: %v", err)
+	}
+	if pending.Count > 0 {
+		h.t.Fatalf("%d work request(s) left pending", pending.Count)
+	}
+}
+
+// collect reads decisions and error events until n outcomes arrived
+func (h *harness) collect(ctx context.Context, n int) ([]map[string]interface{}, error) {
+	errorStream := h.cfg.ResultStream + ".errors"
+	last := map[string]string{h.cfg.ResultStream: "0", errorStream: "0"}
+	var outcomes []map[string]interface{}
+
+	for len(outcomes) < n {
+		streams, err := h.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{h.cfg.ResultStream, errorStream, last[h.cfg.ResultStream], last[errorStream]},
+			Block:   time.Second,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return outcomes, fmt.Errorf("received %d of %d outcomes: %w", len(outcomes), n, err)
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				last[stream.Stream] = message.ID
+				var outcome map[string]interface{}
+				data, _ := message.Values["data"].(string)
+				if err := json.Unmarshal([]byte(data), &outcome); err != nil {
+					continue
+				}
+				if stream.Stream == errorStream {
+					outcome["target_node"] = "(error)"
+					outcome["reasoning"] = outcome["error"]
+				}
+				outcomes = append(outcomes, outcome)
+			}
+		}
+	}
+	return outcomes, nil
+}
+
+// nopEventBus satisfies ports.EventBus; the worker publishes outcomes to
+// Redis streams directly
+type nopEventBus struct{}
+
+// Publish discards the event
+func (nopEventBus) Publish(context.Context, string, ports.Event) error { return nil }
+
+// Subscribe is a no-op
+func (nopEventBus) Subscribe(context.Context, string, ports.EventHandler) error { return nil }
+
+// Unsubscribe is a no-op
+func (nopEventBus) Unsubscribe(context.Context, string) error { return nil }
+
+// Close is a no-op
+func (nopEventBus) Close() error { return nil }
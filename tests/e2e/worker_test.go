@@ -0,0 +1,175 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestRoutingModes checks the decisions published for the seeded states in
+// each routing mode
+func TestRoutingModes(t *testing.T) {
+	modes := map[string]map[string]string{
+		"deterministic": {
+			"demo-outage":   "incident_response",
+			"demo-refund":   "billing_agent",
+			"demo-question": "general_agent",
+		},
+		"llm": {
+			"demo-outage":   "tech_support",
+			"demo-refund":   "billing_agent",
+			"demo-question": "general_agent",
+		},
+		"hybrid": {
+			"demo-outage":   "incident_response",
+			"demo-refund":   "billing_agent",
+			"demo-question": "general_agent",
+		},
+	}
+
+	for mode, targets := range modes {
+		mode, targets := mode, targets
+		t.Run(mode, func(t *testing.T) {
+			h := newHarness(t)
+			h.startWorker(h.cfg.WorkerID)
+
+			var expected []expectation
+			for _, executionID := range []string{"demo-outage", "demo-refund", "demo-question"} {
+				nodeID := mode + "-" + executionID
+				h.enqueue(executionID, nodeID, testConfigs[mode])
+				expected = append(expected, expectation{nodeID: nodeID, target: targets[executionID]})
+			}
+			h.expect(expected...)
+		})
+	}
+}
+
+// TestErrorEvents checks that invalid configs and missing states publish
+// error events, and that malformed messages are dead-lettered and acked
+func TestErrorEvents(t *testing.T) {
+	h := newHarness(t)
+	h.startWorker(h.cfg.WorkerID)
+
+	h.enqueue("demo-outage", "invalid-mode", map[string]interface{}{"mode": "bogus", "fallback": "general_agent"})
+	h.enqueue("e2e-missing", "missing-state", testConfigs["deterministic"])
+	h.enqueueValues(map[string]interface{}{"data": "{not json"})
+
+	h.expect(
+		expectation{nodeID: "invalid-mode", err: "unknown routing mode"},
+		expectation{nodeID: "missing-state", err: "failed to load state"},
+	)
+
+	dead, err := h.client.XLen(h.ctx, h.cfg.DeadLetterStream).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dead != 1 {
+		t.Fatalf("expected the malformed message in the dead letter stream, found %d entries", dead)
+	}
+}
+
+// TestRetryMissingState checks that a request whose state is written after
+// it arrived is retried and routed
+func TestRetryMissingState(t *testing.T) {
+	h := newHarness(t)
+	h.cfg.MaxRetries = 5
+	h.cfg.RetryDelay = 200 * time.Millisecond
+
+	executionID := "e2e-late-state"
+	if err := h.store.Delete(h.ctx, executionID); err != nil {
+		t.Fatal(err)
+	}
+	h.startWorker(h.cfg.WorkerID)
+
+	h.enqueue(executionID, "late-state", testConfigs["deterministic"])
+	time.Sleep(300 * time.Millisecond)
+	if err := h.store.Save(h.ctx, executionID, testStates["demo-refund"]); err != nil {
+		t.Fatal(err)
+	}
+
+	h.expect(expectation{nodeID: "late-state", target: "billing_agent"})
+}
+
+// TestRecoveryClaim checks that a request delivered to a consumer that
+// crashed before acking it is claimed and routed by another worker
+func TestRecoveryClaim(t *testing.T) {
+	h := newHarness(t)
+	h.cfg.ClaimEnabled = true
+	h.cfg.ClaimInterval = 100 * time.Millisecond
+	h.cfg.ClaimMinIdle = 300 * time.Millisecond
+
+	if err := h.client.XGroupCreateMkStream(h.ctx, h.cfg.StreamKey, h.cfg.ConsumerGroup, "0").Err(); err != nil {
+		t.Fatalf("failed to create consumer group: %v", err)
+	}
+	h.enqueue("demo-outage", "orphaned", testConfigs["deterministic"])
+
+	// The crashed consumer reads the request and never acks it
+	delivered, err := h.client.XReadGroup(h.ctx, &redis.XReadGroupArgs{
+		Group:    h.cfg.ConsumerGroup,
+		Consumer: "e2e-crashed",
+		Streams:  []string{h.cfg.StreamKey, ">"},
+		Count:    1,
+	}).Result()
+	if err != nil || len(delivered) == 0 || len(delivered[0].Messages) != 1 {
+		t.Fatalf("crashed consumer did not receive the request: %v", err)
+	}
+
+	h.startWorker(h.cfg.WorkerID)
+	h.expect(expectation{nodeID: "orphaned", target: "incident_response"})
+}
+
+// TestRecoveryRestart checks that requests enqueued while no worker runs are
+// routed exactly once by the next worker of the consumer group
+func TestRecoveryRestart(t *testing.T) {
+	h := newHarness(t)
+
+	first := h.startWorker("e2e-worker-1")
+	h.enqueue("demo-outage", "before-restart", testConfigs["deterministic"])
+	h.expect(expectation{nodeID: "before-restart", target: "incident_response"})
+	_ = first.Stop()
+
+	for _, executionID := range []string{"demo-refund", "demo-question"} {
+		h.enqueue(executionID, "while-down-"+executionID, testConfigs["deterministic"])
+	}
+
+	h.startWorker("e2e-worker-2")
+	h.expect(
+		expectation{nodeID: "before-restart", target: "incident_response"},
+		expectation{nodeID: "while-down-demo-refund", target: "billing_agent"},
+		expectation{nodeID: "while-down-demo-question", target: "general_agent"},
+	)
+}
+
+// TestRecordDecision checks that a decision recorded in the execution state
+// is visible to the rules of the next node
+func TestRecordDecision(t *testing.T) {
+	h := newHarness(t)
+
+	executionID := "e2e-recorded"
+	if err := h.store.Save(h.ctx, executionID, testStates["demo-refund"]); err != nil {
+		t.Fatal(err)
+	}
+	h.startWorker(h.cfg.WorkerID)
+
+	triage := map[string]interface{}{}
+	for key, value := range testConfigs["deterministic"] {
+		triage[key] = value
+	}
+	triage["record_decision_in_state"] = true
+	h.enqueue(executionID, "triage", triage)
+	h.expect(expectation{nodeID: "triage", target: "billing_agent"})
+
+	followUp := map[string]interface{}{
+		"mode": "deterministic",
+		"rules": []map[string]interface{}{
+			{"condition": `state.node_states["triage"].output.target_node == "billing_agent"`, "target": "refund_review"},
+		},
+		"fallback": "general_agent",
+	}
+	h.enqueue(executionID, "follow-up", followUp)
+	h.expect(
+		expectation{nodeID: "triage", target: "billing_agent"},
+		expectation{nodeID: "follow-up", target: "refund_review"},
+	)
+}